@@ -3,9 +3,11 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"go.uber.org/zap"
@@ -34,8 +36,11 @@ func main() {
 }
 
 func run() int {
+	configPath := flag.String("config", "", "path of a YAML or TOML config file; environment variables override it")
+	flag.Parse()
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.LoadFile(*configPath)
 	if err != nil {
 		// Use a basic logger for startup errors
 		basicLogger, _ := zap.NewProduction()
@@ -43,7 +48,7 @@ func run() int {
 	}
 
 	// Initialize logger
-	logger, err := initLogger(cfg.LogLevel)
+	logger, level, err := initLogger(cfg.LogLevel)
 	if err != nil {
 		basicLogger, _ := zap.NewProduction()
 		basicLogger.Fatal("failed to initialize logger", zap.Error(err))
@@ -59,7 +64,10 @@ func run() int {
 		zap.Duration("shutdown_timeout", cfg.ShutdownTimeout),
 		zap.Bool("metrics_enabled", cfg.MetricsEnabled),
 		zap.String("auth_mode", cfg.AuthMode),
+		zap.Bool("authz_enabled", cfg.AuthzPolicyFile != ""),
 		zap.Bool("tls_enabled", cfg.TLSEnabled),
+		zap.String("store_driver", cfg.StoreDriver),
+		zap.String("config_file", *configPath),
 		zap.String("version", Version),
 		zap.String("commit", Commit),
 	)
@@ -90,11 +98,41 @@ func run() int {
 		logger.Fatal("failed to create authenticator", zap.Error(err))
 	}
 
-	// Create memory store wrapped with metrics instrumentation.
-	itemStore := store.NewInstrumentedStore(store.NewMemoryStore())
+	// Create the configured store backend wrapped with metrics instrumentation.
+	backend, closeStore, err := createStore(rootCtx, cfg, logger)
+	if err != nil {
+		logger.Fatal("failed to create store", zap.Error(err))
+	}
+	defer func() {
+		if err := closeStore(); err != nil {
+			logger.Error("failed to close store", zap.Error(err))
+		}
+	}()
+	itemStore := store.NewInstrumentedStore(backend)
 
 	// Create and start server (pass authenticator + tracer)
 	srv := server.New(cfg, logger, itemStore, authenticator, telemetry.Tracer())
+	if keys, ok := findAuthenticator[*auth.APIKeyAuthenticator](authenticator); ok {
+		srv.EnableAPIKeyManagement(keys)
+	}
+
+	// Watch the htpasswd file of Basic auth for changes.
+	basic, _ := findAuthenticator[*auth.BasicAuthenticator](authenticator)
+	if basic != nil {
+		basic.Start()
+		defer basic.Stop()
+	}
+
+	current := *cfg
+	reload := &reloader{
+		path:    *configPath,
+		current: &current,
+		level:   level,
+		srv:     srv,
+		basic:   basic,
+		logger:  logger,
+	}
+	reload.apiKeys, _ = findAuthenticator[*auth.APIKeyAuthenticator](authenticator)
 
 	// Start server in a goroutine
 	serverErrors := make(chan error, 1)
@@ -102,46 +140,73 @@ func run() int {
 		serverErrors <- srv.Start()
 	}()
 
-	// Wait for shutdown signal
+	// Wait for shutdown signal, reloading the configuration on SIGHUP
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
 
-	select {
-	case err := <-serverErrors:
+	sig, err := waitForShutdown(serverErrors, shutdown, hangup, reload)
+	if err != nil {
 		logger.Error("server error", zap.Error(err))
 		return 1
-	case sig := <-shutdown:
-		logger.Info("shutdown signal received", zap.String("signal", sig.String()))
+	}
 
-		// Create shutdown context with timeout (reuse the root context).
-		ctx, cancel := context.WithTimeout(rootCtx, cfg.ShutdownTimeout)
-		defer cancel()
+	logger.Info("shutdown signal received", zap.String("signal", sig.String()))
 
-		// Graceful shutdown
-		if err := srv.Shutdown(ctx); err != nil {
-			logger.Error("graceful shutdown failed", zap.Error(err))
-			return 1
-		}
+	// Create shutdown context with timeout (reuse the root context).
+	ctx, cancel := context.WithTimeout(rootCtx, cfg.ShutdownTimeout)
+	defer cancel()
 
-		// Flush any buffered spans within the shutdown deadline.
-		if err := telemetry.Shutdown(ctx); err != nil {
-			logger.Error("telemetry shutdown failed", zap.Error(err))
-		}
+	// Graceful shutdown
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Error("graceful shutdown failed", zap.Error(err))
+		return 1
+	}
+
+	// Flush any buffered spans within the shutdown deadline.
+	if err := telemetry.Shutdown(ctx); err != nil {
+		logger.Error("telemetry shutdown failed", zap.Error(err))
 	}
 
 	logger.Info("server stopped")
 	return 0
 }
 
-// initLogger initializes a zap logger with the specified log level.
-func initLogger(level string) (*zap.Logger, error) {
+// waitForShutdown waits for a shutdown signal or a server error, reloading
+// the configuration whenever a signal arrives on hangup. A failed reload
+// keeps the current configuration.
+func waitForShutdown(
+	serverErrors <-chan error,
+	shutdown, hangup <-chan os.Signal,
+	reload *reloader,
+) (os.Signal, error) {
+	for {
+		select {
+		case err := <-serverErrors:
+			return nil, err
+		case sig := <-shutdown:
+			return sig, nil
+		case <-hangup:
+			reload.logger.Info("reload signal received")
+			if err := reload.reload(); err != nil {
+				reload.logger.Error("failed to reload configuration, keeping the current one", zap.Error(err))
+			}
+		}
+	}
+}
+
+// initLogger initializes a zap logger with the specified log level. The
+// returned level can be changed while the logger is in use.
+func initLogger(level string) (*zap.Logger, zap.AtomicLevel, error) {
 	var zapLevel zapcore.Level
 	if err := zapLevel.UnmarshalText([]byte(level)); err != nil {
 		zapLevel = zapcore.InfoLevel
 	}
+	atomicLevel := zap.NewAtomicLevelAt(zapLevel)
 
 	zapConfig := zap.Config{
-		Level:       zap.NewAtomicLevelAt(zapLevel),
+		Level:       atomicLevel,
 		Development: false,
 		Sampling: &zap.SamplingConfig{
 			Initial:    100,
@@ -166,7 +231,46 @@ func initLogger(level string) (*zap.Logger, error) {
 		ErrorOutputPaths: []string{"stderr"},
 	}
 
-	return zapConfig.Build()
+	logger, err := zapConfig.Build()
+	return logger, atomicLevel, err
+}
+
+// createStore creates the item store backend selected by the config store
+// driver. The returned close function releases backend resources and is
+// never nil.
+func createStore(
+	ctx context.Context,
+	cfg *config.Config,
+	logger *zap.Logger,
+) (store.Store, func() error, error) {
+	switch cfg.StoreDriver {
+	case "memory", "":
+		logger.Info("store driver: memory")
+		return store.NewMemoryStore(), func() error { return nil }, nil
+	case "postgres":
+		logger.Info("store driver: postgres")
+		openCtx, cancel := context.WithTimeout(ctx, cfg.StoreConnectTimeoutOrDefault())
+		defer cancel()
+		pg, err := store.OpenPostgres(openCtx, store.PostgresConfig{
+			DSN:             cfg.StoreDSN,
+			MaxOpenConns:    cfg.StoreMaxOpenConns,
+			MaxIdleConns:    cfg.StoreMaxIdleConns,
+			ConnMaxLifetime: cfg.StoreConnMaxLifetime,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("creating postgres store: %w", err)
+		}
+		return pg, pg.Close, nil
+	case "file":
+		logger.Info("store driver: file", zap.String("path", cfg.StorePath))
+		fs, err := store.OpenFileStore(cfg.StorePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("creating file store: %w", err)
+		}
+		return fs, fs.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown store driver: %s", cfg.StoreDriver)
+	}
 }
 
 // createAuthenticator creates an authenticator based on the config auth mode.
@@ -179,28 +283,32 @@ func createAuthenticator(
 		logger.Info("authentication disabled")
 		return nil, nil
 	case "mtls":
-		logger.Info("authentication mode: mTLS")
-		return auth.NewMTLSAuthenticator(), nil
+		logger.Info("authentication mode: mTLS",
+			zap.String("subject", cfg.MTLSSubject),
+		)
+		return newMTLSAuthenticator(cfg)
 	case "basic":
-		logger.Info("authentication mode: basic auth")
-		return auth.NewBasicAuthenticator(cfg.BasicAuthUsers)
+		logger.Info("authentication mode: basic auth",
+			zap.String("users_file", cfg.BasicAuthFile),
+		)
+		return newBasicAuthenticator(cfg, logger)
 	case "apikey":
-		logger.Info("authentication mode: API key")
-		return auth.NewAPIKeyAuthenticator(cfg.APIKeys)
+		logger.Info("authentication mode: API key",
+			zap.String("key_file", cfg.APIKeyFile),
+		)
+		return newAPIKeyAuthenticator(cfg)
 	case "oidc":
 		logger.Info("authentication mode: OIDC",
-			zap.String("issuer_url", cfg.OIDCIssuerURL),
+			zap.Strings("issuer_urls", oidcIssuerURLs(cfg)),
 			zap.String("client_id", cfg.OIDCClientID),
 		)
-		verifier, err := auth.NewOIDCTokenVerifier(cfg.OIDCIssuerURL)
-		if err != nil {
-			return nil, fmt.Errorf(
-				"creating OIDC token verifier: %w", err,
-			)
-		}
-		return auth.NewOIDCAuthenticator(
-			verifier, cfg.OIDCAudience,
-		), nil
+		return newOIDCAuthenticator(cfg)
+	case "introspection":
+		logger.Info("authentication mode: token introspection",
+			zap.String("introspection_url", cfg.IntrospectionURL),
+			zap.String("client_id", cfg.IntrospectionClientID),
+		)
+		return newIntrospectionAuthenticator(cfg, false)
 	case "multi":
 		logger.Info("authentication mode: multi")
 		return createMultiAuthenticator(cfg, logger)
@@ -209,6 +317,170 @@ func createAuthenticator(
 	}
 }
 
+// newMTLSAuthenticator creates an mTLS authenticator with the configured
+// identity mapping.
+func newMTLSAuthenticator(cfg *config.Config) (*auth.MTLSAuthenticator, error) {
+	ma, err := auth.NewMTLSAuthenticator(auth.MTLSConfig{
+		SubjectSource: cfg.MTLSSubject,
+		TrustDomains:  config.SplitList(cfg.MTLSTrustDomains),
+		AllowedOUs:    config.SplitList(cfg.MTLSAllowedOUs),
+		AllowedSANs:   config.SplitList(cfg.MTLSAllowedSANs),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating mTLS authenticator: %w", err)
+	}
+	return ma, nil
+}
+
+// newAPIKeyAuthenticator creates an API key authenticator for the static keys
+// in the config and the managed keys in the API key file or, without one, in
+// memory.
+func newAPIKeyAuthenticator(cfg *config.Config) (*auth.APIKeyAuthenticator, error) {
+	var keyStore auth.APIKeyStore = auth.NewMemoryAPIKeyStore()
+	if cfg.APIKeyFile != "" {
+		fileStore, err := auth.OpenFileAPIKeyStore(cfg.APIKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("opening API key store: %w", err)
+		}
+		keyStore = fileStore
+	}
+
+	ak, err := auth.NewAPIKeyStoreAuthenticator(cfg.APIKeys, keyStore)
+	if err != nil {
+		return nil, fmt.Errorf("creating API key authenticator: %w", err)
+	}
+
+	ak.SetLockout(newLockout(cfg, auth.AuthMethodAPIKey))
+	return ak, nil
+}
+
+// newLockout creates the lockout of failed attempts for authenticators of
+// the given method, or nil if lockouts are disabled.
+func newLockout(cfg *config.Config, method auth.AuthMethod) *auth.Lockout {
+	if !cfg.LockoutEnabled() {
+		return nil
+	}
+
+	return auth.NewLockout(auth.LockoutConfig{
+		SubjectMaxFailures: cfg.LockoutMaxFailures,
+		ClientMaxFailures:  cfg.LockoutMaxClientFailures,
+		BaseDelay:          cfg.LockoutBaseDelay,
+		MaxDelay:           cfg.LockoutMaxDelay,
+		ResetAfter:         cfg.LockoutResetAfter,
+	}, method)
+}
+
+// findAuthenticator returns the authenticator of type T used by a, if any.
+func findAuthenticator[T auth.Authenticator](a auth.Authenticator) (T, bool) {
+	switch a := a.(type) {
+	case T:
+		return a, true
+	case *auth.MultiAuthenticator:
+		for _, inner := range a.Authenticators() {
+			if found, ok := findAuthenticator[T](inner); ok {
+				return found, true
+			}
+		}
+	}
+
+	var zero T
+	return zero, false
+}
+
+// newBasicAuthenticator creates a Basic authenticator for the users in the
+// htpasswd file or, without one, in the config.
+func newBasicAuthenticator(cfg *config.Config, logger *zap.Logger) (*auth.BasicAuthenticator, error) {
+	var ba *auth.BasicAuthenticator
+	var err error
+	if cfg.BasicAuthFile != "" {
+		ba, err = auth.NewHtpasswdAuthenticator(auth.HtpasswdConfig{
+			Path:           cfg.BasicAuthFile,
+			ReloadInterval: cfg.BasicAuthReload,
+			Logger:         logger,
+		})
+	} else {
+		ba, err = auth.NewBasicAuthenticator(cfg.BasicAuthUsers)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating basic authenticator: %w", err)
+	}
+
+	ba.SetCacheTTL(cfg.BasicAuthCacheTTL)
+	ba.SetLockout(newLockout(cfg, auth.AuthMethodBasic))
+	return ba, nil
+}
+
+// newOIDCAuthenticator creates an OIDC authenticator that routes tokens to
+// the configured issuers by their iss claim.
+func newOIDCAuthenticator(cfg *config.Config) (*auth.OIDCAuthenticator, error) {
+	var issuers []auth.OIDCIssuer
+	for _, issuer := range cfg.OIDCIssuerList() {
+		verifier, err := auth.NewOIDCTokenVerifier(
+			issuer.URL, config.SplitList(issuer.Algorithms)...,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"creating OIDC token verifier for %s: %w", issuer.URL, err,
+			)
+		}
+		verifier.SetClockSkew(issuer.ClockSkew)
+
+		issuers = append(issuers, auth.OIDCIssuer{
+			Issuer:         issuer.URL,
+			Verifier:       verifier,
+			Audience:       issuer.Audience,
+			RequiredClaims: parseRequiredClaims(issuer.RequiredClaims),
+		})
+	}
+
+	oa, err := auth.NewMultiIssuerOIDCAuthenticator(issuers...)
+	if err != nil {
+		return nil, fmt.Errorf("creating OIDC authenticator: %w", err)
+	}
+	return oa, nil
+}
+
+// oidcIssuerURLs returns the URLs of the configured OIDC issuers.
+func oidcIssuerURLs(cfg *config.Config) []string {
+	var urls []string
+	for _, issuer := range cfg.OIDCIssuerList() {
+		urls = append(urls, issuer.URL)
+	}
+	return urls
+}
+
+// parseRequiredClaims parses a comma-separated list of "name" or
+// "name=value" claim requirements.
+func parseRequiredClaims(list string) map[string]string {
+	claims := make(map[string]string)
+	for _, item := range config.SplitList(list) {
+		name, value, _ := strings.Cut(item, "=")
+		claims[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return claims
+}
+
+// newIntrospectionAuthenticator creates a token introspection
+// authenticator. With opaqueOnly, JWTs are left to another authenticator.
+func newIntrospectionAuthenticator(
+	cfg *config.Config,
+	opaqueOnly bool,
+) (*auth.IntrospectionAuthenticator, error) {
+	ia, err := auth.NewIntrospectionAuthenticator(auth.IntrospectionConfig{
+		URL:              cfg.IntrospectionURL,
+		ClientID:         cfg.IntrospectionClientID,
+		ClientSecret:     cfg.IntrospectionClientSecret,
+		Audience:         cfg.OIDCAudience,
+		CacheTTL:         cfg.IntrospectionCacheTTL,
+		NegativeCacheTTL: cfg.IntrospectionNegativeTTL,
+		OpaqueOnly:       opaqueOnly,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating introspection authenticator: %w", err)
+	}
+	return ia, nil
+}
+
 // createMultiAuthenticator creates a multi-method authenticator
 // from the available auth configurations.
 func createMultiAuthenticator(
@@ -218,47 +490,54 @@ func createMultiAuthenticator(
 	var authenticators []auth.Authenticator
 
 	if cfg.TLSEnabled && cfg.TLSClientAuth == "require" {
-		authenticators = append(
-			authenticators, auth.NewMTLSAuthenticator(),
-		)
+		ma, err := newMTLSAuthenticator(cfg)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, ma)
 		logger.Info("multi-auth: mTLS enabled")
 	}
 
-	if cfg.BasicAuthUsers != "" {
-		ba, err := auth.NewBasicAuthenticator(cfg.BasicAuthUsers)
+	if cfg.BasicAuthUsers != "" || cfg.BasicAuthFile != "" {
+		ba, err := newBasicAuthenticator(cfg, logger)
 		if err != nil {
-			return nil, fmt.Errorf(
-				"creating basic authenticator: %w", err,
-			)
+			return nil, err
 		}
 		authenticators = append(authenticators, ba)
 		logger.Info("multi-auth: basic auth enabled")
 	}
 
-	if cfg.APIKeys != "" {
-		ak, err := auth.NewAPIKeyAuthenticator(cfg.APIKeys)
+	if cfg.APIKeys != "" || cfg.APIKeyFile != "" {
+		ak, err := newAPIKeyAuthenticator(cfg)
 		if err != nil {
-			return nil, fmt.Errorf(
-				"creating API key authenticator: %w", err,
-			)
+			return nil, err
 		}
 		authenticators = append(authenticators, ak)
 		logger.Info("multi-auth: API key auth enabled")
 	}
 
-	if cfg.OIDCIssuerURL != "" && cfg.OIDCClientID != "" {
-		verifier, err := auth.NewOIDCTokenVerifier(cfg.OIDCIssuerURL)
+	oidcEnabled := len(cfg.OIDCIssuerList()) > 0 && cfg.OIDCClientID != ""
+
+	// Opaque tokens are introspected; with OIDC enabled too, JWTs are left
+	// to the local OIDC verifier that follows.
+	if cfg.IntrospectionURL != "" {
+		ia, err := newIntrospectionAuthenticator(cfg, oidcEnabled)
 		if err != nil {
-			return nil, fmt.Errorf(
-				"creating OIDC token verifier for multi-auth: %w",
-				err,
-			)
+			return nil, err
+		}
+		authenticators = append(authenticators, ia)
+		logger.Info("multi-auth: token introspection enabled")
+	}
+
+	if oidcEnabled {
+		oa, err := newOIDCAuthenticator(cfg)
+		if err != nil {
+			return nil, err
 		}
-		authenticators = append(
-			authenticators,
-			auth.NewOIDCAuthenticator(verifier, cfg.OIDCAudience),
+		authenticators = append(authenticators, oa)
+		logger.Info("multi-auth: OIDC enabled",
+			zap.Strings("issuer_urls", oidcIssuerURLs(cfg)),
 		)
-		logger.Info("multi-auth: OIDC enabled")
 	}
 
 	if len(authenticators) == 0 {
@@ -1,30 +1,44 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
 	"github.com/vyrodovalexey/restapi-example/internal/config"
+	"github.com/vyrodovalexey/restapi-example/internal/store"
 )
 
 func TestInitLogger(t *testing.T) {
 	tests := []struct {
-		name    string
-		level   string
-		wantErr bool
+		name      string
+		level     string
+		wantLevel zapcore.Level
+		wantErr   bool
 	}{
-		{"debug level", "debug", false},
-		{"info level", "info", false},
-		{"warn level", "warn", false},
-		{"error level", "error", false},
-		{"invalid level defaults to info", "invalid", false},
+		{"debug level", "debug", zapcore.DebugLevel, false},
+		{"info level", "info", zapcore.InfoLevel, false},
+		{"warn level", "warn", zapcore.WarnLevel, false},
+		{"error level", "error", zapcore.ErrorLevel, false},
+		{"invalid level defaults to info", "invalid", zapcore.InfoLevel, false},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Act
-			logger, err := initLogger(tt.level)
+			logger, level, err := initLogger(tt.level)
 
 			// Assert
 			if tt.wantErr {
@@ -40,6 +54,9 @@ func TestInitLogger(t *testing.T) {
 			if logger == nil {
 				t.Error("initLogger() returned nil logger")
 			}
+			if level.Level() != tt.wantLevel {
+				t.Errorf("initLogger() level = %v, want %v", level.Level(), tt.wantLevel)
+			}
 		})
 	}
 }
@@ -101,6 +118,62 @@ func TestCreateAuthenticator_MTLS(t *testing.T) {
 	}
 }
 
+func TestCreateAuthenticator_MTLSIdentityMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		wantErr bool
+	}{
+		{
+			name: "SPIFFE subject with allow-lists",
+			cfg: &config.Config{
+				AuthMode:         "mtls",
+				MTLSSubject:      "spiffe",
+				MTLSTrustDomains: "example.org, prod.example.org",
+				MTLSAllowedOUs:   "payments",
+				MTLSAllowedSANs:  "spiffe://example.org/ns/*/sa/*",
+			},
+		},
+		{
+			name: "unknown subject source",
+			cfg: &config.Config{
+				AuthMode:    "mtls",
+				MTLSSubject: "serial",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid SAN pattern",
+			cfg: &config.Config{
+				AuthMode:        "mtls",
+				MTLSAllowedSANs: "[",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			authenticator, err := createAuthenticator(tt.cfg, zap.NewNop())
+
+			// Assert
+			if tt.wantErr {
+				if err == nil {
+					t.Error("createAuthenticator() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("createAuthenticator() error = %v", err)
+			}
+			if authenticator == nil {
+				t.Error("createAuthenticator() should return non-nil for 'mtls' mode")
+			}
+		})
+	}
+}
+
 func TestCreateAuthenticator_Basic(t *testing.T) {
 	// Arrange
 	cfg := &config.Config{
@@ -121,6 +194,93 @@ func TestCreateAuthenticator_Basic(t *testing.T) {
 	}
 }
 
+func TestCreateAuthenticator_BasicFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		content string
+		wantErr bool
+	}{
+		{name: "basic mode", mode: "basic", content: "admin:$5$salt$hash\n"},
+		{name: "multi mode", mode: "multi", content: "admin:$5$salt$hash\n"},
+		{name: "unsupported hash", mode: "basic", content: "admin:secret\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			path := filepath.Join(t.TempDir(), "htpasswd")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("writing htpasswd file: %v", err)
+			}
+			cfg := &config.Config{
+				AuthMode:          tt.mode,
+				BasicAuthFile:     path,
+				BasicAuthReload:   time.Second,
+				BasicAuthCacheTTL: time.Minute,
+			}
+
+			// Act
+			authenticator, err := createAuthenticator(cfg, zap.NewNop())
+
+			// Assert
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "creating basic authenticator") {
+					t.Errorf("createAuthenticator() error = %v, want basic authenticator error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("createAuthenticator() error = %v", err)
+			}
+			if _, ok := findAuthenticator[*auth.BasicAuthenticator](authenticator); !ok {
+				t.Error("findAuthenticator() found no Basic authenticator")
+			}
+		})
+	}
+}
+
+func TestNewLockout(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        *config.Config
+		wantLocked bool
+	}{
+		{name: "disabled", cfg: &config.Config{}},
+		{
+			name: "enabled",
+			cfg: &config.Config{
+				LockoutMaxFailures: 2,
+				LockoutBaseDelay:   time.Minute,
+				LockoutMaxDelay:    time.Hour,
+				LockoutResetAfter:  time.Hour,
+			},
+			wantLocked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			lockout := newLockout(tt.cfg, auth.AuthMethodBasic)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			// Act
+			lockout.Failure(req, "admin")
+			lockout.Failure(req, "admin")
+			err := lockout.Check(req, "admin")
+
+			// Assert
+			if (lockout == nil) == tt.wantLocked {
+				t.Errorf("newLockout() = %v, want nil: %v", lockout, !tt.wantLocked)
+			}
+			if locked := errors.Is(err, auth.ErrLockedOut); locked != tt.wantLocked {
+				t.Errorf("Check() error = %v, want locked out: %v", err, tt.wantLocked)
+			}
+		})
+	}
+}
+
 func TestCreateAuthenticator_APIKey(t *testing.T) {
 	// Arrange
 	cfg := &config.Config{
@@ -141,6 +301,149 @@ func TestCreateAuthenticator_APIKey(t *testing.T) {
 	}
 }
 
+func TestCreateAuthenticator_APIKeyFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "missing file is created on first change"},
+		{name: "existing file", content: "[]"},
+		{name: "corrupt file", content: "{", wantErr: "opening API key store"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			path := filepath.Join(t.TempDir(), "api-keys.json")
+			if tt.content != "" {
+				if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+					t.Fatalf("writing API key file: %v", err)
+				}
+			}
+			cfg := &config.Config{
+				AuthMode:   "apikey",
+				APIKeyFile: path,
+			}
+
+			// Act
+			authenticator, err := createAuthenticator(cfg, zap.NewNop())
+
+			// Assert
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("createAuthenticator() error = %v, want to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("createAuthenticator() error = %v", err)
+			}
+			if _, ok := findAuthenticator[*auth.APIKeyAuthenticator](authenticator); !ok {
+				t.Error("findAuthenticator() found no API key authenticator")
+			}
+		})
+	}
+}
+
+func TestFindAuthenticator(t *testing.T) {
+	// Arrange
+	keys, err := auth.NewAPIKeyAuthenticator("secret-key:service-a")
+	if err != nil {
+		t.Fatalf("NewAPIKeyAuthenticator() error = %v", err)
+	}
+	basic, err := auth.NewBasicAuthenticator("admin:secret")
+	if err != nil {
+		t.Fatalf("NewBasicAuthenticator() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		authenticator auth.Authenticator
+		want          *auth.APIKeyAuthenticator
+	}{
+		{name: "none", authenticator: nil},
+		{name: "API key", authenticator: keys, want: keys},
+		{name: "other method", authenticator: basic},
+		{name: "multi with API key", authenticator: auth.NewMultiAuthenticator(basic, keys), want: keys},
+		{name: "multi without API key", authenticator: auth.NewMultiAuthenticator(basic)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			got, ok := findAuthenticator[*auth.APIKeyAuthenticator](tt.authenticator)
+
+			// Assert
+			if got != tt.want || ok != (tt.want != nil) {
+				t.Errorf("findAuthenticator() = %p, %v; want %p", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateAuthenticator_Introspection(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		AuthMode:                  "introspection",
+		IntrospectionURL:          "http://idp.example.com/introspect",
+		IntrospectionClientID:     "restapi",
+		IntrospectionClientSecret: "secret",
+		IntrospectionCacheTTL:     time.Minute,
+	}
+	logger := zap.NewNop()
+
+	// Act
+	authenticator, err := createAuthenticator(cfg, logger)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("createAuthenticator() error = %v", err)
+	}
+	if authenticator == nil || authenticator.Method() != auth.AuthMethodIntrospection {
+		t.Errorf("createAuthenticator() = %v, want introspection authenticator", authenticator)
+	}
+}
+
+func TestCreateAuthenticator_IntrospectionMissingClientID(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		AuthMode:         "introspection",
+		IntrospectionURL: "http://idp.example.com/introspect",
+	}
+	logger := zap.NewNop()
+
+	// Act
+	_, err := createAuthenticator(cfg, logger)
+
+	// Assert
+	if err == nil {
+		t.Error("createAuthenticator() expected error for missing introspection client ID")
+	}
+}
+
+func TestCreateMultiAuthenticator_WithIntrospection(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		AuthMode:              "multi",
+		APIKeys:               "secret-key:service-a",
+		IntrospectionURL:      "http://idp.example.com/introspect",
+		IntrospectionClientID: "restapi",
+	}
+	logger := zap.NewNop()
+
+	// Act
+	authenticator, err := createMultiAuthenticator(cfg, logger)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("createMultiAuthenticator() error = %v", err)
+	}
+	if authenticator == nil {
+		t.Error("createMultiAuthenticator() should return non-nil")
+	}
+}
+
 func TestCreateAuthenticator_OIDC(t *testing.T) {
 	// Arrange
 	cfg := &config.Config{
@@ -159,6 +462,100 @@ func TestCreateAuthenticator_OIDC(t *testing.T) {
 	}
 }
 
+// oidcDiscoveryServer starts an OIDC provider stub that serves a discovery
+// document and an empty JWKS.
+func oidcDiscoveryServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/jwks" {
+			_ = json.NewEncoder(w).Encode(map[string]any{"keys": []any{}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   server.URL,
+			"jwks_uri": server.URL + "/jwks",
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestCreateAuthenticator_OIDCMultipleIssuers(t *testing.T) {
+	// Arrange
+	corp := oidcDiscoveryServer(t)
+	partner := oidcDiscoveryServer(t)
+	cfg := &config.Config{
+		AuthMode:      "oidc",
+		OIDCIssuerURL: corp.URL,
+		OIDCClientID:  "client-id",
+		OIDCIssuers: []config.OIDCIssuer{
+			{URL: partner.URL, Audience: "partner-api", Algorithms: "ES256", ClockSkew: time.Minute},
+		},
+	}
+	logger := zap.NewNop()
+
+	// Act
+	authenticator, err := createAuthenticator(cfg, logger)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("createAuthenticator() error = %v", err)
+	}
+	if _, ok := authenticator.(*auth.OIDCAuthenticator); !ok {
+		t.Errorf("createAuthenticator() = %T, want *auth.OIDCAuthenticator", authenticator)
+	}
+}
+
+func TestCreateAuthenticator_OIDCUnreachableIssuer(t *testing.T) {
+	// Arrange: the second issuer cannot be discovered.
+	cfg := &config.Config{
+		AuthMode:      "oidc",
+		OIDCIssuerURL: oidcDiscoveryServer(t).URL,
+		OIDCClientID:  "client-id",
+		OIDCIssuers:   []config.OIDCIssuer{{URL: "http://127.0.0.1:1"}},
+	}
+	logger := zap.NewNop()
+
+	// Act
+	_, err := createAuthenticator(cfg, logger)
+
+	// Assert
+	if err == nil {
+		t.Error("createAuthenticator() expected error for an unreachable issuer")
+	}
+}
+
+func TestParseRequiredClaims(t *testing.T) {
+	tests := []struct {
+		name string
+		list string
+		want map[string]string
+	}{
+		{name: "empty", list: "", want: map[string]string{}},
+		{
+			name: "names and values",
+			list: "email_verified = true, groups ,tenant=acme",
+			want: map[string]string{"email_verified": "true", "groups": "", "tenant": "acme"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			got := parseRequiredClaims(tt.list)
+
+			// Assert
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("parseRequiredClaims(%q) = %v, want %v", tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestCreateAuthenticator_UnknownMode(t *testing.T) {
 	// Arrange
 	cfg := &config.Config{
@@ -328,3 +725,78 @@ func TestCreateAuthenticator_Multi(t *testing.T) {
 		t.Error("createAuthenticator() should return non-nil for 'multi' mode")
 	}
 }
+
+func TestCreateStore_Memory(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{StoreDriver: "memory"}
+	logger := zap.NewNop()
+
+	// Act
+	s, closeFn, err := createStore(context.Background(), cfg, logger)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("createStore() error = %v", err)
+	}
+	if _, ok := s.(*store.MemoryStore); !ok {
+		t.Errorf("createStore() returned %T, want *store.MemoryStore", s)
+	}
+	if err := closeFn(); err != nil {
+		t.Errorf("close error = %v", err)
+	}
+}
+
+func TestCreateStore_PostgresUnreachable(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		StoreDriver:     "postgres",
+		StoreDSN:        "postgres://127.0.0.1:1/none?connect_timeout=1",
+		ShutdownTimeout: 2 * time.Second,
+	}
+	logger := zap.NewNop()
+
+	// Act
+	_, _, err := createStore(context.Background(), cfg, logger)
+
+	// Assert
+	if err == nil {
+		t.Fatal("createStore() expected error for unreachable postgres, got nil")
+	}
+}
+
+func TestCreateStore_File(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		StoreDriver: "file",
+		StorePath:   filepath.Join(t.TempDir(), "items.db"),
+	}
+	logger := zap.NewNop()
+
+	// Act
+	s, closeFn, err := createStore(context.Background(), cfg, logger)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("createStore() error = %v", err)
+	}
+	if _, ok := s.(*store.FileStore); !ok {
+		t.Errorf("createStore() returned %T, want *store.FileStore", s)
+	}
+	if err := closeFn(); err != nil {
+		t.Errorf("close error = %v", err)
+	}
+}
+
+func TestCreateStore_UnknownDriver(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{StoreDriver: "cassandra"}
+	logger := zap.NewNop()
+
+	// Act
+	_, _, err := createStore(context.Background(), cfg, logger)
+
+	// Assert
+	if err == nil {
+		t.Fatal("createStore() expected error for unknown driver, got nil")
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/config"
+	"github.com/vyrodovalexey/restapi-example/internal/server"
+)
+
+// reloadableFields are the config fields a reload applies to the running
+// server, besides the CORS settings. Changes to other fields take effect on
+// restart.
+var reloadableFields = []string{"LogLevel", "APIKeys", "BasicAuthUsers"}
+
+// reloader reloads the configuration on SIGHUP and applies the settings
+// that can change safely at runtime: the log level, the CORS policy, the
+// static API keys and the Basic auth users from the config.
+type reloader struct {
+	path    string         // config file, if any
+	current *config.Config // settings in effect
+	level   zap.AtomicLevel
+	srv     *server.Server
+	basic   *auth.BasicAuthenticator  // nil without Basic auth
+	apiKeys *auth.APIKeyAuthenticator // nil without API key auth
+	logger  *zap.Logger
+}
+
+// reload loads the configuration again and applies its reloadable
+// settings. Every setting is parsed before any is applied, so if the
+// configuration is invalid nothing is applied; changes that require a
+// restart are logged.
+func (r *reloader) reload() error {
+	cfg, err := config.LoadFile(r.path)
+	if err != nil {
+		return err
+	}
+
+	var restart []string
+	corsChanged := false
+	for _, field := range r.current.Diff(cfg) {
+		switch {
+		case strings.HasPrefix(field, "CORS"):
+			corsChanged = true
+		case !slices.Contains(reloadableFields, field):
+			restart = append(restart, field)
+		}
+	}
+
+	var (
+		applied []string
+		changes []func()
+	)
+	if cfg.BasicAuthUsers != r.current.BasicAuthUsers {
+		if r.basic == nil || r.current.BasicAuthFile != "" {
+			restart = append(restart, "BasicAuthUsers")
+		} else {
+			setUsers, err := r.basic.PrepareUsers(cfg.BasicAuthUsers)
+			if err != nil {
+				return fmt.Errorf("reloading basic auth users: %w", err)
+			}
+			changes = append(changes, func() {
+				setUsers()
+				r.current.BasicAuthUsers = cfg.BasicAuthUsers
+			})
+			applied = append(applied, "BasicAuthUsers")
+		}
+	}
+
+	if cfg.APIKeys != r.current.APIKeys {
+		if r.apiKeys == nil {
+			restart = append(restart, "APIKeys")
+		} else {
+			setKeys, err := r.apiKeys.PrepareStaticKeys(cfg.APIKeys)
+			if err != nil {
+				return fmt.Errorf("reloading API keys: %w", err)
+			}
+			changes = append(changes, func() {
+				setKeys()
+				r.current.APIKeys = cfg.APIKeys
+			})
+			applied = append(applied, "APIKeys")
+		}
+	}
+
+	if corsChanged {
+		setPolicy, err := r.srv.PrepareCORSPolicy(cfg)
+		if err != nil {
+			return fmt.Errorf("reloading CORS policy: %w", err)
+		}
+		changes = append(changes, func() {
+			setPolicy()
+			r.current.CORSAllowedOrigins = cfg.CORSAllowedOrigins
+			r.current.CORSOriginRegex = cfg.CORSOriginRegex
+			r.current.CORSAllowedMethods = cfg.CORSAllowedMethods
+			r.current.CORSAllowedHeaders = cfg.CORSAllowedHeaders
+			r.current.CORSExposedHeaders = cfg.CORSExposedHeaders
+			r.current.CORSAllowCredentials = cfg.CORSAllowCredentials
+			r.current.CORSMaxAge = cfg.CORSMaxAge
+		})
+		applied = append(applied, "CORS")
+	}
+
+	if cfg.LogLevel != r.current.LogLevel {
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+			return fmt.Errorf("reloading log level: %w", err)
+		}
+		changes = append(changes, func() {
+			r.level.SetLevel(level)
+			r.current.LogLevel = cfg.LogLevel
+		})
+		applied = append(applied, "LogLevel")
+	}
+
+	for _, change := range changes {
+		change()
+	}
+
+	if len(restart) > 0 {
+		r.logger.Warn("configuration changes require a restart", zap.Strings("fields", restart))
+	}
+	r.logger.Info("configuration reloaded", zap.Strings("applied", applied))
+	return nil
+}
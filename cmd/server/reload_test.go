@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/config"
+	"github.com/vyrodovalexey/restapi-example/internal/server"
+	"github.com/vyrodovalexey/restapi-example/internal/store"
+)
+
+// reloadConfig is a config file template with placeholders for the
+// reloadable settings and the server port.
+const reloadConfig = `
+server:
+  port: %d
+log:
+  level: %s
+cors:
+  allowed_origins: %s
+auth:
+  mode: multi
+basic_auth:
+  users: "%s"
+api_keys:
+  keys: %s
+`
+
+// writeReloadConfig writes the config file at path.
+func writeReloadConfig(t *testing.T, path string, port int, level, origins, users, keys string) {
+	t.Helper()
+
+	content := []byte(fmt.Sprintf(reloadConfig, port, level, origins, users, keys))
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatalf("writing config file: %v", err)
+	}
+}
+
+// newTestReloader loads the config file at path and returns a reloader for
+// a server and authenticators created from it, and its observed logs.
+func newTestReloader(t *testing.T, path string) (*reloader, *observer.ObservedLogs) {
+	t.Helper()
+
+	cfg, err := config.LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	authenticator, err := createAuthenticator(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("createAuthenticator() error = %v", err)
+	}
+	core, logs := observer.New(zapcore.InfoLevel)
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+	current := *cfg
+	r := &reloader{
+		path:    path,
+		current: &current,
+		level:   level,
+		srv:     server.New(cfg, zap.NewNop(), store.NewMemoryStore(), authenticator),
+		logger:  zap.New(core),
+	}
+	r.basic, _ = findAuthenticator[*auth.BasicAuthenticator](authenticator)
+	r.apiKeys, _ = findAuthenticator[*auth.APIKeyAuthenticator](authenticator)
+	return r, logs
+}
+
+// bcryptHash returns a bcrypt hash of password.
+func bcryptHash(t *testing.T, password string) string {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("generating bcrypt hash: %v", err)
+	}
+	return string(hash)
+}
+
+func TestReloader_Reload(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeReloadConfig(t, path, 8080, "info", "https://app.example.com",
+		"alice:"+bcryptHash(t, "alice-password"), "old-key:ci")
+	r, logs := newTestReloader(t, path)
+	writeReloadConfig(t, path, 8080, "debug", "https://admin.example.com",
+		"bob:"+bcryptHash(t, "bob-password"), "new-key:ci")
+
+	// Act
+	err := r.reload()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("reload() error = %v", err)
+	}
+	if r.level.Level() != zapcore.DebugLevel {
+		t.Errorf("log level = %v, want debug", r.level.Level())
+	}
+	if got := corsOrigin(r.srv, "https://admin.example.com"); got != "https://admin.example.com" {
+		t.Errorf("Access-Control-Allow-Origin for the new origin = %q, want it allowed", got)
+	}
+	if got := corsOrigin(r.srv, "https://app.example.com"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin for the old origin = %q, want none", got)
+	}
+	if _, err := r.basic.Authenticate(basicAuthRequest("alice", "alice-password")); err == nil {
+		t.Error("Authenticate() of a removed Basic user succeeded")
+	}
+	if _, err := r.basic.Authenticate(basicAuthRequest("bob", "bob-password")); err != nil {
+		t.Errorf("Authenticate() of an added Basic user error = %v", err)
+	}
+	if _, err := r.apiKeys.Authenticate(apiKeyRequest("old-key")); err == nil {
+		t.Error("Authenticate() with a removed API key succeeded")
+	}
+	if _, err := r.apiKeys.Authenticate(apiKeyRequest("new-key")); err != nil {
+		t.Errorf("Authenticate() with an added API key error = %v", err)
+	}
+	if logs.FilterMessage("configuration changes require a restart").Len() != 0 {
+		t.Error("restart warning logged for reloadable changes only")
+	}
+}
+
+func TestReloader_Reload_RestartRequired(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	users := "alice:" + bcryptHash(t, "alice-password")
+	writeReloadConfig(t, path, 8080, "info", "'*'", users, "key:ci")
+	r, logs := newTestReloader(t, path)
+	writeReloadConfig(t, path, 8081, "info", "'*'", users, "key:ci")
+
+	// Act
+	err := r.reload()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("reload() error = %v", err)
+	}
+	warnings := logs.FilterMessage("configuration changes require a restart").All()
+	if len(warnings) != 1 {
+		t.Fatalf("restart warnings = %d, want 1", len(warnings))
+	}
+	fields, ok := warnings[0].ContextMap()["fields"].([]any)
+	if !ok || len(fields) != 1 || fields[0] != "ServerPort" {
+		t.Errorf("restart warning fields = %v, want [ServerPort]", warnings[0].ContextMap()["fields"])
+	}
+	if r.current.ServerPort != 8080 {
+		t.Errorf("current ServerPort = %d, want 8080 until restart", r.current.ServerPort)
+	}
+}
+
+func TestReloader_Reload_InvalidConfig(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	users := "alice:" + bcryptHash(t, "alice-password")
+	writeReloadConfig(t, path, 8080, "info", "'*'", users, "key:ci")
+	r, _ := newTestReloader(t, path)
+	writeReloadConfig(t, path, 8080, "verbose", "'*'", users, "key:ci")
+
+	// Act
+	err := r.reload()
+
+	// Assert
+	if err == nil {
+		t.Fatal("reload() error = nil, want error for an invalid log level")
+	}
+	if r.level.Level() != zapcore.InfoLevel || r.current.LogLevel != "info" {
+		t.Errorf("log level = %v (%s), want info kept", r.level.Level(), r.current.LogLevel)
+	}
+}
+
+func TestReloader_Reload_AppliesNothingOnError(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeReloadConfig(t, path, 8080, "info", "https://app.example.com",
+		"alice:"+bcryptHash(t, "alice-password"), "old-key:ci")
+	r, _ := newTestReloader(t, path)
+	// The users, CORS origins and log level are valid, but the API keys,
+	// which the config does not validate, are malformed.
+	writeReloadConfig(t, path, 8080, "debug", "https://admin.example.com",
+		"bob:"+bcryptHash(t, "bob-password"), "no-name")
+
+	// Act
+	err := r.reload()
+
+	// Assert
+	if err == nil {
+		t.Fatal("reload() error = nil, want error for malformed API keys")
+	}
+	if _, err := r.basic.Authenticate(basicAuthRequest("alice", "alice-password")); err != nil {
+		t.Errorf("Authenticate() of the current Basic user error = %v, want it kept", err)
+	}
+	if _, err := r.apiKeys.Authenticate(apiKeyRequest("old-key")); err != nil {
+		t.Errorf("Authenticate() with the current API key error = %v, want it kept", err)
+	}
+	if got := corsOrigin(r.srv, "https://app.example.com"); got != "https://app.example.com" {
+		t.Errorf("Access-Control-Allow-Origin for the current origin = %q, want it kept", got)
+	}
+	if r.level.Level() != zapcore.InfoLevel {
+		t.Errorf("log level = %v, want info kept", r.level.Level())
+	}
+	if r.current.APIKeys != "old-key:ci" || r.current.LogLevel != "info" ||
+		r.current.CORSAllowedOrigins != "https://app.example.com" {
+		t.Errorf("current config = %+v, want it unchanged", r.current)
+	}
+}
+
+// corsOrigin returns the Access-Control-Allow-Origin header srv answers a
+// request from origin with.
+func corsOrigin(srv *server.Server, origin string) string {
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("Origin", origin)
+	rec := httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, req)
+	return rec.Header().Get("Access-Control-Allow-Origin")
+}
+
+// basicAuthRequest returns a request with Basic credentials.
+func basicAuthRequest(username, password string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth(username, password)
+	return req
+}
+
+// apiKeyRequest returns a request with an API key.
+func apiKeyRequest(key string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(auth.APIKeyHeader, key)
+	return req
+}
@@ -94,6 +94,17 @@ Return the TLS secret name
 {{- end }}
 {{- end }}
 
+{{/*
+Return the PersistentVolumeClaim name for the file store
+*/}}
+{{- define "restapi-example.pvcName" -}}
+{{- if .Values.persistence.existingClaim }}
+{{- .Values.persistence.existingClaim }}
+{{- else }}
+{{- printf "%s-data" (include "restapi-example.fullname" .) }}
+{{- end }}
+{{- end }}
+
 {{/*
 Return the Vault secret name
 */}}
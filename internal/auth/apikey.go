@@ -1,39 +1,131 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // APIKeyHeader is the HTTP header name for API key authentication.
 const APIKeyHeader = "X-API-Key" //nolint:gosec // header name, not a credential
 
+const (
+	// apiKeyIDSize and apiKeySecretSize are the random bytes in the ID and
+	// secret of generated keys, which have the form "<id>.<secret>".
+	apiKeyIDSize     = 12
+	apiKeySecretSize = 32
+	// apiKeySaltSize is the size of the salt of key hashes.
+	apiKeySaltSize = 16
+	// lastUsedResolution limits how often the last use of a key is saved.
+	lastUsedResolution = time.Minute
+)
+
+// ErrInvalidAPIKeyParams is returned for invalid names, scopes or lifetimes
+// of managed API keys.
+var ErrInvalidAPIKeyParams = errors.New("invalid API key parameters")
+
+// errAPIKeyStore marks failures to look up a managed key, which are not
+// counted as failed attempts.
+var errAPIKeyStore = errors.New("looking up API key")
+
 // APIKeyAuthenticator authenticates requests using API keys provided
-// in the X-API-Key header with constant-time comparison.
+// in the X-API-Key header. Keys are kept as salted SHA-256 hashes: static
+// keys from the configuration in memory, and managed keys, which may expire
+// and carry scopes, in an APIKeyStore.
 type APIKeyAuthenticator struct {
-	keys map[string]string // key value -> key name
+	static atomic.Pointer[[]staticAPIKey]
+	store  APIKeyStore
+
+	// lockout locks out managed keys and clients after failed attempts.
+	lockout *Lockout
+
+	// mu serializes changes to managed keys.
+	mu sync.Mutex
+}
+
+// staticAPIKey is a key from the configuration.
+type staticAPIKey struct {
+	name string
+	salt []byte
+	hash []byte
 }
 
 // NewAPIKeyAuthenticator creates a new API key authenticator from a
 // configuration string in the format "key1:name1,key2:name2".
 // Each entry must contain exactly one colon separating the key value
-// from the key name.
+// from the key name. Managed keys are kept in memory.
 func NewAPIKeyAuthenticator(
 	keysConfig string,
 ) (*APIKeyAuthenticator, error) {
-	trimmed := strings.TrimSpace(keysConfig)
-	if trimmed == "" {
+	if strings.TrimSpace(keysConfig) == "" {
 		return nil, fmt.Errorf(
 			"apikey auth: keys config must not be empty",
 		)
 	}
 
-	keys := make(map[string]string)
-	entries := strings.Split(trimmed, ",")
+	return NewAPIKeyStoreAuthenticator(keysConfig, NewMemoryAPIKeyStore())
+}
+
+// NewAPIKeyStoreAuthenticator creates an API key authenticator for the
+// managed keys in store and the static keys in keysConfig, which has the
+// format of NewAPIKeyAuthenticator and may be empty.
+func NewAPIKeyStoreAuthenticator(
+	keysConfig string,
+	store APIKeyStore,
+) (*APIKeyAuthenticator, error) {
+	a := &APIKeyAuthenticator{store: store}
+	if err := a.SetStaticKeys(keysConfig); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// SetStaticKeys replaces the static keys with those in keysConfig, which
+// has the format of NewAPIKeyAuthenticator and may be empty. On error the
+// current keys are kept.
+func (a *APIKeyAuthenticator) SetStaticKeys(keysConfig string) error {
+	apply, err := a.PrepareStaticKeys(keysConfig)
+	if err != nil {
+		return err
+	}
+
+	apply()
+	return nil
+}
+
+// PrepareStaticKeys parses keysConfig like SetStaticKeys and returns a
+// function that replaces the static keys with them, so that callers can
+// check several settings before applying any.
+func (a *APIKeyAuthenticator) PrepareStaticKeys(keysConfig string) (func(), error) {
+	static, err := parseStaticAPIKeys(keysConfig)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, entry := range entries {
+	return func() { a.static.Store(&static) }, nil
+}
+
+// parseStaticAPIKeys parses and hashes the keys in keysConfig.
+func parseStaticAPIKeys(keysConfig string) ([]staticAPIKey, error) {
+	trimmed := strings.TrimSpace(keysConfig)
+	if trimmed == "" {
+		return nil, nil
+	}
+
+	var static []staticAPIKey
+	for _, entry := range strings.Split(trimmed, ",") {
 		entry = strings.TrimSpace(entry)
 		if entry == "" {
 			continue
@@ -55,22 +147,36 @@ func NewAPIKeyAuthenticator(
 			)
 		}
 
-		keys[key] = name
+		salt, hash, err := hashAPIKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("apikey auth: %w", err)
+		}
+		static = append(static, staticAPIKey{name: name, salt: salt, hash: hash})
 	}
 
-	if len(keys) == 0 {
+	if len(static) == 0 {
 		return nil, fmt.Errorf(
 			"apikey auth: no valid key entries found",
 		)
 	}
 
-	return &APIKeyAuthenticator{keys: keys}, nil
+	return static, nil
+}
+
+// SetLockout locks out managed key IDs and clients after failed attempts;
+// nil disables lockouts. Static keys have no ID, so failures to match them
+// only count for the client.
+func (a *APIKeyAuthenticator) SetLockout(lockout *Lockout) {
+	a.lockout = lockout
 }
 
 // Authenticate extracts the API key from the X-API-Key header and
-// validates it against the configured keys using constant-time
-// comparison. All keys are always compared to prevent timing-based
-// information leakage about which keys exist.
+// validates it against the managed and static keys. Managed keys are looked
+// up by the ID part of the key; static keys are all compared to prevent
+// timing-based information leakage about which keys exist. Expired and
+// revoked keys return an error wrapping ErrInvalidAPIKey. While the key ID
+// or the client is locked out, a LockoutError is returned without checking
+// the key.
 func (a *APIKeyAuthenticator) Authenticate(
 	r *http.Request,
 ) (*AuthInfo, error) {
@@ -79,19 +185,49 @@ func (a *APIKeyAuthenticator) Authenticate(
 		return nil, ErrUnauthenticated
 	}
 
+	id, secret, managed := strings.Cut(apiKey, ".")
+	if !managed {
+		id = ""
+	}
+	if err := a.lockout.Check(r, id); err != nil {
+		return nil, err
+	}
+
+	info, err := a.authenticate(r.Context(), apiKey, id, secret)
+	switch {
+	case err == nil:
+		a.lockout.Success(id)
+	case errors.Is(err, ErrInvalidAPIKey) && !errors.Is(err, errAPIKeyStore):
+		a.lockout.Failure(r, id)
+	}
+
+	return info, err
+}
+
+// authenticate validates apiKey; id and secret are its parts if it has the
+// form of a managed key, and empty otherwise.
+func (a *APIKeyAuthenticator) authenticate(
+	ctx context.Context,
+	apiKey, id, secret string,
+) (*AuthInfo, error) {
+	if id != "" {
+		key, err := a.store.Get(ctx, id)
+		if err == nil && verifyAPIKey(secret, key.Salt, key.Hash) {
+			return a.managedKeyInfo(ctx, key)
+		}
+		if err != nil && !errors.Is(err, ErrAPIKeyNotFound) {
+			return nil, fmt.Errorf("%w: %w: %w", ErrInvalidAPIKey, errAPIKeyStore, err)
+		}
+	}
+
 	var matchedName string
-	found := false
-
-	for key, name := range a.keys {
-		if subtle.ConstantTimeCompare(
-			[]byte(apiKey), []byte(key),
-		) == 1 {
-			matchedName = name
-			found = true
+	for _, key := range *a.static.Load() {
+		if verifyAPIKey(apiKey, key.salt, key.hash) {
+			matchedName = key.name
 		}
 	}
 
-	if !found {
+	if matchedName == "" {
 		return nil, ErrInvalidAPIKey
 	}
 
@@ -105,3 +241,222 @@ func (a *APIKeyAuthenticator) Authenticate(
 func (a *APIKeyAuthenticator) Method() AuthMethod {
 	return AuthMethodAPIKey
 }
+
+// managedKeyInfo checks that a managed key is usable, records its use and
+// returns its identity. The key ID and scopes are exposed as the key_id and
+// scopes claims.
+func (a *APIKeyAuthenticator) managedKeyInfo(ctx context.Context, key *APIKey) (*AuthInfo, error) {
+	now := time.Now()
+
+	if key.Revoked() {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAPIKey, ErrAPIKeyRevoked)
+	}
+	if key.Expired(now) {
+		return nil, fmt.Errorf("%w: key expired at %v", ErrInvalidAPIKey, key.ExpiresAt)
+	}
+
+	// The last use is only saved once per lastUsedResolution, and failing
+	// to save it does not fail authentication.
+	if now.Sub(key.LastUsedAt) >= lastUsedResolution {
+		_ = a.store.Touch(ctx, key.ID, now)
+	}
+
+	claims := map[string]any{"key_id": key.ID}
+	if len(key.Scopes) > 0 {
+		claims["scopes"] = key.Scopes
+	}
+
+	return &AuthInfo{
+		Method:  AuthMethodAPIKey,
+		Subject: key.Name,
+		Claims:  claims,
+		Expiry:  key.ExpiresAt,
+	}, nil
+}
+
+// CreateKey creates a managed key with the given name and scopes that
+// expires after ttl, or never if ttl is zero. It returns the key, which is
+// not stored and cannot be retrieved later, and its metadata.
+func (a *APIKeyAuthenticator) CreateKey(
+	ctx context.Context,
+	name string,
+	scopes []string,
+	ttl time.Duration,
+) (string, *APIKey, error) {
+	if err := validateAPIKeyParams(name, scopes, ttl); err != nil {
+		return "", nil, err
+	}
+
+	id, err := randomString(apiKeyIDSize, hex.EncodeToString)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	key := &APIKey{
+		ID:        id,
+		Name:      name,
+		Scopes:    slices.Clone(scopes),
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		key.ExpiresAt = now.Add(ttl)
+	}
+
+	secret, err := a.issue(ctx, key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return secret, key, nil
+}
+
+// ListKeys returns the metadata of all managed keys, including revoked
+// ones.
+func (a *APIKeyAuthenticator) ListKeys(ctx context.Context) ([]*APIKey, error) {
+	keys, err := a.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RotateKey replaces the secret of a managed key; the previous key stops
+// working immediately. The key then expires after ttl or, if ttl is zero,
+// after the lifetime it was created or last rotated with. It returns the new
+// key and its metadata.
+func (a *APIKeyAuthenticator) RotateKey(
+	ctx context.Context,
+	id string,
+	ttl time.Duration,
+) (string, *APIKey, error) {
+	if ttl < 0 {
+		return "", nil, fmt.Errorf("%w: lifetime must not be negative", ErrInvalidAPIKeyParams)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key, err := a.store.Get(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+	if key.Revoked() {
+		return "", nil, ErrAPIKeyRevoked
+	}
+
+	now := time.Now()
+	if ttl == 0 && !key.ExpiresAt.IsZero() {
+		issued := key.CreatedAt
+		if !key.RotatedAt.IsZero() {
+			issued = key.RotatedAt
+		}
+		ttl = key.ExpiresAt.Sub(issued)
+	}
+	if ttl > 0 {
+		key.ExpiresAt = now.Add(ttl)
+	}
+	key.RotatedAt = now
+
+	secret, err := a.issue(ctx, key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return secret, key, nil
+}
+
+// RevokeKey revokes a managed key. Revoked keys are kept for auditing but
+// no longer authenticate and cannot be rotated.
+func (a *APIKeyAuthenticator) RevokeKey(ctx context.Context, id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key, err := a.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if key.Revoked() {
+		return nil
+	}
+
+	key.RevokedAt = time.Now()
+	if err := a.store.Put(ctx, key); err != nil {
+		return fmt.Errorf("saving API key: %w", err)
+	}
+	return nil
+}
+
+// issue generates a new secret for key, stores the key with the hash of the
+// secret and returns the full key.
+func (a *APIKeyAuthenticator) issue(ctx context.Context, key *APIKey) (string, error) {
+	secret, err := randomString(apiKeySecretSize, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", err
+	}
+
+	key.Salt, key.Hash, err = hashAPIKey(secret)
+	if err != nil {
+		return "", err
+	}
+
+	if err := a.store.Put(ctx, key); err != nil {
+		return "", fmt.Errorf("saving API key: %w", err)
+	}
+
+	return key.ID + "." + secret, nil
+}
+
+// validateAPIKeyParams checks the parameters of a new managed key.
+func validateAPIKeyParams(name string, scopes []string, ttl time.Duration) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%w: name must not be empty", ErrInvalidAPIKeyParams)
+	}
+	for _, scope := range scopes {
+		if scope == "" || strings.ContainsFunc(scope, isSpaceOrComma) {
+			return fmt.Errorf("%w: invalid scope %q", ErrInvalidAPIKeyParams, scope)
+		}
+	}
+	if ttl < 0 {
+		return fmt.Errorf("%w: lifetime must not be negative", ErrInvalidAPIKeyParams)
+	}
+	return nil
+}
+
+// isSpaceOrComma reports whether r separates scopes in lists.
+func isSpaceOrComma(r rune) bool {
+	return r == ',' || r == ' ' || r == '\t' || r == '\n'
+}
+
+// hashAPIKey returns a random salt and the salted SHA-256 hash of key.
+// Keys are long random strings, so a fast hash is sufficient.
+func hashAPIKey(key string) (salt, hash []byte, err error) {
+	salt = make([]byte, apiKeySaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, fmt.Errorf("generating salt: %w", err)
+	}
+	return salt, saltedHash(key, salt), nil
+}
+
+// verifyAPIKey reports in constant time whether key has the given salted
+// hash.
+func verifyAPIKey(key string, salt, hash []byte) bool {
+	return subtle.ConstantTimeCompare(saltedHash(key, salt), hash) == 1
+}
+
+// saltedHash returns SHA-256(salt || key).
+func saltedHash(key string, salt []byte) []byte {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(key))
+	return h.Sum(nil)
+}
+
+// randomString returns size random bytes encoded with encode.
+func randomString(size int, encode func([]byte) string) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating API key: %w", err)
+	}
+	return encode(b), nil
+}
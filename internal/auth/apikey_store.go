@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API key store errors.
+var (
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrAPIKeyRevoked  = errors.New("API key has been revoked")
+)
+
+// APIKey is a managed API key. Only a salted hash of the secret is kept; the
+// key itself is shown once when it is created or rotated.
+type APIKey struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes,omitempty"`
+
+	Salt []byte `json:"salt"`
+	Hash []byte `json:"hash"`
+
+	CreatedAt  time.Time `json:"created_at"`
+	RotatedAt  time.Time `json:"rotated_at,omitzero"`
+	ExpiresAt  time.Time `json:"expires_at,omitzero"` // Zero if the key does not expire.
+	LastUsedAt time.Time `json:"last_used_at,omitzero"`
+	RevokedAt  time.Time `json:"revoked_at,omitzero"`
+}
+
+// Expired reports whether the key has expired at now.
+func (k *APIKey) Expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt)
+}
+
+// Revoked reports whether the key has been revoked.
+func (k *APIKey) Revoked() bool {
+	return !k.RevokedAt.IsZero()
+}
+
+// clone returns a deep copy of the key.
+func (k *APIKey) clone() *APIKey {
+	c := *k
+	c.Scopes = slices.Clone(k.Scopes)
+	c.Salt = slices.Clone(k.Salt)
+	c.Hash = slices.Clone(k.Hash)
+	return &c
+}
+
+// APIKeyStore persists managed API keys. Implementations must be safe for
+// concurrent use and return copies of the stored keys.
+type APIKeyStore interface {
+	// Get returns the key with the given ID or ErrAPIKeyNotFound.
+	Get(ctx context.Context, id string) (*APIKey, error)
+	// List returns all keys, including revoked ones, ordered by ID.
+	List(ctx context.Context) ([]*APIKey, error)
+	// Put creates or replaces a key.
+	Put(ctx context.Context, key *APIKey) error
+	// Touch records that the key with the given ID was used at the given
+	// time. It returns ErrAPIKeyNotFound if there is no such key.
+	Touch(ctx context.Context, id string, at time.Time) error
+}
+
+// MemoryAPIKeyStore is an in-memory APIKeyStore. Keys are lost on restart.
+type MemoryAPIKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]*APIKey
+}
+
+// NewMemoryAPIKeyStore creates an empty in-memory API key store.
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{keys: make(map[string]*APIKey)}
+}
+
+// Get returns the key with the given ID.
+func (s *MemoryAPIKeyStore) Get(_ context.Context, id string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key.clone(), nil
+}
+
+// List returns all keys ordered by ID.
+func (s *MemoryAPIKeyStore) List(_ context.Context) ([]*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]*APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key.clone())
+	}
+	slices.SortFunc(keys, func(a, b *APIKey) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return keys, nil
+}
+
+// Put creates or replaces a key.
+func (s *MemoryAPIKeyStore) Put(_ context.Context, key *APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key.ID] = key.clone()
+	return nil
+}
+
+// Touch records the last use of a key.
+func (s *MemoryAPIKeyStore) Touch(_ context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+	key.LastUsedAt = at
+	return nil
+}
+
+// FileAPIKeyStore is an APIKeyStore backed by a JSON file. The file is
+// rewritten atomically on every change and is only readable by its owner.
+type FileAPIKeyStore struct {
+	path string
+
+	mu     sync.Mutex
+	memory *MemoryAPIKeyStore
+}
+
+// OpenFileAPIKeyStore opens the API key store at path, creating it on the
+// first change if it does not exist.
+func OpenFileAPIKeyStore(path string) (*FileAPIKeyStore, error) {
+	s := &FileAPIKeyStore{path: path, memory: NewMemoryAPIKeyStore()}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading API key file: %w", err)
+	}
+
+	var keys []*APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("parsing API key file %s: %w", path, err)
+	}
+	for _, key := range keys {
+		s.memory.keys[key.ID] = key
+	}
+
+	return s, nil
+}
+
+// Get returns the key with the given ID.
+func (s *FileAPIKeyStore) Get(ctx context.Context, id string) (*APIKey, error) {
+	return s.memory.Get(ctx, id)
+}
+
+// List returns all keys ordered by ID.
+func (s *FileAPIKeyStore) List(ctx context.Context) ([]*APIKey, error) {
+	return s.memory.List(ctx)
+}
+
+// Put creates or replaces a key and saves the file.
+func (s *FileAPIKeyStore) Put(ctx context.Context, key *APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.memory.Put(ctx, key); err != nil {
+		return err
+	}
+	return s.save(ctx)
+}
+
+// Touch records the last use of a key and saves the file.
+func (s *FileAPIKeyStore) Touch(ctx context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.memory.Touch(ctx, id, at); err != nil {
+		return err
+	}
+	return s.save(ctx)
+}
+
+// save writes all keys to a temporary file and renames it over the store
+// file.
+func (s *FileAPIKeyStore) save(ctx context.Context) error {
+	keys, err := s.memory.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding API keys: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating API key file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing API key file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing API key file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("replacing API key file: %w", err)
+	}
+
+	return nil
+}
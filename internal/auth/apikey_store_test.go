@@ -0,0 +1,311 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+)
+
+// apiKeyRequest returns a request authenticated with key.
+func apiKeyRequest(key string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(auth.APIKeyHeader, key)
+	return req
+}
+
+func TestAPIKeyAuthenticator_ManagedKeys(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	authenticator, err := auth.NewAPIKeyStoreAuthenticator("static-key:service-static", auth.NewMemoryAPIKeyStore())
+	if err != nil {
+		t.Fatalf("NewAPIKeyStoreAuthenticator() error = %v", err)
+	}
+
+	// Act
+	secret, key, err := authenticator.CreateKey(ctx, "ci-pipeline", []string{"reader"}, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateKey() error = %v", err)
+	}
+	info, err := authenticator.Authenticate(apiKeyRequest(secret))
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	staticInfo, err := authenticator.Authenticate(apiKeyRequest("static-key"))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Authenticate() static key error = %v", err)
+	}
+	if staticInfo.Subject != "service-static" {
+		t.Errorf("static Subject = %q, want %q", staticInfo.Subject, "service-static")
+	}
+	if info.Subject != "ci-pipeline" {
+		t.Errorf("Subject = %q, want %q", info.Subject, "ci-pipeline")
+	}
+	if info.Claims["key_id"] != key.ID {
+		t.Errorf("key_id claim = %v, want %q", info.Claims["key_id"], key.ID)
+	}
+	if scopes, _ := info.Claims["scopes"].([]string); !slices.Equal(scopes, []string{"reader"}) {
+		t.Errorf("scopes claim = %v, want [reader]", info.Claims["scopes"])
+	}
+	if !info.Expiry.Equal(key.ExpiresAt) {
+		t.Errorf("Expiry = %v, want %v", info.Expiry, key.ExpiresAt)
+	}
+	if len(key.Hash) == 0 || string(key.Hash) == secret {
+		t.Error("key hash must be set and differ from the key")
+	}
+
+	keys, err := authenticator.ListKeys(ctx)
+	if err != nil {
+		t.Fatalf("ListKeys() error = %v", err)
+	}
+	if len(keys) != 1 || keys[0].LastUsedAt.IsZero() {
+		t.Errorf("ListKeys() = %+v, want one key with its last use recorded", keys)
+	}
+}
+
+func TestAPIKeyAuthenticator_RotateKey(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	authenticator, err := auth.NewAPIKeyStoreAuthenticator("", auth.NewMemoryAPIKeyStore())
+	if err != nil {
+		t.Fatalf("NewAPIKeyStoreAuthenticator() error = %v", err)
+	}
+	oldSecret, created, err := authenticator.CreateKey(ctx, "ci", nil, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateKey() error = %v", err)
+	}
+
+	// Act
+	newSecret, rotated, err := authenticator.RotateKey(ctx, created.ID, 0)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("RotateKey() error = %v", err)
+	}
+	if rotated.ID != created.ID || rotated.RotatedAt.IsZero() {
+		t.Errorf("RotateKey() = %+v, want same ID with RotatedAt set", rotated)
+	}
+	if got := rotated.ExpiresAt.Sub(rotated.RotatedAt); got != time.Hour {
+		t.Errorf("lifetime after rotation = %v, want %v", got, time.Hour)
+	}
+	if _, err := authenticator.Authenticate(apiKeyRequest(oldSecret)); !errors.Is(err, auth.ErrInvalidAPIKey) {
+		t.Errorf("Authenticate() old key error = %v, want %v", err, auth.ErrInvalidAPIKey)
+	}
+	if _, err := authenticator.Authenticate(apiKeyRequest(newSecret)); err != nil {
+		t.Errorf("Authenticate() new key error = %v", err)
+	}
+}
+
+func TestAPIKeyAuthenticator_RevokeKey(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	authenticator, err := auth.NewAPIKeyStoreAuthenticator("", auth.NewMemoryAPIKeyStore())
+	if err != nil {
+		t.Fatalf("NewAPIKeyStoreAuthenticator() error = %v", err)
+	}
+	secret, key, err := authenticator.CreateKey(ctx, "ci", nil, 0)
+	if err != nil {
+		t.Fatalf("CreateKey() error = %v", err)
+	}
+
+	// Act
+	revokeErr := authenticator.RevokeKey(ctx, key.ID)
+
+	// Assert
+	if revokeErr != nil {
+		t.Fatalf("RevokeKey() error = %v", revokeErr)
+	}
+	_, err = authenticator.Authenticate(apiKeyRequest(secret))
+	if !errors.Is(err, auth.ErrInvalidAPIKey) || !errors.Is(err, auth.ErrAPIKeyRevoked) {
+		t.Errorf("Authenticate() error = %v, want %v and %v", err, auth.ErrInvalidAPIKey, auth.ErrAPIKeyRevoked)
+	}
+	if err := authenticator.RevokeKey(ctx, key.ID); err != nil {
+		t.Errorf("RevokeKey() again error = %v, want nil", err)
+	}
+	if _, _, err := authenticator.RotateKey(ctx, key.ID, 0); !errors.Is(err, auth.ErrAPIKeyRevoked) {
+		t.Errorf("RotateKey() error = %v, want %v", err, auth.ErrAPIKeyRevoked)
+	}
+	if err := authenticator.RevokeKey(ctx, "unknown"); !errors.Is(err, auth.ErrAPIKeyNotFound) {
+		t.Errorf("RevokeKey() unknown error = %v, want %v", err, auth.ErrAPIKeyNotFound)
+	}
+}
+
+func TestAPIKeyAuthenticator_ExpiredKey(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	store := auth.NewMemoryAPIKeyStore()
+	authenticator, err := auth.NewAPIKeyStoreAuthenticator("", store)
+	if err != nil {
+		t.Fatalf("NewAPIKeyStoreAuthenticator() error = %v", err)
+	}
+	secret, key, err := authenticator.CreateKey(ctx, "ci", nil, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateKey() error = %v", err)
+	}
+	key.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := store.Put(ctx, key); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	// Act
+	_, err = authenticator.Authenticate(apiKeyRequest(secret))
+
+	// Assert
+	if !errors.Is(err, auth.ErrInvalidAPIKey) {
+		t.Errorf("Authenticate() error = %v, want %v", err, auth.ErrInvalidAPIKey)
+	}
+}
+
+func TestAPIKeyAuthenticator_InvalidParams(t *testing.T) {
+	t.Parallel()
+
+	authenticator, err := auth.NewAPIKeyStoreAuthenticator("", auth.NewMemoryAPIKeyStore())
+	if err != nil {
+		t.Fatalf("NewAPIKeyStoreAuthenticator() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		keyFor string
+		scopes []string
+		ttl    time.Duration
+	}{
+		{name: "empty name", keyFor: " "},
+		{name: "empty scope", keyFor: "ci", scopes: []string{""}},
+		{name: "scope with comma", keyFor: "ci", scopes: []string{"reader,writer"}},
+		{name: "negative lifetime", keyFor: "ci", ttl: -time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			_, _, err := authenticator.CreateKey(context.Background(), tt.keyFor, tt.scopes, tt.ttl)
+
+			// Assert
+			if !errors.Is(err, auth.ErrInvalidAPIKeyParams) {
+				t.Errorf("CreateKey() error = %v, want %v", err, auth.ErrInvalidAPIKeyParams)
+			}
+		})
+	}
+}
+
+func TestFileAPIKeyStore_Persistence(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "api-keys.json")
+	store, err := auth.OpenFileAPIKeyStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileAPIKeyStore() error = %v", err)
+	}
+	authenticator, err := auth.NewAPIKeyStoreAuthenticator("", store)
+	if err != nil {
+		t.Fatalf("NewAPIKeyStoreAuthenticator() error = %v", err)
+	}
+	secret, key, err := authenticator.CreateKey(ctx, "ci", []string{"writer"}, 0)
+	if err != nil {
+		t.Fatalf("CreateKey() error = %v", err)
+	}
+
+	// Act
+	reopened, err := auth.OpenFileAPIKeyStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileAPIKeyStore() reopen error = %v", err)
+	}
+	restarted, err := auth.NewAPIKeyStoreAuthenticator("", reopened)
+	if err != nil {
+		t.Fatalf("NewAPIKeyStoreAuthenticator() error = %v", err)
+	}
+	info, err := restarted.Authenticate(apiKeyRequest(secret))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Authenticate() after reopen error = %v", err)
+	}
+	if info.Claims["key_id"] != key.ID {
+		t.Errorf("key_id claim = %v, want %q", info.Claims["key_id"], key.ID)
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if perm := stat.Mode().Perm(); perm != 0o600 {
+		t.Errorf("file permissions = %v, want %v", perm, os.FileMode(0o600))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if strings.Contains(string(data), secret) {
+		t.Error("API key file must not contain the key")
+	}
+}
+
+func TestOpenFileAPIKeyStore_Errors(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	path := filepath.Join(t.TempDir(), "api-keys.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	// Act
+	_, err := auth.OpenFileAPIKeyStore(path)
+
+	// Assert
+	if err == nil {
+		t.Error("OpenFileAPIKeyStore() error = nil, want error")
+	}
+}
+
+func TestMemoryAPIKeyStore_ReturnsCopies(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	store := auth.NewMemoryAPIKeyStore()
+	key := &auth.APIKey{ID: "a", Name: "ci", Scopes: []string{"reader"}}
+	if err := store.Put(ctx, key); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	// Act
+	key.Scopes[0] = "admin"
+	got, err := store.Get(ctx, "a")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Scopes[0] != "reader" {
+		t.Errorf("Scopes = %v, want [reader]", got.Scopes)
+	}
+	if _, err := store.Get(ctx, "b"); !errors.Is(err, auth.ErrAPIKeyNotFound) {
+		t.Errorf("Get() unknown error = %v, want %v", err, auth.ErrAPIKeyNotFound)
+	}
+	if err := store.Touch(ctx, "b", time.Now()); !errors.Is(err, auth.ErrAPIKeyNotFound) {
+		t.Errorf("Touch() unknown error = %v, want %v", err, auth.ErrAPIKeyNotFound)
+	}
+}
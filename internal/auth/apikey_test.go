@@ -198,6 +198,45 @@ func TestAPIKeyAuthenticator_Authenticate(t *testing.T) {
 	}
 }
 
+func TestAPIKeyAuthenticator_SetStaticKeys(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	authenticator, err := auth.NewAPIKeyAuthenticator("old-key:old-service")
+	if err != nil {
+		t.Fatalf("NewAPIKeyAuthenticator() error = %v", err)
+	}
+	authenticate := func(key string) (*auth.AuthInfo, error) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(auth.APIKeyHeader, key)
+		return authenticator.Authenticate(req)
+	}
+
+	// Act
+	invalidErr := authenticator.SetStaticKeys("no-name")
+	_, keptErr := authenticate("old-key")
+	setErr := authenticator.SetStaticKeys("new-key:new-service")
+	_, oldErr := authenticate("old-key")
+	info, newErr := authenticate("new-key")
+
+	// Assert
+	if invalidErr == nil {
+		t.Error("SetStaticKeys() with an invalid config error = nil, want error")
+	}
+	if keptErr != nil {
+		t.Errorf("Authenticate() after a failed SetStaticKeys() error = %v, want the current keys kept", keptErr)
+	}
+	if setErr != nil {
+		t.Fatalf("SetStaticKeys() error = %v", setErr)
+	}
+	if !errors.Is(oldErr, auth.ErrInvalidAPIKey) {
+		t.Errorf("Authenticate() with a removed key error = %v, want %v", oldErr, auth.ErrInvalidAPIKey)
+	}
+	if newErr != nil || info.Subject != "new-service" {
+		t.Errorf("Authenticate() with an added key = %v, %v, want new-service", info, newErr)
+	}
+}
+
 func TestAPIKeyAuthenticator_Method(t *testing.T) {
 	t.Parallel()
 
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"time"
 )
 
 // AuthMethod represents the authentication method used.
@@ -21,6 +22,8 @@ const (
 	AuthMethodBasic AuthMethod = "basic"
 	// AuthMethodAPIKey indicates API key authentication.
 	AuthMethodAPIKey AuthMethod = "apikey"
+	// AuthMethodIntrospection indicates OAuth2 token introspection (RFC 7662).
+	AuthMethodIntrospection AuthMethod = "introspection"
 	// AuthMethodMulti indicates multi-method authentication.
 	AuthMethodMulti AuthMethod = "multi"
 )
@@ -30,6 +33,9 @@ type AuthInfo struct {
 	Method  AuthMethod
 	Subject string
 	Claims  map[string]any
+
+	// Expiry is when the presented credentials expire; zero if they do not.
+	Expiry time.Time
 }
 
 // Authenticator validates a request and returns auth info.
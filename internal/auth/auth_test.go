@@ -20,6 +20,7 @@ func TestAuthMethodConstants(t *testing.T) {
 		{"AuthMethodOIDC", auth.AuthMethodOIDC, "oidc"},
 		{"AuthMethodBasic", auth.AuthMethodBasic, "basic"},
 		{"AuthMethodAPIKey", auth.AuthMethodAPIKey, "apikey"},
+		{"AuthMethodIntrospection", auth.AuthMethodIntrospection, "introspection"},
 		{"AuthMethodMulti", auth.AuthMethodMulti, "multi"},
 	}
 
@@ -1,27 +1,104 @@
 package auth
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// maxBasicCacheEntries bounds the cache of successful verifications.
+const maxBasicCacheEntries = 10000
+
 // BasicAuthenticator authenticates requests using HTTP Basic authentication
-// with bcrypt-hashed passwords.
+// with bcrypt, SHA-crypt or argon2id password hashes. Users come from the
+// configuration or from an htpasswd file that is reloaded when it changes.
+// Successful verifications can be cached to avoid the cost of the password
+// hash on every request.
 type BasicAuthenticator struct {
-	users     map[string]string // username -> bcrypt hash
-	dummyHash []byte            // pre-computed hash used when user is not found to prevent timing leaks
+	users     atomic.Pointer[basicUsers]
+	dummyHash []byte // pre-computed hash used when user is not found to prevent timing leaks
+
+	// cacheTTL is how long successful verifications are cached (0 = never).
+	// Cache keys are HMACs of the credentials under cacheKey, so passwords
+	// are not kept in memory.
+	cacheTTL time.Duration
+	cacheKey []byte
+	mu       sync.Mutex
+	cache    map[[sha256.Size]byte]basicCacheEntry
+
+	// file is set when users are read from an htpasswd file.
+	file *htpasswdWatcher
+
+	// lockout locks out users and clients after failed attempts.
+	lockout *Lockout
+}
+
+// basicUsers is one consistent set of users.
+type basicUsers struct {
+	digest [sha256.Size]byte // digest of the htpasswd file contents
+	hashes map[string]string // username -> password hash
+}
+
+// basicCacheEntry is a cached successful verification.
+type basicCacheEntry struct {
+	hash    string // password hash the password was verified against
+	expires time.Time
 }
 
 // NewBasicAuthenticator creates a new Basic authenticator from a
 // configuration string in the format "user1:hash1,user2:hash2".
 // Each entry must contain exactly one colon separating the username
-// from the bcrypt hash.
+// from the password hash.
 func NewBasicAuthenticator(
 	usersConfig string,
 ) (*BasicAuthenticator, error) {
+	users, err := parseBasicUsers(usersConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return newBasicAuthenticator(users)
+}
+
+// SetUsers replaces the users with those in usersConfig, which has the
+// format of NewBasicAuthenticator. On error the current users are kept.
+// Users read from an htpasswd file cannot be replaced.
+func (a *BasicAuthenticator) SetUsers(usersConfig string) error {
+	apply, err := a.PrepareUsers(usersConfig)
+	if err != nil {
+		return err
+	}
+
+	apply()
+	return nil
+}
+
+// PrepareUsers parses usersConfig like SetUsers and returns a function that
+// replaces the users with them, so that callers can check several settings
+// before applying any.
+func (a *BasicAuthenticator) PrepareUsers(usersConfig string) (func(), error) {
+	if a.file != nil {
+		return nil, fmt.Errorf("basic auth: users are read from %s", a.file.config.Path)
+	}
+
+	users, err := parseBasicUsers(usersConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() { a.users.Store(users) }, nil
+}
+
+// parseBasicUsers parses the users in usersConfig.
+func parseBasicUsers(usersConfig string) (*basicUsers, error) {
 	trimmed := strings.TrimSpace(usersConfig)
 	if trimmed == "" {
 		return nil, fmt.Errorf(
@@ -38,9 +115,9 @@ func NewBasicAuthenticator(
 			continue
 		}
 
-		// Find the first colon to split username from bcrypt hash.
-		// Bcrypt hashes contain '$' but not additional colons in the
-		// user:hash format.
+		// Find the first colon to split username from the hash. Password
+		// hashes contain '$' but not additional colons in the user:hash
+		// format.
 		idx := strings.Index(entry, ":")
 		if idx < 0 {
 			return nil, fmt.Errorf(
@@ -66,6 +143,11 @@ func NewBasicAuthenticator(
 		)
 	}
 
+	return &basicUsers{hashes: users}, nil
+}
+
+// newBasicAuthenticator creates a Basic authenticator serving users.
+func newBasicAuthenticator(users *basicUsers) (*BasicAuthenticator, error) {
 	// Generate a dummy bcrypt hash at init time. This hash is compared against
 	// when the requested user does not exist, ensuring constant-time behavior
 	// regardless of whether the user is known.
@@ -76,13 +158,45 @@ func NewBasicAuthenticator(
 		return nil, fmt.Errorf("basic auth: generating dummy hash: %w", err)
 	}
 
-	return &BasicAuthenticator{users: users, dummyHash: dummyHash}, nil
+	cacheKey := make([]byte, sha256.Size)
+	if _, err := rand.Read(cacheKey); err != nil {
+		return nil, fmt.Errorf("basic auth: generating cache key: %w", err)
+	}
+
+	a := &BasicAuthenticator{
+		dummyHash: dummyHash,
+		cacheKey:  cacheKey,
+		cache:     make(map[[sha256.Size]byte]basicCacheEntry),
+	}
+	a.users.Store(users)
+
+	return a, nil
+}
+
+// SetCacheTTL sets how long successful verifications are cached; zero
+// disables caching. A cached verification only applies while the user's
+// password hash is unchanged.
+func (a *BasicAuthenticator) SetCacheTTL(ttl time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.cacheTTL = ttl
+	clear(a.cache)
+}
+
+// SetLockout locks out users and clients after failed attempts; nil
+// disables lockouts. Failures are tracked for unknown users as well, so
+// lockouts do not reveal which users exist.
+func (a *BasicAuthenticator) SetLockout(lockout *Lockout) {
+	a.lockout = lockout
 }
 
 // Authenticate extracts Basic auth credentials from the request,
 // looks up the user, and verifies the password against the stored
-// bcrypt hash. When the user is not found, a comparison against a
+// password hash. When the user is not found, a comparison against a
 // dummy hash is performed to prevent timing-based user enumeration.
+// While the user or the client is locked out, a LockoutError is returned
+// without checking the password.
 func (a *BasicAuthenticator) Authenticate(
 	r *http.Request,
 ) (*AuthInfo, error) {
@@ -91,21 +205,26 @@ func (a *BasicAuthenticator) Authenticate(
 		return nil, ErrUnauthenticated
 	}
 
-	hash, exists := a.users[username]
+	if err := a.lockout.Check(r, username); err != nil {
+		return nil, err
+	}
+
+	hash, exists := a.users.Load().hashes[username]
 	if !exists {
 		// Compare against dummy hash to consume the same time as a real
 		// comparison, preventing timing-based user enumeration.
 		_ = bcrypt.CompareHashAndPassword(a.dummyHash, []byte(password))
+		a.lockout.Failure(r, username)
 		return nil, fmt.Errorf("%w: invalid username or password", ErrInvalidCredentials)
 	}
 
-	if err := bcrypt.CompareHashAndPassword(
-		[]byte(hash), []byte(password),
-	); err != nil {
+	if !a.verify(username, password, hash) {
+		a.lockout.Failure(r, username)
 		return nil, fmt.Errorf(
 			"%w: invalid username or password", ErrInvalidCredentials,
 		)
 	}
+	a.lockout.Success(username)
 
 	return &AuthInfo{
 		Method:  AuthMethodBasic,
@@ -117,3 +236,58 @@ func (a *BasicAuthenticator) Authenticate(
 func (a *BasicAuthenticator) Method() AuthMethod {
 	return AuthMethodBasic
 }
+
+// verify checks password against hash, using and filling the cache.
+func (a *BasicAuthenticator) verify(username, password, hash string) bool {
+	mac := hmac.New(sha256.New, a.cacheKey)
+	mac.Write([]byte(username))
+	mac.Write([]byte{0})
+	mac.Write([]byte(password))
+	var key [sha256.Size]byte
+	mac.Sum(key[:0])
+
+	now := time.Now()
+	if a.cached(key, hash, now) {
+		return true
+	}
+
+	if err := verifyPassword(hash, password); err != nil {
+		return false
+	}
+
+	a.remember(key, hash, now)
+	return true
+}
+
+// cached reports whether the credentials with the given key were verified
+// against hash recently.
+func (a *BasicAuthenticator) cached(key [sha256.Size]byte, hash string, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry, ok := a.cache[key]
+	return ok && entry.hash == hash && now.Before(entry.expires)
+}
+
+// remember caches a successful verification, dropping expired entries when
+// the cache is full.
+func (a *BasicAuthenticator) remember(key [sha256.Size]byte, hash string, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cacheTTL <= 0 {
+		return
+	}
+
+	if len(a.cache) >= maxBasicCacheEntries {
+		for k, e := range a.cache {
+			if !now.Before(e.expires) {
+				delete(a.cache, k)
+			}
+		}
+		if len(a.cache) >= maxBasicCacheEntries {
+			return
+		}
+	}
+	a.cache[key] = basicCacheEntry{hash: hash, expires: now.Add(a.cacheTTL)}
+}
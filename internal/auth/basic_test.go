@@ -255,6 +255,40 @@ func TestBasicAuthenticator_MultipleUsers(t *testing.T) {
 	}
 }
 
+func TestBasicAuthenticator_SetUsers(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	authenticator, err := auth.NewBasicAuthenticator("alice:" + generateBcryptHash(t, "pass1"))
+	if err != nil {
+		t.Fatalf("NewBasicAuthenticator() error = %v", err)
+	}
+
+	// Act
+	invalidErr := authenticator.SetUsers("bob")
+	aliceBefore, _ := authenticator.Authenticate(basicRequest("alice", "pass1"))
+	setErr := authenticator.SetUsers("bob:" + generateBcryptHash(t, "pass2"))
+	_, aliceErr := authenticator.Authenticate(basicRequest("alice", "pass1"))
+	bob, bobErr := authenticator.Authenticate(basicRequest("bob", "pass2"))
+
+	// Assert
+	if invalidErr == nil {
+		t.Error("SetUsers() with an invalid config error = nil, want error")
+	}
+	if aliceBefore == nil {
+		t.Error("Authenticate() after a failed SetUsers() = nil, want the current users kept")
+	}
+	if setErr != nil {
+		t.Fatalf("SetUsers() error = %v", setErr)
+	}
+	if !errors.Is(aliceErr, auth.ErrInvalidCredentials) {
+		t.Errorf("Authenticate() of a removed user error = %v, want %v", aliceErr, auth.ErrInvalidCredentials)
+	}
+	if bobErr != nil || bob.Subject != "bob" {
+		t.Errorf("Authenticate() of an added user = %v, %v, want bob", bob, bobErr)
+	}
+}
+
 func TestBasicAuthenticator_Method(t *testing.T) {
 	t.Parallel()
 
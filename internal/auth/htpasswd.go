@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrInvalidHtpasswd is returned for htpasswd files that cannot be parsed.
+var ErrInvalidHtpasswd = errors.New("invalid htpasswd file")
+
+// HtpasswdConfig configures a BasicAuthenticator reading its users from an
+// htpasswd file.
+type HtpasswdConfig struct {
+	// Path is the htpasswd file: one "user:hash" entry per line, with blank
+	// lines and lines starting with '#' ignored. Hashes must be bcrypt,
+	// SHA-crypt ($5$, $6$) or argon2id.
+	Path string
+	// ReloadInterval is how often the file is checked for changes; zero
+	// disables reloading.
+	ReloadInterval time.Duration
+	// Logger reports reloads. It defaults to a no-op logger.
+	Logger *zap.Logger
+}
+
+// htpasswdWatcher reloads the users of a BasicAuthenticator from an
+// htpasswd file.
+type htpasswdWatcher struct {
+	config       HtpasswdConfig
+	failedDigest [sha256.Size]byte // contents that last failed to parse
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewHtpasswdAuthenticator creates a Basic authenticator for the users in an
+// htpasswd file. Call Start to begin watching the file for changes, e.g.
+// after a mounted Kubernetes Secret was updated.
+func NewHtpasswdAuthenticator(config HtpasswdConfig) (*BasicAuthenticator, error) {
+	if config.Logger == nil {
+		config.Logger = zap.NewNop()
+	}
+
+	users, err := readHtpasswd(config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("basic auth: %w", err)
+	}
+
+	a, err := newBasicAuthenticator(users)
+	if err != nil {
+		return nil, err
+	}
+	a.file = &htpasswdWatcher{config: config, stop: make(chan struct{})}
+
+	config.Logger.Info("htpasswd file loaded",
+		zap.String("path", config.Path),
+		zap.Int("users", len(users.hashes)),
+	)
+
+	return a, nil
+}
+
+// Start watches the htpasswd file for changes until Stop is called. It does
+// nothing when users do not come from a file or no reload interval is
+// configured.
+func (a *BasicAuthenticator) Start() {
+	if a.file == nil || a.file.config.ReloadInterval <= 0 {
+		return
+	}
+
+	a.file.wg.Add(1)
+	go func() {
+		defer a.file.wg.Done()
+		a.watch()
+	}()
+}
+
+// Stop ends watching the htpasswd file and waits for it to finish. The
+// current users continue to be served.
+func (a *BasicAuthenticator) Stop() {
+	if a.file == nil {
+		return
+	}
+
+	a.file.stopOnce.Do(func() { close(a.file.stop) })
+	a.file.wg.Wait()
+}
+
+// watch reloads the htpasswd file every interval until Stop is called.
+func (a *BasicAuthenticator) watch() {
+	ticker := time.NewTicker(a.file.config.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.file.stop:
+			return
+		case <-ticker.C:
+			a.reload()
+		}
+	}
+}
+
+// reload serves the users of the htpasswd file if its contents changed and
+// can be parsed. Contents that failed to parse are reported once, not on
+// every check; until they are fixed the previous users keep being served.
+func (a *BasicAuthenticator) reload() {
+	config := a.file.config
+
+	data, err := os.ReadFile(config.Path)
+	if err != nil {
+		config.Logger.Error("failed to reload htpasswd file, keeping the current users",
+			zap.String("path", config.Path),
+			zap.Error(err),
+		)
+		return
+	}
+
+	digest := sha256.Sum256(data)
+	if digest == a.users.Load().digest || digest == a.file.failedDigest {
+		return
+	}
+
+	users, err := parseHtpasswd(data)
+	if err != nil {
+		a.file.failedDigest = digest
+		config.Logger.Error("failed to reload htpasswd file, keeping the current users",
+			zap.String("path", config.Path),
+			zap.Error(err),
+		)
+		return
+	}
+
+	a.users.Store(users)
+	config.Logger.Info("htpasswd file reloaded",
+		zap.String("path", config.Path),
+		zap.Int("users", len(users.hashes)),
+	)
+}
+
+// readHtpasswd reads and parses the htpasswd file at path.
+func readHtpasswd(path string) (*basicUsers, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading htpasswd file: %w", err)
+	}
+
+	users, err := parseHtpasswd(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return users, nil
+}
+
+// parseHtpasswd parses the contents of an htpasswd file.
+func parseHtpasswd(data []byte) (*basicUsers, error) {
+	users := &basicUsers{
+		digest: sha256.Sum256(data),
+		hashes: make(map[string]string),
+	}
+
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		entry := strings.TrimSpace(string(line))
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		username, hash, ok := strings.Cut(entry, ":")
+		if !ok || username == "" || hash == "" {
+			return nil, fmt.Errorf("%w: line %d: expected user:hash", ErrInvalidHtpasswd, i+1)
+		}
+		if _, exists := users.hashes[username]; exists {
+			return nil, fmt.Errorf("%w: line %d: duplicate user %q", ErrInvalidHtpasswd, i+1, username)
+		}
+		if err := checkPasswordHash(hash); err != nil {
+			return nil, fmt.Errorf("%w: line %d: user %q: %w", ErrInvalidHtpasswd, i+1, username, err)
+		}
+
+		users.hashes[username] = hash
+	}
+
+	if len(users.hashes) == 0 {
+		return nil, fmt.Errorf("%w: no users found", ErrInvalidHtpasswd)
+	}
+
+	return users, nil
+}
@@ -0,0 +1,265 @@
+package auth_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/crypto/argon2"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+)
+
+// generateArgon2idHash returns an argon2id hash of password with cheap
+// parameters.
+func generateArgon2idHash(t *testing.T, password string) string {
+	t.Helper()
+
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte(password), salt, 1, 1024, 1, 32)
+	return "$argon2id$v=19$m=1024,t=1,p=1$" +
+		base64.RawStdEncoding.EncodeToString(salt) + "$" +
+		base64.RawStdEncoding.EncodeToString(key)
+}
+
+// writeHtpasswd writes an htpasswd file with the given lines.
+func writeHtpasswd(t *testing.T, path string, lines ...string) {
+	t.Helper()
+
+	// Replace the file atomically so that a reload never sees it half written.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatalf("writing htpasswd file: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("replacing htpasswd file: %v", err)
+	}
+}
+
+// basicRequest returns a request with Basic credentials.
+func basicRequest(username, password string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth(username, password)
+	return req
+}
+
+func TestBasicAuthenticator_PasswordHashSchemes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+	}{
+		{name: "bcrypt", hash: generateBcryptHash(t, "Hello world!"), password: "Hello world!"},
+		{
+			name:     "bcrypt 2y",
+			hash:     "$2y" + strings.TrimPrefix(generateBcryptHash(t, "Hello world!"), "$2a"),
+			password: "Hello world!",
+		},
+		{
+			name:     "SHA-256-crypt",
+			hash:     "$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5",
+			password: "Hello world!",
+		},
+		{
+			name:     "SHA-256-crypt with rounds",
+			hash:     "$5$rounds=10000$saltstringsaltst$3xv.VbSHBb41AL9AvLeujZkZRBAwqFMz2.opqey6IcA",
+			password: "Hello world!",
+		},
+		{
+			name: "SHA-512-crypt",
+			hash: "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OT" +
+				"LiBFdcbYEdFCoEOfaS35inz1",
+			password: "Hello world!",
+		},
+		{
+			name: "SHA-512-crypt with rounds and long password",
+			hash: "$6$rounds=1000$abc$u/qZCQeglXjb2Y5C4mKODP.oHRwEgVIcWQw4hhlvuQ1g4WEgyf.NHR0QUzKd" +
+				"Xdc.JLEtomhyE3bOB68r1VhDZ0",
+			password: strings.Repeat("a", 100),
+		},
+		{name: "argon2id", hash: generateArgon2idHash(t, "Hello world!"), password: "Hello world!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			path := filepath.Join(t.TempDir(), "htpasswd")
+			writeHtpasswd(t, path, "alice:"+tt.hash)
+			authenticator, err := auth.NewHtpasswdAuthenticator(auth.HtpasswdConfig{Path: path})
+			if err != nil {
+				t.Fatalf("NewHtpasswdAuthenticator() error = %v", err)
+			}
+
+			// Act
+			info, authErr := authenticator.Authenticate(basicRequest("alice", tt.password))
+			_, wrongErr := authenticator.Authenticate(basicRequest("alice", tt.password+"x"))
+
+			// Assert
+			if authErr != nil {
+				t.Fatalf("Authenticate() error = %v, want nil", authErr)
+			}
+			if info.Subject != "alice" {
+				t.Errorf("Subject = %q, want %q", info.Subject, "alice")
+			}
+			if !errors.Is(wrongErr, auth.ErrInvalidCredentials) {
+				t.Errorf("Authenticate() wrong password error = %v, want %v", wrongErr, auth.ErrInvalidCredentials)
+			}
+		})
+	}
+}
+
+func TestNewHtpasswdAuthenticator_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		lines   []string
+		wantErr string
+	}{
+		{name: "no users", lines: []string{"# comment", ""}, wantErr: "no users found"},
+		{name: "missing hash", lines: []string{"alice"}, wantErr: "line 1: expected user:hash"},
+		{
+			name:    "duplicate user",
+			lines:   []string{"alice:$5$salt$x", "alice:$5$salt$y"},
+			wantErr: `line 2: duplicate user "alice"`,
+		},
+		{name: "apr1 MD5 hash", lines: []string{"alice:$apr1$salt$hash"}, wantErr: "unsupported password hash"},
+		{name: "plain text password", lines: []string{"alice:secret"}, wantErr: "unsupported password hash"},
+		{name: "malformed bcrypt hash", lines: []string{"alice:$2y$10$short"}, wantErr: `user "alice"`},
+		{
+			name:    "malformed argon2id hash",
+			lines:   []string{"alice:$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5"},
+			wantErr: "invalid argon2id hash",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			path := filepath.Join(t.TempDir(), "htpasswd")
+			writeHtpasswd(t, path, tt.lines...)
+
+			// Act
+			_, err := auth.NewHtpasswdAuthenticator(auth.HtpasswdConfig{Path: path})
+
+			// Assert
+			if !errors.Is(err, auth.ErrInvalidHtpasswd) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewHtpasswdAuthenticator() error = %v, want %v containing %q",
+					err, auth.ErrInvalidHtpasswd, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := auth.NewHtpasswdAuthenticator(auth.HtpasswdConfig{
+			Path: filepath.Join(t.TempDir(), "missing"),
+		})
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("NewHtpasswdAuthenticator() error = %v, want %v", err, os.ErrNotExist)
+		}
+	})
+}
+
+func TestBasicAuthenticator_SetUsers_Htpasswd(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	writeHtpasswd(t, path, "alice:"+generateBcryptHash(t, "password"))
+	authenticator, err := auth.NewHtpasswdAuthenticator(auth.HtpasswdConfig{Path: path, Logger: zap.NewNop()})
+	if err != nil {
+		t.Fatalf("NewHtpasswdAuthenticator() error = %v", err)
+	}
+
+	// Act
+	err = authenticator.SetUsers("bob:" + generateBcryptHash(t, "password"))
+
+	// Assert
+	if err == nil {
+		t.Error("SetUsers() error = nil, want error for file-backed users")
+	}
+	if _, err := authenticator.Authenticate(basicRequest("alice", "password")); err != nil {
+		t.Errorf("Authenticate() error = %v, want the file users kept", err)
+	}
+}
+
+func TestBasicAuthenticator_HtpasswdReload(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	writeHtpasswd(t, path, "# users", "alice:"+generateBcryptHash(t, "old-password"))
+	core, logs := observer.New(zap.InfoLevel)
+	authenticator, err := auth.NewHtpasswdAuthenticator(auth.HtpasswdConfig{
+		Path:           path,
+		ReloadInterval: 10 * time.Millisecond,
+		Logger:         zap.New(core),
+	})
+	if err != nil {
+		t.Fatalf("NewHtpasswdAuthenticator() error = %v", err)
+	}
+	authenticator.SetCacheTTL(time.Minute)
+	authenticator.Start()
+	t.Cleanup(authenticator.Stop)
+
+	if _, err := authenticator.Authenticate(basicRequest("alice", "old-password")); err != nil {
+		t.Fatalf("Authenticate() before reload error = %v", err)
+	}
+
+	// Act
+	writeHtpasswd(t, path,
+		"alice:"+generateBcryptHash(t, "new-password"),
+		"bob:"+generateArgon2idHash(t, "bob-password"),
+	)
+
+	// Assert
+	waitFor(t, func() bool { return logs.FilterMessage("htpasswd file reloaded").Len() == 1 })
+	if _, err := authenticator.Authenticate(basicRequest("bob", "bob-password")); err != nil {
+		t.Errorf("Authenticate() with a new user error = %v", err)
+	}
+	if _, err := authenticator.Authenticate(basicRequest("alice", "old-password")); err == nil {
+		t.Error("Authenticate() with the cached old password error = nil, want error")
+	}
+	if _, err := authenticator.Authenticate(basicRequest("alice", "new-password")); err != nil {
+		t.Errorf("Authenticate() with the new password error = %v", err)
+	}
+
+	// An invalid file keeps the current users.
+	writeHtpasswd(t, path, "carol:plain-text")
+	waitFor(t, func() bool {
+		return logs.FilterMessage("failed to reload htpasswd file, keeping the current users").Len() == 1
+	})
+	if _, err := authenticator.Authenticate(basicRequest("bob", "bob-password")); err != nil {
+		t.Errorf("Authenticate() after invalid reload error = %v", err)
+	}
+}
+
+// waitFor polls cond until it holds or five seconds have passed. cond should
+// be cheap, such as inspecting logs or metrics rather than authenticating.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 5s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
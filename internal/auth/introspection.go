@@ -0,0 +1,284 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTokenInactive is returned for tokens the introspection endpoint
+// reports as not active (revoked, expired or unknown).
+var ErrTokenInactive = errors.New("token is not active")
+
+const (
+	// maxIntrospectionCacheEntries bounds the introspection cache.
+	maxIntrospectionCacheEntries = 10000
+	// maxIntrospectionResponseSize bounds the size of an introspection response.
+	maxIntrospectionResponseSize = 1 << 20
+)
+
+// IntrospectionConfig configures an IntrospectionAuthenticator.
+type IntrospectionConfig struct {
+	// URL is the RFC 7662 introspection endpoint of the authorization server.
+	URL string
+	// ClientID and ClientSecret authenticate this server to the endpoint
+	// with HTTP Basic authentication.
+	ClientID     string
+	ClientSecret string
+	// Audience, when set, must be contained in the token's aud claim.
+	Audience string
+
+	// CacheTTL is how long active tokens are cached; tokens are never
+	// cached past their expiry. NegativeCacheTTL is how long inactive tokens
+	// are cached. Zero disables the respective caching.
+	CacheTTL         time.Duration
+	NegativeCacheTTL time.Duration
+
+	// OpaqueOnly leaves JWTs to a local verifier: they are reported as
+	// ErrUnauthenticated so that a MultiAuthenticator tries the next
+	// authenticator, typically an OIDCAuthenticator.
+	OpaqueOnly bool
+}
+
+// IntrospectionAuthenticator authenticates requests with opaque bearer
+// tokens by asking the authorization server whether they are active
+// (RFC 7662). Results are cached by token hash, so the raw tokens are not
+// kept in memory.
+type IntrospectionAuthenticator struct {
+	config IntrospectionConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]introspectionEntry
+}
+
+// introspectionEntry is a cached introspection result. A nil info marks an
+// inactive token.
+type introspectionEntry struct {
+	info     *AuthInfo
+	audience []string
+	expires  time.Time
+}
+
+// introspectionResponse holds the standard members of an introspection
+// response used by the authenticator.
+type introspectionResponse struct {
+	Active   bool     `json:"active"`
+	Sub      string   `json:"sub"`
+	Username string   `json:"username"`
+	ClientID string   `json:"client_id"`
+	Aud      audience `json:"aud"`
+	Exp      float64  `json:"exp"`
+}
+
+// NewIntrospectionAuthenticator creates a new token introspection
+// authenticator.
+func NewIntrospectionAuthenticator(
+	config IntrospectionConfig,
+) (*IntrospectionAuthenticator, error) {
+	if config.URL == "" || config.ClientID == "" {
+		return nil, fmt.Errorf(
+			"introspection auth: endpoint URL and client ID must not be empty",
+		)
+	}
+
+	return &IntrospectionAuthenticator{
+		config: config,
+		client: &http.Client{Timeout: httpClientTimeout},
+		cache:  make(map[[sha256.Size]byte]introspectionEntry),
+	}, nil
+}
+
+// Authenticate extracts a Bearer token from the Authorization header and
+// introspects it. Inactive tokens return an error wrapping
+// ErrInvalidToken and ErrTokenInactive.
+func (a *IntrospectionAuthenticator) Authenticate(
+	r *http.Request,
+) (*AuthInfo, error) {
+	token, ok := bearerToken(r)
+	if !ok || (a.config.OpaqueOnly && isJWT(token)) {
+		return nil, ErrUnauthenticated
+	}
+
+	now := time.Now()
+	key := sha256.Sum256([]byte(token))
+
+	entry, ok := a.cached(key, now)
+	if !ok {
+		var err error
+		entry, err = a.introspect(r.Context(), token, now)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+		}
+		a.store(key, entry, now)
+	}
+
+	if entry.info == nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, ErrTokenInactive)
+	}
+
+	if a.config.Audience != "" && !containsAudience(entry.audience, a.config.Audience) {
+		return nil, fmt.Errorf(
+			"%w: token audience %v does not contain %s",
+			ErrInvalidToken, entry.audience, a.config.Audience,
+		)
+	}
+
+	// Callers get their own copy of the cached identity.
+	info := *entry.info
+	return &info, nil
+}
+
+// Method returns the authentication method type.
+func (a *IntrospectionAuthenticator) Method() AuthMethod {
+	return AuthMethodIntrospection
+}
+
+// introspect asks the introspection endpoint about token and returns the
+// result to cache.
+func (a *IntrospectionAuthenticator) introspect(
+	ctx context.Context,
+	token string,
+	now time.Time,
+) (introspectionEntry, error) {
+	body, err := a.post(ctx, token)
+	if err != nil {
+		return introspectionEntry{}, err
+	}
+
+	var resp introspectionResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return introspectionEntry{}, fmt.Errorf("decoding introspection response: %w", err)
+	}
+
+	expiry := time.Time{}
+	if resp.Exp > 0 {
+		expiry = time.Unix(int64(resp.Exp), 0)
+	}
+
+	if !resp.Active || (!expiry.IsZero() && !now.Before(expiry)) {
+		return introspectionEntry{expires: now.Add(a.config.NegativeCacheTTL)}, nil
+	}
+
+	var claims map[string]any
+	if err := json.Unmarshal(body, &claims); err != nil {
+		return introspectionEntry{}, fmt.Errorf("decoding introspection response: %w", err)
+	}
+	delete(claims, "active")
+
+	subject := resp.Sub
+	if subject == "" {
+		subject = resp.Username
+	}
+	if subject == "" {
+		subject = resp.ClientID
+	}
+
+	expires := now.Add(a.config.CacheTTL)
+	if !expiry.IsZero() && expiry.Before(expires) {
+		expires = expiry
+	}
+
+	return introspectionEntry{
+		info: &AuthInfo{
+			Method:  AuthMethodIntrospection,
+			Subject: subject,
+			Claims:  claims,
+			Expiry:  expiry,
+		},
+		audience: []string(resp.Aud),
+		expires:  expires,
+	}, nil
+}
+
+// post sends the introspection request for token and returns the response
+// body.
+func (a *IntrospectionAuthenticator) post(ctx context.Context, token string) ([]byte, error) {
+	form := url.Values{
+		"token":           {token},
+		"token_type_hint": {"access_token"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.config.URL,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("creating introspection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// RFC 6749 section 2.3.1: client credentials are form-encoded before
+	// being used as Basic credentials.
+	req.SetBasicAuth(url.QueryEscape(a.config.ClientID), url.QueryEscape(a.config.ClientSecret))
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("introspection request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection endpoint returned status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIntrospectionResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("reading introspection response: %w", err)
+	}
+
+	return body, nil
+}
+
+// cached returns the cached result for key, if any.
+func (a *IntrospectionAuthenticator) cached(
+	key [sha256.Size]byte,
+	now time.Time,
+) (introspectionEntry, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry, ok := a.cache[key]
+	if !ok || !now.Before(entry.expires) {
+		return introspectionEntry{}, false
+	}
+	return entry, true
+}
+
+// store caches entry for key, dropping expired entries when the cache is
+// full.
+func (a *IntrospectionAuthenticator) store(
+	key [sha256.Size]byte,
+	entry introspectionEntry,
+	now time.Time,
+) {
+	if !now.Before(entry.expires) {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.cache) >= maxIntrospectionCacheEntries {
+		for k, e := range a.cache {
+			if !now.Before(e.expires) {
+				delete(a.cache, k)
+			}
+		}
+		if len(a.cache) >= maxIntrospectionCacheEntries {
+			return
+		}
+	}
+	a.cache[key] = entry
+}
+
+// isJWT reports whether token looks like a compact JWS.
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
@@ -0,0 +1,394 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+)
+
+// stubIntrospection is an RFC 7662 introspection endpoint serving canned
+// responses per token and counting requests.
+type stubIntrospection struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses map[string]map[string]any
+	requests  int
+}
+
+func newStubIntrospection(t *testing.T, responses map[string]map[string]any) *stubIntrospection {
+	t.Helper()
+
+	stub := &stubIntrospection{responses: responses}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		stub.requests++
+		stub.mu.Unlock()
+
+		// Client credentials are form-encoded (RFC 6749 section 2.3.1).
+		id, secret, ok := r.BasicAuth()
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+		if r.Method != http.MethodPost || !ok || id != "restapi" || secret != "s3cr%t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.PostFormValue("token_type_hint") != "access_token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		resp, ok := responses[r.PostFormValue("token")]
+		if !ok {
+			resp = map[string]any{"active": false}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(stub.Close)
+
+	return stub
+}
+
+func (s *stubIntrospection) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func newTestIntrospector(t *testing.T, config auth.IntrospectionConfig) *auth.IntrospectionAuthenticator {
+	t.Helper()
+
+	config.ClientID = "restapi"
+	config.ClientSecret = "s3cr%t"
+
+	a, err := auth.NewIntrospectionAuthenticator(config)
+	if err != nil {
+		t.Fatalf("NewIntrospectionAuthenticator() error = %v", err)
+	}
+	return a
+}
+
+func bearerRequest(token string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func TestNewIntrospectionAuthenticator_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config auth.IntrospectionConfig
+	}{
+		{name: "missing URL", config: auth.IntrospectionConfig{ClientID: "restapi"}},
+		{name: "missing client ID", config: auth.IntrospectionConfig{URL: "http://idp/introspect"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			a, err := auth.NewIntrospectionAuthenticator(tt.config)
+
+			// Assert
+			if err == nil {
+				t.Fatal("NewIntrospectionAuthenticator() error = nil, want error")
+			}
+			if a != nil {
+				t.Error("NewIntrospectionAuthenticator() returned non-nil authenticator on error")
+			}
+		})
+	}
+}
+
+func TestIntrospectionAuthenticator_Authenticate(t *testing.T) {
+	t.Parallel()
+
+	exp := time.Now().Add(time.Hour).Unix()
+	stub := newStubIntrospection(t, map[string]map[string]any{
+		"user-token": {
+			"active":    true,
+			"sub":       "user-1",
+			"username":  "alice",
+			"client_id": "web",
+			"scope":     "items:read items:write",
+			"aud":       []string{"restapi", "other"},
+			"exp":       exp,
+		},
+		"service-token":  {"active": true, "client_id": "batch", "aud": "restapi"},
+		"username-token": {"active": true, "username": "bob", "aud": "restapi"},
+		"other-audience": {"active": true, "sub": "user-2", "aud": "other"},
+		"expired-token": {
+			"active": true,
+			"sub":    "user-3",
+			"exp":    time.Now().Add(-time.Minute).Unix(),
+		},
+	})
+
+	tests := []struct {
+		name        string
+		token       string
+		header      string
+		wantSubject string
+		wantErr     error
+	}{
+		{name: "user token", token: "user-token", wantSubject: "user-1"},
+		{name: "subject falls back to username", token: "username-token", wantSubject: "bob"},
+		{name: "subject falls back to client ID", token: "service-token", wantSubject: "batch"},
+		{name: "inactive token", token: "revoked-token", wantErr: auth.ErrTokenInactive},
+		{name: "expired token", token: "expired-token", wantErr: auth.ErrTokenInactive},
+		{name: "other audience", token: "other-audience", wantErr: auth.ErrInvalidToken},
+		{name: "no Authorization header", wantErr: auth.ErrUnauthenticated},
+		{name: "Basic credentials", header: "Basic dXNlcjpwYXNz", wantErr: auth.ErrUnauthenticated},
+	}
+
+	a := newTestIntrospector(t, auth.IntrospectionConfig{URL: stub.URL, Audience: "restapi"})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			req := bearerRequest(tt.token)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			// Act
+			info, err := a.Authenticate(req)
+
+			// Assert
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Authenticate() error = %v, want %v", err, tt.wantErr)
+				}
+				if tt.wantErr != auth.ErrUnauthenticated && !errors.Is(err, auth.ErrInvalidToken) {
+					t.Errorf("Authenticate() error = %v, want it to wrap ErrInvalidToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if info.Method != auth.AuthMethodIntrospection {
+				t.Errorf("Method = %q, want %q", info.Method, auth.AuthMethodIntrospection)
+			}
+			if info.Subject != tt.wantSubject {
+				t.Errorf("Subject = %q, want %q", info.Subject, tt.wantSubject)
+			}
+		})
+	}
+}
+
+func TestIntrospectionAuthenticator_Claims(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	exp := time.Now().Add(time.Hour).Unix()
+	stub := newStubIntrospection(t, map[string]map[string]any{
+		"token": {
+			"active":   true,
+			"sub":      "user-1",
+			"scope":    "items:read",
+			"groups":   []string{"editors"},
+			"exp":      exp,
+			"tenant":   "acme",
+			"username": "alice",
+		},
+	})
+	a := newTestIntrospector(t, auth.IntrospectionConfig{URL: stub.URL})
+
+	// Act
+	info, err := a.Authenticate(bearerRequest("token"))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if !info.Expiry.Equal(time.Unix(exp, 0)) {
+		t.Errorf("Expiry = %v, want %v", info.Expiry, time.Unix(exp, 0))
+	}
+	for key, want := range map[string]string{"scope": "items:read", "tenant": "acme", "username": "alice"} {
+		if got := info.Claims[key]; got != want {
+			t.Errorf("Claims[%q] = %v, want %q", key, got, want)
+		}
+	}
+	if groups, ok := info.Claims["groups"].([]any); !ok || len(groups) != 1 || groups[0] != "editors" {
+		t.Errorf("Claims[groups] = %v, want [editors]", info.Claims["groups"])
+	}
+	if _, ok := info.Claims["active"]; ok {
+		t.Error("Claims should not contain active")
+	}
+}
+
+func TestIntrospectionAuthenticator_Cache(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		token        string
+		config       auth.IntrospectionConfig
+		wantRequests int
+	}{
+		{
+			name:         "active token is cached",
+			token:        "active",
+			config:       auth.IntrospectionConfig{CacheTTL: time.Minute},
+			wantRequests: 1,
+		},
+		{
+			name:         "inactive token is cached",
+			token:        "inactive",
+			config:       auth.IntrospectionConfig{NegativeCacheTTL: time.Minute},
+			wantRequests: 1,
+		},
+		{
+			name:         "caching disabled",
+			token:        "active",
+			config:       auth.IntrospectionConfig{NegativeCacheTTL: time.Minute},
+			wantRequests: 3,
+		},
+		{
+			name:         "negative caching disabled",
+			token:        "inactive",
+			config:       auth.IntrospectionConfig{CacheTTL: time.Minute},
+			wantRequests: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			stub := newStubIntrospection(t, map[string]map[string]any{
+				"active": {"active": true, "sub": "user-1"},
+			})
+			config := tt.config
+			config.URL = stub.URL
+			a := newTestIntrospector(t, config)
+
+			// Act
+			for range 3 {
+				_, _ = a.Authenticate(bearerRequest(tt.token))
+			}
+
+			// Assert
+			if got := stub.requestCount(); got != tt.wantRequests {
+				t.Errorf("introspection requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestIntrospectionAuthenticator_CacheExpiry(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	exp := time.Now().Add(2 * time.Second).Truncate(time.Second)
+	stub := newStubIntrospection(t, map[string]map[string]any{
+		"token": {"active": true, "sub": "user-1", "exp": exp.Unix()},
+	})
+	a := newTestIntrospector(t, auth.IntrospectionConfig{URL: stub.URL, CacheTTL: time.Hour})
+
+	// Act
+	_, errBefore := a.Authenticate(bearerRequest("token"))
+	_, errCached := a.Authenticate(bearerRequest("token"))
+	time.Sleep(time.Until(exp) + 10*time.Millisecond)
+	_, errAfter := a.Authenticate(bearerRequest("token"))
+
+	// Assert
+	if errBefore != nil || errCached != nil {
+		t.Fatalf("Authenticate() before expiry errors = %v, %v, want nil", errBefore, errCached)
+	}
+	if !errors.Is(errAfter, auth.ErrTokenInactive) {
+		t.Errorf("Authenticate() after expiry error = %v, want ErrTokenInactive", errAfter)
+	}
+	if got := stub.requestCount(); got != 2 {
+		t.Errorf("introspection requests = %d, want 2", got)
+	}
+}
+
+func TestIntrospectionAuthenticator_EndpointErrors(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	var requests int
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	a := newTestIntrospector(t, auth.IntrospectionConfig{URL: server.URL})
+
+	// Act
+	_, err1 := a.Authenticate(bearerRequest("token"))
+	_, err2 := a.Authenticate(bearerRequest("token"))
+
+	// Assert
+	for _, err := range []error{err1, err2} {
+		if !errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrTokenInactive) {
+			t.Errorf("Authenticate() error = %v, want ErrInvalidToken without ErrTokenInactive", err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 2 {
+		t.Errorf("introspection requests = %d, want 2 (failures are not cached)", requests)
+	}
+}
+
+func TestIntrospectionAuthenticator_OpaqueOnly(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	stub := newStubIntrospection(t, map[string]map[string]any{
+		"opaque": {"active": true, "sub": "user-1"},
+	})
+	a := newTestIntrospector(t, auth.IntrospectionConfig{URL: stub.URL, OpaqueOnly: true})
+	oidc := auth.NewOIDCAuthenticator(&mockTokenVerifier{
+		claims: &auth.TokenClaims{Subject: "jwt-user"},
+	}, "")
+	multi := auth.NewMultiAuthenticator(a, oidc)
+
+	// Act
+	opaqueInfo, opaqueErr := multi.Authenticate(bearerRequest("opaque"))
+	jwtInfo, jwtErr := multi.Authenticate(bearerRequest("eyJhbGciOiJFUzI1NiJ9.eyJzdWIiOiJqd3QtdXNlciJ9.c2ln"))
+
+	// Assert
+	if opaqueErr != nil || opaqueInfo.Method != auth.AuthMethodIntrospection {
+		t.Errorf("opaque token: info = %+v, error = %v, want introspected identity", opaqueInfo, opaqueErr)
+	}
+	if jwtErr != nil || jwtInfo.Method != auth.AuthMethodOIDC {
+		t.Errorf("JWT: info = %+v, error = %v, want OIDC identity", jwtInfo, jwtErr)
+	}
+	if got := stub.requestCount(); got != 1 {
+		t.Errorf("introspection requests = %d, want 1", got)
+	}
+}
+
+func TestIntrospectionAuthenticator_Method(t *testing.T) {
+	t.Parallel()
+
+	a := newTestIntrospector(t, auth.IntrospectionConfig{URL: "http://idp/introspect"})
+
+	if a.Method() != auth.AuthMethodIntrospection {
+		t.Errorf("Method() = %q, want %q", a.Method(), auth.AuthMethodIntrospection)
+	}
+}
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+)
+
+// maxLockoutEntries bounds the subjects and the clients tracked by a
+// Lockout.
+const maxLockoutEntries = 10000
+
+// ErrLockedOut is matched by LockoutError.
+var ErrLockedOut = errors.New("too many failed authentication attempts")
+
+// LockoutError is returned while the subject or the client of a request is
+// locked out after repeated failed authentication attempts.
+type LockoutError struct {
+	// RetryAfter is how long the lockout lasts.
+	RetryAfter time.Duration
+}
+
+// Error implements error.
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%v, retry after %v", ErrLockedOut, e.RetryAfter.Round(time.Second))
+}
+
+// Is reports whether target is ErrLockedOut.
+func (e *LockoutError) Is(target error) bool {
+	return target == ErrLockedOut
+}
+
+// LockoutConfig configures a Lockout.
+type LockoutConfig struct {
+	// SubjectMaxFailures is the number of consecutive failed attempts for a
+	// subject (a user name or API key ID) after which it is locked out;
+	// zero disables subject lockouts.
+	SubjectMaxFailures int
+	// ClientMaxFailures is the number of failed attempts from a client IP
+	// address after which it is locked out; zero disables client lockouts.
+	ClientMaxFailures int
+	// BaseDelay is the first lockout. Each further failure after a lockout
+	// doubles it, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// ResetAfter is how long after the last failure the failures of a
+	// subject or client are forgotten.
+	ResetAfter time.Duration
+}
+
+// Lockout tracks failed authentication attempts per subject and per client
+// IP address and locks them out with exponential backoff. A nil Lockout
+// never locks out. The client IP address is the host of the request's
+// RemoteAddr.
+type Lockout struct {
+	config LockoutConfig
+	method AuthMethod
+
+	mu       sync.Mutex
+	subjects map[string]*lockoutEntry
+	clients  map[string]*lockoutEntry
+}
+
+// lockoutEntry holds the failed attempts of a subject or client.
+type lockoutEntry struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+	// unlock ends the lockout in the auth_lockouts_active metric.
+	unlock *time.Timer
+}
+
+// NewLockout creates a Lockout for an authenticator of the given method.
+func NewLockout(config LockoutConfig, method AuthMethod) *Lockout {
+	return &Lockout{
+		config:   config,
+		method:   method,
+		subjects: make(map[string]*lockoutEntry),
+		clients:  make(map[string]*lockoutEntry),
+	}
+}
+
+// Check returns a LockoutError if the subject or the client of r is locked
+// out.
+func (l *Lockout) Check(r *http.Request, subject string) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	var retryAfter time.Duration
+	if entry, ok := l.subjects[subject]; ok {
+		retryAfter = entry.lockedUntil.Sub(now)
+	}
+	if entry, ok := l.clients[clientIP(r)]; ok {
+		retryAfter = max(retryAfter, entry.lockedUntil.Sub(now))
+	}
+
+	if retryAfter > 0 {
+		return &LockoutError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// Failure records a failed attempt for the client of r and, unless subject
+// is empty, for subject.
+func (l *Lockout) Failure(r *http.Request, subject string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if subject != "" && l.config.SubjectMaxFailures > 0 {
+		l.fail(l.subjects, subject, l.config.SubjectMaxFailures, observability.ScopeSubject, now)
+	}
+	if l.config.ClientMaxFailures > 0 {
+		l.fail(l.clients, clientIP(r), l.config.ClientMaxFailures, observability.ScopeClient, now)
+	}
+}
+
+// Success forgets the failed attempts of subject. Failed attempts of the
+// client are kept, so that one valid credential does not allow a client to
+// keep guessing others.
+func (l *Lockout) Success(subject string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.subjects, subject)
+}
+
+// fail records a failed attempt for key in entries and locks it out once
+// it reached maxFailures failures.
+func (l *Lockout) fail(
+	entries map[string]*lockoutEntry,
+	key string,
+	maxFailures int,
+	scope string,
+	now time.Time,
+) {
+	entry, ok := entries[key]
+	if !ok {
+		if len(entries) >= maxLockoutEntries {
+			l.prune(entries, now)
+			if len(entries) >= maxLockoutEntries {
+				return
+			}
+		}
+		entry = &lockoutEntry{}
+		entries[key] = entry
+	}
+
+	if now.Sub(entry.lastFailure) > l.config.ResetAfter && !now.Before(entry.lockedUntil) {
+		entry.failures = 0
+	}
+	entry.failures++
+	entry.lastFailure = now
+	if entry.failures < maxFailures {
+		return
+	}
+
+	delay := l.delay(entry.failures - maxFailures)
+	entry.lockedUntil = now.Add(delay)
+
+	// A lockout extended before its timer fired is still counted once.
+	if entry.unlock != nil && entry.unlock.Stop() {
+		entry.unlock.Reset(delay)
+		return
+	}
+	active := observability.AuthLockoutsActive.WithLabelValues(string(l.method), scope)
+	active.Inc()
+	entry.unlock = time.AfterFunc(delay, active.Dec)
+}
+
+// delay returns the lockout after the given number of failures beyond the
+// threshold: BaseDelay doubled for each, up to MaxDelay.
+func (l *Lockout) delay(extra int) time.Duration {
+	delay := l.config.BaseDelay
+	for range extra {
+		if delay >= l.config.MaxDelay/2 {
+			return l.config.MaxDelay
+		}
+		delay *= 2
+	}
+	return min(delay, l.config.MaxDelay)
+}
+
+// prune drops the entries that are neither locked out nor have recent
+// failures.
+func (l *Lockout) prune(entries map[string]*lockoutEntry, now time.Time) {
+	for key, entry := range entries {
+		if !now.Before(entry.lockedUntil) && now.Sub(entry.lastFailure) > l.config.ResetAfter {
+			delete(entries, key)
+		}
+	}
+}
+
+// clientIP returns the IP address of the client of r.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
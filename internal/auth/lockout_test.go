@@ -0,0 +1,261 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+)
+
+// newLockedBasicAuthenticator returns a Basic authenticator for alice with
+// the given lockout.
+func newLockedBasicAuthenticator(t *testing.T, config auth.LockoutConfig) *auth.BasicAuthenticator {
+	t.Helper()
+
+	authenticator, err := auth.NewBasicAuthenticator("alice:" + generateBcryptHash(t, "secret"))
+	if err != nil {
+		t.Fatalf("NewBasicAuthenticator() error = %v", err)
+	}
+	authenticator.SetLockout(auth.NewLockout(config, auth.AuthMethodBasic))
+	return authenticator
+}
+
+// fromClient returns r as sent from the client IP address ip.
+func fromClient(r *http.Request, ip string) *http.Request {
+	r.RemoteAddr = ip + ":40000"
+	return r
+}
+
+// lockoutRetryAfter returns how long the error of an attempt says to wait,
+// failing the test unless it is a LockoutError.
+func lockoutRetryAfter(t *testing.T, err error) time.Duration {
+	t.Helper()
+
+	var lockout *auth.LockoutError
+	if !errors.As(err, &lockout) || !errors.Is(err, auth.ErrLockedOut) {
+		t.Fatalf("Authenticate() error = %v, want %v", err, auth.ErrLockedOut)
+	}
+	return lockout.RetryAfter
+}
+
+func TestLockout_BasicSubject(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	authenticator := newLockedBasicAuthenticator(t, auth.LockoutConfig{
+		SubjectMaxFailures: 3,
+		BaseDelay:          time.Minute,
+		MaxDelay:           time.Hour,
+		ResetAfter:         time.Hour,
+	})
+
+	// Act
+	for range 3 {
+		_, err := authenticator.Authenticate(basicRequest("alice", "wrong"))
+		if !errors.Is(err, auth.ErrInvalidCredentials) {
+			t.Fatalf("Authenticate() error = %v, want %v", err, auth.ErrInvalidCredentials)
+		}
+	}
+	_, lockedErr := authenticator.Authenticate(basicRequest("alice", "secret"))
+	_, otherErr := authenticator.Authenticate(basicRequest("bob", "secret"))
+
+	// Assert
+	if retryAfter := lockoutRetryAfter(t, lockedErr); retryAfter <= 59*time.Second || retryAfter > time.Minute {
+		t.Errorf("RetryAfter = %v, want about 1m", retryAfter)
+	}
+	if !errors.Is(otherErr, auth.ErrInvalidCredentials) {
+		t.Errorf("Authenticate() other user error = %v, want %v", otherErr, auth.ErrInvalidCredentials)
+	}
+}
+
+func TestLockout_BasicSuccessResetsSubject(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	authenticator := newLockedBasicAuthenticator(t, auth.LockoutConfig{
+		SubjectMaxFailures: 2,
+		BaseDelay:          time.Minute,
+		MaxDelay:           time.Hour,
+		ResetAfter:         time.Hour,
+	})
+
+	// Act
+	_, _ = authenticator.Authenticate(basicRequest("alice", "wrong"))
+	if _, err := authenticator.Authenticate(basicRequest("alice", "secret")); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	_, _ = authenticator.Authenticate(basicRequest("alice", "wrong"))
+	_, err := authenticator.Authenticate(basicRequest("alice", "secret"))
+
+	// Assert
+	if err != nil {
+		t.Errorf("Authenticate() after a success and one failure error = %v, want nil", err)
+	}
+}
+
+func TestLockout_BasicClient(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	authenticator := newLockedBasicAuthenticator(t, auth.LockoutConfig{
+		ClientMaxFailures: 3,
+		BaseDelay:         time.Minute,
+		MaxDelay:          time.Hour,
+		ResetAfter:        time.Hour,
+	})
+
+	// Act
+	for _, username := range []string{"alice", "bob", "carol"} {
+		_, _ = authenticator.Authenticate(fromClient(basicRequest(username, "wrong"), "198.51.100.7"))
+	}
+	_, lockedErr := authenticator.Authenticate(fromClient(basicRequest("alice", "secret"), "198.51.100.7"))
+	_, otherErr := authenticator.Authenticate(fromClient(basicRequest("alice", "secret"), "198.51.100.8"))
+
+	// Assert
+	lockoutRetryAfter(t, lockedErr)
+	if otherErr != nil {
+		t.Errorf("Authenticate() from another client error = %v, want nil", otherErr)
+	}
+}
+
+func TestLockout_ExponentialBackoff(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	const base = 100 * time.Millisecond
+	authenticator := newLockedBasicAuthenticator(t, auth.LockoutConfig{
+		SubjectMaxFailures: 2,
+		BaseDelay:          base,
+		MaxDelay:           4 * base,
+		ResetAfter:         time.Hour,
+	})
+	_, _ = authenticator.Authenticate(basicRequest("alice", "wrong"))
+
+	for _, want := range []time.Duration{base, 2 * base, 4 * base, 4 * base} {
+		// Act
+		_, _ = authenticator.Authenticate(basicRequest("alice", "wrong"))
+		_, err := authenticator.Authenticate(basicRequest("alice", "secret"))
+
+		// Assert
+		if retryAfter := lockoutRetryAfter(t, err); retryAfter <= want/2 || retryAfter > want {
+			t.Fatalf("RetryAfter = %v, want about %v", retryAfter, want)
+		}
+		time.Sleep(want)
+	}
+
+	if _, err := authenticator.Authenticate(basicRequest("alice", "secret")); err != nil {
+		t.Errorf("Authenticate() after the lockout error = %v, want nil", err)
+	}
+}
+
+func TestLockout_ResetAfter(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	authenticator := newLockedBasicAuthenticator(t, auth.LockoutConfig{
+		SubjectMaxFailures: 2,
+		BaseDelay:          time.Minute,
+		MaxDelay:           time.Hour,
+		ResetAfter:         50 * time.Millisecond,
+	})
+
+	// Act
+	_, _ = authenticator.Authenticate(basicRequest("alice", "wrong"))
+	time.Sleep(100 * time.Millisecond)
+	_, _ = authenticator.Authenticate(basicRequest("alice", "wrong"))
+	_, err := authenticator.Authenticate(basicRequest("alice", "secret"))
+
+	// Assert
+	if err != nil {
+		t.Errorf("Authenticate() error = %v, want nil after the failures were reset", err)
+	}
+}
+
+func TestLockout_APIKey(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	authenticator, err := auth.NewAPIKeyStoreAuthenticator("static-key:service-static", auth.NewMemoryAPIKeyStore())
+	if err != nil {
+		t.Fatalf("NewAPIKeyStoreAuthenticator() error = %v", err)
+	}
+	authenticator.SetLockout(auth.NewLockout(auth.LockoutConfig{
+		SubjectMaxFailures: 2,
+		ClientMaxFailures:  4,
+		BaseDelay:          time.Minute,
+		MaxDelay:           time.Hour,
+		ResetAfter:         time.Hour,
+	}, auth.AuthMethodAPIKey))
+	secret, key, err := authenticator.CreateKey(ctx, "ci-pipeline", nil, 0)
+	if err != nil {
+		t.Fatalf("CreateKey() error = %v", err)
+	}
+
+	// Act
+	for range 2 {
+		_, _ = authenticator.Authenticate(apiKeyRequest(key.ID + ".wrong-secret"))
+	}
+	_, keyErr := authenticator.Authenticate(apiKeyRequest(secret))
+	_, staticErr := authenticator.Authenticate(apiKeyRequest("static-key"))
+	for range 2 {
+		_, _ = authenticator.Authenticate(apiKeyRequest("wrong-static-key"))
+	}
+	_, clientErr := authenticator.Authenticate(apiKeyRequest("static-key"))
+
+	// Assert
+	lockoutRetryAfter(t, keyErr)
+	if staticErr != nil {
+		t.Errorf("Authenticate() static key error = %v, want nil", staticErr)
+	}
+	lockoutRetryAfter(t, clientErr)
+}
+
+func TestLockout_ActiveMetric(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	const method auth.AuthMethod = "lockout-metric-test"
+	lockout := auth.NewLockout(auth.LockoutConfig{
+		SubjectMaxFailures: 1,
+		ClientMaxFailures:  1,
+		BaseDelay:          50 * time.Millisecond,
+		MaxDelay:           time.Second,
+		ResetAfter:         time.Hour,
+	}, method)
+	subjects := observability.AuthLockoutsActive.WithLabelValues(string(method), observability.ScopeSubject)
+	clients := observability.AuthLockoutsActive.WithLabelValues(string(method), observability.ScopeClient)
+
+	// Act
+	lockout.Failure(basicRequest("alice", "wrong"), "alice")
+	lockout.Failure(basicRequest("alice", "wrong"), "alice")
+
+	// Assert
+	if got := testutil.ToFloat64(subjects); got != 1 {
+		t.Errorf("auth_lockouts_active{scope=subject} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(clients); got != 1 {
+		t.Errorf("auth_lockouts_active{scope=client} = %v, want 1", got)
+	}
+	waitFor(t, func() bool {
+		return testutil.ToFloat64(subjects) == 0 && testutil.ToFloat64(clients) == 0
+	})
+}
+
+func TestLockout_Nil(t *testing.T) {
+	t.Parallel()
+
+	var lockout *auth.Lockout
+	lockout.Failure(basicRequest("alice", "wrong"), "alice")
+	lockout.Success("alice")
+
+	if err := lockout.Check(basicRequest("alice", "wrong"), "alice"); err != nil {
+		t.Errorf("Check() error = %v, want nil", err)
+	}
+}
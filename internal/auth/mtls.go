@@ -1,17 +1,77 @@
 package auth
 
-import "net/http"
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+)
+
+// Subject sources for MTLSConfig.SubjectSource.
+const (
+	// MTLSSubjectCN uses the subject common name (the default).
+	MTLSSubjectCN = "cn"
+	// MTLSSubjectURI uses the first URI subject alternative name.
+	MTLSSubjectURI = "uri"
+	// MTLSSubjectEmail uses the first email subject alternative name.
+	MTLSSubjectEmail = "email"
+	// MTLSSubjectSPIFFE uses the SPIFFE ID (a spiffe:// URI SAN).
+	MTLSSubjectSPIFFE = "spiffe"
+)
+
+// spiffeScheme is the URI scheme of SPIFFE IDs.
+const spiffeScheme = "spiffe"
+
+// MTLSConfig configures how a client certificate is mapped to an identity.
+// The zero value uses the common name and allows every verified certificate.
+type MTLSConfig struct {
+	// SubjectSource selects the AuthInfo subject: cn, uri, email or spiffe.
+	SubjectSource string
+
+	// The allow-list rules below are all applied when set.
+
+	// TrustDomains requires a SPIFFE ID in one of these trust domains.
+	TrustDomains []string
+	// AllowedOUs requires one of these organizational units.
+	AllowedOUs []string
+	// AllowedSANs requires a DNS, URI, email or IP SAN matching one of these
+	// path.Match patterns, e.g. "*.example.com" or "spiffe://example.org/ns/*/sa/*".
+	AllowedSANs []string
+}
 
 // MTLSAuthenticator authenticates requests using mutual TLS client certificates.
-type MTLSAuthenticator struct{}
+type MTLSAuthenticator struct {
+	config MTLSConfig
+}
 
 // NewMTLSAuthenticator creates a new mTLS authenticator.
-func NewMTLSAuthenticator() *MTLSAuthenticator {
-	return &MTLSAuthenticator{}
+func NewMTLSAuthenticator(config MTLSConfig) (*MTLSAuthenticator, error) {
+	switch config.SubjectSource {
+	case "":
+		config.SubjectSource = MTLSSubjectCN
+	case MTLSSubjectCN, MTLSSubjectURI, MTLSSubjectEmail, MTLSSubjectSPIFFE:
+	default:
+		return nil, fmt.Errorf(
+			"mtls: unknown subject source %q, expected one of: cn, uri, email, spiffe",
+			config.SubjectSource,
+		)
+	}
+
+	for _, pattern := range config.AllowedSANs {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("mtls: invalid SAN pattern %q: %w", pattern, err)
+		}
+	}
+
+	return &MTLSAuthenticator{config: config}, nil
 }
 
 // Authenticate validates the client certificate from the TLS connection
-// and extracts identity information from the certificate subject.
+// against the allow-list rules and extracts identity information from it.
+// Rejected certificates return an error wrapping ErrInvalidCert.
 func (a *MTLSAuthenticator) Authenticate(r *http.Request) (*AuthInfo, error) {
 	if r.TLS == nil {
 		return nil, ErrUnauthenticated
@@ -22,19 +82,26 @@ func (a *MTLSAuthenticator) Authenticate(r *http.Request) (*AuthInfo, error) {
 	}
 
 	cert := r.TLS.PeerCertificates[0]
-	cn := cert.Subject.CommonName
+	spiffeID, trustDomain := spiffeID(cert)
 
-	claims := make(map[string]any)
-	if len(cert.Subject.Organization) > 0 {
-		claims["organizations"] = cert.Subject.Organization
+	if err := a.allow(cert, trustDomain); err != nil {
+		return nil, err
 	}
-	if len(cert.DNSNames) > 0 {
-		claims["dns_names"] = cert.DNSNames
+
+	subject, err := a.subject(cert, spiffeID)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := certClaims(cert)
+	if spiffeID != "" {
+		claims["spiffe_id"] = spiffeID
+		claims["trust_domain"] = trustDomain
 	}
 
 	return &AuthInfo{
 		Method:  AuthMethodMTLS,
-		Subject: cn,
+		Subject: subject,
 		Claims:  claims,
 	}, nil
 }
@@ -43,3 +110,124 @@ func (a *MTLSAuthenticator) Authenticate(r *http.Request) (*AuthInfo, error) {
 func (a *MTLSAuthenticator) Method() AuthMethod {
 	return AuthMethodMTLS
 }
+
+// subject returns the identity of cert according to the subject source.
+func (a *MTLSAuthenticator) subject(cert *x509.Certificate, spiffeID string) (string, error) {
+	switch a.config.SubjectSource {
+	case MTLSSubjectURI:
+		if len(cert.URIs) == 0 {
+			return "", fmt.Errorf("%w: no URI subject alternative name", ErrInvalidCert)
+		}
+		return cert.URIs[0].String(), nil
+	case MTLSSubjectEmail:
+		if len(cert.EmailAddresses) == 0 {
+			return "", fmt.Errorf("%w: no email subject alternative name", ErrInvalidCert)
+		}
+		return cert.EmailAddresses[0], nil
+	case MTLSSubjectSPIFFE:
+		if spiffeID == "" {
+			return "", fmt.Errorf("%w: no SPIFFE ID", ErrInvalidCert)
+		}
+		return spiffeID, nil
+	default:
+		return cert.Subject.CommonName, nil
+	}
+}
+
+// allow applies the allow-list rules to cert.
+func (a *MTLSAuthenticator) allow(cert *x509.Certificate, trustDomain string) error {
+	if len(a.config.TrustDomains) > 0 && !slices.Contains(a.config.TrustDomains, trustDomain) {
+		if trustDomain == "" {
+			return fmt.Errorf("%w: no SPIFFE ID", ErrInvalidCert)
+		}
+		return fmt.Errorf("%w: trust domain %q is not allowed", ErrInvalidCert, trustDomain)
+	}
+
+	if len(a.config.AllowedOUs) > 0 &&
+		!slices.ContainsFunc(cert.Subject.OrganizationalUnit, func(ou string) bool {
+			return slices.Contains(a.config.AllowedOUs, ou)
+		}) {
+		return fmt.Errorf("%w: organizational unit is not allowed", ErrInvalidCert)
+	}
+
+	if len(a.config.AllowedSANs) > 0 && !slices.ContainsFunc(sans(cert), a.sanAllowed) {
+		return fmt.Errorf("%w: subject alternative names are not allowed", ErrInvalidCert)
+	}
+
+	return nil
+}
+
+// sanAllowed reports whether san matches one of the allowed SAN patterns.
+func (a *MTLSAuthenticator) sanAllowed(san string) bool {
+	for _, pattern := range a.config.AllowedSANs {
+		// Patterns are validated by NewMTLSAuthenticator.
+		if ok, _ := path.Match(pattern, san); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// certClaims returns the subject, SANs, serial number and fingerprint of
+// cert. Empty attributes are omitted.
+func certClaims(cert *x509.Certificate) map[string]any {
+	claims := make(map[string]any)
+	if len(cert.Subject.Organization) > 0 {
+		claims["organizations"] = cert.Subject.Organization
+	}
+	if len(cert.Subject.OrganizationalUnit) > 0 {
+		claims["organizational_units"] = cert.Subject.OrganizationalUnit
+	}
+	if len(cert.DNSNames) > 0 {
+		claims["dns_names"] = cert.DNSNames
+	}
+	if len(cert.URIs) > 0 {
+		uris := make([]string, 0, len(cert.URIs))
+		for _, uri := range cert.URIs {
+			uris = append(uris, uri.String())
+		}
+		claims["uris"] = uris
+	}
+	if len(cert.EmailAddresses) > 0 {
+		claims["email_addresses"] = cert.EmailAddresses
+	}
+	if len(cert.IPAddresses) > 0 {
+		ips := make([]string, 0, len(cert.IPAddresses))
+		for _, ip := range cert.IPAddresses {
+			ips = append(ips, ip.String())
+		}
+		claims["ip_addresses"] = ips
+	}
+	if cert.SerialNumber != nil {
+		claims["serial"] = cert.SerialNumber.String()
+	}
+	if len(cert.Raw) > 0 {
+		fingerprint := sha256.Sum256(cert.Raw)
+		claims["fingerprint_sha256"] = hex.EncodeToString(fingerprint[:])
+	}
+	return claims
+}
+
+// sans returns all DNS, URI, email and IP subject alternative names of cert.
+func sans(cert *x509.Certificate) []string {
+	names := slices.Clone(cert.DNSNames)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	names = append(names, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	return names
+}
+
+// spiffeID returns the SPIFFE ID of cert and its trust domain, or empty
+// strings if cert has no spiffe:// URI SAN.
+func spiffeID(cert *x509.Certificate) (id, trustDomain string) {
+	for _, uri := range cert.URIs {
+		if uri.Scheme == spiffeScheme && uri.Host != "" {
+			return uri.String(), uri.Host
+		}
+	}
+	return "", ""
+}
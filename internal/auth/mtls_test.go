@@ -1,11 +1,18 @@
 package auth_test
 
 import (
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/vyrodovalexey/restapi-example/internal/auth"
@@ -170,7 +177,10 @@ func TestMTLSAuthenticator_Authenticate(t *testing.T) {
 			t.Parallel()
 
 			// Arrange
-			authenticator := auth.NewMTLSAuthenticator()
+			authenticator, err := auth.NewMTLSAuthenticator(auth.MTLSConfig{})
+			if err != nil {
+				t.Fatalf("NewMTLSAuthenticator() error = %v", err)
+			}
 			req := tt.setupReq()
 
 			// Act
@@ -243,9 +253,340 @@ func assertSliceEqual(t *testing.T, key string, got, want any) {
 func TestMTLSAuthenticator_Method(t *testing.T) {
 	t.Parallel()
 
-	authenticator := auth.NewMTLSAuthenticator()
+	authenticator, err := auth.NewMTLSAuthenticator(auth.MTLSConfig{})
+	if err != nil {
+		t.Fatalf("NewMTLSAuthenticator() error = %v", err)
+	}
 
 	if authenticator.Method() != auth.AuthMethodMTLS {
 		t.Errorf("Method() = %q, want %q", authenticator.Method(), auth.AuthMethodMTLS)
 	}
 }
+
+func TestNewMTLSAuthenticator_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  auth.MTLSConfig
+		wantErr string
+	}{
+		{
+			name:    "unknown subject source",
+			config:  auth.MTLSConfig{SubjectSource: "serial"},
+			wantErr: `unknown subject source "serial"`,
+		},
+		{
+			name:    "invalid SAN pattern",
+			config:  auth.MTLSConfig{AllowedSANs: []string{"*.example.com", "[a-"}},
+			wantErr: `invalid SAN pattern "[a-"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			authenticator, err := auth.NewMTLSAuthenticator(tt.config)
+
+			// Assert
+			if err == nil {
+				t.Fatal("NewMTLSAuthenticator() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewMTLSAuthenticator() error = %q, want it to contain %q", err, tt.wantErr)
+			}
+			if authenticator != nil {
+				t.Error("NewMTLSAuthenticator() returned non-nil authenticator on error")
+			}
+		})
+	}
+}
+
+// createSANCert returns a certificate of the payments workload in the
+// example.org trust domain.
+func createSANCert(t *testing.T) *x509.Certificate {
+	t.Helper()
+
+	return &x509.Certificate{
+		Raw:          []byte("certificate"),
+		SerialNumber: big.NewInt(4711),
+		Subject: pkix.Name{
+			CommonName:         "payments",
+			Organization:       []string{"Example"},
+			OrganizationalUnit: []string{"payments", "billing"},
+		},
+		DNSNames: []string{"payments.example.org"},
+		URIs: []*url.URL{
+			mustParseURL(t, "https://example.org/payments"),
+			mustParseURL(t, "spiffe://example.org/ns/prod/sa/payments"),
+		},
+		EmailAddresses: []string{"payments@example.org"},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.7")},
+	}
+}
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("url.Parse(%q) error = %v", raw, err)
+	}
+	return u
+}
+
+func mtlsRequest(cert *x509.Certificate) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	return req
+}
+
+func TestMTLSAuthenticator_SubjectSource(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		source      string
+		cert        func(t *testing.T) *x509.Certificate
+		wantSubject string
+		wantErr     bool
+	}{
+		{
+			name:        "default uses common name",
+			source:      "",
+			cert:        createSANCert,
+			wantSubject: "payments",
+		},
+		{
+			name:        "cn",
+			source:      auth.MTLSSubjectCN,
+			cert:        createSANCert,
+			wantSubject: "payments",
+		},
+		{
+			name:        "uri uses first URI SAN",
+			source:      auth.MTLSSubjectURI,
+			cert:        createSANCert,
+			wantSubject: "https://example.org/payments",
+		},
+		{
+			name:        "email uses first email SAN",
+			source:      auth.MTLSSubjectEmail,
+			cert:        createSANCert,
+			wantSubject: "payments@example.org",
+		},
+		{
+			name:        "spiffe uses SPIFFE ID",
+			source:      auth.MTLSSubjectSPIFFE,
+			cert:        createSANCert,
+			wantSubject: "spiffe://example.org/ns/prod/sa/payments",
+		},
+		{
+			name:    "uri without URI SAN",
+			source:  auth.MTLSSubjectURI,
+			cert:    func(*testing.T) *x509.Certificate { return createTestCert("client", nil, nil) },
+			wantErr: true,
+		},
+		{
+			name:    "email without email SAN",
+			source:  auth.MTLSSubjectEmail,
+			cert:    func(*testing.T) *x509.Certificate { return createTestCert("client", nil, nil) },
+			wantErr: true,
+		},
+		{
+			name:   "spiffe without SPIFFE ID",
+			source: auth.MTLSSubjectSPIFFE,
+			cert: func(t *testing.T) *x509.Certificate {
+				cert := createTestCert("client", nil, nil)
+				cert.URIs = []*url.URL{mustParseURL(t, "https://example.org/client")}
+				return cert
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			authenticator, err := auth.NewMTLSAuthenticator(auth.MTLSConfig{SubjectSource: tt.source})
+			if err != nil {
+				t.Fatalf("NewMTLSAuthenticator() error = %v", err)
+			}
+
+			// Act
+			info, err := authenticator.Authenticate(mtlsRequest(tt.cert(t)))
+
+			// Assert
+			if tt.wantErr {
+				if !errors.Is(err, auth.ErrInvalidCert) {
+					t.Errorf("Authenticate() error = %v, want %v", err, auth.ErrInvalidCert)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if info.Subject != tt.wantSubject {
+				t.Errorf("Subject = %q, want %q", info.Subject, tt.wantSubject)
+			}
+		})
+	}
+}
+
+func TestMTLSAuthenticator_AllowRules(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  auth.MTLSConfig
+		cert    func(t *testing.T) *x509.Certificate
+		wantErr string
+	}{
+		{
+			name:   "allowed trust domain",
+			config: auth.MTLSConfig{TrustDomains: []string{"other.org", "example.org"}},
+			cert:   createSANCert,
+		},
+		{
+			name:    "other trust domain",
+			config:  auth.MTLSConfig{TrustDomains: []string{"other.org"}},
+			cert:    createSANCert,
+			wantErr: `trust domain "example.org" is not allowed`,
+		},
+		{
+			name:    "trust domain rule without SPIFFE ID",
+			config:  auth.MTLSConfig{TrustDomains: []string{"example.org"}},
+			cert:    func(*testing.T) *x509.Certificate { return createTestCert("client", nil, nil) },
+			wantErr: "no SPIFFE ID",
+		},
+		{
+			name:   "allowed organizational unit",
+			config: auth.MTLSConfig{AllowedOUs: []string{"billing"}},
+			cert:   createSANCert,
+		},
+		{
+			name:    "other organizational unit",
+			config:  auth.MTLSConfig{AllowedOUs: []string{"ops"}},
+			cert:    createSANCert,
+			wantErr: "organizational unit is not allowed",
+		},
+		{
+			name:   "DNS SAN matches pattern",
+			config: auth.MTLSConfig{AllowedSANs: []string{"*.example.org"}},
+			cert:   createSANCert,
+		},
+		{
+			name:   "SPIFFE ID matches pattern",
+			config: auth.MTLSConfig{AllowedSANs: []string{"spiffe://example.org/ns/*/sa/payments"}},
+			cert:   createSANCert,
+		},
+		{
+			name:   "IP SAN matches pattern",
+			config: auth.MTLSConfig{AllowedSANs: []string{"10.0.0.*"}},
+			cert:   createSANCert,
+		},
+		{
+			name:    "no SAN matches pattern",
+			config:  auth.MTLSConfig{AllowedSANs: []string{"*.other.org", "spiffe://example.org/ns/dev/*"}},
+			cert:    createSANCert,
+			wantErr: "subject alternative names are not allowed",
+		},
+		{
+			name: "all rules must pass",
+			config: auth.MTLSConfig{
+				TrustDomains: []string{"example.org"},
+				AllowedOUs:   []string{"payments"},
+				AllowedSANs:  []string{"*.other.org"},
+			},
+			cert:    createSANCert,
+			wantErr: "subject alternative names are not allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			authenticator, err := auth.NewMTLSAuthenticator(tt.config)
+			if err != nil {
+				t.Fatalf("NewMTLSAuthenticator() error = %v", err)
+			}
+
+			// Act
+			info, err := authenticator.Authenticate(mtlsRequest(tt.cert(t)))
+
+			// Assert
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Authenticate() error = %v", err)
+				}
+				if info == nil {
+					t.Fatal("Authenticate() returned nil AuthInfo")
+				}
+				return
+			}
+			if !errors.Is(err, auth.ErrInvalidCert) {
+				t.Errorf("Authenticate() error = %v, want %v", err, auth.ErrInvalidCert)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Authenticate() error = %q, want it to contain %q", err, tt.wantErr)
+			}
+			if info != nil {
+				t.Errorf("Authenticate() returned %v, want nil", info)
+			}
+		})
+	}
+}
+
+func TestMTLSAuthenticator_Claims(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	authenticator, err := auth.NewMTLSAuthenticator(auth.MTLSConfig{})
+	if err != nil {
+		t.Fatalf("NewMTLSAuthenticator() error = %v", err)
+	}
+	cert := createSANCert(t)
+	fingerprint := sha256.Sum256(cert.Raw)
+
+	// Act
+	info, err := authenticator.Authenticate(mtlsRequest(cert))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	wantSlices := map[string][]string{
+		"organizations":        {"Example"},
+		"organizational_units": {"payments", "billing"},
+		"dns_names":            {"payments.example.org"},
+		"uris":                 {"https://example.org/payments", "spiffe://example.org/ns/prod/sa/payments"},
+		"email_addresses":      {"payments@example.org"},
+		"ip_addresses":         {"10.0.0.7"},
+	}
+	for key, want := range wantSlices {
+		assertSliceEqual(t, key, info.Claims[key], want)
+	}
+
+	wantStrings := map[string]string{
+		"serial":             "4711",
+		"fingerprint_sha256": hex.EncodeToString(fingerprint[:]),
+		"spiffe_id":          "spiffe://example.org/ns/prod/sa/payments",
+		"trust_domain":       "example.org",
+	}
+	for key, want := range wantStrings {
+		if got := info.Claims[key]; got != want {
+			t.Errorf("Claims[%q] = %v, want %q", key, got, want)
+		}
+	}
+
+	if len(info.Claims) != len(wantSlices)+len(wantStrings) {
+		t.Errorf("len(Claims) = %d, want %d", len(info.Claims), len(wantSlices)+len(wantStrings))
+	}
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"errors"
 	"net/http"
+	"slices"
 )
 
 // MultiAuthenticator tries multiple authenticators in order, returning
@@ -52,6 +53,11 @@ func (a *MultiAuthenticator) Authenticate(
 	return nil, ErrUnauthenticated
 }
 
+// Authenticators returns the authenticators that are tried, in order.
+func (a *MultiAuthenticator) Authenticators() []Authenticator {
+	return slices.Clone(a.authenticators)
+}
+
 // Method returns the authentication method type.
 func (a *MultiAuthenticator) Method() AuthMethod {
 	return AuthMethodMulti
@@ -2,8 +2,12 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -22,10 +26,30 @@ type TokenClaims struct {
 	Claims   map[string]any
 }
 
+// ErrRequiredClaim is returned for tokens that lack a required claim or
+// carry an unexpected value for it.
+var ErrRequiredClaim = errors.New("required claim not satisfied")
+
+// OIDCIssuer is a token issuer trusted by an OIDCAuthenticator.
+type OIDCIssuer struct {
+	// Issuer is the iss claim of the tokens issued by this issuer.
+	Issuer string
+	// Verifier verifies the tokens of this issuer.
+	Verifier TokenVerifier
+	// Audience, when set, must be contained in the token's aud claim.
+	Audience string
+	// RequiredClaims maps claim names to their required value. An empty
+	// value only requires the claim to be present; for array claims one of
+	// the elements must match.
+	RequiredClaims map[string]string
+}
+
 // OIDCAuthenticator authenticates requests using OIDC/JWT bearer tokens.
 type OIDCAuthenticator struct {
-	verifier TokenVerifier
-	audience string
+	// issuers routes tokens by their iss claim; when nil, every token is
+	// handed to single.
+	issuers map[string]OIDCIssuer
+	single  OIDCIssuer
 }
 
 // NewOIDCAuthenticator creates a new OIDC authenticator with the given
@@ -35,46 +59,76 @@ func NewOIDCAuthenticator(
 	audience string,
 ) *OIDCAuthenticator {
 	return &OIDCAuthenticator{
-		verifier: verifier,
-		audience: audience,
+		single: OIDCIssuer{
+			Verifier: verifier,
+			Audience: audience,
+		},
 	}
 }
 
+// NewMultiIssuerOIDCAuthenticator creates an OIDC authenticator that routes
+// each token by its iss claim to the verifier of that issuer. Tokens from
+// other issuers are rejected.
+func NewMultiIssuerOIDCAuthenticator(
+	issuers ...OIDCIssuer,
+) (*OIDCAuthenticator, error) {
+	if len(issuers) == 0 {
+		return nil, fmt.Errorf("oidc auth: at least one issuer is required")
+	}
+
+	byIssuer := make(map[string]OIDCIssuer, len(issuers))
+	for _, issuer := range issuers {
+		if issuer.Issuer == "" || issuer.Verifier == nil {
+			return nil, fmt.Errorf("oidc auth: issuer and verifier must not be empty")
+		}
+		if _, ok := byIssuer[issuer.Issuer]; ok {
+			return nil, fmt.Errorf("oidc auth: duplicate issuer %q", issuer.Issuer)
+		}
+		byIssuer[issuer.Issuer] = issuer
+	}
+
+	return &OIDCAuthenticator{issuers: byIssuer}, nil
+}
+
 // Authenticate extracts a Bearer token from the Authorization header,
-// verifies it using the configured TokenVerifier, and returns the
+// verifies it using the TokenVerifier of its issuer, and returns the
 // authenticated identity.
 func (a *OIDCAuthenticator) Authenticate(
 	r *http.Request,
 ) (*AuthInfo, error) {
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
+	token, ok := bearerToken(r)
+	if !ok {
 		return nil, ErrUnauthenticated
 	}
 
-	if !strings.HasPrefix(authHeader, "Bearer ") {
-		return nil, ErrUnauthenticated
+	issuer, err := a.route(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
 	}
 
-	token := strings.TrimPrefix(authHeader, "Bearer ")
-
-	claims, err := a.verifier.Verify(r.Context(), token)
+	claims, err := issuer.Verifier.Verify(r.Context(), token)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
 	}
 
-	if a.audience != "" && !containsAudience(claims.Audience, a.audience) {
+	if issuer.Audience != "" && !containsAudience(claims.Audience, issuer.Audience) {
 		return nil, fmt.Errorf(
 			"%w: token audience %v does not contain %s",
 			ErrInvalidToken,
 			claims.Audience,
-			a.audience,
+			issuer.Audience,
 		)
 	}
 
+	if err := checkRequiredClaims(claims.Claims, issuer.RequiredClaims); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
 	return &AuthInfo{
 		Method:  AuthMethodOIDC,
 		Subject: claims.Subject,
 		Claims:  claims.Claims,
+		Expiry:  claims.Expiry,
 	}, nil
 }
 
@@ -83,6 +137,65 @@ func (a *OIDCAuthenticator) Method() AuthMethod {
 	return AuthMethodOIDC
 }
 
+// bearerToken returns the bearer token from the Authorization header.
+func bearerToken(r *http.Request) (string, bool) {
+	return strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// route returns the issuer that verifies token. The iss claim is read
+// without verification; the issuer's verifier checks it again.
+func (a *OIDCAuthenticator) route(token string) (OIDCIssuer, error) {
+	if a.issuers == nil {
+		return a.single, nil
+	}
+
+	claims, err := extractAllClaims(token)
+	if err != nil {
+		return OIDCIssuer{}, fmt.Errorf("%w: %w", ErrTokenMalformed, err)
+	}
+
+	iss, _ := claims["iss"].(string)
+	issuer, ok := a.issuers[iss]
+	if !ok {
+		return OIDCIssuer{}, fmt.Errorf("%w: untrusted issuer %q", ErrIssuerMismatch, iss)
+	}
+
+	return issuer, nil
+}
+
+// checkRequiredClaims checks claims against the required claims.
+func checkRequiredClaims(claims map[string]any, required map[string]string) error {
+	for _, name := range slices.Sorted(maps.Keys(required)) {
+		value, ok := claims[name]
+		if !ok {
+			return fmt.Errorf("%w: %s is missing", ErrRequiredClaim, name)
+		}
+		if want := required[name]; want != "" && !claimMatches(value, want) {
+			return fmt.Errorf("%w: %s is not %q", ErrRequiredClaim, name, want)
+		}
+	}
+	return nil
+}
+
+// claimMatches reports whether a JSON claim value equals want. Arrays match
+// if any of their elements does.
+func claimMatches(value any, want string) bool {
+	switch v := value.(type) {
+	case string:
+		return v == want
+	case bool:
+		return strconv.FormatBool(v) == want
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64) == want
+	case []any:
+		return slices.ContainsFunc(v, func(elem any) bool {
+			return claimMatches(elem, want)
+		})
+	default:
+		return false
+	}
+}
+
 // containsAudience checks if the expected audience is present in the
 // audience list.
 func containsAudience(audiences []string, expected string) bool {
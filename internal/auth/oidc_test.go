@@ -2,6 +2,7 @@ package auth_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -238,6 +239,9 @@ func TestOIDCAuthenticator_Authenticate(t *testing.T) {
 			if info.Subject != tt.wantSubject {
 				t.Errorf("Subject = %q, want %q", info.Subject, tt.wantSubject)
 			}
+			if !info.Expiry.Equal(tt.verifier.claims.Expiry) {
+				t.Errorf("Expiry = %v, want %v", info.Expiry, tt.verifier.claims.Expiry)
+			}
 			for key, wantVal := range tt.wantClaims {
 				gotVal, exists := info.Claims[key]
 				if !exists {
@@ -262,3 +266,278 @@ func TestOIDCAuthenticator_Method(t *testing.T) {
 		t.Errorf("Method() = %q, want %q", authenticator.Method(), auth.AuthMethodOIDC)
 	}
 }
+
+// unsignedToken builds a JWT with the given payload and no valid signature,
+// for use with mock verifiers.
+func unsignedToken(t *testing.T, payload map[string]any) string {
+	t.Helper()
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("marshalling payload: %v", err)
+	}
+
+	return base64URLEncode([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." +
+		base64URLEncode(payloadJSON) + ".c2ln"
+}
+
+func TestNewMultiIssuerOIDCAuthenticator_Errors(t *testing.T) {
+	t.Parallel()
+
+	verifier := &mockTokenVerifier{}
+
+	tests := []struct {
+		name    string
+		issuers []auth.OIDCIssuer
+	}{
+		{
+			name: "no issuers",
+		},
+		{
+			name:    "empty issuer",
+			issuers: []auth.OIDCIssuer{{Verifier: verifier}},
+		},
+		{
+			name:    "nil verifier",
+			issuers: []auth.OIDCIssuer{{Issuer: "https://corp.example.com"}},
+		},
+		{
+			name: "duplicate issuer",
+			issuers: []auth.OIDCIssuer{
+				{Issuer: "https://corp.example.com", Verifier: verifier},
+				{Issuer: "https://corp.example.com", Verifier: verifier},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			authenticator, err := auth.NewMultiIssuerOIDCAuthenticator(tt.issuers...)
+
+			// Assert
+			if err == nil {
+				t.Fatal("NewMultiIssuerOIDCAuthenticator() error = nil, want error")
+			}
+			if authenticator != nil {
+				t.Error("NewMultiIssuerOIDCAuthenticator() returned non-nil authenticator on error")
+			}
+		})
+	}
+}
+
+func TestOIDCAuthenticator_MultiIssuer(t *testing.T) {
+	t.Parallel()
+
+	const (
+		corpIssuer    = "https://corp.example.com"
+		partnerIssuer = "https://partner.example.com"
+	)
+
+	expiry := time.Now().Add(time.Hour)
+	corp := &mockTokenVerifier{claims: &auth.TokenClaims{
+		Subject:  "employee",
+		Audience: []string{"corp-api"},
+		Issuer:   corpIssuer,
+		Expiry:   expiry,
+		Claims:   map[string]any{"iss": corpIssuer},
+	}}
+	partner := &mockTokenVerifier{claims: &auth.TokenClaims{
+		Subject:  "partner-user",
+		Audience: []string{"partner-api"},
+		Issuer:   partnerIssuer,
+		Expiry:   expiry,
+		Claims:   map[string]any{"iss": partnerIssuer},
+	}}
+
+	authenticator, err := auth.NewMultiIssuerOIDCAuthenticator(
+		auth.OIDCIssuer{Issuer: corpIssuer, Verifier: corp, Audience: "corp-api"},
+		auth.OIDCIssuer{Issuer: partnerIssuer, Verifier: partner, Audience: "partner-api"},
+	)
+	if err != nil {
+		t.Fatalf("NewMultiIssuerOIDCAuthenticator() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		token       string
+		wantSubject string
+		wantErrIs   error
+	}{
+		{
+			name:        "corporate token is verified by the corporate issuer",
+			token:       unsignedToken(t, map[string]any{"iss": corpIssuer}),
+			wantSubject: "employee",
+		},
+		{
+			name:        "partner token is verified by the partner issuer",
+			token:       unsignedToken(t, map[string]any{"iss": partnerIssuer}),
+			wantSubject: "partner-user",
+		},
+		{
+			name:      "unknown issuer is rejected",
+			token:     unsignedToken(t, map[string]any{"iss": "https://evil.example.com"}),
+			wantErrIs: auth.ErrIssuerMismatch,
+		},
+		{
+			name:      "missing issuer is rejected",
+			token:     unsignedToken(t, map[string]any{"sub": "user"}),
+			wantErrIs: auth.ErrIssuerMismatch,
+		},
+		{
+			name:      "malformed token is rejected",
+			token:     "not-a-jwt",
+			wantErrIs: auth.ErrTokenMalformed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			info, err := authenticator.Authenticate(bearerRequest(tt.token))
+
+			// Assert
+			if tt.wantErrIs != nil {
+				if !errors.Is(err, auth.ErrInvalidToken) || !errors.Is(err, tt.wantErrIs) {
+					t.Fatalf("Authenticate() error = %v, want ErrInvalidToken and %v", err, tt.wantErrIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if info.Subject != tt.wantSubject {
+				t.Errorf("Subject = %q, want %q", info.Subject, tt.wantSubject)
+			}
+		})
+	}
+}
+
+func TestOIDCAuthenticator_PerIssuerAudience(t *testing.T) {
+	t.Parallel()
+
+	// Arrange: the partner verifier accepts a token meant for the corporate API.
+	const partnerIssuer = "https://partner.example.com"
+	partner := &mockTokenVerifier{claims: &auth.TokenClaims{
+		Subject:  "partner-user",
+		Audience: []string{"corp-api"},
+		Issuer:   partnerIssuer,
+	}}
+	authenticator, err := auth.NewMultiIssuerOIDCAuthenticator(
+		auth.OIDCIssuer{Issuer: "https://corp.example.com", Verifier: &mockTokenVerifier{}, Audience: "corp-api"},
+		auth.OIDCIssuer{Issuer: partnerIssuer, Verifier: partner, Audience: "partner-api"},
+	)
+	if err != nil {
+		t.Fatalf("NewMultiIssuerOIDCAuthenticator() error = %v", err)
+	}
+
+	// Act
+	_, err = authenticator.Authenticate(
+		bearerRequest(unsignedToken(t, map[string]any{"iss": partnerIssuer})),
+	)
+
+	// Assert
+	if !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("Authenticate() error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestOIDCAuthenticator_RequiredClaims(t *testing.T) {
+	t.Parallel()
+
+	const issuer = "https://corp.example.com"
+	claims := map[string]any{
+		"iss":            issuer,
+		"tenant":         "acme",
+		"email_verified": true,
+		"level":          float64(3),
+		"groups":         []any{"staff", "admins"},
+	}
+	verifier := &mockTokenVerifier{claims: &auth.TokenClaims{
+		Subject: "employee",
+		Issuer:  issuer,
+		Claims:  claims,
+	}}
+
+	tests := []struct {
+		name     string
+		required map[string]string
+		wantErr  bool
+	}{
+		{
+			name: "no required claims",
+		},
+		{
+			name:     "present claim",
+			required: map[string]string{"tenant": ""},
+		},
+		{
+			name: "matching values",
+			required: map[string]string{
+				"tenant":         "acme",
+				"email_verified": "true",
+				"level":          "3",
+				"groups":         "admins",
+			},
+		},
+		{
+			name:     "missing claim",
+			required: map[string]string{"department": ""},
+			wantErr:  true,
+		},
+		{
+			name:     "mismatched string",
+			required: map[string]string{"tenant": "globex"},
+			wantErr:  true,
+		},
+		{
+			name:     "mismatched boolean",
+			required: map[string]string{"email_verified": "false"},
+			wantErr:  true,
+		},
+		{
+			name:     "array without the value",
+			required: map[string]string{"groups": "partners"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			authenticator, err := auth.NewMultiIssuerOIDCAuthenticator(auth.OIDCIssuer{
+				Issuer:         issuer,
+				Verifier:       verifier,
+				RequiredClaims: tt.required,
+			})
+			if err != nil {
+				t.Fatalf("NewMultiIssuerOIDCAuthenticator() error = %v", err)
+			}
+
+			// Act
+			info, err := authenticator.Authenticate(
+				bearerRequest(unsignedToken(t, map[string]any{"iss": issuer})),
+			)
+
+			// Assert
+			if tt.wantErr {
+				if !errors.Is(err, auth.ErrInvalidToken) || !errors.Is(err, auth.ErrRequiredClaim) {
+					t.Errorf("Authenticate() error = %v, want ErrInvalidToken and ErrRequiredClaim", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if info.Subject != "employee" {
+				t.Errorf("Subject = %q, want %q", info.Subject, "employee")
+			}
+		})
+	}
+}
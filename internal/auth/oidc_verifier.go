@@ -3,6 +3,9 @@ package auth
 import (
 	"context"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/sha512"
@@ -13,6 +16,7 @@ import (
 	"hash"
 	"math/big"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -23,6 +27,7 @@ var (
 	ErrJWKSFetch        = errors.New("failed to fetch JWKS")
 	ErrTokenMalformed   = errors.New("malformed JWT token")
 	ErrTokenExpired     = errors.New("token has expired")
+	ErrTokenNotYetValid = errors.New("token is not valid yet")
 	ErrIssuerMismatch   = errors.New("token issuer mismatch")
 	ErrKeyNotFound      = errors.New("signing key not found in JWKS")
 	ErrUnsupportedAlgo  = errors.New("unsupported signing algorithm")
@@ -60,6 +65,15 @@ type jwkKey struct {
 	Alg string `json:"alg"`
 	N   string `json:"n"`
 	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey is a verification key parsed from a JWK.
+type publicKey struct {
+	key crypto.PublicKey // *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey
+	alg string           // Algorithm the key is restricted to; empty if any.
 }
 
 // jwtHeader represents the header portion of a JWT.
@@ -75,6 +89,7 @@ type jwtPayload struct {
 	Iss string   `json:"iss"`
 	Aud audience `json:"aud"`
 	Exp float64  `json:"exp"`
+	Nbf float64  `json:"nbf"`
 	Iat float64  `json:"iat"`
 }
 
@@ -109,8 +124,13 @@ type OIDCTokenVerifier struct {
 	jwksURI   string
 	client    *http.Client
 
+	// algorithms are the accepted JWS algorithms.
+	algorithms []string
+	// clockSkew is the leeway applied to the exp and nbf claims.
+	clockSkew time.Duration
+
 	mu   sync.RWMutex
-	keys map[string]*rsa.PublicKey // kid -> public key
+	keys map[string]publicKey // kid -> public key
 
 	stopRefresh chan struct{}
 }
@@ -118,7 +138,20 @@ type OIDCTokenVerifier struct {
 // NewOIDCTokenVerifier creates a new OIDC token verifier that fetches
 // the discovery document and JWKS from the given issuer URL.
 // It starts a background goroutine to periodically refresh the JWKS keys.
-func NewOIDCTokenVerifier(issuerURL string) (*OIDCTokenVerifier, error) {
+//
+// Tokens must be signed with one of algorithms, which defaults to all
+// SupportedAlgorithms. Restricting it to the algorithms the provider
+// actually uses prevents algorithm downgrade attacks.
+func NewOIDCTokenVerifier(issuerURL string, algorithms ...string) (*OIDCTokenVerifier, error) {
+	if len(algorithms) == 0 {
+		algorithms = SupportedAlgorithms
+	}
+	for _, alg := range algorithms {
+		if _, ok := jwsAlgorithms[alg]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgo, alg)
+		}
+	}
+
 	client := &http.Client{Timeout: httpClientTimeout}
 
 	// Fetch the OIDC discovery document to obtain the JWKS URI.
@@ -133,7 +166,8 @@ func NewOIDCTokenVerifier(issuerURL string) (*OIDCTokenVerifier, error) {
 		issuerURL:   issuerURL,
 		jwksURI:     disc.JWKSURI,
 		client:      client,
-		keys:        make(map[string]*rsa.PublicKey),
+		algorithms:  slices.Clone(algorithms),
+		keys:        make(map[string]publicKey),
 		stopRefresh: make(chan struct{}),
 	}
 
@@ -153,8 +187,15 @@ func (v *OIDCTokenVerifier) Stop() {
 	close(v.stopRefresh)
 }
 
+// SetClockSkew sets the leeway allowed between the issuer's clock and ours
+// when checking the exp and nbf claims. It must be called before the
+// verifier is used.
+func (v *OIDCTokenVerifier) SetClockSkew(skew time.Duration) {
+	v.clockSkew = skew
+}
+
 // Verify validates the given raw JWT token string and returns the extracted claims.
-// It checks the token signature, expiry, and issuer.
+// It checks the token signature, expiry, not-before time, and issuer.
 func (v *OIDCTokenVerifier) Verify(ctx context.Context, rawToken string) (*TokenClaims, error) {
 	header, payload, err := v.parseAndVerifySignature(ctx, rawToken)
 	if err != nil {
@@ -169,11 +210,18 @@ func (v *OIDCTokenVerifier) Verify(ctx context.Context, rawToken string) (*Token
 		)
 	}
 
-	// Validate expiry.
+	// Validate expiry and not-before time.
+	now := time.Now()
 	expiry := time.Unix(int64(payload.Exp), 0)
-	if time.Now().After(expiry) {
+	if now.After(expiry.Add(v.clockSkew)) {
 		return nil, fmt.Errorf("%w: expired at %v", ErrTokenExpired, expiry)
 	}
+	if payload.Nbf > 0 {
+		notBefore := time.Unix(int64(payload.Nbf), 0)
+		if now.Add(v.clockSkew).Before(notBefore) {
+			return nil, fmt.Errorf("%w: valid from %v", ErrTokenNotYetValid, notBefore)
+		}
+	}
 
 	// Build all claims map from the raw payload for extensibility.
 	allClaims, err := extractAllClaims(rawToken)
@@ -215,9 +263,8 @@ func (v *OIDCTokenVerifier) parseAndVerifySignature(
 	}
 
 	// Validate signing algorithm.
-	hashFunc, err := rsaHashForAlgorithm(header.Alg)
-	if err != nil {
-		return nil, nil, err
+	if !slices.Contains(v.algorithms, header.Alg) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgo, header.Alg)
 	}
 
 	// Look up the signing key.
@@ -233,7 +280,7 @@ func (v *OIDCTokenVerifier) parseAndVerifySignature(
 		return nil, nil, fmt.Errorf("%w: decoding signature: %w", ErrTokenMalformed, err)
 	}
 
-	if err := verifyRSASignature(key, hashFunc, []byte(signingInput), signatureBytes); err != nil {
+	if err := verifySignature(header.Alg, key, []byte(signingInput), signatureBytes); err != nil {
 		return nil, nil, fmt.Errorf("%w: %w", ErrSignatureInvalid, err)
 	}
 
@@ -251,8 +298,8 @@ func (v *OIDCTokenVerifier) parseAndVerifySignature(
 	return &header, &payload, nil
 }
 
-// getKey retrieves the RSA public key for the given key ID from the cached JWKS.
-func (v *OIDCTokenVerifier) getKey(kid string) (*rsa.PublicKey, error) {
+// getKey retrieves the public key for the given key ID from the cached JWKS.
+func (v *OIDCTokenVerifier) getKey(kid string) (publicKey, error) {
 	v.mu.RLock()
 	key, ok := v.keys[kid]
 	v.mu.RUnlock()
@@ -263,7 +310,7 @@ func (v *OIDCTokenVerifier) getKey(kid string) (*rsa.PublicKey, error) {
 
 	// Key not found; attempt a refresh in case keys were rotated.
 	if err := v.refreshKeys(); err != nil {
-		return nil, fmt.Errorf("%w: refresh failed: %w", ErrKeyNotFound, err)
+		return publicKey{}, fmt.Errorf("%w: refresh failed: %w", ErrKeyNotFound, err)
 	}
 
 	v.mu.RLock()
@@ -271,7 +318,7 @@ func (v *OIDCTokenVerifier) getKey(kid string) (*rsa.PublicKey, error) {
 	v.mu.RUnlock()
 
 	if !ok {
-		return nil, fmt.Errorf("%w: kid=%q", ErrKeyNotFound, kid)
+		return publicKey{}, fmt.Errorf("%w: kid=%q", ErrKeyNotFound, kid)
 	}
 
 	return key, nil
@@ -347,8 +394,9 @@ func fetchDiscoveryDocument(client *http.Client, url string) (*oidcDiscoveryDocu
 	return &doc, nil
 }
 
-// fetchJWKS retrieves the JWKS document and parses RSA public keys.
-func fetchJWKS(client *http.Client, jwksURI string) (map[string]*rsa.PublicKey, error) {
+// fetchJWKS retrieves the JWKS document and parses its RSA, EC and OKP
+// signing keys.
+func fetchJWKS(client *http.Client, jwksURI string) (map[string]publicKey, error) {
 	resp, err := client.Get(jwksURI) //nolint:noctx // JWKS fetch uses short-lived HTTP client with timeout
 	if err != nil {
 		return nil, fmt.Errorf("HTTP request failed: %w", err)
@@ -364,28 +412,42 @@ func fetchJWKS(client *http.Client, jwksURI string) (map[string]*rsa.PublicKey,
 		return nil, fmt.Errorf("decoding JWKS document: %w", err)
 	}
 
-	keys := make(map[string]*rsa.PublicKey, len(doc.Keys))
+	keys := make(map[string]publicKey, len(doc.Keys))
 
 	for _, jwk := range doc.Keys {
-		if jwk.Kty != "RSA" {
+		if jwk.Use != "" && jwk.Use != "sig" {
 			continue
 		}
 
-		if jwk.Use != "" && jwk.Use != "sig" {
+		if _, ok := jwsAlgorithms[jwk.Alg]; jwk.Alg != "" && !ok {
 			continue
 		}
 
-		pubKey, err := parseRSAPublicKey(jwk)
+		pubKey, err := parsePublicKey(jwk)
 		if err != nil {
 			continue // Skip keys that cannot be parsed.
 		}
 
-		keys[jwk.Kid] = pubKey
+		keys[jwk.Kid] = publicKey{key: pubKey, alg: jwk.Alg}
 	}
 
 	return keys, nil
 }
 
+// parsePublicKey constructs a public key from a JWK of type RSA, EC or OKP.
+func parsePublicKey(jwk jwkKey) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		return parseRSAPublicKey(jwk)
+	case "EC":
+		return parseECPublicKey(jwk)
+	case "OKP":
+		return parseOKPPublicKey(jwk)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
 // parseRSAPublicKey constructs an RSA public key from a JWK.
 func parseRSAPublicKey(jwk jwkKey) (*rsa.PublicKey, error) {
 	nBytes, err := base64URLDecode(jwk.N)
@@ -407,6 +469,58 @@ func parseRSAPublicKey(jwk jwkKey) (*rsa.PublicKey, error) {
 	}, nil
 }
 
+// jwkCurves maps JWK curve names to elliptic curves.
+var jwkCurves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+// parseECPublicKey constructs an ECDSA public key from a JWK.
+func parseECPublicKey(jwk jwkKey) (*ecdsa.PublicKey, error) {
+	curve, ok := jwkCurves[jwk.Crv]
+	if !ok {
+		return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+	}
+
+	xBytes, err := base64URLDecode(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("decoding x coordinate: %w", err)
+	}
+
+	yBytes, err := base64URLDecode(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("decoding y coordinate: %w", err)
+	}
+
+	size := (curve.Params().BitSize + 7) / 8
+	if len(xBytes) != size || len(yBytes) != size {
+		return nil, fmt.Errorf("invalid %s coordinate length", jwk.Crv)
+	}
+
+	// ParseUncompressedPublicKey also checks that the point is on the curve.
+	point := append([]byte{4}, xBytes...)
+	return ecdsa.ParseUncompressedPublicKey(curve, append(point, yBytes...))
+}
+
+// parseOKPPublicKey constructs an Ed25519 public key from a JWK.
+func parseOKPPublicKey(jwk jwkKey) (ed25519.PublicKey, error) {
+	if jwk.Crv != "Ed25519" {
+		return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+	}
+
+	xBytes, err := base64URLDecode(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("decoding public key: %w", err)
+	}
+
+	if len(xBytes) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid Ed25519 public key length %d", len(xBytes))
+	}
+
+	return ed25519.PublicKey(xBytes), nil
+}
+
 // base64URLDecode decodes a base64url-encoded string (without padding).
 func base64URLDecode(s string) ([]byte, error) {
 	// Add padding if necessary.
@@ -440,19 +554,61 @@ func extractAllClaims(rawToken string) (map[string]any, error) {
 	return claims, nil
 }
 
-// rsaHashForAlgorithm returns the hash function and crypto.Hash identifier
-// for the given RSA signing algorithm.
-func rsaHashForAlgorithm(alg string) (crypto.Hash, error) {
-	switch alg {
-	case "RS256":
-		return crypto.SHA256, nil
-	case "RS384":
-		return crypto.SHA384, nil
-	case "RS512":
-		return crypto.SHA512, nil
-	default:
-		return 0, fmt.Errorf("%w: %s", ErrUnsupportedAlgo, alg)
+// SupportedAlgorithms lists the JWS algorithms OIDCTokenVerifier can verify.
+// Symmetric (HS*) and unsigned ("none") tokens are never accepted.
+var SupportedAlgorithms = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// jwsAlgorithm describes how tokens signed with a JWS algorithm are verified.
+type jwsAlgorithm struct {
+	hash   crypto.Hash    // Digest of the signing input; zero for EdDSA.
+	curve  elliptic.Curve // Curve of ECDSA keys.
+	verify func(alg jwsAlgorithm, key crypto.PublicKey, signingInput, signature []byte) error
+}
+
+// jwsAlgorithms maps the supported JWS algorithms (RFC 7518, RFC 8037) to
+// their verification.
+var jwsAlgorithms = map[string]jwsAlgorithm{
+	"RS256": {hash: crypto.SHA256, verify: verifyRSASignature},
+	"RS384": {hash: crypto.SHA384, verify: verifyRSASignature},
+	"RS512": {hash: crypto.SHA512, verify: verifyRSASignature},
+	"PS256": {hash: crypto.SHA256, verify: verifyRSAPSSSignature},
+	"PS384": {hash: crypto.SHA384, verify: verifyRSAPSSSignature},
+	"PS512": {hash: crypto.SHA512, verify: verifyRSAPSSSignature},
+	"ES256": {hash: crypto.SHA256, curve: elliptic.P256(), verify: verifyECDSASignature},
+	"ES384": {hash: crypto.SHA384, curve: elliptic.P384(), verify: verifyECDSASignature},
+	"ES512": {hash: crypto.SHA512, curve: elliptic.P521(), verify: verifyECDSASignature},
+	"EdDSA": {verify: verifyEdDSASignature},
+}
+
+// errKeyMismatch reports a signing key that cannot be used with the token
+// algorithm.
+var errKeyMismatch = errors.New("key cannot be used with the token algorithm")
+
+// verifySignature verifies the signature of a token signed with alg.
+func verifySignature(alg string, key publicKey, signingInput, signature []byte) error {
+	algorithm, ok := jwsAlgorithms[alg]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedAlgo, alg)
 	}
+
+	// A key that names its algorithm must only be used with it.
+	if key.alg != "" && key.alg != alg {
+		return fmt.Errorf("%w: key is for %s, token uses %s", errKeyMismatch, key.alg, alg)
+	}
+
+	return algorithm.verify(algorithm, key.key, signingInput, signature)
+}
+
+// digest hashes the signing input with the algorithm hash.
+func (a jwsAlgorithm) digest(signingInput []byte) []byte {
+	h := newHashFunc(a.hash)
+	h.Write(signingInput)
+	return h.Sum(nil)
 }
 
 // newHashFunc creates a new hash.Hash for the given crypto.Hash.
@@ -469,16 +625,61 @@ func newHashFunc(h crypto.Hash) hash.Hash {
 	}
 }
 
-// verifyRSASignature verifies an RSA PKCS#1 v1.5 signature.
-func verifyRSASignature(
-	key *rsa.PublicKey,
-	hashAlg crypto.Hash,
-	signingInput []byte,
-	signature []byte,
-) error {
-	h := newHashFunc(hashAlg)
-	h.Write(signingInput)
-	digest := h.Sum(nil)
+// verifyRSASignature verifies an RSA PKCS#1 v1.5 signature (RS256, RS384,
+// RS512).
+func verifyRSASignature(alg jwsAlgorithm, key crypto.PublicKey, signingInput, signature []byte) error {
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return errKeyMismatch
+	}
+
+	return rsa.VerifyPKCS1v15(rsaKey, alg.hash, alg.digest(signingInput), signature)
+}
 
-	return rsa.VerifyPKCS1v15(key, hashAlg, digest, signature)
+// verifyRSAPSSSignature verifies an RSASSA-PSS signature (PS256, PS384,
+// PS512) whose salt is as long as the digest.
+func verifyRSAPSSSignature(alg jwsAlgorithm, key crypto.PublicKey, signingInput, signature []byte) error {
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return errKeyMismatch
+	}
+
+	return rsa.VerifyPSS(rsaKey, alg.hash, alg.digest(signingInput), signature,
+		&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+}
+
+// verifyECDSASignature verifies an ECDSA signature (ES256, ES384, ES512),
+// which JWS encodes as the fixed-size concatenation of R and S.
+func verifyECDSASignature(alg jwsAlgorithm, key crypto.PublicKey, signingInput, signature []byte) error {
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok || ecKey.Curve != alg.curve {
+		return errKeyMismatch
+	}
+
+	size := (alg.curve.Params().BitSize + 7) / 8
+	if len(signature) != 2*size {
+		return fmt.Errorf("invalid ECDSA signature length %d", len(signature))
+	}
+
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+	if !ecdsa.Verify(ecKey, alg.digest(signingInput), r, s) {
+		return errors.New("invalid ECDSA signature")
+	}
+
+	return nil
+}
+
+// verifyEdDSASignature verifies an Ed25519 signature (EdDSA).
+func verifyEdDSASignature(_ jwsAlgorithm, key crypto.PublicKey, signingInput, signature []byte) error {
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return errKeyMismatch
+	}
+
+	if !ed25519.Verify(edKey, signingInput, signature) {
+		return errors.New("invalid Ed25519 signature")
+	}
+
+	return nil
 }
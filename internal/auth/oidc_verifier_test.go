@@ -2,6 +2,10 @@ package auth_test
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
@@ -624,9 +628,9 @@ func TestOIDCTokenVerifier_UnsupportedAlgorithm(t *testing.T) {
 	}
 	defer verifier.Stop()
 
-	// Create token with unsupported algorithm.
+	// Create token with unsupported (symmetric) algorithm.
 	header := map[string]any{
-		"alg": "ES256",
+		"alg": "HS256",
 		"typ": "JWT",
 		"kid": testKeyID,
 	}
@@ -799,3 +803,534 @@ func TestOIDCTokenVerifier_ExpiryBoundary(t *testing.T) {
 		t.Errorf("Verify() error = %v, want ErrTokenExpired", err)
 	}
 }
+
+// publicJWK builds a JWK for an RSA, ECDSA or Ed25519 public key. The "alg"
+// member is omitted when alg is empty.
+func publicJWK(t *testing.T, kid, alg string, key crypto.PublicKey) map[string]any {
+	t.Helper()
+
+	jwk := map[string]any{"use": "sig", "kid": kid}
+	if alg != "" {
+		jwk["alg"] = alg
+	}
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		jwk["kty"] = "RSA"
+		jwk["n"] = base64URLEncode(k.N.Bytes())
+		jwk["e"] = base64URLEncode(big.NewInt(int64(k.E)).Bytes())
+	case *ecdsa.PublicKey:
+		point, err := k.Bytes()
+		if err != nil {
+			t.Fatalf("encoding EC public key: %v", err)
+		}
+		size := (len(point) - 1) / 2
+		jwk["kty"] = "EC"
+		jwk["crv"] = k.Curve.Params().Name
+		jwk["x"] = base64URLEncode(point[1 : 1+size])
+		jwk["y"] = base64URLEncode(point[1+size:])
+	case ed25519.PublicKey:
+		jwk["kty"] = "OKP"
+		jwk["crv"] = "Ed25519"
+		jwk["x"] = base64URLEncode(k)
+	default:
+		t.Fatalf("unsupported key type %T", key)
+	}
+
+	return jwk
+}
+
+// jwksTestServer serves OIDC discovery and a JWKS with the given keys.
+func jwksTestServer(t *testing.T, keys ...map[string]any) *httptest.Server {
+	t.Helper()
+
+	var serverURL string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(createDiscoveryResponse(serverURL, serverURL+"/jwks"))
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	})
+
+	server := httptest.NewServer(mux)
+	serverURL = server.URL
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// signJWS creates a token for issuer signed with key using alg.
+func signJWS(t *testing.T, alg, kid string, key crypto.Signer, issuer string) string {
+	t.Helper()
+
+	headerJSON, _ := json.Marshal(map[string]any{"alg": alg, "typ": "JWT", "kid": kid})
+	payloadJSON, _ := json.Marshal(map[string]any{
+		"sub": "user@example.com",
+		"iss": issuer,
+		"aud": "my-api",
+		"exp": float64(time.Now().Add(time.Hour).Unix()),
+	})
+	signingInput := base64URLEncode(headerJSON) + "." + base64URLEncode(payloadJSON)
+
+	hashes := map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512}
+	hashAlg := hashes[alg[len(alg)-3:]]
+
+	var digest []byte
+	if hashAlg != 0 {
+		h := hashAlg.New()
+		h.Write([]byte(signingInput))
+		digest = h.Sum(nil)
+	}
+
+	var (
+		sig []byte
+		err error
+	)
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if strings.HasPrefix(alg, "PS") {
+			sig, err = rsa.SignPSS(rand.Reader, k, hashAlg, digest,
+				&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			sig, err = rsa.SignPKCS1v15(rand.Reader, k, hashAlg, digest)
+		}
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest)
+		if err == nil {
+			size := (k.Curve.Params().BitSize + 7) / 8
+			sig = make([]byte, 2*size)
+			r.FillBytes(sig[:size])
+			s.FillBytes(sig[size:])
+		}
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, []byte(signingInput))
+	default:
+		t.Fatalf("unsupported key type %T", key)
+	}
+	if err != nil {
+		t.Fatalf("signing JWT: %v", err)
+	}
+
+	return signingInput + "." + base64URLEncode(sig)
+}
+
+func generateTestECKey(t *testing.T, curve elliptic.Curve) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("generating EC key: %v", err)
+	}
+	return key
+}
+
+func generateTestEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating Ed25519 key: %v", err)
+	}
+	return key
+}
+
+func TestOIDCTokenVerifier_Algorithms(t *testing.T) {
+	t.Parallel()
+
+	rsaKey := generateTestRSAKey(t)
+
+	tests := []struct {
+		alg string
+		key crypto.Signer
+	}{
+		{alg: "RS256", key: rsaKey},
+		{alg: "RS384", key: rsaKey},
+		{alg: "RS512", key: rsaKey},
+		{alg: "PS256", key: rsaKey},
+		{alg: "PS384", key: rsaKey},
+		{alg: "PS512", key: rsaKey},
+		{alg: "ES256", key: generateTestECKey(t, elliptic.P256())},
+		{alg: "ES384", key: generateTestECKey(t, elliptic.P384())},
+		{alg: "ES512", key: generateTestECKey(t, elliptic.P521())},
+		{alg: "EdDSA", key: generateTestEd25519Key(t)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			server := jwksTestServer(t, publicJWK(t, testKeyID, tt.alg, tt.key.Public()))
+
+			verifier, err := auth.NewOIDCTokenVerifier(server.URL)
+			if err != nil {
+				t.Fatalf("creating verifier: %v", err)
+			}
+			defer verifier.Stop()
+
+			token := signJWS(t, tt.alg, testKeyID, tt.key, server.URL)
+
+			// Act
+			claims, err := verifier.Verify(context.Background(), token)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("Verify() error = %v, want nil", err)
+			}
+			if claims.Subject != "user@example.com" {
+				t.Errorf("Subject = %q, want %q", claims.Subject, "user@example.com")
+			}
+		})
+	}
+}
+
+func TestOIDCTokenVerifier_AlgorithmAllowList(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	rsaKey := generateTestRSAKey(t)
+	ecKey := generateTestECKey(t, elliptic.P256())
+	server := jwksTestServer(t,
+		publicJWK(t, "rsa", "", &rsaKey.PublicKey),
+		publicJWK(t, "ec", "", &ecKey.PublicKey),
+	)
+
+	verifier, err := auth.NewOIDCTokenVerifier(server.URL, "ES256")
+	if err != nil {
+		t.Fatalf("creating verifier: %v", err)
+	}
+	defer verifier.Stop()
+
+	// Act
+	_, allowedErr := verifier.Verify(context.Background(), signJWS(t, "ES256", "ec", ecKey, server.URL))
+	_, deniedErr := verifier.Verify(context.Background(), signJWS(t, "RS256", "rsa", rsaKey, server.URL))
+
+	// Assert
+	if allowedErr != nil {
+		t.Errorf("Verify(ES256) error = %v, want nil", allowedErr)
+	}
+	if !errors.Is(deniedErr, auth.ErrUnsupportedAlgo) {
+		t.Errorf("Verify(RS256) error = %v, want ErrUnsupportedAlgo", deniedErr)
+	}
+}
+
+func TestNewOIDCTokenVerifier_UnsupportedAlgorithm(t *testing.T) {
+	t.Parallel()
+
+	for _, alg := range []string{"HS256", "none", "ES256K"} {
+		t.Run(alg, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			verifier, err := auth.NewOIDCTokenVerifier("http://127.0.0.1:0", "RS256", alg)
+
+			// Assert
+			if !errors.Is(err, auth.ErrUnsupportedAlgo) {
+				t.Errorf("NewOIDCTokenVerifier() error = %v, want ErrUnsupportedAlgo", err)
+			}
+			if verifier != nil {
+				t.Error("NewOIDCTokenVerifier() returned non-nil verifier on error")
+			}
+		})
+	}
+}
+
+func TestOIDCTokenVerifier_KeyMismatch(t *testing.T) {
+	t.Parallel()
+
+	rsaKey := generateTestRSAKey(t)
+	p256Key := generateTestECKey(t, elliptic.P256())
+	p384Key := generateTestECKey(t, elliptic.P384())
+	server := jwksTestServer(t,
+		publicJWK(t, "rs256", "RS256", &rsaKey.PublicKey),
+		publicJWK(t, "rsa", "", &rsaKey.PublicKey),
+		publicJWK(t, "p256", "", &p256Key.PublicKey),
+	)
+
+	truncated := signJWS(t, "ES256", "p256", p256Key, server.URL)
+	truncated = truncated[:len(truncated)-4]
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{
+			name:  "key restricted to another algorithm",
+			token: signJWS(t, "PS256", "rs256", rsaKey, server.URL),
+		},
+		{
+			name:  "RSA key for ECDSA token",
+			token: signJWS(t, "ES256", "rsa", p256Key, server.URL),
+		},
+		{
+			name:  "EC key on another curve",
+			token: signJWS(t, "ES384", "p256", p384Key, server.URL),
+		},
+		{
+			name:  "truncated ECDSA signature",
+			token: truncated,
+		},
+	}
+
+	verifier, err := auth.NewOIDCTokenVerifier(server.URL)
+	if err != nil {
+		t.Fatalf("creating verifier: %v", err)
+	}
+	t.Cleanup(verifier.Stop)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			_, err := verifier.Verify(context.Background(), tt.token)
+
+			// Assert
+			if !errors.Is(err, auth.ErrSignatureInvalid) {
+				t.Errorf("Verify() error = %v, want ErrSignatureInvalid", err)
+			}
+		})
+	}
+}
+
+func TestOIDCTokenVerifier_SkipsUnusableKeys(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ecKey := generateTestECKey(t, elliptic.P256())
+	edKey := generateTestEd25519Key(t)
+
+	offCurve := publicJWK(t, "off-curve", "", &ecKey.PublicKey)
+	offCurve["y"] = offCurve["x"]
+	unknownCurve := publicJWK(t, "unknown-curve", "", &ecKey.PublicKey)
+	unknownCurve["crv"] = "secp256k1"
+	ed448 := publicJWK(t, "ed448", "", edKey.Public())
+	ed448["crv"] = "Ed448"
+	encryption := publicJWK(t, "encryption", "", &ecKey.PublicKey)
+	encryption["use"] = "enc"
+	unknownAlg := publicJWK(t, "unknown-alg", "ES256K", &ecKey.PublicKey)
+
+	server := jwksTestServer(t, offCurve, unknownCurve, ed448, encryption, unknownAlg)
+
+	verifier, err := auth.NewOIDCTokenVerifier(server.URL)
+	if err != nil {
+		t.Fatalf("creating verifier: %v", err)
+	}
+	t.Cleanup(verifier.Stop)
+
+	tests := []struct {
+		kid string
+		alg string
+		key crypto.Signer
+	}{
+		{kid: "off-curve", alg: "ES256", key: ecKey},
+		{kid: "unknown-curve", alg: "ES256", key: ecKey},
+		{kid: "ed448", alg: "EdDSA", key: edKey},
+		{kid: "encryption", alg: "ES256", key: ecKey},
+		{kid: "unknown-alg", alg: "ES256", key: ecKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kid, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			_, err := verifier.Verify(context.Background(), signJWS(t, tt.alg, tt.kid, tt.key, server.URL))
+
+			// Assert
+			if !errors.Is(err, auth.ErrKeyNotFound) {
+				t.Errorf("Verify() error = %v, want ErrKeyNotFound", err)
+			}
+		})
+	}
+}
+
+func TestOIDCTokenVerifier_ClockSkew(t *testing.T) {
+	t.Parallel()
+
+	rsaKey := generateTestRSAKey(t)
+	server := oidcTestServer(t, &rsaKey.PublicKey, testKeyID)
+
+	tests := []struct {
+		name      string
+		skew      time.Duration
+		expiresIn time.Duration // exp relative to now
+		notBefore time.Duration // nbf relative to now; zero omits it
+		wantErrIs error
+	}{
+		{
+			name:      "recently expired token without skew",
+			expiresIn: -30 * time.Second,
+			wantErrIs: auth.ErrTokenExpired,
+		},
+		{
+			name:      "recently expired token within skew",
+			skew:      time.Minute,
+			expiresIn: -30 * time.Second,
+		},
+		{
+			name:      "expired token beyond skew",
+			skew:      time.Minute,
+			expiresIn: -2 * time.Minute,
+			wantErrIs: auth.ErrTokenExpired,
+		},
+		{
+			name:      "token already valid",
+			expiresIn: time.Hour,
+			notBefore: -time.Minute,
+		},
+		{
+			name:      "token not valid yet without skew",
+			expiresIn: time.Hour,
+			notBefore: 30 * time.Second,
+			wantErrIs: auth.ErrTokenNotYetValid,
+		},
+		{
+			name:      "token not valid yet within skew",
+			skew:      time.Minute,
+			expiresIn: time.Hour,
+			notBefore: 30 * time.Second,
+		},
+		{
+			name:      "token not valid yet beyond skew",
+			skew:      time.Minute,
+			expiresIn: time.Hour,
+			notBefore: 2 * time.Minute,
+			wantErrIs: auth.ErrTokenNotYetValid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			verifier, err := auth.NewOIDCTokenVerifier(server.URL)
+			if err != nil {
+				t.Fatalf("creating verifier: %v", err)
+			}
+			defer verifier.Stop()
+			verifier.SetClockSkew(tt.skew)
+
+			// Times are computed here, not in the parent test: parallel
+			// subtests only start once the parent returns.
+			now := time.Now()
+			payload := map[string]any{
+				"sub": "user@example.com",
+				"iss": server.URL,
+				"aud": "my-api",
+				"exp": float64(now.Add(tt.expiresIn).Unix()),
+			}
+			if tt.notBefore != 0 {
+				payload["nbf"] = float64(now.Add(tt.notBefore).Unix())
+			}
+			token := signJWT(t, rsaKey, map[string]any{"alg": "RS256", "typ": "JWT", "kid": testKeyID}, payload)
+
+			// Act
+			_, err = verifier.Verify(context.Background(), token)
+
+			// Assert
+			if tt.wantErrIs != nil {
+				if !errors.Is(err, tt.wantErrIs) {
+					t.Errorf("Verify() error = %v, want %v", err, tt.wantErrIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestOIDCAuthenticator_MultipleIssuerVerifiers(t *testing.T) {
+	t.Parallel()
+
+	// Arrange: two identity providers with their own signing keys.
+	corpKey := generateTestRSAKey(t)
+	corpServer := oidcTestServer(t, &corpKey.PublicKey, testKeyID)
+	partnerKey := generateTestRSAKey(t)
+	partnerServer := oidcTestServer(t, &partnerKey.PublicKey, testKeyID)
+
+	corpVerifier, err := auth.NewOIDCTokenVerifier(corpServer.URL)
+	if err != nil {
+		t.Fatalf("creating corporate verifier: %v", err)
+	}
+	defer corpVerifier.Stop()
+	partnerVerifier, err := auth.NewOIDCTokenVerifier(partnerServer.URL)
+	if err != nil {
+		t.Fatalf("creating partner verifier: %v", err)
+	}
+	defer partnerVerifier.Stop()
+
+	authenticator, err := auth.NewMultiIssuerOIDCAuthenticator(
+		auth.OIDCIssuer{Issuer: corpServer.URL, Verifier: corpVerifier, Audience: "corp-api"},
+		auth.OIDCIssuer{Issuer: partnerServer.URL, Verifier: partnerVerifier, Audience: "partner-api"},
+	)
+	if err != nil {
+		t.Fatalf("NewMultiIssuerOIDCAuthenticator() error = %v", err)
+	}
+
+	expiry := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		token       string
+		wantSubject string
+		wantErrIs   error
+	}{
+		{
+			name: "corporate token",
+			token: createValidToken(t, corpKey, corpServer.URL, "employee",
+				"corp-api", expiry, testKeyID),
+			wantSubject: "employee",
+		},
+		{
+			name: "partner token",
+			token: createValidToken(t, partnerKey, partnerServer.URL, "partner-user",
+				"partner-api", expiry, testKeyID),
+			wantSubject: "partner-user",
+		},
+		{
+			name: "partner token with the corporate audience",
+			token: createValidToken(t, partnerKey, partnerServer.URL, "partner-user",
+				"corp-api", expiry, testKeyID),
+			wantErrIs: auth.ErrInvalidToken,
+		},
+		{
+			name: "token claiming the partner issuer signed with the corporate key",
+			token: createValidToken(t, corpKey, partnerServer.URL, "employee",
+				"partner-api", expiry, testKeyID),
+			wantErrIs: auth.ErrSignatureInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			info, err := authenticator.Authenticate(bearerRequest(tt.token))
+
+			// Assert
+			if tt.wantErrIs != nil {
+				if !errors.Is(err, tt.wantErrIs) {
+					t.Errorf("Authenticate() error = %v, want %v", err, tt.wantErrIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if info.Subject != tt.wantSubject {
+				t.Errorf("Subject = %q, want %q", info.Subject, tt.wantSubject)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// argon2idPrefix starts argon2id hashes in the PHC string format.
+const argon2idPrefix = "$argon2id$"
+
+// Password hash errors.
+var (
+	errUnsupportedPasswordHash = errors.New(
+		"unsupported password hash, expected bcrypt, SHA-crypt ($5$, $6$) or argon2id",
+	)
+	errPasswordMismatch = errors.New("password does not match")
+)
+
+// argon2idHash is a parsed argon2id hash.
+type argon2idHash struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// checkPasswordHash reports whether hash is a well-formed password hash of a
+// supported scheme: bcrypt, SHA-256-crypt, SHA-512-crypt or argon2id.
+func checkPasswordHash(hash string) error {
+	switch {
+	case isBcryptHash(hash):
+		_, err := bcrypt.Cost([]byte(hash))
+		return err
+	case isSHACryptHash(hash):
+		_, err := parseSHACrypt(hash)
+		return err
+	case strings.HasPrefix(hash, argon2idPrefix):
+		_, err := parseArgon2id(hash)
+		return err
+	default:
+		return errUnsupportedPasswordHash
+	}
+}
+
+// verifyPassword checks password against hash, comparing in constant time.
+func verifyPassword(hash, password string) error {
+	switch {
+	case isBcryptHash(hash):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	case isSHACryptHash(hash):
+		computed, err := shaCrypt(password, hash)
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare([]byte(computed), []byte(hash)) != 1 {
+			return errPasswordMismatch
+		}
+		return nil
+	case strings.HasPrefix(hash, argon2idPrefix):
+		parsed, err := parseArgon2id(hash)
+		if err != nil {
+			return err
+		}
+		key := argon2.IDKey([]byte(password), parsed.salt, parsed.time, parsed.memory,
+			parsed.threads, uint32(len(parsed.key)))
+		if subtle.ConstantTimeCompare(key, parsed.key) != 1 {
+			return errPasswordMismatch
+		}
+		return nil
+	default:
+		return errUnsupportedPasswordHash
+	}
+}
+
+// isBcryptHash reports whether hash looks like a bcrypt hash.
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") ||
+		strings.HasPrefix(hash, "$2b$") ||
+		strings.HasPrefix(hash, "$2y$")
+}
+
+// isSHACryptHash reports whether hash looks like a SHA-crypt hash.
+func isSHACryptHash(hash string) bool {
+	return strings.HasPrefix(hash, shaCrypt256.prefix) || strings.HasPrefix(hash, shaCrypt512.prefix)
+}
+
+// parseArgon2id parses an argon2id hash of the form
+// "$argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<key>" with
+// unpadded base64 salt and key.
+func parseArgon2id(hash string) (*argon2idHash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return nil, errors.New("invalid argon2id hash: expected 6 fields")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("invalid argon2id hash: unsupported version %q", parts[2])
+	}
+
+	var parsed argon2idHash
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &parsed.memory, &parsed.time, &parsed.threads)
+	if err != nil || parsed.time == 0 || parsed.threads == 0 {
+		return nil, fmt.Errorf("invalid argon2id hash: invalid parameters %q", parts[3])
+	}
+
+	if parsed.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id hash: decoding salt: %w", err)
+	}
+	if parsed.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(parsed.key) == 0 {
+		return nil, errors.New("invalid argon2id hash: invalid key")
+	}
+
+	return &parsed, nil
+}
@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+// SHA-crypt parameters, see https://www.akkadia.org/drepper/SHA-crypt.txt.
+const (
+	shaCryptRoundsDefault = 5000
+	shaCryptRoundsMin     = 1000
+	shaCryptRoundsMax     = 999999999
+	shaCryptSaltMax       = 16
+	shaCryptRoundsPrefix  = "rounds="
+	shaCryptAlphabet      = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// errInvalidSHACrypt is returned for malformed SHA-crypt hashes.
+var errInvalidSHACrypt = errors.New("invalid SHA-crypt hash")
+
+// shaCryptScheme is a SHA-crypt variant.
+type shaCryptScheme struct {
+	prefix  string
+	newHash func() hash.Hash
+	// order lists the digest bytes encoded by each group of four characters;
+	// -1 stands for a zero byte. The last group may encode fewer characters.
+	order [][3]int
+	tail  int // characters encoding the last group
+}
+
+var (
+	shaCrypt256 = shaCryptScheme{
+		prefix:  "$5$",
+		newHash: sha256.New,
+		order: [][3]int{
+			{0, 10, 20}, {21, 1, 11}, {12, 22, 2}, {3, 13, 23}, {24, 4, 14},
+			{15, 25, 5}, {6, 16, 26}, {27, 7, 17}, {18, 28, 8}, {9, 19, 29},
+			{-1, 31, 30},
+		},
+		tail: 3,
+	}
+	shaCrypt512 = shaCryptScheme{
+		prefix:  "$6$",
+		newHash: sha512.New,
+		order: [][3]int{
+			{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4},
+			{47, 5, 26}, {6, 27, 48}, {28, 49, 7}, {50, 8, 29}, {9, 30, 51},
+			{31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13}, {56, 14, 35},
+			{15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19},
+			{62, 20, 41}, {-1, -1, 63},
+		},
+		tail: 2,
+	}
+)
+
+// shaCryptSetting holds the parameters of a SHA-crypt hash.
+type shaCryptSetting struct {
+	scheme       shaCryptScheme
+	rounds       int
+	customRounds bool // rounds are part of the hash
+	salt         string
+}
+
+// parseSHACrypt parses the parameters of setting, which is a SHA-crypt hash
+// or a "$5$[rounds=<n>$]<salt>" prefix of one.
+func parseSHACrypt(setting string) (shaCryptSetting, error) {
+	var parsed shaCryptSetting
+	switch {
+	case strings.HasPrefix(setting, shaCrypt256.prefix):
+		parsed.scheme = shaCrypt256
+	case strings.HasPrefix(setting, shaCrypt512.prefix):
+		parsed.scheme = shaCrypt512
+	default:
+		return parsed, errInvalidSHACrypt
+	}
+
+	rest := setting[len(parsed.scheme.prefix):]
+	parsed.rounds = shaCryptRoundsDefault
+	if value, ok := strings.CutPrefix(rest, shaCryptRoundsPrefix); ok {
+		number, tail, found := strings.Cut(value, "$")
+		n, err := strconv.Atoi(number)
+		if !found || err != nil {
+			return parsed, errInvalidSHACrypt
+		}
+		parsed.rounds = min(max(n, shaCryptRoundsMin), shaCryptRoundsMax)
+		parsed.customRounds = true
+		rest = tail
+	}
+
+	parsed.salt, _, _ = strings.Cut(rest, "$")
+	if len(parsed.salt) > shaCryptSaltMax {
+		parsed.salt = parsed.salt[:shaCryptSaltMax]
+	}
+
+	return parsed, nil
+}
+
+// shaCrypt computes the SHA-crypt hash ($5$ for SHA-256, $6$ for SHA-512) of
+// password using the parameters of setting.
+func shaCrypt(password, setting string) (string, error) {
+	parsed, err := parseSHACrypt(setting)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(parsed.scheme.prefix)
+	if parsed.customRounds {
+		b.WriteString(shaCryptRoundsPrefix + strconv.Itoa(parsed.rounds) + "$")
+	}
+	b.WriteString(parsed.salt)
+	b.WriteByte('$')
+	digest := parsed.scheme.sum([]byte(password), []byte(parsed.salt), parsed.rounds)
+	parsed.scheme.encode(&b, digest)
+
+	return b.String(), nil
+}
+
+// sum computes the SHA-crypt digest of password.
+func (s shaCryptScheme) sum(password, salt []byte, rounds int) []byte {
+	h := s.newHash()
+
+	h.Write(password)
+	h.Write(salt)
+	h.Write(password)
+	alternate := h.Sum(nil)
+
+	h.Reset()
+	h.Write(password)
+	h.Write(salt)
+	h.Write(repeatBytes(alternate, len(password)))
+	for n := len(password); n > 0; n >>= 1 {
+		if n&1 != 0 {
+			h.Write(alternate)
+		} else {
+			h.Write(password)
+		}
+	}
+	digest := h.Sum(nil)
+
+	h.Reset()
+	for range len(password) {
+		h.Write(password)
+	}
+	passwordSeq := repeatBytes(h.Sum(nil), len(password))
+
+	h.Reset()
+	for range 16 + int(digest[0]) {
+		h.Write(salt)
+	}
+	saltSeq := repeatBytes(h.Sum(nil), len(salt))
+
+	for i := range rounds {
+		h.Reset()
+		if i%2 != 0 {
+			h.Write(passwordSeq)
+		} else {
+			h.Write(digest)
+		}
+		if i%3 != 0 {
+			h.Write(saltSeq)
+		}
+		if i%7 != 0 {
+			h.Write(passwordSeq)
+		}
+		if i%2 != 0 {
+			h.Write(digest)
+		} else {
+			h.Write(passwordSeq)
+		}
+		digest = h.Sum(digest[:0])
+	}
+
+	return digest
+}
+
+// encode writes digest in the SHA-crypt base64 encoding.
+func (s shaCryptScheme) encode(b *strings.Builder, digest []byte) {
+	for i, group := range s.order {
+		var w uint32
+		for _, idx := range group {
+			w <<= 8
+			if idx >= 0 {
+				w |= uint32(digest[idx])
+			}
+		}
+
+		chars := 4
+		if i == len(s.order)-1 {
+			chars = s.tail
+		}
+		for range chars {
+			b.WriteByte(shaCryptAlphabet[w&0x3f])
+			w >>= 6
+		}
+	}
+}
+
+// repeatBytes returns b repeated up to a length of n bytes.
+func repeatBytes(b []byte, n int) []byte {
+	out := make([]byte, 0, n)
+	for len(out) < n {
+		out = append(out, b[:min(len(b), n-len(out))]...)
+	}
+	return out
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// WebSocketTokenProtocol is the Sec-WebSocket-Protocol value that marks the
+// next offered subprotocol as a bearer token. Browsers cannot set headers on
+// WebSocket requests, so they pass a token as
+// new WebSocket(url, ["access_token", token]).
+const WebSocketTokenProtocol = "access_token"
+
+// WebSocketProtocolToken returns the bearer token offered in the
+// Sec-WebSocket-Protocol header of r, or an empty string if there is none.
+func WebSocketProtocolToken(r *http.Request) string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			protocols = append(protocols, strings.TrimSpace(protocol))
+		}
+	}
+
+	for i := 0; i+1 < len(protocols); i++ {
+		if protocols[i] == WebSocketTokenProtocol {
+			return protocols[i+1]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,46 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+)
+
+func TestWebSocketProtocolToken(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		headers []string
+		want    string
+	}{
+		{"no header", nil, ""},
+		{"token after marker", []string{"access_token, eyJhbGciOi.payload.sig"}, "eyJhbGciOi.payload.sig"},
+		{"marker among other protocols", []string{"graphql-transport-ws, access_token,tok"}, "tok"},
+		{"marker and token in separate headers", []string{"access_token", "tok"}, "tok"},
+		{"marker without token", []string{"chat, access_token"}, ""},
+		{"token without marker", []string{"chat, tok"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			for _, h := range tt.headers {
+				req.Header.Add("Sec-WebSocket-Protocol", h)
+			}
+
+			// Act
+			got := auth.WebSocketProtocolToken(req)
+
+			// Assert
+			if got != tt.want {
+				t.Errorf("WebSocketProtocolToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package authz
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+)
+
+// Resource kinds recorded in authz_decisions_total.
+const (
+	resourceRoute   = "route"
+	resourceGraphQL = "graphql_field"
+)
+
+// ErrForbidden is the error all denied authorization decisions match.
+var ErrForbidden = errors.New("forbidden")
+
+// ForbiddenError describes a denied authorization decision. It matches
+// ErrForbidden with errors.Is.
+type ForbiddenError struct {
+	// Resource is the denied operation, e.g. "DELETE /api/v1/items/{id}" or
+	// "Mutation.deleteItem".
+	Resource string
+
+	// RequiredRoles are the roles that would have been allowed; empty when
+	// no rule applies to the resource.
+	RequiredRoles []string
+}
+
+// Error implements the error interface.
+func (e *ForbiddenError) Error() string {
+	if len(e.RequiredRoles) == 0 {
+		return fmt.Sprintf("forbidden: no policy allows %s", e.Resource)
+	}
+	return fmt.Sprintf("forbidden: %s requires one of roles: %s",
+		e.Resource, strings.Join(e.RequiredRoles, ", "))
+}
+
+// Unwrap returns ErrForbidden.
+func (e *ForbiddenError) Unwrap() error {
+	return ErrForbidden
+}
+
+// Authorizer decides whether authenticated callers may perform an operation
+// according to a Policy. A nil Authorizer allows everything, so callers need
+// not check whether authorization is enabled. It is safe for concurrent use.
+type Authorizer struct {
+	policy     Policy
+	roleClaims [][]string
+}
+
+// NewAuthorizer creates an Authorizer for the given policy.
+func NewAuthorizer(policy *Policy) (*Authorizer, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	a := &Authorizer{policy: *policy}
+
+	claims := policy.RoleClaims
+	if len(claims) == 0 {
+		claims = DefaultRoleClaims
+	}
+	for _, claim := range claims {
+		a.roleClaims = append(a.roleClaims, strings.Split(claim, "."))
+	}
+
+	return a, nil
+}
+
+// Roles returns the sorted roles bound to the identity described by info.
+func (a *Authorizer) Roles(info *auth.AuthInfo) []string {
+	if a == nil || info == nil {
+		return nil
+	}
+
+	b := a.policy.Bindings
+	roles := slices.Clone(b.Authenticated)
+
+	switch info.Method {
+	case auth.AuthMethodAPIKey:
+		roles = append(roles, b.APIKeys[info.Subject]...)
+		// A scoped key only holds the roles that are among its scopes.
+		if scopes, ok := info.Claims["scopes"].([]string); ok {
+			roles = slices.DeleteFunc(roles, func(role string) bool {
+				return !slices.Contains(scopes, role)
+			})
+		}
+	case auth.AuthMethodMTLS:
+		roles = append(roles, b.Subjects[info.Subject]...)
+		if orgs, ok := info.Claims["organizations"].([]string); ok {
+			for _, org := range orgs {
+				roles = append(roles, b.Organizations[org]...)
+			}
+		}
+	case auth.AuthMethodOIDC, auth.AuthMethodIntrospection:
+		roles = append(roles, b.Subjects[info.Subject]...)
+		for _, group := range a.groups(info.Claims) {
+			roles = append(roles, b.Groups[group]...)
+		}
+	default:
+		roles = append(roles, b.Subjects[info.Subject]...)
+	}
+
+	slices.Sort(roles)
+	return slices.Compact(roles)
+}
+
+// AuthorizeRequest authorizes an HTTP request against the route rules. The
+// route is identified by the template of the matched mux route, falling back
+// to the request path.
+func (a *Authorizer) AuthorizeRequest(info *auth.AuthInfo, r *http.Request) error {
+	path := r.URL.Path
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			path = tmpl
+		}
+	}
+	return a.AuthorizeRoute(info, r.Method, path)
+}
+
+// AuthorizeRoute authorizes a call of the route with the given method and
+// path template. It returns a *ForbiddenError when the call is denied.
+func (a *Authorizer) AuthorizeRoute(info *auth.AuthInfo, method, path string) error {
+	if a == nil {
+		return nil
+	}
+
+	resource := method + " " + path
+	for _, rule := range a.policy.Routes {
+		if !rule.matches(method, path) {
+			continue
+		}
+		return a.decide(resourceRoute, resource, info, rule.Roles)
+	}
+
+	return a.decide(resourceRoute, resource, info, nil)
+}
+
+// AuthorizeField authorizes the resolution of a GraphQL field. Fields without
+// a rule are allowed and not recorded as decisions. It returns a
+// *ForbiddenError when the field is denied.
+func (a *Authorizer) AuthorizeField(info *auth.AuthInfo, typeName, fieldName string) error {
+	if a == nil {
+		return nil
+	}
+
+	resource := typeName + "." + fieldName
+	roles, ok := a.policy.GraphQL[resource]
+	if !ok {
+		roles, ok = a.policy.GraphQL[typeName+".*"]
+	}
+	if !ok {
+		return nil
+	}
+
+	return a.decide(resourceGraphQL, resource, info, roles)
+}
+
+// decide allows the caller when it holds any of the allowed roles and records
+// the decision.
+func (a *Authorizer) decide(kind, resource string, info *auth.AuthInfo, allowed []string) error {
+	for _, role := range a.Roles(info) {
+		if slices.Contains(allowed, role) {
+			observability.AuthzDecisionsTotal.
+				WithLabelValues(kind, observability.DecisionAllow).
+				Inc()
+			return nil
+		}
+	}
+
+	observability.AuthzDecisionsTotal.
+		WithLabelValues(kind, observability.DecisionDeny).
+		Inc()
+	return &ForbiddenError{Resource: resource, RequiredRoles: allowed}
+}
+
+// groups returns the group and role names found in the role claims. Claim
+// values may be a list of strings or a space-separated string.
+func (a *Authorizer) groups(claims map[string]any) []string {
+	var groups []string
+	for _, path := range a.roleClaims {
+		switch v := lookupClaim(claims, path).(type) {
+		case string:
+			groups = append(groups, strings.Fields(v)...)
+		case []string:
+			groups = append(groups, v...)
+		case []any:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					groups = append(groups, s)
+				}
+			}
+		}
+	}
+	return groups
+}
+
+// lookupClaim returns the claim at the given path of nested objects, or nil.
+func lookupClaim(claims map[string]any, path []string) any {
+	var value any = claims
+	for _, key := range path {
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = obj[key]
+	}
+	return value
+}
+
+// matches reports whether the rule applies to the method and path template.
+func (r *RouteRule) matches(method, path string) bool {
+	if len(r.Methods) > 0 && !slices.ContainsFunc(r.Methods, func(m string) bool {
+		return strings.EqualFold(m, method)
+	}) {
+		return false
+	}
+
+	if prefix, ok := strings.CutSuffix(r.Path, "*"); ok {
+		return strings.HasPrefix(path, prefix)
+	}
+	return r.Path == path
+}
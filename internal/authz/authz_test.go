@@ -0,0 +1,342 @@
+package authz_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/authz"
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+)
+
+func newTestAuthorizer(t *testing.T) *authz.Authorizer {
+	t.Helper()
+
+	a, err := authz.NewAuthorizer(&authz.Policy{
+		RoleClaims: []string{"groups", "realm_access.roles"},
+		Bindings: authz.Bindings{
+			Authenticated: []string{"reader"},
+			Subjects:      map[string][]string{"alice": {"admin"}},
+			Groups:        map[string][]string{"editors": {"writer"}, "ops": {"admin"}},
+			Organizations: map[string][]string{"Acme": {"writer"}},
+			APIKeys:       map[string][]string{"ci": {"writer"}, "alice": {"auditor"}},
+		},
+		Routes: []authz.RouteRule{
+			{Methods: []string{"GET"}, Path: "/api/v1/items*", Roles: []string{"reader"}},
+			{Methods: []string{"DELETE"}, Path: "/api/v1/items/{id}", Roles: []string{"admin"}},
+			{Path: "/api/v1/items*", Roles: []string{"writer", "admin"}},
+			{Path: "/graphql", Roles: []string{"reader"}},
+		},
+		GraphQL: map[string][]string{
+			"Mutation.deleteItem": {"admin"},
+			"Mutation.*":          {"writer", "admin"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error = %v", err)
+	}
+	return a
+}
+
+func TestAuthorizer_Roles(t *testing.T) {
+	tests := []struct {
+		name string
+		info *auth.AuthInfo
+		want []string
+	}{
+		{name: "no identity", info: nil, want: nil},
+		{
+			name: "basic auth subject",
+			info: &auth.AuthInfo{Method: auth.AuthMethodBasic, Subject: "alice"},
+			want: []string{"admin", "reader"},
+		},
+		{
+			name: "API key name",
+			info: &auth.AuthInfo{Method: auth.AuthMethodAPIKey, Subject: "ci"},
+			want: []string{"reader", "writer"},
+		},
+		{
+			name: "API key names are not subjects",
+			info: &auth.AuthInfo{Method: auth.AuthMethodAPIKey, Subject: "alice"},
+			want: []string{"auditor", "reader"},
+		},
+		{
+			name: "scoped API key",
+			info: &auth.AuthInfo{
+				Method:  auth.AuthMethodAPIKey,
+				Subject: "ci",
+				Claims:  map[string]any{"scopes": []string{"reader", "admin"}},
+			},
+			want: []string{"reader"},
+		},
+		{
+			name: "mTLS organizations",
+			info: &auth.AuthInfo{
+				Method:  auth.AuthMethodMTLS,
+				Subject: "client.example.com",
+				Claims:  map[string]any{"organizations": []string{"Acme", "Other"}},
+			},
+			want: []string{"reader", "writer"},
+		},
+		{
+			name: "OIDC groups claim",
+			info: &auth.AuthInfo{
+				Method:  auth.AuthMethodOIDC,
+				Subject: "user-1",
+				Claims:  map[string]any{"groups": []any{"editors", 42}},
+			},
+			want: []string{"reader", "writer"},
+		},
+		{
+			name: "OIDC nested roles claim",
+			info: &auth.AuthInfo{
+				Method:  auth.AuthMethodOIDC,
+				Subject: "user-2",
+				Claims: map[string]any{
+					"realm_access": map[string]any{"roles": []any{"ops"}},
+				},
+			},
+			want: []string{"admin", "reader"},
+		},
+		{
+			name: "OIDC space-separated claim",
+			info: &auth.AuthInfo{
+				Method:  auth.AuthMethodOIDC,
+				Subject: "user-3",
+				Claims:  map[string]any{"groups": "editors ops"},
+			},
+			want: []string{"admin", "reader", "writer"},
+		},
+		{
+			name: "introspected token groups claim",
+			info: &auth.AuthInfo{
+				Method:  auth.AuthMethodIntrospection,
+				Subject: "user-4",
+				Claims:  map[string]any{"groups": []any{"ops"}},
+			},
+			want: []string{"admin", "reader"},
+		},
+	}
+
+	a := newTestAuthorizer(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			got := a.Roles(tt.info)
+
+			// Assert
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Roles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizer_AuthorizeRoute(t *testing.T) {
+	reader := &auth.AuthInfo{Method: auth.AuthMethodAPIKey, Subject: "viewer"}
+	writer := &auth.AuthInfo{Method: auth.AuthMethodAPIKey, Subject: "ci"}
+	admin := &auth.AuthInfo{Method: auth.AuthMethodBasic, Subject: "alice"}
+
+	tests := []struct {
+		name      string
+		info      *auth.AuthInfo
+		method    string
+		path      string
+		wantRoles []string // required roles reported on denial; nil when allowed
+		wantDeny  bool
+	}{
+		{name: "reader lists items", info: reader, method: http.MethodGet, path: "/api/v1/items"},
+		{name: "reader gets an item", info: reader, method: http.MethodGet, path: "/api/v1/items/{id}"},
+		{
+			name: "reader cannot create", info: reader, method: http.MethodPost, path: "/api/v1/items",
+			wantDeny: true, wantRoles: []string{"writer", "admin"},
+		},
+		{name: "writer creates", info: writer, method: http.MethodPost, path: "/api/v1/items"},
+		{
+			name: "writer cannot delete", info: writer, method: http.MethodDelete, path: "/api/v1/items/{id}",
+			wantDeny: true, wantRoles: []string{"admin"},
+		},
+		{name: "admin deletes", info: admin, method: http.MethodDelete, path: "/api/v1/items/{id}"},
+		{name: "methods match case-insensitively", info: reader, method: "get", path: "/api/v1/items"},
+		{name: "exact path", info: reader, method: http.MethodPost, path: "/graphql"},
+		{name: "no matching rule", info: admin, method: http.MethodGet, path: "/ws", wantDeny: true},
+		{
+			name: "no identity", info: nil, method: http.MethodGet, path: "/api/v1/items",
+			wantDeny: true, wantRoles: []string{"reader"},
+		},
+	}
+
+	a := newTestAuthorizer(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := a.AuthorizeRoute(tt.info, tt.method, tt.path)
+
+			// Assert
+			if !tt.wantDeny {
+				if err != nil {
+					t.Fatalf("AuthorizeRoute() unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, authz.ErrForbidden) {
+				t.Fatalf("AuthorizeRoute() error = %v, want ErrForbidden", err)
+			}
+			var denied *authz.ForbiddenError
+			if !errors.As(err, &denied) {
+				t.Fatalf("AuthorizeRoute() error %T is not a *ForbiddenError", err)
+			}
+			if want := tt.method + " " + tt.path; denied.Resource != want {
+				t.Errorf("Resource = %q, want %q", denied.Resource, want)
+			}
+			if !slices.Equal(denied.RequiredRoles, tt.wantRoles) {
+				t.Errorf("RequiredRoles = %v, want %v", denied.RequiredRoles, tt.wantRoles)
+			}
+		})
+	}
+}
+
+func TestAuthorizer_AuthorizeField(t *testing.T) {
+	reader := &auth.AuthInfo{Method: auth.AuthMethodAPIKey, Subject: "viewer"}
+	writer := &auth.AuthInfo{Method: auth.AuthMethodAPIKey, Subject: "ci"}
+	admin := &auth.AuthInfo{Method: auth.AuthMethodBasic, Subject: "alice"}
+
+	tests := []struct {
+		name      string
+		info      *auth.AuthInfo
+		typeName  string
+		fieldName string
+		wantDeny  bool
+	}{
+		{name: "field without rule", info: reader, typeName: "Query", fieldName: "items"},
+		{name: "type wildcard denies", info: reader, typeName: "Mutation", fieldName: "createItem", wantDeny: true},
+		{name: "type wildcard allows", info: writer, typeName: "Mutation", fieldName: "createItem"},
+		{name: "field rule overrides wildcard", info: writer, typeName: "Mutation", fieldName: "deleteItem",
+			wantDeny: true},
+		{name: "field rule allows", info: admin, typeName: "Mutation", fieldName: "deleteItem"},
+	}
+
+	a := newTestAuthorizer(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := a.AuthorizeField(tt.info, tt.typeName, tt.fieldName)
+
+			// Assert
+			if got := errors.Is(err, authz.ErrForbidden); got != tt.wantDeny {
+				t.Errorf("AuthorizeField() error = %v, want denied = %v", err, tt.wantDeny)
+			}
+		})
+	}
+}
+
+func TestAuthorizer_AuthorizeRequest_UsesRouteTemplate(t *testing.T) {
+	// Arrange
+	a := newTestAuthorizer(t)
+	writer := &auth.AuthInfo{Method: auth.AuthMethodAPIKey, Subject: "ci"}
+
+	var err error
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/items/{id}", func(_ http.ResponseWriter, r *http.Request) {
+		err = a.AuthorizeRequest(writer, r)
+	})
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/items/abc", nil)
+
+	// Act
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Assert
+	var denied *authz.ForbiddenError
+	if !errors.As(err, &denied) {
+		t.Fatalf("AuthorizeRequest() error = %v, want *ForbiddenError", err)
+	}
+	if denied.Resource != "DELETE /api/v1/items/{id}" {
+		t.Errorf("Resource = %q, want DELETE /api/v1/items/{id}", denied.Resource)
+	}
+}
+
+func TestAuthorizer_Nil(t *testing.T) {
+	// Arrange
+	var a *authz.Authorizer
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/items/abc", nil)
+
+	// Act & Assert
+	if err := a.AuthorizeRequest(nil, req); err != nil {
+		t.Errorf("AuthorizeRequest() error = %v, want nil", err)
+	}
+	if err := a.AuthorizeField(nil, "Mutation", "deleteItem"); err != nil {
+		t.Errorf("AuthorizeField() error = %v, want nil", err)
+	}
+	if roles := a.Roles(&auth.AuthInfo{Subject: "alice"}); roles != nil {
+		t.Errorf("Roles() = %v, want nil", roles)
+	}
+}
+
+func TestAuthorizer_RecordsDecisions(t *testing.T) {
+	// Arrange
+	a := newTestAuthorizer(t)
+	reader := &auth.AuthInfo{Method: auth.AuthMethodAPIKey, Subject: "viewer"}
+	observability.AuthzDecisionsTotal.Reset()
+
+	// Act
+	_ = a.AuthorizeRoute(reader, http.MethodGet, "/api/v1/items")
+	_ = a.AuthorizeRoute(reader, http.MethodPost, "/api/v1/items")
+	_ = a.AuthorizeField(reader, "Mutation", "createItem")
+	_ = a.AuthorizeField(reader, "Query", "items") // no rule: not a decision
+
+	// Assert
+	tests := []struct {
+		resource string
+		decision string
+		want     float64
+	}{
+		{"route", observability.DecisionAllow, 1},
+		{"route", observability.DecisionDeny, 1},
+		{"graphql_field", observability.DecisionAllow, 0},
+		{"graphql_field", observability.DecisionDeny, 1},
+	}
+	for _, tt := range tests {
+		got := testutil.ToFloat64(observability.AuthzDecisionsTotal.WithLabelValues(tt.resource, tt.decision))
+		if got != tt.want {
+			t.Errorf("authz_decisions_total{resource=%q,decision=%q} = %v, want %v",
+				tt.resource, tt.decision, got, tt.want)
+		}
+	}
+}
+
+func TestForbiddenError_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *authz.ForbiddenError
+		want string
+	}{
+		{
+			name: "with roles",
+			err:  &authz.ForbiddenError{Resource: "Mutation.deleteItem", RequiredRoles: []string{"admin", "owner"}},
+			want: "forbidden: Mutation.deleteItem requires one of roles: admin, owner",
+		},
+		{
+			name: "without rule",
+			err:  &authz.ForbiddenError{Resource: "GET /ws"},
+			want: "forbidden: no policy allows GET /ws",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act & Assert
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
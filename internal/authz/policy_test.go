@@ -0,0 +1,162 @@
+package authz_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vyrodovalexey/restapi-example/internal/authz"
+)
+
+func writePolicyFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("writing policy file: %v", err)
+	}
+	return path
+}
+
+func TestLoadPolicy(t *testing.T) {
+	// Arrange
+	path := writePolicyFile(t, `{
+		"role_claims": ["realm_access.roles"],
+		"bindings": {
+			"authenticated": ["reader"],
+			"api_keys": {"ci": ["writer"]}
+		},
+		"routes": [
+			{"methods": ["GET"], "path": "/api/v1/items*", "roles": ["reader"]}
+		],
+		"graphql": {"Mutation.*": ["writer"]}
+	}`)
+
+	// Act
+	policy, err := authz.LoadPolicy(path)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("LoadPolicy() error = %v", err)
+	}
+	if len(policy.RoleClaims) != 1 || policy.RoleClaims[0] != "realm_access.roles" {
+		t.Errorf("RoleClaims = %v, want [realm_access.roles]", policy.RoleClaims)
+	}
+	if got := policy.Bindings.APIKeys["ci"]; len(got) != 1 || got[0] != "writer" {
+		t.Errorf("Bindings.APIKeys[ci] = %v, want [writer]", got)
+	}
+	if len(policy.Routes) != 1 || policy.Routes[0].Path != "/api/v1/items*" {
+		t.Errorf("Routes = %+v, want one rule for /api/v1/items*", policy.Routes)
+	}
+	if got := policy.GraphQL["Mutation.*"]; len(got) != 1 || got[0] != "writer" {
+		t.Errorf("GraphQL[Mutation.*] = %v, want [writer]", got)
+	}
+}
+
+func TestLoadPolicy_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		missing     bool
+		wantInvalid bool
+	}{
+		{name: "missing file", missing: true},
+		{name: "invalid JSON", content: `{"routes": [`, wantInvalid: true},
+		{
+			name:        "invalid rule",
+			content:     `{"routes": [{"path": "/api/v1/items"}]}`,
+			wantInvalid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			path := filepath.Join(t.TempDir(), "missing.json")
+			if !tt.missing {
+				path = writePolicyFile(t, tt.content)
+			}
+
+			// Act
+			_, err := authz.LoadPolicy(path)
+
+			// Assert
+			if err == nil {
+				t.Fatal("LoadPolicy() expected error, got nil")
+			}
+			if got := errors.Is(err, authz.ErrInvalidPolicy); got != tt.wantInvalid {
+				t.Errorf("errors.Is(err, ErrInvalidPolicy) = %v, want %v (err = %v)", got, tt.wantInvalid, err)
+			}
+		})
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  authz.Policy
+		wantErr bool
+	}{
+		{name: "empty policy", policy: authz.Policy{}},
+		{
+			name: "valid rules",
+			policy: authz.Policy{
+				Routes: []authz.RouteRule{
+					{Methods: []string{"get", "POST"}, Path: "/api/v1/items", Roles: []string{"writer"}},
+				},
+				GraphQL: map[string][]string{"Query.items": {"reader"}},
+			},
+		},
+		{
+			name:    "route without path",
+			policy:  authz.Policy{Routes: []authz.RouteRule{{Roles: []string{"reader"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "route without roles",
+			policy:  authz.Policy{Routes: []authz.RouteRule{{Path: "/ws"}}},
+			wantErr: true,
+		},
+		{
+			name: "route with unknown method",
+			policy: authz.Policy{Routes: []authz.RouteRule{
+				{Methods: []string{"FETCH"}, Path: "/ws", Roles: []string{"reader"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "GraphQL rule without field",
+			policy:  authz.Policy{GraphQL: map[string][]string{"Query": {"reader"}}},
+			wantErr: true,
+		},
+		{
+			name:    "GraphQL rule without roles",
+			policy:  authz.Policy{GraphQL: map[string][]string{"Query.items": {}}},
+			wantErr: true,
+		},
+		{
+			name:    "empty role claim",
+			policy:  authz.Policy{RoleClaims: []string{""}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := tt.policy.Validate()
+
+			// Assert
+			if tt.wantErr {
+				if !errors.Is(err, authz.ErrInvalidPolicy) {
+					t.Errorf("Validate() error = %v, want ErrInvalidPolicy", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Validate() unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+)
+
+// sourceFile names certificates read from disk in metrics and logs.
+const sourceFile = "file"
+
+// FileConfig names the PEM files a FileReloader serves.
+type FileConfig struct {
+	// CertPath and KeyPath hold the server certificate and private key. They
+	// are empty when the certificate is provided by the base config instead.
+	CertPath string
+	KeyPath  string
+	// CAPath holds the CA bundle client certificates are verified against.
+	// When empty, the base config's ClientCAs are used.
+	CAPath string
+	// Interval is how often the files are checked for changes; zero
+	// disables reloading.
+	Interval time.Duration
+}
+
+// FileReloader serves a TLS server certificate and client CA bundle read
+// from disk and reloads them when the files change, e.g. after cert-manager
+// renewed a mounted secret. The certificate and CA bundle are swapped
+// together, so a handshake never sees a mix of old and new files. When a
+// reload fails the previous files keep being served.
+type FileReloader struct {
+	config FileConfig
+	base   *tls.Config
+	logger *zap.Logger
+
+	current      atomic.Pointer[fileBundle]
+	failedDigest [sha256.Size]byte // contents that last failed to parse
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// fileBundle is one consistent set of loaded files.
+type fileBundle struct {
+	digest [sha256.Size]byte
+	cert   *tls.Certificate
+	config *tls.Config // served by GetConfigForClient
+}
+
+// fileContents holds the raw contents of the watched files.
+type fileContents struct {
+	cert, key, ca []byte
+}
+
+// NewFileReloader loads the files named by config and returns a
+// FileReloader serving them on top of base. Call Start to begin watching
+// the files for changes.
+func NewFileReloader(config FileConfig, base *tls.Config, logger *zap.Logger) (*FileReloader, error) {
+	r := &FileReloader{
+		config: config,
+		base:   base,
+		logger: logger,
+		stop:   make(chan struct{}),
+	}
+
+	contents, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	bundle, err := r.parse(contents)
+	if err != nil {
+		return nil, err
+	}
+	r.store(bundle)
+
+	return r, nil
+}
+
+// TLSConfig returns a copy of the base config that serves the current
+// certificate and client CA bundle.
+func (r *FileReloader) TLSConfig() *tls.Config {
+	config := r.base.Clone()
+	config.ClientCAs = r.current.Load().config.ClientCAs
+	if r.config.CertPath != "" {
+		config.GetCertificate = r.GetCertificate
+	}
+	config.GetConfigForClient = r.GetConfigForClient
+	return config
+}
+
+// GetCertificate returns the current certificate. It is meant for
+// tls.Config.GetCertificate.
+func (r *FileReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := r.current.Load().cert
+	if cert == nil {
+		return nil, ErrNoCertificate
+	}
+	return cert, nil
+}
+
+// GetConfigForClient returns the TLS config for a new connection, holding
+// the current certificate and client CA bundle. It is meant for
+// tls.Config.GetConfigForClient.
+func (r *FileReloader) GetConfigForClient(_ *tls.ClientHelloInfo) (*tls.Config, error) {
+	return r.current.Load().config, nil
+}
+
+// Start watches the files for changes until Stop is called. It does nothing
+// when no reload interval is configured.
+func (r *FileReloader) Start() {
+	if r.config.Interval <= 0 {
+		return
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.watch()
+	}()
+}
+
+// Stop ends watching the files and waits for it to finish. The current
+// files continue to be served.
+func (r *FileReloader) Stop() {
+	r.stopOnce.Do(func() { close(r.stop) })
+	r.wg.Wait()
+}
+
+// watch reloads the files every interval until Stop is called.
+func (r *FileReloader) watch() {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.reload()
+		}
+	}
+}
+
+// reload serves the files if their contents changed and can be parsed.
+// Contents that failed to parse are reported once, not on every check.
+func (r *FileReloader) reload() {
+	contents, err := r.read()
+	if err != nil {
+		r.reloadFailed(err)
+		return
+	}
+
+	digest := contents.digest()
+	if digest == r.current.Load().digest || digest == r.failedDigest {
+		return
+	}
+
+	bundle, err := r.parse(contents)
+	if err != nil {
+		r.failedDigest = digest
+		r.reloadFailed(err)
+		return
+	}
+	r.store(bundle)
+}
+
+// reloadFailed records a failed reload.
+func (r *FileReloader) reloadFailed(err error) {
+	observability.TLSCertificateRenewalsTotal.
+		WithLabelValues(sourceFile, observability.ResultFailure).
+		Inc()
+	r.logger.Error("failed to reload TLS files, keeping the current ones",
+		zap.String("cert_path", r.config.CertPath),
+		zap.String("ca_path", r.config.CAPath),
+		zap.Error(err),
+	)
+}
+
+// read returns the contents of the configured files.
+func (r *FileReloader) read() (fileContents, error) {
+	var contents fileContents
+	var err error
+
+	if r.config.CertPath != "" {
+		if contents.cert, err = os.ReadFile(r.config.CertPath); err != nil {
+			return contents, fmt.Errorf("loading TLS key pair: %w", err)
+		}
+		if contents.key, err = os.ReadFile(r.config.KeyPath); err != nil {
+			return contents, fmt.Errorf("loading TLS key pair: %w", err)
+		}
+	}
+
+	if r.config.CAPath != "" {
+		if contents.ca, err = os.ReadFile(r.config.CAPath); err != nil {
+			return contents, fmt.Errorf("reading TLS CA cert: %w", err)
+		}
+	}
+
+	return contents, nil
+}
+
+// parse builds a bundle from the file contents.
+func (r *FileReloader) parse(contents fileContents) (*fileBundle, error) {
+	bundle := &fileBundle{
+		digest: contents.digest(),
+		config: r.base.Clone(),
+	}
+
+	if r.config.CertPath != "" {
+		cert, err := tls.X509KeyPair(contents.cert, contents.key)
+		if err == nil {
+			err = parseLeaf(&cert)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("loading TLS key pair: %w", err)
+		}
+		bundle.cert = &cert
+		bundle.config.Certificates = []tls.Certificate{cert}
+		bundle.config.GetCertificate = nil
+	}
+
+	if r.config.CAPath != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(contents.ca) {
+			return nil, fmt.Errorf("parsing TLS CA cert: no valid certificates found in %s", r.config.CAPath)
+		}
+		bundle.config.ClientCAs = pool
+	}
+
+	return bundle, nil
+}
+
+// store starts serving bundle.
+func (r *FileReloader) store(bundle *fileBundle) {
+	r.current.Store(bundle)
+
+	observability.TLSCertificateRenewalsTotal.
+		WithLabelValues(sourceFile, observability.ResultSuccess).
+		Inc()
+
+	fields := []zap.Field{
+		zap.String("cert_path", r.config.CertPath),
+		zap.String("ca_path", r.config.CAPath),
+	}
+	if bundle.cert != nil {
+		leaf := bundle.cert.Leaf
+		observability.TLSCertificateExpiry.
+			WithLabelValues(sourceFile).
+			Set(float64(leaf.NotAfter.Unix()))
+		fields = append(fields,
+			zap.String("subject", leaf.Subject.CommonName),
+			zap.String("serial", leaf.SerialNumber.String()),
+			zap.Time("not_after", leaf.NotAfter),
+		)
+	}
+
+	r.logger.Info("TLS files loaded", fields...)
+}
+
+// digest identifies the file contents.
+func (c fileContents) digest() [sha256.Size]byte {
+	return sha256.Sum256(bytes.Join([][]byte{c.cert, c.key, c.ca}, []byte{0}))
+}
@@ -0,0 +1,275 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+)
+
+// testFiles names the certificate, key and CA files of a test.
+type testFiles struct {
+	cert, key, ca string
+}
+
+func newTestFiles(t *testing.T) testFiles {
+	t.Helper()
+
+	dir := t.TempDir()
+	return testFiles{
+		cert: filepath.Join(dir, "tls.crt"),
+		key:  filepath.Join(dir, "tls.key"),
+		ca:   filepath.Join(dir, "ca.crt"),
+	}
+}
+
+// writeFile replaces the contents of path.
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+// writeKeyPair writes a certificate issued by ca for commonName.
+func (f testFiles) writeKeyPair(t *testing.T, ca *testCA, commonName string) {
+	t.Helper()
+
+	certPEM, keyPEM := ca.issue(t, vaultIssueRequest{CommonName: commonName, IPSANs: "127.0.0.1"}, time.Hour)
+	writeFile(t, f.cert, certPEM)
+	writeFile(t, f.key, keyPEM)
+}
+
+// servedCommonName returns the common name of the certificate r serves.
+func servedCommonName(t *testing.T, r *FileReloader) string {
+	t.Helper()
+
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	return cert.Leaf.Subject.CommonName
+}
+
+func TestNewFileReloader_Errors(t *testing.T) {
+	ca := newTestCA(t)
+
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, f testFiles)
+		wantMsg string
+	}{
+		{
+			name:    "missing certificate",
+			prepare: func(*testing.T, testFiles) {},
+			wantMsg: "loading TLS key pair",
+		},
+		{
+			name: "mismatched key",
+			prepare: func(t *testing.T, f testFiles) {
+				f.writeKeyPair(t, ca, "server")
+				_, otherKey := ca.issue(t, vaultIssueRequest{CommonName: "other"}, time.Hour)
+				writeFile(t, f.key, otherKey)
+				writeFile(t, f.ca, ca.pem)
+			},
+			wantMsg: "loading TLS key pair",
+		},
+		{
+			name:    "missing CA bundle",
+			prepare: func(t *testing.T, f testFiles) { f.writeKeyPair(t, ca, "server") },
+			wantMsg: "reading TLS CA cert",
+		},
+		{
+			name: "invalid CA bundle",
+			prepare: func(t *testing.T, f testFiles) {
+				f.writeKeyPair(t, ca, "server")
+				writeFile(t, f.ca, "not a certificate")
+			},
+			wantMsg: "parsing TLS CA cert: no valid certificates found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			files := newTestFiles(t)
+			tt.prepare(t, files)
+			config := FileConfig{CertPath: files.cert, KeyPath: files.key, CAPath: files.ca}
+
+			// Act
+			_, err := NewFileReloader(config, &tls.Config{MinVersion: tls.VersionTLS12}, zap.NewNop())
+
+			// Assert
+			if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("NewFileReloader() error = %v, want it to contain %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestFileReloader_ReloadsChangedFiles(t *testing.T) {
+	// Arrange
+	ca := newTestCA(t)
+	files := newTestFiles(t)
+	files.writeKeyPair(t, ca, "first")
+	writeFile(t, files.ca, ca.pem)
+
+	config := FileConfig{CertPath: files.cert, KeyPath: files.key, CAPath: files.ca, Interval: 10 * time.Millisecond}
+	r, err := NewFileReloader(config, &tls.Config{MinVersion: tls.VersionTLS12}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewFileReloader() error = %v", err)
+	}
+	r.Start()
+	defer r.Stop()
+
+	// Act
+	files.writeKeyPair(t, ca, "second")
+
+	// Assert
+	waitFor(t, func() bool { return servedCommonName(t, r) == "second" })
+
+	config2, _ := r.GetConfigForClient(nil)
+	if len(config2.Certificates) != 1 || config2.Certificates[0].Leaf.Subject.CommonName != "second" {
+		t.Error("GetConfigForClient() should serve the reloaded certificate")
+	}
+	cert, _ := r.GetCertificate(nil)
+	want := float64(cert.Leaf.NotAfter.Unix())
+	if got := testutil.ToFloat64(observability.TLSCertificateExpiry.WithLabelValues(sourceFile)); got != want {
+		t.Errorf("tls_certificate_expiry_timestamp_seconds{source=file} = %v, want %v", got, want)
+	}
+}
+
+func TestFileReloader_KeepsServingOnInvalidFiles(t *testing.T) {
+	// Arrange
+	ca := newTestCA(t)
+	files := newTestFiles(t)
+	files.writeKeyPair(t, ca, "good")
+
+	config := FileConfig{CertPath: files.cert, KeyPath: files.key, Interval: 10 * time.Millisecond}
+	r, err := NewFileReloader(config, &tls.Config{MinVersion: tls.VersionTLS12}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewFileReloader() error = %v", err)
+	}
+	failures := observability.TLSCertificateRenewalsTotal.WithLabelValues(sourceFile, observability.ResultFailure)
+	before := testutil.ToFloat64(failures)
+
+	r.Start()
+	defer r.Stop()
+
+	// Act
+	writeFile(t, files.cert, "not a certificate")
+	waitFor(t, func() bool { return testutil.ToFloat64(failures) > before })
+	time.Sleep(50 * time.Millisecond)
+
+	// Assert
+	if got := servedCommonName(t, r); got != "good" {
+		t.Errorf("served certificate = %q, want the previous one", got)
+	}
+	if got := testutil.ToFloat64(failures) - before; got != 1 {
+		t.Errorf("failed reloads = %v, want 1 (unchanged invalid files are reported once)", got)
+	}
+}
+
+func TestFileReloader_ReloadsClientCAs(t *testing.T) {
+	// Arrange
+	serverCA, oldClientCA, newClientCA := newTestCA(t), newTestCA(t), newTestCA(t)
+	files := newTestFiles(t)
+	files.writeKeyPair(t, serverCA, "server")
+	writeFile(t, files.ca, oldClientCA.pem)
+
+	config := FileConfig{CertPath: files.cert, KeyPath: files.key, CAPath: files.ca, Interval: 10 * time.Millisecond}
+	base := &tls.Config{MinVersion: tls.VersionTLS12, ClientAuth: tls.RequireAndVerifyClientCert}
+	r, err := NewFileReloader(config, base, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewFileReloader() error = %v", err)
+	}
+	r.Start()
+	defer r.Stop()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", r.TLSConfig())
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+
+	certPEM, keyPEM := newClientCA.issue(t, vaultIssueRequest{CommonName: "client"}, time.Hour)
+	clientCert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		t.Fatalf("failed to load client certificate: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+
+	handshake := func() error {
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: time.Second}, "tcp", listener.Addr().String(), &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			MaxVersion:   tls.VersionTLS12, // report client certificate rejection from Dial
+			RootCAs:      roots,
+			ServerName:   "127.0.0.1",
+			Certificates: []tls.Certificate{clientCert},
+		})
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	if err := handshake(); err == nil {
+		t.Fatal("handshake with a client certificate from an untrusted CA should fail")
+	}
+
+	// Act
+	writeFile(t, files.ca, newClientCA.pem)
+
+	// Assert
+	waitFor(t, func() bool { return handshake() == nil })
+}
+
+func TestFileReloader_CAOnly(t *testing.T) {
+	// Arrange
+	ca := newTestCA(t)
+	files := newTestFiles(t)
+	writeFile(t, files.ca, ca.pem)
+
+	vaultCert := func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return &tls.Certificate{}, nil }
+	base := &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: vaultCert}
+
+	// Act
+	r, err := NewFileReloader(FileConfig{CAPath: files.ca}, base, zap.NewNop())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("NewFileReloader() error = %v", err)
+	}
+	config := r.TLSConfig()
+	if config.ClientCAs == nil {
+		t.Error("ClientCAs should be loaded from the CA file")
+	}
+	if _, err := config.GetCertificate(nil); err != nil {
+		t.Errorf("GetCertificate() should come from the base config, got error %v", err)
+	}
+	forClient, _ := config.GetConfigForClient(nil)
+	if forClient.GetCertificate == nil || len(forClient.Certificates) != 0 {
+		t.Error("GetConfigForClient() should keep the base certificate source")
+	}
+}
@@ -0,0 +1,146 @@
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	// defaultOCSPCacheTTL is how long a response without a next update
+	// time is cached.
+	defaultOCSPCacheTTL = time.Hour
+	// maxOCSPCacheEntries bounds the OCSP response cache.
+	maxOCSPCacheEntries = 10000
+	// maxOCSPResponseSize bounds the size of an OCSP response.
+	maxOCSPResponseSize = 1 << 20
+)
+
+// ocspCache caches OCSP responses until their next update.
+type ocspCache struct {
+	mu      sync.Mutex
+	entries map[string]ocspCacheEntry
+}
+
+// ocspCacheEntry is a cached OCSP status.
+type ocspCacheEntry struct {
+	status  int
+	reason  int
+	expires time.Time
+}
+
+func newOCSPCache() *ocspCache {
+	return &ocspCache{entries: make(map[string]ocspCacheEntry)}
+}
+
+// get returns the cached status for key, if any.
+func (c *ocspCache) get(key string, now time.Time) (ocspCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || now.After(entry.expires) {
+		return ocspCacheEntry{}, false
+	}
+	return entry, true
+}
+
+// put caches entry for key, dropping expired entries when the cache is full.
+func (c *ocspCache) put(key string, entry ocspCacheEntry, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxOCSPCacheEntries {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxOCSPCacheEntries {
+			return
+		}
+	}
+	c.entries[key] = entry
+}
+
+// checkOCSP returns a RevokedError if the OCSP responder of cert reports it
+// as revoked, or an error wrapping ErrRevocationUnknown if the status cannot
+// be determined.
+func (c *RevocationChecker) checkOCSP(cert, issuer *x509.Certificate) error {
+	now := time.Now()
+	issuerHash := sha256.Sum256(issuer.Raw)
+	key := fmt.Sprintf("%x:%s", issuerHash, cert.SerialNumber)
+
+	entry, ok := c.ocsp.get(key, now)
+	if !ok {
+		resp, err := c.queryOCSP(cert, issuer)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrRevocationUnknown, err)
+		}
+
+		entry = ocspCacheEntry{status: resp.Status, reason: resp.RevocationReason, expires: resp.NextUpdate}
+		if resp.NextUpdate.IsZero() {
+			entry.expires = now.Add(defaultOCSPCacheTTL)
+		}
+		c.ocsp.put(key, entry, now)
+	}
+
+	switch entry.status {
+	case ocsp.Good:
+		return nil
+	case ocsp.Revoked:
+		return &RevokedError{
+			Subject: cert.Subject.CommonName,
+			Serial:  cert.SerialNumber.String(),
+			Reason:  reasonName(entry.reason),
+			Source:  RevocationSourceOCSP,
+		}
+	default:
+		return fmt.Errorf("%w: OCSP responder does not know the certificate", ErrRevocationUnknown)
+	}
+}
+
+// queryOCSP asks the first OCSP responder named in cert for its status.
+func (c *RevocationChecker) queryOCSP(cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	body, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating OCSP request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, cert.OCSPServer[0],
+		bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating OCSP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OCSP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OCSP responder returned status code %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxOCSPResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("reading OCSP response: %w", err)
+	}
+
+	parsed, err := ocsp.ParseResponseForCert(data, cert, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("parsing OCSP response: %w", err)
+	}
+	return parsed, nil
+}
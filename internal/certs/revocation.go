@@ -0,0 +1,410 @@
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+)
+
+// Revocation errors.
+var (
+	// ErrCertificateRevoked is wrapped by RevokedError.
+	ErrCertificateRevoked = errors.New("certificate revoked")
+	// ErrRevocationUnknown is returned in strict mode when the revocation
+	// status of a certificate cannot be determined.
+	ErrRevocationUnknown = errors.New("certificate revocation status unknown")
+)
+
+// Revocation sources reported in RevokedError and logs.
+const (
+	RevocationSourceCRL  = "crl"
+	RevocationSourceOCSP = "ocsp"
+)
+
+const (
+	// revocationHTTPTimeout bounds CRL downloads and OCSP requests.
+	revocationHTTPTimeout = 5 * time.Second
+	// maxCRLSize bounds the size of a downloaded CRL.
+	maxCRLSize = 32 << 20
+	// methodMTLS is the auth_attempts_total method label of mTLS clients.
+	methodMTLS = "mtls"
+)
+
+// crlReasons names the RFC 5280 revocation reason codes.
+var crlReasons = map[int]string{
+	0:  "unspecified",
+	1:  "keyCompromise",
+	2:  "cACompromise",
+	3:  "affiliationChanged",
+	4:  "superseded",
+	5:  "cessationOfOperation",
+	6:  "certificateHold",
+	8:  "removeFromCRL",
+	9:  "privilegeWithdrawn",
+	10: "aACompromise",
+}
+
+// reasonRemoveFromCRL marks delta CRL entries of certificates that are no
+// longer revoked.
+const reasonRemoveFromCRL = 8
+
+// RevokedError reports a revoked client certificate.
+type RevokedError struct {
+	Subject string // Common name of the revoked certificate.
+	Serial  string
+	Reason  string // RFC 5280 revocation reason, e.g. keyCompromise.
+	Source  string // crl or ocsp.
+}
+
+// Error implements the error interface.
+func (e *RevokedError) Error() string {
+	return fmt.Sprintf("certificate %q (serial %s) revoked by %s: %s", e.Subject, e.Serial, e.Source, e.Reason)
+}
+
+// Unwrap returns ErrCertificateRevoked.
+func (e *RevokedError) Unwrap() error {
+	return ErrCertificateRevoked
+}
+
+// RevocationConfig configures a RevocationChecker.
+type RevocationConfig struct {
+	// CRLPath and CRLURL name a CRL file and a CRL download URL (PEM or
+	// DER); either or both may be set.
+	CRLPath string
+	CRLURL  string
+	// CRLRefresh is how often the CRLs are reloaded; zero disables refresh.
+	CRLRefresh time.Duration
+	// OCSP enables checking certificates against the OCSP responder named
+	// in them. Responses are cached until their next update.
+	OCSP bool
+	// OCSPStrict rejects certificates whose OCSP status cannot be
+	// determined, or whose CRL is past its next update, instead of
+	// accepting them.
+	OCSPStrict bool
+}
+
+// RevocationChecker rejects revoked client certificates during the TLS
+// handshake. Rejections are logged with the revocation reason and counted in
+// auth_attempts_total with result "revoked".
+type RevocationChecker struct {
+	config RevocationConfig
+	client *http.Client
+	logger *zap.Logger
+
+	crls atomic.Pointer[[]*crl]
+	ocsp *ocspCache
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// crl is a parsed CRL indexed by serial number.
+type crl struct {
+	list    *x509.RevocationList
+	revoked map[string]int // serial number -> reason code
+	// verified records the issuers (by raw certificate) whose signature on
+	// the CRL has been checked.
+	verified sync.Map
+}
+
+// NewRevocationChecker loads the configured CRLs and returns a checker.
+// Call Start to begin refreshing them.
+func NewRevocationChecker(config RevocationConfig, logger *zap.Logger) (*RevocationChecker, error) {
+	c := &RevocationChecker{
+		config: config,
+		client: &http.Client{Timeout: revocationHTTPTimeout},
+		logger: logger,
+		ocsp:   newOCSPCache(),
+		stop:   make(chan struct{}),
+	}
+
+	if err := c.loadCRLs(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Start refreshes the CRLs until Stop is called. It does nothing when no
+// CRL or refresh interval is configured.
+func (c *RevocationChecker) Start() {
+	if c.config.CRLRefresh <= 0 || (c.config.CRLPath == "" && c.config.CRLURL == "") {
+		return
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(c.config.CRLRefresh)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				// The previously loaded CRLs stay in use when a refresh fails.
+				if err := c.loadCRLs(); err != nil {
+					c.logger.Error("failed to refresh CRLs, keeping the current ones", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// Stop ends CRL refresh and waits for it to finish.
+func (c *RevocationChecker) Stop() {
+	c.stopOnce.Do(func() { close(c.stop) })
+	c.wg.Wait()
+}
+
+// VerifyPeerCertificate rejects a client whose verified certificate chain
+// contains a revoked certificate. It is meant for
+// tls.Config.VerifyPeerCertificate and only sees chains verified by
+// crypto/tls, i.e. when client certificates are required and verified.
+func (c *RevocationChecker) VerifyPeerCertificate(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+	if len(verifiedChains) == 0 {
+		return nil
+	}
+
+	chain := verifiedChains[0]
+	for i := 0; i < len(chain)-1; i++ {
+		if err := c.check(chain[i], chain[i+1]); err != nil {
+			c.reject(chain[i], err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// check returns an error if cert, issued by issuer, is revoked.
+func (c *RevocationChecker) check(cert, issuer *x509.Certificate) error {
+	if err := c.checkCRL(cert, issuer); err != nil {
+		return err
+	}
+
+	if !c.config.OCSP || len(cert.OCSPServer) == 0 {
+		return nil
+	}
+
+	err := c.checkOCSP(cert, issuer)
+	if err == nil || errors.Is(err, ErrCertificateRevoked) || c.config.OCSPStrict {
+		return err
+	}
+
+	// Soft-fail: an unreachable or undecided responder does not block clients.
+	c.logger.Warn("OCSP status unavailable, accepting certificate",
+		zap.String("subject", cert.Subject.CommonName),
+		zap.String("serial", cert.SerialNumber.String()),
+		zap.Error(err),
+	)
+	return nil
+}
+
+// checkCRL returns a RevokedError if a CRL signed by issuer lists cert. A CRL
+// past its next update still revokes the certificates it lists, but cannot
+// vouch for the others: in strict mode they are rejected, otherwise accepted
+// with a warning.
+func (c *RevocationChecker) checkCRL(cert, issuer *x509.Certificate) error {
+	crls := c.crls.Load()
+	if crls == nil {
+		return nil
+	}
+
+	now := time.Now()
+	serial := cert.SerialNumber.String()
+	for _, list := range *crls {
+		if !bytes.Equal(list.list.RawIssuer, cert.RawIssuer) || !list.signedBy(issuer) {
+			continue
+		}
+		if reason, ok := list.revoked[serial]; ok {
+			return &RevokedError{
+				Subject: cert.Subject.CommonName,
+				Serial:  serial,
+				Reason:  reasonName(reason),
+				Source:  RevocationSourceCRL,
+			}
+		}
+
+		nextUpdate := list.list.NextUpdate
+		if nextUpdate.IsZero() || now.Before(nextUpdate) {
+			continue
+		}
+		if c.config.OCSPStrict {
+			return fmt.Errorf("%w: CRL of %s expired at %s", ErrRevocationUnknown,
+				list.list.Issuer, nextUpdate.Format(time.RFC3339))
+		}
+		c.logger.Warn("CRL is past its next update, accepting certificate",
+			zap.String("subject", cert.Subject.CommonName),
+			zap.String("serial", serial),
+			zap.String("issuer", list.list.Issuer.String()),
+			zap.Time("next_update", nextUpdate),
+		)
+	}
+
+	return nil
+}
+
+// reject records a rejected client certificate.
+func (c *RevocationChecker) reject(cert *x509.Certificate, err error) {
+	result := observability.ResultFailure
+	fields := []zap.Field{
+		zap.String("subject", cert.Subject.CommonName),
+		zap.String("serial", cert.SerialNumber.String()),
+		zap.Error(err),
+	}
+
+	var revoked *RevokedError
+	if errors.As(err, &revoked) {
+		result = observability.ResultRevoked
+		fields = append(fields,
+			zap.String("reason", revoked.Reason),
+			zap.String("source", revoked.Source),
+		)
+	}
+
+	observability.AuthAttemptsTotal.WithLabelValues(methodMTLS, result).Inc()
+	c.logger.Warn("client certificate rejected", fields...)
+}
+
+// loadCRLs loads the configured CRL file and URL.
+func (c *RevocationChecker) loadCRLs() error {
+	var crls []*crl
+
+	if c.config.CRLPath != "" {
+		data, err := os.ReadFile(c.config.CRLPath)
+		if err != nil {
+			return fmt.Errorf("reading CRL: %w", err)
+		}
+		parsed, err := parseCRLs(data)
+		if err != nil {
+			return fmt.Errorf("parsing CRL %s: %w", c.config.CRLPath, err)
+		}
+		crls = append(crls, parsed...)
+	}
+
+	if c.config.CRLURL != "" {
+		data, err := c.download(c.config.CRLURL)
+		if err != nil {
+			return fmt.Errorf("downloading CRL: %w", err)
+		}
+		parsed, err := parseCRLs(data)
+		if err != nil {
+			return fmt.Errorf("parsing CRL %s: %w", c.config.CRLURL, err)
+		}
+		crls = append(crls, parsed...)
+	}
+
+	if len(crls) == 0 {
+		return nil
+	}
+
+	c.crls.Store(&crls)
+	for _, list := range crls {
+		c.logger.Info("CRL loaded",
+			zap.String("issuer", list.list.Issuer.String()),
+			zap.Int("revoked", len(list.revoked)),
+			zap.Time("next_update", list.list.NextUpdate),
+		)
+	}
+
+	return nil
+}
+
+// download fetches url.
+func (c *RevocationChecker) download(url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxCRLSize))
+}
+
+// parseCRLs parses one DER CRL or any number of PEM "X509 CRL" blocks.
+func parseCRLs(data []byte) ([]*crl, error) {
+	var ders [][]byte
+	if bytes.Contains(data, []byte("-----BEGIN")) {
+		for {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			if block.Type == "X509 CRL" {
+				ders = append(ders, block.Bytes)
+			}
+		}
+		if len(ders) == 0 {
+			return nil, errors.New("no X509 CRL PEM blocks found")
+		}
+	} else {
+		ders = [][]byte{data}
+	}
+
+	crls := make([]*crl, 0, len(ders))
+	for _, der := range ders {
+		list, err := x509.ParseRevocationList(der)
+		if err != nil {
+			return nil, err
+		}
+
+		revoked := make(map[string]int, len(list.RevokedCertificateEntries))
+		for _, entry := range list.RevokedCertificateEntries {
+			if entry.ReasonCode != reasonRemoveFromCRL {
+				revoked[entry.SerialNumber.String()] = entry.ReasonCode
+			}
+		}
+		crls = append(crls, &crl{list: list, revoked: revoked})
+	}
+
+	return crls, nil
+}
+
+// signedBy reports whether the CRL is signed by issuer. CRLs that are not
+// signed by the certificate's issuer are ignored.
+func (l *crl) signedBy(issuer *x509.Certificate) bool {
+	key := string(issuer.Raw)
+	if _, ok := l.verified.Load(key); ok {
+		return true
+	}
+	if l.list.CheckSignatureFrom(issuer) != nil {
+		return false
+	}
+	l.verified.Store(key, struct{}{})
+	return true
+}
+
+// reasonName returns the RFC 5280 name of a revocation reason code.
+func reasonName(code int) string {
+	if name, ok := crlReasons[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("reason %d", code)
+}
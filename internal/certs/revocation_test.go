@@ -0,0 +1,506 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+)
+
+// clientCert is a client certificate issued by a testCA.
+type clientCert struct {
+	leaf *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// issueClient issues a client certificate for commonName that names
+// ocspServer as its OCSP responder, if set.
+func (ca *testCA) issueClient(t *testing.T, commonName, ocspServer string) clientCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial.Add(1)),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if ocspServer != "" {
+		template.OCSPServer = []string{ocspServer}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	return clientCert{leaf: leaf, key: key}
+}
+
+// crlPEM returns a PEM CRL revoking the given certificates with reason.
+func (ca *testCA) crlPEM(t *testing.T, reason int, revoked ...*x509.Certificate) string {
+	t.Helper()
+	return ca.crlPEMUntil(t, time.Now().Add(time.Hour), reason, revoked...)
+}
+
+// crlPEMUntil is crlPEM with the given next update.
+func (ca *testCA) crlPEMUntil(t *testing.T, nextUpdate time.Time, reason int, revoked ...*x509.Certificate) string {
+	t.Helper()
+
+	entries := make([]x509.RevocationListEntry, 0, len(revoked))
+	for _, cert := range revoked {
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   cert.SerialNumber,
+			RevocationTime: time.Now().Add(-time.Minute),
+			ReasonCode:     reason,
+		})
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(time.Now().UnixNano()),
+		ThisUpdate:                nextUpdate.Add(-2 * time.Hour),
+		NextUpdate:                nextUpdate,
+		RevokedCertificateEntries: entries,
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatalf("failed to create CRL: %v", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}))
+}
+
+// stubOCSP is an OCSP responder for certificates issued by a testCA.
+type stubOCSP struct {
+	*httptest.Server
+	requests atomic.Int64
+
+	mu       sync.Mutex
+	statuses map[string]int // serial number -> ocsp.Good|Revoked|Unknown
+}
+
+func newStubOCSP(t *testing.T, ca *testCA) *stubOCSP {
+	t.Helper()
+
+	responder := &stubOCSP{statuses: make(map[string]int)}
+	responder.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		responder.requests.Add(1)
+
+		body, _ := io.ReadAll(r.Body)
+		req, err := ocsp.ParseRequest(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		responder.mu.Lock()
+		status, ok := responder.statuses[req.SerialNumber.String()]
+		responder.mu.Unlock()
+		if !ok {
+			status = ocsp.Unknown
+		}
+
+		resp, err := ocsp.CreateResponse(ca.cert, ca.cert, ocsp.Response{
+			Status:           status,
+			SerialNumber:     req.SerialNumber,
+			ThisUpdate:       time.Now().Add(-time.Minute),
+			NextUpdate:       time.Now().Add(time.Hour),
+			RevokedAt:        time.Now().Add(-time.Minute),
+			RevocationReason: ocsp.CessationOfOperation,
+		}, ca.key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		_, _ = w.Write(resp)
+	}))
+	t.Cleanup(responder.Close)
+
+	return responder
+}
+
+func (s *stubOCSP) set(cert *x509.Certificate, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[cert.SerialNumber.String()] = status
+}
+
+// verify runs the checker on the chain of cert issued by ca.
+func verify(c *RevocationChecker, cert clientCert, ca *testCA) error {
+	return c.VerifyPeerCertificate(nil, [][]*x509.Certificate{{cert.leaf, ca.cert}})
+}
+
+func TestRevocationChecker_CRL(t *testing.T) {
+	ca, otherCA := newTestCA(t), newTestCA(t)
+	good := ca.issueClient(t, "good", "")
+	revoked := ca.issueClient(t, "revoked", "")
+	files := newTestFiles(t)
+	crlPath := filepath.Join(filepath.Dir(files.ca), "crl.pem")
+	// otherCA has the same subject as ca, but its CRL must not apply.
+	writeFile(t, crlPath, ca.crlPEM(t, ocsp.KeyCompromise, revoked.leaf)+otherCA.crlPEM(t, 0, good.leaf))
+
+	checker, err := NewRevocationChecker(RevocationConfig{CRLPath: crlPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewRevocationChecker() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		cert       clientCert
+		wantReason string
+	}{
+		{name: "not revoked", cert: good},
+		{name: "revoked", cert: revoked, wantReason: "keyCompromise"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := verify(checker, tt.cert, ca)
+
+			// Assert
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Fatalf("VerifyPeerCertificate() error = %v, want nil", err)
+				}
+				return
+			}
+			var revokedErr *RevokedError
+			if !errors.As(err, &revokedErr) || !errors.Is(err, ErrCertificateRevoked) {
+				t.Fatalf("VerifyPeerCertificate() error = %v, want *RevokedError", err)
+			}
+			if revokedErr.Reason != tt.wantReason || revokedErr.Source != RevocationSourceCRL {
+				t.Errorf("revoked by %s: %s, want crl: %s", revokedErr.Source, revokedErr.Reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestRevocationChecker_ExpiredCRL(t *testing.T) {
+	ca := newTestCA(t)
+	good := ca.issueClient(t, "good", "")
+	revoked := ca.issueClient(t, "revoked", "")
+	crlPath := filepath.Join(t.TempDir(), "crl.pem")
+	writeFile(t, crlPath, ca.crlPEMUntil(t, time.Now().Add(-time.Minute), ocsp.KeyCompromise, revoked.leaf))
+
+	tests := []struct {
+		name     string
+		cert     clientCert
+		strict   bool
+		wantErr  error
+		wantWarn bool
+	}{
+		{name: "soft-fail accepts with a warning", cert: good, wantWarn: true},
+		{name: "strict rejects", cert: good, strict: true, wantErr: ErrRevocationUnknown},
+		{name: "listed certificate stays revoked", cert: revoked, wantErr: ErrCertificateRevoked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			core, logs := observer.New(zap.WarnLevel)
+			checker, err := NewRevocationChecker(RevocationConfig{CRLPath: crlPath, OCSPStrict: tt.strict}, zap.New(core))
+			if err != nil {
+				t.Fatalf("NewRevocationChecker() error = %v", err)
+			}
+
+			// Act
+			err = verify(checker, tt.cert, ca)
+
+			// Assert
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("VerifyPeerCertificate() error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyPeerCertificate() error = %v, want %v", err, tt.wantErr)
+			}
+			warned := logs.FilterMessage("CRL is past its next update, accepting certificate").Len() > 0
+			if warned != tt.wantWarn {
+				t.Errorf("stale CRL warning logged = %v, want %v", warned, tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestRevocationChecker_CRLFromURL(t *testing.T) {
+	// Arrange
+	ca := newTestCA(t)
+	revoked := ca.issueClient(t, "revoked", "")
+	crl := ca.crlPEM(t, ocsp.Superseded, revoked.leaf)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		block, _ := pem.Decode([]byte(crl))
+		_, _ = w.Write(block.Bytes) // DER
+	}))
+	defer server.Close()
+
+	checker, err := NewRevocationChecker(RevocationConfig{CRLURL: server.URL}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewRevocationChecker() error = %v", err)
+	}
+
+	// Act
+	err = verify(checker, revoked, ca)
+
+	// Assert
+	var revokedErr *RevokedError
+	if !errors.As(err, &revokedErr) || revokedErr.Reason != "superseded" {
+		t.Errorf("VerifyPeerCertificate() error = %v, want revoked: superseded", err)
+	}
+}
+
+func TestRevocationChecker_RefreshesCRL(t *testing.T) {
+	// Arrange
+	ca := newTestCA(t)
+	cert := ca.issueClient(t, "client", "")
+	crlPath := filepath.Join(t.TempDir(), "crl.pem")
+	writeFile(t, crlPath, ca.crlPEM(t, 0))
+
+	checker, err := NewRevocationChecker(RevocationConfig{CRLPath: crlPath, CRLRefresh: 10 * time.Millisecond},
+		zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewRevocationChecker() error = %v", err)
+	}
+	checker.Start()
+	defer checker.Stop()
+
+	if err := verify(checker, cert, ca); err != nil {
+		t.Fatalf("VerifyPeerCertificate() before revocation error = %v", err)
+	}
+
+	// Act
+	writeFile(t, crlPath, ca.crlPEM(t, ocsp.AffiliationChanged, cert.leaf))
+
+	// Assert
+	waitFor(t, func() bool { return errors.Is(verify(checker, cert, ca), ErrCertificateRevoked) })
+}
+
+func TestNewRevocationChecker_Errors(t *testing.T) {
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+	invalid := filepath.Join(t.TempDir(), "invalid.pem")
+	writeFile(t, invalid, "-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n")
+
+	tests := []struct {
+		name    string
+		config  RevocationConfig
+		wantMsg string
+	}{
+		{name: "missing file", config: RevocationConfig{CRLPath: "/nonexistent/crl.pem"}, wantMsg: "reading CRL"},
+		{name: "no CRL in file", config: RevocationConfig{CRLPath: invalid}, wantMsg: "no X509 CRL PEM blocks"},
+		{name: "download fails", config: RevocationConfig{CRLURL: notFound.URL}, wantMsg: "status code: 404"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, err := NewRevocationChecker(tt.config, zap.NewNop())
+
+			// Assert
+			if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("NewRevocationChecker() error = %v, want it to contain %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestRevocationChecker_OCSP(t *testing.T) {
+	ca := newTestCA(t)
+	responder := newStubOCSP(t, ca)
+	good := ca.issueClient(t, "good", responder.URL)
+	revoked := ca.issueClient(t, "revoked", responder.URL)
+	unknown := ca.issueClient(t, "unknown", responder.URL)
+	unreachable := ca.issueClient(t, "unreachable", "http://127.0.0.1:1")
+	noResponder := ca.issueClient(t, "no-responder", "")
+	responder.set(good.leaf, ocsp.Good)
+	responder.set(revoked.leaf, ocsp.Revoked)
+
+	tests := []struct {
+		name        string
+		cert        clientCert
+		strict      bool
+		wantRevoked bool
+		wantUnknown bool
+	}{
+		{name: "good", cert: good},
+		{name: "revoked", cert: revoked, wantRevoked: true},
+		{name: "unknown accepted", cert: unknown},
+		{name: "unknown rejected when strict", cert: unknown, strict: true, wantUnknown: true},
+		{name: "unreachable accepted", cert: unreachable},
+		{name: "unreachable rejected when strict", cert: unreachable, strict: true, wantUnknown: true},
+		{name: "no responder", cert: noResponder, strict: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			checker, err := NewRevocationChecker(RevocationConfig{OCSP: true, OCSPStrict: tt.strict}, zap.NewNop())
+			if err != nil {
+				t.Fatalf("NewRevocationChecker() error = %v", err)
+			}
+
+			// Act
+			err = verify(checker, tt.cert, ca)
+
+			// Assert
+			if got := errors.Is(err, ErrCertificateRevoked); got != tt.wantRevoked {
+				t.Errorf("VerifyPeerCertificate() error = %v, want revoked = %v", err, tt.wantRevoked)
+			}
+			if got := errors.Is(err, ErrRevocationUnknown); got != tt.wantUnknown {
+				t.Errorf("VerifyPeerCertificate() error = %v, want unknown = %v", err, tt.wantUnknown)
+			}
+			var revokedErr *RevokedError
+			if errors.As(err, &revokedErr) && revokedErr.Reason != "cessationOfOperation" {
+				t.Errorf("Reason = %q, want cessationOfOperation", revokedErr.Reason)
+			}
+		})
+	}
+}
+
+func TestRevocationChecker_OCSPCache(t *testing.T) {
+	// Arrange
+	ca := newTestCA(t)
+	responder := newStubOCSP(t, ca)
+	cert := ca.issueClient(t, "client", responder.URL)
+	responder.set(cert.leaf, ocsp.Good)
+
+	checker, err := NewRevocationChecker(RevocationConfig{OCSP: true}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewRevocationChecker() error = %v", err)
+	}
+
+	// Act
+	for range 3 {
+		if err := verify(checker, cert, ca); err != nil {
+			t.Fatalf("VerifyPeerCertificate() error = %v", err)
+		}
+	}
+
+	// Assert
+	if got := responder.requests.Load(); got != 1 {
+		t.Errorf("OCSP requests = %d, want 1 (responses are cached until their next update)", got)
+	}
+}
+
+func TestRevocationChecker_RecordsRejections(t *testing.T) {
+	// Arrange
+	ca := newTestCA(t)
+	revoked := ca.issueClient(t, "revoked", "")
+	crlPath := filepath.Join(t.TempDir(), "crl.pem")
+	writeFile(t, crlPath, ca.crlPEM(t, ocsp.KeyCompromise, revoked.leaf))
+
+	checker, err := NewRevocationChecker(RevocationConfig{CRLPath: crlPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewRevocationChecker() error = %v", err)
+	}
+	counter := observability.AuthAttemptsTotal.WithLabelValues("mtls", observability.ResultRevoked)
+	before := testutil.ToFloat64(counter)
+
+	// Act
+	_ = verify(checker, revoked, ca)
+
+	// Assert
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Errorf("auth_attempts_total{method=mtls,result=revoked} increased by %v, want 1", got)
+	}
+}
+
+func TestRevocationChecker_TLSHandshake(t *testing.T) {
+	// Arrange
+	ca := newTestCA(t)
+	good := ca.issueClient(t, "good", "")
+	revoked := ca.issueClient(t, "revoked", "")
+	crlPath := filepath.Join(t.TempDir(), "crl.pem")
+	writeFile(t, crlPath, ca.crlPEM(t, ocsp.KeyCompromise, revoked.leaf))
+
+	checker, err := NewRevocationChecker(RevocationConfig{CRLPath: crlPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewRevocationChecker() error = %v", err)
+	}
+
+	serverPEM, serverKey := ca.issue(t, vaultIssueRequest{CommonName: "server", IPSANs: "127.0.0.1"}, time.Hour)
+	serverCert, err := tls.X509KeyPair([]byte(serverPEM), []byte(serverKey))
+	if err != nil {
+		t.Fatalf("failed to load server certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		MinVersion:            tls.VersionTLS12,
+		Certificates:          []tls.Certificate{serverCert},
+		ClientAuth:            tls.RequireAndVerifyClientCert,
+		ClientCAs:             pool,
+		VerifyPeerCertificate: checker.VerifyPeerCertificate,
+	})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+
+	handshake := func(client clientCert) error {
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: time.Second}, "tcp", listener.Addr().String(), &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			MaxVersion: tls.VersionTLS12, // report client certificate rejection from Dial
+			RootCAs:    pool,
+			ServerName: "127.0.0.1",
+			Certificates: []tls.Certificate{{
+				Certificate: [][]byte{client.leaf.Raw},
+				PrivateKey:  client.key,
+			}},
+		})
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	// Act & Assert
+	if err := handshake(good); err != nil {
+		t.Errorf("handshake with a valid certificate error = %v", err)
+	}
+	if err := handshake(revoked); err == nil {
+		t.Error("handshake with a revoked certificate should fail")
+	}
+}
@@ -0,0 +1,200 @@
+// Package certs provides TLS server certificates and client CA bundles that
+// are replaced while the server is running, so that certificate rotation
+// needs no restart. Certificates are either issued by Vault PKI (Rotator) or
+// read from disk (FileReloader). RevocationChecker rejects revoked client
+// certificates using CRLs and OCSP.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+)
+
+// ErrNoCertificate is returned by GetCertificate before a certificate has
+// been obtained.
+var ErrNoCertificate = errors.New("no TLS certificate available")
+
+// DefaultRetryInterval is the delay before a failed renewal is retried.
+const DefaultRetryInterval = 30 * time.Second
+
+// issueTimeout bounds a single background renewal attempt.
+const issueTimeout = 30 * time.Second
+
+// Issuer obtains a new certificate, e.g. from a certificate authority.
+type Issuer interface {
+	Issue(ctx context.Context) (*tls.Certificate, error)
+}
+
+// Rotator serves the certificate obtained from an Issuer and renews it once
+// two thirds of its lifetime have elapsed. Failed renewals are retried while
+// the current certificate keeps being served. Renewals are counted in
+// tls_certificate_renewals_total and the served certificate's expiry is
+// reported in tls_certificate_expiry_timestamp_seconds.
+type Rotator struct {
+	issuer        Issuer
+	source        string
+	logger        *zap.Logger
+	retryInterval time.Duration
+
+	current atomic.Pointer[tls.Certificate]
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// NewRotator creates a Rotator for the given issuer. The source names the
+// issuer in metrics and logs, e.g. "vault".
+func NewRotator(issuer Issuer, source string, logger *zap.Logger) *Rotator {
+	return &Rotator{
+		issuer:        issuer,
+		source:        source,
+		logger:        logger,
+		retryInterval: DefaultRetryInterval,
+	}
+}
+
+// Start obtains the initial certificate and renews it in the background
+// until Stop is called.
+func (r *Rotator) Start(ctx context.Context) error {
+	if err := r.renew(ctx); err != nil {
+		return fmt.Errorf("obtaining initial %s certificate: %w", r.source, err)
+	}
+
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		cancel()
+		return nil
+	}
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.run(runCtx)
+	}()
+
+	return nil
+}
+
+// Stop ends background renewal and waits for it to finish. The current
+// certificate continues to be served.
+func (r *Rotator) Stop() {
+	r.mu.Lock()
+	r.stopped = true
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.mu.Unlock()
+
+	r.wg.Wait()
+}
+
+// GetCertificate returns the current certificate. It is meant for
+// tls.Config.GetCertificate.
+func (r *Rotator) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := r.current.Load()
+	if cert == nil {
+		return nil, ErrNoCertificate
+	}
+	return cert, nil
+}
+
+// run renews the certificate whenever it is due until ctx is cancelled.
+func (r *Rotator) run(ctx context.Context) {
+	delay := renewalDelay(r.current.Load().Leaf, time.Now())
+	for {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		issueCtx, cancel := context.WithTimeout(ctx, issueTimeout)
+		err := r.renew(issueCtx)
+		cancel()
+
+		if err != nil {
+			delay = r.retryInterval
+			continue
+		}
+		delay = renewalDelay(r.current.Load().Leaf, time.Now())
+	}
+}
+
+// renew obtains a certificate from the issuer and starts serving it.
+func (r *Rotator) renew(ctx context.Context) error {
+	cert, err := r.issuer.Issue(ctx)
+	if err == nil {
+		err = parseLeaf(cert)
+	}
+	if err != nil {
+		observability.TLSCertificateRenewalsTotal.
+			WithLabelValues(r.source, observability.ResultFailure).
+			Inc()
+		r.logger.Error("failed to obtain TLS certificate",
+			zap.String("source", r.source),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	r.current.Store(cert)
+
+	observability.TLSCertificateRenewalsTotal.
+		WithLabelValues(r.source, observability.ResultSuccess).
+		Inc()
+	observability.TLSCertificateExpiry.
+		WithLabelValues(r.source).
+		Set(float64(cert.Leaf.NotAfter.Unix()))
+
+	r.logger.Info("TLS certificate obtained",
+		zap.String("source", r.source),
+		zap.String("subject", cert.Leaf.Subject.CommonName),
+		zap.String("serial", cert.Leaf.SerialNumber.String()),
+		zap.Time("not_after", cert.Leaf.NotAfter),
+	)
+
+	return nil
+}
+
+// parseLeaf sets cert.Leaf if the certificate was loaded without it.
+func parseLeaf(cert *tls.Certificate) error {
+	if cert.Leaf != nil {
+		return nil
+	}
+	if len(cert.Certificate) == 0 {
+		return ErrNoCertificate
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("parsing certificate: %w", err)
+	}
+	cert.Leaf = leaf
+	return nil
+}
+
+// renewalDelay returns the time until two thirds of the certificate's
+// lifetime have elapsed, or zero if that time has passed.
+func renewalDelay(leaf *x509.Certificate, now time.Time) time.Duration {
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+	renewAt := leaf.NotBefore.Add(lifetime * 2 / 3)
+	return max(renewAt.Sub(now), 0)
+}
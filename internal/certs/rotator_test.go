@@ -0,0 +1,210 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+)
+
+// fakeIssuer issues certificates from a test CA and fails while err is set.
+type fakeIssuer struct {
+	t   *testing.T
+	ca  *testCA
+	ttl time.Duration
+
+	mu    sync.Mutex
+	err   error
+	calls int
+}
+
+func (f *fakeIssuer) Issue(_ context.Context) (*tls.Certificate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	certPEM, keyPEM := f.ca.issue(f.t, vaultIssueRequest{CommonName: "localhost"}, f.ttl)
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+func (f *fakeIssuer) setErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+func (f *fakeIssuer) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// serial returns the serial number of the certificate served by r.
+func serial(t *testing.T, r *Rotator) string {
+	t.Helper()
+
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	return cert.Leaf.SerialNumber.String()
+}
+
+// waitFor polls cond until it holds or the deadline passes.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRotator_GetCertificate_BeforeStart(t *testing.T) {
+	// Arrange
+	r := NewRotator(&fakeIssuer{t: t, ca: newTestCA(t), ttl: time.Hour}, "test", zap.NewNop())
+
+	// Act
+	_, err := r.GetCertificate(nil)
+
+	// Assert
+	if !errors.Is(err, ErrNoCertificate) {
+		t.Errorf("GetCertificate() error = %v, want ErrNoCertificate", err)
+	}
+}
+
+func TestRotator_Start_InitialIssueFails(t *testing.T) {
+	// Arrange
+	issuer := &fakeIssuer{t: t, ca: newTestCA(t), err: errors.New("vault sealed")}
+	r := NewRotator(issuer, "test", zap.NewNop())
+	defer r.Stop()
+
+	// Act
+	err := r.Start(context.Background())
+
+	// Assert
+	if err == nil || err.Error() != "obtaining initial test certificate: vault sealed" {
+		t.Errorf("Start() error = %v", err)
+	}
+}
+
+func TestRotator_RenewsBeforeExpiry(t *testing.T) {
+	// Arrange
+	issuer := &fakeIssuer{t: t, ca: newTestCA(t), ttl: 3 * time.Second}
+	r := NewRotator(issuer, "rotation-test", zap.NewNop())
+	defer r.Stop()
+
+	// Act
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	first := serial(t, r)
+
+	// Assert
+	waitFor(t, func() bool { return serial(t, r) != first })
+
+	cert, _ := r.GetCertificate(nil)
+	if time.Now().After(cert.Leaf.NotAfter) {
+		t.Error("served certificate has expired")
+	}
+	if got := testutil.ToFloat64(observability.TLSCertificateExpiry.WithLabelValues("rotation-test")); got <= 0 {
+		t.Errorf("tls_certificate_expiry_timestamp_seconds = %v, want > 0", got)
+	}
+	if got := testutil.ToFloat64(observability.TLSCertificateRenewalsTotal.WithLabelValues(
+		"rotation-test", observability.ResultSuccess)); got < 2 {
+		t.Errorf("tls_certificate_renewals_total{result=success} = %v, want >= 2", got)
+	}
+}
+
+func TestRotator_RetriesFailedRenewal(t *testing.T) {
+	// Arrange
+	issuer := &fakeIssuer{t: t, ca: newTestCA(t), ttl: 2 * time.Second}
+	r := NewRotator(issuer, "retry-test", zap.NewNop())
+	r.retryInterval = 10 * time.Millisecond
+	defer r.Stop()
+
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	first := serial(t, r)
+
+	// Act
+	issuer.setErr(errors.New("vault unavailable"))
+	waitFor(t, func() bool { return issuer.callCount() >= 3 })
+	issuer.setErr(nil)
+
+	// Assert
+	waitFor(t, func() bool { return serial(t, r) != first })
+	if got := testutil.ToFloat64(observability.TLSCertificateRenewalsTotal.WithLabelValues(
+		"retry-test", observability.ResultFailure)); got < 2 {
+		t.Errorf("tls_certificate_renewals_total{result=failure} = %v, want >= 2", got)
+	}
+}
+
+func TestRotator_StopEndsRenewal(t *testing.T) {
+	// Arrange
+	issuer := &fakeIssuer{t: t, ca: newTestCA(t), ttl: time.Second}
+	r := NewRotator(issuer, "stop-test", zap.NewNop())
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	// Act
+	r.Stop()
+	calls := issuer.callCount()
+	time.Sleep(100 * time.Millisecond)
+
+	// Assert
+	if got := issuer.callCount(); got != calls {
+		t.Errorf("Issue() called %d times after Stop, want 0", got-calls)
+	}
+	if _, err := r.GetCertificate(nil); err != nil {
+		t.Errorf("GetCertificate() after Stop error = %v", err)
+	}
+}
+
+func TestRenewalDelay(t *testing.T) {
+	notBefore := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	leaf := &x509.Certificate{NotBefore: notBefore, NotAfter: notBefore.Add(90 * time.Hour)}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Duration
+	}{
+		{name: "just issued", now: notBefore, want: 60 * time.Hour},
+		{name: "half way", now: notBefore.Add(45 * time.Hour), want: 15 * time.Hour},
+		{name: "renewal overdue", now: notBefore.Add(80 * time.Hour), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			got := renewalDelay(leaf, tt.now)
+
+			// Assert
+			if got != tt.want {
+				t.Errorf("renewalDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
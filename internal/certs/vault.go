@@ -0,0 +1,149 @@
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrVaultIssue is returned when Vault does not issue a certificate.
+var ErrVaultIssue = errors.New("vault PKI certificate issuance failed")
+
+// vaultHTTPTimeout is the timeout for HTTP requests to Vault.
+const vaultHTTPTimeout = 10 * time.Second
+
+// maxVaultErrorBody bounds how much of an error response is read.
+const maxVaultErrorBody = 4 << 10
+
+// VaultConfig configures a VaultIssuer.
+type VaultConfig struct {
+	// Addr is the Vault server address, e.g. https://vault:8200.
+	Addr string
+	// Token authenticates requests to Vault.
+	Token string
+	// PKIPath is the mount path of the PKI secrets engine, e.g. "pki".
+	PKIPath string
+	// Role is the PKI role certificates are issued for.
+	Role string
+
+	CommonName string
+	AltNames   []string      // DNS subject alternative names.
+	IPSANs     []string      // IP subject alternative names.
+	TTL        time.Duration // Requested lifetime; zero uses the role default.
+}
+
+// VaultIssuer issues certificates from the PKI secrets engine of a Vault
+// server using its issue endpoint.
+type VaultIssuer struct {
+	config VaultConfig
+	client *http.Client
+}
+
+// NewVaultIssuer creates a VaultIssuer.
+func NewVaultIssuer(config VaultConfig) *VaultIssuer {
+	return &VaultIssuer{
+		config: config,
+		client: &http.Client{Timeout: vaultHTTPTimeout},
+	}
+}
+
+// vaultIssueRequest is the body of a PKI issue request.
+type vaultIssueRequest struct {
+	CommonName string `json:"common_name"`
+	AltNames   string `json:"alt_names,omitempty"`
+	IPSANs     string `json:"ip_sans,omitempty"`
+	TTL        string `json:"ttl,omitempty"`
+}
+
+// vaultIssueResponse is the response of a PKI issue request.
+type vaultIssueResponse struct {
+	Data struct {
+		Certificate string   `json:"certificate"`
+		IssuingCA   string   `json:"issuing_ca"`
+		CAChain     []string `json:"ca_chain"`
+		PrivateKey  string   `json:"private_key"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+// Issue requests a new certificate and private key from Vault. The returned
+// certificate includes the issuing CA chain.
+func (v *VaultIssuer) Issue(ctx context.Context) (*tls.Certificate, error) {
+	body, err := json.Marshal(v.issueRequest())
+	if err != nil {
+		return nil, fmt.Errorf("encoding issue request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.issueURL(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating issue request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", v.config.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrVaultIssue, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", ErrVaultIssue, vaultErrorMessage(resp))
+	}
+
+	var issued vaultIssueResponse
+	if err := json.NewDecoder(resp.Body).Decode(&issued); err != nil {
+		return nil, fmt.Errorf("%w: decoding response: %w", ErrVaultIssue, err)
+	}
+
+	chain := issued.Data.CAChain
+	if len(chain) == 0 && issued.Data.IssuingCA != "" {
+		chain = []string{issued.Data.IssuingCA}
+	}
+	certPEM := strings.Join(append([]string{issued.Data.Certificate}, chain...), "\n")
+
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(issued.Data.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("%w: parsing issued key pair: %w", ErrVaultIssue, err)
+	}
+
+	return &cert, nil
+}
+
+// issueURL returns the URL of the issue endpoint for the configured role.
+func (v *VaultIssuer) issueURL() string {
+	return strings.TrimRight(v.config.Addr, "/") + "/v1/" +
+		strings.Trim(v.config.PKIPath, "/") + "/issue/" + url.PathEscape(v.config.Role)
+}
+
+// issueRequest returns the body of the issue request.
+func (v *VaultIssuer) issueRequest() vaultIssueRequest {
+	req := vaultIssueRequest{
+		CommonName: v.config.CommonName,
+		AltNames:   strings.Join(v.config.AltNames, ","),
+		IPSANs:     strings.Join(v.config.IPSANs, ","),
+	}
+	if v.config.TTL > 0 {
+		req.TTL = fmt.Sprintf("%ds", int64(v.config.TTL.Seconds()))
+	}
+	return req
+}
+
+// vaultErrorMessage describes an unsuccessful Vault response using the
+// errors it reports, if any.
+func vaultErrorMessage(resp *http.Response) string {
+	var body vaultIssueResponse
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxVaultErrorBody))
+	if err := json.Unmarshal(data, &body); err == nil && len(body.Errors) > 0 {
+		return fmt.Sprintf("status %d: %s", resp.StatusCode, strings.Join(body.Errors, "; "))
+	}
+	return fmt.Sprintf("status %d", resp.StatusCode)
+}
@@ -0,0 +1,266 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testVaultToken = "s.test-token"
+	testVaultRole  = "web"
+)
+
+// testCA signs the certificates issued by the stub Vault server.
+type testCA struct {
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	pem    string
+	serial atomic.Int64
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate CA key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse CA certificate: %v", err)
+	}
+
+	ca := &testCA{
+		cert: cert,
+		key:  key,
+		pem:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	}
+	ca.serial.Store(1)
+	return ca
+}
+
+// issue returns a PEM certificate and key for the request, valid for ttl.
+func (ca *testCA) issue(t *testing.T, req vaultIssueRequest, ttl time.Duration) (certPEM, keyPEM string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial.Add(1)),
+		Subject:      pkix.Name{CommonName: req.CommonName},
+		NotBefore:    now,
+		NotAfter:     now.Add(ttl),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if req.AltNames != "" {
+		template.DNSNames = strings.Split(req.AltNames, ",")
+	}
+	if req.IPSANs != "" {
+		for _, ip := range strings.Split(req.IPSANs, ",") {
+			template.IPAddresses = append(template.IPAddresses, net.ParseIP(ip))
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+// newStubVault starts a server implementing the PKI issue endpoint of the
+// secrets engine mounted at "pki". Certificates are valid for the requested
+// TTL, or one hour when none is requested.
+func newStubVault(t *testing.T, ca *testCA) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Header.Get("X-Vault-Token") != testVaultToken {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/pki/issue/"+testVaultRole {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":["unknown role"]}`))
+			return
+		}
+
+		var req vaultIssueRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		ttl := time.Hour
+		if req.TTL != "" {
+			parsed, err := time.ParseDuration(req.TTL)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			ttl = parsed
+		}
+
+		certPEM, keyPEM := ca.issue(t, req, ttl)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]any{
+				"certificate": certPEM,
+				"issuing_ca":  ca.pem,
+				"ca_chain":    []string{ca.pem},
+				"private_key": keyPEM,
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestVaultIssuer_Issue(t *testing.T) {
+	// Arrange
+	ca := newTestCA(t)
+	vault := newStubVault(t, ca)
+	issuer := NewVaultIssuer(VaultConfig{
+		Addr:       vault.URL + "/",
+		Token:      testVaultToken,
+		PKIPath:    "/pki/",
+		Role:       testVaultRole,
+		CommonName: "api.example.com",
+		AltNames:   []string{"api.example.com", "localhost"},
+		IPSANs:     []string{"127.0.0.1"},
+		TTL:        2 * time.Hour,
+	})
+
+	// Act
+	cert, err := issuer.Issue(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if len(cert.Certificate) != 2 {
+		t.Errorf("chain length = %d, want 2 (leaf and CA)", len(cert.Certificate))
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse leaf: %v", err)
+	}
+	if leaf.Subject.CommonName != "api.example.com" {
+		t.Errorf("CommonName = %q, want api.example.com", leaf.Subject.CommonName)
+	}
+	if err := leaf.VerifyHostname("localhost"); err != nil {
+		t.Errorf("VerifyHostname(localhost) error = %v", err)
+	}
+	if err := leaf.VerifyHostname("127.0.0.1"); err != nil {
+		t.Errorf("VerifyHostname(127.0.0.1) error = %v", err)
+	}
+	if lifetime := leaf.NotAfter.Sub(leaf.NotBefore); lifetime != 2*time.Hour {
+		t.Errorf("lifetime = %v, want 2h", lifetime)
+	}
+}
+
+func TestVaultIssuer_Issue_Errors(t *testing.T) {
+	ca := newTestCA(t)
+	vault := newStubVault(t, ca)
+
+	tests := []struct {
+		name    string
+		config  VaultConfig
+		wantMsg string
+	}{
+		{
+			name:    "invalid token",
+			config:  VaultConfig{Addr: vault.URL, Token: "wrong", PKIPath: "pki", Role: testVaultRole},
+			wantMsg: "status 403: permission denied",
+		},
+		{
+			name:    "unknown role",
+			config:  VaultConfig{Addr: vault.URL, Token: testVaultToken, PKIPath: "pki", Role: "other"},
+			wantMsg: "status 404: unknown role",
+		},
+		{
+			name:    "unreachable server",
+			config:  VaultConfig{Addr: "http://127.0.0.1:1", Token: testVaultToken, PKIPath: "pki", Role: testVaultRole},
+			wantMsg: "connection refused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			issuer := NewVaultIssuer(tt.config)
+
+			// Act
+			_, err := issuer.Issue(context.Background())
+
+			// Assert
+			if !errors.Is(err, ErrVaultIssue) {
+				t.Fatalf("Issue() error = %v, want ErrVaultIssue", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("Issue() error = %q, want it to contain %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestVaultIssuer_Issue_InvalidKeyPair(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"certificate":"not a certificate","private_key":"not a key"}}`))
+	}))
+	defer server.Close()
+	issuer := NewVaultIssuer(VaultConfig{Addr: server.URL, Token: testVaultToken, PKIPath: "pki", Role: testVaultRole})
+
+	// Act
+	_, err := issuer.Issue(context.Background())
+
+	// Assert
+	if !errors.Is(err, ErrVaultIssue) {
+		t.Errorf("Issue() error = %v, want ErrVaultIssue", err)
+	}
+}
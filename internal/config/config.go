@@ -5,7 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"reflect"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,7 +20,40 @@ const (
 	DefaultMetricsEnabled  = true
 	DefaultAuthMode        = "none"
 	DefaultTLSClientAuth   = "none"
+	DefaultMTLSSubject     = "cn"
 	DefaultProbePort       = 9090
+	DefaultStoreDriver     = "memory"
+	DefaultStoreMaxOpen    = 10
+	DefaultStoreMaxIdle    = 5
+	DefaultStoreConnMaxAge = 30 * time.Minute
+	DefaultStoreTimeout    = 30 * time.Second
+	DefaultCORSOrigins     = "*"
+
+	DefaultIntrospectCacheTTL = 5 * time.Minute
+	DefaultIntrospectNegTTL   = 30 * time.Second
+	DefaultBasicAuthReload    = 10 * time.Second
+	DefaultBasicAuthCacheTTL  = 5 * time.Minute
+	DefaultLockoutFailures    = 5
+	DefaultLockoutIPFailures  = 20
+	DefaultLockoutBaseDelay   = 30 * time.Second
+	DefaultLockoutMaxDelay    = 15 * time.Minute
+	DefaultLockoutResetAfter  = 15 * time.Minute
+	DefaultRateLimitBackend   = "memory"
+	DefaultRateLimitRead      = "600/1m"
+	DefaultRateLimitWrite     = "120/1m"
+	DefaultRateLimitGraphQL   = "300/1m"
+	DefaultIdempotencyEnabled = true
+	DefaultIdempotencyTTL     = 24 * time.Hour
+	DefaultIdempotencyBackend = "memory"
+	DefaultTLSReloadInterval  = 10 * time.Second
+	DefaultTLSCRLRefresh      = time.Hour
+	DefaultVaultPKICommonName = "localhost"
+	DefaultCORSMethods        = "GET,POST,PUT,PATCH,DELETE,OPTIONS"
+	DefaultCORSHeaders        = "Content-Type,Authorization,X-Request-ID,X-API-Key,If-Match,If-None-Match," +
+		"Idempotency-Key"
+	DefaultCORSExposedHeaders = "X-Request-ID,ETag,Location,Retry-After," +
+		"RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,RateLimit-Policy,Idempotency-Replayed"
+	DefaultCORSMaxAge = 24 * time.Hour
 )
 
 // Environment variable names.
@@ -33,17 +69,79 @@ const (
 	EnvTLSKeyPath      = "APP_TLS_KEY_PATH"
 	EnvTLSCAPath       = "APP_TLS_CA_PATH"
 	EnvTLSClientAuth   = "APP_TLS_CLIENT_AUTH"
+	EnvTLSReload       = "APP_TLS_RELOAD_INTERVAL"
+	EnvTLSCRLPath      = "APP_TLS_CRL_PATH"
+	EnvTLSCRLURL       = "APP_TLS_CRL_URL"
+	EnvTLSCRLRefresh   = "APP_TLS_CRL_REFRESH_INTERVAL"
+	EnvTLSOCSPEnabled  = "APP_TLS_OCSP_ENABLED"
+	EnvTLSOCSPStrict   = "APP_TLS_OCSP_STRICT"
+	EnvMTLSSubject     = "APP_MTLS_SUBJECT"
+	EnvMTLSDomains     = "APP_MTLS_TRUST_DOMAINS"
+	EnvMTLSAllowedOUs  = "APP_MTLS_ALLOWED_OUS"
+	EnvMTLSAllowedSANs = "APP_MTLS_ALLOWED_SANS"
 	EnvOIDCIssuerURL   = "APP_OIDC_ISSUER_URL"
 	EnvOIDCClientID    = "APP_OIDC_CLIENT_ID"
 	EnvOIDCAudience    = "APP_OIDC_AUDIENCE"
+	EnvOIDCAlgorithms  = "APP_OIDC_ALGORITHMS"
+	EnvOIDCClockSkew   = "APP_OIDC_CLOCK_SKEW"
+	EnvOIDCClaims      = "APP_OIDC_REQUIRED_CLAIMS"
+	EnvOIDCIssuerN     = "APP_OIDC_ISSUER_%d_%s" // Indexed issuer settings, e.g. APP_OIDC_ISSUER_1_URL.
+	EnvIntroURL        = "APP_INTROSPECTION_URL"
+	EnvIntroClientID   = "APP_INTROSPECTION_CLIENT_ID"
+	EnvIntroSecret     = "APP_INTROSPECTION_CLIENT_SECRET" //nolint:gosec // env var name, not a credential
+	EnvIntroCacheTTL   = "APP_INTROSPECTION_CACHE_TTL"
+	EnvIntroNegTTL     = "APP_INTROSPECTION_NEGATIVE_CACHE_TTL"
 	EnvBasicAuthUsers  = "APP_BASIC_AUTH_USERS"
+	EnvBasicAuthFile   = "APP_BASIC_AUTH_FILE"
+	EnvBasicAuthReload = "APP_BASIC_AUTH_RELOAD_INTERVAL"
+	EnvBasicAuthCache  = "APP_BASIC_AUTH_CACHE_TTL"
+	EnvLockoutFailures = "APP_AUTH_LOCKOUT_MAX_FAILURES"
+	EnvLockoutIPFails  = "APP_AUTH_LOCKOUT_MAX_CLIENT_FAILURES"
+	EnvLockoutBase     = "APP_AUTH_LOCKOUT_BASE_DELAY"
+	EnvLockoutMaxDelay = "APP_AUTH_LOCKOUT_MAX_DELAY"
+	EnvLockoutReset    = "APP_AUTH_LOCKOUT_RESET_AFTER"
 	EnvAPIKeys         = "APP_API_KEYS" //nolint:gosec // env var name, not a credential
+	EnvAPIKeyFile      = "APP_API_KEY_FILE"
 	EnvVaultEnabled    = "APP_VAULT_ENABLED"
 	EnvVaultAddr       = "APP_VAULT_ADDR"
 	EnvVaultToken      = "APP_VAULT_TOKEN" //nolint:gosec // env var name, not a credential
 	EnvVaultPKIPath    = "APP_VAULT_PKI_PATH"
 	EnvVaultPKIRole    = "APP_VAULT_PKI_ROLE"
+	EnvVaultCommonName = "APP_VAULT_PKI_COMMON_NAME"
+	EnvVaultAltNames   = "APP_VAULT_PKI_ALT_NAMES"
+	EnvVaultIPSANs     = "APP_VAULT_PKI_IP_SANS"
+	EnvVaultPKITTL     = "APP_VAULT_PKI_TTL"
 	EnvProbePort       = "APP_PROBE_PORT"
+	EnvStoreDriver     = "APP_STORE_DRIVER"
+	EnvStoreDSN        = "APP_STORE_DSN"
+	EnvStorePath       = "APP_STORE_PATH"
+	EnvStoreMaxOpen    = "APP_STORE_MAX_OPEN_CONNS"
+	EnvStoreMaxIdle    = "APP_STORE_MAX_IDLE_CONNS"
+	EnvStoreConnMaxAge = "APP_STORE_CONN_MAX_LIFETIME"
+	EnvStoreIDPattern  = "APP_STORE_ID_PATTERN"
+	EnvStoreTimeout    = "APP_STORE_CONNECT_TIMEOUT"
+	EnvAuthzPolicyFile = "APP_AUTHZ_POLICY_FILE"
+	EnvRateLimit       = "APP_RATE_LIMIT_ENABLED"
+	EnvRateLimitStore  = "APP_RATE_LIMIT_BACKEND"
+	EnvRateLimitRedis  = "APP_RATE_LIMIT_REDIS_ADDR"
+	EnvRateLimitPass   = "APP_RATE_LIMIT_REDIS_PASSWORD" //nolint:gosec // env var name, not a credential
+	EnvRateLimitDB     = "APP_RATE_LIMIT_REDIS_DB"
+	EnvRateLimitRead   = "APP_RATE_LIMIT_READ"
+	EnvRateLimitWrite  = "APP_RATE_LIMIT_WRITE"
+	EnvRateLimitGQL    = "APP_RATE_LIMIT_GRAPHQL"
+	EnvIdempotency     = "APP_IDEMPOTENCY_ENABLED"
+	EnvIdempotencyTTL  = "APP_IDEMPOTENCY_TTL"
+	EnvIdemStore       = "APP_IDEMPOTENCY_BACKEND"
+	EnvIdemRedis       = "APP_IDEMPOTENCY_REDIS_ADDR"
+	EnvIdemRedisPass   = "APP_IDEMPOTENCY_REDIS_PASSWORD" //nolint:gosec // env var name, not a credential
+	EnvIdemRedisDB     = "APP_IDEMPOTENCY_REDIS_DB"
+	EnvCORSOrigins     = "APP_CORS_ALLOWED_ORIGINS"
+	EnvCORSOriginRegex = "APP_CORS_ALLOWED_ORIGIN_REGEX"
+	EnvCORSMethods     = "APP_CORS_ALLOWED_METHODS"
+	EnvCORSHeaders     = "APP_CORS_ALLOWED_HEADERS"
+	EnvCORSExposed     = "APP_CORS_EXPOSED_HEADERS"
+	EnvCORSCredentials = "APP_CORS_ALLOW_CREDENTIALS"
+	EnvCORSMaxAge      = "APP_CORS_MAX_AGE"
 )
 
 // Config holds the application configuration.
@@ -56,7 +154,19 @@ type Config struct {
 	MetricsEnabled  bool
 	OTLPEndpoint    string
 
-	// Authentication mode: none, mtls, oidc, basic, apikey, multi.
+	// CORS settings. Origins are exact, wildcard subdomains such as
+	// "https://*.example.com" or "*" for all; the regex must match an
+	// origin in full. Lists are comma-separated.
+	CORSAllowedOrigins   string
+	CORSOriginRegex      string
+	CORSAllowedMethods   string
+	CORSAllowedHeaders   string
+	CORSExposedHeaders   string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration // How long preflight responses are cached (0 = not sent).
+
+	// Authentication mode: none, mtls, oidc, introspection, basic, apikey,
+	// multi.
 	AuthMode string
 
 	// TLS settings.
@@ -66,23 +176,148 @@ type Config struct {
 	TLSCAPath     string
 	TLSClientAuth string
 
+	// How often the TLS certificate, key and CA files are checked for
+	// changes and reloaded (0 = never).
+	TLSReloadInterval time.Duration
+
+	// Revocation checking of verified client certificates (client auth
+	// require): a CRL file and/or URL refreshed every TLSCRLRefresh, and
+	// OCSP using the responder named in the certificate.
+	TLSCRLPath     string
+	TLSCRLURL      string
+	TLSCRLRefresh  time.Duration
+	TLSOCSPEnabled bool
+	TLSOCSPStrict  bool // Reject certificates whose OCSP status is unknown or CRL stale.
+
+	// mTLS identity mapping: the certificate attribute used as the subject
+	// (cn, uri, email, spiffe) and comma-separated allow-lists of SPIFFE
+	// trust domains, organizational units and SAN patterns.
+	MTLSSubject      string
+	MTLSTrustDomains string
+	MTLSAllowedOUs   string
+	MTLSAllowedSANs  string
+
 	// OIDC settings.
 	OIDCIssuerURL string
 	OIDCClientID  string
 	OIDCAudience  string
 
+	// Comma-separated JWS algorithms accepted for OIDC tokens; empty accepts
+	// all supported asymmetric algorithms.
+	OIDCAlgorithms string
+
+	// Leeway for the exp and nbf claims, and comma-separated claims every
+	// token must carry ("name" or "name=value").
+	OIDCClockSkew      time.Duration
+	OIDCRequiredClaims string
+
+	// Additional trusted issuers, loaded from APP_OIDC_ISSUER_<n>_* for
+	// n = 1, 2, ...; tokens are routed to an issuer by their iss claim.
+	OIDCIssuers []OIDCIssuer
+
+	// OAuth2 token introspection (RFC 7662) settings for opaque tokens. The
+	// client credentials authenticate this server to the endpoint; results
+	// are cached for IntrospectionCacheTTL (active tokens) and
+	// IntrospectionNegativeTTL (inactive tokens); 0 disables caching.
+	IntrospectionURL          string
+	IntrospectionClientID     string
+	IntrospectionClientSecret string
+	IntrospectionCacheTTL     time.Duration
+	IntrospectionNegativeTTL  time.Duration
+
 	// Basic auth settings (format: "user1:bcrypt_hash,user2:bcrypt_hash").
 	BasicAuthUsers string
 
+	// Alternatively, an htpasswd file checked for changes every
+	// BasicAuthReload (0 = never). Successful password verifications are
+	// cached for BasicAuthCacheTTL (0 = never).
+	BasicAuthFile     string
+	BasicAuthReload   time.Duration
+	BasicAuthCacheTTL time.Duration
+
+	// Lockout of Basic auth users, managed API keys and client IP addresses
+	// after LockoutMaxFailures and LockoutMaxClientFailures failed attempts
+	// (0 = never). Lockouts start at LockoutBaseDelay and double with each
+	// further failure up to LockoutMaxDelay; failures are forgotten
+	// LockoutResetAfter after the last one.
+	LockoutMaxFailures       int
+	LockoutMaxClientFailures int
+	LockoutBaseDelay         time.Duration
+	LockoutMaxDelay          time.Duration
+	LockoutResetAfter        time.Duration
+
 	// API key settings (format: "key1:name1,key2:name2").
 	APIKeys string
 
-	// Vault settings.
-	VaultEnabled bool
-	VaultAddr    string
-	VaultToken   string
-	VaultPKIPath string
-	VaultPKIRole string
+	// JSON file storing the managed API keys; empty keeps them in memory.
+	APIKeyFile string
+
+	// Authorization policy file (JSON); empty disables authorization.
+	AuthzPolicyFile string
+
+	// Vault settings. When Vault and TLS are both enabled, the server
+	// certificate is issued by the PKI secrets engine mounted at VaultPKIPath
+	// instead of being read from TLSCertPath and TLSKeyPath.
+	VaultEnabled       bool
+	VaultAddr          string
+	VaultToken         string
+	VaultPKIPath       string
+	VaultPKIRole       string
+	VaultPKICommonName string
+	VaultPKIAltNames   string        // Comma-separated DNS subject alternative names.
+	VaultPKIIPSANs     string        // Comma-separated IP subject alternative names.
+	VaultPKITTL        time.Duration // Requested certificate TTL (0 = role default).
+
+	// Store settings.
+	StoreDriver          string // Storage backend: memory, postgres, file.
+	StoreDSN             string // Connection string for the postgres driver.
+	StorePath            string // Database file path for the file driver.
+	StoreMaxOpenConns    int
+	StoreMaxIdleConns    int
+	StoreConnMaxLifetime time.Duration
+	StoreConnectTimeout  time.Duration
+	StoreIDPattern       string // Pattern of item IDs chosen by clients (empty = UUID).
+
+	// Rate limiting of each client (authenticated subject or IP address)
+	// with token buckets kept in memory or, to share them between replicas,
+	// in Redis. Limits apply per route class and have the form
+	// "<requests>/<period>[:<burst>]"; "0" disables a class.
+	RateLimitEnabled       bool
+	RateLimitBackend       string // memory, redis.
+	RateLimitRedisAddr     string
+	RateLimitRedisPassword string
+	RateLimitRedisDB       int
+	RateLimitRead          string // GET and HEAD requests.
+	RateLimitWrite         string // Other REST requests.
+	RateLimitGraphQL       string // GraphQL requests.
+
+	// Idempotency of POST requests to the items API made with an
+	// Idempotency-Key header: their responses are replayed to retries for
+	// the TTL, and kept in memory or, to share them between replicas, in
+	// Redis.
+	IdempotencyEnabled       bool
+	IdempotencyTTL           time.Duration
+	IdempotencyBackend       string // memory, redis.
+	IdempotencyRedisAddr     string
+	IdempotencyRedisPassword string
+	IdempotencyRedisDB       int
+}
+
+// RateLimit is a token bucket limit: Burst requests at once, refilled at
+// Requests per Period.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// OIDCIssuer configures a trusted OIDC token issuer.
+type OIDCIssuer struct {
+	URL            string
+	Audience       string
+	Algorithms     string        // Comma-separated JWS algorithms; empty accepts all supported.
+	ClockSkew      time.Duration // Leeway for the exp and nbf claims.
+	RequiredClaims string        // Comma-separated "name" or "name=value" requirements.
 }
 
 // Validation errors.
@@ -91,7 +326,7 @@ var (
 	ErrInvalidLogLevel        = errors.New("log level must be one of: debug, info, warn, error")
 	ErrInvalidShutdownTimeout = errors.New("shutdown timeout must be positive")
 	ErrInvalidAuthMode        = errors.New(
-		"auth mode must be one of: none, mtls, oidc, basic, apikey, multi",
+		"auth mode must be one of: none, mtls, oidc, introspection, basic, apikey, multi",
 	)
 	ErrInvalidTLSClientAuth = errors.New(
 		"TLS client auth must be one of: none, request, require",
@@ -102,30 +337,161 @@ var (
 	ErrInvalidTLSCARequired = errors.New(
 		"TLS CA path must be set when TLS client auth is require",
 	)
+	ErrInvalidTLSReload = errors.New(
+		"TLS reload interval must not be negative",
+	)
+	ErrInvalidTLSRevocation = errors.New(
+		"TLS must be enabled with client auth require when CRL or OCSP checking is configured",
+	)
+	ErrInvalidTLSCRLRefresh = errors.New(
+		"TLS CRL refresh interval must not be negative",
+	)
+	ErrInvalidMTLSSubject = errors.New(
+		"mTLS subject must be one of: cn, uri, email, spiffe",
+	)
 	ErrInvalidOIDCConfig = errors.New(
 		"OIDC issuer URL and client ID must be set when auth mode is oidc",
 	)
+	ErrInvalidOIDCIssuers = errors.New(
+		"OIDC issuer URLs must be unique",
+	)
+	ErrInvalidOIDCClockSkew = errors.New(
+		"OIDC clock skew must not be negative",
+	)
+	ErrInvalidOIDCClaims = errors.New(
+		"OIDC required claims must be a list of name or name=value",
+	)
+	ErrInvalidOIDCAlgorithms = errors.New(
+		"OIDC algorithms must be a list of: RS256, RS384, RS512, PS256, PS384, PS512, ES256, ES384, ES512, EdDSA",
+	)
+	ErrInvalidIntrospection = errors.New(
+		"introspection URL and client ID must be set when auth mode is introspection",
+	)
+	ErrInvalidIntrospectTTL = errors.New(
+		"introspection cache TTLs must not be negative",
+	)
 	ErrInvalidBasicAuthConfig = errors.New(
-		"basic auth users must be set when auth mode is basic",
+		"basic auth users or a users file must be set when auth mode is basic",
+	)
+	ErrInvalidBasicAuthFile = errors.New(
+		"basic auth users and users file are mutually exclusive",
+	)
+	ErrInvalidBasicAuthTTL = errors.New(
+		"basic auth reload interval and cache TTL must not be negative",
+	)
+	ErrInvalidLockout = errors.New(
+		"auth lockout thresholds must not be negative, and its delays and reset time must be positive " +
+			"with the base delay not above the max delay",
 	)
 	ErrInvalidAPIKeyConfig = errors.New(
-		"API keys must be set when auth mode is apikey",
+		"API keys or an API key file must be set when auth mode is apikey",
 	)
 	ErrInvalidMultiAuthConfig = errors.New(
 		"at least one auth config must be provided when auth mode is multi",
 	)
+	ErrInvalidVaultConfig = errors.New(
+		"vault address, token, PKI path and PKI role must be set when Vault is enabled",
+	)
+	ErrInvalidVaultTTL = errors.New(
+		"vault PKI TTL must not be negative",
+	)
+	ErrInvalidAuthzConfig = errors.New(
+		"auth mode must not be none when an authorization policy is set",
+	)
 	ErrInvalidProbePort = errors.New(
 		"probe port must be between 0 and 65535",
 	)
 	ErrProbePortConflict = errors.New(
 		"probe port must differ from server port when probe port is not 0",
 	)
+	ErrInvalidStoreDriver = errors.New(
+		"store driver must be one of: memory, postgres, file",
+	)
+	ErrInvalidStoreDSN = errors.New(
+		"store DSN must be set when store driver is postgres",
+	)
+	ErrInvalidStorePath = errors.New(
+		"store path must be set when store driver is file",
+	)
+	ErrInvalidStorePool = errors.New(
+		"store connection pool settings must not be negative",
+	)
+	ErrInvalidStoreIDPattern = errors.New(
+		"store ID pattern must be a valid regular expression",
+	)
+	ErrInvalidStoreTimeout = errors.New(
+		"store connect timeout must not be negative",
+	)
+	ErrInvalidRateLimit = errors.New(
+		"rate limits must have the form <requests>/<period>[:<burst>] or be 0",
+	)
+	ErrInvalidRateLimitBackend = errors.New(
+		"rate limit backend must be one of: memory, redis",
+	)
+	ErrInvalidRateLimitRedis = errors.New(
+		"rate limit Redis address must be set when the backend is redis",
+	)
+	ErrInvalidIdempotencyTTL = errors.New(
+		"idempotency TTL must be positive",
+	)
+	ErrInvalidIdempotencyBackend = errors.New(
+		"idempotency backend must be one of: memory, redis",
+	)
+	ErrInvalidIdempotencyRedis = errors.New(
+		"idempotency Redis address must be set when the backend is redis",
+	)
+
+	// CORS errors.
+	ErrInvalidCORSOrigin = errors.New(
+		"CORS origins must be \"*\" or have the form scheme://host[:port] or scheme://*.domain[:port]",
+	)
+	ErrInvalidCORSRegex       = errors.New("CORS origin regex is invalid")
+	ErrInvalidCORSMaxAge      = errors.New("CORS max age must not be negative")
+	ErrInvalidCORSCredentials = errors.New("CORS credentials cannot be allowed for all origins")
 )
 
 // Load reads configuration from environment variables with defaults.
 // Environment variables have priority over default values.
 func Load() (*Config, error) {
-	cfg := &Config{
+	return LoadFile("")
+}
+
+// LoadFile reads configuration from the YAML or TOML file at path, unless
+// path is empty, and from environment variables with defaults. Environment
+// variables have priority over the file, which has priority over default
+// values. Unknown keys in the file are errors. All invalid values are
+// reported at once.
+func LoadFile(path string) (*Config, error) {
+	cfg := defaultConfig()
+
+	getenv := os.Getenv
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("loading config file: %w", err)
+		}
+		getenv = func(key string) string {
+			if val := os.Getenv(key); val != "" {
+				return val
+			}
+			return values[key]
+		}
+	}
+
+	if err := cfg.loadFromEnv(getenv); err != nil {
+		return nil, fmt.Errorf("loading config from environment: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("validating config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// defaultConfig returns the configuration with default values.
+func defaultConfig() *Config {
+	return &Config{
 		ServerPort:      DefaultServerPort,
 		ProbePort:       DefaultProbePort,
 		LogLevel:        DefaultLogLevel,
@@ -134,35 +500,74 @@ func Load() (*Config, error) {
 		OTLPEndpoint:    "",
 		AuthMode:        DefaultAuthMode,
 		TLSClientAuth:   DefaultTLSClientAuth,
+		MTLSSubject:     DefaultMTLSSubject,
+
+		TLSReloadInterval:  DefaultTLSReloadInterval,
+		TLSCRLRefresh:      DefaultTLSCRLRefresh,
+		VaultPKICommonName: DefaultVaultPKICommonName,
+
+		IntrospectionCacheTTL:    DefaultIntrospectCacheTTL,
+		IntrospectionNegativeTTL: DefaultIntrospectNegTTL,
+		BasicAuthReload:          DefaultBasicAuthReload,
+		BasicAuthCacheTTL:        DefaultBasicAuthCacheTTL,
+		LockoutMaxFailures:       DefaultLockoutFailures,
+		LockoutMaxClientFailures: DefaultLockoutIPFailures,
+		LockoutBaseDelay:         DefaultLockoutBaseDelay,
+		LockoutMaxDelay:          DefaultLockoutMaxDelay,
+		LockoutResetAfter:        DefaultLockoutResetAfter,
+
+		StoreDriver:          DefaultStoreDriver,
+		StoreMaxOpenConns:    DefaultStoreMaxOpen,
+		StoreMaxIdleConns:    DefaultStoreMaxIdle,
+		StoreConnMaxLifetime: DefaultStoreConnMaxAge,
+		StoreConnectTimeout:  DefaultStoreTimeout,
+
+		RateLimitBackend: DefaultRateLimitBackend,
+		RateLimitRead:    DefaultRateLimitRead,
+		RateLimitWrite:   DefaultRateLimitWrite,
+		RateLimitGraphQL: DefaultRateLimitGraphQL,
+
+		IdempotencyEnabled: DefaultIdempotencyEnabled,
+		IdempotencyTTL:     DefaultIdempotencyTTL,
+		IdempotencyBackend: DefaultIdempotencyBackend,
+
+		CORSAllowedOrigins: DefaultCORSOrigins,
+		CORSAllowedMethods: DefaultCORSMethods,
+		CORSAllowedHeaders: DefaultCORSHeaders,
+		CORSExposedHeaders: DefaultCORSExposedHeaders,
+		CORSMaxAge:         DefaultCORSMaxAge,
 	}
+}
 
-	if err := cfg.loadFromEnv(); err != nil {
-		return nil, fmt.Errorf("loading config from environment: %w", err)
+// loadFromEnv loads configuration values from environment variables, as
+// returned by getenv.
+func (c *Config) loadFromEnv(getenv func(string) string) error {
+	if err := c.loadServerEnv(getenv); err != nil {
+		return err
 	}
 
-	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("validating config: %w", err)
+	if err := c.loadAuthEnv(getenv); err != nil {
+		return err
 	}
 
-	return cfg, nil
-}
+	if err := c.loadStoreEnv(getenv); err != nil {
+		return err
+	}
 
-// loadFromEnv loads configuration values from environment variables.
-func (c *Config) loadFromEnv() error {
-	if err := c.loadServerEnv(); err != nil {
+	if err := c.loadRateLimitEnv(getenv); err != nil {
 		return err
 	}
 
-	if err := c.loadAuthEnv(); err != nil {
+	if err := c.loadIdempotencyEnv(getenv); err != nil {
 		return err
 	}
 
-	return nil
+	return c.loadCORSEnv(getenv)
 }
 
 // loadServerEnv loads server-related environment variables.
-func (c *Config) loadServerEnv() error {
-	if val := os.Getenv(EnvServerPort); val != "" {
+func (c *Config) loadServerEnv(getenv func(string) string) error {
+	if val := getenv(EnvServerPort); val != "" {
 		port, err := strconv.Atoi(val)
 		if err != nil {
 			return fmt.Errorf("parsing %s: %w", EnvServerPort, err)
@@ -170,7 +575,7 @@ func (c *Config) loadServerEnv() error {
 		c.ServerPort = port
 	}
 
-	if val := os.Getenv(EnvProbePort); val != "" {
+	if val := getenv(EnvProbePort); val != "" {
 		port, err := strconv.Atoi(val)
 		if err != nil {
 			return fmt.Errorf("parsing %s: %w", EnvProbePort, err)
@@ -178,11 +583,11 @@ func (c *Config) loadServerEnv() error {
 		c.ProbePort = port
 	}
 
-	if val := os.Getenv(EnvLogLevel); val != "" {
+	if val := getenv(EnvLogLevel); val != "" {
 		c.LogLevel = val
 	}
 
-	if val := os.Getenv(EnvShutdownTimeout); val != "" {
+	if val := getenv(EnvShutdownTimeout); val != "" {
 		timeout, err := time.ParseDuration(val)
 		if err != nil {
 			return fmt.Errorf("parsing %s: %w", EnvShutdownTimeout, err)
@@ -190,7 +595,7 @@ func (c *Config) loadServerEnv() error {
 		c.ShutdownTimeout = timeout
 	}
 
-	if val := os.Getenv(EnvMetricsEnabled); val != "" {
+	if val := getenv(EnvMetricsEnabled); val != "" {
 		enabled, err := strconv.ParseBool(val)
 		if err != nil {
 			return fmt.Errorf("parsing %s: %w", EnvMetricsEnabled, err)
@@ -198,28 +603,88 @@ func (c *Config) loadServerEnv() error {
 		c.MetricsEnabled = enabled
 	}
 
-	if val := os.Getenv(EnvOTLPEndpoint); val != "" {
+	if val := getenv(EnvOTLPEndpoint); val != "" {
 		c.OTLPEndpoint = val
 	}
 
 	return nil
 }
 
+// loadCORSEnv loads CORS environment variables.
+func (c *Config) loadCORSEnv(getenv func(string) string) error {
+	if val := getenv(EnvCORSOrigins); val != "" {
+		c.CORSAllowedOrigins = val
+	}
+
+	if val := getenv(EnvCORSOriginRegex); val != "" {
+		c.CORSOriginRegex = val
+	}
+
+	if val := getenv(EnvCORSMethods); val != "" {
+		c.CORSAllowedMethods = val
+	}
+
+	if val := getenv(EnvCORSHeaders); val != "" {
+		c.CORSAllowedHeaders = val
+	}
+
+	if val := getenv(EnvCORSExposed); val != "" {
+		c.CORSExposedHeaders = val
+	}
+
+	if val := getenv(EnvCORSCredentials); val != "" {
+		allow, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvCORSCredentials, err)
+		}
+		c.CORSAllowCredentials = allow
+	}
+
+	if val := getenv(EnvCORSMaxAge); val != "" {
+		maxAge, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvCORSMaxAge, err)
+		}
+		c.CORSMaxAge = maxAge
+	}
+
+	return nil
+}
+
 // loadAuthEnv loads authentication and security environment variables.
-func (c *Config) loadAuthEnv() error {
-	if val := os.Getenv(EnvAuthMode); val != "" {
+func (c *Config) loadAuthEnv(getenv func(string) string) error {
+	if val := getenv(EnvAuthMode); val != "" {
 		c.AuthMode = val
 	}
 
-	if err := c.loadTLSEnv(); err != nil {
+	if err := c.loadTLSEnv(getenv); err != nil {
+		return err
+	}
+
+	c.loadMTLSEnv(getenv)
+	if err := c.loadOIDCEnv(getenv); err != nil {
+		return err
+	}
+
+	if err := c.loadIntrospectionEnv(getenv); err != nil {
 		return err
 	}
 
-	c.loadOIDCEnv()
-	c.loadBasicAuthEnv()
-	c.loadAPIKeyEnv()
+	if err := c.loadBasicAuthEnv(getenv); err != nil {
+		return err
+	}
 
-	if err := c.loadVaultEnv(); err != nil {
+	if err := c.loadLockoutEnv(getenv); err != nil {
+		return err
+	}
+
+	c.loadAPIKeyEnv(getenv)
+
+	if val := getenv(EnvAuthzPolicyFile); val != "" {
+		c.AuthzPolicyFile = val
+	}
+
+	if err := c.loadVaultEnv(getenv); err != nil {
 		return err
 	}
 
@@ -227,8 +692,8 @@ func (c *Config) loadAuthEnv() error {
 }
 
 // loadTLSEnv loads TLS-related environment variables.
-func (c *Config) loadTLSEnv() error {
-	if val := os.Getenv(EnvTLSEnabled); val != "" {
+func (c *Config) loadTLSEnv(getenv func(string) string) error {
+	if val := getenv(EnvTLSEnabled); val != "" {
 		enabled, err := strconv.ParseBool(val)
 		if err != nil {
 			return fmt.Errorf("parsing %s: %w", EnvTLSEnabled, err)
@@ -236,57 +701,273 @@ func (c *Config) loadTLSEnv() error {
 		c.TLSEnabled = enabled
 	}
 
-	if val := os.Getenv(EnvTLSCertPath); val != "" {
+	if val := getenv(EnvTLSCertPath); val != "" {
 		c.TLSCertPath = val
 	}
 
-	if val := os.Getenv(EnvTLSKeyPath); val != "" {
+	if val := getenv(EnvTLSKeyPath); val != "" {
 		c.TLSKeyPath = val
 	}
 
-	if val := os.Getenv(EnvTLSCAPath); val != "" {
+	if val := getenv(EnvTLSCAPath); val != "" {
 		c.TLSCAPath = val
 	}
 
-	if val := os.Getenv(EnvTLSClientAuth); val != "" {
+	if val := getenv(EnvTLSClientAuth); val != "" {
 		c.TLSClientAuth = val
 	}
 
+	if val := getenv(EnvTLSReload); val != "" {
+		interval, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvTLSReload, err)
+		}
+		c.TLSReloadInterval = interval
+	}
+
+	return c.loadRevocationEnv(getenv)
+}
+
+// loadRevocationEnv loads client certificate revocation environment variables.
+func (c *Config) loadRevocationEnv(getenv func(string) string) error {
+	if val := getenv(EnvTLSCRLPath); val != "" {
+		c.TLSCRLPath = val
+	}
+
+	if val := getenv(EnvTLSCRLURL); val != "" {
+		c.TLSCRLURL = val
+	}
+
+	if val := getenv(EnvTLSCRLRefresh); val != "" {
+		interval, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvTLSCRLRefresh, err)
+		}
+		c.TLSCRLRefresh = interval
+	}
+
+	if val := getenv(EnvTLSOCSPEnabled); val != "" {
+		enabled, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvTLSOCSPEnabled, err)
+		}
+		c.TLSOCSPEnabled = enabled
+	}
+
+	if val := getenv(EnvTLSOCSPStrict); val != "" {
+		strict, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvTLSOCSPStrict, err)
+		}
+		c.TLSOCSPStrict = strict
+	}
+
 	return nil
 }
 
+// loadMTLSEnv loads mTLS identity mapping environment variables.
+func (c *Config) loadMTLSEnv(getenv func(string) string) {
+	if val := getenv(EnvMTLSSubject); val != "" {
+		c.MTLSSubject = val
+	}
+
+	if val := getenv(EnvMTLSDomains); val != "" {
+		c.MTLSTrustDomains = val
+	}
+
+	if val := getenv(EnvMTLSAllowedOUs); val != "" {
+		c.MTLSAllowedOUs = val
+	}
+
+	if val := getenv(EnvMTLSAllowedSANs); val != "" {
+		c.MTLSAllowedSANs = val
+	}
+}
+
 // loadOIDCEnv loads OIDC-related environment variables.
-func (c *Config) loadOIDCEnv() {
-	if val := os.Getenv(EnvOIDCIssuerURL); val != "" {
+func (c *Config) loadOIDCEnv(getenv func(string) string) error {
+	if val := getenv(EnvOIDCIssuerURL); val != "" {
 		c.OIDCIssuerURL = val
 	}
 
-	if val := os.Getenv(EnvOIDCClientID); val != "" {
+	if val := getenv(EnvOIDCClientID); val != "" {
 		c.OIDCClientID = val
 	}
 
-	if val := os.Getenv(EnvOIDCAudience); val != "" {
+	if val := getenv(EnvOIDCAudience); val != "" {
 		c.OIDCAudience = val
 	}
+
+	if val := getenv(EnvOIDCAlgorithms); val != "" {
+		c.OIDCAlgorithms = val
+	}
+
+	if val := getenv(EnvOIDCClockSkew); val != "" {
+		skew, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvOIDCClockSkew, err)
+		}
+		c.OIDCClockSkew = skew
+	}
+
+	if val := getenv(EnvOIDCClaims); val != "" {
+		c.OIDCRequiredClaims = val
+	}
+
+	return c.loadOIDCIssuersEnv(getenv)
+}
+
+// loadOIDCIssuersEnv loads the indexed APP_OIDC_ISSUER_<n>_* variables of
+// additional OIDC issuers, stopping at the first n without a URL.
+func (c *Config) loadOIDCIssuersEnv(getenv func(string) string) error {
+	for n := 1; ; n++ {
+		env := func(name string) string {
+			return getenv(fmt.Sprintf(EnvOIDCIssuerN, n, name))
+		}
+
+		issuer := OIDCIssuer{
+			URL:            env("URL"),
+			Audience:       env("AUDIENCE"),
+			Algorithms:     env("ALGORITHMS"),
+			RequiredClaims: env("REQUIRED_CLAIMS"),
+		}
+		if issuer.URL == "" {
+			return nil
+		}
+
+		if val := env("CLOCK_SKEW"); val != "" {
+			skew, err := time.ParseDuration(val)
+			if err != nil {
+				return fmt.Errorf("parsing %s: %w", fmt.Sprintf(EnvOIDCIssuerN, n, "CLOCK_SKEW"), err)
+			}
+			issuer.ClockSkew = skew
+		}
+
+		c.OIDCIssuers = append(c.OIDCIssuers, issuer)
+	}
+}
+
+// loadIntrospectionEnv loads token introspection environment variables.
+func (c *Config) loadIntrospectionEnv(getenv func(string) string) error {
+	if val := getenv(EnvIntroURL); val != "" {
+		c.IntrospectionURL = val
+	}
+
+	if val := getenv(EnvIntroClientID); val != "" {
+		c.IntrospectionClientID = val
+	}
+
+	if val := getenv(EnvIntroSecret); val != "" {
+		c.IntrospectionClientSecret = val
+	}
+
+	if val := getenv(EnvIntroCacheTTL); val != "" {
+		ttl, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvIntroCacheTTL, err)
+		}
+		c.IntrospectionCacheTTL = ttl
+	}
+
+	if val := getenv(EnvIntroNegTTL); val != "" {
+		ttl, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvIntroNegTTL, err)
+		}
+		c.IntrospectionNegativeTTL = ttl
+	}
+
+	return nil
 }
 
 // loadBasicAuthEnv loads basic auth environment variables.
-func (c *Config) loadBasicAuthEnv() {
-	if val := os.Getenv(EnvBasicAuthUsers); val != "" {
+func (c *Config) loadBasicAuthEnv(getenv func(string) string) error {
+	if val := getenv(EnvBasicAuthUsers); val != "" {
 		c.BasicAuthUsers = val
 	}
+
+	if val := getenv(EnvBasicAuthFile); val != "" {
+		c.BasicAuthFile = val
+	}
+
+	if val := getenv(EnvBasicAuthReload); val != "" {
+		interval, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvBasicAuthReload, err)
+		}
+		c.BasicAuthReload = interval
+	}
+
+	if val := getenv(EnvBasicAuthCache); val != "" {
+		ttl, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvBasicAuthCache, err)
+		}
+		c.BasicAuthCacheTTL = ttl
+	}
+
+	return nil
+}
+
+// loadLockoutEnv loads auth lockout environment variables.
+func (c *Config) loadLockoutEnv(getenv func(string) string) error {
+	if val := getenv(EnvLockoutFailures); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvLockoutFailures, err)
+		}
+		c.LockoutMaxFailures = n
+	}
+
+	if val := getenv(EnvLockoutIPFails); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvLockoutIPFails, err)
+		}
+		c.LockoutMaxClientFailures = n
+	}
+
+	if val := getenv(EnvLockoutBase); val != "" {
+		delay, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvLockoutBase, err)
+		}
+		c.LockoutBaseDelay = delay
+	}
+
+	if val := getenv(EnvLockoutMaxDelay); val != "" {
+		delay, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvLockoutMaxDelay, err)
+		}
+		c.LockoutMaxDelay = delay
+	}
+
+	if val := getenv(EnvLockoutReset); val != "" {
+		reset, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvLockoutReset, err)
+		}
+		c.LockoutResetAfter = reset
+	}
+
+	return nil
 }
 
 // loadAPIKeyEnv loads API key environment variables.
-func (c *Config) loadAPIKeyEnv() {
-	if val := os.Getenv(EnvAPIKeys); val != "" {
+func (c *Config) loadAPIKeyEnv(getenv func(string) string) {
+	if val := getenv(EnvAPIKeys); val != "" {
 		c.APIKeys = val
 	}
+
+	if val := getenv(EnvAPIKeyFile); val != "" {
+		c.APIKeyFile = val
+	}
 }
 
 // loadVaultEnv loads Vault-related environment variables.
-func (c *Config) loadVaultEnv() error {
-	if val := os.Getenv(EnvVaultEnabled); val != "" {
+func (c *Config) loadVaultEnv(getenv func(string) string) error {
+	if val := getenv(EnvVaultEnabled); val != "" {
 		enabled, err := strconv.ParseBool(val)
 		if err != nil {
 			return fmt.Errorf("parsing %s: %w", EnvVaultEnabled, err)
@@ -294,50 +975,146 @@ func (c *Config) loadVaultEnv() error {
 		c.VaultEnabled = enabled
 	}
 
-	if val := os.Getenv(EnvVaultAddr); val != "" {
+	if val := getenv(EnvVaultAddr); val != "" {
 		c.VaultAddr = val
 	}
 
-	if val := os.Getenv(EnvVaultToken); val != "" {
+	if val := getenv(EnvVaultToken); val != "" {
 		c.VaultToken = val
 	}
 
-	if val := os.Getenv(EnvVaultPKIPath); val != "" {
+	if val := getenv(EnvVaultPKIPath); val != "" {
 		c.VaultPKIPath = val
 	}
 
-	if val := os.Getenv(EnvVaultPKIRole); val != "" {
+	if val := getenv(EnvVaultPKIRole); val != "" {
 		c.VaultPKIRole = val
 	}
 
+	if val := getenv(EnvVaultCommonName); val != "" {
+		c.VaultPKICommonName = val
+	}
+
+	if val := getenv(EnvVaultAltNames); val != "" {
+		c.VaultPKIAltNames = val
+	}
+
+	if val := getenv(EnvVaultIPSANs); val != "" {
+		c.VaultPKIIPSANs = val
+	}
+
+	if val := getenv(EnvVaultPKITTL); val != "" {
+		ttl, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvVaultPKITTL, err)
+		}
+		c.VaultPKITTL = ttl
+	}
+
 	return nil
 }
 
-// Validate checks if the configuration values are valid.
-func (c *Config) Validate() error {
-	if err := c.validateServer(); err != nil {
-		return err
+// loadStoreEnv loads storage backend environment variables.
+func (c *Config) loadStoreEnv(getenv func(string) string) error {
+	if val := getenv(EnvStoreDriver); val != "" {
+		c.StoreDriver = val
 	}
 
-	if err := c.validateAuth(); err != nil {
-		return err
+	if val := getenv(EnvStoreDSN); val != "" {
+		c.StoreDSN = val
+	}
+
+	if val := getenv(EnvStorePath); val != "" {
+		c.StorePath = val
+	}
+
+	if val := getenv(EnvStoreMaxOpen); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvStoreMaxOpen, err)
+		}
+		c.StoreMaxOpenConns = n
+	}
+
+	if val := getenv(EnvStoreMaxIdle); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvStoreMaxIdle, err)
+		}
+		c.StoreMaxIdleConns = n
+	}
+
+	if val := getenv(EnvStoreConnMaxAge); val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvStoreConnMaxAge, err)
+		}
+		c.StoreConnMaxLifetime = d
+	}
+
+	if val := getenv(EnvStoreIDPattern); val != "" {
+		c.StoreIDPattern = val
+	}
+
+	if val := getenv(EnvStoreTimeout); val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvStoreTimeout, err)
+		}
+		c.StoreConnectTimeout = d
 	}
 
 	return nil
 }
 
+// Validate checks if the configuration values are valid. It reports all
+// invalid values at once: a single error is returned as is, several are
+// joined.
+func (c *Config) Validate() error {
+	return joinErrors(
+		c.validateServer(),
+		c.validateAuth(),
+		c.validateStore(),
+		c.validateRateLimit(),
+		c.validateIdempotency(),
+		c.validateCORS(),
+	)
+}
+
+// joinErrors returns nil if all errs are nil, the only non-nil error, or
+// the non-nil errors joined. Joined errors are flattened, so that each
+// error is reported on its own line.
+func joinErrors(errs ...error) error {
+	var all []error
+	for _, err := range errs {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			all = append(all, joined.Unwrap()...)
+		} else if err != nil {
+			all = append(all, err)
+		}
+	}
+
+	switch len(all) {
+	case 0:
+		return nil
+	case 1:
+		return all[0]
+	default:
+		return errors.Join(all...)
+	}
+}
+
 // validateServer validates server-related configuration.
 func (c *Config) validateServer() error {
+	var errs []error
 	if c.ServerPort < 1 || c.ServerPort > 65535 {
-		return ErrInvalidServerPort
+		errs = append(errs, ErrInvalidServerPort)
 	}
 
 	if c.ProbePort != 0 && (c.ProbePort < 1 || c.ProbePort > 65535) {
-		return ErrInvalidProbePort
-	}
-
-	if c.ProbePort != 0 && c.ProbePort == c.ServerPort {
-		return ErrProbePortConflict
+		errs = append(errs, ErrInvalidProbePort)
+	} else if c.ProbePort != 0 && c.ProbePort == c.ServerPort {
+		errs = append(errs, ErrProbePortConflict)
 	}
 
 	validLogLevels := map[string]bool{
@@ -347,35 +1124,80 @@ func (c *Config) validateServer() error {
 		"error": true,
 	}
 	if !validLogLevels[c.LogLevel] {
-		return ErrInvalidLogLevel
+		errs = append(errs, ErrInvalidLogLevel)
 	}
 
 	if c.ShutdownTimeout <= 0 {
-		return ErrInvalidShutdownTimeout
+		errs = append(errs, ErrInvalidShutdownTimeout)
 	}
 
-	return nil
+	return joinErrors(errs...)
 }
 
 // validateAuth validates authentication and security configuration.
 func (c *Config) validateAuth() error {
 	authMode := c.authModeOrDefault()
 
-	if err := c.validateAuthMode(authMode); err != nil {
-		return err
+	var introspectionErr, authzErr error
+	if c.IntrospectionCacheTTL < 0 || c.IntrospectionNegativeTTL < 0 {
+		introspectionErr = ErrInvalidIntrospectTTL
+	}
+	if c.AuthzPolicyFile != "" && authMode == DefaultAuthMode {
+		authzErr = ErrInvalidAuthzConfig
 	}
 
-	if err := c.validateTLS(); err != nil {
-		return err
+	return joinErrors(
+		c.validateAuthMode(authMode),
+		c.validateTLS(),
+		c.validateVault(),
+		c.validateMTLS(),
+		c.validateOIDC(),
+		introspectionErr,
+		c.validateBasicAuth(),
+		c.validateLockout(),
+		c.validateAuthModeRequirements(authMode),
+		authzErr,
+	)
+}
+
+// validateBasicAuth validates Basic auth configuration.
+func (c *Config) validateBasicAuth() error {
+	var errs []error
+	if c.BasicAuthUsers != "" && c.BasicAuthFile != "" {
+		errs = append(errs, ErrInvalidBasicAuthFile)
 	}
 
-	if err := c.validateAuthModeRequirements(authMode); err != nil {
-		return err
+	if c.BasicAuthReload < 0 || c.BasicAuthCacheTTL < 0 {
+		errs = append(errs, ErrInvalidBasicAuthTTL)
+	}
+
+	return joinErrors(errs...)
+}
+
+// validateLockout validates auth lockout configuration. The delays and
+// reset time only matter while lockouts are enabled.
+func (c *Config) validateLockout() error {
+	if c.LockoutMaxFailures < 0 || c.LockoutMaxClientFailures < 0 {
+		return ErrInvalidLockout
+	}
+
+	if !c.LockoutEnabled() {
+		return nil
+	}
+
+	if c.LockoutBaseDelay <= 0 || c.LockoutMaxDelay < c.LockoutBaseDelay || c.LockoutResetAfter <= 0 {
+		return ErrInvalidLockout
 	}
 
 	return nil
 }
 
+// LockoutEnabled reports whether users, API keys or clients are locked out
+// after failed attempts.
+func (c *Config) LockoutEnabled() bool {
+	return c.LockoutMaxFailures > 0 || c.LockoutMaxClientFailures > 0
+}
+
 // authModeOrDefault returns the auth mode, defaulting to "none" if empty.
 func (c *Config) authModeOrDefault() string {
 	if c.AuthMode == "" {
@@ -395,12 +1217,13 @@ func (c *Config) tlsClientAuthOrDefault() string {
 // validateAuthMode checks that the auth mode is a valid value.
 func (c *Config) validateAuthMode(authMode string) error {
 	validAuthModes := map[string]bool{
-		"none":   true,
-		"mtls":   true,
-		"oidc":   true,
-		"basic":  true,
-		"apikey": true,
-		"multi":  true,
+		"none":          true,
+		"mtls":          true,
+		"oidc":          true,
+		"introspection": true,
+		"basic":         true,
+		"apikey":        true,
+		"multi":         true,
 	}
 	if !validAuthModes[authMode] {
 		return ErrInvalidAuthMode
@@ -418,34 +1241,167 @@ func (c *Config) validateTLS() error {
 		"request": true,
 		"require": true,
 	}
+	var errs []error
 	if !validClientAuth[clientAuth] {
-		return ErrInvalidTLSClientAuth
+		errs = append(errs, ErrInvalidTLSClientAuth)
 	}
 
-	if c.TLSEnabled && (c.TLSCertPath == "" || c.TLSKeyPath == "") {
-		return ErrInvalidTLSCertRequired
+	if c.TLSEnabled && !c.VaultEnabled && (c.TLSCertPath == "" || c.TLSKeyPath == "") {
+		errs = append(errs, ErrInvalidTLSCertRequired)
 	}
 
 	if clientAuth == "require" && c.TLSCAPath == "" {
-		return ErrInvalidTLSCARequired
+		errs = append(errs, ErrInvalidTLSCARequired)
+	}
+
+	if c.TLSReloadInterval < 0 {
+		errs = append(errs, ErrInvalidTLSReload)
+	}
+
+	return joinErrors(append(errs, c.validateRevocation(clientAuth))...)
+}
+
+// validateRevocation validates client certificate revocation configuration.
+func (c *Config) validateRevocation(clientAuth string) error {
+	var errs []error
+	if c.TLSCRLRefresh < 0 {
+		errs = append(errs, ErrInvalidTLSCRLRefresh)
+	}
+
+	if c.RevocationEnabled() && (!c.TLSEnabled || clientAuth != "require") {
+		errs = append(errs, ErrInvalidTLSRevocation)
+	}
+
+	return joinErrors(errs...)
+}
+
+// RevocationEnabled reports whether client certificates are checked for
+// revocation.
+func (c *Config) RevocationEnabled() bool {
+	return c.TLSCRLPath != "" || c.TLSCRLURL != "" || c.TLSOCSPEnabled
+}
+
+// validateMTLS validates mTLS identity mapping configuration.
+func (c *Config) validateMTLS() error {
+	switch c.MTLSSubject {
+	case "", "cn", "uri", "email", "spiffe":
+		return nil
+	default:
+		return ErrInvalidMTLSSubject
+	}
+}
+
+// OIDCIssuerList returns all trusted OIDC issuers: the one configured by
+// OIDCIssuerURL, if any, followed by OIDCIssuers.
+func (c *Config) OIDCIssuerList() []OIDCIssuer {
+	var issuers []OIDCIssuer
+	if c.OIDCIssuerURL != "" {
+		issuers = append(issuers, OIDCIssuer{
+			URL:            c.OIDCIssuerURL,
+			Audience:       c.OIDCAudience,
+			Algorithms:     c.OIDCAlgorithms,
+			ClockSkew:      c.OIDCClockSkew,
+			RequiredClaims: c.OIDCRequiredClaims,
+		})
+	}
+	return append(issuers, c.OIDCIssuers...)
+}
+
+// validateOIDC validates the settings of all OIDC issuers.
+func (c *Config) validateOIDC() error {
+	primary := OIDCIssuer{
+		Algorithms:     c.OIDCAlgorithms,
+		ClockSkew:      c.OIDCClockSkew,
+		RequiredClaims: c.OIDCRequiredClaims,
+	}
+	errs := []error{primary.validate()}
+
+	seen := map[string]bool{c.OIDCIssuerURL: c.OIDCIssuerURL != ""}
+	for _, issuer := range c.OIDCIssuers {
+		if seen[issuer.URL] {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidOIDCIssuers, issuer.URL))
+		}
+		seen[issuer.URL] = true
+
+		errs = append(errs, issuer.validate())
+	}
+
+	return joinErrors(errs...)
+}
+
+// validate validates the token rules of an OIDC issuer.
+func (i OIDCIssuer) validate() error {
+	if err := validateOIDCAlgorithms(i.Algorithms); err != nil {
+		return err
+	}
+
+	if i.ClockSkew < 0 {
+		return ErrInvalidOIDCClockSkew
+	}
+
+	for _, claim := range strings.Split(i.RequiredClaims, ",") {
+		name, _, _ := strings.Cut(claim, "=")
+		if strings.TrimSpace(claim) != "" && strings.TrimSpace(name) == "" {
+			return fmt.Errorf("%w: %s", ErrInvalidOIDCClaims, claim)
+		}
 	}
 
 	return nil
 }
 
+// validateOIDCAlgorithms checks that an OIDC algorithm allow-list only
+// names supported asymmetric JWS algorithms.
+func validateOIDCAlgorithms(algorithms string) error {
+	supported := map[string]bool{
+		"RS256": true, "RS384": true, "RS512": true,
+		"PS256": true, "PS384": true, "PS512": true,
+		"ES256": true, "ES384": true, "ES512": true,
+		"EdDSA": true,
+	}
+	for _, alg := range SplitList(algorithms) {
+		if !supported[alg] {
+			return fmt.Errorf("%w: %s", ErrInvalidOIDCAlgorithms, alg)
+		}
+	}
+
+	return nil
+}
+
+// validateVault validates Vault-related configuration.
+func (c *Config) validateVault() error {
+	if !c.VaultEnabled {
+		return nil
+	}
+
+	var errs []error
+	if c.VaultAddr == "" || c.VaultToken == "" || c.VaultPKIPath == "" || c.VaultPKIRole == "" {
+		errs = append(errs, ErrInvalidVaultConfig)
+	}
+
+	if c.VaultPKITTL < 0 {
+		errs = append(errs, ErrInvalidVaultTTL)
+	}
+
+	return joinErrors(errs...)
+}
+
 // validateAuthModeRequirements validates auth-mode-specific requirements.
 func (c *Config) validateAuthModeRequirements(authMode string) error {
 	switch authMode {
 	case "oidc":
-		if c.OIDCIssuerURL == "" || c.OIDCClientID == "" {
+		if len(c.OIDCIssuerList()) == 0 || c.OIDCClientID == "" {
 			return ErrInvalidOIDCConfig
 		}
+	case "introspection":
+		if c.IntrospectionURL == "" || c.IntrospectionClientID == "" {
+			return ErrInvalidIntrospection
+		}
 	case "basic":
-		if c.BasicAuthUsers == "" {
+		if c.BasicAuthUsers == "" && c.BasicAuthFile == "" {
 			return ErrInvalidBasicAuthConfig
 		}
 	case "apikey":
-		if c.APIKeys == "" {
+		if c.APIKeys == "" && c.APIKeyFile == "" {
 			return ErrInvalidAPIKeyConfig
 		}
 	case "multi":
@@ -460,12 +1416,312 @@ func (c *Config) validateAuthModeRequirements(authMode string) error {
 // hasAnyAuthConfig checks if at least one auth-related configuration is provided.
 func (c *Config) hasAnyAuthConfig() bool {
 	return c.OIDCIssuerURL != "" ||
+		len(c.OIDCIssuers) > 0 ||
 		c.OIDCClientID != "" ||
+		c.IntrospectionURL != "" ||
 		c.BasicAuthUsers != "" ||
+		c.BasicAuthFile != "" ||
 		c.APIKeys != "" ||
+		c.APIKeyFile != "" ||
 		c.TLSEnabled
 }
 
+// storeDriverOrDefault returns the store driver, defaulting to "memory" if empty.
+func (c *Config) storeDriverOrDefault() string {
+	if c.StoreDriver == "" {
+		return DefaultStoreDriver
+	}
+	return c.StoreDriver
+}
+
+// StoreConnectTimeoutOrDefault returns the deadline to connect to and
+// migrate the database on startup, defaulting to DefaultStoreTimeout if 0.
+func (c *Config) StoreConnectTimeoutOrDefault() time.Duration {
+	if c.StoreConnectTimeout == 0 {
+		return DefaultStoreTimeout
+	}
+	return c.StoreConnectTimeout
+}
+
+// validateStore validates storage backend configuration.
+func (c *Config) validateStore() error {
+	var errs []error
+	switch c.storeDriverOrDefault() {
+	case "memory":
+	case "postgres":
+		if c.StoreDSN == "" {
+			errs = append(errs, ErrInvalidStoreDSN)
+		}
+	case "file":
+		if c.StorePath == "" {
+			errs = append(errs, ErrInvalidStorePath)
+		}
+	default:
+		errs = append(errs, ErrInvalidStoreDriver)
+	}
+
+	if c.StoreMaxOpenConns < 0 || c.StoreMaxIdleConns < 0 || c.StoreConnMaxLifetime < 0 {
+		errs = append(errs, ErrInvalidStorePool)
+	}
+
+	if c.StoreConnectTimeout < 0 {
+		errs = append(errs, ErrInvalidStoreTimeout)
+	}
+
+	if c.StoreIDPattern != "" {
+		if _, err := regexp.Compile(c.StoreIDPattern); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %w", ErrInvalidStoreIDPattern, err))
+		}
+	}
+
+	return joinErrors(errs...)
+}
+
+// loadRateLimitEnv loads rate limiting environment variables.
+func (c *Config) loadRateLimitEnv(getenv func(string) string) error {
+	if val := getenv(EnvRateLimit); val != "" {
+		enabled, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvRateLimit, err)
+		}
+		c.RateLimitEnabled = enabled
+	}
+
+	if val := getenv(EnvRateLimitStore); val != "" {
+		c.RateLimitBackend = val
+	}
+
+	if val := getenv(EnvRateLimitRedis); val != "" {
+		c.RateLimitRedisAddr = val
+	}
+
+	if val := getenv(EnvRateLimitPass); val != "" {
+		c.RateLimitRedisPassword = val
+	}
+
+	if val := getenv(EnvRateLimitDB); val != "" {
+		db, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvRateLimitDB, err)
+		}
+		c.RateLimitRedisDB = db
+	}
+
+	if val := getenv(EnvRateLimitRead); val != "" {
+		c.RateLimitRead = val
+	}
+
+	if val := getenv(EnvRateLimitWrite); val != "" {
+		c.RateLimitWrite = val
+	}
+
+	if val := getenv(EnvRateLimitGQL); val != "" {
+		c.RateLimitGraphQL = val
+	}
+
+	return nil
+}
+
+// validateRateLimit validates rate limiting configuration.
+func (c *Config) validateRateLimit() error {
+	if !c.RateLimitEnabled {
+		return nil
+	}
+
+	var errs []error
+	switch c.RateLimitBackend {
+	case "", "memory":
+	case "redis":
+		if c.RateLimitRedisAddr == "" {
+			errs = append(errs, ErrInvalidRateLimitRedis)
+		}
+	default:
+		errs = append(errs, ErrInvalidRateLimitBackend)
+	}
+
+	for _, limit := range []string{c.RateLimitRead, c.RateLimitWrite, c.RateLimitGraphQL} {
+		if _, err := ParseRateLimit(limit); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return joinErrors(errs...)
+}
+
+// loadIdempotencyEnv loads idempotency environment variables.
+func (c *Config) loadIdempotencyEnv(getenv func(string) string) error {
+	if val := getenv(EnvIdempotency); val != "" {
+		enabled, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvIdempotency, err)
+		}
+		c.IdempotencyEnabled = enabled
+	}
+
+	if val := getenv(EnvIdempotencyTTL); val != "" {
+		ttl, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvIdempotencyTTL, err)
+		}
+		c.IdempotencyTTL = ttl
+	}
+
+	if val := getenv(EnvIdemStore); val != "" {
+		c.IdempotencyBackend = val
+	}
+
+	if val := getenv(EnvIdemRedis); val != "" {
+		c.IdempotencyRedisAddr = val
+	}
+
+	if val := getenv(EnvIdemRedisPass); val != "" {
+		c.IdempotencyRedisPassword = val
+	}
+
+	if val := getenv(EnvIdemRedisDB); val != "" {
+		db, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", EnvIdemRedisDB, err)
+		}
+		c.IdempotencyRedisDB = db
+	}
+
+	return nil
+}
+
+// validateIdempotency validates idempotency configuration.
+func (c *Config) validateIdempotency() error {
+	if !c.IdempotencyEnabled {
+		return nil
+	}
+
+	var errs []error
+	if c.IdempotencyTTL <= 0 {
+		errs = append(errs, ErrInvalidIdempotencyTTL)
+	}
+
+	switch c.IdempotencyBackend {
+	case "", "memory":
+	case "redis":
+		if c.IdempotencyRedisAddr == "" {
+			errs = append(errs, ErrInvalidIdempotencyRedis)
+		}
+	default:
+		errs = append(errs, ErrInvalidIdempotencyBackend)
+	}
+
+	return joinErrors(errs...)
+}
+
+// ParseRateLimit parses a rate limit of the form "<requests>/<period>"
+// with an optional ":<burst>", e.g. "100/1m" or "10/s:50". The period is a
+// duration, where a bare unit such as "s" stands for one of it; the burst
+// defaults to the requests. Empty and "0" return the zero RateLimit, which
+// disables limiting.
+func ParseRateLimit(s string) (RateLimit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return RateLimit{}, nil
+	}
+
+	rate, burst, hasBurst := strings.Cut(s, ":")
+	requests, period, ok := strings.Cut(rate, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("%w: %q", ErrInvalidRateLimit, s)
+	}
+
+	var limit RateLimit
+	var err error
+	if limit.Requests, err = strconv.Atoi(requests); err != nil || limit.Requests <= 0 {
+		return RateLimit{}, fmt.Errorf("%w: %q: invalid requests", ErrInvalidRateLimit, s)
+	}
+	if period != "" && !strings.ContainsAny(period[:1], "0123456789") {
+		period = "1" + period
+	}
+	if limit.Period, err = time.ParseDuration(period); err != nil || limit.Period <= 0 {
+		return RateLimit{}, fmt.Errorf("%w: %q: invalid period", ErrInvalidRateLimit, s)
+	}
+	limit.Burst = limit.Requests
+	if hasBurst {
+		if limit.Burst, err = strconv.Atoi(burst); err != nil || limit.Burst <= 0 {
+			return RateLimit{}, fmt.Errorf("%w: %q: invalid burst", ErrInvalidRateLimit, s)
+		}
+	}
+
+	return limit, nil
+}
+
+// SplitList splits a comma-separated list value, such as the mTLS trust
+// domains or the Vault PKI alt names, trimming entries and dropping empty
+// ones.
+func SplitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// validateCORS validates CORS configuration.
+func (c *Config) validateCORS() error {
+	var errs []error
+	anyOrigin := false
+	for _, origin := range strings.Split(c.CORSAllowedOrigins, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "*" {
+			anyOrigin = true
+			continue
+		}
+		if origin != "" && !validCORSOrigin(origin) {
+			errs = append(errs, fmt.Errorf("%w: %q", ErrInvalidCORSOrigin, origin))
+		}
+	}
+
+	if c.CORSOriginRegex != "" {
+		if _, err := regexp.Compile(c.CORSOriginRegex); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %w", ErrInvalidCORSRegex, err))
+		}
+	}
+
+	if c.CORSMaxAge < 0 {
+		errs = append(errs, ErrInvalidCORSMaxAge)
+	}
+
+	if c.CORSAllowCredentials && anyOrigin {
+		errs = append(errs, ErrInvalidCORSCredentials)
+	}
+
+	return joinErrors(errs...)
+}
+
+// validCORSOrigin reports whether origin has the form scheme://host[:port],
+// where host may start with a "*." subdomain wildcard.
+func validCORSOrigin(origin string) bool {
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok || scheme == "" || strings.Contains(host, "/") {
+		return false
+	}
+
+	host = strings.TrimPrefix(host, "*.")
+	return host != "" && !strings.Contains(host, "*")
+}
+
+// Diff returns the names of the fields whose values differ between c and
+// other, in declaration order.
+func (c *Config) Diff(other *Config) []string {
+	a, b := reflect.ValueOf(c).Elem(), reflect.ValueOf(other).Elem()
+
+	var changed []string
+	for i := range a.NumField() {
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			changed = append(changed, a.Type().Field(i).Name)
+		}
+	}
+	return changed
+}
+
 // Address returns the server address in host:port format.
 func (c *Config) Address() string {
 	return fmt.Sprintf(":%d", c.ServerPort)
@@ -2,7 +2,11 @@
 package config
 
 import (
+	"errors"
 	"os"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -478,6 +482,60 @@ func TestConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestConfig_Validate_MultipleErrors(t *testing.T) {
+	// Arrange
+	cfg := Config{
+		ServerPort:       0,
+		LogLevel:         "loud",
+		ShutdownTimeout:  30 * time.Second,
+		AuthMode:         "basic",
+		BasicAuthUsers:   "admin:secret",
+		BasicAuthReload:  -time.Second,
+		StoreDriver:      "mongo",
+		RateLimitEnabled: true,
+		RateLimitRead:    "fast",
+	}
+
+	// Act
+	err := cfg.Validate()
+
+	// Assert
+	for _, want := range []error{
+		ErrInvalidServerPort,
+		ErrInvalidLogLevel,
+		ErrInvalidBasicAuthTTL,
+		ErrInvalidStoreDriver,
+		ErrInvalidRateLimit,
+	} {
+		if !errors.Is(err, want) {
+			t.Errorf("Validate() error = %v, want it to include %v", err, want)
+		}
+	}
+	if errors.Is(err, ErrInvalidShutdownTimeout) {
+		t.Errorf("Validate() error = %v, want no %v", err, ErrInvalidShutdownTimeout)
+	}
+}
+
+func TestConfig_Diff(t *testing.T) {
+	// Arrange
+	a := Config{ServerPort: 8080, LogLevel: "info", OIDCIssuers: []OIDCIssuer{{URL: "https://a"}}}
+	b := a
+	b.LogLevel = "debug"
+	b.OIDCIssuers = []OIDCIssuer{{URL: "https://b"}}
+
+	// Act
+	same := a.Diff(&a)
+	changed := a.Diff(&b)
+
+	// Assert
+	if len(same) != 0 {
+		t.Errorf("Diff() of equal configs = %v, want none", same)
+	}
+	if want := []string{"LogLevel", "OIDCIssuers"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("Diff() = %v, want %v", changed, want)
+	}
+}
+
 func TestConfig_Address(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -549,6 +607,32 @@ func TestConfig_ProbeAddress(t *testing.T) {
 	}
 }
 
+func TestConfig_StoreConnectTimeoutOrDefault(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{"unset", 0, DefaultStoreTimeout},
+		{"configured", 5 * time.Second, 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			cfg := &Config{StoreConnectTimeout: tt.timeout}
+
+			// Act
+			got := cfg.StoreConnectTimeoutOrDefault()
+
+			// Assert
+			if got != tt.want {
+				t.Errorf("StoreConnectTimeoutOrDefault() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestValidLogLevels(t *testing.T) {
 	validLevels := []string{"debug", "info", "warn", "error"}
 
@@ -595,6 +679,9 @@ func TestLoadAuthModeDefaults(t *testing.T) {
 	if cfg.TLSClientAuth != DefaultTLSClientAuth {
 		t.Errorf("TLSClientAuth = %s, want %s", cfg.TLSClientAuth, DefaultTLSClientAuth)
 	}
+	if cfg.MTLSSubject != DefaultMTLSSubject {
+		t.Errorf("MTLSSubject = %s, want %s", cfg.MTLSSubject, DefaultMTLSSubject)
+	}
 	if cfg.VaultEnabled {
 		t.Error("VaultEnabled should default to false")
 	}
@@ -909,33 +996,349 @@ func TestLoadTLSValidation(t *testing.T) {
 	}
 }
 
-func TestLoadOIDCConfig(t *testing.T) {
+func TestLoadTLSReloadInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "default", want: DefaultTLSReloadInterval},
+		{name: "custom", value: "1m", want: time.Minute},
+		{name: "disabled", value: "0s", want: 0},
+		{name: "negative", value: "-1s", wantErr: true},
+		{name: "invalid", value: "often", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			if tt.value != "" {
+				t.Setenv(EnvTLSReload, tt.value)
+			}
+
+			// Act
+			cfg, err := Load()
+
+			// Assert
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() returned unexpected error: %v", err)
+			}
+			if cfg.TLSReloadInterval != tt.want {
+				t.Errorf("TLSReloadInterval = %v, want %v", cfg.TLSReloadInterval, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRevocationConfig(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvTLSEnabled, "true")
+	t.Setenv(EnvTLSCertPath, "/certs/tls.crt")
+	t.Setenv(EnvTLSKeyPath, "/certs/tls.key")
+	t.Setenv(EnvTLSCAPath, "/certs/ca.crt")
+	t.Setenv(EnvTLSClientAuth, "require")
+	t.Setenv(EnvTLSCRLPath, "/certs/ca.crl")
+	t.Setenv(EnvTLSCRLURL, "http://pki.example.com/ca.crl")
+	t.Setenv(EnvTLSCRLRefresh, "15m")
+	t.Setenv(EnvTLSOCSPEnabled, "true")
+	t.Setenv(EnvTLSOCSPStrict, "true")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.TLSCRLPath != "/certs/ca.crl" {
+		t.Errorf("TLSCRLPath = %s, want /certs/ca.crl", cfg.TLSCRLPath)
+	}
+	if cfg.TLSCRLURL != "http://pki.example.com/ca.crl" {
+		t.Errorf("TLSCRLURL = %s, want http://pki.example.com/ca.crl", cfg.TLSCRLURL)
+	}
+	if cfg.TLSCRLRefresh != 15*time.Minute {
+		t.Errorf("TLSCRLRefresh = %v, want 15m", cfg.TLSCRLRefresh)
+	}
+	if !cfg.TLSOCSPEnabled || !cfg.TLSOCSPStrict {
+		t.Errorf("TLSOCSPEnabled = %v, TLSOCSPStrict = %v, want both true", cfg.TLSOCSPEnabled, cfg.TLSOCSPStrict)
+	}
+	if !cfg.RevocationEnabled() {
+		t.Error("RevocationEnabled() should be true")
+	}
+}
+
+func TestLoadRevocationConfigErrors(t *testing.T) {
+	mtls := map[string]string{
+		EnvTLSEnabled:    "true",
+		EnvTLSCertPath:   "/certs/tls.crt",
+		EnvTLSKeyPath:    "/certs/tls.key",
+		EnvTLSCAPath:     "/certs/ca.crt",
+		EnvTLSClientAuth: "require",
+	}
+
 	tests := []struct {
 		name    string
 		envVars map[string]string
+		extra   map[string]string
 		wantErr error
 	}{
 		{
-			name: "OIDC mode without issuer URL",
+			name:    "CRL without TLS",
+			extra:   map[string]string{EnvTLSCRLPath: "/certs/ca.crl"},
+			wantErr: ErrInvalidTLSRevocation,
+		},
+		{
+			name: "OCSP without required client certificates",
 			envVars: map[string]string{
-				EnvAuthMode:     "oidc",
-				EnvOIDCClientID: "my-client",
+				EnvTLSEnabled:  "true",
+				EnvTLSCertPath: "/certs/tls.crt",
+				EnvTLSKeyPath:  "/certs/tls.key",
 			},
-			wantErr: ErrInvalidOIDCConfig,
+			extra:   map[string]string{EnvTLSOCSPEnabled: "true"},
+			wantErr: ErrInvalidTLSRevocation,
 		},
 		{
-			name: "OIDC mode without client ID",
+			name:    "negative refresh interval",
+			envVars: mtls,
+			extra:   map[string]string{EnvTLSCRLPath: "/certs/ca.crl", EnvTLSCRLRefresh: "-1m"},
+			wantErr: ErrInvalidTLSCRLRefresh,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			for k, v := range tt.envVars {
+				t.Setenv(k, v)
+			}
+			for k, v := range tt.extra {
+				t.Setenv(k, v)
+			}
+
+			// Act
+			_, err := Load()
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Load() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadRevocationConfigParseErrors(t *testing.T) {
+	for _, env := range []string{EnvTLSCRLRefresh, EnvTLSOCSPEnabled, EnvTLSOCSPStrict} {
+		t.Run(env, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			t.Setenv(env, "invalid")
+
+			// Act
+			_, err := Load()
+
+			// Assert
+			if err == nil || !strings.Contains(err.Error(), env) {
+				t.Errorf("Load() error = %v, want parse error for %s", err, env)
+			}
+		})
+	}
+}
+
+func TestLoadMTLSConfig(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvAuthMode, "mtls")
+	t.Setenv(EnvMTLSSubject, "spiffe")
+	t.Setenv(EnvMTLSDomains, "example.org,prod.example.org")
+	t.Setenv(EnvMTLSAllowedOUs, "payments")
+	t.Setenv(EnvMTLSAllowedSANs, "spiffe://example.org/ns/*/sa/*")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.MTLSSubject != "spiffe" {
+		t.Errorf("MTLSSubject = %s, want spiffe", cfg.MTLSSubject)
+	}
+	if cfg.MTLSTrustDomains != "example.org,prod.example.org" {
+		t.Errorf("MTLSTrustDomains = %s, want example.org,prod.example.org", cfg.MTLSTrustDomains)
+	}
+	if cfg.MTLSAllowedOUs != "payments" {
+		t.Errorf("MTLSAllowedOUs = %s, want payments", cfg.MTLSAllowedOUs)
+	}
+	if cfg.MTLSAllowedSANs != "spiffe://example.org/ns/*/sa/*" {
+		t.Errorf("MTLSAllowedSANs = %s, want spiffe://example.org/ns/*/sa/*", cfg.MTLSAllowedSANs)
+	}
+}
+
+func TestLoadMTLSConfigInvalidSubject(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvMTLSSubject, "serial")
+
+	// Act
+	_, err := Load()
+
+	// Assert
+	if !errors.Is(err, ErrInvalidMTLSSubject) {
+		t.Errorf("Load() error = %v, want %v", err, ErrInvalidMTLSSubject)
+	}
+}
+
+func TestLoadOIDCAlgorithms(t *testing.T) {
+	tests := []struct {
+		name       string
+		algorithms string
+		wantErr    error
+	}{
+		{name: "asymmetric algorithms", algorithms: "ES256, EdDSA,PS512"},
+		{name: "symmetric algorithm", algorithms: "RS256,HS256", wantErr: ErrInvalidOIDCAlgorithms},
+		{name: "none", algorithms: "none", wantErr: ErrInvalidOIDCAlgorithms},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			t.Setenv(EnvOIDCAlgorithms, tt.algorithms)
+
+			// Act
+			cfg, err := Load()
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Load() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.OIDCAlgorithms != tt.algorithms {
+				t.Errorf("OIDCAlgorithms = %s, want %s", cfg.OIDCAlgorithms, tt.algorithms)
+			}
+		})
+	}
+}
+
+func TestLoadOIDCIssuers(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvAuthMode, "oidc")
+	t.Setenv(EnvOIDCIssuerURL, "https://corp.example.com")
+	t.Setenv(EnvOIDCClientID, "restapi")
+	t.Setenv(EnvOIDCAudience, "corp-api")
+	t.Setenv(EnvOIDCClockSkew, "30s")
+	t.Setenv(EnvOIDCClaims, "email_verified=true")
+	t.Setenv("APP_OIDC_ISSUER_1_URL", "https://partner.example.com")
+	t.Setenv("APP_OIDC_ISSUER_1_AUDIENCE", "partner-api")
+	t.Setenv("APP_OIDC_ISSUER_1_ALGORITHMS", "ES256")
+	t.Setenv("APP_OIDC_ISSUER_1_CLOCK_SKEW", "2m")
+	t.Setenv("APP_OIDC_ISSUER_1_REQUIRED_CLAIMS", "tenant=acme,groups")
+	t.Setenv("APP_OIDC_ISSUER_2_URL", "https://other.example.com")
+	// Not loaded: issuer 3 is missing.
+	t.Setenv("APP_OIDC_ISSUER_4_URL", "https://ignored.example.com")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	want := []OIDCIssuer{
+		{
+			URL:            "https://corp.example.com",
+			Audience:       "corp-api",
+			ClockSkew:      30 * time.Second,
+			RequiredClaims: "email_verified=true",
+		},
+		{
+			URL:            "https://partner.example.com",
+			Audience:       "partner-api",
+			Algorithms:     "ES256",
+			ClockSkew:      2 * time.Minute,
+			RequiredClaims: "tenant=acme,groups",
+		},
+		{
+			URL: "https://other.example.com",
+		},
+	}
+	if got := cfg.OIDCIssuerList(); !reflect.DeepEqual(got, want) {
+		t.Errorf("OIDCIssuerList() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadOIDCIssuersWithoutPrimary(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvAuthMode, "oidc")
+	t.Setenv(EnvOIDCClientID, "restapi")
+	t.Setenv("APP_OIDC_ISSUER_1_URL", "https://partner.example.com")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if got := cfg.OIDCIssuerList(); len(got) != 1 || got[0].URL != "https://partner.example.com" {
+		t.Errorf("OIDCIssuerList() = %+v, want only the partner issuer", got)
+	}
+}
+
+func TestLoadOIDCIssuersErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		wantErr error
+	}{
+		{
+			name: "duplicate issuer",
 			envVars: map[string]string{
-				EnvAuthMode:      "oidc",
-				EnvOIDCIssuerURL: "https://issuer.example.com",
+				EnvOIDCIssuerURL:        "https://corp.example.com",
+				"APP_OIDC_ISSUER_1_URL": "https://corp.example.com",
 			},
-			wantErr: ErrInvalidOIDCConfig,
+			wantErr: ErrInvalidOIDCIssuers,
 		},
 		{
-			name: "OIDC mode without both issuer and client",
+			name:    "negative clock skew",
+			envVars: map[string]string{EnvOIDCClockSkew: "-1s"},
+			wantErr: ErrInvalidOIDCClockSkew,
+		},
+		{
+			name: "negative issuer clock skew",
 			envVars: map[string]string{
-				EnvAuthMode: "oidc",
+				"APP_OIDC_ISSUER_1_URL":        "https://partner.example.com",
+				"APP_OIDC_ISSUER_1_CLOCK_SKEW": "-1s",
+			},
+			wantErr: ErrInvalidOIDCClockSkew,
+		},
+		{
+			name:    "required claim without name",
+			envVars: map[string]string{EnvOIDCClaims: "tenant=acme,=value"},
+			wantErr: ErrInvalidOIDCClaims,
+		},
+		{
+			name: "unsupported issuer algorithm",
+			envVars: map[string]string{
+				"APP_OIDC_ISSUER_1_URL":        "https://partner.example.com",
+				"APP_OIDC_ISSUER_1_ALGORITHMS": "HS256",
 			},
+			wantErr: ErrInvalidOIDCAlgorithms,
+		},
+		{
+			name:    "oidc mode without any issuer",
+			envVars: map[string]string{EnvAuthMode: "oidc", EnvOIDCClientID: "restapi"},
 			wantErr: ErrInvalidOIDCConfig,
 		},
 	}
@@ -944,34 +1347,1289 @@ func TestLoadOIDCConfig(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Arrange
 			clearEnvVars(t)
-			for key, value := range tt.envVars {
-				t.Setenv(key, value)
+			for k, v := range tt.envVars {
+				t.Setenv(k, v)
 			}
 
 			// Act
-			cfg, err := Load()
+			_, err := Load()
 
 			// Assert
-			if err == nil {
-				t.Fatal("Load() expected error, got nil")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Load() error = %v, want %v", err, tt.wantErr)
 			}
-			if cfg != nil {
-				t.Errorf("Load() expected nil config on error, got %+v", cfg)
+		})
+	}
+}
+
+func TestLoadOIDCIssuersParseErrors(t *testing.T) {
+	for _, env := range []string{EnvOIDCClockSkew, "APP_OIDC_ISSUER_1_CLOCK_SKEW"} {
+		t.Run(env, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			t.Setenv("APP_OIDC_ISSUER_1_URL", "https://partner.example.com")
+			t.Setenv(env, "invalid")
+
+			// Act
+			_, err := Load()
+
+			// Assert
+			if err == nil {
+				t.Fatal("Load() error = nil, want parse error")
 			}
-			if !containsError(err, tt.wantErr) {
-				t.Errorf("Load() error = %v, want error containing %v", err, tt.wantErr)
+			if !strings.Contains(err.Error(), env) {
+				t.Errorf("Load() error = %v, want it to name %s", err, env)
 			}
 		})
 	}
 }
 
-func TestLoadOIDCConfigValid(t *testing.T) {
+func TestLoadIntrospectionConfig(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvAuthMode, "introspection")
+	t.Setenv(EnvIntroURL, "https://idp.example.com/introspect")
+	t.Setenv(EnvIntroClientID, "restapi")
+	t.Setenv(EnvIntroSecret, "secret")
+	t.Setenv(EnvIntroCacheTTL, "2m")
+	t.Setenv(EnvIntroNegTTL, "0s")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.IntrospectionURL != "https://idp.example.com/introspect" {
+		t.Errorf("IntrospectionURL = %s, want https://idp.example.com/introspect", cfg.IntrospectionURL)
+	}
+	if cfg.IntrospectionClientID != "restapi" || cfg.IntrospectionClientSecret != "secret" {
+		t.Errorf("introspection client = %s:%s, want restapi:secret",
+			cfg.IntrospectionClientID, cfg.IntrospectionClientSecret)
+	}
+	if cfg.IntrospectionCacheTTL != 2*time.Minute {
+		t.Errorf("IntrospectionCacheTTL = %v, want 2m", cfg.IntrospectionCacheTTL)
+	}
+	if cfg.IntrospectionNegativeTTL != 0 {
+		t.Errorf("IntrospectionNegativeTTL = %v, want 0", cfg.IntrospectionNegativeTTL)
+	}
+}
+
+func TestLoadIntrospectionConfigDefaults(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.IntrospectionCacheTTL != DefaultIntrospectCacheTTL {
+		t.Errorf("IntrospectionCacheTTL = %v, want %v", cfg.IntrospectionCacheTTL, DefaultIntrospectCacheTTL)
+	}
+	if cfg.IntrospectionNegativeTTL != DefaultIntrospectNegTTL {
+		t.Errorf("IntrospectionNegativeTTL = %v, want %v", cfg.IntrospectionNegativeTTL, DefaultIntrospectNegTTL)
+	}
+}
+
+func TestLoadIntrospectionConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		wantErr error
+	}{
+		{
+			name:    "introspection mode without URL",
+			envVars: map[string]string{EnvAuthMode: "introspection", EnvIntroClientID: "restapi"},
+			wantErr: ErrInvalidIntrospection,
+		},
+		{
+			name: "introspection mode without client ID",
+			envVars: map[string]string{
+				EnvAuthMode: "introspection",
+				EnvIntroURL: "https://idp.example.com/introspect",
+			},
+			wantErr: ErrInvalidIntrospection,
+		},
+		{
+			name:    "negative cache TTL",
+			envVars: map[string]string{EnvIntroCacheTTL: "-1m"},
+			wantErr: ErrInvalidIntrospectTTL,
+		},
+		{
+			name:    "negative negative-cache TTL",
+			envVars: map[string]string{EnvIntroNegTTL: "-1s"},
+			wantErr: ErrInvalidIntrospectTTL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			for k, v := range tt.envVars {
+				t.Setenv(k, v)
+			}
+
+			// Act
+			_, err := Load()
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Load() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadIntrospectionConfigParseErrors(t *testing.T) {
+	for _, env := range []string{EnvIntroCacheTTL, EnvIntroNegTTL} {
+		t.Run(env, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			t.Setenv(env, "invalid")
+
+			// Act
+			_, err := Load()
+
+			// Assert
+			if err == nil || !strings.Contains(err.Error(), env) {
+				t.Errorf("Load() error = %v, want parse error for %s", err, env)
+			}
+		})
+	}
+}
+
+func TestLoadOIDCConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		wantErr error
+	}{
+		{
+			name: "OIDC mode without issuer URL",
+			envVars: map[string]string{
+				EnvAuthMode:     "oidc",
+				EnvOIDCClientID: "my-client",
+			},
+			wantErr: ErrInvalidOIDCConfig,
+		},
+		{
+			name: "OIDC mode without client ID",
+			envVars: map[string]string{
+				EnvAuthMode:      "oidc",
+				EnvOIDCIssuerURL: "https://issuer.example.com",
+			},
+			wantErr: ErrInvalidOIDCConfig,
+		},
+		{
+			name: "OIDC mode without both issuer and client",
+			envVars: map[string]string{
+				EnvAuthMode: "oidc",
+			},
+			wantErr: ErrInvalidOIDCConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			for key, value := range tt.envVars {
+				t.Setenv(key, value)
+			}
+
+			// Act
+			cfg, err := Load()
+
+			// Assert
+			if err == nil {
+				t.Fatal("Load() expected error, got nil")
+			}
+			if cfg != nil {
+				t.Errorf("Load() expected nil config on error, got %+v", cfg)
+			}
+			if !containsError(err, tt.wantErr) {
+				t.Errorf("Load() error = %v, want error containing %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadOIDCConfigValid(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvAuthMode, "oidc")
+	t.Setenv(EnvOIDCIssuerURL, "https://issuer.example.com")
+	t.Setenv(EnvOIDCClientID, "my-client")
+	t.Setenv(EnvOIDCAudience, "my-audience")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.OIDCIssuerURL != "https://issuer.example.com" {
+		t.Errorf("OIDCIssuerURL = %s, want https://issuer.example.com", cfg.OIDCIssuerURL)
+	}
+	if cfg.OIDCClientID != "my-client" {
+		t.Errorf("OIDCClientID = %s, want my-client", cfg.OIDCClientID)
+	}
+	if cfg.OIDCAudience != "my-audience" {
+		t.Errorf("OIDCAudience = %s, want my-audience", cfg.OIDCAudience)
+	}
+}
+
+func TestLoadBasicAuthConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		wantErr error
+	}{
+		{
+			name: "basic mode without users config",
+			envVars: map[string]string{
+				EnvAuthMode: "basic",
+			},
+			wantErr: ErrInvalidBasicAuthConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			for key, value := range tt.envVars {
+				t.Setenv(key, value)
+			}
+
+			// Act
+			cfg, err := Load()
+
+			// Assert
+			if err == nil {
+				t.Fatal("Load() expected error, got nil")
+			}
+			if cfg != nil {
+				t.Errorf("Load() expected nil config on error, got %+v", cfg)
+			}
+			if !containsError(err, tt.wantErr) {
+				t.Errorf("Load() error = %v, want error containing %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadBasicAuthConfigValid(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvAuthMode, "basic")
+	t.Setenv(EnvBasicAuthUsers, "user1:$2a$04$hash1,user2:$2a$04$hash2")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.BasicAuthUsers != "user1:$2a$04$hash1,user2:$2a$04$hash2" {
+		t.Errorf("BasicAuthUsers = %s, want user1:$2a$04$hash1,user2:$2a$04$hash2",
+			cfg.BasicAuthUsers)
+	}
+}
+
+func TestLoadBasicAuthFileConfig(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvAuthMode, "basic")
+	t.Setenv(EnvBasicAuthFile, "/etc/restapi/htpasswd")
+	t.Setenv(EnvBasicAuthReload, "30s")
+	t.Setenv(EnvBasicAuthCache, "0s")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.BasicAuthFile != "/etc/restapi/htpasswd" {
+		t.Errorf("BasicAuthFile = %s, want /etc/restapi/htpasswd", cfg.BasicAuthFile)
+	}
+	if cfg.BasicAuthReload != 30*time.Second {
+		t.Errorf("BasicAuthReload = %v, want 30s", cfg.BasicAuthReload)
+	}
+	if cfg.BasicAuthCacheTTL != 0 {
+		t.Errorf("BasicAuthCacheTTL = %v, want 0", cfg.BasicAuthCacheTTL)
+	}
+}
+
+func TestLoadBasicAuthConfigDefaults(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.BasicAuthReload != DefaultBasicAuthReload {
+		t.Errorf("BasicAuthReload = %v, want %v", cfg.BasicAuthReload, DefaultBasicAuthReload)
+	}
+	if cfg.BasicAuthCacheTTL != DefaultBasicAuthCacheTTL {
+		t.Errorf("BasicAuthCacheTTL = %v, want %v", cfg.BasicAuthCacheTTL, DefaultBasicAuthCacheTTL)
+	}
+}
+
+func TestLoadBasicAuthConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		wantErr error
+	}{
+		{
+			name:    "basic mode without users",
+			envVars: map[string]string{EnvAuthMode: "basic"},
+			wantErr: ErrInvalidBasicAuthConfig,
+		},
+		{
+			name: "users and users file",
+			envVars: map[string]string{
+				EnvBasicAuthUsers: "user1:$2a$04$hash1",
+				EnvBasicAuthFile:  "/etc/restapi/htpasswd",
+			},
+			wantErr: ErrInvalidBasicAuthFile,
+		},
+		{
+			name:    "negative reload interval",
+			envVars: map[string]string{EnvBasicAuthReload: "-1s"},
+			wantErr: ErrInvalidBasicAuthTTL,
+		},
+		{
+			name:    "negative cache TTL",
+			envVars: map[string]string{EnvBasicAuthCache: "-1m"},
+			wantErr: ErrInvalidBasicAuthTTL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			for k, v := range tt.envVars {
+				t.Setenv(k, v)
+			}
+
+			// Act
+			_, err := Load()
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Load() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadBasicAuthConfigParseErrors(t *testing.T) {
+	for _, env := range []string{EnvBasicAuthReload, EnvBasicAuthCache} {
+		t.Run(env, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			t.Setenv(env, "invalid")
+
+			// Act
+			_, err := Load()
+
+			// Assert
+			if err == nil || !strings.Contains(err.Error(), env) {
+				t.Errorf("Load() error = %v, want parse error for %s", err, env)
+			}
+		})
+	}
+}
+
+func TestLoadLockoutConfig(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvLockoutFailures, "3")
+	t.Setenv(EnvLockoutIPFails, "0")
+	t.Setenv(EnvLockoutBase, "1m")
+	t.Setenv(EnvLockoutMaxDelay, "1h")
+	t.Setenv(EnvLockoutReset, "30m")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.LockoutMaxFailures != 3 {
+		t.Errorf("LockoutMaxFailures = %d, want 3", cfg.LockoutMaxFailures)
+	}
+	if cfg.LockoutMaxClientFailures != 0 {
+		t.Errorf("LockoutMaxClientFailures = %d, want 0", cfg.LockoutMaxClientFailures)
+	}
+	if cfg.LockoutBaseDelay != time.Minute {
+		t.Errorf("LockoutBaseDelay = %v, want 1m", cfg.LockoutBaseDelay)
+	}
+	if cfg.LockoutMaxDelay != time.Hour {
+		t.Errorf("LockoutMaxDelay = %v, want 1h", cfg.LockoutMaxDelay)
+	}
+	if cfg.LockoutResetAfter != 30*time.Minute {
+		t.Errorf("LockoutResetAfter = %v, want 30m", cfg.LockoutResetAfter)
+	}
+	if !cfg.LockoutEnabled() {
+		t.Error("LockoutEnabled() = false, want true")
+	}
+}
+
+func TestLoadLockoutConfigDefaults(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.LockoutMaxFailures != DefaultLockoutFailures {
+		t.Errorf("LockoutMaxFailures = %d, want %d", cfg.LockoutMaxFailures, DefaultLockoutFailures)
+	}
+	if cfg.LockoutMaxClientFailures != DefaultLockoutIPFailures {
+		t.Errorf("LockoutMaxClientFailures = %d, want %d", cfg.LockoutMaxClientFailures, DefaultLockoutIPFailures)
+	}
+	if cfg.LockoutBaseDelay != DefaultLockoutBaseDelay {
+		t.Errorf("LockoutBaseDelay = %v, want %v", cfg.LockoutBaseDelay, DefaultLockoutBaseDelay)
+	}
+	if cfg.LockoutMaxDelay != DefaultLockoutMaxDelay {
+		t.Errorf("LockoutMaxDelay = %v, want %v", cfg.LockoutMaxDelay, DefaultLockoutMaxDelay)
+	}
+	if cfg.LockoutResetAfter != DefaultLockoutResetAfter {
+		t.Errorf("LockoutResetAfter = %v, want %v", cfg.LockoutResetAfter, DefaultLockoutResetAfter)
+	}
+}
+
+func TestLoadLockoutConfigDisabled(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvLockoutFailures, "0")
+	t.Setenv(EnvLockoutIPFails, "0")
+	t.Setenv(EnvLockoutBase, "0s")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.LockoutEnabled() {
+		t.Error("LockoutEnabled() = true, want false")
+	}
+}
+
+func TestLoadLockoutConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+	}{
+		{name: "negative max failures", envVars: map[string]string{EnvLockoutFailures: "-1"}},
+		{name: "negative max client failures", envVars: map[string]string{EnvLockoutIPFails: "-1"}},
+		{name: "zero base delay", envVars: map[string]string{EnvLockoutBase: "0s"}},
+		{name: "max delay below base delay", envVars: map[string]string{EnvLockoutMaxDelay: "10s"}},
+		{name: "zero reset after", envVars: map[string]string{EnvLockoutReset: "0s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			for k, v := range tt.envVars {
+				t.Setenv(k, v)
+			}
+
+			// Act
+			_, err := Load()
+
+			// Assert
+			if !errors.Is(err, ErrInvalidLockout) {
+				t.Errorf("Load() error = %v, want %v", err, ErrInvalidLockout)
+			}
+		})
+	}
+}
+
+func TestLoadLockoutConfigParseErrors(t *testing.T) {
+	envs := []string{EnvLockoutFailures, EnvLockoutIPFails, EnvLockoutBase, EnvLockoutMaxDelay, EnvLockoutReset}
+	for _, env := range envs {
+		t.Run(env, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			t.Setenv(env, "invalid")
+
+			// Act
+			_, err := Load()
+
+			// Assert
+			if err == nil || !strings.Contains(err.Error(), env) {
+				t.Errorf("Load() error = %v, want parse error for %s", err, env)
+			}
+		})
+	}
+}
+
+func TestLoadAPIKeyConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		wantErr error
+	}{
+		{
+			name: "apikey mode without keys config",
+			envVars: map[string]string{
+				EnvAuthMode: "apikey",
+			},
+			wantErr: ErrInvalidAPIKeyConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			for key, value := range tt.envVars {
+				t.Setenv(key, value)
+			}
+
+			// Act
+			cfg, err := Load()
+
+			// Assert
+			if err == nil {
+				t.Fatal("Load() expected error, got nil")
+			}
+			if cfg != nil {
+				t.Errorf("Load() expected nil config on error, got %+v", cfg)
+			}
+			if !containsError(err, tt.wantErr) {
+				t.Errorf("Load() error = %v, want error containing %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadAPIKeyConfigValid(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvAuthMode, "apikey")
+	t.Setenv(EnvAPIKeys, "key1:service1,key2:service2")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.APIKeys != "key1:service1,key2:service2" {
+		t.Errorf("APIKeys = %s, want key1:service1,key2:service2", cfg.APIKeys)
+	}
+}
+
+func TestLoadAPIKeyFileConfig(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvAuthMode, "apikey")
+	t.Setenv(EnvAPIKeyFile, "/data/api-keys.json")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.APIKeyFile != "/data/api-keys.json" {
+		t.Errorf("APIKeyFile = %s, want /data/api-keys.json", cfg.APIKeyFile)
+	}
+}
+
+func TestLoadMultiAuthConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		wantErr error
+	}{
+		{
+			name: "multi mode without any auth config",
+			envVars: map[string]string{
+				EnvAuthMode: "multi",
+			},
+			wantErr: ErrInvalidMultiAuthConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			for key, value := range tt.envVars {
+				t.Setenv(key, value)
+			}
+
+			// Act
+			cfg, err := Load()
+
+			// Assert
+			if err == nil {
+				t.Fatal("Load() expected error, got nil")
+			}
+			if cfg != nil {
+				t.Errorf("Load() expected nil config on error, got %+v", cfg)
+			}
+			if !containsError(err, tt.wantErr) {
+				t.Errorf("Load() error = %v, want error containing %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadMultiAuthConfigValid(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+	}{
+		{
+			name: "multi mode with basic auth users",
+			envVars: map[string]string{
+				EnvAuthMode:       "multi",
+				EnvBasicAuthUsers: "user1:hash1",
+			},
+		},
+		{
+			name: "multi mode with API keys",
+			envVars: map[string]string{
+				EnvAuthMode: "multi",
+				EnvAPIKeys:  "key1:name1",
+			},
+		},
+		{
+			name: "multi mode with OIDC issuer URL",
+			envVars: map[string]string{
+				EnvAuthMode:      "multi",
+				EnvOIDCIssuerURL: "https://issuer.example.com",
+			},
+		},
+		{
+			name: "multi mode with OIDC client ID",
+			envVars: map[string]string{
+				EnvAuthMode:     "multi",
+				EnvOIDCClientID: "my-client",
+			},
+		},
+		{
+			name: "multi mode with TLS enabled",
+			envVars: map[string]string{
+				EnvAuthMode:    "multi",
+				EnvTLSEnabled:  "true",
+				EnvTLSCertPath: "/path/to/cert.pem",
+				EnvTLSKeyPath:  "/path/to/key.pem",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			for key, value := range tt.envVars {
+				t.Setenv(key, value)
+			}
+
+			// Act
+			cfg, err := Load()
+
+			// Assert
+			if err != nil {
+				t.Fatalf("Load() returned unexpected error: %v", err)
+			}
+			if cfg.AuthMode != "multi" {
+				t.Errorf("AuthMode = %s, want multi", cfg.AuthMode)
+			}
+		})
+	}
+}
+
+func TestLoadVaultConfig(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvVaultEnabled, "true")
+	t.Setenv(EnvVaultAddr, "https://vault.example.com:8200")
+	t.Setenv(EnvVaultToken, "s.mytoken123")
+	t.Setenv(EnvVaultPKIPath, "pki/issue/my-role")
+	t.Setenv(EnvVaultPKIRole, "my-role")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if !cfg.VaultEnabled {
+		t.Error("VaultEnabled should be true")
+	}
+	if cfg.VaultAddr != "https://vault.example.com:8200" {
+		t.Errorf("VaultAddr = %s, want https://vault.example.com:8200", cfg.VaultAddr)
+	}
+	if cfg.VaultToken != "s.mytoken123" {
+		t.Errorf("VaultToken = %s, want s.mytoken123", cfg.VaultToken)
+	}
+	if cfg.VaultPKIPath != "pki/issue/my-role" {
+		t.Errorf("VaultPKIPath = %s, want pki/issue/my-role", cfg.VaultPKIPath)
+	}
+	if cfg.VaultPKIRole != "my-role" {
+		t.Errorf("VaultPKIRole = %s, want my-role", cfg.VaultPKIRole)
+	}
+}
+
+func TestLoadVaultConfigParseError(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvVaultEnabled, "notabool")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err == nil {
+		t.Fatal("Load() expected error, got nil")
+	}
+	if cfg != nil {
+		t.Errorf("Load() expected nil config on error, got %+v", cfg)
+	}
+}
+
+func TestLoadVaultPKIConfig(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvTLSEnabled, "true")
+	t.Setenv(EnvVaultEnabled, "true")
+	t.Setenv(EnvVaultAddr, "https://vault.example.com:8200")
+	t.Setenv(EnvVaultToken, "s.mytoken123")
+	t.Setenv(EnvVaultPKIPath, "pki")
+	t.Setenv(EnvVaultPKIRole, "web")
+	t.Setenv(EnvVaultCommonName, "api.example.com")
+	t.Setenv(EnvVaultAltNames, "api.example.com,localhost")
+	t.Setenv(EnvVaultIPSANs, "127.0.0.1")
+	t.Setenv(EnvVaultPKITTL, "72h")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.VaultPKICommonName != "api.example.com" {
+		t.Errorf("VaultPKICommonName = %s, want api.example.com", cfg.VaultPKICommonName)
+	}
+	if cfg.VaultPKIAltNames != "api.example.com,localhost" {
+		t.Errorf("VaultPKIAltNames = %s, want api.example.com,localhost", cfg.VaultPKIAltNames)
+	}
+	if cfg.VaultPKIIPSANs != "127.0.0.1" {
+		t.Errorf("VaultPKIIPSANs = %s, want 127.0.0.1", cfg.VaultPKIIPSANs)
+	}
+	if cfg.VaultPKITTL != 72*time.Hour {
+		t.Errorf("VaultPKITTL = %v, want 72h", cfg.VaultPKITTL)
+	}
+}
+
+func TestLoadVaultPKIConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		wantErr error
+	}{
+		{
+			name: "missing role",
+			envVars: map[string]string{
+				EnvVaultEnabled: "true",
+				EnvVaultAddr:    "https://vault:8200",
+				EnvVaultToken:   "s.token",
+				EnvVaultPKIPath: "pki",
+			},
+			wantErr: ErrInvalidVaultConfig,
+		},
+		{
+			name: "negative TTL",
+			envVars: map[string]string{
+				EnvVaultEnabled: "true",
+				EnvVaultAddr:    "https://vault:8200",
+				EnvVaultToken:   "s.token",
+				EnvVaultPKIPath: "pki",
+				EnvVaultPKIRole: "web",
+				EnvVaultPKITTL:  "-1h",
+			},
+			wantErr: ErrInvalidVaultTTL,
+		},
+		{
+			name: "TLS without cert paths and Vault disabled",
+			envVars: map[string]string{
+				EnvTLSEnabled: "true",
+			},
+			wantErr: ErrInvalidTLSCertRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			for k, v := range tt.envVars {
+				t.Setenv(k, v)
+			}
+
+			// Act
+			_, err := Load()
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Load() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadVaultPKITTLParseError(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvVaultPKITTL, "three days")
+
+	// Act
+	_, err := Load()
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), EnvVaultPKITTL) {
+		t.Errorf("Load() error = %v, want parse error for %s", err, EnvVaultPKITTL)
+	}
+}
+
+func TestLoadAuthzConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		authMode string
+		wantErr  error
+	}{
+		{name: "with auth mode", authMode: "apikey"},
+		{name: "without auth", authMode: "none", wantErr: ErrInvalidAuthzConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			t.Setenv(EnvAuthMode, tt.authMode)
+			t.Setenv(EnvAPIKeys, "key1:service1")
+			t.Setenv(EnvAuthzPolicyFile, "/etc/restapi/policy.json")
+
+			// Act
+			cfg, err := Load()
+
+			// Assert
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() returned unexpected error: %v", err)
+			}
+			if cfg.AuthzPolicyFile != "/etc/restapi/policy.json" {
+				t.Errorf("AuthzPolicyFile = %s, want /etc/restapi/policy.json", cfg.AuthzPolicyFile)
+			}
+		})
+	}
+}
+
+func TestLoadStoreConfig(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvStoreDriver, "postgres")
+	t.Setenv(EnvStoreDSN, "postgres://app:secret@db:5432/items?sslmode=disable")
+	t.Setenv(EnvStoreMaxOpen, "20")
+	t.Setenv(EnvStoreMaxIdle, "4")
+	t.Setenv(EnvStoreConnMaxAge, "5m")
+	t.Setenv(EnvStoreIDPattern, "sku-[0-9]+")
+	t.Setenv(EnvStoreTimeout, "2m")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.StoreDriver != "postgres" {
+		t.Errorf("StoreDriver = %s, want postgres", cfg.StoreDriver)
+	}
+	if cfg.StoreDSN != "postgres://app:secret@db:5432/items?sslmode=disable" {
+		t.Errorf("StoreDSN = %s, want configured DSN", cfg.StoreDSN)
+	}
+	if cfg.StoreMaxOpenConns != 20 {
+		t.Errorf("StoreMaxOpenConns = %d, want 20", cfg.StoreMaxOpenConns)
+	}
+	if cfg.StoreMaxIdleConns != 4 {
+		t.Errorf("StoreMaxIdleConns = %d, want 4", cfg.StoreMaxIdleConns)
+	}
+	if cfg.StoreConnMaxLifetime != 5*time.Minute {
+		t.Errorf("StoreConnMaxLifetime = %v, want 5m", cfg.StoreConnMaxLifetime)
+	}
+	if cfg.StoreIDPattern != "sku-[0-9]+" {
+		t.Errorf("StoreIDPattern = %s, want sku-[0-9]+", cfg.StoreIDPattern)
+	}
+	if cfg.StoreConnectTimeout != 2*time.Minute {
+		t.Errorf("StoreConnectTimeout = %v, want 2m", cfg.StoreConnectTimeout)
+	}
+}
+
+func TestLoadFileStoreConfig(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvStoreDriver, "file")
+	t.Setenv(EnvStorePath, "/data/items.db")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.StoreDriver != "file" {
+		t.Errorf("StoreDriver = %s, want file", cfg.StoreDriver)
+	}
+	if cfg.StorePath != "/data/items.db" {
+		t.Errorf("StorePath = %s, want /data/items.db", cfg.StorePath)
+	}
+}
+
+func TestLoadStoreConfigDefaults(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.StoreDriver != DefaultStoreDriver {
+		t.Errorf("StoreDriver = %s, want %s", cfg.StoreDriver, DefaultStoreDriver)
+	}
+	if cfg.StoreMaxOpenConns != DefaultStoreMaxOpen {
+		t.Errorf("StoreMaxOpenConns = %d, want %d", cfg.StoreMaxOpenConns, DefaultStoreMaxOpen)
+	}
+	if cfg.StoreConnectTimeout != DefaultStoreTimeout {
+		t.Errorf("StoreConnectTimeout = %v, want %v", cfg.StoreConnectTimeout, DefaultStoreTimeout)
+	}
+}
+
+func TestLoadStoreConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		wantErr error
+	}{
+		{
+			name:    "unknown driver",
+			envVars: map[string]string{EnvStoreDriver: "cassandra"},
+			wantErr: ErrInvalidStoreDriver,
+		},
+		{
+			name:    "postgres without DSN",
+			envVars: map[string]string{EnvStoreDriver: "postgres"},
+			wantErr: ErrInvalidStoreDSN,
+		},
+		{
+			name:    "file without path",
+			envVars: map[string]string{EnvStoreDriver: "file"},
+			wantErr: ErrInvalidStorePath,
+		},
+		{
+			name: "negative pool size",
+			envVars: map[string]string{
+				EnvStoreMaxOpen: "-1",
+			},
+			wantErr: ErrInvalidStorePool,
+		},
+		{
+			name:    "invalid ID pattern",
+			envVars: map[string]string{EnvStoreIDPattern: "sku-("},
+			wantErr: ErrInvalidStoreIDPattern,
+		},
+		{
+			name:    "negative connect timeout",
+			envVars: map[string]string{EnvStoreTimeout: "-1s"},
+			wantErr: ErrInvalidStoreTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			for k, v := range tt.envVars {
+				t.Setenv(k, v)
+			}
+
+			// Act
+			_, err := Load()
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Load() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadStoreConfigParseErrors(t *testing.T) {
+	envVars := []string{EnvStoreMaxOpen, EnvStoreMaxIdle, EnvStoreConnMaxAge, EnvStoreTimeout}
+
+	for _, env := range envVars {
+		t.Run(env, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			t.Setenv(env, "invalid")
+
+			// Act
+			cfg, err := Load()
+
+			// Assert
+			if err == nil {
+				t.Fatal("Load() expected error, got nil")
+			}
+			if cfg != nil {
+				t.Errorf("Load() expected nil config on error, got %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestLoadRateLimitConfig(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvRateLimit, "true")
+	t.Setenv(EnvRateLimitStore, "redis")
+	t.Setenv(EnvRateLimitRedis, "redis:6379")
+	t.Setenv(EnvRateLimitPass, "secret")
+	t.Setenv(EnvRateLimitDB, "3")
+	t.Setenv(EnvRateLimitRead, "100/s:200")
+	t.Setenv(EnvRateLimitWrite, "10/1m")
+	t.Setenv(EnvRateLimitGQL, "0")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if !cfg.RateLimitEnabled {
+		t.Error("RateLimitEnabled = false, want true")
+	}
+	if cfg.RateLimitBackend != "redis" {
+		t.Errorf("RateLimitBackend = %q, want %q", cfg.RateLimitBackend, "redis")
+	}
+	if cfg.RateLimitRedisAddr != "redis:6379" {
+		t.Errorf("RateLimitRedisAddr = %q, want %q", cfg.RateLimitRedisAddr, "redis:6379")
+	}
+	if cfg.RateLimitRedisPassword != "secret" {
+		t.Errorf("RateLimitRedisPassword = %q, want %q", cfg.RateLimitRedisPassword, "secret")
+	}
+	if cfg.RateLimitRedisDB != 3 {
+		t.Errorf("RateLimitRedisDB = %d, want 3", cfg.RateLimitRedisDB)
+	}
+	if cfg.RateLimitRead != "100/s:200" {
+		t.Errorf("RateLimitRead = %q, want %q", cfg.RateLimitRead, "100/s:200")
+	}
+	if cfg.RateLimitWrite != "10/1m" {
+		t.Errorf("RateLimitWrite = %q, want %q", cfg.RateLimitWrite, "10/1m")
+	}
+	if cfg.RateLimitGraphQL != "0" {
+		t.Errorf("RateLimitGraphQL = %q, want %q", cfg.RateLimitGraphQL, "0")
+	}
+}
+
+func TestLoadRateLimitConfigDefaults(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.RateLimitEnabled {
+		t.Error("RateLimitEnabled = true, want false")
+	}
+	if cfg.RateLimitBackend != DefaultRateLimitBackend {
+		t.Errorf("RateLimitBackend = %q, want %q", cfg.RateLimitBackend, DefaultRateLimitBackend)
+	}
+	if cfg.RateLimitRead != DefaultRateLimitRead {
+		t.Errorf("RateLimitRead = %q, want %q", cfg.RateLimitRead, DefaultRateLimitRead)
+	}
+	if cfg.RateLimitWrite != DefaultRateLimitWrite {
+		t.Errorf("RateLimitWrite = %q, want %q", cfg.RateLimitWrite, DefaultRateLimitWrite)
+	}
+	if cfg.RateLimitGraphQL != DefaultRateLimitGraphQL {
+		t.Errorf("RateLimitGraphQL = %q, want %q", cfg.RateLimitGraphQL, DefaultRateLimitGraphQL)
+	}
+}
+
+func TestLoadRateLimitConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		wantErr error
+	}{
+		{
+			name:    "unknown backend",
+			envVars: map[string]string{EnvRateLimitStore: "memcached"},
+			wantErr: ErrInvalidRateLimitBackend,
+		},
+		{
+			name:    "redis without address",
+			envVars: map[string]string{EnvRateLimitStore: "redis"},
+			wantErr: ErrInvalidRateLimitRedis,
+		},
+		{
+			name:    "invalid read limit",
+			envVars: map[string]string{EnvRateLimitRead: "many"},
+			wantErr: ErrInvalidRateLimit,
+		},
+		{
+			name:    "invalid write limit",
+			envVars: map[string]string{EnvRateLimitWrite: "10/forever"},
+			wantErr: ErrInvalidRateLimit,
+		},
+		{
+			name:    "invalid graphql limit",
+			envVars: map[string]string{EnvRateLimitGQL: "10/s:0"},
+			wantErr: ErrInvalidRateLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			t.Setenv(EnvRateLimit, "true")
+			for k, v := range tt.envVars {
+				t.Setenv(k, v)
+			}
+
+			// Act
+			_, err := Load()
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Load() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadRateLimitConfigDisabledSkipsValidation(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvRateLimitStore, "memcached")
+	t.Setenv(EnvRateLimitRead, "many")
+
+	// Act
+	_, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Errorf("Load() error = %v, want nil while rate limiting is disabled", err)
+	}
+}
+
+func TestLoadRateLimitConfigParseErrors(t *testing.T) {
+	for _, env := range []string{EnvRateLimit, EnvRateLimitDB} {
+		t.Run(env, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			t.Setenv(env, "invalid")
+
+			// Act
+			_, err := Load()
+
+			// Assert
+			if err == nil || !strings.Contains(err.Error(), env) {
+				t.Errorf("Load() error = %v, want parse error for %s", err, env)
+			}
+		})
+	}
+}
+
+func TestLoadIdempotencyConfig(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	t.Setenv(EnvIdempotency, "true")
+	t.Setenv(EnvIdempotencyTTL, "1h")
+	t.Setenv(EnvIdemStore, "redis")
+	t.Setenv(EnvIdemRedis, "redis:6379")
+	t.Setenv(EnvIdemRedisPass, "secret")
+	t.Setenv(EnvIdemRedisDB, "4")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if !cfg.IdempotencyEnabled {
+		t.Error("IdempotencyEnabled = false, want true")
+	}
+	if cfg.IdempotencyTTL != time.Hour {
+		t.Errorf("IdempotencyTTL = %v, want 1h", cfg.IdempotencyTTL)
+	}
+	if cfg.IdempotencyBackend != "redis" {
+		t.Errorf("IdempotencyBackend = %q, want %q", cfg.IdempotencyBackend, "redis")
+	}
+	if cfg.IdempotencyRedisAddr != "redis:6379" {
+		t.Errorf("IdempotencyRedisAddr = %q, want %q", cfg.IdempotencyRedisAddr, "redis:6379")
+	}
+	if cfg.IdempotencyRedisPassword != "secret" {
+		t.Errorf("IdempotencyRedisPassword = %q, want %q", cfg.IdempotencyRedisPassword, "secret")
+	}
+	if cfg.IdempotencyRedisDB != 4 {
+		t.Errorf("IdempotencyRedisDB = %d, want 4", cfg.IdempotencyRedisDB)
+	}
+}
+
+func TestLoadIdempotencyConfigDefaults(t *testing.T) {
 	// Arrange
 	clearEnvVars(t)
-	t.Setenv(EnvAuthMode, "oidc")
-	t.Setenv(EnvOIDCIssuerURL, "https://issuer.example.com")
-	t.Setenv(EnvOIDCClientID, "my-client")
-	t.Setenv(EnvOIDCAudience, "my-audience")
 
 	// Act
 	cfg, err := Load()
@@ -980,29 +2638,37 @@ func TestLoadOIDCConfigValid(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Load() returned unexpected error: %v", err)
 	}
-	if cfg.OIDCIssuerURL != "https://issuer.example.com" {
-		t.Errorf("OIDCIssuerURL = %s, want https://issuer.example.com", cfg.OIDCIssuerURL)
+	if cfg.IdempotencyEnabled != DefaultIdempotencyEnabled {
+		t.Errorf("IdempotencyEnabled = %v, want %v", cfg.IdempotencyEnabled, DefaultIdempotencyEnabled)
 	}
-	if cfg.OIDCClientID != "my-client" {
-		t.Errorf("OIDCClientID = %s, want my-client", cfg.OIDCClientID)
+	if cfg.IdempotencyTTL != DefaultIdempotencyTTL {
+		t.Errorf("IdempotencyTTL = %v, want %v", cfg.IdempotencyTTL, DefaultIdempotencyTTL)
 	}
-	if cfg.OIDCAudience != "my-audience" {
-		t.Errorf("OIDCAudience = %s, want my-audience", cfg.OIDCAudience)
+	if cfg.IdempotencyBackend != DefaultIdempotencyBackend {
+		t.Errorf("IdempotencyBackend = %q, want %q", cfg.IdempotencyBackend, DefaultIdempotencyBackend)
 	}
 }
 
-func TestLoadBasicAuthConfig(t *testing.T) {
+func TestLoadIdempotencyConfigErrors(t *testing.T) {
 	tests := []struct {
 		name    string
 		envVars map[string]string
 		wantErr error
 	}{
 		{
-			name: "basic mode without users config",
-			envVars: map[string]string{
-				EnvAuthMode: "basic",
-			},
-			wantErr: ErrInvalidBasicAuthConfig,
+			name:    "zero TTL",
+			envVars: map[string]string{EnvIdempotencyTTL: "0s"},
+			wantErr: ErrInvalidIdempotencyTTL,
+		},
+		{
+			name:    "unknown backend",
+			envVars: map[string]string{EnvIdemStore: "memcached"},
+			wantErr: ErrInvalidIdempotencyBackend,
+		},
+		{
+			name:    "redis without address",
+			envVars: map[string]string{EnvIdemStore: "redis"},
+			wantErr: ErrInvalidIdempotencyRedis,
 		},
 	}
 
@@ -1010,91 +2676,64 @@ func TestLoadBasicAuthConfig(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Arrange
 			clearEnvVars(t)
-			for key, value := range tt.envVars {
-				t.Setenv(key, value)
+			for k, v := range tt.envVars {
+				t.Setenv(k, v)
 			}
 
 			// Act
-			cfg, err := Load()
+			_, err := Load()
 
 			// Assert
-			if err == nil {
-				t.Fatal("Load() expected error, got nil")
-			}
-			if cfg != nil {
-				t.Errorf("Load() expected nil config on error, got %+v", cfg)
-			}
-			if !containsError(err, tt.wantErr) {
-				t.Errorf("Load() error = %v, want error containing %v", err, tt.wantErr)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Load() error = %v, want %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func TestLoadBasicAuthConfigValid(t *testing.T) {
+func TestLoadIdempotencyConfigDisabledSkipsValidation(t *testing.T) {
 	// Arrange
 	clearEnvVars(t)
-	t.Setenv(EnvAuthMode, "basic")
-	t.Setenv(EnvBasicAuthUsers, "user1:$2a$04$hash1,user2:$2a$04$hash2")
+	t.Setenv(EnvIdempotency, "false")
+	t.Setenv(EnvIdemStore, "memcached")
 
 	// Act
-	cfg, err := Load()
+	_, err := Load()
 
 	// Assert
 	if err != nil {
-		t.Fatalf("Load() returned unexpected error: %v", err)
-	}
-	if cfg.BasicAuthUsers != "user1:$2a$04$hash1,user2:$2a$04$hash2" {
-		t.Errorf("BasicAuthUsers = %s, want user1:$2a$04$hash1,user2:$2a$04$hash2",
-			cfg.BasicAuthUsers)
+		t.Errorf("Load() error = %v, want nil while idempotency is disabled", err)
 	}
 }
 
-func TestLoadAPIKeyConfig(t *testing.T) {
-	tests := []struct {
-		name    string
-		envVars map[string]string
-		wantErr error
-	}{
-		{
-			name: "apikey mode without keys config",
-			envVars: map[string]string{
-				EnvAuthMode: "apikey",
-			},
-			wantErr: ErrInvalidAPIKeyConfig,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
+func TestLoadIdempotencyConfigParseErrors(t *testing.T) {
+	for _, env := range []string{EnvIdempotency, EnvIdempotencyTTL, EnvIdemRedisDB} {
+		t.Run(env, func(t *testing.T) {
 			// Arrange
 			clearEnvVars(t)
-			for key, value := range tt.envVars {
-				t.Setenv(key, value)
-			}
+			t.Setenv(env, "invalid")
 
 			// Act
-			cfg, err := Load()
+			_, err := Load()
 
 			// Assert
-			if err == nil {
-				t.Fatal("Load() expected error, got nil")
-			}
-			if cfg != nil {
-				t.Errorf("Load() expected nil config on error, got %+v", cfg)
-			}
-			if !containsError(err, tt.wantErr) {
-				t.Errorf("Load() error = %v, want error containing %v", err, tt.wantErr)
+			if err == nil || !strings.Contains(err.Error(), env) {
+				t.Errorf("Load() error = %v, want parse error for %s", err, env)
 			}
 		})
 	}
 }
 
-func TestLoadAPIKeyConfigValid(t *testing.T) {
+func TestLoadCORSConfig(t *testing.T) {
 	// Arrange
 	clearEnvVars(t)
-	t.Setenv(EnvAuthMode, "apikey")
-	t.Setenv(EnvAPIKeys, "key1:service1,key2:service2")
+	t.Setenv(EnvCORSOrigins, "https://app.example.com,https://*.example.org")
+	t.Setenv(EnvCORSOriginRegex, `https://pr-[0-9]+\.preview\.example\.net`)
+	t.Setenv(EnvCORSMethods, "GET,POST")
+	t.Setenv(EnvCORSHeaders, "Content-Type")
+	t.Setenv(EnvCORSExposed, "ETag")
+	t.Setenv(EnvCORSCredentials, "true")
+	t.Setenv(EnvCORSMaxAge, "10m")
 
 	// Act
 	cfg, err := Load()
@@ -1103,92 +2742,95 @@ func TestLoadAPIKeyConfigValid(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Load() returned unexpected error: %v", err)
 	}
-	if cfg.APIKeys != "key1:service1,key2:service2" {
-		t.Errorf("APIKeys = %s, want key1:service1,key2:service2", cfg.APIKeys)
+	if cfg.CORSAllowedOrigins != "https://app.example.com,https://*.example.org" {
+		t.Errorf("CORSAllowedOrigins = %q, want the configured origins", cfg.CORSAllowedOrigins)
 	}
-}
-
-func TestLoadMultiAuthConfig(t *testing.T) {
-	tests := []struct {
-		name    string
-		envVars map[string]string
-		wantErr error
-	}{
-		{
-			name: "multi mode without any auth config",
-			envVars: map[string]string{
-				EnvAuthMode: "multi",
-			},
-			wantErr: ErrInvalidMultiAuthConfig,
-		},
+	if cfg.CORSOriginRegex != `https://pr-[0-9]+\.preview\.example\.net` {
+		t.Errorf("CORSOriginRegex = %q, want the configured regex", cfg.CORSOriginRegex)
 	}
+	if cfg.CORSAllowedMethods != "GET,POST" {
+		t.Errorf("CORSAllowedMethods = %q, want %q", cfg.CORSAllowedMethods, "GET,POST")
+	}
+	if cfg.CORSAllowedHeaders != "Content-Type" {
+		t.Errorf("CORSAllowedHeaders = %q, want %q", cfg.CORSAllowedHeaders, "Content-Type")
+	}
+	if cfg.CORSExposedHeaders != "ETag" {
+		t.Errorf("CORSExposedHeaders = %q, want %q", cfg.CORSExposedHeaders, "ETag")
+	}
+	if !cfg.CORSAllowCredentials {
+		t.Error("CORSAllowCredentials = false, want true")
+	}
+	if cfg.CORSMaxAge != 10*time.Minute {
+		t.Errorf("CORSMaxAge = %v, want 10m", cfg.CORSMaxAge)
+	}
+}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Arrange
-			clearEnvVars(t)
-			for key, value := range tt.envVars {
-				t.Setenv(key, value)
-			}
+func TestLoadCORSConfigDefaults(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
 
-			// Act
-			cfg, err := Load()
+	// Act
+	cfg, err := Load()
 
-			// Assert
-			if err == nil {
-				t.Fatal("Load() expected error, got nil")
-			}
-			if cfg != nil {
-				t.Errorf("Load() expected nil config on error, got %+v", cfg)
-			}
-			if !containsError(err, tt.wantErr) {
-				t.Errorf("Load() error = %v, want error containing %v", err, tt.wantErr)
-			}
-		})
+	// Assert
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.CORSAllowedOrigins != DefaultCORSOrigins {
+		t.Errorf("CORSAllowedOrigins = %q, want %q", cfg.CORSAllowedOrigins, DefaultCORSOrigins)
+	}
+	if cfg.CORSAllowedMethods != DefaultCORSMethods {
+		t.Errorf("CORSAllowedMethods = %q, want %q", cfg.CORSAllowedMethods, DefaultCORSMethods)
+	}
+	if cfg.CORSAllowedHeaders != DefaultCORSHeaders {
+		t.Errorf("CORSAllowedHeaders = %q, want %q", cfg.CORSAllowedHeaders, DefaultCORSHeaders)
+	}
+	if cfg.CORSExposedHeaders != DefaultCORSExposedHeaders {
+		t.Errorf("CORSExposedHeaders = %q, want %q", cfg.CORSExposedHeaders, DefaultCORSExposedHeaders)
+	}
+	if cfg.CORSAllowCredentials {
+		t.Error("CORSAllowCredentials = true, want false")
+	}
+	if cfg.CORSMaxAge != DefaultCORSMaxAge {
+		t.Errorf("CORSMaxAge = %v, want %v", cfg.CORSMaxAge, DefaultCORSMaxAge)
 	}
 }
 
-func TestLoadMultiAuthConfigValid(t *testing.T) {
+func TestLoadCORSConfigErrors(t *testing.T) {
 	tests := []struct {
 		name    string
 		envVars map[string]string
+		wantErr error
 	}{
 		{
-			name: "multi mode with basic auth users",
-			envVars: map[string]string{
-				EnvAuthMode:       "multi",
-				EnvBasicAuthUsers: "user1:hash1",
-			},
+			name:    "origin without scheme",
+			envVars: map[string]string{EnvCORSOrigins: "app.example.com"},
+			wantErr: ErrInvalidCORSOrigin,
 		},
 		{
-			name: "multi mode with API keys",
-			envVars: map[string]string{
-				EnvAuthMode: "multi",
-				EnvAPIKeys:  "key1:name1",
-			},
+			name:    "origin with path",
+			envVars: map[string]string{EnvCORSOrigins: "https://app.example.com/app"},
+			wantErr: ErrInvalidCORSOrigin,
 		},
 		{
-			name: "multi mode with OIDC issuer URL",
-			envVars: map[string]string{
-				EnvAuthMode:      "multi",
-				EnvOIDCIssuerURL: "https://issuer.example.com",
-			},
+			name:    "wildcard inside host",
+			envVars: map[string]string{EnvCORSOrigins: "https://app.*.example.com"},
+			wantErr: ErrInvalidCORSOrigin,
 		},
 		{
-			name: "multi mode with OIDC client ID",
-			envVars: map[string]string{
-				EnvAuthMode:     "multi",
-				EnvOIDCClientID: "my-client",
-			},
+			name:    "invalid regex",
+			envVars: map[string]string{EnvCORSOriginRegex: "("},
+			wantErr: ErrInvalidCORSRegex,
 		},
 		{
-			name: "multi mode with TLS enabled",
-			envVars: map[string]string{
-				EnvAuthMode:    "multi",
-				EnvTLSEnabled:  "true",
-				EnvTLSCertPath: "/path/to/cert.pem",
-				EnvTLSKeyPath:  "/path/to/key.pem",
-			},
+			name:    "negative max age",
+			envVars: map[string]string{EnvCORSMaxAge: "-1s"},
+			wantErr: ErrInvalidCORSMaxAge,
+		},
+		{
+			name:    "credentials for all origins",
+			envVars: map[string]string{EnvCORSOrigins: "https://app.example.com,*", EnvCORSCredentials: "true"},
+			wantErr: ErrInvalidCORSCredentials,
 		},
 	}
 
@@ -1196,71 +2838,107 @@ func TestLoadMultiAuthConfigValid(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Arrange
 			clearEnvVars(t)
-			for key, value := range tt.envVars {
-				t.Setenv(key, value)
+			for k, v := range tt.envVars {
+				t.Setenv(k, v)
 			}
 
 			// Act
-			cfg, err := Load()
+			_, err := Load()
 
 			// Assert
-			if err != nil {
-				t.Fatalf("Load() returned unexpected error: %v", err)
-			}
-			if cfg.AuthMode != "multi" {
-				t.Errorf("AuthMode = %s, want multi", cfg.AuthMode)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Load() error = %v, want %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func TestLoadVaultConfig(t *testing.T) {
-	// Arrange
-	clearEnvVars(t)
-	t.Setenv(EnvVaultEnabled, "true")
-	t.Setenv(EnvVaultAddr, "https://vault.example.com:8200")
-	t.Setenv(EnvVaultToken, "s.mytoken123")
-	t.Setenv(EnvVaultPKIPath, "pki/issue/my-role")
-	t.Setenv(EnvVaultPKIRole, "my-role")
+func TestLoadCORSConfigParseErrors(t *testing.T) {
+	for _, env := range []string{EnvCORSCredentials, EnvCORSMaxAge} {
+		t.Run(env, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			t.Setenv(env, "invalid")
 
-	// Act
-	cfg, err := Load()
+			// Act
+			_, err := Load()
 
-	// Assert
-	if err != nil {
-		t.Fatalf("Load() returned unexpected error: %v", err)
-	}
-	if !cfg.VaultEnabled {
-		t.Error("VaultEnabled should be true")
-	}
-	if cfg.VaultAddr != "https://vault.example.com:8200" {
-		t.Errorf("VaultAddr = %s, want https://vault.example.com:8200", cfg.VaultAddr)
-	}
-	if cfg.VaultToken != "s.mytoken123" {
-		t.Errorf("VaultToken = %s, want s.mytoken123", cfg.VaultToken)
+			// Assert
+			if err == nil || !strings.Contains(err.Error(), env) {
+				t.Errorf("Load() error = %v, want parse error for %s", err, env)
+			}
+		})
 	}
-	if cfg.VaultPKIPath != "pki/issue/my-role" {
-		t.Errorf("VaultPKIPath = %s, want pki/issue/my-role", cfg.VaultPKIPath)
+}
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		name string
+		list string
+		want []string
+	}{
+		{name: "empty", list: "", want: nil},
+		{name: "trims and drops empty entries", list: " a, ,b ,", want: []string{"a", "b"}},
 	}
-	if cfg.VaultPKIRole != "my-role" {
-		t.Errorf("VaultPKIRole = %s, want my-role", cfg.VaultPKIRole)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			got := SplitList(tt.list)
+
+			// Assert
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SplitList(%q) = %q, want %q", tt.list, got, tt.want)
+			}
+		})
 	}
 }
 
-func TestLoadVaultConfigParseError(t *testing.T) {
-	// Arrange
-	clearEnvVars(t)
-	t.Setenv(EnvVaultEnabled, "notabool")
-
-	// Act
-	cfg, err := Load()
+func TestParseRateLimit(t *testing.T) {
+	t.Parallel()
 
-	// Assert
-	if err == nil {
-		t.Fatal("Load() expected error, got nil")
+	tests := []struct {
+		name    string
+		input   string
+		want    RateLimit
+		wantErr bool
+	}{
+		{name: "empty", input: "", want: RateLimit{}},
+		{name: "zero", input: "0", want: RateLimit{}},
+		{name: "duration period", input: "600/1m", want: RateLimit{Requests: 600, Period: time.Minute, Burst: 600}},
+		{name: "unit period", input: "10/s", want: RateLimit{Requests: 10, Period: time.Second, Burst: 10}},
+		{name: "burst", input: " 5/2s:20 ", want: RateLimit{Requests: 5, Period: 2 * time.Second, Burst: 20}},
+		{name: "no period", input: "100", wantErr: true},
+		{name: "invalid requests", input: "x/1m", wantErr: true},
+		{name: "zero requests", input: "0/1m", wantErr: true},
+		{name: "invalid period", input: "10/week", wantErr: true},
+		{name: "empty period", input: "10/", wantErr: true},
+		{name: "negative period", input: "10/-1s", wantErr: true},
+		{name: "invalid burst", input: "10/s:x", wantErr: true},
+		{name: "zero burst", input: "10/s:0", wantErr: true},
 	}
-	if cfg != nil {
-		t.Errorf("Load() expected nil config on error, got %+v", cfg)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			got, err := ParseRateLimit(tt.input)
+
+			// Assert
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRateLimit) {
+					t.Errorf("ParseRateLimit(%q) error = %v, want %v", tt.input, err, ErrInvalidRateLimit)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRateLimit(%q) error = %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRateLimit(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
 	}
 }
 
@@ -1387,6 +3065,17 @@ func TestConfig_ValidateAuth(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name: "valid config with auth mode apikey and key file",
+			config: Config{
+				ServerPort:      8080,
+				LogLevel:        "info",
+				ShutdownTimeout: 30 * time.Second,
+				AuthMode:        "apikey",
+				APIKeyFile:      "/data/api-keys.json",
+			},
+			wantErr: nil,
+		},
 		{
 			name: "invalid auth mode",
 			config: Config{
@@ -1734,11 +3423,21 @@ func TestHasAnyAuthConfig(t *testing.T) {
 			config: Config{BasicAuthUsers: "user1:hash1"},
 			want:   true,
 		},
+		{
+			name:   "basic auth file set",
+			config: Config{BasicAuthFile: "/etc/restapi/htpasswd"},
+			want:   true,
+		},
 		{
 			name:   "API keys set",
 			config: Config{APIKeys: "key1:name1"},
 			want:   true,
 		},
+		{
+			name:   "API key file set",
+			config: Config{APIKeyFile: "/data/api-keys.json"},
+			want:   true,
+		},
 		{
 			name:   "TLS enabled",
 			config: Config{TLSEnabled: true},
@@ -1776,16 +3475,77 @@ func clearEnvVars(t *testing.T) {
 		EnvTLSKeyPath,
 		EnvTLSCAPath,
 		EnvTLSClientAuth,
+		EnvTLSReload,
+		EnvTLSCRLPath,
+		EnvTLSCRLURL,
+		EnvTLSCRLRefresh,
+		EnvTLSOCSPEnabled,
+		EnvTLSOCSPStrict,
+		EnvMTLSSubject,
+		EnvMTLSDomains,
+		EnvMTLSAllowedOUs,
+		EnvMTLSAllowedSANs,
+		EnvOIDCAlgorithms,
+		EnvOIDCClockSkew,
+		EnvOIDCClaims,
+		EnvIntroURL,
+		EnvIntroClientID,
+		EnvIntroSecret,
+		EnvIntroCacheTTL,
+		EnvIntroNegTTL,
 		EnvOIDCIssuerURL,
 		EnvOIDCClientID,
 		EnvOIDCAudience,
 		EnvBasicAuthUsers,
+		EnvBasicAuthFile,
+		EnvBasicAuthReload,
+		EnvBasicAuthCache,
+		EnvLockoutFailures,
+		EnvLockoutIPFails,
+		EnvLockoutBase,
+		EnvLockoutMaxDelay,
+		EnvLockoutReset,
 		EnvAPIKeys,
+		EnvAPIKeyFile,
 		EnvVaultEnabled,
 		EnvVaultAddr,
 		EnvVaultToken,
 		EnvVaultPKIPath,
 		EnvVaultPKIRole,
+		EnvVaultCommonName,
+		EnvVaultAltNames,
+		EnvVaultIPSANs,
+		EnvVaultPKITTL,
+		EnvStoreDriver,
+		EnvStoreDSN,
+		EnvStorePath,
+		EnvStoreMaxOpen,
+		EnvStoreMaxIdle,
+		EnvStoreConnMaxAge,
+		EnvStoreIDPattern,
+		EnvStoreTimeout,
+		EnvAuthzPolicyFile,
+		EnvRateLimit,
+		EnvRateLimitStore,
+		EnvRateLimitRedis,
+		EnvRateLimitPass,
+		EnvRateLimitDB,
+		EnvRateLimitRead,
+		EnvRateLimitWrite,
+		EnvRateLimitGQL,
+		EnvIdempotency,
+		EnvIdempotencyTTL,
+		EnvIdemStore,
+		EnvIdemRedis,
+		EnvIdemRedisPass,
+		EnvIdemRedisDB,
+		EnvCORSOrigins,
+		EnvCORSOriginRegex,
+		EnvCORSMethods,
+		EnvCORSHeaders,
+		EnvCORSExposed,
+		EnvCORSCredentials,
+		EnvCORSMaxAge,
 	}
 	for _, env := range envVars {
 		if err := os.Unsetenv(env); err != nil {
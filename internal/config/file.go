@@ -0,0 +1,283 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"go.yaml.in/yaml/v3"
+)
+
+// Configuration file errors.
+var (
+	ErrInvalidConfigFile = errors.New("config file must have a .yaml, .yml or .toml extension")
+	ErrUnknownConfigKey  = errors.New("unknown config file keys")
+	ErrInvalidConfigKey  = errors.New("invalid config file value")
+	ErrInvalidTOML       = errors.New("invalid TOML")
+)
+
+// fileKeys maps the dotted keys of a configuration file to the environment
+// variables they stand for.
+var fileKeys = map[string]string{
+	"server.port":             EnvServerPort,
+	"server.probe_port":       EnvProbePort,
+	"server.shutdown_timeout": EnvShutdownTimeout,
+	"log.level":               EnvLogLevel,
+	"metrics.enabled":         EnvMetricsEnabled,
+	"tracing.otlp_endpoint":   EnvOTLPEndpoint,
+
+	"cors.allowed_origins":      EnvCORSOrigins,
+	"cors.allowed_origin_regex": EnvCORSOriginRegex,
+	"cors.allowed_methods":      EnvCORSMethods,
+	"cors.allowed_headers":      EnvCORSHeaders,
+	"cors.exposed_headers":      EnvCORSExposed,
+	"cors.allow_credentials":    EnvCORSCredentials,
+	"cors.max_age":              EnvCORSMaxAge,
+
+	"auth.mode":                EnvAuthMode,
+	"tls.enabled":              EnvTLSEnabled,
+	"tls.cert_path":            EnvTLSCertPath,
+	"tls.key_path":             EnvTLSKeyPath,
+	"tls.ca_path":              EnvTLSCAPath,
+	"tls.client_auth":          EnvTLSClientAuth,
+	"tls.reload_interval":      EnvTLSReload,
+	"tls.crl_path":             EnvTLSCRLPath,
+	"tls.crl_url":              EnvTLSCRLURL,
+	"tls.crl_refresh_interval": EnvTLSCRLRefresh,
+	"tls.ocsp_enabled":         EnvTLSOCSPEnabled,
+	"tls.ocsp_strict":          EnvTLSOCSPStrict,
+	"mtls.subject":             EnvMTLSSubject,
+	"mtls.trust_domains":       EnvMTLSDomains,
+	"mtls.allowed_ous":         EnvMTLSAllowedOUs,
+	"mtls.allowed_sans":        EnvMTLSAllowedSANs,
+
+	"oidc.issuer_url":                  EnvOIDCIssuerURL,
+	"oidc.client_id":                   EnvOIDCClientID,
+	"oidc.audience":                    EnvOIDCAudience,
+	"oidc.algorithms":                  EnvOIDCAlgorithms,
+	"oidc.clock_skew":                  EnvOIDCClockSkew,
+	"oidc.required_claims":             EnvOIDCClaims,
+	"introspection.url":                EnvIntroURL,
+	"introspection.client_id":          EnvIntroClientID,
+	"introspection.client_secret":      EnvIntroSecret,
+	"introspection.cache_ttl":          EnvIntroCacheTTL,
+	"introspection.negative_cache_ttl": EnvIntroNegTTL,
+	"basic_auth.users":                 EnvBasicAuthUsers,
+	"basic_auth.file":                  EnvBasicAuthFile,
+	"basic_auth.reload_interval":       EnvBasicAuthReload,
+	"basic_auth.cache_ttl":             EnvBasicAuthCache,
+	"lockout.max_failures":             EnvLockoutFailures,
+	"lockout.max_client_failures":      EnvLockoutIPFails,
+	"lockout.base_delay":               EnvLockoutBase,
+	"lockout.max_delay":                EnvLockoutMaxDelay,
+	"lockout.reset_after":              EnvLockoutReset,
+	"api_keys.keys":                    EnvAPIKeys,
+	"api_keys.file":                    EnvAPIKeyFile,
+	"authz.policy_file":                EnvAuthzPolicyFile,
+
+	"vault.enabled":         EnvVaultEnabled,
+	"vault.addr":            EnvVaultAddr,
+	"vault.token":           EnvVaultToken,
+	"vault.pki_path":        EnvVaultPKIPath,
+	"vault.pki_role":        EnvVaultPKIRole,
+	"vault.pki_common_name": EnvVaultCommonName,
+	"vault.pki_alt_names":   EnvVaultAltNames,
+	"vault.pki_ip_sans":     EnvVaultIPSANs,
+	"vault.pki_ttl":         EnvVaultPKITTL,
+
+	"store.driver":            EnvStoreDriver,
+	"store.dsn":               EnvStoreDSN,
+	"store.path":              EnvStorePath,
+	"store.max_open_conns":    EnvStoreMaxOpen,
+	"store.max_idle_conns":    EnvStoreMaxIdle,
+	"store.conn_max_lifetime": EnvStoreConnMaxAge,
+	"store.id_pattern":        EnvStoreIDPattern,
+	"store.connect_timeout":   EnvStoreTimeout,
+
+	"rate_limit.enabled":        EnvRateLimit,
+	"rate_limit.backend":        EnvRateLimitStore,
+	"rate_limit.redis_addr":     EnvRateLimitRedis,
+	"rate_limit.redis_password": EnvRateLimitPass,
+	"rate_limit.redis_db":       EnvRateLimitDB,
+	"rate_limit.read":           EnvRateLimitRead,
+	"rate_limit.write":          EnvRateLimitWrite,
+	"rate_limit.graphql":        EnvRateLimitGQL,
+
+	"idempotency.enabled":        EnvIdempotency,
+	"idempotency.ttl":            EnvIdempotencyTTL,
+	"idempotency.backend":        EnvIdemStore,
+	"idempotency.redis_addr":     EnvIdemRedis,
+	"idempotency.redis_password": EnvIdemRedisPass,
+	"idempotency.redis_db":       EnvIdemRedisDB,
+}
+
+// issuerFileKeys maps the keys of an oidc.issuers entry to the names of the
+// indexed APP_OIDC_ISSUER_<n>_* variables.
+var issuerFileKeys = map[string]string{
+	"url":             "URL",
+	"audience":        "AUDIENCE",
+	"algorithms":      "ALGORITHMS",
+	"clock_skew":      "CLOCK_SKEW",
+	"required_claims": "REQUIRED_CLAIMS",
+}
+
+// readFile reads the YAML or TOML configuration file at path and returns its
+// values keyed by the environment variables they stand for.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is chosen by the operator
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	var doc map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		if _, tomlErr := toml.Decode(string(data), &doc); tomlErr != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidTOML, tomlErr)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidConfigFile, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	var unknown []string
+	if err := flattenFile(doc, values, &unknown); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%w in %s: %s", ErrUnknownConfigKey, path, strings.Join(unknown, ", "))
+	}
+
+	return values, nil
+}
+
+// flattenFile stores the values of the sections of doc in values and the
+// keys it does not know in unknown.
+func flattenFile(doc map[string]any, values map[string]string, unknown *[]string) error {
+	for section, raw := range doc {
+		if raw == nil {
+			continue
+		}
+		table, ok := raw.(map[string]any)
+		if !ok {
+			*unknown = append(*unknown, section)
+			continue
+		}
+
+		for name, value := range table {
+			key := section + "." + name
+			if key == "oidc.issuers" {
+				if err := flattenIssuers(value, values, unknown); err != nil {
+					return err
+				}
+				continue
+			}
+
+			env, ok := fileKeys[key]
+			if !ok {
+				*unknown = append(*unknown, key)
+				continue
+			}
+			if err := setFileValue(values, env, key, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// flattenIssuers stores the oidc.issuers list as indexed
+// APP_OIDC_ISSUER_<n>_* values.
+func flattenIssuers(raw any, values map[string]string, unknown *[]string) error {
+	var issuers []any
+	switch list := raw.(type) {
+	case []any:
+		issuers = list
+	case []map[string]any: // a TOML array of tables
+		for _, issuer := range list {
+			issuers = append(issuers, issuer)
+		}
+	default:
+		return fmt.Errorf("%w: oidc.issuers must be a list", ErrInvalidConfigKey)
+	}
+
+	for i, entry := range issuers {
+		issuer, ok := entry.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%w: oidc.issuers[%d] must be a table", ErrInvalidConfigKey, i)
+		}
+
+		for name, value := range issuer {
+			key := fmt.Sprintf("oidc.issuers[%d].%s", i, name)
+			suffix, ok := issuerFileKeys[name]
+			if !ok {
+				*unknown = append(*unknown, key)
+				continue
+			}
+			if err := setFileValue(values, fmt.Sprintf(EnvOIDCIssuerN, i+1, suffix), key, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// setFileValue stores value, a scalar or a list of scalars, as the string
+// value of env. Lists are joined with commas.
+func setFileValue(values map[string]string, env, key string, value any) error {
+	if list, ok := value.([]any); ok {
+		items := make([]string, 0, len(list))
+		for _, item := range list {
+			s, ok := scalarString(item)
+			if !ok {
+				return fmt.Errorf("%w: %s must be a list of scalars", ErrInvalidConfigKey, key)
+			}
+			items = append(items, s)
+		}
+		values[env] = strings.Join(items, ",")
+		return nil
+	}
+
+	s, ok := scalarString(value)
+	if !ok {
+		return fmt.Errorf("%w: %s must be a scalar or a list", ErrInvalidConfigKey, key)
+	}
+	values[env] = s
+	return nil
+}
+
+// scalarString formats a scalar file value the way it would be written in
+// an environment variable.
+func scalarString(value any) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", true
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
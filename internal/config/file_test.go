@@ -0,0 +1,283 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes content to a file named name in a temporary
+// directory and returns its path.
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("writing config file: %v", err)
+	}
+	return path
+}
+
+const yamlConfig = `
+server:
+  port: 8443
+  shutdown_timeout: 10s
+log:
+  level: debug
+metrics:
+  enabled: false
+cors:
+  allowed_origins:
+    - https://app.example.com
+    - https://admin.example.com
+auth:
+  mode: multi
+api_keys:
+  keys: "ci:secret"
+oidc:
+  issuer_url: https://auth.example.com
+  audience: api
+  issuers:
+    - url: https://partner.example.com
+      audience: partner-api
+      clock_skew: 1m
+rate_limit:
+  enabled: true
+  read: 100/1m:20
+`
+
+const tomlConfig = `
+# Equivalent to yamlConfig.
+[server]
+port = 8_443
+shutdown_timeout = "10s"
+
+[log]
+level = "debug" # overridden in some tests
+
+[metrics]
+enabled = false
+
+[cors]
+allowed_origins = [
+  "https://app.example.com",
+  "https://admin.example.com",
+]
+
+[auth]
+mode = 'multi'
+
+[api_keys]
+keys = "ci:secret"
+
+[oidc]
+issuer_url = "https://auth.example.com"
+audience = "api"
+
+[[oidc.issuers]]
+url = "https://partner.example.com"
+audience = "partner-api"
+clock_skew = "1m"
+
+[rate_limit]
+enabled = true
+read = "100/1m:20"
+`
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "yaml", file: "config.yaml", content: yamlConfig},
+		{name: "yml", file: "config.yml", content: yamlConfig},
+		{name: "toml", file: "config.toml", content: tomlConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			path := writeConfigFile(t, tt.file, tt.content)
+
+			// Act
+			cfg, err := LoadFile(path)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("LoadFile() error = %v", err)
+			}
+			if cfg.ServerPort != 8443 {
+				t.Errorf("ServerPort = %d, want 8443", cfg.ServerPort)
+			}
+			if cfg.ShutdownTimeout != 10*time.Second {
+				t.Errorf("ShutdownTimeout = %v, want 10s", cfg.ShutdownTimeout)
+			}
+			if cfg.LogLevel != "debug" {
+				t.Errorf("LogLevel = %q, want debug", cfg.LogLevel)
+			}
+			if cfg.MetricsEnabled {
+				t.Error("MetricsEnabled = true, want false")
+			}
+			if want := "https://app.example.com,https://admin.example.com"; cfg.CORSAllowedOrigins != want {
+				t.Errorf("CORSAllowedOrigins = %q, want %q", cfg.CORSAllowedOrigins, want)
+			}
+			if cfg.AuthMode != "multi" || cfg.APIKeys != "ci:secret" {
+				t.Errorf("AuthMode, APIKeys = %q, %q, want multi, ci:secret", cfg.AuthMode, cfg.APIKeys)
+			}
+			if len(cfg.OIDCIssuers) != 1 || cfg.OIDCIssuers[0].URL != "https://partner.example.com" ||
+				cfg.OIDCIssuers[0].Audience != "partner-api" || cfg.OIDCIssuers[0].ClockSkew != time.Minute {
+				t.Errorf("OIDCIssuers = %+v, want the partner issuer", cfg.OIDCIssuers)
+			}
+			if !cfg.RateLimitEnabled || cfg.RateLimitRead != "100/1m:20" {
+				t.Errorf("RateLimitEnabled, RateLimitRead = %v, %q, want true, 100/1m:20",
+					cfg.RateLimitEnabled, cfg.RateLimitRead)
+			}
+			if cfg.ProbePort != DefaultProbePort {
+				t.Errorf("ProbePort = %d, want default %d", cfg.ProbePort, DefaultProbePort)
+			}
+		})
+	}
+}
+
+func TestLoadFile_EnvironmentOverrides(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+	path := writeConfigFile(t, "config.yaml", yamlConfig)
+	t.Setenv(EnvLogLevel, "warn")
+	t.Setenv(EnvServerPort, "9000")
+
+	// Act
+	cfg, err := LoadFile(path)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if cfg.LogLevel != "warn" {
+		t.Errorf("LogLevel = %q, want warn from the environment", cfg.LogLevel)
+	}
+	if cfg.ServerPort != 9000 {
+		t.Errorf("ServerPort = %d, want 9000 from the environment", cfg.ServerPort)
+	}
+	if cfg.AuthMode != "multi" {
+		t.Errorf("AuthMode = %q, want multi from the file", cfg.AuthMode)
+	}
+}
+
+func TestLoadFile_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		wantErr  error
+		wantText []string
+	}{
+		{
+			name:     "unknown keys",
+			file:     "config.yaml",
+			content:  "server:\n  prot: 80\nlogging:\n  level: debug\noidc:\n  issuers:\n    - url: x\n      aud: y\n",
+			wantErr:  ErrUnknownConfigKey,
+			wantText: []string{"logging.level", "oidc.issuers[0].aud", "server.prot"},
+		},
+		{
+			name:     "unknown section",
+			file:     "config.toml",
+			content:  "port = 80\n",
+			wantErr:  ErrUnknownConfigKey,
+			wantText: []string{"port"},
+		},
+		{
+			name:    "unsupported extension",
+			file:    "config.json",
+			content: "{}",
+			wantErr: ErrInvalidConfigFile,
+		},
+		{
+			name:    "nested value",
+			file:    "config.yaml",
+			content: "server:\n  port:\n    value: 80\n",
+			wantErr: ErrInvalidConfigKey,
+		},
+		{
+			name:    "issuers not a list",
+			file:    "config.yaml",
+			content: "oidc:\n  issuers: https://auth.example.com\n",
+			wantErr: ErrInvalidConfigKey,
+		},
+		{
+			name:    "invalid toml",
+			file:    "config.toml",
+			content: "[server]\nport = \n",
+			wantErr: ErrInvalidTOML,
+		},
+		{
+			name:    "toml duplicate table",
+			file:    "config.toml",
+			content: "[server]\nport = 80\n[server]\nprobe_port = 81\n",
+			wantErr: ErrInvalidTOML,
+		},
+		{
+			name:    "toml leading zero",
+			file:    "config.toml",
+			content: "[server]\nport = 010\n",
+			wantErr: ErrInvalidTOML,
+		},
+		{
+			name:    "toml invalid escape",
+			file:    "config.toml",
+			content: "[log]\nlevel = \"debug\\a\"\n",
+			wantErr: ErrInvalidTOML,
+		},
+		{
+			name:    "toml issuers not tables",
+			file:    "config.toml",
+			content: "[oidc]\nissuers = [\"https://auth.example.com\"]\n",
+			wantErr: ErrInvalidConfigKey,
+		},
+		{
+			name:    "invalid value",
+			file:    "config.yaml",
+			content: "server:\n  port: 0\nlog:\n  level: loud\n",
+			wantErr: ErrInvalidServerPort,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			clearEnvVars(t)
+			path := writeConfigFile(t, tt.file, tt.content)
+
+			// Act
+			_, err := LoadFile(path)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LoadFile() error = %v, want %v", err, tt.wantErr)
+			}
+			for _, text := range tt.wantText {
+				if !strings.Contains(err.Error(), text) {
+					t.Errorf("LoadFile() error = %v, want it to mention %q", err, text)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFile_Missing(t *testing.T) {
+	// Arrange
+	clearEnvVars(t)
+
+	// Act
+	_, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml"))
+
+	// Assert
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadFile() error = %v, want %v", err, os.ErrNotExist)
+	}
+}
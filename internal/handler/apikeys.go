@@ -0,0 +1,203 @@
+// apikeys.go implements the admin API for managing API keys at runtime.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+)
+
+// APIKeyManager creates, lists, rotates and revokes managed API keys.
+// *auth.APIKeyAuthenticator implements it.
+type APIKeyManager interface {
+	CreateKey(ctx context.Context, name string, scopes []string, ttl time.Duration) (string, *auth.APIKey, error)
+	ListKeys(ctx context.Context) ([]*auth.APIKey, error)
+	RotateKey(ctx context.Context, id string, ttl time.Duration) (string, *auth.APIKey, error)
+	RevokeKey(ctx context.Context, id string) error
+}
+
+// APIKeyHandler handles the API key admin API.
+type APIKeyHandler struct {
+	keys   APIKeyManager
+	logger *zap.Logger
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler.
+func NewAPIKeyHandler(keys APIKeyManager, logger *zap.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		keys:   keys,
+		logger: logger,
+	}
+}
+
+// RegisterRoutes registers the API key admin routes with the router.
+func (h *APIKeyHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/admin/api-keys", h.ListKeys).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/admin/api-keys", h.CreateKey).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/admin/api-keys/{id}/rotate", h.RotateKey).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/admin/api-keys/{id}", h.RevokeKey).Methods(http.MethodDelete)
+}
+
+// ListKeys handles GET /api/v1/admin/api-keys requests.
+func (h *APIKeyHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.keys.ListKeys(r.Context())
+	if err != nil {
+		h.handleError(w, err, "list API keys")
+		return
+	}
+
+	response := make([]APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, apiKeyResponse(key, ""))
+	}
+	h.writeJSON(w, http.StatusOK, model.NewSuccessResponse(response))
+}
+
+// CreateKey handles POST /api/v1/admin/api-keys requests. The key is only
+// returned in this response.
+func (h *APIKeyHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	var input APIKeyRequest
+	ttl, err := h.decodeRequest(w, r, &input, false)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	secret, key, err := h.keys.CreateKey(r.Context(), input.Name, input.Scopes, ttl)
+	if err != nil {
+		h.handleError(w, err, "create API key")
+		return
+	}
+
+	h.logger.Info("API key created",
+		zap.String("key_id", key.ID),
+		zap.String("name", key.Name),
+	)
+	h.writeJSON(w, http.StatusCreated, model.NewSuccessResponse(apiKeyResponse(key, secret)))
+}
+
+// RotateKey handles POST /api/v1/admin/api-keys/{id}/rotate requests. The
+// body is optional. The new key is only returned in this response.
+func (h *APIKeyHandler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	var input APIKeyRequest
+	ttl, err := h.decodeRequest(w, r, &input, true)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	secret, key, err := h.keys.RotateKey(r.Context(), mux.Vars(r)["id"], ttl)
+	if err != nil {
+		h.handleError(w, err, "rotate API key")
+		return
+	}
+
+	h.logger.Info("API key rotated", zap.String("key_id", key.ID))
+	h.writeJSON(w, http.StatusOK, model.NewSuccessResponse(apiKeyResponse(key, secret)))
+}
+
+// RevokeKey handles DELETE /api/v1/admin/api-keys/{id} requests.
+func (h *APIKeyHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := h.keys.RevokeKey(r.Context(), id); err != nil {
+		h.handleError(w, err, "revoke API key")
+		return
+	}
+
+	h.logger.Info("API key revoked", zap.String("key_id", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeRequest decodes an APIKeyRequest body into input and returns the
+// requested lifetime. An empty body is accepted when optional is set.
+func (h *APIKeyHandler) decodeRequest(
+	w http.ResponseWriter,
+	r *http.Request,
+	input *APIKeyRequest,
+	optional bool,
+) (time.Duration, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	err := json.NewDecoder(r.Body).Decode(input)
+	if err != nil && !(optional && errors.Is(err, io.EOF)) {
+		return 0, errors.New("invalid request body")
+	}
+
+	if input.ExpiresIn == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(input.ExpiresIn)
+	if err != nil || ttl <= 0 {
+		return 0, errors.New(`expires_in must be a positive duration such as "720h"`)
+	}
+	return ttl, nil
+}
+
+// handleError writes the response for an API key management error.
+func (h *APIKeyHandler) handleError(w http.ResponseWriter, err error, operation string) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidAPIKeyParams):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, auth.ErrAPIKeyNotFound):
+		h.writeError(w, http.StatusNotFound, "API key not found")
+	case errors.Is(err, auth.ErrAPIKeyRevoked):
+		h.writeError(w, http.StatusConflict, "API key has been revoked")
+	default:
+		h.logger.Error("API key operation failed", zap.String("operation", operation), zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+// apiKeyResponse converts key to its API representation, with the given
+// secret key if it was just issued.
+func apiKeyResponse(key *auth.APIKey, secret string) APIKeyResponse {
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Scopes:     scopes,
+		Key:        secret,
+		CreatedAt:  key.CreatedAt,
+		RotatedAt:  optionalTime(key.RotatedAt),
+		ExpiresAt:  optionalTime(key.ExpiresAt),
+		LastUsedAt: optionalTime(key.LastUsedAt),
+		RevokedAt:  optionalTime(key.RevokedAt),
+	}
+}
+
+// writeJSON writes a JSON response with the given status code.
+func (h *APIKeyHandler) writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+// writeError writes an error response with the given status code and message.
+func (h *APIKeyHandler) writeError(w http.ResponseWriter, status int, message string) {
+	h.writeJSON(w, status, model.ErrorResponse{Code: status, Message: message})
+}
+
+// optionalTime returns nil for the zero time.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
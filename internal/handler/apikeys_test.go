@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+)
+
+// failingAPIKeyManager is an APIKeyManager whose operations all fail.
+type failingAPIKeyManager struct{ err error }
+
+func (m failingAPIKeyManager) CreateKey(context.Context, string, []string, time.Duration) (string, *auth.APIKey, error) {
+	return "", nil, m.err
+}
+
+func (m failingAPIKeyManager) ListKeys(context.Context) ([]*auth.APIKey, error) {
+	return nil, m.err
+}
+
+func (m failingAPIKeyManager) RotateKey(context.Context, string, time.Duration) (string, *auth.APIKey, error) {
+	return "", nil, m.err
+}
+
+func (m failingAPIKeyManager) RevokeKey(context.Context, string) error {
+	return m.err
+}
+
+// newAPIKeyTestRouter returns a router serving the API key admin API backed
+// by keys.
+func newAPIKeyTestRouter(keys APIKeyManager) *mux.Router {
+	router := mux.NewRouter()
+	NewAPIKeyHandler(keys, zap.NewNop()).RegisterRoutes(router)
+	return router
+}
+
+// serveAPIKeyRequest sends a request to router and decodes the data of a
+// success response into data.
+func serveAPIKeyRequest(router http.Handler, method, path, body string, data any) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if data != nil && rr.Code < http.StatusBadRequest {
+		envelope := struct {
+			Data any `json:"data"`
+		}{Data: data}
+		_ = json.Unmarshal(rr.Body.Bytes(), &envelope)
+	}
+	return rr
+}
+
+func TestAPIKeyHandler_Lifecycle(t *testing.T) {
+	// Arrange
+	keys, err := auth.NewAPIKeyStoreAuthenticator("", auth.NewMemoryAPIKeyStore())
+	if err != nil {
+		t.Fatalf("NewAPIKeyStoreAuthenticator() error = %v", err)
+	}
+	router := newAPIKeyTestRouter(keys)
+
+	// Act
+	var created APIKeyResponse
+	rr := serveAPIKeyRequest(router, http.MethodPost, "/api/v1/admin/api-keys",
+		`{"name":"ci","scopes":["reader"],"expires_in":"24h"}`, &created)
+
+	// Assert
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, want %d: %s", rr.Code, http.StatusCreated, rr.Body)
+	}
+	if created.ID == "" || created.Key == "" || created.ExpiresAt == nil {
+		t.Fatalf("create response = %+v, want ID, key and expiry", created)
+	}
+
+	var listed []APIKeyResponse
+	rr = serveAPIKeyRequest(router, http.MethodGet, "/api/v1/admin/api-keys", "", &listed)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if len(listed) != 1 || listed[0].ID != created.ID || listed[0].Key != "" {
+		t.Errorf("list response = %+v, want the created key without its secret", listed)
+	}
+
+	var rotated APIKeyResponse
+	rr = serveAPIKeyRequest(router, http.MethodPost, "/api/v1/admin/api-keys/"+created.ID+"/rotate", "", &rotated)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("rotate status = %d, want %d: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if rotated.Key == "" || rotated.Key == created.Key || rotated.RotatedAt == nil {
+		t.Errorf("rotate response = %+v, want a new key", rotated)
+	}
+
+	rr = serveAPIKeyRequest(router, http.MethodDelete, "/api/v1/admin/api-keys/"+created.ID, "", nil)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("revoke status = %d, want %d", rr.Code, http.StatusNoContent)
+	}
+
+	rr = serveAPIKeyRequest(router, http.MethodPost, "/api/v1/admin/api-keys/"+created.ID+"/rotate", "", nil)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("rotate revoked status = %d, want %d", rr.Code, http.StatusConflict)
+	}
+}
+
+func TestAPIKeyHandler_Errors(t *testing.T) {
+	keys, err := auth.NewAPIKeyStoreAuthenticator("", auth.NewMemoryAPIKeyStore())
+	if err != nil {
+		t.Fatalf("NewAPIKeyStoreAuthenticator() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		keys       APIKeyManager
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{
+			name: "create with malformed body", keys: keys,
+			method: http.MethodPost, path: "/api/v1/admin/api-keys", body: `{`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "create without body", keys: keys,
+			method: http.MethodPost, path: "/api/v1/admin/api-keys",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "create without name", keys: keys,
+			method: http.MethodPost, path: "/api/v1/admin/api-keys", body: `{"scopes":["reader"]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "create with invalid lifetime", keys: keys,
+			method: http.MethodPost, path: "/api/v1/admin/api-keys", body: `{"name":"ci","expires_in":"-1h"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "rotate unknown key", keys: keys,
+			method: http.MethodPost, path: "/api/v1/admin/api-keys/unknown/rotate",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "revoke unknown key", keys: keys,
+			method: http.MethodDelete, path: "/api/v1/admin/api-keys/unknown",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "store failure", keys: failingAPIKeyManager{err: errors.New("disk full")},
+			method: http.MethodGet, path: "/api/v1/admin/api-keys",
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			router := newAPIKeyTestRouter(tt.keys)
+
+			// Act
+			rr := serveAPIKeyRequest(router, tt.method, tt.path, tt.body, nil)
+
+			// Assert
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rr.Code, tt.wantStatus, rr.Body)
+			}
+		})
+	}
+}
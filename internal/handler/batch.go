@@ -0,0 +1,101 @@
+// batch.go implements batch item operations shared by the REST
+// /api/v1/items:batch endpoint and the GraphQL batchItems mutation.
+// Operations are validated up front; an atomic batch containing an invalid
+// operation is rejected without touching the store.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vyrodovalexey/restapi-example/internal/store"
+)
+
+// MaxBatchSize is the largest number of operations accepted in one batch.
+const MaxBatchSize = 1000
+
+// maxBatchRequestBodySize is the maximum allowed size for batch request
+// bodies (10 MB), sized for MaxBatchSize operations.
+const maxBatchRequestBodySize = 10 << 20
+
+// Batch modes accepted by the REST endpoint.
+const (
+	batchModeAtomic     = "atomic"
+	batchModeBestEffort = "best_effort"
+)
+
+// validateBatchOp checks that op is well formed, that its item passes model
+// validation and that ids accepts the ID chosen for a created item.
+func validateBatchOp(op *store.BatchOp, ids *store.IDValidator) error {
+	switch op.Type {
+	case store.BatchCreate:
+		if op.Item != nil && op.Item.ID != "" {
+			if err := ids.Validate(op.Item.ID); err != nil {
+				return err
+			}
+		}
+	case store.BatchUpdate, store.BatchDelete:
+		if op.ID == "" {
+			return store.ErrInvalidID
+		}
+	default:
+		return fmt.Errorf("%w: unknown op %q", store.ErrInvalidBatchOp, op.Type)
+	}
+
+	if op.Type == store.BatchDelete {
+		return nil
+	}
+
+	if op.Item == nil {
+		return fmt.Errorf("%w: %w", errValidation, store.ErrNilItem)
+	}
+	if err := op.Item.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", errValidation, err)
+	}
+
+	return nil
+}
+
+// runBatch validates ops and applies the valid ones. An atomic batch with an
+// invalid operation is not sent to the store: the invalid operations report
+// their validation error and all others ErrBatchAborted. In best-effort mode
+// invalid operations fail individually and the rest are applied.
+func runBatch(
+	ctx context.Context, s store.Store, ids *store.IDValidator, ops []store.BatchOp, atomic bool,
+) ([]store.BatchResult, error) {
+	results := make([]store.BatchResult, len(ops))
+	valid := make([]store.BatchOp, 0, len(ops))
+	validIndex := make([]int, 0, len(ops))
+
+	for i := range ops {
+		if err := validateBatchOp(&ops[i], ids); err != nil {
+			results[i].Err = err
+			continue
+		}
+		valid = append(valid, ops[i])
+		validIndex = append(validIndex, i)
+	}
+
+	if atomic && len(valid) < len(ops) {
+		for _, i := range validIndex {
+			results[i].Err = store.ErrBatchAborted
+		}
+		return results, nil
+	}
+
+	if len(valid) == 0 {
+		return results, nil
+	}
+
+	applied, err := s.Batch(ctx, valid, atomic)
+	if err != nil {
+		return nil, err
+	}
+
+	for j, i := range validIndex {
+		results[i] = applied[j]
+	}
+
+	return results, nil
+}
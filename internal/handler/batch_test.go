@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+	"github.com/vyrodovalexey/restapi-example/internal/store"
+)
+
+func TestValidateBatchOp(t *testing.T) {
+	tests := []struct {
+		name    string
+		op      store.BatchOp
+		wantErr error
+	}{
+		{name: "create", op: store.BatchOp{Type: store.BatchCreate, Item: &model.Item{Name: "A", Price: 1}}},
+		{name: "update", op: store.BatchOp{Type: store.BatchUpdate, ID: "1", Item: &model.Item{Name: "A"}}},
+		{name: "delete", op: store.BatchOp{Type: store.BatchDelete, ID: "1"}},
+		{name: "unknown type", op: store.BatchOp{Type: "upsert"}, wantErr: store.ErrInvalidBatchOp},
+		{name: "update without id", op: store.BatchOp{Type: store.BatchUpdate, Item: &model.Item{Name: "A"}},
+			wantErr: store.ErrInvalidID},
+		{name: "delete without id", op: store.BatchOp{Type: store.BatchDelete}, wantErr: store.ErrInvalidID},
+		{name: "create without item", op: store.BatchOp{Type: store.BatchCreate}, wantErr: errValidation},
+		{name: "invalid item", op: store.BatchOp{Type: store.BatchCreate, Item: &model.Item{Price: 1}},
+			wantErr: model.ErrEmptyName},
+		{name: "create with id", op: store.BatchOp{Type: store.BatchCreate, Item: &model.Item{ID: testUUID, Name: "A"}}},
+		{name: "create with invalid id", op: store.BatchOp{Type: store.BatchCreate, Item: &model.Item{ID: "a", Name: "A"}},
+			wantErr: store.ErrInvalidID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := validateBatchOp(&tt.op, nil)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("validateBatchOp() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		atomic    bool
+		wantErrs  []error
+		wantCount int
+	}{
+		{
+			name:      "atomic batch with invalid op is not applied",
+			atomic:    true,
+			wantErrs:  []error{store.ErrBatchAborted, errValidation, store.ErrBatchAborted},
+			wantCount: 1,
+		},
+		{
+			name:      "best effort applies valid ops",
+			atomic:    false,
+			wantErrs:  []error{nil, errValidation, nil},
+			wantCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			s := store.NewMemoryStore()
+			existing, err := s.Create(ctx, &model.Item{Name: "Existing", Price: 1})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			ops := []store.BatchOp{
+				{Type: store.BatchCreate, Item: &model.Item{Name: "New", Price: 2}},
+				{Type: store.BatchCreate, Item: &model.Item{Name: "Bad", Price: -1}},
+				{Type: store.BatchDelete, ID: existing.ID},
+			}
+
+			// Act
+			results, err := runBatch(ctx, s, nil, ops, tt.atomic)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("runBatch() error = %v", err)
+			}
+			for i, want := range tt.wantErrs {
+				if !errors.Is(results[i].Err, want) {
+					t.Errorf("results[%d].Err = %v, want %v", i, results[i].Err, want)
+				}
+			}
+			page, _ := s.List(ctx, store.ListOptions{})
+			if page.Total != tt.wantCount {
+				t.Errorf("item count = %d, want %d", page.Total, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestRunBatch_StoreError(t *testing.T) {
+	// Arrange
+	ms := newMockStore()
+	ms.batchErr = context.Canceled
+	ops := []store.BatchOp{{Type: store.BatchDelete, ID: "1"}}
+
+	// Act
+	_, err := runBatch(context.Background(), ms, nil, ops, true)
+
+	// Assert
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("runBatch() error = %v, want %v", err, context.Canceled)
+	}
+}
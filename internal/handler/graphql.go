@@ -1,20 +1,26 @@
 // graphql.go implements the GraphQL HTTP handler for querying and mutating items.
 // It exposes a /graphql endpoint supporting both POST queries/mutations and
-// a GET-accessible GraphiQL playground.
+// a GET-accessible GraphiQL playground. Subscriptions are served on the same
+// path over WebSocket (see graphql_ws.go).
 
 package handler
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
 	"github.com/graphql-go/graphql"
 	gqlhandler "github.com/graphql-go/handler"
 	"go.uber.org/zap"
 
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/authz"
 	"github.com/vyrodovalexey/restapi-example/internal/model"
 	"github.com/vyrodovalexey/restapi-example/internal/store"
 )
@@ -26,6 +32,24 @@ const (
 	fieldName        = "name"
 	fieldDescription = "description"
 	fieldPrice       = "price"
+	fieldVersion     = "version"
+
+	argExpectedVersion = "expectedVersion"
+
+	argLimit      = "limit"
+	argOffset     = "offset"
+	argAfter      = "after"
+	argSortBy     = "sortBy"
+	argSortOrder  = "sortOrder"
+	argNamePrefix = "namePrefix"
+	argMinPrice   = "minPrice"
+	argMaxPrice   = "maxPrice"
+
+	sortOrderDesc = "DESC"
+
+	argOperations = "operations"
+	argMode       = "mode"
+	fieldOp       = "op"
 )
 
 // GraphQLHandler handles GraphQL API requests for items.
@@ -34,14 +58,39 @@ type GraphQLHandler struct {
 	logger  *zap.Logger
 	schema  graphql.Schema
 	handler *gqlhandler.Handler
+	ids     *store.IDValidator
+
+	// Subscription transport state; see graphql_ws.go.
+	events        ItemEventSource
+	authenticator auth.Authenticator
+	authorizer    *authz.Authorizer
+	upgrader      websocket.Upgrader
+	mu            sync.Mutex
+	conns         map[*websocket.Conn]*subscriptionConn
+	wg            sync.WaitGroup // tracks active subscription connections
 }
 
-// NewGraphQLHandler creates a new GraphQLHandler instance.
-// It panics if the GraphQL schema cannot be built, which indicates a programming error.
-func NewGraphQLHandler(s store.Store, logger *zap.Logger) *GraphQLHandler {
+// NewGraphQLHandler creates a new GraphQLHandler instance. The optional event
+// source backs the Subscription root; without it, subscriptions fail with an
+// error. It panics if the GraphQL schema cannot be built, which indicates a
+// programming error.
+func NewGraphQLHandler(s store.Store, logger *zap.Logger, events ...ItemEventSource) *GraphQLHandler {
 	h := &GraphQLHandler{
 		store:  s,
 		logger: logger,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			Subprotocols:    []string{graphqlTransportWSProtocol},
+			CheckOrigin: func(_ *http.Request) bool {
+				return true // Allow all origins for development
+			},
+		},
+		conns: make(map[*websocket.Conn]*subscriptionConn),
+	}
+
+	if len(events) > 0 {
+		h.events = events[0]
 	}
 
 	schema, err := h.buildSchema()
@@ -60,23 +109,46 @@ func NewGraphQLHandler(s store.Store, logger *zap.Logger) *GraphQLHandler {
 	return h
 }
 
-// RegisterRoutes registers the GraphQL routes with the router.
+// SetIDValidator sets the validator of the item IDs chosen by clients. It
+// must be called before the handler serves requests; without it, clients
+// may only choose UUIDs.
+func (h *GraphQLHandler) SetIDValidator(ids *store.IDValidator) {
+	h.ids = ids
+}
+
+// RegisterRoutes registers the GraphQL routes with the router. WebSocket
+// upgrades of /graphql are routed to the subscription transport, on the
+// route named RouteGraphQLSubscriptions.
 func (h *GraphQLHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/graphql", h.HandleSubscriptions).
+		Methods(http.MethodGet).
+		MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
+			return websocket.IsWebSocketUpgrade(r)
+		}).
+		Name(RouteGraphQLSubscriptions)
 	router.Handle("/graphql", h.handler).Methods(http.MethodPost, http.MethodGet)
 }
 
-// buildSchema constructs the GraphQL schema with all types, queries, and mutations.
+// buildSchema constructs the GraphQL schema with all types, queries, mutations,
+// and subscriptions.
 func (h *GraphQLHandler) buildSchema() (graphql.Schema, error) {
 	itemType := h.buildItemType()
 	createItemInput := h.buildCreateItemInput()
 	updateItemInput := h.buildUpdateItemInput()
+	upsertItemInput := h.buildUpsertItemInput()
 
 	queryType := h.buildQueryType(itemType)
-	mutationType := h.buildMutationType(itemType, createItemInput, updateItemInput)
+	mutationType := h.buildMutationType(itemType, createItemInput, updateItemInput, upsertItemInput)
+	subscriptionType := h.buildSubscriptionType(itemType)
+
+	for _, obj := range []*graphql.Object{itemType, queryType, mutationType, subscriptionType} {
+		h.authorizeFields(obj)
+	}
 
 	schema, err := graphql.NewSchema(graphql.SchemaConfig{
-		Query:    queryType,
-		Mutation: mutationType,
+		Query:        queryType,
+		Mutation:     mutationType,
+		Subscription: subscriptionType,
 	})
 	if err != nil {
 		return graphql.Schema{}, fmt.Errorf("building GraphQL schema: %w", err)
@@ -102,6 +174,9 @@ func (h *GraphQLHandler) buildItemType() *graphql.Object {
 			fieldPrice: &graphql.Field{
 				Type: graphql.NewNonNull(graphql.Float),
 			},
+			fieldVersion: &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
 			"createdAt": &graphql.Field{
 				Type: graphql.NewNonNull(graphql.String),
 				Resolve: func(p graphql.ResolveParams) (any, error) {
@@ -126,35 +201,106 @@ func (h *GraphQLHandler) buildItemType() *graphql.Object {
 
 // buildCreateItemInput defines the GraphQL input type for creating items.
 func (h *GraphQLHandler) buildCreateItemInput() *graphql.InputObject {
+	fields := itemInputFields()
+	fields[fieldID] = &graphql.InputObjectFieldConfig{
+		Type:        graphql.ID,
+		Description: "ID of the new item; a UUID is generated if omitted.",
+	}
+
 	return graphql.NewInputObject(graphql.InputObjectConfig{
-		Name: "CreateItemInput",
-		Fields: graphql.InputObjectConfigFieldMap{
-			fieldName: &graphql.InputObjectFieldConfig{
-				Type: graphql.NewNonNull(graphql.String),
-			},
-			fieldDescription: &graphql.InputObjectFieldConfig{
-				Type: graphql.String,
-			},
-			fieldPrice: &graphql.InputObjectFieldConfig{
-				Type: graphql.NewNonNull(graphql.Float),
-			},
-		},
+		Name:   "CreateItemInput",
+		Fields: fields,
+	})
+}
+
+// buildUpsertItemInput defines the GraphQL input type for upserting items,
+// whose ID is an argument of the mutation.
+func (h *GraphQLHandler) buildUpsertItemInput() *graphql.InputObject {
+	return graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:   "UpsertItemInput",
+		Fields: itemInputFields(),
 	})
 }
 
+// itemInputFields returns the fields of the input types carrying a full item.
+func itemInputFields() graphql.InputObjectConfigFieldMap {
+	return graphql.InputObjectConfigFieldMap{
+		fieldName: &graphql.InputObjectFieldConfig{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		fieldDescription: &graphql.InputObjectFieldConfig{
+			Type: graphql.String,
+		},
+		fieldPrice: &graphql.InputObjectFieldConfig{
+			Type: graphql.NewNonNull(graphql.Float),
+		},
+	}
+}
+
 // buildUpdateItemInput defines the GraphQL input type for updating items.
+// All fields are optional; omitted fields keep their stored value.
 func (h *GraphQLHandler) buildUpdateItemInput() *graphql.InputObject {
 	return graphql.NewInputObject(graphql.InputObjectConfig{
 		Name: "UpdateItemInput",
 		Fields: graphql.InputObjectConfigFieldMap{
 			fieldName: &graphql.InputObjectFieldConfig{
-				Type: graphql.NewNonNull(graphql.String),
+				Type: graphql.String,
 			},
 			fieldDescription: &graphql.InputObjectFieldConfig{
 				Type: graphql.String,
 			},
 			fieldPrice: &graphql.InputObjectFieldConfig{
-				Type: graphql.NewNonNull(graphql.Float),
+				Type: graphql.Float,
+			},
+		},
+	})
+}
+
+// buildListArgs defines the filtering, sorting and pagination arguments
+// shared by the items and itemsPage queries.
+func (h *GraphQLHandler) buildListArgs() graphql.FieldConfigArgument {
+	sortFieldEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "ItemSortField",
+		Values: graphql.EnumValueConfigMap{
+			"CREATED_AT": &graphql.EnumValueConfig{Value: string(store.SortByCreatedAt)},
+			"NAME":       &graphql.EnumValueConfig{Value: string(store.SortByName)},
+			"PRICE":      &graphql.EnumValueConfig{Value: string(store.SortByPrice)},
+		},
+	})
+
+	sortOrderEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "SortOrder",
+		Values: graphql.EnumValueConfigMap{
+			"ASC":         &graphql.EnumValueConfig{Value: "ASC"},
+			sortOrderDesc: &graphql.EnumValueConfig{Value: sortOrderDesc},
+		},
+	})
+
+	return graphql.FieldConfigArgument{
+		argLimit:      &graphql.ArgumentConfig{Type: graphql.Int},
+		argOffset:     &graphql.ArgumentConfig{Type: graphql.Int},
+		argAfter:      &graphql.ArgumentConfig{Type: graphql.String},
+		argSortBy:     &graphql.ArgumentConfig{Type: sortFieldEnum},
+		argSortOrder:  &graphql.ArgumentConfig{Type: sortOrderEnum},
+		argNamePrefix: &graphql.ArgumentConfig{Type: graphql.String},
+		argMinPrice:   &graphql.ArgumentConfig{Type: graphql.Float},
+		argMaxPrice:   &graphql.ArgumentConfig{Type: graphql.Float},
+	}
+}
+
+// buildItemPageType defines the GraphQL ItemPage object type returned by itemsPage.
+func (h *GraphQLHandler) buildItemPageType(itemType *graphql.Object) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "ItemPage",
+		Fields: graphql.Fields{
+			"items": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(itemType))),
+			},
+			"totalCount": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+			"nextCursor": &graphql.Field{
+				Type: graphql.String,
 			},
 		},
 	})
@@ -162,15 +308,26 @@ func (h *GraphQLHandler) buildUpdateItemInput() *graphql.InputObject {
 
 // buildQueryType defines the GraphQL root query type.
 func (h *GraphQLHandler) buildQueryType(itemType *graphql.Object) *graphql.Object {
+	listArgs := h.buildListArgs()
+
 	return graphql.NewObject(graphql.ObjectConfig{
 		Name: "Query",
 		Fields: graphql.Fields{
 			"items": &graphql.Field{
-				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(itemType))),
+				Type:              graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(itemType))),
+				Args:              listArgs,
+				DeprecationReason: "Use itemsPage, which pages by default and returns the total count and next cursor.",
 				Resolve: func(p graphql.ResolveParams) (any, error) {
 					return h.resolveItems(p)
 				},
 			},
+			"itemsPage": &graphql.Field{
+				Type: graphql.NewNonNull(h.buildItemPageType(itemType)),
+				Args: listArgs,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return h.resolveItemsPage(p)
+				},
+			},
 			"item": &graphql.Field{
 				Type: itemType,
 				Args: graphql.FieldConfigArgument{
@@ -191,6 +348,7 @@ func (h *GraphQLHandler) buildMutationType(
 	itemType *graphql.Object,
 	createInput *graphql.InputObject,
 	updateInput *graphql.InputObject,
+	upsertInput *graphql.InputObject,
 ) *graphql.Object {
 	return graphql.NewObject(graphql.ObjectConfig{
 		Name: "Mutation",
@@ -215,45 +373,281 @@ func (h *GraphQLHandler) buildMutationType(
 					"input": &graphql.ArgumentConfig{
 						Type: graphql.NewNonNull(updateInput),
 					},
+					argExpectedVersion: &graphql.ArgumentConfig{
+						Type:        graphql.Int,
+						Description: "Fail with a version conflict unless the item is at this version.",
+					},
 				},
 				Resolve: func(p graphql.ResolveParams) (any, error) {
 					return h.resolveUpdateItem(p)
 				},
 			},
+			"upsertItem": &graphql.Field{
+				Type:        graphql.NewNonNull(itemType),
+				Description: "Replaces the item with the given ID, creating it if it does not exist.",
+				Args: graphql.FieldConfigArgument{
+					fieldID: &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+					"input": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(upsertInput),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return h.resolveUpsertItem(p)
+				},
+			},
 			"deleteItem": &graphql.Field{
 				Type: graphql.NewNonNull(graphql.Boolean),
 				Args: graphql.FieldConfigArgument{
 					fieldID: &graphql.ArgumentConfig{
 						Type: graphql.NewNonNull(graphql.ID),
 					},
+					argExpectedVersion: &graphql.ArgumentConfig{
+						Type:        graphql.Int,
+						Description: "Fail with a version conflict unless the item is at this version.",
+					},
 				},
 				Resolve: func(p graphql.ResolveParams) (any, error) {
 					return h.resolveDeleteItem(p)
 				},
 			},
+			"batchItems": h.buildBatchItemsField(itemType, createInput),
+		},
+	})
+}
+
+// buildBatchItemsField defines the batchItems mutation and its types.
+func (h *GraphQLHandler) buildBatchItemsField(
+	itemType *graphql.Object, createInput *graphql.InputObject,
+) *graphql.Field {
+	opTypeEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "BatchOperationType",
+		Values: graphql.EnumValueConfigMap{
+			"CREATE": &graphql.EnumValueConfig{Value: string(store.BatchCreate)},
+			"UPDATE": &graphql.EnumValueConfig{Value: string(store.BatchUpdate)},
+			"DELETE": &graphql.EnumValueConfig{Value: string(store.BatchDelete)},
+		},
+	})
+
+	modeEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "BatchMode",
+		Values: graphql.EnumValueConfigMap{
+			"ATOMIC":      &graphql.EnumValueConfig{Value: batchModeAtomic},
+			"BEST_EFFORT": &graphql.EnumValueConfig{Value: batchModeBestEffort},
+		},
+	})
+
+	operationInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "BatchOperationInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			fieldOp: &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(opTypeEnum),
+			},
+			fieldID: &graphql.InputObjectFieldConfig{
+				Type:        graphql.ID,
+				Description: "Item to update or delete.",
+			},
+			"input": &graphql.InputObjectFieldConfig{
+				Type:        createInput,
+				Description: "Full item for create and update.",
+			},
+			argExpectedVersion: &graphql.InputObjectFieldConfig{
+				Type: graphql.Int,
+			},
+		},
+	})
+
+	operationResult := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BatchOperationResult",
+		Fields: graphql.Fields{
+			"index":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			fieldOp:   &graphql.Field{Type: graphql.NewNonNull(opTypeEnum)},
+			"success": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"item":    &graphql.Field{Type: itemType},
+			"error":   &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	batchResult := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BatchResult",
+		Fields: graphql.Fields{
+			"applied": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "True when every operation succeeded.",
+			},
+			"results": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(operationResult))),
+			},
+		},
+	})
+
+	return &graphql.Field{
+		Type: graphql.NewNonNull(batchResult),
+		Args: graphql.FieldConfigArgument{
+			argOperations: &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(operationInput))),
+			},
+			argMode: &graphql.ArgumentConfig{
+				Type:         modeEnum,
+				DefaultValue: batchModeAtomic,
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			return h.resolveBatchItems(p)
+		},
+	}
+}
+
+// buildSubscriptionType defines the GraphQL root subscription type. Each field
+// emits one value per matching item event; the optional id argument restricts
+// it to a single item.
+func (h *GraphQLHandler) buildSubscriptionType(itemType *graphql.Object) *graphql.Object {
+	idFilter := graphql.FieldConfigArgument{
+		fieldID: &graphql.ArgumentConfig{
+			Type:        graphql.ID,
+			Description: "Only report events for this item.",
+		},
+	}
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"itemCreated": &graphql.Field{
+				Type: graphql.NewNonNull(itemType),
+				Subscribe: func(p graphql.ResolveParams) (any, error) {
+					return h.subscribeItemEvents(p, store.ItemCreated)
+				},
+				Resolve: resolveEventItem,
+			},
+			"itemUpdated": &graphql.Field{
+				Type: graphql.NewNonNull(itemType),
+				Args: idFilter,
+				Subscribe: func(p graphql.ResolveParams) (any, error) {
+					return h.subscribeItemEvents(p, store.ItemUpdated)
+				},
+				Resolve: resolveEventItem,
+			},
+			"itemDeleted": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.ID),
+				Description: "Reports the ID of each deleted item.",
+				Args:        idFilter,
+				Subscribe: func(p graphql.ResolveParams) (any, error) {
+					return h.subscribeItemEvents(p, store.ItemDeleted)
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if event, ok := p.Source.(store.ItemEvent); ok {
+						return event.ItemID, nil
+					}
+					return nil, nil
+				},
+			},
 		},
 	})
 }
 
-// resolveItems handles the items query by listing all items from the store.
+// resolveItems handles the deprecated items query. Without a limit argument
+// it returns every matching item, as it did before pagination was
+// introduced, so that existing clients never silently lose items.
 func (h *GraphQLHandler) resolveItems(p graphql.ResolveParams) (any, error) {
-	ctx := p.Context
+	page, err := h.listItems(p, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return itemPointers(page.Items), nil
+}
+
+// resolveItemsPage handles the itemsPage query, returning one page of items
+// together with the total count and the cursor of the next page.
+func (h *GraphQLHandler) resolveItemsPage(p graphql.ResolveParams) (any, error) {
+	page, err := h.listItems(p, DefaultPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var nextCursor any
+	if page.NextCursor != "" {
+		nextCursor = page.NextCursor
+	}
+
+	return map[string]any{
+		"items":      itemPointers(page.Items),
+		"totalCount": page.Total,
+		"nextCursor": nextCursor,
+	}, nil
+}
 
-	items, err := h.store.List(ctx)
+// listItems parses the list arguments and queries the store.
+func (h *GraphQLHandler) listItems(p graphql.ResolveParams, defaultLimit int) (*store.ListPage, error) {
+	opts, err := parseListArgs(p.Args, defaultLimit)
 	if err != nil {
+		return nil, err
+	}
+
+	page, err := h.store.List(p.Context, opts)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidListOptions) || errors.Is(err, store.ErrInvalidCursor) {
+			return nil, err
+		}
 		h.logger.Error("failed to list items via GraphQL", zap.Error(err))
 		return nil, fmt.Errorf("failed to retrieve items: %w", err)
 	}
 
-	h.logger.Debug("listed items via GraphQL", zap.Int("count", len(items)))
+	h.logger.Debug("listed items via GraphQL", zap.Int("count", len(page.Items)), zap.Int("total", page.Total))
+
+	return page, nil
+}
+
+// parseListArgs converts GraphQL list arguments into store.ListOptions.
+func parseListArgs(args map[string]any, defaultLimit int) (store.ListOptions, error) {
+	opts := store.ListOptions{Limit: defaultLimit}
+
+	if limit, ok := args[argLimit].(int); ok {
+		if limit < 1 || limit > MaxPageSize {
+			return opts, fmt.Errorf("limit must be between 1 and %d", MaxPageSize)
+		}
+		opts.Limit = limit
+	}
+
+	if offset, ok := args[argOffset].(int); ok {
+		opts.Offset = offset
+	}
+
+	if after, ok := args[argAfter].(string); ok {
+		opts.Cursor = after
+	}
 
-	// Convert to pointer slice so field resolvers receive *model.Item.
+	if sortBy, ok := args[argSortBy].(string); ok {
+		opts.SortBy = store.SortField(sortBy)
+	}
+
+	if order, ok := args[argSortOrder].(string); ok {
+		opts.Descending = order == sortOrderDesc
+	}
+
+	if prefix, ok := args[argNamePrefix].(string); ok {
+		opts.NamePrefix = prefix
+	}
+
+	if minPrice, ok := args[argMinPrice].(float64); ok {
+		opts.MinPrice = &minPrice
+	}
+
+	if maxPrice, ok := args[argMaxPrice].(float64); ok {
+		opts.MaxPrice = &maxPrice
+	}
+
+	return opts, nil
+}
+
+// itemPointers converts items to a pointer slice so field resolvers receive *model.Item.
+func itemPointers(items []model.Item) []*model.Item {
 	ptrs := make([]*model.Item, len(items))
 	for i := range items {
 		ptrs[i] = &items[i]
 	}
-
-	return ptrs, nil
+	return ptrs
 }
 
 // resolveItem handles the item query by fetching a single item from the store.
@@ -291,6 +685,12 @@ func (h *GraphQLHandler) resolveCreateItem(p graphql.ResolveParams) (any, error)
 		return nil, fmt.Errorf("validation error: %w", err)
 	}
 
+	if input.ID != "" {
+		if err := h.ids.Validate(input.ID); err != nil {
+			return nil, h.mapStoreError(err, "create item")
+		}
+	}
+
 	item, err := h.store.Create(ctx, &input)
 	if err != nil {
 		return nil, h.mapStoreError(err, "create item")
@@ -301,7 +701,9 @@ func (h *GraphQLHandler) resolveCreateItem(p graphql.ResolveParams) (any, error)
 	return item, nil
 }
 
-// resolveUpdateItem handles the updateItem mutation.
+// resolveUpdateItem handles the updateItem mutation. Only the input fields
+// that are present are changed; the update is applied as a JSON Merge Patch
+// so it shares semantics with PATCH /api/v1/items/{id}.
 func (h *GraphQLHandler) resolveUpdateItem(p graphql.ResolveParams) (any, error) {
 	ctx := p.Context
 
@@ -315,19 +717,61 @@ func (h *GraphQLHandler) resolveUpdateItem(p graphql.ResolveParams) (any, error)
 		return nil, fmt.Errorf("invalid input")
 	}
 
+	patch, err := json.Marshal(inputMap)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input")
+	}
+
+	apply, err := newPatchFunc(mediaTypeMergePatch, patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input")
+	}
+
+	item, err := patchItem(ctx, h.store, id, expectedVersionArg(p.Args), apply)
+	if err != nil {
+		if errors.Is(err, errValidation) {
+			h.logger.Warn("GraphQL updateItem validation failed", zap.String("id", id), zap.Error(err))
+			return nil, err
+		}
+		return nil, h.mapStoreError(err, "update item")
+	}
+
+	h.logger.Info("updated item via GraphQL", zap.String("id", id))
+
+	return item, nil
+}
+
+// resolveUpsertItem handles the upsertItem mutation.
+func (h *GraphQLHandler) resolveUpsertItem(p graphql.ResolveParams) (any, error) {
+	ctx := p.Context
+
+	id, ok := p.Args[fieldID].(string)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("invalid item ID")
+	}
+
+	inputMap, ok := p.Args["input"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid input")
+	}
+
 	input := h.parseItemInput(inputMap)
 
 	if err := input.Validate(); err != nil {
-		h.logger.Warn("GraphQL updateItem validation failed", zap.String("id", id), zap.Error(err))
+		h.logger.Warn("GraphQL upsertItem validation failed", zap.String("id", id), zap.Error(err))
 		return nil, fmt.Errorf("validation error: %w", err)
 	}
 
-	item, err := h.store.Update(ctx, id, &input)
+	if err := h.ids.Validate(id); err != nil {
+		return nil, h.mapStoreError(err, "upsert item")
+	}
+
+	item, created, err := h.store.Upsert(ctx, id, &input)
 	if err != nil {
-		return nil, h.mapStoreError(err, "update item")
+		return nil, h.mapStoreError(err, "upsert item")
 	}
 
-	h.logger.Info("updated item via GraphQL", zap.String("id", id))
+	h.logger.Info("upserted item via GraphQL", zap.String("id", id), zap.Bool("created", created))
 
 	return item, nil
 }
@@ -341,7 +785,7 @@ func (h *GraphQLHandler) resolveDeleteItem(p graphql.ResolveParams) (any, error)
 		return false, fmt.Errorf("invalid item ID")
 	}
 
-	if err := h.store.Delete(ctx, id); err != nil {
+	if err := h.store.Delete(ctx, id, expectedVersionArg(p.Args)); err != nil {
 		return false, h.mapStoreError(err, "delete item")
 	}
 
@@ -350,10 +794,137 @@ func (h *GraphQLHandler) resolveDeleteItem(p graphql.ResolveParams) (any, error)
 	return true, nil
 }
 
+// resolveBatchItems handles the batchItems mutation. Operation failures are
+// reported per operation; only a failure of the batch as a whole is
+// returned as a GraphQL error.
+func (h *GraphQLHandler) resolveBatchItems(p graphql.ResolveParams) (any, error) {
+	ctx := p.Context
+
+	rawOps, ok := p.Args[argOperations].([]any)
+	if !ok || len(rawOps) == 0 || len(rawOps) > MaxBatchSize {
+		return nil, fmt.Errorf("operations must contain between 1 and %d entries", MaxBatchSize)
+	}
+
+	ops := make([]store.BatchOp, len(rawOps))
+	for i, raw := range rawOps {
+		opMap, _ := raw.(map[string]any)
+		opType, _ := opMap[fieldOp].(string)
+		id, _ := opMap[fieldID].(string)
+		ops[i] = store.BatchOp{
+			Type:            store.BatchOpType(opType),
+			ID:              id,
+			ExpectedVersion: expectedVersionArg(opMap),
+		}
+		if inputMap, ok := opMap["input"].(map[string]any); ok {
+			item := h.parseItemInput(inputMap)
+			ops[i].Item = &item
+		}
+	}
+
+	mode, _ := p.Args[argMode].(string)
+	results, err := runBatch(ctx, h.store, h.ids, ops, mode != batchModeBestEffort)
+	if err != nil {
+		return nil, h.mapStoreError(err, "batch items")
+	}
+
+	applied := true
+	entries := make([]map[string]any, len(results))
+	for i, result := range results {
+		entry := map[string]any{"index": i, fieldOp: string(ops[i].Type), "success": result.Err == nil}
+		if result.Item != nil {
+			entry["item"] = result.Item
+		}
+		if result.Err != nil {
+			applied = false
+			entry["error"] = h.batchErrorMessage(result.Err)
+		}
+		entries[i] = entry
+	}
+
+	h.logger.Info("applied item batch via GraphQL",
+		zap.Int("operations", len(ops)),
+		zap.Bool("applied", applied),
+	)
+
+	return map[string]any{"applied": applied, "results": entries}, nil
+}
+
+// subscribeItemEvents starts a subscription to item events of the given type.
+// The returned channel yields store.ItemEvent values and is closed, releasing
+// the event subscription, when the subscription context is cancelled.
+func (h *GraphQLHandler) subscribeItemEvents(p graphql.ResolveParams, eventType store.ItemEventType) (any, error) {
+	if h.events == nil {
+		return nil, fmt.Errorf("item events are not available")
+	}
+
+	id, _ := p.Args[fieldID].(string)
+	events, unsubscribe := h.events.Subscribe(eventBufferSize)
+	out := make(chan any)
+
+	go func() {
+		defer close(out)
+		defer unsubscribe()
+
+		for {
+			select {
+			case <-p.Context.Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				if event.Type != eventType || (id != "" && event.ItemID != id) {
+					continue
+				}
+				select {
+				case out <- event:
+				case <-p.Context.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// resolveEventItem resolves an item subscription field from its event.
+func resolveEventItem(p graphql.ResolveParams) (any, error) {
+	if event, ok := p.Source.(store.ItemEvent); ok {
+		return event.Item, nil
+	}
+	return nil, nil
+}
+
+// batchErrorMessage describes the error of a single batch operation.
+func (h *GraphQLHandler) batchErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, store.ErrBatchAborted),
+		errors.Is(err, errValidation),
+		errors.Is(err, store.ErrInvalidBatchOp):
+		return err.Error()
+	default:
+		return h.mapStoreError(err, "batch items").Error()
+	}
+}
+
+// expectedVersionArg returns the optional expectedVersion argument, or 0
+// (unconditional) when it is absent.
+func expectedVersionArg(args map[string]any) int64 {
+	if version, ok := args[argExpectedVersion].(int); ok {
+		return int64(version)
+	}
+	return 0
+}
+
 // parseItemInput extracts item fields from a GraphQL input map.
 func (h *GraphQLHandler) parseItemInput(inputMap map[string]any) model.Item {
 	var item model.Item
 
+	if id, ok := inputMap[fieldID].(string); ok {
+		item.ID = id
+	}
+
 	if name, ok := inputMap[fieldName].(string); ok {
 		item.Name = name
 	}
@@ -378,6 +949,8 @@ func (h *GraphQLHandler) mapStoreError(err error, operation string) error {
 		return fmt.Errorf("invalid item ID")
 	case errors.Is(err, store.ErrAlreadyExists):
 		return fmt.Errorf("item already exists")
+	case errors.Is(err, store.ErrVersionConflict):
+		return fmt.Errorf("item version conflict")
 	default:
 		h.logger.Error("GraphQL store operation failed",
 			zap.String("operation", operation),
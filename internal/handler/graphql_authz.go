@@ -0,0 +1,71 @@
+// graphql_authz.go enforces the GraphQL field rules of the authorization
+// policy by wrapping the resolvers of the schema's object types.
+
+package handler
+
+import (
+	"errors"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/authz"
+)
+
+// gqlErrorCodeForbidden is the extensions code of denied GraphQL fields.
+const gqlErrorCodeForbidden = "FORBIDDEN"
+
+// authorizeFields wraps the resolvers of every field of obj with a check of
+// the handler's authorizer. Subscription fields are checked once when the
+// subscription starts rather than for every event.
+func (h *GraphQLHandler) authorizeFields(obj *graphql.Object) {
+	typeName := obj.Name()
+	for fieldName, field := range obj.Fields() {
+		if field.Subscribe != nil {
+			field.Subscribe = h.authorizeField(typeName, fieldName, field.Subscribe)
+			continue
+		}
+
+		resolve := field.Resolve
+		if resolve == nil {
+			resolve = graphql.DefaultResolveFn
+		}
+		field.Resolve = h.authorizeField(typeName, fieldName, resolve)
+	}
+}
+
+// authorizeField returns a resolver that calls next only when the caller may
+// resolve typeName.fieldName.
+func (h *GraphQLHandler) authorizeField(
+	typeName, fieldName string, next graphql.FieldResolveFn,
+) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		if h.authorizer == nil {
+			return next(p)
+		}
+
+		info, _ := auth.FromContext(p.Context)
+		if err := h.authorizer.AuthorizeField(info, typeName, fieldName); err != nil {
+			var denied *authz.ForbiddenError
+			if errors.As(err, &denied) {
+				return nil, forbiddenFieldError{denied}
+			}
+			return nil, err
+		}
+		return next(p)
+	}
+}
+
+// forbiddenFieldError reports a denied field with a FORBIDDEN extensions code
+// and the roles that would have been allowed.
+type forbiddenFieldError struct {
+	*authz.ForbiddenError
+}
+
+// Extensions implements gqlerrors.ExtendedError.
+func (e forbiddenFieldError) Extensions() map[string]any {
+	return map[string]any{
+		"code":          gqlErrorCodeForbidden,
+		"requiredRoles": e.RequiredRoles,
+	}
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/authz"
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+	"github.com/vyrodovalexey/restapi-example/internal/store"
+)
+
+// newTestGraphQLAuthorizer returns an authorizer where API key "reader" may
+// query, "writer" may also mutate and only "admin" may delete or see prices.
+func newTestGraphQLAuthorizer(t *testing.T) *authz.Authorizer {
+	t.Helper()
+
+	authorizer, err := authz.NewAuthorizer(&authz.Policy{
+		Bindings: authz.Bindings{
+			APIKeys: map[string][]string{
+				"reader": {"reader"},
+				"writer": {"writer"},
+				"admin":  {"admin", "writer"},
+			},
+		},
+		GraphQL: map[string][]string{
+			"Mutation.*":          {"writer"},
+			"Mutation.deleteItem": {"admin"},
+			"Subscription.*":      {"writer"},
+			"Item.price":          {"admin"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error = %v", err)
+	}
+	return authorizer
+}
+
+func TestGraphQLHandler_FieldAuthorization(t *testing.T) {
+	tests := []struct {
+		name          string
+		subject       string
+		query         string
+		wantForbidden bool
+	}{
+		{name: "reader queries", subject: "reader", query: `{ items { id name } }`},
+		{name: "reader cannot see price", subject: "reader", query: `{ items { id price } }`, wantForbidden: true},
+		{name: "admin sees price", subject: "admin", query: `{ items { id price } }`},
+		{
+			name: "reader cannot create", subject: "reader",
+			query:         `mutation { createItem(input: {name: "x", price: 1}) { id } }`,
+			wantForbidden: true,
+		},
+		{name: "writer creates", subject: "writer", query: `mutation { createItem(input: {name: "x", price: 1}) { id } }`},
+		{name: "writer cannot delete", subject: "writer", query: `mutation { deleteItem(id: "1") }`, wantForbidden: true},
+		{name: "admin deletes", subject: "admin", query: `mutation { deleteItem(id: "1") }`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ms := newMockStore()
+			ms.items["1"] = model.Item{ID: "1", Name: "Widget", Price: 10}
+			h := NewGraphQLHandler(ms, zap.NewNop())
+			h.SetAuthorizer(newTestGraphQLAuthorizer(t))
+			router := mux.NewRouter()
+			h.RegisterRoutes(router)
+
+			info := &auth.AuthInfo{Method: auth.AuthMethodAPIKey, Subject: tt.subject}
+			req := graphqlRequest(tt.query)
+			req = req.WithContext(auth.WithAuthInfo(req.Context(), info))
+			rr := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rr, req)
+
+			// Assert
+			if rr.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+			}
+
+			var resp struct {
+				Errors []struct {
+					Message    string         `json:"message"`
+					Extensions map[string]any `json:"extensions"`
+				} `json:"errors"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			if !tt.wantForbidden {
+				if len(resp.Errors) > 0 {
+					t.Fatalf("unexpected errors: %v", resp.Errors)
+				}
+				return
+			}
+			if len(resp.Errors) == 0 {
+				t.Fatal("expected a forbidden error, got none")
+			}
+			if code := resp.Errors[0].Extensions["code"]; code != gqlErrorCodeForbidden {
+				t.Errorf("extensions.code = %v, want %s (message %q)", code, gqlErrorCodeForbidden, resp.Errors[0].Message)
+			}
+		})
+	}
+}
+
+// startAuthzSubscriptionServer serves the GraphQL routes with in-band
+// authentication of apiKeyTestAuthenticator, whose "client" subject is bound
+// to the reader role, and the given route and field rules.
+func startAuthzSubscriptionServer(t *testing.T, routeRoles []string, fieldRules map[string][]string) string {
+	t.Helper()
+
+	authorizer, err := authz.NewAuthorizer(&authz.Policy{
+		Bindings: authz.Bindings{APIKeys: map[string][]string{"client": {"reader"}}},
+		Routes:   []authz.RouteRule{{Path: "/graphql", Roles: routeRoles}},
+		GraphQL:  fieldRules,
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error = %v", err)
+	}
+
+	events := store.NewEventStore(store.NewMemoryStore())
+	h := NewGraphQLHandler(events, zap.NewNop(), events)
+	h.SetAuthenticator(apiKeyTestAuthenticator{})
+	h.SetAuthorizer(authorizer)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(func() {
+		h.CloseSubscriptions()
+		server.Close()
+	})
+
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/graphql"
+}
+
+func TestGraphQLSubscriptions_Authorization(t *testing.T) {
+	// Arrange
+	url := startAuthzSubscriptionServer(t, []string{"reader"}, map[string][]string{"Subscription.*": {"writer"}})
+	conn := dialSubscriptions(t, url, nil)
+	initSubscriptions(t, conn, map[string]any{"X-API-Key": "secret"})
+
+	// Act
+	sendGQLWS(t, conn, gqlwsSubscribe, "1", gqlwsSubscribePayload{Query: `subscription { itemCreated { id } }`})
+
+	// Assert
+	msg := readGQLWS(t, conn)
+	if msg.Type != gqlwsError || msg.ID != "1" {
+		t.Fatalf("message = %+v, want error for operation 1", msg)
+	}
+	// graphql-go drops the extensions of errors returned by Subscribe.
+	if !strings.Contains(string(msg.Payload), "forbidden: Subscription.itemCreated") {
+		t.Errorf("payload = %s, want forbidden error", msg.Payload)
+	}
+}
+
+func TestGraphQLSubscriptions_InBandAuthorizationDenied(t *testing.T) {
+	// Arrange
+	url := startAuthzSubscriptionServer(t, []string{"writer"}, nil)
+	conn := dialSubscriptions(t, url, nil)
+
+	// Act
+	sendGQLWS(t, conn, gqlwsConnectionInit, "", map[string]any{"X-API-Key": "secret"})
+
+	// Assert
+	expectCloseCode(t, conn, gqlwsCloseForbidden)
+}
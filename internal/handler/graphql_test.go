@@ -232,6 +232,164 @@ func TestGraphQLHandler_QueryItems_StoreError(t *testing.T) {
 	}
 }
 
+func TestGraphQLHandler_QueryItems_ListArguments(t *testing.T) {
+	// Arrange
+	ms := newMockStore()
+	router := setupGraphQLRouter(ms)
+
+	query := `{ items(limit: 5, after: "abc", sortBy: PRICE, sortOrder: DESC,
+		namePrefix: "Wid", minPrice: 1.5, maxPrice: 20) { id } }`
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, graphqlRequest(query))
+
+	// Assert
+	var resp graphqlResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("QueryItems() unexpected errors: %v", resp.Errors)
+	}
+
+	got := ms.listOpts
+	if got.Limit != 5 || got.Cursor != "abc" || got.SortBy != store.SortByPrice || !got.Descending ||
+		got.NamePrefix != "Wid" {
+		t.Errorf("ListOptions = %+v, want limit=5 cursor=abc sort=price desc prefix=Wid", got)
+	}
+	if got.MinPrice == nil || *got.MinPrice != 1.5 || got.MaxPrice == nil || *got.MaxPrice != 20 {
+		t.Errorf("price range = (%v, %v), want (1.5, 20)", got.MinPrice, got.MaxPrice)
+	}
+}
+
+func TestGraphQLHandler_QueryItems_NoLimitByDefault(t *testing.T) {
+	// Arrange
+	ms := newMockStore()
+	router := setupGraphQLRouter(ms)
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, graphqlRequest(`{ items { id } }`))
+
+	// Assert
+	if ms.listOpts.Limit != 0 {
+		t.Errorf("items Limit = %d, want 0 (unbounded)", ms.listOpts.Limit)
+	}
+}
+
+func TestGraphQLHandler_QueryItems_Deprecated(t *testing.T) {
+	// Arrange
+	router := setupGraphQLRouter(newMockStore())
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, graphqlRequest(
+		`{ __type(name: "Query") { fields(includeDeprecated: true) { name isDeprecated } } }`))
+
+	// Assert
+	var resp struct {
+		Data struct {
+			Type struct {
+				Fields []struct {
+					Name         string `json:"name"`
+					IsDeprecated bool   `json:"isDeprecated"`
+				} `json:"fields"`
+			} `json:"__type"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	deprecated := map[string]bool{}
+	for _, field := range resp.Data.Type.Fields {
+		deprecated[field.Name] = field.IsDeprecated
+	}
+	if !deprecated["items"] || deprecated["itemsPage"] {
+		t.Errorf("deprecated fields = %v, want items deprecated and itemsPage not", deprecated)
+	}
+}
+
+func TestGraphQLHandler_QueryItemsPage(t *testing.T) {
+	// Arrange
+	ms := newMockStore()
+	ms.items["1"] = model.Item{ID: "1", Name: "Widget", Price: 10}
+	ms.nextCursor = "next-page"
+	router := setupGraphQLRouter(ms)
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, graphqlRequest(`{ itemsPage(offset: 2) { items { id } totalCount nextCursor } }`))
+
+	// Assert
+	var resp graphqlResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("QueryItemsPage() unexpected errors: %v", resp.Errors)
+	}
+
+	var data struct {
+		ItemsPage struct {
+			Items      []struct{ ID string } `json:"items"`
+			TotalCount int                   `json:"totalCount"`
+			NextCursor *string               `json:"nextCursor"`
+		} `json:"itemsPage"`
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+
+	if len(data.ItemsPage.Items) != 1 || data.ItemsPage.TotalCount != 1 {
+		t.Errorf("itemsPage = %+v, want one item and totalCount 1", data.ItemsPage)
+	}
+	if data.ItemsPage.NextCursor == nil || *data.ItemsPage.NextCursor != "next-page" {
+		t.Errorf("nextCursor = %v, want next-page", data.ItemsPage.NextCursor)
+	}
+	if ms.listOpts.Limit != DefaultPageSize || ms.listOpts.Offset != 2 {
+		t.Errorf("ListOptions = %+v, want default limit and offset 2", ms.listOpts)
+	}
+}
+
+func TestGraphQLHandler_QueryItems_InvalidArguments(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		listErr error
+		wantMsg string
+	}{
+		{name: "zero limit", query: `{ items(limit: 0) { id } }`, wantMsg: "limit must be between"},
+		{name: "limit above maximum", query: `{ itemsPage(limit: 5000) { totalCount } }`, wantMsg: "limit must be between"},
+		{
+			name: "invalid cursor", query: `{ itemsPage(after: "bogus") { totalCount } }`,
+			listErr: store.ErrInvalidCursor, wantMsg: "invalid pagination cursor",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ms := newMockStore()
+			ms.listErr = tt.listErr
+			router := setupGraphQLRouter(ms)
+			rr := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rr, graphqlRequest(tt.query))
+
+			// Assert
+			var resp graphqlResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, tt.wantMsg) {
+				t.Errorf("errors = %v, want message containing %q", resp.Errors, tt.wantMsg)
+			}
+		})
+	}
+}
+
 func TestGraphQLHandler_QueryItem_Exists(t *testing.T) {
 	// Arrange
 	ms := newMockStore()
@@ -552,6 +710,115 @@ func TestGraphQLHandler_CreateItem_StoreError(t *testing.T) {
 	}
 }
 
+func TestGraphQLHandler_CreateItem_WithID(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		taken   bool
+		wantErr string
+	}{
+		{name: "client ID", id: testUUID},
+		{name: "client ID taken", id: testUUID, taken: true, wantErr: "item already exists"},
+		{name: "invalid client ID", id: "sku-1", wantErr: "invalid item ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ms := newMockStore()
+			if tt.taken {
+				ms.items[tt.id] = model.Item{ID: tt.id, Name: "Existing", Version: 1}
+			}
+			router := setupGraphQLRouter(ms)
+			query := `mutation { createItem(input: {id: "` + tt.id + `", name: "New", price: 1.0}) { id } }`
+			rr := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rr, graphqlRequest(query))
+
+			// Assert
+			var resp graphqlResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if tt.wantErr != "" {
+				if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, tt.wantErr) {
+					t.Errorf("CreateItem() errors = %v, want %q", resp.Errors, tt.wantErr)
+				}
+				return
+			}
+			if len(resp.Errors) > 0 {
+				t.Fatalf("CreateItem() unexpected errors: %v", resp.Errors)
+			}
+			if stored, exists := ms.items[tt.id]; !exists || stored.Name != "New" {
+				t.Errorf("stored item = %+v, want the new item under %s", stored, tt.id)
+			}
+		})
+	}
+}
+
+func TestGraphQLHandler_UpsertItem(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          string
+		input       string
+		existing    bool
+		wantErr     string
+		wantVersion int
+	}{
+		{name: "creates missing item", id: testUUID, input: `{name: "Upserted", price: 2.0}`, wantVersion: 1},
+		{name: "replaces existing item", id: testUUID, input: `{name: "Upserted", price: 2.0}`, existing: true,
+			wantVersion: 4},
+		{name: "invalid ID", id: "sku-1", input: `{name: "Upserted", price: 2.0}`, wantErr: "invalid item ID"},
+		{name: "invalid input", id: testUUID, input: `{name: "", price: 2.0}`, wantErr: "validation error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ms := newMockStore()
+			if tt.existing {
+				ms.items[tt.id] = model.Item{ID: tt.id, Name: "Item", Price: 1, Version: 3}
+			}
+			router := setupGraphQLRouter(ms)
+			query := `mutation { upsertItem(id: "` + tt.id + `", input: ` + tt.input + `) { id name version } }`
+			rr := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rr, graphqlRequest(query))
+
+			// Assert
+			var resp graphqlResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if tt.wantErr != "" {
+				if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, tt.wantErr) {
+					t.Errorf("UpsertItem() errors = %v, want %q", resp.Errors, tt.wantErr)
+				}
+				return
+			}
+			if len(resp.Errors) > 0 {
+				t.Fatalf("UpsertItem() unexpected errors: %v", resp.Errors)
+			}
+
+			var data struct {
+				UpsertItem struct {
+					ID      string `json:"id"`
+					Name    string `json:"name"`
+					Version int    `json:"version"`
+				} `json:"upsertItem"`
+			}
+			if err := json.Unmarshal(resp.Data, &data); err != nil {
+				t.Fatalf("Failed to unmarshal data: %v", err)
+			}
+			if data.UpsertItem.ID != tt.id || data.UpsertItem.Name != "Upserted" || data.UpsertItem.Version != tt.wantVersion {
+				t.Errorf("UpsertItem() = %+v, want %s at version %d", data.UpsertItem, tt.id, tt.wantVersion)
+			}
+		})
+	}
+}
+
 func TestGraphQLHandler_UpdateItem_Valid(t *testing.T) {
 	// Arrange
 	ms := newMockStore()
@@ -614,6 +881,132 @@ func TestGraphQLHandler_UpdateItem_Valid(t *testing.T) {
 	}
 }
 
+func TestGraphQLHandler_UpdateItem_ExpectedVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     string
+		wantErr     string
+		wantVersion int
+	}{
+		{name: "current version", version: "3", wantVersion: 4},
+		{name: "stale version", version: "2", wantErr: "item version conflict"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ms := newMockStore()
+			ms.items["123"] = model.Item{ID: "123", Name: "Item", Price: 1, Version: 3}
+			router := setupGraphQLRouter(ms)
+
+			query := `mutation { updateItem(id: "123", input: {name: "Renamed", price: 2.0}, expectedVersion: ` +
+				tt.version + `) { id version } }`
+			rr := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rr, graphqlRequest(query))
+
+			// Assert
+			var resp graphqlResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			if tt.wantErr != "" {
+				if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, tt.wantErr) {
+					t.Errorf("UpdateItem() errors = %v, want %q", resp.Errors, tt.wantErr)
+				}
+				return
+			}
+			if len(resp.Errors) > 0 {
+				t.Fatalf("UpdateItem() unexpected errors: %v", resp.Errors)
+			}
+
+			var data struct {
+				UpdateItem struct {
+					Version int `json:"version"`
+				} `json:"updateItem"`
+			}
+			if err := json.Unmarshal(resp.Data, &data); err != nil {
+				t.Fatalf("Failed to unmarshal data: %v", err)
+			}
+			if data.UpdateItem.Version != tt.wantVersion {
+				t.Errorf("UpdateItem() version = %d, want %d", data.UpdateItem.Version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestGraphQLHandler_DeleteItem_ExpectedVersion(t *testing.T) {
+	// Arrange
+	ms := newMockStore()
+	ms.items["123"] = model.Item{ID: "123", Name: "Item", Version: 3}
+	router := setupGraphQLRouter(ms)
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, graphqlRequest(`mutation { deleteItem(id: "123", expectedVersion: 1) }`))
+
+	// Assert
+	var resp graphqlResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, "item version conflict") {
+		t.Errorf("DeleteItem() errors = %v, want version conflict", resp.Errors)
+	}
+	if ms.gotVersion != 1 {
+		t.Errorf("expectedVersion = %d, want 1", ms.gotVersion)
+	}
+	if _, exists := ms.items["123"]; !exists {
+		t.Error("item should not be deleted on version conflict")
+	}
+}
+
+func TestGraphQLHandler_UpdateItem_Partial(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantName string
+		wantDesc string
+		wantCost float64
+	}{
+		{name: "price only", input: `{price: 5.5}`, wantName: "Item", wantDesc: "Keep me", wantCost: 5.5},
+		{name: "name only", input: `{name: "Renamed"}`, wantName: "Renamed", wantDesc: "Keep me", wantCost: 1},
+		{name: "clear description", input: `{description: ""}`, wantName: "Item", wantDesc: "", wantCost: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ms := newMockStore()
+			ms.items["123"] = model.Item{ID: "123", Name: "Item", Description: "Keep me", Price: 1, Version: 1}
+			router := setupGraphQLRouter(ms)
+
+			query := `mutation { updateItem(id: "123", input: ` + tt.input + `) { name description price } }`
+			rr := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rr, graphqlRequest(query))
+
+			// Assert
+			var resp graphqlResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(resp.Errors) > 0 {
+				t.Fatalf("UpdateItem() unexpected errors: %v", resp.Errors)
+			}
+
+			stored := ms.items["123"]
+			if stored.Name != tt.wantName || stored.Description != tt.wantDesc || stored.Price != tt.wantCost {
+				t.Errorf("stored item = %+v, want name %q description %q price %v",
+					stored, tt.wantName, tt.wantDesc, tt.wantCost)
+			}
+		})
+	}
+}
+
 func TestGraphQLHandler_UpdateItem_NotFound(t *testing.T) {
 	// Arrange
 	ms := newMockStore()
@@ -849,6 +1242,112 @@ func TestGraphQLHandler_DeleteItem_StoreError(t *testing.T) {
 
 // --- Integration-style Tests ---
 
+func TestGraphQLHandler_BatchItems(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		wantApplied bool
+		wantSuccess []bool
+		wantError   []string
+		wantItems   int
+	}{
+		{
+			name:        "atomic by default",
+			wantSuccess: []bool{false, false, false},
+			wantError:   []string{"batch aborted", "validation error", "batch aborted"},
+			wantItems:   1,
+		},
+		{
+			name:        "best effort",
+			mode:        ", mode: BEST_EFFORT",
+			wantSuccess: []bool{true, false, true},
+			wantError:   []string{"", "validation error", ""},
+			wantItems:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ms := newMockStore()
+			ms.items["123"] = model.Item{ID: "123", Name: "Item", Price: 10, Version: 1}
+			router := setupGraphQLRouter(ms)
+			rr := httptest.NewRecorder()
+			query := `mutation { batchItems(operations: [
+				{op: CREATE, input: {name: "New", price: 1}},
+				{op: CREATE, input: {name: "", price: 1}},
+				{op: DELETE, id: "123", expectedVersion: 1}
+			]` + tt.mode + `) { applied results { index op success error item { id name } } } }`
+
+			// Act
+			router.ServeHTTP(rr, graphqlRequest(query))
+
+			// Assert
+			var resp graphqlResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(resp.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", resp.Errors)
+			}
+			var data struct {
+				BatchItems struct {
+					Applied bool `json:"applied"`
+					Results []struct {
+						Index   int         `json:"index"`
+						Op      string      `json:"op"`
+						Success bool        `json:"success"`
+						Error   *string     `json:"error"`
+						Item    *model.Item `json:"item"`
+					} `json:"results"`
+				} `json:"batchItems"`
+			}
+			if err := json.Unmarshal(resp.Data, &data); err != nil {
+				t.Fatalf("Failed to decode data: %v", err)
+			}
+			if data.BatchItems.Applied != tt.wantApplied {
+				t.Errorf("applied = %v, want %v", data.BatchItems.Applied, tt.wantApplied)
+			}
+			for i, result := range data.BatchItems.Results {
+				if result.Index != i || result.Success != tt.wantSuccess[i] {
+					t.Errorf("results[%d] = %+v, want success %v", i, result, tt.wantSuccess[i])
+				}
+				gotError := ""
+				if result.Error != nil {
+					gotError = *result.Error
+				}
+				if !strings.HasPrefix(gotError, tt.wantError[i]) || (tt.wantError[i] == "") != (gotError == "") {
+					t.Errorf("results[%d].error = %q, want prefix %q", i, gotError, tt.wantError[i])
+				}
+			}
+			if data.BatchItems.Results[0].Op != "CREATE" {
+				t.Errorf("results[0].op = %q, want CREATE", data.BatchItems.Results[0].Op)
+			}
+			if len(ms.items) != tt.wantItems {
+				t.Errorf("item count = %d, want %d", len(ms.items), tt.wantItems)
+			}
+		})
+	}
+}
+
+func TestGraphQLHandler_BatchItems_Empty(t *testing.T) {
+	// Arrange
+	router := setupGraphQLRouter(newMockStore())
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, graphqlRequest(`mutation { batchItems(operations: []) { applied } }`))
+
+	// Assert
+	var resp graphqlResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, "operations must contain") {
+		t.Errorf("BatchItems() errors = %v, want operations error", resp.Errors)
+	}
+}
+
 func TestGraphQLHandler_GraphiQLPlayground(t *testing.T) {
 	// Arrange
 	ms := newMockStore()
@@ -975,6 +1474,7 @@ func TestGraphQLHandler_CreateItem_AlreadyExists(t *testing.T) {
 func TestGraphQLHandler_UpdateItem_InvalidID(t *testing.T) {
 	// Arrange
 	ms := newMockStore()
+	ms.items["invalid"] = model.Item{ID: "invalid", Name: "Existing", Price: 1, Version: 1}
 	ms.updateErr = store.ErrInvalidID
 	router := setupGraphQLRouter(ms)
 
@@ -1014,6 +1514,7 @@ func TestGraphQLHandler_UpdateItem_InvalidID(t *testing.T) {
 func TestGraphQLHandler_UpdateItem_StoreError(t *testing.T) {
 	// Arrange
 	ms := newMockStore()
+	ms.items["123"] = model.Item{ID: "123", Name: "Existing", Price: 1, Version: 1}
 	ms.updateErr = errors.New("database update failed")
 	router := setupGraphQLRouter(ms)
 
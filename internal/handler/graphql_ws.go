@@ -0,0 +1,561 @@
+// graphql_ws.go implements the graphql-transport-ws WebSocket subprotocol used
+// by Apollo and urql clients to run GraphQL subscriptions on /graphql.
+//
+// Clients authenticate with the upgrade request (see wsauth.go) or, because
+// browsers cannot set headers on WebSocket requests, with the connection_init
+// payload: its string values are applied as request headers, e.g.
+// {"Authorization": "Bearer <token>"}.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"go.uber.org/zap"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/authz"
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+)
+
+// graphqlTransportWSProtocol is the WebSocket subprotocol spoken on /graphql.
+const graphqlTransportWSProtocol = "graphql-transport-ws"
+
+// graphql-transport-ws message types.
+const (
+	gqlwsConnectionInit = "connection_init"
+	gqlwsConnectionAck  = "connection_ack"
+	gqlwsPing           = "ping"
+	gqlwsPong           = "pong"
+	gqlwsSubscribe      = "subscribe"
+	gqlwsNext           = "next"
+	gqlwsError          = "error"
+	gqlwsComplete       = "complete"
+)
+
+// graphql-transport-ws close codes.
+const (
+	gqlwsCloseInvalidMessage   = 4400
+	gqlwsCloseUnauthorized     = 4401
+	gqlwsCloseForbidden        = 4403
+	gqlwsCloseInitTimeout      = 4408
+	gqlwsCloseSubscriberExists = 4409
+	gqlwsCloseTooManyInit      = 4429
+)
+
+// Subscription transport configuration constants.
+const (
+	connectionInitWait      = 10 * time.Second // time allowed for connection_init
+	maxGraphQLWSMessageSize = 64 << 10         // fits a subscribe message with its query
+)
+
+// gqlwsMessage is a graphql-transport-ws protocol message.
+type gqlwsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// gqlwsSubscribePayload is the payload of a subscribe message.
+type gqlwsSubscribePayload struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// subscriptionConn holds the state of one graphql-transport-ws connection.
+type subscriptionConn struct {
+	conn    *websocket.Conn
+	request *http.Request // copy of the upgrade request
+	ctx     context.Context
+	cancel  context.CancelFunc
+	writeMu sync.Mutex // serializes all writes to this connection
+
+	mu           sync.Mutex
+	initReceived bool
+	acked        bool
+	authInfo     *auth.AuthInfo
+	expiry       *time.Timer // closes the connection when authInfo expires
+	operations   map[string]context.CancelFunc
+	operationWG  sync.WaitGroup
+}
+
+// authenticate records the connection's identity and arranges for the
+// connection to be closed when its credentials expire.
+func (c *subscriptionConn) authenticate(info *auth.AuthInfo, onExpiry func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.authInfo = info
+	c.expiry = afterExpiry(info, onExpiry)
+}
+
+// isAcked reports whether the connection has been acknowledged.
+func (c *subscriptionConn) isAcked() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.acked
+}
+
+// startOperation registers operation id and returns its context, which
+// carries the connection's identity. It reports false when id is in use.
+func (c *subscriptionConn) startOperation(id string) (context.Context, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.operations[id]; exists {
+		return nil, false
+	}
+
+	ctx := c.ctx
+	if c.authInfo != nil {
+		ctx = auth.WithAuthInfo(ctx, c.authInfo)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.operations[id] = cancel
+	c.operationWG.Add(1)
+
+	return ctx, true
+}
+
+// stopOperation cancels operation id on behalf of the client.
+func (c *subscriptionConn) stopOperation(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cancel, exists := c.operations[id]; exists {
+		cancel()
+		delete(c.operations, id)
+	}
+}
+
+// finishOperation unregisters operation id once it has ended. It reports
+// whether the operation was still registered, i.e. not stopped by the client.
+func (c *subscriptionConn) finishOperation(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cancel, exists := c.operations[id]
+	if exists {
+		cancel()
+		delete(c.operations, id)
+	}
+	return exists
+}
+
+// SetAuthenticator sets the authenticator for subscription connections. It
+// must be called before the handler serves requests; without it,
+// subscriptions are not authenticated.
+func (h *GraphQLHandler) SetAuthenticator(authenticator auth.Authenticator) {
+	h.authenticator = authenticator
+}
+
+// SetAuthorizer sets the authorizer for the GraphQL field rules, which apply
+// to every operation, and for the route rules of subscription connections
+// that authenticate in connection_init. It must be called before the handler
+// serves requests.
+func (h *GraphQLHandler) SetAuthorizer(authorizer *authz.Authorizer) {
+	h.authorizer = authorizer
+}
+
+// SetCheckOrigin sets the function deciding whether a subscription upgrade
+// request from its Origin is accepted. It must be called before the handler
+// serves requests; without it, all origins are accepted.
+func (h *GraphQLHandler) SetCheckOrigin(check func(r *http.Request) bool) {
+	h.upgrader.CheckOrigin = check
+}
+
+// HandleSubscriptions upgrades a /graphql request to a graphql-transport-ws
+// connection. Unless middleware.Auth authenticated the upgrade request, a
+// configured authenticator is applied to the connection_init payload.
+//
+//nolint:contextcheck // intentional: WebSocket connections outlive the HTTP request context
+func (h *GraphQLHandler) HandleSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if !slices.Contains(websocket.Subprotocols(r), graphqlTransportWSProtocol) {
+		h.writeError(w, http.StatusBadRequest, "unsupported WebSocket subprotocol, expected "+graphqlTransportWSProtocol)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.logger.Error("failed to upgrade GraphQL subscription connection", zap.Error(err))
+		return
+	}
+
+	// Use a background context: the request context is cancelled when this
+	// handler returns, but the connection persists beyond the upgrade.
+	ctx, cancel := context.WithCancel(context.Background())
+	sc := &subscriptionConn{
+		conn:       conn,
+		request:    r.Clone(ctx),
+		ctx:        ctx,
+		cancel:     cancel,
+		operations: make(map[string]context.CancelFunc),
+	}
+	if info, ok := auth.FromContext(r.Context()); ok {
+		sc.authenticate(info, h.expireSubscriptionConn(sc))
+	}
+
+	h.mu.Lock()
+	h.conns[conn] = sc
+	h.mu.Unlock()
+
+	observability.WebSocketActiveConnections.Inc()
+
+	h.logger.Info("GraphQL subscription client connected", zap.String("remote_addr", conn.RemoteAddr().String()))
+
+	h.wg.Add(1)
+	go h.serveSubscriptionConn(sc)
+}
+
+// serveSubscriptionConn reads and dispatches protocol messages until the
+// connection is closed.
+func (h *GraphQLHandler) serveSubscriptionConn(sc *subscriptionConn) {
+	defer h.wg.Done()
+	defer h.releaseSubscriptionConn(sc)
+
+	conn := sc.conn
+	conn.SetReadLimit(maxGraphQLWSMessageSize)
+	if err := conn.SetReadDeadline(time.Now().Add(pongWait)); err != nil {
+		h.logger.Error("failed to set read deadline", zap.Error(err))
+		return
+	}
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	initTimer := time.AfterFunc(connectionInitWait, func() {
+		if !sc.isAcked() {
+			h.closeSubscriptionConn(sc, gqlwsCloseInitTimeout, "Connection initialisation timeout")
+		}
+	})
+	defer initTimer.Stop()
+
+	go h.keepAlive(sc)
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				h.logger.Warn("GraphQL subscription read error", zap.Error(err))
+			}
+			return
+		}
+		if !h.handleSubscriptionMessage(sc, data) {
+			return
+		}
+	}
+}
+
+// keepAlive pings the client until the connection context is cancelled.
+func (h *GraphQLHandler) keepAlive(sc *subscriptionConn) {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sc.ctx.Done():
+			return
+		case <-ticker.C:
+			sc.writeMu.Lock()
+			err := sc.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err == nil {
+				err = sc.conn.WriteMessage(websocket.PingMessage, nil)
+			}
+			sc.writeMu.Unlock()
+			if err != nil {
+				h.logger.Debug("failed to send ping", zap.Error(err))
+				return
+			}
+		}
+	}
+}
+
+// handleSubscriptionMessage processes one client message. It returns false
+// when the connection must be closed.
+func (h *GraphQLHandler) handleSubscriptionMessage(sc *subscriptionConn, data []byte) bool {
+	var msg gqlwsMessage
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Type == "" {
+		h.closeSubscriptionConn(sc, gqlwsCloseInvalidMessage, "Invalid message received")
+		return false
+	}
+
+	switch msg.Type {
+	case gqlwsConnectionInit:
+		return h.handleConnectionInit(sc, msg.Payload)
+	case gqlwsPing:
+		return h.sendSubscriptionMessage(sc, gqlwsPong, "", nil) == nil
+	case gqlwsPong:
+		return true
+	case gqlwsSubscribe:
+		return h.handleSubscribe(sc, &msg)
+	case gqlwsComplete:
+		sc.stopOperation(msg.ID)
+		return true
+	default:
+		h.closeSubscriptionConn(sc, gqlwsCloseInvalidMessage, "Invalid message received")
+		return false
+	}
+}
+
+// handleConnectionInit authenticates and authorizes the connection, unless
+// the upgrade request already did, and acknowledges it.
+func (h *GraphQLHandler) handleConnectionInit(sc *subscriptionConn, payload json.RawMessage) bool {
+	sc.mu.Lock()
+	repeated := sc.initReceived
+	sc.initReceived = true
+	authInfo := sc.authInfo
+	sc.mu.Unlock()
+
+	if repeated {
+		h.closeSubscriptionConn(sc, gqlwsCloseTooManyInit, "Too many initialisation requests")
+		return false
+	}
+
+	if h.authenticator != nil && authInfo == nil {
+		headers, err := connectionInitHeaders(payload)
+		if err != nil {
+			h.closeSubscriptionConn(sc, gqlwsCloseInvalidMessage, "Invalid message received")
+			return false
+		}
+
+		info, err := authenticateInBand(h.authenticator, h.logger, sc.request, headers)
+		if err != nil {
+			h.closeSubscriptionConn(sc, gqlwsCloseForbidden, "Forbidden")
+			return false
+		}
+		if err := authorizeInBand(h.authorizer, h.logger, sc.request, info); err != nil {
+			h.closeSubscriptionConn(sc, gqlwsCloseForbidden, "Forbidden")
+			return false
+		}
+		sc.authenticate(info, h.expireSubscriptionConn(sc))
+	}
+
+	sc.mu.Lock()
+	sc.acked = true
+	sc.mu.Unlock()
+
+	return h.sendSubscriptionMessage(sc, gqlwsConnectionAck, "", nil) == nil
+}
+
+// connectionInitHeaders returns the string values of a connection_init
+// payload, which are applied as request headers for authentication.
+func connectionInitHeaders(payload json.RawMessage) (map[string]string, error) {
+	var params map[string]any
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &params); err != nil {
+			return nil, fmt.Errorf("decoding connection_init payload: %w", err)
+		}
+	}
+
+	headers := make(map[string]string, len(params))
+	for name, value := range params {
+		if s, ok := value.(string); ok {
+			headers[name] = s
+		}
+	}
+
+	return headers, nil
+}
+
+// expireSubscriptionConn returns a function that closes sc because its
+// credentials expired.
+func (h *GraphQLHandler) expireSubscriptionConn(sc *subscriptionConn) func() {
+	return func() {
+		h.logger.Info("closing GraphQL subscription connection: credentials expired")
+		h.closeSubscriptionConn(sc, gqlwsCloseForbidden, "Token expired")
+	}
+}
+
+// handleSubscribe starts the operation requested by a subscribe message.
+func (h *GraphQLHandler) handleSubscribe(sc *subscriptionConn, msg *gqlwsMessage) bool {
+	if !sc.isAcked() {
+		h.closeSubscriptionConn(sc, gqlwsCloseUnauthorized, "Unauthorized")
+		return false
+	}
+
+	var payload gqlwsSubscribePayload
+	if msg.ID == "" || json.Unmarshal(msg.Payload, &payload) != nil || payload.Query == "" {
+		h.closeSubscriptionConn(sc, gqlwsCloseInvalidMessage, "Invalid message received")
+		return false
+	}
+
+	ctx, ok := sc.startOperation(msg.ID)
+	if !ok {
+		h.closeSubscriptionConn(sc, gqlwsCloseSubscriberExists,
+			fmt.Sprintf("Subscriber for %s already exists", msg.ID))
+		return false
+	}
+
+	go h.runOperation(ctx, sc, msg.ID, &payload)
+
+	return true
+}
+
+// runOperation executes an operation and streams its results to the client.
+// Subscriptions yield a result per event; queries and mutations yield one.
+// Errors raised before the first result are reported with an error message,
+// anything else is delivered in next messages followed by complete.
+func (h *GraphQLHandler) runOperation(
+	ctx context.Context, sc *subscriptionConn, id string, payload *gqlwsSubscribePayload,
+) {
+	defer sc.operationWG.Done()
+
+	params := graphql.Params{
+		Schema:         h.schema,
+		RequestString:  payload.Query,
+		VariableValues: payload.Variables,
+		OperationName:  payload.OperationName,
+		Context:        ctx,
+	}
+
+	var results <-chan *graphql.Result
+	if operationType(payload.Query, payload.OperationName) == ast.OperationTypeSubscription {
+		results = graphql.Subscribe(params)
+	} else {
+		single := make(chan *graphql.Result, 1)
+		single <- graphql.Do(params)
+		close(single)
+		results = single
+	}
+
+	first, done := true, false
+	for result := range results {
+		// Keep draining after the operation has ended so the executor exits.
+		if done {
+			continue
+		}
+		if first && result.Data == nil && result.HasErrors() {
+			_ = h.sendSubscriptionMessage(sc, gqlwsError, id, result.Errors)
+			sc.finishOperation(id)
+			done = true
+			continue
+		}
+		first = false
+		if err := h.sendSubscriptionMessage(sc, gqlwsNext, id, result); err != nil {
+			sc.finishOperation(id)
+			done = true
+		}
+	}
+
+	if sc.finishOperation(id) && sc.ctx.Err() == nil {
+		_ = h.sendSubscriptionMessage(sc, gqlwsComplete, id, nil)
+	}
+}
+
+// operationType returns the type of the operation selected by operationName,
+// or an empty string when the document cannot be parsed.
+func operationType(query, operationName string) string {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return ""
+	}
+
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (op.Name != nil && op.Name.Value == operationName) {
+			return op.Operation
+		}
+	}
+
+	return ""
+}
+
+// sendSubscriptionMessage writes a protocol message with an optional payload.
+func (h *GraphQLHandler) sendSubscriptionMessage(sc *subscriptionConn, msgType, id string, payload any) error {
+	msg := gqlwsMessage{ID: id, Type: msgType}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			h.logger.Error("failed to encode GraphQL subscription message", zap.Error(err))
+			return fmt.Errorf("encoding %s payload: %w", msgType, err)
+		}
+		msg.Payload = data
+	}
+
+	sc.writeMu.Lock()
+	defer sc.writeMu.Unlock()
+
+	if err := sc.conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+		return err
+	}
+	return sc.conn.WriteJSON(msg)
+}
+
+// closeSubscriptionConn sends a close frame with the given code and closes
+// the connection, which ends its read loop.
+func (h *GraphQLHandler) closeSubscriptionConn(sc *subscriptionConn, code int, reason string) {
+	sc.writeMu.Lock()
+	err := sc.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if err == nil {
+		err = sc.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+	}
+	sc.writeMu.Unlock()
+	if err != nil {
+		h.logger.Debug("failed to send close message", zap.Error(err))
+	}
+
+	sc.cancel()
+	if err := sc.conn.Close(); err != nil {
+		h.logger.Debug("error closing connection", zap.Error(err))
+	}
+}
+
+// releaseSubscriptionConn stops all operations of a finished connection and
+// unregisters it.
+func (h *GraphQLHandler) releaseSubscriptionConn(sc *subscriptionConn) {
+	sc.mu.Lock()
+	if sc.expiry != nil {
+		sc.expiry.Stop()
+	}
+	sc.mu.Unlock()
+
+	sc.cancel()
+	_ = sc.conn.Close()
+	sc.operationWG.Wait()
+
+	h.mu.Lock()
+	delete(h.conns, sc.conn)
+	h.mu.Unlock()
+
+	observability.WebSocketActiveConnections.Dec()
+	h.logger.Info("GraphQL subscription client disconnected", zap.String("remote_addr", sc.conn.RemoteAddr().String()))
+}
+
+// CloseSubscriptions closes all GraphQL subscription connections and waits
+// for their operations to stop.
+func (h *GraphQLHandler) CloseSubscriptions() {
+	h.mu.Lock()
+	for _, sc := range h.conns {
+		h.closeSubscriptionConn(sc, websocket.CloseNormalClosure, "server shutting down")
+	}
+	h.mu.Unlock()
+
+	h.wg.Wait()
+
+	h.logger.Info("all GraphQL subscription connections closed")
+}
+
+// writeError writes a JSON error response before a connection is upgraded.
+func (h *GraphQLHandler) writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(model.ErrorResponse{Code: status, Message: message}); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
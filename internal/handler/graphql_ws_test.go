@@ -0,0 +1,576 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/middleware"
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+	"github.com/vyrodovalexey/restapi-example/internal/store"
+)
+
+// apiKeyTestAuthenticator accepts requests carrying the key "secret" as
+// X-API-Key or bearer token. The credentials it issues expire after ttl, if
+// set.
+type apiKeyTestAuthenticator struct {
+	ttl time.Duration
+}
+
+func (a apiKeyTestAuthenticator) Authenticate(r *http.Request) (*auth.AuthInfo, error) {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		key = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+
+	switch key {
+	case "":
+		return nil, auth.ErrUnauthenticated
+	case "secret":
+		info := &auth.AuthInfo{Method: auth.AuthMethodAPIKey, Subject: "client"}
+		if a.ttl > 0 {
+			info.Expiry = time.Now().Add(a.ttl)
+		}
+		return info, nil
+	default:
+		return nil, auth.ErrInvalidAPIKey
+	}
+}
+
+func (apiKeyTestAuthenticator) Method() auth.AuthMethod {
+	return auth.AuthMethodAPIKey
+}
+
+// startSubscriptionServer serves the GraphQL routes backed by an event store,
+// behind the auth middleware when an authenticator is given.
+func startSubscriptionServer(t *testing.T, authenticator auth.Authenticator) (*store.EventStore, string) {
+	t.Helper()
+
+	events := store.NewEventStore(store.NewMemoryStore())
+	h := NewGraphQLHandler(events, zap.NewNop(), events)
+	router := mux.NewRouter()
+	if authenticator != nil {
+		h.SetAuthenticator(authenticator)
+		router.Use(mux.MiddlewareFunc(middleware.Auth(authenticator, zap.NewNop(), RouteWebSocket, RouteGraphQLSubscriptions)))
+	}
+	h.RegisterRoutes(router)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(func() {
+		h.CloseSubscriptions()
+		server.Close()
+	})
+
+	return events, "ws" + strings.TrimPrefix(server.URL, "http") + "/graphql"
+}
+
+// dialSubscriptions opens a graphql-transport-ws connection.
+func dialSubscriptions(t *testing.T, url string, header http.Header) *websocket.Conn {
+	t.Helper()
+
+	dialer := websocket.Dialer{Subprotocols: []string{graphqlTransportWSProtocol}}
+	conn, resp, err := dialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	resp.Body.Close()
+	t.Cleanup(func() { conn.Close() })
+
+	if conn.Subprotocol() != graphqlTransportWSProtocol {
+		t.Fatalf("Subprotocol() = %q, want %q", conn.Subprotocol(), graphqlTransportWSProtocol)
+	}
+
+	return conn
+}
+
+// sendGQLWS writes a protocol message with the given payload.
+func sendGQLWS(t *testing.T, conn *websocket.Conn, msgType, id string, payload any) {
+	t.Helper()
+
+	msg := gqlwsMessage{ID: id, Type: msgType}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatalf("Failed to encode payload: %v", err)
+		}
+		msg.Payload = data
+	}
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatalf("Failed to send %s: %v", msgType, err)
+	}
+}
+
+// readGQLWS reads the next protocol message.
+func readGQLWS(t *testing.T, conn *websocket.Conn) gqlwsMessage {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg gqlwsMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	return msg
+}
+
+// expectCloseCode reads until the connection is closed and checks the code.
+func expectCloseCode(t *testing.T, conn *websocket.Conn, code int) {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) {
+			t.Fatalf("read error = %v, want close code %d", err, code)
+		}
+		if closeErr.Code != code {
+			t.Errorf("close code = %d (%s), want %d", closeErr.Code, closeErr.Text, code)
+		}
+		return
+	}
+}
+
+// initSubscriptions performs the connection_init handshake.
+func initSubscriptions(t *testing.T, conn *websocket.Conn, payload any) {
+	t.Helper()
+
+	sendGQLWS(t, conn, gqlwsConnectionInit, "", payload)
+	if msg := readGQLWS(t, conn); msg.Type != gqlwsConnectionAck {
+		t.Fatalf("got %s, want %s", msg.Type, gqlwsConnectionAck)
+	}
+}
+
+// subscribeAndWait subscribes and gives the operation time to register with
+// the event source before events are produced.
+func subscribeAndWait(t *testing.T, conn *websocket.Conn, id, query string) {
+	t.Helper()
+
+	sendGQLWS(t, conn, gqlwsSubscribe, id, gqlwsSubscribePayload{Query: query})
+	time.Sleep(100 * time.Millisecond)
+}
+
+func TestGraphQLSubscriptions_ItemEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		produce func(ctx context.Context, s store.Store, target, other *model.Item) error
+		want    string
+	}{
+		{
+			name:  "item created",
+			query: `subscription { itemCreated { name price } }`,
+			produce: func(ctx context.Context, s store.Store, _, _ *model.Item) error {
+				_, err := s.Create(ctx, &model.Item{Name: "Fresh", Price: 3})
+				return err
+			},
+			want: `{"data":{"itemCreated":{"name":"Fresh","price":3}}}`,
+		},
+		{
+			name:  "item updated filtered by id",
+			query: `subscription($id: ID) { itemUpdated(id: $id) { name version } }`,
+			produce: func(ctx context.Context, s store.Store, target, other *model.Item) error {
+				if _, err := s.Update(ctx, other.ID, &model.Item{Name: "Other v2", Price: 1}, 0); err != nil {
+					return err
+				}
+				_, err := s.Update(ctx, target.ID, &model.Item{Name: "Target v2", Price: 1}, 0)
+				return err
+			},
+			want: `{"data":{"itemUpdated":{"name":"Target v2","version":2}}}`,
+		},
+		{
+			name:  "item deleted",
+			query: `subscription($id: ID) { itemDeleted(id: $id) }`,
+			produce: func(ctx context.Context, s store.Store, target, other *model.Item) error {
+				if err := s.Delete(ctx, other.ID, 0); err != nil {
+					return err
+				}
+				return s.Delete(ctx, target.ID, 0)
+			},
+			want: `{"data":{"itemDeleted":"TARGET_ID"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ctx := t.Context()
+			events, url := startSubscriptionServer(t, nil)
+			target, err := events.Create(ctx, &model.Item{Name: "Target", Price: 1})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			other, err := events.Create(ctx, &model.Item{Name: "Other", Price: 1})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			conn := dialSubscriptions(t, url, nil)
+			initSubscriptions(t, conn, nil)
+			sendGQLWS(t, conn, gqlwsSubscribe, "1", gqlwsSubscribePayload{
+				Query:     tt.query,
+				Variables: map[string]any{"id": target.ID},
+			})
+			time.Sleep(100 * time.Millisecond)
+
+			// Act
+			if err := tt.produce(ctx, events, target, other); err != nil {
+				t.Fatalf("produce() error = %v", err)
+			}
+			msg := readGQLWS(t, conn)
+
+			// Assert
+			if msg.Type != gqlwsNext || msg.ID != "1" {
+				t.Fatalf("got %s for %q, want %s for \"1\"", msg.Type, msg.ID, gqlwsNext)
+			}
+			want := strings.ReplaceAll(tt.want, "TARGET_ID", target.ID)
+			if string(msg.Payload) != want {
+				t.Errorf("payload = %s, want %s", msg.Payload, want)
+			}
+		})
+	}
+}
+
+func TestGraphQLSubscriptions_ClientComplete(t *testing.T) {
+	// Arrange
+	ctx := t.Context()
+	events, url := startSubscriptionServer(t, nil)
+	conn := dialSubscriptions(t, url, nil)
+	initSubscriptions(t, conn, nil)
+	subscribeAndWait(t, conn, "created", `subscription { itemCreated { name } }`)
+	subscribeAndWait(t, conn, "deleted", `subscription { itemDeleted }`)
+
+	// Act
+	sendGQLWS(t, conn, gqlwsComplete, "created", nil)
+	time.Sleep(100 * time.Millisecond)
+	created, err := events.Create(ctx, &model.Item{Name: "Ignored", Price: 1})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := events.Delete(ctx, created.ID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	msg := readGQLWS(t, conn)
+
+	// Assert - only the remaining subscription reports
+	if msg.Type != gqlwsNext || msg.ID != "deleted" {
+		t.Errorf("got %s for %q, want %s for \"deleted\"", msg.Type, msg.ID, gqlwsNext)
+	}
+}
+
+func TestGraphQLSubscriptions_QueryOperation(t *testing.T) {
+	// Arrange
+	ctx := t.Context()
+	events, url := startSubscriptionServer(t, nil)
+	if _, err := events.Create(ctx, &model.Item{Name: "Listed", Price: 1}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	conn := dialSubscriptions(t, url, nil)
+	initSubscriptions(t, conn, nil)
+
+	// Act
+	sendGQLWS(t, conn, gqlwsSubscribe, "q", gqlwsSubscribePayload{Query: `{ items { name } }`})
+	next := readGQLWS(t, conn)
+	complete := readGQLWS(t, conn)
+
+	// Assert
+	if next.Type != gqlwsNext || string(next.Payload) != `{"data":{"items":[{"name":"Listed"}]}}` {
+		t.Errorf("got %s %s, want next with the item list", next.Type, next.Payload)
+	}
+	if complete.Type != gqlwsComplete || complete.ID != "q" {
+		t.Errorf("got %s for %q, want %s for \"q\"", complete.Type, complete.ID, gqlwsComplete)
+	}
+}
+
+func TestGraphQLSubscriptions_InvalidOperation(t *testing.T) {
+	// Arrange
+	_, url := startSubscriptionServer(t, nil)
+	conn := dialSubscriptions(t, url, nil)
+	initSubscriptions(t, conn, nil)
+
+	// Act
+	sendGQLWS(t, conn, gqlwsSubscribe, "bad", gqlwsSubscribePayload{Query: `subscription { itemMissing }`})
+	msg := readGQLWS(t, conn)
+
+	// Assert
+	if msg.Type != gqlwsError || msg.ID != "bad" {
+		t.Fatalf("got %s for %q, want %s for \"bad\"", msg.Type, msg.ID, gqlwsError)
+	}
+	var errs []map[string]any
+	if err := json.Unmarshal(msg.Payload, &errs); err != nil || len(errs) == 0 {
+		t.Errorf("payload = %s, want a list of GraphQL errors", msg.Payload)
+	}
+}
+
+func TestGraphQLSubscriptions_PingPong(t *testing.T) {
+	// Arrange
+	_, url := startSubscriptionServer(t, nil)
+	conn := dialSubscriptions(t, url, nil)
+	initSubscriptions(t, conn, nil)
+
+	// Act
+	sendGQLWS(t, conn, gqlwsPing, "", nil)
+	msg := readGQLWS(t, conn)
+
+	// Assert
+	if msg.Type != gqlwsPong {
+		t.Errorf("got %s, want %s", msg.Type, gqlwsPong)
+	}
+}
+
+func TestGraphQLSubscriptions_ProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []gqlwsMessage
+		code     int
+	}{
+		{
+			name:     "subscribe before init",
+			messages: []gqlwsMessage{{ID: "1", Type: gqlwsSubscribe, Payload: json.RawMessage(`{"query":"{ items { id } }"}`)}},
+			code:     gqlwsCloseUnauthorized,
+		},
+		{
+			name:     "repeated init",
+			messages: []gqlwsMessage{{Type: gqlwsConnectionInit}, {Type: gqlwsConnectionInit}},
+			code:     gqlwsCloseTooManyInit,
+		},
+		{
+			name:     "unknown message type",
+			messages: []gqlwsMessage{{Type: "start"}},
+			code:     gqlwsCloseInvalidMessage,
+		},
+		{
+			name:     "subscribe without id",
+			messages: []gqlwsMessage{{Type: gqlwsConnectionInit}, {Type: gqlwsSubscribe, Payload: json.RawMessage(`{"query":"{ items { id } }"}`)}},
+			code:     gqlwsCloseInvalidMessage,
+		},
+		{
+			name: "duplicate operation id",
+			messages: []gqlwsMessage{
+				{Type: gqlwsConnectionInit},
+				{ID: "1", Type: gqlwsSubscribe, Payload: json.RawMessage(`{"query":"subscription { itemCreated { id } }"}`)},
+				{ID: "1", Type: gqlwsSubscribe, Payload: json.RawMessage(`{"query":"subscription { itemCreated { id } }"}`)},
+			},
+			code: gqlwsCloseSubscriberExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			_, url := startSubscriptionServer(t, nil)
+			conn := dialSubscriptions(t, url, nil)
+
+			// Act
+			for _, msg := range tt.messages {
+				if err := conn.WriteJSON(msg); err != nil {
+					t.Fatalf("Failed to send %s: %v", msg.Type, err)
+				}
+			}
+
+			// Assert
+			expectCloseCode(t, conn, tt.code)
+		})
+	}
+}
+
+func TestGraphQLSubscriptions_InvalidJSON(t *testing.T) {
+	// Arrange
+	_, url := startSubscriptionServer(t, nil)
+	conn := dialSubscriptions(t, url, nil)
+
+	// Act
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("not json")); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+
+	// Assert
+	expectCloseCode(t, conn, gqlwsCloseInvalidMessage)
+}
+
+func TestGraphQLSubscriptions_Authentication(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      http.Header
+		initPayload any
+		wantAck     bool
+		wantCode    int
+	}{
+		{
+			name:    "credentials on upgrade request",
+			header:  http.Header{"X-Api-Key": []string{"secret"}},
+			wantAck: true,
+		},
+		{
+			name:        "credentials in connection_init payload",
+			initPayload: map[string]any{"x-api-key": "secret"},
+			wantAck:     true,
+		},
+		{
+			name:        "invalid credentials in connection_init payload",
+			initPayload: map[string]any{"X-API-Key": "wrong"},
+			wantCode:    gqlwsCloseForbidden,
+		},
+		{
+			name:     "no credentials",
+			wantCode: gqlwsCloseForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			_, url := startSubscriptionServer(t, apiKeyTestAuthenticator{})
+			conn := dialSubscriptions(t, url, tt.header)
+
+			// Act
+			sendGQLWS(t, conn, gqlwsConnectionInit, "", tt.initPayload)
+
+			// Assert
+			if !tt.wantAck {
+				expectCloseCode(t, conn, tt.wantCode)
+				return
+			}
+			if msg := readGQLWS(t, conn); msg.Type != gqlwsConnectionAck {
+				t.Errorf("got %s, want %s", msg.Type, gqlwsConnectionAck)
+			}
+		})
+	}
+}
+
+func TestGraphQLSubscriptions_CredentialsExpire(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      http.Header
+		initPayload any
+	}{
+		{name: "authenticated on upgrade", header: http.Header{"X-Api-Key": []string{"secret"}}},
+		{name: "authenticated in connection_init", initPayload: map[string]any{"X-API-Key": "secret"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			_, url := startSubscriptionServer(t, apiKeyTestAuthenticator{ttl: 300 * time.Millisecond})
+			conn := dialSubscriptions(t, url, tt.header)
+			initSubscriptions(t, conn, tt.initPayload)
+
+			// Act
+			subscribeAndWait(t, conn, "1", `subscription { itemCreated { id } }`)
+
+			// Assert
+			expectCloseCode(t, conn, gqlwsCloseForbidden)
+		})
+	}
+}
+
+func TestGraphQLSubscriptions_UpgradeRejected(t *testing.T) {
+	tests := []struct {
+		name         string
+		subprotocols []string
+		header       http.Header
+		wantStatus   int
+	}{
+		{
+			name:         "invalid credentials on upgrade request",
+			subprotocols: []string{graphqlTransportWSProtocol},
+			header:       http.Header{"X-Api-Key": []string{"wrong"}},
+			wantStatus:   http.StatusUnauthorized,
+		},
+		{
+			name:         "unsupported subprotocol",
+			subprotocols: []string{"graphql-ws"},
+			wantStatus:   http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			_, url := startSubscriptionServer(t, apiKeyTestAuthenticator{})
+			dialer := websocket.Dialer{Subprotocols: tt.subprotocols}
+
+			// Act
+			conn, resp, err := dialer.Dial(url, tt.header)
+
+			// Assert
+			if err == nil {
+				conn.Close()
+				t.Fatal("Dial() succeeded, want rejected upgrade")
+			}
+			if resp == nil {
+				t.Fatalf("Dial() error = %v, want HTTP response", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestGraphQLSubscriptions_NoEventSource(t *testing.T) {
+	// Arrange
+	h := NewGraphQLHandler(newMockStore(), zap.NewNop())
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	server := httptest.NewServer(router)
+	t.Cleanup(func() {
+		h.CloseSubscriptions()
+		server.Close()
+	})
+	conn := dialSubscriptions(t, "ws"+strings.TrimPrefix(server.URL, "http")+"/graphql", nil)
+	initSubscriptions(t, conn, nil)
+
+	// Act
+	sendGQLWS(t, conn, gqlwsSubscribe, "1", gqlwsSubscribePayload{Query: `subscription { itemCreated { id } }`})
+	msg := readGQLWS(t, conn)
+
+	// Assert
+	if msg.Type != gqlwsError || !strings.Contains(string(msg.Payload), "item events are not available") {
+		t.Errorf("got %s %s, want error message", msg.Type, msg.Payload)
+	}
+}
+
+func TestOperationType(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		want          string
+	}{
+		{name: "shorthand query", query: `{ items { id } }`, want: "query"},
+		{name: "subscription", query: `subscription { itemCreated { id } }`, want: "subscription"},
+		{
+			name:          "selected by name",
+			query:         `query A { items { id } } subscription B { itemDeleted }`,
+			operationName: "B",
+			want:          "subscription",
+		},
+		{name: "unparsable", query: `subscription {`, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			got := operationType(tt.query, tt.operationName)
+
+			// Assert
+			if got != tt.want {
+				t.Errorf("operationType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
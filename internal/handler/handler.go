@@ -1,6 +1,19 @@
 // Package handler provides HTTP request handlers for the REST API.
 package handler
 
+import (
+	"time"
+
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+)
+
+// Names of the routes of the WebSocket endpoints, which authenticate and
+// authorize clients in-band.
+const (
+	RouteWebSocket            = "websocket"
+	RouteGraphQLSubscriptions = "graphql-subscriptions"
+)
+
 // HealthResponse represents the health check response.
 type HealthResponse struct {
 	Status  string `json:"status"`
@@ -11,3 +24,61 @@ type HealthResponse struct {
 type ReadyResponse struct {
 	Status string `json:"status"`
 }
+
+// BatchRequest is the body of POST /api/v1/items:batch.
+type BatchRequest struct {
+	// Mode is "atomic" (default) or "best_effort".
+	Mode       string                  `json:"mode,omitempty"`
+	Operations []BatchOperationRequest `json:"operations"`
+}
+
+// BatchOperationRequest describes a single operation within a BatchRequest.
+type BatchOperationRequest struct {
+	// Op is "create", "update" or "delete".
+	Op              string      `json:"op"`
+	ID              string      `json:"id,omitempty"`
+	Item            *model.Item `json:"item,omitempty"`
+	ExpectedVersion int64       `json:"expected_version,omitempty"`
+}
+
+// BatchResponse reports the outcome of a batch request.
+type BatchResponse struct {
+	Mode      string                 `json:"mode"`
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
+	Results   []BatchOperationResult `json:"results"`
+}
+
+// BatchOperationResult is the per-operation entry of a BatchResponse. Status
+// is the HTTP status the operation would have had as a standalone request.
+type BatchOperationResult struct {
+	Index  int         `json:"index"`
+	Op     string      `json:"op"`
+	Status int         `json:"status"`
+	Item   *model.Item `json:"item,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// APIKeyRequest is the body of POST /api/v1/admin/api-keys and, optionally,
+// of POST /api/v1/admin/api-keys/{id}/rotate, which only uses ExpiresIn.
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes,omitempty"`
+	// ExpiresIn is the key lifetime as a Go duration, e.g. "720h"; empty
+	// means the key does not expire (or, on rotation, keeps its lifetime).
+	ExpiresIn string `json:"expires_in,omitempty"`
+}
+
+// APIKeyResponse describes a managed API key. Key is only set when the key
+// is created or rotated.
+type APIKeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	Key        string     `json:"key,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
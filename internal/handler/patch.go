@@ -0,0 +1,128 @@
+// patch.go implements partial item updates shared by the REST PATCH endpoint
+// and the GraphQL updateItem mutation. Patches are applied to the JSON form
+// of the stored item, the result is validated, and the write is made
+// conditional on the version the patch was applied to.
+
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+	"github.com/vyrodovalexey/restapi-example/internal/store"
+)
+
+// Patch media types accepted by PATCH /api/v1/items/{id}.
+const (
+	mediaTypeMergePatch = "application/merge-patch+json" // RFC 7386
+	mediaTypeJSONPatch  = "application/json-patch+json"  // RFC 6902
+)
+
+// maxPatchAttempts bounds how often an unconditional patch is re-applied when
+// a concurrent writer changes the item between read and write.
+const maxPatchAttempts = 3
+
+// Patch errors.
+var (
+	errUnsupportedPatchType = errors.New("unsupported patch media type")
+	errInvalidPatch         = errors.New("invalid patch")
+	errPatchTestFailed      = errors.New("patch test operation failed")
+	errValidation           = errors.New("validation error")
+)
+
+// patchFunc transforms the JSON document of an item.
+type patchFunc func(doc []byte) ([]byte, error)
+
+// newPatchFunc parses a patch document of the given media type.
+func newPatchFunc(mediaType string, patch []byte) (patchFunc, error) {
+	switch mediaType {
+	case mediaTypeMergePatch:
+		if !json.Valid(patch) || !bytes.HasPrefix(bytes.TrimSpace(patch), []byte("{")) {
+			return nil, fmt.Errorf("%w: merge patch must be a JSON object", errInvalidPatch)
+		}
+		return func(doc []byte) ([]byte, error) {
+			return jsonpatch.MergePatch(doc, patch)
+		}, nil
+	case mediaTypeJSONPatch:
+		ops, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidPatch, err)
+		}
+		return ops.Apply, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnsupportedPatchType, mediaType)
+	}
+}
+
+// patchItem applies a patch to the stored item and writes the validated
+// result. A non-zero expectedVersion must match the stored version; without
+// one, the patch is re-applied to the fresh item if a concurrent update wins
+// the race.
+func patchItem(
+	ctx context.Context, s store.Store, id string, expectedVersion int64, apply patchFunc,
+) (*model.Item, error) {
+	for attempt := 1; ; attempt++ {
+		current, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if expectedVersion != 0 && current.Version != expectedVersion {
+			return nil, store.ErrVersionConflict
+		}
+
+		patched, err := applyItemPatch(current, apply)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := patched.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %w", errValidation, err)
+		}
+
+		updated, err := s.Update(ctx, id, patched, current.Version)
+		if errors.Is(err, store.ErrVersionConflict) && expectedVersion == 0 && attempt < maxPatchAttempts {
+			continue
+		}
+
+		return updated, err
+	}
+}
+
+// applyItemPatch runs apply against the JSON form of item and decodes the
+// result. Server-managed fields (id, version, timestamps) may be read by
+// JSON Patch "test" operations but not changed.
+func applyItemPatch(item *model.Item, apply patchFunc) (*model.Item, error) {
+	doc, err := json.Marshal(item)
+	if err != nil {
+		return nil, fmt.Errorf("encoding item: %w", err)
+	}
+
+	patchedDoc, err := apply(doc)
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil, fmt.Errorf("%w: %w", errPatchTestFailed, err)
+		}
+		return nil, fmt.Errorf("%w: %w", errInvalidPatch, err)
+	}
+
+	var patched model.Item
+	decoder := json.NewDecoder(bytes.NewReader(patchedDoc))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidPatch, err)
+	}
+
+	if patched.ID != item.ID || patched.Version != item.Version ||
+		!patched.CreatedAt.Equal(item.CreatedAt) || !patched.UpdatedAt.Equal(item.UpdatedAt) {
+		return nil, fmt.Errorf("%w: id, version, created_at and updated_at are read-only", errInvalidPatch)
+	}
+
+	return &patched, nil
+}
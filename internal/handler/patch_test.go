@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+	"github.com/vyrodovalexey/restapi-example/internal/store"
+)
+
+func TestNewPatchFunc(t *testing.T) {
+	tests := []struct {
+		name      string
+		mediaType string
+		patch     string
+		wantErr   error
+	}{
+		{name: "merge patch", mediaType: mediaTypeMergePatch, patch: `{"name":"x"}`},
+		{name: "json patch", mediaType: mediaTypeJSONPatch, patch: `[{"op":"replace","path":"/name","value":"x"}]`},
+		{name: "merge patch not an object", mediaType: mediaTypeMergePatch, patch: `["name"]`, wantErr: errInvalidPatch},
+		{name: "merge patch malformed", mediaType: mediaTypeMergePatch, patch: `{`, wantErr: errInvalidPatch},
+		{name: "json patch malformed", mediaType: mediaTypeJSONPatch, patch: `{"op":"add"}`, wantErr: errInvalidPatch},
+		{name: "plain json", mediaType: "application/json", patch: `{}`, wantErr: errUnsupportedPatchType},
+		{name: "missing content type", mediaType: "", patch: `{}`, wantErr: errUnsupportedPatchType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			apply, err := newPatchFunc(tt.mediaType, []byte(tt.patch))
+
+			// Assert
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("newPatchFunc() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || apply == nil {
+				t.Fatalf("newPatchFunc() = %v, %v; want patch func", apply, err)
+			}
+		})
+	}
+}
+
+func TestPatchItem(t *testing.T) {
+	tests := []struct {
+		name      string
+		mediaType string
+		patch     string
+		wantErr   error
+		want      model.Item
+	}{
+		{
+			name:      "merge patch changes only given fields",
+			mediaType: mediaTypeMergePatch,
+			patch:     `{"price": 12.5}`,
+			want:      model.Item{Name: "Widget", Description: "Blue", Price: 12.5, Version: 2},
+		},
+		{
+			name:      "merge patch null clears description",
+			mediaType: mediaTypeMergePatch,
+			patch:     `{"description": null}`,
+			want:      model.Item{Name: "Widget", Price: 10, Version: 2},
+		},
+		{
+			name:      "json patch with passing test",
+			mediaType: mediaTypeJSONPatch,
+			patch: `[{"op":"test","path":"/version","value":1},
+				{"op":"replace","path":"/name","value":"Gadget"},
+				{"op":"remove","path":"/description"}]`,
+			want: model.Item{Name: "Gadget", Price: 10, Version: 2},
+		},
+		{
+			name:      "json patch with failing test",
+			mediaType: mediaTypeJSONPatch,
+			patch:     `[{"op":"test","path":"/name","value":"Other"}]`,
+			wantErr:   errPatchTestFailed,
+		},
+		{
+			name:      "json patch on missing path",
+			mediaType: mediaTypeJSONPatch,
+			patch:     `[{"op":"replace","path":"/missing","value":1}]`,
+			wantErr:   errInvalidPatch,
+		},
+		{
+			name:      "unknown field",
+			mediaType: mediaTypeMergePatch,
+			patch:     `{"color": "red"}`,
+			wantErr:   errInvalidPatch,
+		},
+		{
+			name:      "read-only id",
+			mediaType: mediaTypeMergePatch,
+			patch:     `{"id": "other"}`,
+			wantErr:   errInvalidPatch,
+		},
+		{
+			name:      "read-only version",
+			mediaType: mediaTypeJSONPatch,
+			patch:     `[{"op":"replace","path":"/version","value":9}]`,
+			wantErr:   errInvalidPatch,
+		},
+		{
+			name:      "invalid result",
+			mediaType: mediaTypeMergePatch,
+			patch:     `{"price": -1}`,
+			wantErr:   errValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			s := store.NewMemoryStore()
+			created, err := s.Create(ctx, &model.Item{Name: "Widget", Description: "Blue", Price: 10})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			apply, err := newPatchFunc(tt.mediaType, []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("newPatchFunc() error = %v", err)
+			}
+
+			// Act
+			got, err := patchItem(ctx, s, created.ID, 0, apply)
+
+			// Assert
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("patchItem() error = %v, want %v", err, tt.wantErr)
+				}
+				stored, _ := s.Get(ctx, created.ID)
+				if stored.Version != created.Version {
+					t.Error("rejected patch must not modify the item")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("patchItem() error = %v", err)
+			}
+			if got.Name != tt.want.Name || got.Description != tt.want.Description ||
+				got.Price != tt.want.Price || got.Version != tt.want.Version {
+				t.Errorf("patchItem() = %+v, want %+v", got, tt.want)
+			}
+			if !got.CreatedAt.Equal(created.CreatedAt) {
+				t.Error("patchItem() should preserve CreatedAt")
+			}
+		})
+	}
+}
+
+func TestPatchItem_ExpectedVersion(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	created, err := s.Create(ctx, &model.Item{Name: "Widget", Price: 10})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	apply, _ := newPatchFunc(mediaTypeMergePatch, []byte(`{"price": 1}`))
+
+	// Act
+	_, err = patchItem(ctx, s, created.ID, created.Version+1, apply)
+
+	// Assert
+	if !errors.Is(err, store.ErrVersionConflict) {
+		t.Errorf("patchItem() error = %v, want %v", err, store.ErrVersionConflict)
+	}
+}
+
+// racingStore reports a version conflict on the first Update call, simulating
+// a concurrent writer between patchItem's read and write.
+type racingStore struct {
+	*store.MemoryStore
+	conflicts int
+}
+
+func (r *racingStore) Update(
+	ctx context.Context, id string, item *model.Item, expectedVersion int64,
+) (*model.Item, error) {
+	if r.conflicts > 0 {
+		r.conflicts--
+		return nil, store.ErrVersionConflict
+	}
+	return r.MemoryStore.Update(ctx, id, item, expectedVersion)
+}
+
+func TestPatchItem_RetriesConcurrentUpdate(t *testing.T) {
+	tests := []struct {
+		name            string
+		conflicts       int
+		expectedVersion int64
+		wantErr         error
+	}{
+		{name: "unconditional patch retries", conflicts: 1},
+		{name: "gives up after max attempts", conflicts: maxPatchAttempts, wantErr: store.ErrVersionConflict},
+		{name: "conditional patch does not retry", conflicts: 1, expectedVersion: 1, wantErr: store.ErrVersionConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			s := &racingStore{MemoryStore: store.NewMemoryStore(), conflicts: tt.conflicts}
+			created, err := s.Create(ctx, &model.Item{Name: "Widget", Price: 10})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			apply, _ := newPatchFunc(mediaTypeMergePatch, []byte(`{"price": 1}`))
+
+			// Act
+			_, err = patchItem(ctx, s, created.ID, tt.expectedVersion, apply)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("patchItem() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -6,7 +6,14 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
@@ -21,10 +28,29 @@ const Version = "1.0.0"
 // maxRequestBodySize is the maximum allowed size for request bodies (1 MB).
 const maxRequestBodySize = 1 << 20
 
+// Pagination limits for item listing.
+const (
+	// DefaultPageSize is the page size used when the client does not request one.
+	DefaultPageSize = 100
+	// MaxPageSize is the largest page size a client may request.
+	MaxPageSize = 1000
+)
+
+// Handler errors.
+var (
+	// errInvalidQuery is returned when list query parameters cannot be parsed.
+	errInvalidQuery = errors.New("invalid query parameter")
+
+	// errPreconditionFailed is returned when an If-Match header cannot match
+	// the current item version.
+	errPreconditionFailed = errors.New("precondition failed")
+)
+
 // RESTHandler handles REST API requests for items.
 type RESTHandler struct {
 	store  store.Store
 	logger *zap.Logger
+	ids    *store.IDValidator
 }
 
 // NewRESTHandler creates a new RESTHandler instance.
@@ -35,14 +61,23 @@ func NewRESTHandler(s store.Store, logger *zap.Logger) *RESTHandler {
 	}
 }
 
+// SetIDValidator sets the validator of the item IDs chosen by clients. It
+// must be called before the handler serves requests; without it, clients
+// may only choose UUIDs.
+func (h *RESTHandler) SetIDValidator(ids *store.IDValidator) {
+	h.ids = ids
+}
+
 // RegisterRoutes registers the REST API routes with the router.
 func (h *RESTHandler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/health", h.HealthCheck).Methods(http.MethodGet)
 	router.HandleFunc("/ready", h.ReadyCheck).Methods(http.MethodGet)
 	router.HandleFunc("/api/v1/items", h.ListItems).Methods(http.MethodGet)
 	router.HandleFunc("/api/v1/items", h.CreateItem).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/items:batch", h.BatchItems).Methods(http.MethodPost)
 	router.HandleFunc("/api/v1/items/{id}", h.GetItem).Methods(http.MethodGet)
 	router.HandleFunc("/api/v1/items/{id}", h.UpdateItem).Methods(http.MethodPut)
+	router.HandleFunc("/api/v1/items/{id}", h.PatchItem).Methods(http.MethodPatch)
 	router.HandleFunc("/api/v1/items/{id}", h.DeleteItem).Methods(http.MethodDelete)
 }
 
@@ -64,17 +99,39 @@ func (h *RESTHandler) ReadyCheck(w http.ResponseWriter, _ *http.Request) {
 }
 
 // ListItems handles GET /api/v1/items requests.
+//
+// Supported query parameters: limit, offset, cursor, sort (name, price or
+// created_at; prefix with "-" for descending order), name_prefix, min_price
+// and max_price.
 func (h *RESTHandler) ListItems(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	items, err := h.store.List(ctx)
+	opts, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		h.logger.Warn("invalid list query", zap.Error(err))
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.store.List(ctx, opts)
 	if err != nil {
+		if errors.Is(err, store.ErrInvalidListOptions) || errors.Is(err, store.ErrInvalidCursor) {
+			h.logger.Warn("invalid list options", zap.Error(err))
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		h.logger.Error("failed to list items", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "failed to retrieve items")
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, model.NewSuccessResponse(items))
+	meta := model.PageMeta{
+		Total:      page.Total,
+		Limit:      opts.Limit,
+		Offset:     opts.Offset,
+		NextCursor: page.NextCursor,
+	}
+	h.writeJSON(w, http.StatusOK, model.NewPageResponse(page.Items, meta))
 }
 
 // GetItem handles GET /api/v1/items/{id} requests.
@@ -89,10 +146,19 @@ func (h *RESTHandler) GetItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	etag := itemETag(item)
+	w.Header().Set("ETag", etag)
+
+	if ifNoneMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	h.writeJSON(w, http.StatusOK, model.NewSuccessResponse(item))
 }
 
-// CreateItem handles POST /api/v1/items requests.
+// CreateItem handles POST /api/v1/items requests. An item with an id keeps
+// it, provided the ID validator accepts it and no item has it yet.
 func (h *RESTHandler) CreateItem(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -111,21 +177,39 @@ func (h *RESTHandler) CreateItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if input.ID != "" {
+		if err := h.ids.Validate(input.ID); err != nil {
+			h.handleStoreError(w, err, "create item")
+			return
+		}
+	}
+
 	item, err := h.store.Create(ctx, &input)
 	if err != nil {
 		h.handleStoreError(w, err, "create item")
 		return
 	}
 
+	w.Header().Set("ETag", itemETag(item))
 	h.writeJSON(w, http.StatusCreated, model.NewSuccessResponse(item))
 }
 
-// UpdateItem handles PUT /api/v1/items/{id} requests.
+// UpdateItem handles PUT /api/v1/items/{id} requests. With upsert=true and
+// no If-Match header, a missing item is created with the ID of the path.
 func (h *RESTHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	upsert := false
+	if raw := r.URL.Query().Get("upsert"); raw != "" {
+		var err error
+		if upsert, err = strconv.ParseBool(raw); err != nil {
+			h.writeError(w, http.StatusBadRequest, "upsert must be true or false")
+			return
+		}
+	}
+
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
 
 	var input model.Item
@@ -141,22 +225,124 @@ func (h *RESTHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	item, err := h.store.Update(ctx, id, &input)
+	if upsert && r.Header.Get("If-Match") == "" {
+		h.upsertItem(w, r, id, &input)
+		return
+	}
+
+	expectedVersion, err := h.ifMatchVersion(r, id)
 	if err != nil {
 		h.handleStoreError(w, err, "update item")
 		return
 	}
 
+	item, err := h.store.Update(ctx, id, &input, expectedVersion)
+	if err != nil {
+		h.handleStoreError(w, err, "update item")
+		return
+	}
+
+	w.Header().Set("ETag", itemETag(item))
 	h.writeJSON(w, http.StatusOK, model.NewSuccessResponse(item))
 }
 
+// upsertItem replaces the item with id by input, creating it if it does not
+// exist, and answers 201 Created or 200 OK accordingly.
+func (h *RESTHandler) upsertItem(w http.ResponseWriter, r *http.Request, id string, input *model.Item) {
+	if err := h.ids.Validate(id); err != nil {
+		h.handleStoreError(w, err, "upsert item")
+		return
+	}
+
+	item, created, err := h.store.Upsert(r.Context(), id, input)
+	if err != nil {
+		h.handleStoreError(w, err, "upsert item")
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("ETag", itemETag(item))
+	h.writeJSON(w, status, model.NewSuccessResponse(item))
+}
+
+// PatchItem handles PATCH /api/v1/items/{id} requests. The body is either a
+// JSON Merge Patch (application/merge-patch+json) or a JSON Patch
+// (application/json-patch+json) applied to the stored item.
+func (h *RESTHandler) PatchItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = ""
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.logger.Warn("invalid request body", zap.Error(err))
+		h.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	apply, err := newPatchFunc(mediaType, body)
+	if err != nil {
+		h.handlePatchError(w, err)
+		return
+	}
+
+	expectedVersion, err := h.ifMatchVersion(r, id)
+	if err != nil {
+		h.handleStoreError(w, err, "patch item")
+		return
+	}
+
+	item, err := patchItem(ctx, h.store, id, expectedVersion, apply)
+	if err != nil {
+		h.handlePatchError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", itemETag(item))
+	h.writeJSON(w, http.StatusOK, model.NewSuccessResponse(item))
+}
+
+// handlePatchError writes the response for errors returned while parsing or
+// applying a patch, deferring to handleStoreError for store errors.
+func (h *RESTHandler) handlePatchError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errUnsupportedPatchType):
+		w.Header().Set("Accept-Patch", mediaTypeMergePatch+", "+mediaTypeJSONPatch)
+		h.writeError(w, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, errInvalidPatch), errors.Is(err, errValidation):
+		h.logger.Warn("patch rejected", zap.Error(err))
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, errPatchTestFailed):
+		h.writeError(w, http.StatusConflict, err.Error())
+	default:
+		h.handleStoreError(w, err, "patch item")
+	}
+}
+
 // DeleteItem handles DELETE /api/v1/items/{id} requests.
 func (h *RESTHandler) DeleteItem(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	if err := h.store.Delete(ctx, id); err != nil {
+	expectedVersion, err := h.ifMatchVersion(r, id)
+	if err != nil {
+		h.handleStoreError(w, err, "delete item")
+		return
+	}
+
+	if err := h.store.Delete(ctx, id, expectedVersion); err != nil {
 		h.handleStoreError(w, err, "delete item")
 		return
 	}
@@ -164,18 +350,286 @@ func (h *RESTHandler) DeleteItem(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, http.StatusNoContent, nil)
 }
 
+// BatchItems handles POST /api/v1/items:batch requests. An atomic batch
+// (the default) is applied all-or-nothing and fails with the status of the
+// failing operation; a best_effort batch applies every valid operation and
+// answers 207 Multi-Status when some of them failed.
+func (h *RESTHandler) BatchItems(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchRequestBodySize)
+
+	var req BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Warn("invalid request body", zap.Error(err))
+		h.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.Mode == "" {
+		req.Mode = batchModeAtomic
+	}
+	if req.Mode != batchModeAtomic && req.Mode != batchModeBestEffort {
+		h.writeError(w, http.StatusBadRequest,
+			fmt.Sprintf("mode must be %q or %q", batchModeAtomic, batchModeBestEffort))
+		return
+	}
+	atomic := req.Mode == batchModeAtomic
+
+	if len(req.Operations) == 0 || len(req.Operations) > MaxBatchSize {
+		h.writeError(w, http.StatusBadRequest,
+			fmt.Sprintf("operations must contain between 1 and %d entries", MaxBatchSize))
+		return
+	}
+
+	ops := make([]store.BatchOp, len(req.Operations))
+	for i, op := range req.Operations {
+		ops[i] = store.BatchOp{
+			Type:            store.BatchOpType(op.Op),
+			ID:              op.ID,
+			Item:            op.Item,
+			ExpectedVersion: op.ExpectedVersion,
+		}
+	}
+
+	results, err := runBatch(ctx, h.store, h.ids, ops, atomic)
+	if err != nil {
+		h.handleStoreError(w, err, "batch items")
+		return
+	}
+
+	response, status := h.batchResponse(req.Mode, ops, results)
+	h.writeJSON(w, status, model.APIResponse[BatchResponse]{
+		Success: response.Failed == 0,
+		Data:    response,
+	})
+}
+
+// batchResponse builds the per-operation report of a batch and the overall
+// response status.
+func (h *RESTHandler) batchResponse(
+	mode string, ops []store.BatchOp, results []store.BatchResult,
+) (BatchResponse, int) {
+	response := BatchResponse{Mode: mode, Results: make([]BatchOperationResult, len(results))}
+	status := http.StatusOK
+
+	for i, result := range results {
+		entry := BatchOperationResult{Index: i, Op: string(ops[i].Type), Item: result.Item}
+		if result.Err == nil {
+			entry.Status = batchSuccessStatus(ops[i].Type)
+			response.Results[i] = entry
+			response.Succeeded++
+			continue
+		}
+
+		entry.Status, entry.Error = h.batchOpErrorStatus(result.Err)
+		response.Results[i] = entry
+		response.Failed++
+		switch {
+		case mode == batchModeBestEffort:
+			status = http.StatusMultiStatus
+		case entry.Status != http.StatusFailedDependency:
+			status = entry.Status
+		}
+	}
+
+	return response, status
+}
+
+// batchSuccessStatus returns the status of a successful batch operation.
+func batchSuccessStatus(opType store.BatchOpType) int {
+	switch opType {
+	case store.BatchCreate:
+		return http.StatusCreated
+	case store.BatchDelete:
+		return http.StatusNoContent
+	default:
+		return http.StatusOK
+	}
+}
+
+// batchOpErrorStatus maps the error of a batch operation to a status and
+// message. Operations rolled back or skipped because of another operation
+// report 424 Failed Dependency.
+func (h *RESTHandler) batchOpErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, store.ErrBatchAborted):
+		return http.StatusFailedDependency, err.Error()
+	case errors.Is(err, errValidation), errors.Is(err, store.ErrInvalidBatchOp):
+		return http.StatusBadRequest, err.Error()
+	}
+
+	status, message := storeErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		h.logger.Error("store operation failed", zap.String("operation", "batch items"), zap.Error(err))
+	}
+	return status, message
+}
+
+// parseListQuery converts list query parameters into store.ListOptions,
+// applying DefaultPageSize when no limit is given.
+func parseListQuery(query url.Values) (store.ListOptions, error) {
+	opts := store.ListOptions{
+		Limit:      DefaultPageSize,
+		Cursor:     query.Get("cursor"),
+		NamePrefix: query.Get("name_prefix"),
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > MaxPageSize {
+			return opts, fmt.Errorf("%w: limit must be between 1 and %d", errInvalidQuery, MaxPageSize)
+		}
+		opts.Limit = limit
+	}
+
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return opts, fmt.Errorf("%w: offset must be a non-negative integer", errInvalidQuery)
+		}
+		opts.Offset = offset
+	}
+
+	if v := query.Get("sort"); v != "" {
+		field, descending := strings.CutPrefix(v, "-")
+		opts.SortBy = store.SortField(field)
+		opts.Descending = descending
+	}
+
+	var err error
+	if opts.MinPrice, err = parsePriceParam(query, "min_price"); err != nil {
+		return opts, err
+	}
+	if opts.MaxPrice, err = parsePriceParam(query, "max_price"); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+// parsePriceParam parses an optional numeric query parameter.
+func parsePriceParam(query url.Values, param string) (*float64, error) {
+	v := query.Get(param)
+	if v == "" {
+		return nil, nil
+	}
+
+	price, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be a number", errInvalidQuery, param)
+	}
+
+	return &price, nil
+}
+
+// ifMatchVersion turns the If-Match header into the expected version passed
+// to the store. It returns 0 (unconditional) when the header is absent, and
+// also for "*" once the item is known to exist: as RFC 9110 requires, "*"
+// fails with ErrVersionConflict when there is no current item. When several
+// entity tags are listed, the current item is read to pick the one that
+// applies; the store still re-checks it atomically.
+func (h *RESTHandler) ifMatchVersion(r *http.Request, id string) (int64, error) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, nil
+	}
+
+	if strings.TrimSpace(header) == "*" {
+		if _, err := h.store.Get(r.Context(), id); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return 0, store.ErrVersionConflict
+			}
+			return 0, err
+		}
+		return 0, nil
+	}
+
+	// If-Match uses strong comparison, so weak tags never match.
+	var versions []int64
+	for _, tag := range strings.Split(header, ",") {
+		if version, ok := parseItemETag(strings.TrimSpace(tag)); ok {
+			versions = append(versions, version)
+		}
+	}
+
+	switch len(versions) {
+	case 0:
+		return 0, errPreconditionFailed
+	case 1:
+		return versions[0], nil
+	}
+
+	current, err := h.store.Get(r.Context(), id)
+	if err != nil {
+		return 0, err
+	}
+	if !slices.Contains(versions, current.Version) {
+		return 0, errPreconditionFailed
+	}
+
+	return current.Version, nil
+}
+
+// itemETag returns the strong entity tag for the item's current version.
+func itemETag(item *model.Item) string {
+	return `"` + strconv.FormatInt(item.Version, 10) + `"`
+}
+
+// parseItemETag extracts the version from a strong entity tag produced by
+// itemETag.
+func parseItemETag(tag string) (int64, bool) {
+	unquoted, ok := strings.CutPrefix(tag, `"`)
+	if !ok {
+		return 0, false
+	}
+	unquoted, ok = strings.CutSuffix(unquoted, `"`)
+	if !ok {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version < 1 {
+		return 0, false
+	}
+
+	return version, true
+}
+
+// ifNoneMatch reports whether an If-None-Match header value matches etag
+// using weak comparison, as RFC 9110 requires for that header.
+func ifNoneMatch(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
 // handleStoreError handles store errors and writes appropriate HTTP responses.
 func (h *RESTHandler) handleStoreError(w http.ResponseWriter, err error, operation string) {
+	status, message := storeErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		h.logger.Error("store operation failed", zap.String("operation", operation), zap.Error(err))
+	}
+	h.writeError(w, status, message)
+}
+
+// storeErrorStatus maps a store error to an HTTP status and client message.
+func storeErrorStatus(err error) (int, string) {
 	switch {
 	case errors.Is(err, store.ErrNotFound):
-		h.writeError(w, http.StatusNotFound, "item not found")
+		return http.StatusNotFound, "item not found"
 	case errors.Is(err, store.ErrInvalidID):
-		h.writeError(w, http.StatusBadRequest, "invalid item ID")
+		return http.StatusBadRequest, "invalid item ID"
 	case errors.Is(err, store.ErrAlreadyExists):
-		h.writeError(w, http.StatusConflict, "item already exists")
+		return http.StatusConflict, "item already exists"
+	case errors.Is(err, store.ErrVersionConflict), errors.Is(err, errPreconditionFailed):
+		return http.StatusPreconditionFailed, "item has been modified"
 	default:
-		h.logger.Error("store operation failed", zap.String("operation", operation), zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "internal server error")
+		return http.StatusInternalServerError, "internal server error"
 	}
 }
 
@@ -5,8 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/mux"
@@ -16,9 +19,14 @@ import (
 	"github.com/vyrodovalexey/restapi-example/internal/store"
 )
 
+// testUUID is an item ID accepted by the default ID validator.
+const testUUID = "5b7c4a38-4f5a-4f5e-9d2a-0f4c1d1e2a3b"
+
 // mockStore implements store.Store for testing
 type mockStore struct {
 	items      map[string]model.Item
+	listOpts   store.ListOptions
+	nextCursor string
 	listErr    error
 	getErr     error
 	createErr  error
@@ -26,6 +34,11 @@ type mockStore struct {
 	deleteErr  error
 	createItem *model.Item
 	updateItem *model.Item
+
+	// gotVersion records the expectedVersion of the last Update/Delete call.
+	gotVersion int64
+
+	batchErr error
 }
 
 func newMockStore() *mockStore {
@@ -34,7 +47,8 @@ func newMockStore() *mockStore {
 	}
 }
 
-func (m *mockStore) List(_ context.Context) ([]model.Item, error) {
+func (m *mockStore) List(_ context.Context, opts store.ListOptions) (*store.ListPage, error) {
+	m.listOpts = opts
 	if m.listErr != nil {
 		return nil, m.listErr
 	}
@@ -42,7 +56,7 @@ func (m *mockStore) List(_ context.Context) ([]model.Item, error) {
 	for _, item := range m.items {
 		items = append(items, item)
 	}
-	return items, nil
+	return &store.ListPage{Items: items, Total: len(items), NextCursor: m.nextCursor}, nil
 }
 
 func (m *mockStore) Get(_ context.Context, id string) (*model.Item, error) {
@@ -64,38 +78,87 @@ func (m *mockStore) Create(_ context.Context, item *model.Item) (*model.Item, er
 		return m.createItem, nil
 	}
 	newItem := *item
-	newItem.ID = "generated-id"
+	if newItem.ID == "" {
+		newItem.ID = "generated-id"
+	}
+	if _, exists := m.items[newItem.ID]; exists {
+		return nil, store.ErrAlreadyExists
+	}
+	newItem.Version = 1
 	m.items[newItem.ID] = newItem
 	return &newItem, nil
 }
 
-func (m *mockStore) Update(_ context.Context, id string, item *model.Item) (*model.Item, error) {
+func (m *mockStore) Update(_ context.Context, id string, item *model.Item, version int64) (*model.Item, error) {
+	m.gotVersion = version
 	if m.updateErr != nil {
 		return nil, m.updateErr
 	}
 	if m.updateItem != nil {
 		return m.updateItem, nil
 	}
-	if _, exists := m.items[id]; !exists {
+	existing, exists := m.items[id]
+	if !exists {
 		return nil, store.ErrNotFound
 	}
+	if version != 0 && version != existing.Version {
+		return nil, store.ErrVersionConflict
+	}
 	updatedItem := *item
 	updatedItem.ID = id
+	updatedItem.Version = existing.Version + 1
 	m.items[id] = updatedItem
 	return &updatedItem, nil
 }
 
-func (m *mockStore) Delete(_ context.Context, id string) error {
+func (m *mockStore) Delete(_ context.Context, id string, version int64) error {
+	m.gotVersion = version
 	if m.deleteErr != nil {
 		return m.deleteErr
 	}
-	if _, exists := m.items[id]; !exists {
+	existing, exists := m.items[id]
+	if !exists {
 		return store.ErrNotFound
 	}
+	if version != 0 && version != existing.Version {
+		return store.ErrVersionConflict
+	}
 	delete(m.items, id)
 	return nil
 }
 
+func (m *mockStore) Upsert(_ context.Context, id string, item *model.Item) (*model.Item, bool, error) {
+	if m.updateErr != nil {
+		return nil, false, m.updateErr
+	}
+	existing, exists := m.items[id]
+	upserted := *item
+	upserted.ID = id
+	upserted.Version = existing.Version + 1
+	m.items[id] = upserted
+	return &upserted, !exists, nil
+}
+
+// Batch applies each operation through the mock's own methods; atomic
+// rollback is not modeled.
+func (m *mockStore) Batch(ctx context.Context, ops []store.BatchOp, _ bool) ([]store.BatchResult, error) {
+	if m.batchErr != nil {
+		return nil, m.batchErr
+	}
+	results := make([]store.BatchResult, len(ops))
+	for i, op := range ops {
+		switch op.Type {
+		case store.BatchCreate:
+			results[i].Item, results[i].Err = m.Create(ctx, op.Item)
+		case store.BatchUpdate:
+			results[i].Item, results[i].Err = m.Update(ctx, op.ID, op.Item, op.ExpectedVersion)
+		case store.BatchDelete:
+			results[i].Err = m.Delete(ctx, op.ID, op.ExpectedVersion)
+		}
+	}
+	return results, nil
+}
+
 func TestNewRESTHandler(t *testing.T) {
 	// Arrange
 	mockStore := newMockStore()
@@ -232,6 +295,125 @@ func TestRESTHandler_ListItems(t *testing.T) {
 	}
 }
 
+func TestRESTHandler_ListItems_QueryParameters(t *testing.T) {
+	minPrice, maxPrice := 1.5, 20.0
+
+	tests := []struct {
+		name     string
+		query    string
+		wantOpts store.ListOptions
+	}{
+		{
+			name:     "defaults",
+			query:    "",
+			wantOpts: store.ListOptions{Limit: DefaultPageSize},
+		},
+		{
+			name:  "offset pagination with descending sort",
+			query: "?limit=10&offset=20&sort=-price",
+			wantOpts: store.ListOptions{
+				Limit: 10, Offset: 20, SortBy: store.SortByPrice, Descending: true,
+			},
+		},
+		{
+			name:  "cursor with filters",
+			query: "?limit=5&cursor=abc&sort=name&name_prefix=Wid&min_price=1.5&max_price=20",
+			wantOpts: store.ListOptions{
+				Limit: 5, Cursor: "abc", SortBy: store.SortByName, NamePrefix: "Wid",
+				MinPrice: &minPrice, MaxPrice: &maxPrice,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockStore := newMockStore()
+			mockStore.items["1"] = model.Item{ID: "1", Name: "Widget", Price: 10}
+			mockStore.nextCursor = "next"
+			handler := NewRESTHandler(mockStore, zap.NewNop())
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/items"+tt.query, nil)
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.ListItems(rr, req)
+
+			// Assert
+			if rr.Code != http.StatusOK {
+				t.Fatalf("ListItems() status = %d, want %d", rr.Code, http.StatusOK)
+			}
+
+			got := mockStore.listOpts
+			if got.Limit != tt.wantOpts.Limit || got.Offset != tt.wantOpts.Offset ||
+				got.Cursor != tt.wantOpts.Cursor || got.SortBy != tt.wantOpts.SortBy ||
+				got.Descending != tt.wantOpts.Descending || got.NamePrefix != tt.wantOpts.NamePrefix {
+				t.Errorf("ListOptions = %+v, want %+v", got, tt.wantOpts)
+			}
+			if !equalFloatPtr(got.MinPrice, tt.wantOpts.MinPrice) || !equalFloatPtr(got.MaxPrice, tt.wantOpts.MaxPrice) {
+				t.Errorf("price range = (%v, %v), want (%v, %v)",
+					got.MinPrice, got.MaxPrice, tt.wantOpts.MinPrice, tt.wantOpts.MaxPrice)
+			}
+
+			var response model.APIResponse[[]model.Item]
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Meta == nil {
+				t.Fatal("ListItems() response.Meta = nil, want pagination metadata")
+			}
+			if response.Meta.Total != 1 || response.Meta.Limit != tt.wantOpts.Limit ||
+				response.Meta.Offset != tt.wantOpts.Offset || response.Meta.NextCursor != "next" {
+				t.Errorf("ListItems() meta = %+v", *response.Meta)
+			}
+		})
+	}
+}
+
+func TestRESTHandler_ListItems_InvalidQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		listErr error
+	}{
+		{name: "non-numeric limit", query: "?limit=abc"},
+		{name: "zero limit", query: "?limit=0"},
+		{name: "limit above maximum", query: "?limit=1001"},
+		{name: "negative offset", query: "?offset=-1"},
+		{name: "non-numeric min price", query: "?min_price=cheap"},
+		{name: "non-numeric max price", query: "?max_price=dear"},
+		{name: "rejected options", listErr: store.ErrInvalidListOptions},
+		{name: "rejected cursor", listErr: store.ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockStore := newMockStore()
+			mockStore.listErr = tt.listErr
+			handler := NewRESTHandler(mockStore, zap.NewNop())
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/items"+tt.query, nil)
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.ListItems(rr, req)
+
+			// Assert
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("ListItems() status = %d, want %d", rr.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func equalFloatPtr(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
 func TestRESTHandler_GetItem(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -371,6 +553,29 @@ func TestRESTHandler_CreateItem(t *testing.T) {
 			wantStatus: http.StatusConflict,
 			wantErr:    true,
 		},
+		{
+			name:       "client ID",
+			body:       model.Item{ID: testUUID, Name: "Test Item", Price: 10},
+			setup:      func(_ *mockStore) {},
+			wantStatus: http.StatusCreated,
+			wantErr:    false,
+		},
+		{
+			name: "client ID taken",
+			body: model.Item{ID: testUUID, Name: "Test Item", Price: 10},
+			setup: func(m *mockStore) {
+				m.items[testUUID] = model.Item{ID: testUUID, Name: "Existing", Version: 1}
+			},
+			wantStatus: http.StatusConflict,
+			wantErr:    true,
+		},
+		{
+			name:       "invalid client ID",
+			body:       model.Item{ID: "sku-1", Name: "Test Item", Price: 10},
+			setup:      func(_ *mockStore) {},
+			wantStatus: http.StatusBadRequest,
+			wantErr:    true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -612,6 +817,524 @@ func TestRESTHandler_DeleteItem(t *testing.T) {
 	}
 }
 
+func TestRESTHandler_GetItem_ETag(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		wantStatus  int
+	}{
+		{name: "no precondition", wantStatus: http.StatusOK},
+		{name: "matching etag", ifNoneMatch: `"3"`, wantStatus: http.StatusNotModified},
+		{name: "matching weak etag", ifNoneMatch: `W/"3"`, wantStatus: http.StatusNotModified},
+		{name: "matching etag in list", ifNoneMatch: `"1", "3"`, wantStatus: http.StatusNotModified},
+		{name: "wildcard", ifNoneMatch: "*", wantStatus: http.StatusNotModified},
+		{name: "stale etag", ifNoneMatch: `"2"`, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockStore := newMockStore()
+			mockStore.items["123"] = model.Item{ID: "123", Name: "Item", Version: 3}
+			handler := NewRESTHandler(mockStore, zap.NewNop())
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/items/123", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "123"})
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.GetItem(rr, req)
+
+			// Assert
+			if rr.Code != tt.wantStatus {
+				t.Errorf("GetItem() status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+			if got := rr.Header().Get("ETag"); got != `"3"` {
+				t.Errorf("ETag = %s, want %q", got, `"3"`)
+			}
+			if tt.wantStatus == http.StatusNotModified && rr.Body.Len() != 0 {
+				t.Errorf("304 response should have no body, got %q", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestRESTHandler_CreateItem_ETag(t *testing.T) {
+	// Arrange
+	handler := NewRESTHandler(newMockStore(), zap.NewNop())
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/items", strings.NewReader(`{"name":"New","price":1}`))
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.CreateItem(rr, req)
+
+	// Assert
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("CreateItem() status = %d, want %d", rr.Code, http.StatusCreated)
+	}
+	if got := rr.Header().Get("ETag"); got != `"1"` {
+		t.Errorf("ETag = %s, want %q", got, `"1"`)
+	}
+}
+
+func TestRESTHandler_UpdateItem_IfMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		itemID      string
+		ifMatch     string
+		wantStatus  int
+		wantVersion int64
+		wantETag    string
+	}{
+		{name: "no precondition", wantStatus: http.StatusOK, wantVersion: 0, wantETag: `"4"`},
+		{name: "wildcard", ifMatch: "*", wantStatus: http.StatusOK, wantVersion: 0, wantETag: `"4"`},
+		{name: "wildcard on missing item", itemID: "missing", ifMatch: "*", wantStatus: http.StatusPreconditionFailed},
+		{name: "current etag", ifMatch: `"3"`, wantStatus: http.StatusOK, wantVersion: 3, wantETag: `"4"`},
+		{name: "current etag in list", ifMatch: `"1", "3"`, wantStatus: http.StatusOK, wantVersion: 3, wantETag: `"4"`},
+		{name: "stale etag", ifMatch: `"2"`, wantStatus: http.StatusPreconditionFailed, wantVersion: 2},
+		{name: "stale etag list", ifMatch: `"1", "2"`, wantStatus: http.StatusPreconditionFailed},
+		{name: "weak etag never matches", ifMatch: `W/"3"`, wantStatus: http.StatusPreconditionFailed},
+		{name: "foreign etag", ifMatch: `"abc"`, wantStatus: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockStore := newMockStore()
+			mockStore.items["123"] = model.Item{ID: "123", Name: "Item", Version: 3}
+			handler := NewRESTHandler(mockStore, zap.NewNop())
+			itemID := tt.itemID
+			if itemID == "" {
+				itemID = "123"
+			}
+
+			body := strings.NewReader(`{"name":"Updated","price":5}`)
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/items/"+itemID, body)
+			req = mux.SetURLVars(req, map[string]string{"id": itemID})
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.UpdateItem(rr, req)
+
+			// Assert
+			if rr.Code != tt.wantStatus {
+				t.Errorf("UpdateItem() status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+			if mockStore.gotVersion != tt.wantVersion {
+				t.Errorf("expectedVersion = %d, want %d", mockStore.gotVersion, tt.wantVersion)
+			}
+			if got := rr.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", got, tt.wantETag)
+			}
+		})
+	}
+}
+
+func TestRESTHandler_UpdateItem_Upsert(t *testing.T) {
+	tests := []struct {
+		name        string
+		pattern     string
+		itemID      string
+		query       string
+		ifMatch     string
+		existing    bool
+		wantStatus  int
+		wantVersion int64
+	}{
+		{name: "creates missing item", itemID: testUUID, query: "?upsert=true", wantStatus: http.StatusCreated, wantVersion: 1},
+		{name: "replaces existing item", itemID: testUUID, query: "?upsert=true", existing: true,
+			wantStatus: http.StatusOK, wantVersion: 4},
+		{name: "custom pattern", pattern: `sku-[0-9]+`, itemID: "sku-1", query: "?upsert=1",
+			wantStatus: http.StatusCreated, wantVersion: 1},
+		{name: "invalid ID", itemID: "sku-1", query: "?upsert=true", wantStatus: http.StatusBadRequest},
+		{name: "invalid upsert parameter", itemID: testUUID, query: "?upsert=maybe", wantStatus: http.StatusBadRequest},
+		{name: "without upsert", itemID: testUUID, query: "?upsert=false", wantStatus: http.StatusNotFound},
+		{name: "If-Match never creates", itemID: testUUID, query: "?upsert=true", ifMatch: "*",
+			wantStatus: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockStore := newMockStore()
+			if tt.existing {
+				mockStore.items[tt.itemID] = model.Item{ID: tt.itemID, Name: "Item", Version: 3}
+			}
+			ids, err := store.NewIDValidator(tt.pattern)
+			if err != nil {
+				t.Fatalf("NewIDValidator() error = %v", err)
+			}
+			handler := NewRESTHandler(mockStore, zap.NewNop())
+			handler.SetIDValidator(ids)
+
+			body := strings.NewReader(`{"name":"Upserted","price":5}`)
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/items/"+tt.itemID+tt.query, body)
+			req = mux.SetURLVars(req, map[string]string{"id": tt.itemID})
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.UpdateItem(rr, req)
+
+			// Assert
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("UpdateItem() status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+			if tt.wantVersion == 0 {
+				return
+			}
+			if got, want := rr.Header().Get("ETag"), fmt.Sprintf(`"%d"`, tt.wantVersion); got != want {
+				t.Errorf("ETag = %s, want %s", got, want)
+			}
+			if stored := mockStore.items[tt.itemID]; stored.Name != "Upserted" {
+				t.Errorf("stored item = %+v, want the upserted item", stored)
+			}
+		})
+	}
+}
+
+func TestRESTHandler_DeleteItem_IfMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		itemID     string
+		ifMatch    string
+		wantStatus int
+	}{
+		{name: "current etag", ifMatch: `"3"`, wantStatus: http.StatusNoContent},
+		{name: "stale etag", ifMatch: `"2"`, wantStatus: http.StatusPreconditionFailed},
+		{name: "wildcard", ifMatch: "*", wantStatus: http.StatusNoContent},
+		{name: "wildcard on missing item", itemID: "missing", ifMatch: "*", wantStatus: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockStore := newMockStore()
+			mockStore.items["123"] = model.Item{ID: "123", Name: "Item", Version: 3}
+			handler := NewRESTHandler(mockStore, zap.NewNop())
+			itemID := tt.itemID
+			if itemID == "" {
+				itemID = "123"
+			}
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/items/"+itemID, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": itemID})
+			req.Header.Set("If-Match", tt.ifMatch)
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.DeleteItem(rr, req)
+
+			// Assert
+			if rr.Code != tt.wantStatus {
+				t.Errorf("DeleteItem() status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+			_, exists := mockStore.items["123"]
+			if exists != (tt.wantStatus != http.StatusNoContent) {
+				t.Errorf("item exists = %v after status %d", exists, rr.Code)
+			}
+		})
+	}
+}
+
+func TestRESTHandler_PatchItem(t *testing.T) {
+	tests := []struct {
+		name          string
+		contentType   string
+		ifMatch       string
+		body          string
+		wantStatus    int
+		wantName      string
+		wantPrice     float64
+		wantETag      string
+		wantAcceptHdr bool
+	}{
+		{
+			name:        "merge patch",
+			contentType: "application/merge-patch+json",
+			body:        `{"price": 42}`,
+			wantStatus:  http.StatusOK,
+			wantName:    "Item",
+			wantPrice:   42,
+			wantETag:    `"4"`,
+		},
+		{
+			name:        "merge patch with charset parameter",
+			contentType: "application/merge-patch+json; charset=utf-8",
+			body:        `{"name": "Renamed"}`,
+			wantStatus:  http.StatusOK,
+			wantName:    "Renamed",
+			wantPrice:   10,
+			wantETag:    `"4"`,
+		},
+		{
+			name:        "json patch with matching If-Match",
+			contentType: "application/json-patch+json",
+			ifMatch:     `"3"`,
+			body:        `[{"op":"replace","path":"/name","value":"Patched"}]`,
+			wantStatus:  http.StatusOK,
+			wantName:    "Patched",
+			wantPrice:   10,
+			wantETag:    `"4"`,
+		},
+		{
+			name:        "stale If-Match",
+			contentType: "application/merge-patch+json",
+			ifMatch:     `"2"`,
+			body:        `{"price": 1}`,
+			wantStatus:  http.StatusPreconditionFailed,
+		},
+		{
+			name:          "unsupported media type",
+			contentType:   "application/json",
+			body:          `{"price": 1}`,
+			wantStatus:    http.StatusUnsupportedMediaType,
+			wantAcceptHdr: true,
+		},
+		{
+			name:        "malformed patch",
+			contentType: "application/json-patch+json",
+			body:        `not json`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "invalid result",
+			contentType: "application/merge-patch+json",
+			body:        `{"name": ""}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "failed test operation",
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"test","path":"/price","value":99}]`,
+			wantStatus:  http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockStore := newMockStore()
+			mockStore.items["123"] = model.Item{ID: "123", Name: "Item", Price: 10, Version: 3}
+			handler := NewRESTHandler(mockStore, zap.NewNop())
+
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/items/123", strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"id": "123"})
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.PatchItem(rr, req)
+
+			// Assert
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("PatchItem() status = %d, want %d (body %s)", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+			if tt.wantAcceptHdr && rr.Header().Get("Accept-Patch") == "" {
+				t.Error("415 response should advertise Accept-Patch")
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response model.APIResponse[model.Item]
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Data.Name != tt.wantName || response.Data.Price != tt.wantPrice {
+				t.Errorf("PatchItem() = %+v, want name %q price %v", response.Data, tt.wantName, tt.wantPrice)
+			}
+			if got := rr.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", got, tt.wantETag)
+			}
+		})
+	}
+}
+
+func TestRESTHandler_PatchItem_NotFound(t *testing.T) {
+	// Arrange
+	handler := NewRESTHandler(newMockStore(), zap.NewNop())
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/items/missing", strings.NewReader(`{"price": 1}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "missing"})
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.PatchItem(rr, req)
+
+	// Assert
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("PatchItem() status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestRESTHandler_BatchItems(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		wantStatus    int
+		wantSuccess   bool
+		wantStatuses  []int
+		wantItemCount int
+	}{
+		{
+			name: "atomic success",
+			body: `{"operations": [
+				{"op": "create", "item": {"name": "New", "price": 1}},
+				{"op": "update", "id": "123", "item": {"name": "Renamed", "price": 2}, "expected_version": 1},
+				{"op": "delete", "id": "456"}]}`,
+			wantStatus:    http.StatusOK,
+			wantSuccess:   true,
+			wantStatuses:  []int{http.StatusCreated, http.StatusOK, http.StatusNoContent},
+			wantItemCount: 2,
+		},
+		{
+			name: "atomic failure is rolled back",
+			body: `{"mode": "atomic", "operations": [
+				{"op": "create", "item": {"name": "New", "price": 1}},
+				{"op": "delete", "id": "123", "expected_version": 5}]}`,
+			wantStatus:    http.StatusPreconditionFailed,
+			wantStatuses:  []int{http.StatusFailedDependency, http.StatusPreconditionFailed},
+			wantItemCount: 2,
+		},
+		{
+			name: "atomic batch with invalid operation",
+			body: `{"operations": [
+				{"op": "delete", "id": "123"},
+				{"op": "create", "item": {"name": "", "price": 1}}]}`,
+			wantStatus:    http.StatusBadRequest,
+			wantStatuses:  []int{http.StatusFailedDependency, http.StatusBadRequest},
+			wantItemCount: 2,
+		},
+		{
+			name: "best effort partial failure",
+			body: `{"mode": "best_effort", "operations": [
+				{"op": "delete", "id": "missing"},
+				{"op": "replace", "id": "123"},
+				{"op": "delete", "id": "123"}]}`,
+			wantStatus:    http.StatusMultiStatus,
+			wantStatuses:  []int{http.StatusNotFound, http.StatusBadRequest, http.StatusNoContent},
+			wantItemCount: 1,
+		},
+		{
+			name:          "unknown mode",
+			body:          `{"mode": "eventual", "operations": [{"op": "delete", "id": "123"}]}`,
+			wantStatus:    http.StatusBadRequest,
+			wantItemCount: 2,
+		},
+		{
+			name:          "no operations",
+			body:          `{"operations": []}`,
+			wantStatus:    http.StatusBadRequest,
+			wantItemCount: 2,
+		},
+		{
+			name:          "invalid JSON",
+			body:          `{"operations": `,
+			wantStatus:    http.StatusBadRequest,
+			wantItemCount: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			s := store.NewMemoryStore()
+			first, _ := s.Create(ctx, &model.Item{Name: "First", Price: 10})
+			second, _ := s.Create(ctx, &model.Item{Name: "Second", Price: 20})
+			body := strings.NewReplacer(`"123"`, strconv.Quote(first.ID), `"456"`, strconv.Quote(second.ID)).
+				Replace(tt.body)
+			router := mux.NewRouter()
+			NewRESTHandler(s, zap.NewNop()).RegisterRoutes(router)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/items:batch", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rr, req)
+
+			// Assert
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("BatchItems() status = %d, want %d (body %s)", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+			page, _ := s.List(ctx, store.ListOptions{})
+			if page.Total != tt.wantItemCount {
+				t.Errorf("item count = %d, want %d", page.Total, tt.wantItemCount)
+			}
+			if tt.wantStatuses == nil {
+				return
+			}
+
+			var response model.APIResponse[BatchResponse]
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v", response.Success, tt.wantSuccess)
+			}
+			if len(response.Data.Results) != len(tt.wantStatuses) {
+				t.Fatalf("got %d results, want %d", len(response.Data.Results), len(tt.wantStatuses))
+			}
+			for i, want := range tt.wantStatuses {
+				result := response.Data.Results[i]
+				if result.Index != i || result.Status != want {
+					t.Errorf("results[%d] = %+v, want status %d", i, result, want)
+				}
+				if (want >= http.StatusBadRequest) != (result.Error != "") {
+					t.Errorf("results[%d].Error = %q does not match status %d", i, result.Error, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRESTHandler_BatchItems_TooManyOperations(t *testing.T) {
+	// Arrange
+	ops := strings.Repeat(`{"op":"delete","id":"1"},`, MaxBatchSize)
+	body := `{"operations": [` + ops + `{"op":"delete","id":"1"}]}`
+	handler := NewRESTHandler(newMockStore(), zap.NewNop())
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/items:batch", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.BatchItems(rr, req)
+
+	// Assert
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("BatchItems() status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestRESTHandler_BatchItems_StoreError(t *testing.T) {
+	// Arrange
+	ms := newMockStore()
+	ms.batchErr = errors.New("connection refused")
+	handler := NewRESTHandler(ms, zap.NewNop())
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/items:batch",
+		strings.NewReader(`{"operations": [{"op": "delete", "id": "1"}]}`))
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.BatchItems(rr, req)
+
+	// Assert
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("BatchItems() status = %d, want %d", rr.Code, http.StatusInternalServerError)
+	}
+}
+
 func TestRESTHandler_RegisterRoutes(t *testing.T) {
 	// Arrange
 	mockStore := newMockStore()
@@ -634,6 +1357,7 @@ func TestRESTHandler_RegisterRoutes(t *testing.T) {
 		{http.MethodPost, "/api/v1/items", http.StatusCreated},
 		{http.MethodGet, "/api/v1/items/123", http.StatusOK},
 		{http.MethodPut, "/api/v1/items/123", http.StatusOK},
+		{http.MethodPatch, "/api/v1/items/123", http.StatusOK},
 		{http.MethodDelete, "/api/v1/items/123", http.StatusNoContent},
 	}
 
@@ -647,7 +1371,7 @@ func TestRESTHandler_RegisterRoutes(t *testing.T) {
 			handler.RegisterRoutes(router)
 
 			var body *bytes.Reader
-			if tt.method == http.MethodPost || tt.method == http.MethodPut {
+			if tt.method == http.MethodPost || tt.method == http.MethodPut || tt.method == http.MethodPatch {
 				body = bytes.NewReader([]byte(`{"name":"Test","price":10}`))
 			} else {
 				body = bytes.NewReader(nil)
@@ -655,6 +1379,9 @@ func TestRESTHandler_RegisterRoutes(t *testing.T) {
 
 			req := httptest.NewRequest(tt.method, tt.path, body)
 			req.Header.Set("Content-Type", "application/json")
+			if tt.method == http.MethodPatch {
+				req.Header.Set("Content-Type", mediaTypeMergePatch)
+			}
 			rr := httptest.NewRecorder()
 
 			router.ServeHTTP(rr, req)
@@ -1,5 +1,8 @@
 // websocket.go implements the WebSocket handler that upgrades HTTP connections
-// and streams random values to connected clients at regular intervals.
+// and streams random values to connected clients at regular intervals. Clients
+// may instead subscribe to item change events, optionally for selected items
+// only. When authentication is enabled, clients that did not authenticate the
+// upgrade request must send an auth message first.
 
 package handler
 
@@ -7,6 +10,8 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/binary"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"sync"
 	"time"
@@ -15,8 +20,11 @@ import (
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/authz"
 	"github.com/vyrodovalexey/restapi-example/internal/model"
 	"github.com/vyrodovalexey/restapi-example/internal/observability"
+	"github.com/vyrodovalexey/restapi-example/internal/store"
 )
 
 // WebSocket configuration constants.
@@ -24,31 +32,122 @@ const (
 	writeWait      = 10 * time.Second
 	pongWait       = 60 * time.Second
 	pingPeriod     = (pongWait * 9) / 10
-	maxMessageSize = 512
+	maxMessageSize = 4096 // fits a subscribe message with maxSubscribedItems IDs
 	sendInterval   = 1 * time.Second
+
+	eventBufferSize    = 64  // item events buffered per connection
+	maxSubscribedItems = 100 // item IDs accepted in one subscribe message
 )
 
+// ItemEventSource publishes item change events. It is implemented by
+// store.EventStore.
+type ItemEventSource interface {
+	Subscribe(buffer int) (<-chan store.ItemEvent, func())
+}
+
+// itemEventMessageTypes maps store events onto WebSocket message types.
+var itemEventMessageTypes = map[store.ItemEventType]string{
+	store.ItemCreated: model.WSMessageTypeItemCreated,
+	store.ItemUpdated: model.WSMessageTypeItemUpdated,
+	store.ItemDeleted: model.WSMessageTypeItemDeleted,
+}
+
 // connState holds per-connection state including write synchronization.
 type connState struct {
-	cancel  context.CancelFunc
-	writeMu sync.Mutex // serializes all writes to this connection
+	cancel    context.CancelFunc
+	writeMu   sync.Mutex // serializes all writes to this connection
+	closeSent bool       // guarded by writeMu
+
+	// request is a copy of the upgrade request, used to authenticate
+	// in-band credentials.
+	request *http.Request
+
+	authMu        sync.Mutex
+	authenticated bool
+	authInfo      *auth.AuthInfo // nil when authentication is disabled
+	authTimer     *time.Timer    // enforces authWait, then credential expiry
+
+	// events receives item events when the handler has an event source;
+	// unsubscribe releases it.
+	events      <-chan store.ItemEvent
+	unsubscribe func()
+
+	subMu      sync.Mutex
+	subscribed bool
+	itemIDs    map[string]struct{} // nil selects all items
+}
+
+// isSubscribed reports whether the client subscribed to item events.
+func (s *connState) isSubscribed() bool {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	return s.subscribed
+}
+
+// isAuthenticated reports whether the client may use the connection.
+func (s *connState) isAuthenticated() bool {
+	s.authMu.Lock()
+	defer s.authMu.Unlock()
+	return s.authenticated
+}
+
+// setAuthTimer replaces the connection's auth timer.
+func (s *connState) setAuthTimer(timer *time.Timer) {
+	s.authMu.Lock()
+	defer s.authMu.Unlock()
+
+	if s.authTimer != nil {
+		s.authTimer.Stop()
+	}
+	s.authTimer = timer
+}
+
+// release unsubscribes the connection from item events and stops its auth
+// timer.
+func (s *connState) release() {
+	if s.unsubscribe != nil {
+		s.unsubscribe()
+	}
+	s.setAuthTimer(nil)
+}
+
+// wants reports whether event matches the client's subscription.
+func (s *connState) wants(event *store.ItemEvent) bool {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	if !s.subscribed {
+		return false
+	}
+	if s.itemIDs == nil {
+		return true
+	}
+	_, ok := s.itemIDs[event.ItemID]
+	return ok
 }
 
 // WebSocketHandler handles WebSocket connections.
 type WebSocketHandler struct {
-	upgrader websocket.Upgrader
-	logger   *zap.Logger
-	mu       sync.RWMutex
-	clients  map[*websocket.Conn]*connState
-	wg       sync.WaitGroup // tracks active writePump goroutines
+	upgrader      websocket.Upgrader
+	logger        *zap.Logger
+	events        ItemEventSource
+	authenticator auth.Authenticator
+	authorizer    *authz.Authorizer
+	mu            sync.RWMutex
+	clients       map[*websocket.Conn]*connState
+	wg            sync.WaitGroup // tracks active writePump goroutines
 }
 
-// NewWebSocketHandler creates a new WebSocketHandler instance.
-func NewWebSocketHandler(logger *zap.Logger) *WebSocketHandler {
-	return &WebSocketHandler{
+// NewWebSocketHandler creates a new WebSocketHandler instance. The optional
+// event source enables item event subscriptions; without it, subscribe
+// requests are answered with an error message.
+func NewWebSocketHandler(logger *zap.Logger, events ...ItemEventSource) *WebSocketHandler {
+	h := &WebSocketHandler{
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
+			// Selected when a browser passes its token as a subprotocol.
+			Subprotocols: []string{auth.WebSocketTokenProtocol},
 			CheckOrigin: func(_ *http.Request) bool {
 				return true // Allow all origins for development
 			},
@@ -56,11 +155,39 @@ func NewWebSocketHandler(logger *zap.Logger) *WebSocketHandler {
 		logger:  logger,
 		clients: make(map[*websocket.Conn]*connState),
 	}
+
+	if len(events) > 0 {
+		h.events = events[0]
+	}
+
+	return h
 }
 
-// RegisterRoutes registers the WebSocket routes with the router.
+// SetAuthenticator sets the authenticator for in-band authentication. It
+// must be called before the handler serves requests; without it, connections
+// are not authenticated.
+func (h *WebSocketHandler) SetAuthenticator(authenticator auth.Authenticator) {
+	h.authenticator = authenticator
+}
+
+// SetAuthorizer sets the authorizer applied to clients that authenticate
+// in-band; upgrade requests carrying credentials are authorized by
+// middleware.Authorize. It must be called before the handler serves requests.
+func (h *WebSocketHandler) SetAuthorizer(authorizer *authz.Authorizer) {
+	h.authorizer = authorizer
+}
+
+// SetCheckOrigin sets the function deciding whether an upgrade request from
+// its Origin is accepted. It must be called before the handler serves
+// requests; without it, all origins are accepted.
+func (h *WebSocketHandler) SetCheckOrigin(check func(r *http.Request) bool) {
+	h.upgrader.CheckOrigin = check
+}
+
+// RegisterRoutes registers the WebSocket routes with the router, on the
+// route named RouteWebSocket.
 func (h *WebSocketHandler) RegisterRoutes(router *mux.Router) {
-	router.HandleFunc("/ws", h.HandleWebSocket).Methods(http.MethodGet)
+	router.HandleFunc("/ws", h.HandleWebSocket).Methods(http.MethodGet).Name(RouteWebSocket)
 }
 
 // HandleWebSocket handles WebSocket connection requests.
@@ -78,7 +205,25 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 	// need to persist beyond the initial HTTP upgrade.
 	ctx, cancel := context.WithCancel(context.Background())
 
-	state := &connState{cancel: cancel}
+	state := &connState{cancel: cancel, request: r.Clone(ctx)}
+	if h.events != nil {
+		state.events, state.unsubscribe = h.events.Subscribe(eventBufferSize)
+	}
+
+	// middleware.Auth attaches the identity of an authenticated upgrade
+	// request; otherwise the client has authWait to send an auth message.
+	switch info, ok := auth.FromContext(r.Context()); {
+	case ok:
+		h.authenticateConn(conn, state, info)
+	case h.authenticator == nil:
+		state.authenticated = true
+	default:
+		state.authTimer = time.AfterFunc(authWait, func() {
+			if !state.isAuthenticated() {
+				h.closeConn(conn, state, websocket.ClosePolicyViolation, "authentication timeout")
+			}
+		})
+	}
 
 	h.mu.Lock()
 	h.clients[conn] = state
@@ -94,13 +239,13 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 		defer h.wg.Done()
 		h.writePump(ctx, conn, state)
 	}()
-	go h.readPump(ctx, conn, cancel)
+	go h.readPump(ctx, conn, state)
 }
 
 // readPump handles incoming messages from the WebSocket connection.
-func (h *WebSocketHandler) readPump(ctx context.Context, conn *websocket.Conn, cancel context.CancelFunc) {
+func (h *WebSocketHandler) readPump(ctx context.Context, conn *websocket.Conn, state *connState) {
 	defer func() {
-		cancel()
+		state.cancel()
 		h.removeClient(conn)
 		if err := conn.Close(); err != nil {
 			h.logger.Debug("error closing connection", zap.Error(err))
@@ -130,14 +275,18 @@ func (h *WebSocketHandler) readPump(ctx context.Context, conn *websocket.Conn, c
 				return
 			}
 			h.logger.Debug("received message", zap.ByteString("message", message))
+			h.handleClientMessage(conn, state, message)
 		}
 	}
 }
 
-// writePump sends random values to the WebSocket connection every second.
+// writePump sends random values to the WebSocket connection every second
+// until the client subscribes to item events, and forwards the item events
+// matching its subscription.
 func (h *WebSocketHandler) writePump(ctx context.Context, conn *websocket.Conn, state *connState) {
 	ticker := time.NewTicker(sendInterval)
 	pingTicker := time.NewTicker(pingPeriod)
+	events := state.events
 
 	defer func() {
 		ticker.Stop()
@@ -147,13 +296,29 @@ func (h *WebSocketHandler) writePump(ctx context.Context, conn *websocket.Conn,
 	for {
 		select {
 		case <-ctx.Done():
-			h.sendCloseMessage(conn, state)
+			h.sendCloseMessage(conn, state, websocket.CloseNormalClosure, "server shutting down")
 			return
 		case <-ticker.C:
+			if state.isSubscribed() || !state.isAuthenticated() {
+				continue
+			}
 			if err := h.sendRandomValue(conn, state); err != nil {
 				h.logger.Debug("failed to send random value", zap.Error(err))
 				return
 			}
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if !state.wants(&event) {
+				continue
+			}
+			msg := model.NewItemEventMessage(itemEventMessageTypes[event.Type], event.ItemID, event.Item, event.Time)
+			if err := h.sendMessage(conn, state, msg); err != nil {
+				h.logger.Debug("failed to send item event", zap.Error(err))
+				return
+			}
 		case <-pingTicker.C:
 			if err := h.sendPing(conn, state); err != nil {
 				h.logger.Debug("failed to send ping", zap.Error(err))
@@ -171,8 +336,11 @@ func (h *WebSocketHandler) sendRandomValue(conn *websocket.Conn, state *connStat
 		return err
 	}
 
-	msg := model.NewRandomValueMessage(value)
+	return h.sendMessage(conn, state, model.NewRandomValueMessage(value))
+}
 
+// sendMessage writes a JSON message to the connection.
+func (h *WebSocketHandler) sendMessage(conn *websocket.Conn, state *connState, msg model.WebSocketMessage) error {
 	state.writeMu.Lock()
 	defer state.writeMu.Unlock()
 
@@ -183,6 +351,124 @@ func (h *WebSocketHandler) sendRandomValue(conn *websocket.Conn, state *connStat
 	return conn.WriteJSON(msg)
 }
 
+// handleClientMessage processes an auth, subscribe or unsubscribe request
+// and acknowledges it. Malformed and unknown messages are answered with an
+// error message; the connection stays open. Any message other than auth
+// from an unauthenticated client closes the connection.
+func (h *WebSocketHandler) handleClientMessage(conn *websocket.Conn, state *connState, data []byte) {
+	var msg model.WebSocketMessage
+	err := json.Unmarshal(data, &msg)
+
+	var reply model.WebSocketMessage
+	switch {
+	case err == nil && msg.Type == model.WSMessageTypeAuth:
+		var ok bool
+		if reply, ok = h.applyAuth(conn, state, &msg); !ok {
+			return
+		}
+	case !state.isAuthenticated():
+		h.closeConn(conn, state, websocket.ClosePolicyViolation, "authentication required")
+		return
+	case err != nil:
+		reply = model.NewErrorMessage("invalid message: expected JSON")
+	default:
+		reply = h.applySubscription(state, &msg)
+	}
+
+	if err := h.sendMessage(conn, state, reply); err != nil {
+		h.logger.Debug("failed to send reply", zap.Error(err))
+	}
+}
+
+// applyAuth authenticates the bearer token of an auth message and returns
+// the reply to send. A failed attempt closes the connection and reports
+// false.
+func (h *WebSocketHandler) applyAuth(
+	conn *websocket.Conn, state *connState, msg *model.WebSocketMessage,
+) (model.WebSocketMessage, bool) {
+	if state.isAuthenticated() {
+		return model.NewErrorMessage("already authenticated"), true
+	}
+
+	var info *auth.AuthInfo
+	err := auth.ErrUnauthenticated
+	if msg.Token != "" {
+		info, err = authenticateInBand(h.authenticator, h.logger, state.request,
+			map[string]string{"Authorization": "Bearer " + msg.Token})
+	}
+	if err != nil {
+		h.closeConn(conn, state, websocket.ClosePolicyViolation, "authentication failed")
+		return model.WebSocketMessage{}, false
+	}
+
+	if err := authorizeInBand(h.authorizer, h.logger, state.request, info); err != nil {
+		h.closeConn(conn, state, websocket.ClosePolicyViolation, "forbidden")
+		return model.WebSocketMessage{}, false
+	}
+
+	h.authenticateConn(conn, state, info)
+
+	return model.WebSocketMessage{Type: model.WSMessageTypeAuthenticated, Timestamp: time.Now().UTC()}, true
+}
+
+// authenticateConn attaches info to the connection and arranges for the
+// connection to be closed when its credentials expire.
+func (h *WebSocketHandler) authenticateConn(conn *websocket.Conn, state *connState, info *auth.AuthInfo) {
+	state.setAuthTimer(afterExpiry(info, func() {
+		h.logger.Info("closing websocket connection: credentials expired", zap.String("subject", info.Subject))
+		h.closeConn(conn, state, websocket.ClosePolicyViolation, "token expired")
+	}))
+
+	state.authMu.Lock()
+	state.authenticated = true
+	state.authInfo = info
+	state.authMu.Unlock()
+}
+
+// applySubscription updates the connection's subscription from msg and
+// returns the reply to send.
+func (h *WebSocketHandler) applySubscription(state *connState, msg *model.WebSocketMessage) model.WebSocketMessage {
+	switch msg.Type {
+	case model.WSMessageTypeSubscribe:
+		if state.events == nil {
+			return model.NewErrorMessage("item events are not available")
+		}
+		if len(msg.ItemIDs) > maxSubscribedItems {
+			return model.NewErrorMessage(fmt.Sprintf("at most %d item_ids may be given", maxSubscribedItems))
+		}
+
+		var itemIDs map[string]struct{}
+		if len(msg.ItemIDs) > 0 {
+			itemIDs = make(map[string]struct{}, len(msg.ItemIDs))
+			for _, id := range msg.ItemIDs {
+				itemIDs[id] = struct{}{}
+			}
+		}
+
+		state.subMu.Lock()
+		state.subscribed = true
+		state.itemIDs = itemIDs
+		state.subMu.Unlock()
+
+		return model.WebSocketMessage{
+			Type:      model.WSMessageTypeSubscribed,
+			ItemIDs:   msg.ItemIDs,
+			Timestamp: time.Now().UTC(),
+		}
+
+	case model.WSMessageTypeUnsubscribe:
+		state.subMu.Lock()
+		state.subscribed = false
+		state.itemIDs = nil
+		state.subMu.Unlock()
+
+		return model.WebSocketMessage{Type: model.WSMessageTypeUnsubscribed, Timestamp: time.Now().UTC()}
+
+	default:
+		return model.NewErrorMessage(fmt.Sprintf("unknown message type %q", msg.Type))
+	}
+}
+
 // sendPing sends a ping message to the connection.
 func (h *WebSocketHandler) sendPing(conn *websocket.Conn, state *connState) error {
 	state.writeMu.Lock()
@@ -194,17 +480,35 @@ func (h *WebSocketHandler) sendPing(conn *websocket.Conn, state *connState) erro
 	return conn.WriteMessage(websocket.PingMessage, nil)
 }
 
-// sendCloseMessage sends a close message to the connection.
-func (h *WebSocketHandler) sendCloseMessage(conn *websocket.Conn, state *connState) {
+// closeConn sends a close message with the given code and stops the
+// connection.
+func (h *WebSocketHandler) closeConn(conn *websocket.Conn, state *connState, code int, reason string) {
+	h.sendCloseMessage(conn, state, code, reason)
+	state.cancel()
+
+	// Unblock readPump if the client does not complete the close handshake.
+	if err := conn.SetReadDeadline(time.Now().Add(writeWait)); err != nil {
+		h.logger.Debug("failed to set read deadline for close", zap.Error(err))
+	}
+}
+
+// sendCloseMessage sends a close message to the connection unless one has
+// already been sent.
+func (h *WebSocketHandler) sendCloseMessage(conn *websocket.Conn, state *connState, code int, reason string) {
 	state.writeMu.Lock()
 	defer state.writeMu.Unlock()
 
+	if state.closeSent {
+		return
+	}
+	state.closeSent = true
+
 	if err := conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
 		h.logger.Debug("failed to set write deadline for close", zap.Error(err))
 		return
 	}
 
-	closeMsg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "server shutting down")
+	closeMsg := websocket.FormatCloseMessage(code, reason)
 	if err := conn.WriteMessage(websocket.CloseMessage, closeMsg); err != nil {
 		h.logger.Debug("failed to send close message", zap.Error(err))
 	}
@@ -217,6 +521,7 @@ func (h *WebSocketHandler) removeClient(conn *websocket.Conn) {
 
 	if state, exists := h.clients[conn]; exists {
 		state.cancel()
+		state.release()
 		delete(h.clients, conn)
 		observability.WebSocketActiveConnections.Dec()
 		h.logger.Info("websocket client disconnected", zap.String("remote_addr", conn.RemoteAddr().String()))
@@ -241,7 +546,8 @@ func (h *WebSocketHandler) CloseAllConnections() {
 
 	// Now close all connections
 	h.mu.Lock()
-	for conn := range h.clients {
+	for conn, state := range h.clients {
+		state.release()
 		if err := conn.Close(); err != nil {
 			h.logger.Debug("error closing connection", zap.Error(err))
 		}
@@ -12,8 +12,12 @@ import (
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"go.uber.org/zap"
 
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/authz"
+	"github.com/vyrodovalexey/restapi-example/internal/middleware"
 	"github.com/vyrodovalexey/restapi-example/internal/model"
 	"github.com/vyrodovalexey/restapi-example/internal/observability"
+	"github.com/vyrodovalexey/restapi-example/internal/store"
 )
 
 // waitForGauge polls the websocket_active_connections gauge until it reaches
@@ -461,8 +465,8 @@ func TestWebSocketConstants(t *testing.T) {
 	if pingPeriod != (pongWait*9)/10 {
 		t.Errorf("pingPeriod = %v, want %v", pingPeriod, (pongWait*9)/10)
 	}
-	if maxMessageSize != 512 {
-		t.Errorf("maxMessageSize = %d, want 512", maxMessageSize)
+	if maxMessageSize != 4096 {
+		t.Errorf("maxMessageSize = %d, want 4096", maxMessageSize)
 	}
 	if sendInterval != 1*time.Second {
 		t.Errorf("sendInterval = %v, want 1s", sendInterval)
@@ -673,3 +677,395 @@ func TestWebSocketHandler_SendRandomValue(t *testing.T) {
 		t.Error("Timestamp should not be zero")
 	}
 }
+
+// dialEventSocket starts a WebSocket server backed by an EventStore and
+// returns the store and a connected client.
+func dialEventSocket(t *testing.T) (*store.EventStore, *websocket.Conn) {
+	t.Helper()
+
+	events := store.NewEventStore(store.NewMemoryStore())
+	wsHandler := NewWebSocketHandler(zap.NewNop(), events)
+	server := httptest.NewServer(http.HandlerFunc(wsHandler.HandleWebSocket))
+	t.Cleanup(func() {
+		wsHandler.CloseAllConnections()
+		server.Close()
+	})
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return events, conn
+}
+
+// readMessageOfType reads messages until one of the given type arrives,
+// skipping random values sent before the subscription took effect.
+func readMessageOfType(t *testing.T, conn *websocket.Conn, msgType string) model.WebSocketMessage {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg model.WebSocketMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("waiting for %s: %v", msgType, err)
+		}
+		if msg.Type == msgType {
+			return msg
+		}
+		if msg.Type != model.WSMessageTypeRandomValue {
+			t.Fatalf("got %+v, want %s", msg, msgType)
+		}
+	}
+}
+
+func TestWebSocketHandler_ItemEvents(t *testing.T) {
+	// Arrange
+	ctx := t.Context()
+	events, conn := dialEventSocket(t)
+	if err := conn.WriteJSON(model.WebSocketMessage{Type: model.WSMessageTypeSubscribe}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	readMessageOfType(t, conn, model.WSMessageTypeSubscribed)
+
+	// Act
+	created, err := events.Create(ctx, &model.Item{Name: "Widget", Price: 1})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := events.Update(ctx, created.ID, &model.Item{Name: "Gadget", Price: 2}, 0); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := events.Delete(ctx, created.ID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	// Assert
+	msg := readMessageOfType(t, conn, model.WSMessageTypeItemCreated)
+	if msg.ItemID != created.ID || msg.Item == nil || msg.Item.Name != "Widget" {
+		t.Errorf("item_created = %+v", msg)
+	}
+	msg = readMessageOfType(t, conn, model.WSMessageTypeItemUpdated)
+	if msg.Item == nil || msg.Item.Name != "Gadget" || msg.Item.Version != 2 {
+		t.Errorf("item_updated = %+v", msg)
+	}
+	msg = readMessageOfType(t, conn, model.WSMessageTypeItemDeleted)
+	if msg.ItemID != created.ID || msg.Item != nil {
+		t.Errorf("item_deleted = %+v", msg)
+	}
+}
+
+func TestWebSocketHandler_ItemEvents_FilterByID(t *testing.T) {
+	// Arrange
+	ctx := t.Context()
+	events, conn := dialEventSocket(t)
+	watched, _ := events.Create(ctx, &model.Item{Name: "Watched", Price: 1})
+	other, _ := events.Create(ctx, &model.Item{Name: "Other", Price: 1})
+	subscribe := model.WebSocketMessage{Type: model.WSMessageTypeSubscribe, ItemIDs: []string{watched.ID}}
+	if err := conn.WriteJSON(subscribe); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	ack := readMessageOfType(t, conn, model.WSMessageTypeSubscribed)
+	if len(ack.ItemIDs) != 1 || ack.ItemIDs[0] != watched.ID {
+		t.Errorf("subscribed item_ids = %v, want [%s]", ack.ItemIDs, watched.ID)
+	}
+
+	// Act
+	_, _ = events.Update(ctx, other.ID, &model.Item{Name: "Other 2", Price: 1}, 0)
+	_, _ = events.Update(ctx, watched.ID, &model.Item{Name: "Watched 2", Price: 1}, 0)
+
+	// Assert
+	msg := readMessageOfType(t, conn, model.WSMessageTypeItemUpdated)
+	if msg.ItemID != watched.ID {
+		t.Errorf("item_updated for %s, want only %s", msg.ItemID, watched.ID)
+	}
+}
+
+func TestWebSocketHandler_ItemEvents_Unsubscribe(t *testing.T) {
+	// Arrange
+	ctx := t.Context()
+	events, conn := dialEventSocket(t)
+	_ = conn.WriteJSON(model.WebSocketMessage{Type: model.WSMessageTypeSubscribe})
+	readMessageOfType(t, conn, model.WSMessageTypeSubscribed)
+
+	// Act
+	_ = conn.WriteJSON(model.WebSocketMessage{Type: model.WSMessageTypeUnsubscribe})
+	readMessageOfType(t, conn, model.WSMessageTypeUnsubscribed)
+	_, _ = events.Create(ctx, &model.Item{Name: "Widget", Price: 1})
+
+	// Assert - random values resume and no item event is delivered
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg model.WebSocketMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if msg.Type != model.WSMessageTypeRandomValue {
+		t.Errorf("Message type = %s, want %s", msg.Type, model.WSMessageTypeRandomValue)
+	}
+}
+
+func TestWebSocketHandler_ClientMessageErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		withEvents  bool
+		message     string
+		wantMessage string
+	}{
+		{name: "not JSON", withEvents: true, message: "hello", wantMessage: "invalid message"},
+		{name: "unknown type", withEvents: true, message: `{"type":"listen"}`, wantMessage: "unknown message type"},
+		{
+			name:        "too many item IDs",
+			withEvents:  true,
+			message:     `{"type":"subscribe","item_ids":[` + strings.Repeat(`"1",`, maxSubscribedItems) + `"1"]}`,
+			wantMessage: "at most",
+		},
+		{name: "no event source", message: `{"type":"subscribe"}`, wantMessage: "not available"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			wsHandler := NewWebSocketHandler(zap.NewNop())
+			if tt.withEvents {
+				wsHandler = NewWebSocketHandler(zap.NewNop(), store.NewEventStore(store.NewMemoryStore()))
+			}
+			server := httptest.NewServer(http.HandlerFunc(wsHandler.HandleWebSocket))
+			defer func() {
+				wsHandler.CloseAllConnections()
+				server.Close()
+			}()
+			conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+			if err != nil {
+				t.Fatalf("Failed to connect: %v", err)
+			}
+			defer conn.Close()
+
+			// Act
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(tt.message)); err != nil {
+				t.Fatalf("Failed to send message: %v", err)
+			}
+
+			// Assert
+			msg := readMessageOfType(t, conn, model.WSMessageTypeError)
+			if !strings.Contains(msg.Message, tt.wantMessage) {
+				t.Errorf("error message = %q, want it to contain %q", msg.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+// dialAuthSocket serves /ws behind the auth middleware and connects to it
+// with the given subprotocols and headers.
+func dialAuthSocket(
+	t *testing.T, authenticator auth.Authenticator, subprotocols []string, header http.Header,
+) *websocket.Conn {
+	t.Helper()
+
+	wsHandler := NewWebSocketHandler(zap.NewNop())
+	wsHandler.SetAuthenticator(authenticator)
+	router := mux.NewRouter()
+	router.Use(mux.MiddlewareFunc(middleware.Auth(authenticator, zap.NewNop(), RouteWebSocket, RouteGraphQLSubscriptions)))
+	wsHandler.RegisterRoutes(router)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(func() {
+		wsHandler.CloseAllConnections()
+		server.Close()
+	})
+
+	dialer := websocket.Dialer{Subprotocols: subprotocols}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", header)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	resp.Body.Close()
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func TestWebSocketHandler_Authentication(t *testing.T) {
+	tests := []struct {
+		name         string
+		subprotocols []string
+		header       http.Header
+		authToken    string
+		wantProtocol string
+	}{
+		{
+			name:   "credentials on upgrade request",
+			header: http.Header{"X-Api-Key": []string{"secret"}},
+		},
+		{
+			name:         "token in Sec-WebSocket-Protocol",
+			subprotocols: []string{auth.WebSocketTokenProtocol, "secret"},
+			wantProtocol: auth.WebSocketTokenProtocol,
+		},
+		{
+			name:      "token in first message",
+			authToken: "secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			conn := dialAuthSocket(t, apiKeyTestAuthenticator{}, tt.subprotocols, tt.header)
+
+			// Act
+			if tt.authToken != "" {
+				if err := conn.WriteJSON(model.WebSocketMessage{Type: model.WSMessageTypeAuth, Token: tt.authToken}); err != nil {
+					t.Fatalf("Failed to send auth message: %v", err)
+				}
+				readMessageOfType(t, conn, model.WSMessageTypeAuthenticated)
+			}
+
+			// Assert
+			if conn.Subprotocol() != tt.wantProtocol {
+				t.Errorf("Subprotocol() = %q, want %q", conn.Subprotocol(), tt.wantProtocol)
+			}
+			readMessageOfType(t, conn, model.WSMessageTypeRandomValue)
+		})
+	}
+}
+
+func TestWebSocketHandler_AuthenticationRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		message model.WebSocketMessage
+	}{
+		{name: "invalid token", message: model.WebSocketMessage{Type: model.WSMessageTypeAuth, Token: "wrong"}},
+		{name: "missing token", message: model.WebSocketMessage{Type: model.WSMessageTypeAuth}},
+		{name: "message before auth", message: model.WebSocketMessage{Type: model.WSMessageTypeSubscribe}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			conn := dialAuthSocket(t, apiKeyTestAuthenticator{}, nil, nil)
+
+			// Act
+			if err := conn.WriteJSON(tt.message); err != nil {
+				t.Fatalf("Failed to send message: %v", err)
+			}
+
+			// Assert - no data is sent before the connection is closed
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			_, data, err := conn.ReadMessage()
+			if err == nil {
+				t.Fatalf("got message %s, want connection closed", data)
+			}
+			if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+				t.Errorf("read error = %v, want close %d", err, websocket.ClosePolicyViolation)
+			}
+		})
+	}
+}
+
+func TestWebSocketHandler_InBandAuthorization(t *testing.T) {
+	tests := []struct {
+		name        string
+		roles       []string
+		wantAllowed bool
+	}{
+		{name: "allowed role", roles: []string{"reader"}, wantAllowed: true},
+		{name: "missing role", roles: []string{"writer"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			authorizer, err := authz.NewAuthorizer(&authz.Policy{
+				Bindings: authz.Bindings{APIKeys: map[string][]string{"client": {"reader"}}},
+				Routes:   []authz.RouteRule{{Path: "/ws", Roles: tt.roles}},
+			})
+			if err != nil {
+				t.Fatalf("NewAuthorizer() error = %v", err)
+			}
+			wsHandler := NewWebSocketHandler(zap.NewNop())
+			wsHandler.SetAuthenticator(apiKeyTestAuthenticator{})
+			wsHandler.SetAuthorizer(authorizer)
+			router := mux.NewRouter()
+			wsHandler.RegisterRoutes(router)
+			server := httptest.NewServer(router)
+			t.Cleanup(func() {
+				wsHandler.CloseAllConnections()
+				server.Close()
+			})
+
+			conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+			if err != nil {
+				t.Fatalf("Failed to connect: %v", err)
+			}
+			resp.Body.Close()
+			t.Cleanup(func() { conn.Close() })
+
+			// Act
+			if err := conn.WriteJSON(model.WebSocketMessage{Type: model.WSMessageTypeAuth, Token: "secret"}); err != nil {
+				t.Fatalf("Failed to send auth message: %v", err)
+			}
+
+			// Assert
+			if tt.wantAllowed {
+				readMessageOfType(t, conn, model.WSMessageTypeAuthenticated)
+				return
+			}
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			_, data, err := conn.ReadMessage()
+			if err == nil {
+				t.Fatalf("got message %s, want connection closed", data)
+			}
+			if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+				t.Errorf("read error = %v, want close %d", err, websocket.ClosePolicyViolation)
+			}
+		})
+	}
+}
+
+func TestWebSocketHandler_InvalidCredentialsOnUpgrade(t *testing.T) {
+	// Arrange
+	wsHandler := NewWebSocketHandler(zap.NewNop())
+	router := mux.NewRouter()
+	router.Use(mux.MiddlewareFunc(middleware.Auth(apiKeyTestAuthenticator{}, zap.NewNop(), RouteWebSocket)))
+	wsHandler.RegisterRoutes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// Act
+	conn, resp, err := websocket.DefaultDialer.Dial(
+		"ws"+strings.TrimPrefix(server.URL, "http")+"/ws",
+		http.Header{"X-Api-Key": []string{"wrong"}},
+	)
+
+	// Assert
+	if err == nil {
+		conn.Close()
+		t.Fatal("Dial() succeeded, want rejected upgrade")
+	}
+	if resp == nil {
+		t.Fatalf("Dial() error = %v, want HTTP response", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestWebSocketHandler_CredentialsExpire(t *testing.T) {
+	// Arrange
+	authenticator := apiKeyTestAuthenticator{ttl: 300 * time.Millisecond}
+	conn := dialAuthSocket(t, authenticator, nil, http.Header{"X-Api-Key": []string{"secret"}})
+
+	// Act - keep reading random values until the server closes the connection
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var err error
+	for err == nil {
+		_, _, err = conn.ReadMessage()
+	}
+
+	// Assert
+	if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Errorf("read error = %v, want close %d", err, websocket.ClosePolicyViolation)
+	}
+}
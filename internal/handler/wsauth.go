@@ -0,0 +1,96 @@
+// wsauth.go holds the authentication helpers shared by the WebSocket
+// endpoints. Upgrade requests are authenticated by middleware.Auth, which
+// attaches the AuthInfo to the request context; clients that sent no
+// credentials with the upgrade (typically browsers) authenticate in-band
+// once the connection is established, after which they are authorized like
+// middleware.Authorize would have done for the upgrade request.
+
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/authz"
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+)
+
+// authWait is the time a client has to authenticate in-band.
+const authWait = 10 * time.Second
+
+// authenticateInBand authenticates credentials a WebSocket client sent after
+// the upgrade by applying them as headers to a copy of the upgrade request.
+// Attempts are counted in auth_attempts_total like those of middleware.Auth.
+func authenticateInBand(
+	authenticator auth.Authenticator,
+	logger *zap.Logger,
+	upgrade *http.Request,
+	headers map[string]string,
+) (*auth.AuthInfo, error) {
+	req := upgrade.Clone(upgrade.Context())
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	info, err := authenticator.Authenticate(req)
+	if err != nil {
+		result := observability.ResultFailure
+		if errors.Is(err, auth.ErrLockedOut) {
+			result = observability.ResultLockedOut
+		}
+		observability.AuthAttemptsTotal.
+			WithLabelValues(string(authenticator.Method()), result).
+			Inc()
+		logger.Warn("websocket authentication failed",
+			zap.String("path", req.URL.Path),
+			zap.String("remote_addr", req.RemoteAddr),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	observability.AuthAttemptsTotal.
+		WithLabelValues(string(info.Method), observability.ResultSuccess).
+		Inc()
+
+	logger.Debug("websocket authentication successful",
+		zap.String("subject", info.Subject),
+		zap.String("method", string(info.Method)),
+		zap.String("path", req.URL.Path),
+	)
+
+	return info, nil
+}
+
+// authorizeInBand applies the route rules to a client that authenticated
+// in-band. A nil authorizer allows every client.
+func authorizeInBand(
+	authorizer *authz.Authorizer,
+	logger *zap.Logger,
+	upgrade *http.Request,
+	info *auth.AuthInfo,
+) error {
+	err := authorizer.AuthorizeRequest(info, upgrade)
+	if err != nil {
+		logger.Warn("websocket authorization denied",
+			zap.String("subject", info.Subject),
+			zap.String("path", upgrade.URL.Path),
+			zap.Error(err),
+		)
+	}
+	return err
+}
+
+// afterExpiry calls fn once the credentials described by info expire, so
+// that connections do not outlive an OIDC token. It returns nil when the
+// credentials do not expire.
+func afterExpiry(info *auth.AuthInfo, fn func()) *time.Timer {
+	if info == nil || info.Expiry.IsZero() {
+		return nil
+	}
+	return time.AfterFunc(time.Until(info.Expiry), fn)
+}
@@ -0,0 +1,47 @@
+// Package idempotency keeps the responses of requests made with an
+// Idempotency-Key, so that retries of a request are answered with its first
+// response instead of being processed again. Records are kept in memory for
+// single instances or in Redis to be shared by replicas.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrStoreFull is returned when a MemoryStore holds too many records to
+// begin another one.
+var ErrStoreFull = errors.New("idempotency store is full")
+
+// Record is the state of an idempotency key.
+type Record struct {
+	// Fingerprint identifies the request the key was first used with.
+	Fingerprint string `json:"fingerprint"`
+	// Done reports whether the response was stored. Until then, the first
+	// request is still being processed.
+	Done bool `json:"done,omitempty"`
+	// Status, Header and Body are the stored response.
+	Status int               `json:"status,omitempty"`
+	Header map[string]string `json:"header,omitempty"`
+	Body   []byte            `json:"body,omitempty"`
+}
+
+// Store keeps the records of idempotency keys until they expire.
+type Store interface {
+	// Begin creates an in-progress record of key for a request with
+	// fingerprint, expiring after ttl, unless key has a record already.
+	// It returns the record of key and whether it was created, in which
+	// case the request must be processed and then completed or aborted.
+	Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (Record, bool, error)
+
+	// Complete stores the response of the request that began key, which
+	// is then kept for ttl.
+	Complete(ctx context.Context, key string, record Record, ttl time.Duration) error
+
+	// Abort deletes the record of key, so that the request may be retried.
+	Abort(ctx context.Context, key string) error
+
+	// Close releases the resources of the store.
+	Close() error
+}
@@ -0,0 +1,88 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxMemoryRecords bounds the records of a MemoryStore.
+const maxMemoryRecords = 100000
+
+// MemoryStore keeps records in memory. Its keys apply to one server
+// instance.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]memoryRecord
+	now     func() time.Time
+}
+
+// memoryRecord is a record and when it expires.
+type memoryRecord struct {
+	record  Record
+	expires time.Time
+}
+
+// NewMemoryStore creates a MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[string]memoryRecord),
+		now:     time.Now,
+	}
+}
+
+// Begin implements Store. When too many keys are tracked and none of them
+// expired, it returns ErrStoreFull.
+func (m *MemoryStore) Begin(_ context.Context, key, fingerprint string, ttl time.Duration) (Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if r, ok := m.records[key]; ok && now.Before(r.expires) {
+		return r.record, false, nil
+	}
+
+	if len(m.records) >= maxMemoryRecords {
+		m.prune(now)
+		if len(m.records) >= maxMemoryRecords {
+			return Record{}, false, ErrStoreFull
+		}
+	}
+
+	record := Record{Fingerprint: fingerprint}
+	m.records[key] = memoryRecord{record: record, expires: now.Add(ttl)}
+	return record, true, nil
+}
+
+// Complete implements Store.
+func (m *MemoryStore) Complete(_ context.Context, key string, record Record, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record.Done = true
+	m.records[key] = memoryRecord{record: record, expires: m.now().Add(ttl)}
+	return nil
+}
+
+// Abort implements Store.
+func (m *MemoryStore) Abort(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, key)
+	return nil
+}
+
+// Close implements Store.
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+// prune drops the expired records.
+func (m *MemoryStore) prune(now time.Time) {
+	for key, r := range m.records {
+		if !now.Before(r.expires) {
+			delete(m.records, key)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newTestMemoryStore returns a MemoryStore whose clock is *now.
+func newTestMemoryStore(now *time.Time) *MemoryStore {
+	m := NewMemoryStore()
+	m.now = func() time.Time { return *now }
+	return m
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	store := newTestMemoryStore(&now)
+	response := Record{
+		Fingerprint: "fp",
+		Status:      201,
+		Header:      map[string]string{"Location": "/api/v1/items/1"},
+		Body:        []byte(`{"id":"1"}`),
+	}
+
+	// Act
+	_, created, beginErr := store.Begin(ctx, "key", "fp", time.Minute)
+	inProgress, createdAgain, _ := store.Begin(ctx, "key", "other", time.Minute)
+	completeErr := store.Complete(ctx, "key", response, time.Hour)
+	now = now.Add(59 * time.Minute)
+	stored, createdStored, _ := store.Begin(ctx, "key", "fp", time.Minute)
+	now = now.Add(time.Minute)
+	_, createdExpired, _ := store.Begin(ctx, "key", "fp", time.Minute)
+
+	// Assert
+	if beginErr != nil || completeErr != nil {
+		t.Fatalf("Begin(), Complete() errors = %v, %v", beginErr, completeErr)
+	}
+	if !created {
+		t.Error("Begin() of a new key created = false, want true")
+	}
+	if createdAgain || inProgress.Done || inProgress.Fingerprint != "fp" {
+		t.Errorf("Begin() of an in-progress key = %+v, %v, want the in-progress record", inProgress, createdAgain)
+	}
+	want := response
+	want.Done = true
+	if createdStored || !reflect.DeepEqual(stored, want) {
+		t.Errorf("Begin() of a completed key = %+v, %v, want %+v", stored, createdStored, want)
+	}
+	if !createdExpired {
+		t.Error("Begin() of an expired key created = false, want true")
+	}
+}
+
+func TestMemoryStore_Abort(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	store := NewMemoryStore()
+	if _, _, err := store.Begin(ctx, "key", "fp", time.Minute); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+
+	// Act
+	err := store.Abort(ctx, "key")
+	_, created, _ := store.Begin(ctx, "key", "fp", time.Minute)
+
+	// Assert
+	if err != nil {
+		t.Errorf("Abort() error = %v", err)
+	}
+	if !created {
+		t.Error("Begin() after Abort() created = false, want true")
+	}
+}
+
+func TestMemoryStore_Full(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	store := newTestMemoryStore(&now)
+	for i := range maxMemoryRecords {
+		ttl := time.Hour
+		if i == 0 {
+			ttl = time.Minute
+		}
+		if _, _, err := store.Begin(ctx, strconv.Itoa(i), "fp", ttl); err != nil {
+			t.Fatalf("Begin() error = %v", err)
+		}
+	}
+
+	// Act
+	_, _, fullErr := store.Begin(ctx, "new", "fp", time.Minute)
+	now = now.Add(time.Minute)
+	_, created, prunedErr := store.Begin(ctx, "new", "fp", time.Minute)
+
+	// Assert
+	if !errors.Is(fullErr, ErrStoreFull) {
+		t.Errorf("Begin() error = %v, want %v", fullErr, ErrStoreFull)
+	}
+	if prunedErr != nil || !created {
+		t.Errorf("Begin() after a record expired = %v, %v, want a new record", created, prunedErr)
+	}
+}
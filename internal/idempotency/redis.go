@@ -0,0 +1,131 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/vyrodovalexey/restapi-example/internal/redis"
+)
+
+// ErrRedis is returned when the Redis server cannot be reached, fails a
+// command or holds an invalid record.
+var ErrRedis = errors.New("redis idempotency store")
+
+// defaultRedisPrefix is the default prefix of record keys.
+const defaultRedisPrefix = "idempotency:"
+
+// beginAttempts bounds the attempts of Begin to create or read a record of
+// a key, which may expire in between.
+const beginAttempts = 3
+
+// RedisConfig configures a RedisStore.
+type RedisConfig struct {
+	redis.Config
+	// Prefix is prepended to record keys. It defaults to "idempotency:".
+	Prefix string
+}
+
+// RedisStore keeps records as JSON in a Redis server, or any server
+// speaking the Redis protocol, so that replicas share them. Records are
+// created with SET NX, so only one replica processes a key, and expire with
+// the key.
+type RedisStore struct {
+	prefix string
+	client *redis.Client
+}
+
+// NewRedisStore creates a RedisStore. Connections are opened when needed.
+func NewRedisStore(config RedisConfig) *RedisStore {
+	if config.Prefix == "" {
+		config.Prefix = defaultRedisPrefix
+	}
+
+	return &RedisStore{
+		prefix: config.Prefix,
+		client: redis.NewClient(config.Config),
+	}
+}
+
+// Begin implements Store.
+func (s *RedisStore) Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (Record, bool, error) {
+	record := Record{Fingerprint: fingerprint}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return Record{}, false, fmt.Errorf("%w: %w", ErrRedis, err)
+	}
+
+	for range beginAttempts {
+		reply, err := s.client.Do(ctx, "SET", s.prefix+key, string(data), "NX", "PX", milliseconds(ttl))
+		if err != nil {
+			return Record{}, false, fmt.Errorf("%w: %w", ErrRedis, err)
+		}
+		if reply != nil {
+			return record, true, nil
+		}
+
+		existing, ok, err := s.get(ctx, key)
+		if err != nil || ok {
+			return existing, false, err
+		}
+	}
+
+	return Record{}, false, fmt.Errorf("%w: record of key kept expiring", ErrRedis)
+}
+
+// Complete implements Store.
+func (s *RedisStore) Complete(ctx context.Context, key string, record Record, ttl time.Duration) error {
+	record.Done = true
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRedis, err)
+	}
+
+	if _, err := s.client.Do(ctx, "SET", s.prefix+key, string(data), "PX", milliseconds(ttl)); err != nil {
+		return fmt.Errorf("%w: %w", ErrRedis, err)
+	}
+	return nil
+}
+
+// Abort implements Store.
+func (s *RedisStore) Abort(ctx context.Context, key string) error {
+	if _, err := s.client.Do(ctx, "DEL", s.prefix+key); err != nil {
+		return fmt.Errorf("%w: %w", ErrRedis, err)
+	}
+	return nil
+}
+
+// Close closes the idle connections. Connections in use are closed when
+// they are returned.
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// get returns the record of key, if it has one.
+func (s *RedisStore) get(ctx context.Context, key string) (Record, bool, error) {
+	reply, err := s.client.Do(ctx, "GET", s.prefix+key)
+	if err != nil {
+		return Record{}, false, fmt.Errorf("%w: %w", ErrRedis, err)
+	}
+	if reply == nil {
+		return Record{}, false, nil
+	}
+
+	data, ok := reply.(string)
+	if !ok {
+		return Record{}, false, fmt.Errorf("%w: unexpected reply %v", ErrRedis, reply)
+	}
+	var record Record
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return Record{}, false, fmt.Errorf("%w: decoding record: %w", ErrRedis, err)
+	}
+	return record, true, nil
+}
+
+// milliseconds formats d as whole milliseconds, at least one.
+func milliseconds(d time.Duration) string {
+	return strconv.FormatInt(max(d.Milliseconds(), 1), 10)
+}
@@ -0,0 +1,129 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vyrodovalexey/restapi-example/internal/redis"
+	"github.com/vyrodovalexey/restapi-example/internal/redis/redistest"
+)
+
+func TestRedisStore(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	server := redistest.NewServer(t, "secret")
+	store := NewRedisStore(RedisConfig{Config: redis.Config{Addr: server.Addr, Password: "secret"}})
+	t.Cleanup(func() { _ = store.Close() })
+	response := Record{
+		Fingerprint: "fp",
+		Status:      201,
+		Header:      map[string]string{"Location": "/api/v1/items/1"},
+		Body:        []byte{0, 1, 2, '"'},
+	}
+
+	// Act
+	_, created, beginErr := store.Begin(ctx, "key", "fp", time.Minute)
+	inProgress, createdAgain, inProgressErr := store.Begin(ctx, "key", "other", time.Minute)
+	completeErr := store.Complete(ctx, "key", response, time.Hour)
+	stored, createdStored, storedErr := store.Begin(ctx, "key", "fp", time.Minute)
+
+	// Assert
+	for _, err := range []error{beginErr, inProgressErr, completeErr, storedErr} {
+		if err != nil {
+			t.Fatalf("RedisStore error = %v", err)
+		}
+	}
+	if !created {
+		t.Error("Begin() of a new key created = false, want true")
+	}
+	if createdAgain || inProgress.Done || inProgress.Fingerprint != "fp" {
+		t.Errorf("Begin() of an in-progress key = %+v, %v, want the in-progress record", inProgress, createdAgain)
+	}
+	want := response
+	want.Done = true
+	if createdStored || !reflect.DeepEqual(stored, want) {
+		t.Errorf("Begin() of a completed key = %+v, %v, want %+v", stored, createdStored, want)
+	}
+	if _, ok := server.Get("idempotency:key"); !ok {
+		t.Error("record not stored under the idempotency: prefix")
+	}
+}
+
+func TestRedisStore_Abort(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	server := redistest.NewServer(t, "")
+	store := NewRedisStore(RedisConfig{Config: redis.Config{Addr: server.Addr}, Prefix: "test:"})
+	t.Cleanup(func() { _ = store.Close() })
+	if _, _, err := store.Begin(ctx, "key", "fp", time.Minute); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+
+	// Act
+	err := store.Abort(ctx, "key")
+	_, created, _ := store.Begin(ctx, "key", "fp", time.Minute)
+
+	// Assert
+	if err != nil {
+		t.Errorf("Abort() error = %v", err)
+	}
+	if !created {
+		t.Error("Begin() after Abort() created = false, want true")
+	}
+	want := []string{"SET", "DEL", "SET"}
+	if got := server.Commands(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("commands = %v, want %v", got, want)
+	}
+}
+
+func TestRedisStore_Errors(t *testing.T) {
+	t.Parallel()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	closedAddr := closed.Addr().String()
+	_ = closed.Close()
+
+	server := redistest.NewServer(t, "")
+	corrupt := NewRedisStore(RedisConfig{Config: redis.Config{Addr: server.Addr}})
+	t.Cleanup(func() { _ = corrupt.Close() })
+	if _, err := corrupt.client.Do(context.Background(), "SET", "idempotency:key", "not json"); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		store *RedisStore
+	}{
+		{
+			name:  "server unreachable",
+			store: NewRedisStore(RedisConfig{Config: redis.Config{Addr: closedAddr, Timeout: 100 * time.Millisecond}}),
+		},
+		{name: "invalid record", store: corrupt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			_, _, err := tt.store.Begin(context.Background(), "key", "fp", time.Minute)
+
+			// Assert
+			if !errors.Is(err, ErrRedis) {
+				t.Errorf("Begin() error = %v, want %v", err, ErrRedis)
+			}
+		})
+	}
+}
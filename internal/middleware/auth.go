@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"slices"
 	"strings"
 
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 
 	"github.com/vyrodovalexey/restapi-example/internal/auth"
@@ -20,11 +23,16 @@ var publicPaths = map[string]bool{
 }
 
 // Auth returns a middleware that authenticates requests.
-// Public paths (health, ready, metrics), CORS preflight requests,
-// and WebSocket upgrade requests are excluded from authentication.
+// Public paths (health, ready, metrics) and CORS preflight requests
+// are excluded from authentication. WebSocket upgrade requests may also
+// carry a bearer token in Sec-WebSocket-Protocol. Upgrades without any
+// credentials to the routes named inBandRoutes are passed on
+// unauthenticated so that their WebSocket handlers can authenticate the
+// client in-band; on every other route they are rejected.
 func Auth(
 	authenticator auth.Authenticator,
 	logger *zap.Logger,
+	inBandRoutes ...string,
 ) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(
@@ -43,16 +51,23 @@ func Auth(
 				return
 			}
 
-			// Skip auth for WebSocket upgrade
-			if isWebSocketUpgrade(r) {
+			authReq := r
+			if websocket.IsWebSocketUpgrade(r) {
+				authReq = withWebSocketProtocolToken(r)
+			}
+
+			info, err := authenticator.Authenticate(authReq)
+			if errors.Is(err, auth.ErrUnauthenticated) && isInBandUpgrade(r, inBandRoutes) {
 				next.ServeHTTP(w, r)
 				return
 			}
-
-			info, err := authenticator.Authenticate(r)
 			if err != nil {
+				result := observability.ResultFailure
+				if errors.Is(err, auth.ErrLockedOut) {
+					result = observability.ResultLockedOut
+				}
 				observability.AuthAttemptsTotal.
-					WithLabelValues(string(authenticator.Method()), observability.ResultFailure).
+					WithLabelValues(string(authenticator.Method()), result).
 					Inc()
 				logger.Warn("authentication failed",
 					zap.String("path", r.URL.Path),
@@ -99,10 +114,28 @@ func isPublicPath(path string) bool {
 	return false
 }
 
-// isWebSocketUpgrade checks whether the request is a WebSocket
-// upgrade request.
-func isWebSocketUpgrade(r *http.Request) bool {
-	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+// isInBandUpgrade reports whether r is a WebSocket upgrade request to one
+// of the routes named routes, whose handlers authenticate and authorize
+// clients in-band.
+func isInBandUpgrade(r *http.Request, routes []string) bool {
+	if !websocket.IsWebSocketUpgrade(r) {
+		return false
+	}
+	route := mux.CurrentRoute(r)
+	return route != nil && route.GetName() != "" && slices.Contains(routes, route.GetName())
+}
+
+// withWebSocketProtocolToken returns r, or a copy of it carrying the bearer
+// token offered in Sec-WebSocket-Protocol as its Authorization header.
+func withWebSocketProtocolToken(r *http.Request) *http.Request {
+	token := auth.WebSocketProtocolToken(r)
+	if token == "" || r.Header.Get("Authorization") != "" {
+		return r
+	}
+
+	clone := r.Clone(r.Context())
+	clone.Header.Set("Authorization", "Bearer "+token)
+	return clone
 }
 
 // authErrorResponse is the JSON error response for auth failures.
@@ -112,16 +145,24 @@ type authErrorResponse struct {
 }
 
 // writeAuthError writes an appropriate HTTP 401 response with
-// WWW-Authenticate header based on the error type.
+// WWW-Authenticate header based on the error type, or a 429 response with
+// a Retry-After header while the subject or client is locked out.
 func writeAuthError(w http.ResponseWriter, err error) {
 	w.Header().Set("Content-Type", "application/json")
 
-	setWWWAuthenticateHeader(w, err)
+	status := http.StatusUnauthorized
+	var lockout *auth.LockoutError
+	if errors.As(err, &lockout) {
+		status = http.StatusTooManyRequests
+		w.Header().Set("Retry-After", ceilSeconds(lockout.RetryAfter))
+	} else {
+		setWWWAuthenticateHeader(w, err)
+	}
 
-	w.WriteHeader(http.StatusUnauthorized)
+	w.WriteHeader(status)
 
 	resp := authErrorResponse{
-		Code:    http.StatusUnauthorized,
+		Code:    status,
 		Message: err.Error(),
 	}
 	_ = json.NewEncoder(w).Encode(resp)
@@ -3,10 +3,13 @@ package middleware_test
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"go.uber.org/zap"
 
@@ -107,48 +110,162 @@ func TestAuth_PublicPaths(t *testing.T) {
 	}
 }
 
+// inBandRoute is the name of the WebSocket route of newUpgradeRouter.
+const inBandRoute = "websocket"
+
+// newUpgradeRouter returns a router serving next on /ws, named inBandRoute,
+// and on /api/v1/items behind mw.
+func newUpgradeRouter(mw middleware.Middleware, next http.Handler) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(mux.MiddlewareFunc(mw))
+	router.Handle("/ws", next).Methods(http.MethodGet).Name(inBandRoute)
+	router.Handle("/api/v1/items", next)
+	return router
+}
+
+// upgradeRequest returns a WebSocket upgrade request to path.
+func upgradeRequest(method, path string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	return req
+}
+
 func TestAuth_WebSocketUpgrade(t *testing.T) {
 	t.Parallel()
 
-	// Arrange
-	failAuth := &testAuthenticator{
-		err:    auth.ErrUnauthenticated,
-		method: auth.AuthMethodBasic,
+	tests := []struct {
+		name       string
+		req        *http.Request
+		wantStatus int
+	}{
+		{
+			name:       "in-band route",
+			req:        upgradeRequest(http.MethodGet, "/ws"),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "in-band route case insensitive",
+			req: func() *http.Request {
+				req := upgradeRequest(http.MethodGet, "/ws")
+				req.Header.Set("Upgrade", "WebSocket")
+				return req
+			}(),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "Upgrade header without Connection",
+			req: func() *http.Request {
+				req := upgradeRequest(http.MethodGet, "/ws")
+				req.Header.Del("Connection")
+				return req
+			}(),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "REST route",
+			req:        upgradeRequest(http.MethodPost, "/api/v1/items"),
+			wantStatus: http.StatusUnauthorized,
+		},
 	}
-	logger := zap.NewNop()
 
-	authMiddleware := middleware.Auth(failAuth, logger)
-	handler := authMiddleware(successHandler())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/ws", nil)
-	req.Header.Set("Upgrade", "websocket")
-	rr := httptest.NewRecorder()
+			// Arrange
+			failAuth := &testAuthenticator{
+				err:    auth.ErrUnauthenticated,
+				method: auth.AuthMethodBasic,
+			}
+			router := newUpgradeRouter(middleware.Auth(failAuth, zap.NewNop(), inBandRoute), successHandler())
+			rr := httptest.NewRecorder()
 
-	// Act
-	handler.ServeHTTP(rr, req)
+			// Act
+			router.ServeHTTP(rr, tt.req)
 
-	// Assert
-	if rr.Code != http.StatusOK {
-		t.Errorf("status = %d, want %d for WebSocket upgrade",
-			rr.Code, http.StatusOK)
+			// Assert
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
 	}
 }
 
-func TestAuth_WebSocketUpgrade_CaseInsensitive(t *testing.T) {
+func TestAuth_WebSocketUpgrade_WithCredentials(t *testing.T) {
 	t.Parallel()
 
-	// Arrange
-	failAuth := &testAuthenticator{
-		err:    auth.ErrUnauthenticated,
-		method: auth.AuthMethodBasic,
+	tests := []struct {
+		name          string
+		authenticator *testAuthenticator
+		wantStatus    int
+	}{
+		{
+			name: "valid credentials attach AuthInfo",
+			authenticator: &testAuthenticator{
+				info:   &auth.AuthInfo{Method: auth.AuthMethodAPIKey, Subject: "ws-client"},
+				method: auth.AuthMethodAPIKey,
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "invalid credentials are rejected",
+			authenticator: &testAuthenticator{
+				err:    auth.ErrInvalidAPIKey,
+				method: auth.AuthMethodAPIKey,
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
 	}
-	logger := zap.NewNop()
 
-	authMiddleware := middleware.Auth(failAuth, logger)
-	handler := authMiddleware(successHandler())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			handler := middleware.Auth(tt.authenticator, zap.NewNop())(contextCheckHandler(t))
+
+			req := upgradeRequest(http.MethodGet, "/ws")
+			req.Header.Set("X-API-Key", "key")
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(rr, req)
+
+			// Assert
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// headerRecordingAuthenticator records the Authorization header it sees.
+type headerRecordingAuthenticator struct {
+	authorization string
+}
+
+func (a *headerRecordingAuthenticator) Authenticate(r *http.Request) (*auth.AuthInfo, error) {
+	a.authorization = r.Header.Get("Authorization")
+	if a.authorization == "" {
+		return nil, auth.ErrUnauthenticated
+	}
+	return &auth.AuthInfo{Method: auth.AuthMethodOIDC, Subject: "browser"}, nil
+}
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/ws", nil)
-	req.Header.Set("Upgrade", "WebSocket")
+func (a *headerRecordingAuthenticator) Method() auth.AuthMethod {
+	return auth.AuthMethodOIDC
+}
+
+func TestAuth_WebSocketUpgrade_ProtocolToken(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	authenticator := &headerRecordingAuthenticator{}
+	handler := middleware.Auth(authenticator, zap.NewNop())(contextCheckHandler(t))
+
+	req := upgradeRequest(http.MethodGet, "/ws")
+	req.Header.Set("Sec-WebSocket-Protocol", auth.WebSocketTokenProtocol+", tok123")
 	rr := httptest.NewRecorder()
 
 	// Act
@@ -156,8 +273,10 @@ func TestAuth_WebSocketUpgrade_CaseInsensitive(t *testing.T) {
 
 	// Assert
 	if rr.Code != http.StatusOK {
-		t.Errorf("status = %d, want %d for WebSocket upgrade",
-			rr.Code, http.StatusOK)
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if authenticator.authorization != "Bearer tok123" {
+		t.Errorf("Authorization = %q, want %q", authenticator.authorization, "Bearer tok123")
 	}
 }
 
@@ -407,6 +526,48 @@ func TestAuth_401Response_WrappedErrors(t *testing.T) {
 	}
 }
 
+// TestAuth_LockedOut_Returns429 asserts that lockouts are answered with 429,
+// a Retry-After header rounded up to whole seconds and result="locked_out".
+func TestAuth_LockedOut_Returns429(t *testing.T) {
+	// Arrange
+	lockedAuth := &testAuthenticator{
+		err:    fmt.Errorf("wrapped: %w", &auth.LockoutError{RetryAfter: 1500 * time.Millisecond}),
+		method: auth.AuthMethodAPIKey,
+	}
+	handler := middleware.Auth(lockedAuth, zap.NewNop())(successHandler())
+	before := authCount(t, string(auth.AuthMethodAPIKey), observability.ResultLockedOut)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/items", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(rr, req)
+
+	// Assert
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusTooManyRequests)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want %q", got, "2")
+	}
+	if got := rr.Header().Get("WWW-Authenticate"); got != "" {
+		t.Errorf("WWW-Authenticate = %q, want none", got)
+	}
+
+	var body map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode JSON body: %v", err)
+	}
+	if code, _ := body["code"].(float64); int(code) != http.StatusTooManyRequests {
+		t.Errorf("body.code = %v, want %d", body["code"], http.StatusTooManyRequests)
+	}
+
+	after := authCount(t, string(auth.AuthMethodAPIKey), observability.ResultLockedOut)
+	if after-before != 1 {
+		t.Errorf("auth_attempts_total{apikey,locked_out} delta = %v, want 1", after-before)
+	}
+}
+
 func TestAuth_HandlerNotCalledOn401(t *testing.T) {
 	t.Parallel()
 
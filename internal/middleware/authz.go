@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/authz"
+)
+
+// Authorize returns a middleware that enforces the route rules of the
+// authorizer and must run after Auth. Public paths and CORS preflight
+// requests are not authorized. WebSocket upgrades that Auth passed on
+// unauthenticated to the routes named inBandRoutes are left to their
+// handlers, which authorize the client once it has authenticated in-band;
+// any other request without an identity is denied.
+func Authorize(
+	authorizer *authz.Authorizer,
+	logger *zap.Logger,
+	inBandRoutes ...string,
+) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(
+			w http.ResponseWriter,
+			r *http.Request,
+		) {
+			if isPublicPath(r.URL.Path) || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			info, ok := auth.FromContext(r.Context())
+			if !ok && isInBandUpgrade(r, inBandRoutes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := authorizer.AuthorizeRequest(info, r); err != nil {
+				subject := ""
+				if info != nil {
+					subject = info.Subject
+				}
+				logger.Warn("authorization denied",
+					zap.String("subject", subject),
+					zap.String("path", r.URL.Path),
+					zap.String("method", r.Method),
+					zap.Error(err),
+				)
+				writeForbidden(w, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forbiddenResponse is the JSON error response for denied requests.
+type forbiddenResponse struct {
+	Code          int      `json:"code"`
+	Message       string   `json:"message"`
+	Resource      string   `json:"resource,omitempty"`
+	RequiredRoles []string `json:"required_roles,omitempty"`
+}
+
+// writeForbidden writes an HTTP 403 response describing the denied decision.
+func writeForbidden(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+
+	resp := forbiddenResponse{
+		Code:    http.StatusForbidden,
+		Message: err.Error(),
+	}
+	var denied *authz.ForbiddenError
+	if errors.As(err, &denied) {
+		resp.Resource = denied.Resource
+		resp.RequiredRoles = denied.RequiredRoles
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
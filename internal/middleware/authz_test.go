@@ -0,0 +1,143 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/authz"
+	"github.com/vyrodovalexey/restapi-example/internal/middleware"
+)
+
+// newAuthzRouter returns a router serving the item routes behind Authorize,
+// with info attached to every request as middleware.Auth would.
+func newAuthzRouter(t *testing.T, info *auth.AuthInfo) *mux.Router {
+	t.Helper()
+
+	authorizer, err := authz.NewAuthorizer(&authz.Policy{
+		Bindings: authz.Bindings{
+			APIKeys: map[string][]string{"reader": {"reader"}, "writer": {"writer"}},
+		},
+		Routes: []authz.RouteRule{
+			{Methods: []string{http.MethodGet}, Path: "/api/v1/items*", Roles: []string{"reader", "writer"}},
+			{Path: "/api/v1/items*", Roles: []string{"writer"}},
+			{Path: "/api/v1/admin/*", Roles: []string{"admin"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error = %v", err)
+	}
+
+	router := mux.NewRouter()
+	if info != nil {
+		router.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(w, r.WithContext(auth.WithAuthInfo(r.Context(), info)))
+			})
+		})
+	}
+	router.Use(mux.MiddlewareFunc(middleware.Authorize(authorizer, zap.NewNop(), inBandRoute)))
+
+	router.Handle("/api/v1/items", successHandler())
+	router.Handle("/api/v1/items/{id}", successHandler())
+	router.Handle("/api/v1/admin/api-keys", successHandler())
+	router.Handle("/health", successHandler())
+	router.Handle("/ws", successHandler()).Name(inBandRoute)
+	return router
+}
+
+func TestAuthorize(t *testing.T) {
+	reader := &auth.AuthInfo{Method: auth.AuthMethodAPIKey, Subject: "reader"}
+	writer := &auth.AuthInfo{Method: auth.AuthMethodAPIKey, Subject: "writer"}
+
+	tests := []struct {
+		name       string
+		info       *auth.AuthInfo
+		method     string
+		path       string
+		upgrade    bool
+		wantStatus int
+	}{
+		{name: "reader reads", info: reader, method: http.MethodGet, path: "/api/v1/items/1", wantStatus: http.StatusOK},
+		{name: "reader writes", info: reader, method: http.MethodPut, path: "/api/v1/items/1",
+			wantStatus: http.StatusForbidden},
+		{name: "writer writes", info: writer, method: http.MethodPut, path: "/api/v1/items/1", wantStatus: http.StatusOK},
+		{name: "route without rule", info: writer, method: http.MethodGet, path: "/ws",
+			wantStatus: http.StatusForbidden},
+		{name: "public path", method: http.MethodGet, path: "/health", wantStatus: http.StatusOK},
+		{name: "CORS preflight", method: http.MethodOptions, path: "/api/v1/items", wantStatus: http.StatusOK},
+		{name: "unauthenticated upgrade", method: http.MethodGet, path: "/ws", upgrade: true,
+			wantStatus: http.StatusOK},
+		{name: "authenticated upgrade", info: reader, method: http.MethodGet, path: "/ws", upgrade: true,
+			wantStatus: http.StatusForbidden},
+		{name: "no identity", method: http.MethodGet, path: "/api/v1/items", wantStatus: http.StatusForbidden},
+		{name: "unauthenticated upgrade to the admin API", method: http.MethodPost, path: "/api/v1/admin/api-keys",
+			upgrade: true, wantStatus: http.StatusForbidden},
+		{name: "unauthenticated upgrade to a REST route", method: http.MethodGet, path: "/api/v1/items",
+			upgrade: true, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			router := newAuthzRouter(t, tt.info)
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.upgrade {
+				req.Header.Set("Connection", "Upgrade")
+				req.Header.Set("Upgrade", "websocket")
+			}
+			rec := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rec, req)
+
+			// Assert
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAuthorize_403Response_HasJSONBody(t *testing.T) {
+	// Arrange
+	router := newAuthzRouter(t, &auth.AuthInfo{Method: auth.AuthMethodAPIKey, Subject: "reader"})
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/items/42", nil)
+	rec := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rec, req)
+
+	// Assert
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	var body struct {
+		Code          int      `json:"code"`
+		Message       string   `json:"message"`
+		Resource      string   `json:"resource"`
+		RequiredRoles []string `json:"required_roles"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if body.Code != http.StatusForbidden {
+		t.Errorf("code = %d, want %d", body.Code, http.StatusForbidden)
+	}
+	if body.Resource != "DELETE /api/v1/items/{id}" {
+		t.Errorf("resource = %q, want DELETE /api/v1/items/{id}", body.Resource)
+	}
+	if !slices.Equal(body.RequiredRoles, []string{"writer"}) {
+		t.Errorf("required_roles = %v, want [writer]", body.RequiredRoles)
+	}
+	if body.Message != "forbidden: DELETE /api/v1/items/{id} requires one of roles: writer" {
+		t.Errorf("message = %q", body.Message)
+	}
+}
@@ -0,0 +1,241 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCORSConfig is returned for CORS policies that cannot be used.
+var ErrInvalidCORSConfig = errors.New("invalid CORS configuration")
+
+// CORSConfig configures a CORS policy.
+type CORSConfig struct {
+	// AllowedOrigins are exact origins such as "https://app.example.com",
+	// wildcard subdomains such as "https://*.example.com", or "*" for all
+	// origins.
+	AllowedOrigins []string
+	// AllowedOriginRegex, if set, allows the origins it matches in full.
+	AllowedOriginRegex string
+	// AllowedMethods and AllowedHeaders are answered to preflight requests.
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers scripts may read.
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and authorization headers.
+	// It cannot be combined with "*", which would expose credentialed
+	// responses to every site.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache preflight responses (0 = not
+	// sent).
+	MaxAge time.Duration
+}
+
+// CORSPolicy decides which origins may make cross-origin requests.
+type CORSPolicy struct {
+	anyOrigin   bool
+	origins     map[string]bool
+	subdomains  []wildcardOrigin
+	originRegex *regexp.Regexp
+
+	methods     string
+	headers     string
+	exposed     string
+	credentials bool
+	maxAge      string
+}
+
+// wildcardOrigin is an allowed origin with a "*." subdomain wildcard, split
+// around the wildcard.
+type wildcardOrigin struct {
+	prefix string // scheme and "://"
+	suffix string // "." and the parent domain, with the port if any
+}
+
+// NewCORSPolicy creates a CORS policy from config.
+func NewCORSPolicy(config CORSConfig) (*CORSPolicy, error) {
+	p := &CORSPolicy{
+		origins:     make(map[string]bool),
+		methods:     strings.Join(config.AllowedMethods, ", "),
+		headers:     strings.Join(config.AllowedHeaders, ", "),
+		exposed:     strings.Join(config.ExposedHeaders, ", "),
+		credentials: config.AllowCredentials,
+	}
+	if config.MaxAge < 0 {
+		return nil, fmt.Errorf("%w: negative max age", ErrInvalidCORSConfig)
+	}
+	if config.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(config.MaxAge.Seconds()))
+	}
+
+	for _, origin := range config.AllowedOrigins {
+		if err := p.addOrigin(origin); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.AllowedOriginRegex != "" {
+		re, err := regexp.Compile(`^(?:` + config.AllowedOriginRegex + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("%w: origin regex: %w", ErrInvalidCORSConfig, err)
+		}
+		p.originRegex = re
+	}
+
+	if p.anyOrigin && p.credentials {
+		return nil, fmt.Errorf("%w: credentials cannot be allowed for all origins", ErrInvalidCORSConfig)
+	}
+
+	return p, nil
+}
+
+// addOrigin adds an allowed origin to p.
+func (p *CORSPolicy) addOrigin(origin string) error {
+	if origin == "*" {
+		p.anyOrigin = true
+		return nil
+	}
+
+	prefix, rest, ok := strings.Cut(origin, "://")
+	if !ok || prefix == "" || rest == "" || strings.Contains(rest, "/") {
+		return fmt.Errorf("%w: origin %q must have the form scheme://host[:port]", ErrInvalidCORSConfig, origin)
+	}
+
+	if parent, ok := strings.CutPrefix(rest, "*."); ok {
+		if parent == "" || strings.Contains(parent, "*") {
+			return fmt.Errorf("%w: invalid wildcard origin %q", ErrInvalidCORSConfig, origin)
+		}
+		p.subdomains = append(p.subdomains, wildcardOrigin{
+			prefix: strings.ToLower(prefix) + "://",
+			suffix: "." + strings.ToLower(parent),
+		})
+		return nil
+	}
+
+	if strings.Contains(rest, "*") {
+		return fmt.Errorf("%w: invalid wildcard origin %q", ErrInvalidCORSConfig, origin)
+	}
+	p.origins[strings.ToLower(origin)] = true
+	return nil
+}
+
+// AllowsOrigin reports whether origin may make cross-origin requests.
+func (p *CORSPolicy) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.anyOrigin {
+		return true
+	}
+
+	lower := strings.ToLower(origin)
+	if p.origins[lower] {
+		return true
+	}
+	if slices.ContainsFunc(p.subdomains, func(w wildcardOrigin) bool { return w.matches(lower) }) {
+		return true
+	}
+	return p.originRegex != nil && p.originRegex.MatchString(origin)
+}
+
+// matches reports whether origin is a subdomain origin matching w.
+func (w wildcardOrigin) matches(origin string) bool {
+	host, ok := strings.CutPrefix(origin, w.prefix)
+	if !ok {
+		return false
+	}
+	sub, ok := strings.CutSuffix(host, w.suffix)
+	if !ok || sub == "" {
+		return false
+	}
+	return !strings.ContainsFunc(sub, func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '.'
+	})
+}
+
+// CheckOrigin reports whether the WebSocket upgrade request r may be
+// accepted: requests without an Origin header, such as those of non-browser
+// clients, same-origin requests and requests from allowed origins are.
+func (p *CORSPolicy) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return p.AllowsOrigin(origin)
+}
+
+// Middleware returns a middleware that applies the policy: responses to
+// allowed origins carry the CORS headers, and preflight requests are
+// answered without calling the next handler.
+func (p *CORSPolicy) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin != "" {
+				w.Header().Add("Vary", "Origin")
+			}
+
+			if p.AllowsOrigin(origin) {
+				p.setHeaders(w.Header(), origin, r.Method == http.MethodOptions)
+			}
+
+			// Handle preflight requests
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setHeaders sets the CORS headers of a response to the allowed origin.
+func (p *CORSPolicy) setHeaders(h http.Header, origin string, preflight bool) {
+	h.Set("Access-Control-Allow-Origin", origin)
+	// Credentials are never allowed for all origins, which browsers
+	// reject, even if a list entry matches too.
+	if p.credentials && !p.anyOrigin {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	h.Set("Access-Control-Allow-Methods", p.methods)
+	h.Set("Access-Control-Allow-Headers", p.headers)
+	if p.maxAge != "" {
+		h.Set("Access-Control-Max-Age", p.maxAge)
+	}
+
+	// Exposed headers only apply to actual responses.
+	if !preflight && p.exposed != "" {
+		h.Set("Access-Control-Expose-Headers", p.exposed)
+	}
+}
+
+// CORS returns a middleware that handles Cross-Origin Resource Sharing for
+// the given origins, allowing credentials for origins other than "*" and
+// caching preflight responses for a day. Invalid origins are ignored; use
+// NewCORSPolicy to configure the policy fully.
+func CORS(allowedOrigins []string, allowedMethods []string, allowedHeaders []string) Middleware {
+	policy := &CORSPolicy{
+		origins:     make(map[string]bool),
+		methods:     strings.Join(allowedMethods, ", "),
+		headers:     strings.Join(allowedHeaders, ", "),
+		credentials: true,
+		maxAge:      strconv.Itoa(int((24 * time.Hour).Seconds())),
+	}
+	for _, origin := range allowedOrigins {
+		_ = policy.addOrigin(origin)
+	}
+
+	return policy.Middleware()
+}
@@ -0,0 +1,244 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewCORSPolicy_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config CORSConfig
+	}{
+		{name: "origin without scheme", config: CORSConfig{AllowedOrigins: []string{"app.example.com"}}},
+		{name: "origin with path", config: CORSConfig{AllowedOrigins: []string{"https://app.example.com/"}}},
+		{name: "wildcard inside host", config: CORSConfig{AllowedOrigins: []string{"https://app.*.example.com"}}},
+		{name: "bare wildcard subdomain", config: CORSConfig{AllowedOrigins: []string{"https://*."}}},
+		{name: "invalid regex", config: CORSConfig{AllowedOriginRegex: "("}},
+		{name: "negative max age", config: CORSConfig{MaxAge: -time.Second}},
+		{
+			name:   "credentials for all origins",
+			config: CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, err := NewCORSPolicy(tt.config)
+
+			// Assert
+			if !errors.Is(err, ErrInvalidCORSConfig) {
+				t.Errorf("NewCORSPolicy() error = %v, want %v", err, ErrInvalidCORSConfig)
+			}
+		})
+	}
+}
+
+func TestCORSPolicy_AllowsOrigin(t *testing.T) {
+	// Arrange
+	policy, err := NewCORSPolicy(CORSConfig{
+		AllowedOrigins:     []string{"https://app.example.com", "https://*.example.org", "http://*.local.test:3000"},
+		AllowedOriginRegex: `https://pr-[0-9]+\.preview\.example\.net`,
+	})
+	if err != nil {
+		t.Fatalf("NewCORSPolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{origin: "https://app.example.com", want: true},
+		{origin: "HTTPS://App.Example.com", want: true},
+		{origin: "http://app.example.com", want: false},
+		{origin: "https://app.example.com:8443", want: false},
+		{origin: "https://admin.example.org", want: true},
+		{origin: "https://a.b.example.org", want: true},
+		{origin: "https://example.org", want: false},
+		{origin: "https://evilexample.org", want: false},
+		{origin: "https://evil.com/.example.org", want: false},
+		{origin: "http://web.local.test:3000", want: true},
+		{origin: "http://web.local.test", want: false},
+		{origin: "https://pr-42.preview.example.net", want: true},
+		{origin: "https://pr-42.preview.example.net.evil.com", want: false},
+		{origin: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			// Act
+			got := policy.AllowsOrigin(tt.origin)
+
+			// Assert
+			if got != tt.want {
+				t.Errorf("AllowsOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCORSPolicy_Middleware(t *testing.T) {
+	// Arrange
+	policy, err := NewCORSPolicy(CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type", "X-Request-ID"},
+		ExposedHeaders:   []string{"X-Request-ID", "ETag"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewCORSPolicy() error = %v", err)
+	}
+	handler := policy.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		origin      string
+		wantStatus  int
+		wantHeaders map[string]string
+	}{
+		{
+			name:       "actual request",
+			method:     http.MethodGet,
+			origin:     "https://app.example.com",
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "X-Request-ID, ETag",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name:       "preflight request",
+			method:     http.MethodOptions,
+			origin:     "https://app.example.com",
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":   "https://app.example.com",
+				"Access-Control-Allow-Methods":  "GET, POST",
+				"Access-Control-Allow-Headers":  "Content-Type, X-Request-ID",
+				"Access-Control-Max-Age":        "600",
+				"Access-Control-Expose-Headers": "",
+			},
+		},
+		{
+			name:       "disallowed origin",
+			method:     http.MethodGet,
+			origin:     "https://evil.example",
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "",
+				"Access-Control-Allow-Credentials": "",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name:       "same-origin request",
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+				"Vary":                        "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/items", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(rr, req)
+
+			// Assert
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+			for name, want := range tt.wantHeaders {
+				if got := rr.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSPolicy_Middleware_AnyOrigin(t *testing.T) {
+	// Arrange
+	policy, err := NewCORSPolicy(CORSConfig{AllowedOrigins: []string{"*"}})
+	if err != nil {
+		t.Fatalf("NewCORSPolicy() error = %v", err)
+	}
+	handler := policy.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	req.Header.Set("Origin", "https://any.example")
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(rr, req)
+
+	// Assert
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "https://any.example" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want https://any.example", got)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want none", got)
+	}
+	if got := rr.Header().Get("Access-Control-Max-Age"); got != "" {
+		t.Errorf("Access-Control-Max-Age = %q, want none", got)
+	}
+}
+
+func TestCORSPolicy_CheckOrigin(t *testing.T) {
+	// Arrange
+	policy, err := NewCORSPolicy(CORSConfig{AllowedOrigins: []string{"https://*.example.com"}})
+	if err != nil {
+		t.Fatalf("NewCORSPolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		host   string
+		origin string
+		want   bool
+	}{
+		{name: "no origin", host: "api.internal", want: true},
+		{name: "same origin", host: "api.internal:8080", origin: "http://api.internal:8080", want: true},
+		{name: "allowed origin", host: "api.internal", origin: "https://app.example.com", want: true},
+		{name: "other origin", host: "api.internal", origin: "https://evil.example", want: false},
+		{name: "other port", host: "api.internal:8080", origin: "http://api.internal:9090", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			req.Host = tt.host
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+
+			// Act
+			got := policy.CheckOrigin(req)
+
+			// Assert
+			if got != tt.want {
+				t.Errorf("CheckOrigin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vyrodovalexey/restapi-example/internal/idempotency"
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+)
+
+// IdempotencyKeyHeader is the HTTP header carrying the idempotency key of a
+// request.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyReplayedHeader marks responses replayed from the first request
+// made with an idempotency key.
+const IdempotencyReplayedHeader = "Idempotency-Replayed"
+
+const (
+	// maxIdempotencyKeyLength bounds the length of idempotency keys.
+	maxIdempotencyKeyLength = 255
+	// idempotencyLockTTL is how long a key stays in progress if its request
+	// never completes, such as when the server stops. It exceeds the write
+	// timeout of the server.
+	idempotencyLockTTL = time.Minute
+	// maxIdempotentBodySize bounds the request bodies read, the size of the
+	// largest request the items API accepts.
+	maxIdempotentBodySize = 10 << 20
+	// maxIdempotentResponseSize bounds the responses stored. Keys of larger
+	// responses are released, so that their requests may be retried.
+	maxIdempotentResponseSize = 1 << 20
+)
+
+// idempotentPaths are the paths whose POST requests may be made idempotent.
+// The API key admin API is not one of them, so that the keys it creates are
+// never stored.
+var idempotentPaths = map[string]bool{
+	"/api/v1/items":       true,
+	"/api/v1/items:batch": true,
+}
+
+// idempotentHeaders are the response headers stored and replayed.
+var idempotentHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// Idempotency returns a middleware that makes POST requests to the items
+// API carrying an Idempotency-Key header idempotent. Keys are scoped to the
+// authenticated subject, or to the client IP address of unauthenticated
+// requests, so Idempotency must run after Auth. The response
+// to the first request with a key is stored for ttl, unless it is a server
+// error, and replayed to retries with the same method, URL and body, with
+// an Idempotency-Replayed header. Reusing a key for a different request is
+// rejected with 422, and retrying while the first request is processed
+// with 409. If the store fails, the request is processed as if it had no
+// key.
+func Idempotency(store idempotency.Store, ttl time.Duration, logger *zap.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return &idempotencyHandler{next: next, store: store, ttl: ttl, logger: logger}
+	}
+}
+
+// idempotencyHandler is the handler of the Idempotency middleware.
+type idempotencyHandler struct {
+	next   http.Handler
+	store  idempotency.Store
+	ttl    time.Duration
+	logger *zap.Logger
+}
+
+// ServeHTTP implements http.Handler.
+func (h *idempotencyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if key == "" || r.Method != http.MethodPost || !idempotentPaths[r.URL.Path] {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	if !validIdempotencyKey(key) {
+		writeIdempotencyError(w, http.StatusBadRequest, "invalid Idempotency-Key header")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+	if err != nil {
+		writeIdempotencyError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	key = idempotencyScope(r) + ":" + key
+	fingerprint := requestFingerprint(r, body)
+	record, created, err := h.store.Begin(r.Context(), key, fingerprint, idempotencyLockTTL)
+	if err != nil {
+		h.logger.Error("idempotency store failed, processing request", zap.Error(err))
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	if !created {
+		replayIdempotent(w, record, fingerprint)
+		return
+	}
+
+	h.serve(w, r, key, fingerprint)
+}
+
+// serve processes the first request with key and stores its response, or
+// releases key if there is no response to store.
+func (h *idempotencyHandler) serve(w http.ResponseWriter, r *http.Request, key, fingerprint string) {
+	ctx := context.WithoutCancel(r.Context())
+	rec := &idempotencyRecorder{ResponseWriter: w, status: http.StatusOK}
+	defer func() {
+		// A panicking handler has no response to store.
+		if recovered := recover(); recovered != nil {
+			h.abort(ctx, key)
+			panic(recovered)
+		}
+	}()
+	h.next.ServeHTTP(rec, r)
+
+	if rec.status >= http.StatusInternalServerError || rec.overflow {
+		h.abort(ctx, key)
+		return
+	}
+
+	response := idempotency.Record{
+		Fingerprint: fingerprint,
+		Status:      rec.status,
+		Header:      make(map[string]string),
+		Body:        rec.body.Bytes(),
+	}
+	for _, name := range idempotentHeaders {
+		if value := w.Header().Get(name); value != "" {
+			response.Header[name] = value
+		}
+	}
+	if err := h.store.Complete(ctx, key, response, h.ttl); err != nil {
+		h.logger.Error("storing idempotent response failed", zap.Error(err))
+		return
+	}
+	observability.IdempotentRequestsTotal.WithLabelValues(observability.ResultStored).Inc()
+}
+
+// abort releases key, logging failures.
+func (h *idempotencyHandler) abort(ctx context.Context, key string) {
+	if err := h.store.Abort(ctx, key); err != nil {
+		h.logger.Error("releasing idempotency key failed", zap.Error(err))
+	}
+}
+
+// validIdempotencyKey reports whether key is at most 255 printable ASCII
+// characters.
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := range len(key) {
+		if key[i] < ' ' || key[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// idempotencyScope returns the scope of the idempotency keys of r: its
+// authenticated subject or, without one, its client IP address, so that
+// unauthenticated clients cannot replay each other's responses. Clients
+// sharing an address, such as behind a NAT, share a scope.
+func idempotencyScope(r *http.Request) string {
+	return clientKey(r)
+}
+
+// requestFingerprint returns a digest of the method, URL and body of r.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replayIdempotent answers a request whose key has a record: with the stored
+// response if the record is of the same request and done, and otherwise
+// with an error.
+func replayIdempotent(w http.ResponseWriter, record idempotency.Record, fingerprint string) {
+	switch {
+	case record.Fingerprint != fingerprint:
+		observability.IdempotentRequestsTotal.WithLabelValues(observability.ResultMismatch).Inc()
+		writeIdempotencyError(w, http.StatusUnprocessableEntity,
+			"Idempotency-Key was already used with a different request")
+	case !record.Done:
+		observability.IdempotentRequestsTotal.WithLabelValues(observability.ResultInProgress).Inc()
+		w.Header().Set("Retry-After", "1")
+		writeIdempotencyError(w, http.StatusConflict,
+			"a request with this Idempotency-Key is being processed")
+	default:
+		observability.IdempotentRequestsTotal.WithLabelValues(observability.ResultReplayed).Inc()
+		for name, value := range record.Header {
+			w.Header().Set(name, value)
+		}
+		w.Header().Set(IdempotencyReplayedHeader, "true")
+		w.WriteHeader(record.Status)
+		_, _ = w.Write(record.Body)
+	}
+}
+
+// writeIdempotencyError writes a JSON error response.
+func writeIdempotencyError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	resp := authErrorResponse{
+		Code:    status,
+		Message: message,
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// idempotencyRecorder passes a response through while keeping its status
+// and a copy of its body, up to maxIdempotentResponseSize.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status   int
+	written  bool
+	body     bytes.Buffer
+	overflow bool
+}
+
+// WriteHeader captures the status code and writes the header.
+func (rec *idempotencyRecorder) WriteHeader(code int) {
+	if !rec.written {
+		rec.status = code
+		rec.written = true
+		rec.ResponseWriter.WriteHeader(code)
+	}
+}
+
+// Write writes the response body and keeps a copy of it.
+func (rec *idempotencyRecorder) Write(b []byte) (int, error) {
+	if !rec.written {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if !rec.overflow {
+		if rec.body.Len()+len(b) > maxIdempotentResponseSize {
+			rec.overflow = true
+			rec.body.Reset()
+		} else {
+			rec.body.Write(b)
+		}
+	}
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,312 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/idempotency"
+	"github.com/vyrodovalexey/restapi-example/internal/middleware"
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+)
+
+// countingHandler answers with status and a body counting its calls, and
+// echoes the request body in the X-Body header.
+type countingHandler struct {
+	status int
+	calls  int
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", `"1"`)
+	w.Header().Set("X-Body", string(body))
+	w.WriteHeader(h.status)
+	_, _ = w.Write([]byte(`{"call":` + strconv.Itoa(h.calls) + `}`))
+}
+
+// idempotentRequest returns a POST request to path with body and key,
+// authenticated as subject unless it is empty.
+func idempotentRequest(path, key, body, subject string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+	if subject != "" {
+		req = req.WithContext(auth.WithAuthInfo(req.Context(), &auth.AuthInfo{
+			Method:  auth.AuthMethodBasic,
+			Subject: subject,
+		}))
+	}
+	return req
+}
+
+// fromAddr sets the remote address of req.
+func fromAddr(req *http.Request, addr string) *http.Request {
+	req.RemoteAddr = addr
+	return req
+}
+
+// failingStore is an idempotency.Store whose operations fail.
+type failingStore struct{}
+
+func (failingStore) Begin(context.Context, string, string, time.Duration) (idempotency.Record, bool, error) {
+	return idempotency.Record{}, false, errors.New("store unavailable")
+}
+
+func (failingStore) Complete(context.Context, string, idempotency.Record, time.Duration) error {
+	return errors.New("store unavailable")
+}
+
+func (failingStore) Abort(context.Context, string) error {
+	return errors.New("store unavailable")
+}
+
+func (failingStore) Close() error {
+	return nil
+}
+
+func TestIdempotency_Replay(t *testing.T) {
+	// Arrange
+	next := &countingHandler{status: http.StatusCreated}
+	handler := middleware.Idempotency(idempotency.NewMemoryStore(), time.Hour, zap.NewNop())(next)
+	replayed := testutil.ToFloat64(observability.IdempotentRequestsTotal.WithLabelValues(observability.ResultReplayed))
+
+	// Act
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, idempotentRequest("/api/v1/items", "key-1", `{"name":"a"}`, "alice"))
+	retry := httptest.NewRecorder()
+	handler.ServeHTTP(retry, idempotentRequest("/api/v1/items", "key-1", `{"name":"a"}`, "alice"))
+
+	// Assert
+	if next.calls != 1 {
+		t.Errorf("handler calls = %d, want 1", next.calls)
+	}
+	if first.Code != http.StatusCreated || first.Header().Get(middleware.IdempotencyReplayedHeader) != "" {
+		t.Errorf("first response = %d with Idempotency-Replayed %q, want 201 without",
+			first.Code, first.Header().Get(middleware.IdempotencyReplayedHeader))
+	}
+	if first.Header().Get("X-Body") != `{"name":"a"}` {
+		t.Errorf("handler read body %q, want the request body", first.Header().Get("X-Body"))
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry response = %d %q, want %d %q", retry.Code, retry.Body.String(), first.Code, first.Body.String())
+	}
+	if got := retry.Header().Get(middleware.IdempotencyReplayedHeader); got != "true" {
+		t.Errorf("Idempotency-Replayed = %q, want true", got)
+	}
+	if retry.Header().Get("ETag") != `"1"` || retry.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("replayed headers = %v, want ETag and Content-Type", retry.Header())
+	}
+	if retry.Header().Get("X-Body") != "" {
+		t.Errorf("X-Body = %q, want headers other than the stored ones not replayed", retry.Header().Get("X-Body"))
+	}
+	got := testutil.ToFloat64(observability.IdempotentRequestsTotal.WithLabelValues(observability.ResultReplayed))
+	if got != replayed+1 {
+		t.Errorf("idempotent_requests_total{result=replayed} = %v, want %v", got, replayed+1)
+	}
+}
+
+func TestIdempotency_Rejections(t *testing.T) {
+	tests := []struct {
+		name       string
+		prepare    func(store idempotency.Store)
+		key        string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "key reused with a different body",
+			prepare:    completeRequest(`{"name":"a"}`),
+			key:        "key-1",
+			body:       `{"name":"b"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "key too long",
+			key:        strings.Repeat("k", 256),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "key with control characters",
+			key:        "key\x01",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			store := idempotency.NewMemoryStore()
+			if tt.prepare != nil {
+				tt.prepare(store)
+			}
+			next := &countingHandler{status: http.StatusCreated}
+			handler := middleware.Idempotency(store, time.Hour, zap.NewNop())(next)
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(rr, idempotentRequest("/api/v1/items", tt.key, tt.body, "alice"))
+
+			// Assert
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+			if next.calls != 0 {
+				t.Errorf("handler calls = %d, want 0", next.calls)
+			}
+		})
+	}
+}
+
+// completeRequest returns a function completing a request with body and
+// the key key-1 of alice in a store.
+func completeRequest(body string) func(store idempotency.Store) {
+	return func(store idempotency.Store) {
+		handler := middleware.Idempotency(store, time.Hour, zap.NewNop())(&countingHandler{status: http.StatusCreated})
+		handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("/api/v1/items", "key-1", body, "alice"))
+	}
+}
+
+func TestIdempotency_InProgress(t *testing.T) {
+	// Arrange
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := middleware.Idempotency(idempotency.NewMemoryStore(), time.Hour, zap.NewNop())(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			close(started)
+			<-release
+			w.WriteHeader(http.StatusCreated)
+		}),
+	)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("/api/v1/items", "key-1", `{}`, "alice"))
+	}()
+	<-started
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(rr, idempotentRequest("/api/v1/items", "key-1", `{}`, "alice"))
+	close(release)
+	<-done
+
+	// Assert
+	if rr.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusConflict)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After not set")
+	}
+}
+
+func TestIdempotency_Processed(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		first  *http.Request
+		retry  *http.Request
+		store  idempotency.Store
+	}{
+		{
+			name:   "server error releases the key",
+			status: http.StatusInternalServerError,
+			first:  idempotentRequest("/api/v1/items", "key-1", `{}`, "alice"),
+			retry:  idempotentRequest("/api/v1/items", "key-1", `{}`, "alice"),
+		},
+		{
+			name:   "keys scoped per subject",
+			status: http.StatusCreated,
+			first:  idempotentRequest("/api/v1/items", "key-1", `{}`, "alice"),
+			retry:  idempotentRequest("/api/v1/items", "key-1", `{}`, "bob"),
+		},
+		{
+			name:   "anonymous keys scoped per client IP",
+			status: http.StatusCreated,
+			first:  fromAddr(idempotentRequest("/api/v1/items", "key-1", `{}`, ""), "192.0.2.1:1234"),
+			retry:  fromAddr(idempotentRequest("/api/v1/items", "key-1", `{}`, ""), "192.0.2.2:1234"),
+		},
+		{
+			name:   "without key",
+			status: http.StatusCreated,
+			first:  idempotentRequest("/api/v1/items", "", `{}`, "alice"),
+			retry:  idempotentRequest("/api/v1/items", "", `{}`, "alice"),
+		},
+		{
+			name:   "other path",
+			status: http.StatusCreated,
+			first:  idempotentRequest("/api/v1/admin/api-keys", "key-1", `{}`, "alice"),
+			retry:  idempotentRequest("/api/v1/admin/api-keys", "key-1", `{}`, "alice"),
+		},
+		{
+			name:   "store failure",
+			status: http.StatusCreated,
+			first:  idempotentRequest("/api/v1/items", "key-1", `{}`, "alice"),
+			retry:  idempotentRequest("/api/v1/items", "key-1", `{}`, "alice"),
+			store:  failingStore{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			store := tt.store
+			if store == nil {
+				store = idempotency.NewMemoryStore()
+			}
+			next := &countingHandler{status: tt.status}
+			handler := middleware.Idempotency(store, time.Hour, zap.NewNop())(next)
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(httptest.NewRecorder(), tt.first)
+			handler.ServeHTTP(rr, tt.retry)
+
+			// Assert
+			if next.calls != 2 {
+				t.Errorf("handler calls = %d, want 2", next.calls)
+			}
+			if rr.Code != tt.status {
+				t.Errorf("status = %d, want %d", rr.Code, tt.status)
+			}
+			if rr.Header().Get(middleware.IdempotencyReplayedHeader) != "" {
+				t.Error("second response replayed, want it processed")
+			}
+		})
+	}
+}
+
+func TestIdempotency_PanicReleasesKey(t *testing.T) {
+	// Arrange
+	store := idempotency.NewMemoryStore()
+	panicking := middleware.Idempotency(store, time.Hour, zap.NewNop())(
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("boom") }),
+	)
+	next := &countingHandler{status: http.StatusCreated}
+	handler := middleware.Idempotency(store, time.Hour, zap.NewNop())(next)
+
+	// Act
+	func() {
+		defer func() { _ = recover() }()
+		panicking.ServeHTTP(httptest.NewRecorder(), idempotentRequest("/api/v1/items", "key-1", `{}`, "alice"))
+	}()
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, idempotentRequest("/api/v1/items", "key-1", `{}`, "alice"))
+
+	// Assert
+	if next.calls != 1 || rr.Code != http.StatusCreated {
+		t.Errorf("retry after panic: calls = %d, status = %d, want processed", next.calls, rr.Code)
+	}
+}
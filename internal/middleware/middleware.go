@@ -233,47 +233,6 @@ func Metrics() Middleware {
 	}
 }
 
-// CORS returns a middleware that handles Cross-Origin Resource Sharing.
-func CORS(allowedOrigins []string, allowedMethods []string, allowedHeaders []string) Middleware {
-	originsMap := make(map[string]bool)
-	for _, origin := range allowedOrigins {
-		originsMap[origin] = true
-	}
-
-	methodsStr := strings.Join(allowedMethods, ", ")
-	headersStr := strings.Join(allowedHeaders, ", ")
-
-	hasWildcard := originsMap["*"]
-
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			origin := r.Header.Get("Origin")
-
-			if hasWildcard {
-				// When wildcard is configured, allow all origins but do not
-				// set credentials header as browsers reject this combination.
-				w.Header().Set("Access-Control-Allow-Origin", origin)
-			} else if originsMap[origin] {
-				// Specific origin matched — safe to allow credentials.
-				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Access-Control-Allow-Credentials", "true")
-			}
-
-			w.Header().Set("Access-Control-Allow-Methods", methodsStr)
-			w.Header().Set("Access-Control-Allow-Headers", headersStr)
-			w.Header().Set("Access-Control-Max-Age", "86400")
-
-			// Handle preflight requests
-			if r.Method == http.MethodOptions {
-				w.WriteHeader(http.StatusNoContent)
-				return
-			}
-
-			next.ServeHTTP(w, r)
-		})
-	}
-}
-
 // getRequestID extracts the request ID from the request header.
 func getRequestID(r *http.Request) string {
 	return r.Header.Get(RequestIDHeader)
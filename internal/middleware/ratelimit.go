@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+	"github.com/vyrodovalexey/restapi-example/internal/ratelimit"
+)
+
+// Rate limit route classes.
+const (
+	// RateLimitRead is the class of GET and HEAD requests.
+	RateLimitRead = "read"
+	// RateLimitWrite is the class of all other requests.
+	RateLimitWrite = "write"
+	// RateLimitGraphQL is the class of GraphQL requests, including
+	// subscription upgrades.
+	RateLimitGraphQL = "graphql"
+)
+
+// RateLimit returns a middleware that limits the requests of each client
+// with the token bucket in limits for the route class of the request.
+// Clients are identified by their authenticated subject, so RateLimit must
+// run after Auth, and otherwise by their IP address. Public paths, CORS
+// preflight requests and classes without a limit are not limited. Responses
+// carry the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers; rejected requests get 429 with Retry-After. If
+// the limiter fails, the request is allowed.
+func RateLimit(
+	limiter ratelimit.Limiter,
+	limits map[string]ratelimit.Limit,
+	logger *zap.Logger,
+) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(
+			w http.ResponseWriter,
+			r *http.Request,
+		) {
+			if isPublicPath(r.URL.Path) || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			class := rateLimitClass(r)
+			limit, ok := limits[class]
+			if !ok || !limit.Enabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := clientKey(r)
+			result, err := limiter.Allow(r.Context(), class+":"+client, limit)
+			if err != nil {
+				logger.Error("rate limiter failed, allowing request",
+					zap.String("class", class),
+					zap.Error(err),
+				)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			setRateLimitHeaders(w, limit, result)
+			if !result.Allowed {
+				observability.RateLimitRejectionsTotal.WithLabelValues(class).Inc()
+				logger.Debug("rate limit exceeded",
+					zap.String("class", class),
+					zap.String("client", client),
+					zap.String("path", r.URL.Path),
+				)
+				writeRateLimited(w, result.RetryAfter)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitClass returns the route class of r.
+func rateLimitClass(r *http.Request) string {
+	switch {
+	case r.URL.Path == "/graphql" || strings.HasPrefix(r.URL.Path, "/graphql/"):
+		return RateLimitGraphQL
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return RateLimitRead
+	default:
+		return RateLimitWrite
+	}
+}
+
+// clientKey returns the key of the client of r: its authenticated subject
+// or, without one, its IP address. It identifies clients to RateLimit and
+// Idempotency.
+func clientKey(r *http.Request) string {
+	if info, ok := auth.FromContext(r.Context()); ok && info != nil && info.Subject != "" {
+		return "subject:" + string(info.Method) + ":" + info.Subject
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// setRateLimitHeaders describes the limit and the state of the client's
+// bucket in the RateLimit-* headers.
+func setRateLimitHeaders(w http.ResponseWriter, limit ratelimit.Limit, result ratelimit.Result) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", ceilSeconds(result.Reset))
+	w.Header().Set("RateLimit-Policy",
+		strconv.Itoa(limit.Requests)+";w="+ceilSeconds(limit.Period)+";burst="+strconv.Itoa(limit.Burst))
+}
+
+// writeRateLimited writes an HTTP 429 response with a Retry-After header.
+func writeRateLimited(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", ceilSeconds(retryAfter))
+	w.WriteHeader(http.StatusTooManyRequests)
+
+	resp := authErrorResponse{
+		Code:    http.StatusTooManyRequests,
+		Message: "rate limit exceeded",
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// ceilSeconds formats d as whole seconds, rounded up.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
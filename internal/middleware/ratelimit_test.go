@@ -0,0 +1,296 @@
+package middleware_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/middleware"
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+	"github.com/vyrodovalexey/restapi-example/internal/ratelimit"
+)
+
+// recordingLimiter is a ratelimit.Limiter that records the keys it is asked
+// about and answers with result and err.
+type recordingLimiter struct {
+	result ratelimit.Result
+	err    error
+	keys   []string
+}
+
+func (l *recordingLimiter) Allow(_ context.Context, key string, _ ratelimit.Limit) (ratelimit.Result, error) {
+	l.keys = append(l.keys, key)
+	return l.result, l.err
+}
+
+func (l *recordingLimiter) Close() error {
+	return nil
+}
+
+// rateLimitRequest returns a request from 192.0.2.10, authenticated as
+// subject unless it is empty.
+func rateLimitRequest(method, path, subject string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = "192.0.2.10:50000"
+	if subject != "" {
+		req = req.WithContext(auth.WithAuthInfo(req.Context(), &auth.AuthInfo{
+			Method:  auth.AuthMethodAPIKey,
+			Subject: subject,
+		}))
+	}
+	return req
+}
+
+func TestRateLimit_Keys(t *testing.T) {
+	t.Parallel()
+
+	limit := ratelimit.Limit{Requests: 10, Period: time.Minute, Burst: 10}
+	limits := map[string]ratelimit.Limit{
+		middleware.RateLimitRead:    limit,
+		middleware.RateLimitWrite:   limit,
+		middleware.RateLimitGraphQL: limit,
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		subject string
+		wantKey string
+	}{
+		{
+			name:    "anonymous read",
+			method:  http.MethodGet,
+			path:    "/api/v1/items",
+			wantKey: "read:ip:192.0.2.10",
+		},
+		{
+			name:    "authenticated read",
+			method:  http.MethodHead,
+			path:    "/api/v1/items/1",
+			subject: "ci-pipeline",
+			wantKey: "read:subject:apikey:ci-pipeline",
+		},
+		{
+			name:    "write",
+			method:  http.MethodDelete,
+			path:    "/api/v1/items/1",
+			subject: "ci-pipeline",
+			wantKey: "write:subject:apikey:ci-pipeline",
+		},
+		{
+			name:    "graphql query",
+			method:  http.MethodPost,
+			path:    "/graphql",
+			wantKey: "graphql:ip:192.0.2.10",
+		},
+		{
+			name:    "graphql subscription",
+			method:  http.MethodGet,
+			path:    "/graphql/ws",
+			wantKey: "graphql:ip:192.0.2.10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			limiter := &recordingLimiter{result: ratelimit.Result{Allowed: true}}
+			handler := middleware.RateLimit(limiter, limits, zap.NewNop())(successHandler())
+			rec := httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(rec, rateLimitRequest(tt.method, tt.path, tt.subject))
+
+			// Assert
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if len(limiter.keys) != 1 || limiter.keys[0] != tt.wantKey {
+				t.Errorf("keys = %v, want [%s]", limiter.keys, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestRateLimit_Skipped(t *testing.T) {
+	t.Parallel()
+
+	limits := map[string]ratelimit.Limit{
+		middleware.RateLimitRead:  {Requests: 10, Period: time.Minute, Burst: 10},
+		middleware.RateLimitWrite: {},
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{name: "public path", method: http.MethodGet, path: "/health"},
+		{name: "metrics", method: http.MethodGet, path: "/metrics"},
+		{name: "preflight", method: http.MethodOptions, path: "/api/v1/items"},
+		{name: "disabled class", method: http.MethodPost, path: "/api/v1/items"},
+		{name: "class without limit", method: http.MethodPost, path: "/graphql"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			limiter := &recordingLimiter{}
+			handler := middleware.RateLimit(limiter, limits, zap.NewNop())(successHandler())
+			rec := httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(rec, rateLimitRequest(tt.method, tt.path, ""))
+
+			// Assert
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if len(limiter.keys) != 0 {
+				t.Errorf("keys = %v, want none", limiter.keys)
+			}
+			if got := rec.Header().Get("RateLimit-Limit"); got != "" {
+				t.Errorf("RateLimit-Limit = %q, want none", got)
+			}
+		})
+	}
+}
+
+func TestRateLimit_Headers(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	limits := map[string]ratelimit.Limit{
+		middleware.RateLimitRead: {Requests: 100, Period: time.Minute, Burst: 20},
+	}
+	limiter := &recordingLimiter{result: ratelimit.Result{
+		Allowed:   true,
+		Remaining: 7,
+		Reset:     7800 * time.Millisecond,
+	}}
+	handler := middleware.RateLimit(limiter, limits, zap.NewNop())(successHandler())
+	rec := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(rec, rateLimitRequest(http.MethodGet, "/api/v1/items", ""))
+
+	// Assert
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	wantHeaders := map[string]string{
+		"RateLimit-Limit":     "20",
+		"RateLimit-Remaining": "7",
+		"RateLimit-Reset":     "8",
+		"RateLimit-Policy":    "100;w=60;burst=20",
+		"Retry-After":         "",
+	}
+	for name, want := range wantHeaders {
+		if got := rec.Header().Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRateLimit_Rejected(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	limits := map[string]ratelimit.Limit{
+		middleware.RateLimitGraphQL: {Requests: 1, Period: time.Second, Burst: 1},
+	}
+	limiter := &recordingLimiter{result: ratelimit.Result{
+		RetryAfter: 1500 * time.Millisecond,
+		Reset:      1500 * time.Millisecond,
+	}}
+	handler := middleware.RateLimit(limiter, limits, zap.NewNop())(successHandler())
+	rejections := observability.RateLimitRejectionsTotal.WithLabelValues(middleware.RateLimitGraphQL)
+	before := testutil.ToFloat64(rejections)
+	rec := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(rec, rateLimitRequest(http.MethodPost, "/graphql", "alice"))
+
+	// Assert
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want %q", got, "2")
+	}
+	if got := rec.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("RateLimit-Remaining = %q, want %q", got, "0")
+	}
+	var body map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if body["code"] != float64(http.StatusTooManyRequests) || body["message"] != "rate limit exceeded" {
+		t.Errorf("body = %v, want code 429 and message %q", body, "rate limit exceeded")
+	}
+	if got := testutil.ToFloat64(rejections) - before; got != 1 {
+		t.Errorf("rate_limit_rejections_total{class=graphql} increased by %v, want 1", got)
+	}
+}
+
+func TestRateLimit_LimiterError_FailsOpen(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	limits := map[string]ratelimit.Limit{
+		middleware.RateLimitWrite: {Requests: 1, Period: time.Second, Burst: 1},
+	}
+	limiter := &recordingLimiter{err: errors.New("connection refused")}
+	handler := middleware.RateLimit(limiter, limits, zap.NewNop())(successHandler())
+	rec := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(rec, rateLimitRequest(http.MethodPut, "/api/v1/items/1", "alice"))
+
+	// Assert
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("RateLimit-Limit"); got != "" {
+		t.Errorf("RateLimit-Limit = %q, want none", got)
+	}
+}
+
+func TestRateLimit_MemoryLimiter(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	limits := map[string]ratelimit.Limit{
+		middleware.RateLimitRead: {Requests: 2, Period: time.Hour, Burst: 2},
+	}
+	handler := middleware.RateLimit(ratelimit.NewMemoryLimiter(), limits, zap.NewNop())(successHandler())
+
+	// Act
+	var codes []int
+	for _, subject := range []string{"alice", "alice", "alice", "bob"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, rateLimitRequest(http.MethodGet, "/api/v1/items", subject))
+		codes = append(codes, rec.Code)
+	}
+
+	// Assert
+	want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusOK}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Errorf("request %d status = %d, want %d", i+1, codes[i], want[i])
+		}
+	}
+}
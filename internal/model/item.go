@@ -28,6 +28,10 @@ type Item struct {
 	Price       float64   `json:"price"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Version starts at 1 and is incremented by the store on every write.
+	// It backs optimistic concurrency control (ETag / If-Match).
+	Version int64 `json:"version"`
 }
 
 // Validate checks if the Item has valid field values.
@@ -53,9 +57,18 @@ func (i *Item) Validate() error {
 
 // APIResponse is a generic wrapper for API responses.
 type APIResponse[T any] struct {
-	Success bool   `json:"success"`
-	Data    T      `json:"data,omitempty"`
-	Error   string `json:"error,omitempty"`
+	Success bool      `json:"success"`
+	Data    T         `json:"data,omitempty"`
+	Meta    *PageMeta `json:"meta,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// PageMeta describes the position of a paginated list response.
+type PageMeta struct {
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // NewSuccessResponse creates a successful API response.
@@ -66,6 +79,15 @@ func NewSuccessResponse[T any](data T) APIResponse[T] {
 	}
 }
 
+// NewPageResponse creates a successful API response carrying pagination metadata.
+func NewPageResponse[T any](data T, meta PageMeta) APIResponse[T] {
+	return APIResponse[T]{
+		Success: true,
+		Data:    data,
+		Meta:    &meta,
+	}
+}
+
 // NewErrorResponse creates an error API response.
 func NewErrorResponse[T any](errMsg string) APIResponse[T] {
 	return APIResponse[T]{
@@ -83,8 +105,24 @@ type ErrorResponse struct {
 
 // WebSocketMessage represents a message sent over WebSocket connection.
 type WebSocketMessage struct {
-	Type      string    `json:"type"`
-	Value     int       `json:"value,omitempty"`
+	Type  string `json:"type"`
+	Value int    `json:"value,omitempty"`
+
+	// ItemIDs restricts a subscribe message to the given items; empty
+	// subscribes to all items.
+	ItemIDs []string `json:"item_ids,omitempty"`
+
+	// ItemID and Item describe the changed item of an item event. Item is
+	// omitted for deletions.
+	ItemID string `json:"item_id,omitempty"`
+	Item   *Item  `json:"item,omitempty"`
+
+	// Message describes the problem in an error message.
+	Message string `json:"message,omitempty"`
+
+	// Token carries the bearer token of an auth message.
+	Token string `json:"token,omitempty"`
+
 	Timestamp time.Time `json:"timestamp"`
 }
 
@@ -94,6 +132,23 @@ const (
 	WSMessageTypePing        = "ping"
 	WSMessageTypePong        = "pong"
 	WSMessageTypeError       = "error"
+
+	// Sent by clients to start and stop receiving item events, and
+	// acknowledged by the server with subscribed and unsubscribed.
+	WSMessageTypeSubscribe    = "subscribe"
+	WSMessageTypeUnsubscribe  = "unsubscribe"
+	WSMessageTypeSubscribed   = "subscribed"
+	WSMessageTypeUnsubscribed = "unsubscribed"
+
+	// Sent by clients that did not authenticate the upgrade request, and
+	// acknowledged by the server with authenticated.
+	WSMessageTypeAuth          = "auth"
+	WSMessageTypeAuthenticated = "authenticated"
+
+	// Item events sent to subscribed clients.
+	WSMessageTypeItemCreated = "item_created"
+	WSMessageTypeItemUpdated = "item_updated"
+	WSMessageTypeItemDeleted = "item_deleted"
 )
 
 // NewRandomValueMessage creates a new WebSocket message with a random value.
@@ -104,3 +159,22 @@ func NewRandomValueMessage(value int) WebSocketMessage {
 		Timestamp: time.Now().UTC(),
 	}
 }
+
+// NewItemEventMessage creates a WebSocket message for a change to an item.
+func NewItemEventMessage(messageType, itemID string, item *Item, timestamp time.Time) WebSocketMessage {
+	return WebSocketMessage{
+		Type:      messageType,
+		ItemID:    itemID,
+		Item:      item,
+		Timestamp: timestamp,
+	}
+}
+
+// NewErrorMessage creates a WebSocket error message.
+func NewErrorMessage(message string) WebSocketMessage {
+	return WebSocketMessage{
+		Type:      WSMessageTypeError,
+		Message:   message,
+		Timestamp: time.Now().UTC(),
+	}
+}
@@ -378,6 +378,48 @@ func TestAPIResponse_JSONMarshal(t *testing.T) {
 	}
 }
 
+func TestNewPageResponse(t *testing.T) {
+	// Arrange
+	resp := NewPageResponse([]Item{{ID: "1"}}, PageMeta{Total: 10, Limit: 1, NextCursor: "abc"})
+
+	// Act
+	data, err := json.Marshal(resp)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("json.Unmarshal() unexpected error: %v", err)
+	}
+
+	meta, ok := result["meta"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("meta = %v, want object", result["meta"])
+	}
+	if meta["total"] != float64(10) || meta["limit"] != float64(1) || meta["next_cursor"] != "abc" {
+		t.Errorf("meta = %v, want total=10 limit=1 next_cursor=abc", meta)
+	}
+	if _, exists := meta["offset"]; exists {
+		t.Error("meta.offset should be omitted when zero")
+	}
+}
+
+func TestAPIResponse_JSONOmitsMeta(t *testing.T) {
+	// Act
+	data, err := json.Marshal(NewSuccessResponse(Item{ID: "1"}))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+	if strings.Contains(string(data), `"meta"`) {
+		t.Errorf("non-paginated response should omit meta, got %s", data)
+	}
+}
+
 func TestErrorResponse(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -574,6 +616,61 @@ func TestWebSocketMessageConstants(t *testing.T) {
 	if WSMessageTypeError != "error" {
 		t.Errorf("WSMessageTypeError = %s, want error", WSMessageTypeError)
 	}
+	if WSMessageTypeSubscribe != "subscribe" {
+		t.Errorf("WSMessageTypeSubscribe = %s, want subscribe", WSMessageTypeSubscribe)
+	}
+	if WSMessageTypeUnsubscribe != "unsubscribe" {
+		t.Errorf("WSMessageTypeUnsubscribe = %s, want unsubscribe", WSMessageTypeUnsubscribe)
+	}
+	if WSMessageTypeAuth != "auth" {
+		t.Errorf("WSMessageTypeAuth = %s, want auth", WSMessageTypeAuth)
+	}
+	if WSMessageTypeAuthenticated != "authenticated" {
+		t.Errorf("WSMessageTypeAuthenticated = %s, want authenticated", WSMessageTypeAuthenticated)
+	}
+	if WSMessageTypeItemCreated != "item_created" {
+		t.Errorf("WSMessageTypeItemCreated = %s, want item_created", WSMessageTypeItemCreated)
+	}
+	if WSMessageTypeItemUpdated != "item_updated" {
+		t.Errorf("WSMessageTypeItemUpdated = %s, want item_updated", WSMessageTypeItemUpdated)
+	}
+	if WSMessageTypeItemDeleted != "item_deleted" {
+		t.Errorf("WSMessageTypeItemDeleted = %s, want item_deleted", WSMessageTypeItemDeleted)
+	}
+}
+
+func TestNewItemEventMessage(t *testing.T) {
+	// Arrange
+	item := &Item{ID: "123", Name: "Widget", Price: 1}
+	now := time.Now().UTC()
+
+	// Act
+	msg := NewItemEventMessage(WSMessageTypeItemUpdated, item.ID, item, now)
+	data, err := json.Marshal(msg)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+	if msg.Type != WSMessageTypeItemUpdated || msg.ItemID != "123" || msg.Item != item || !msg.Timestamp.Equal(now) {
+		t.Errorf("NewItemEventMessage() = %+v", msg)
+	}
+	if !strings.Contains(string(data), `"item_id":"123"`) || strings.Contains(string(data), `"value"`) {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+}
+
+func TestNewErrorMessage(t *testing.T) {
+	// Act
+	msg := NewErrorMessage("unknown message type")
+
+	// Assert
+	if msg.Type != WSMessageTypeError || msg.Message != "unknown message type" {
+		t.Errorf("NewErrorMessage() = %+v", msg)
+	}
+	if msg.Timestamp.IsZero() {
+		t.Error("Timestamp should be set")
+	}
 }
 
 func TestValidationConstants(t *testing.T) {
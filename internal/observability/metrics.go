@@ -19,6 +19,32 @@ const (
 	ResultSuccess = "success"
 	// ResultFailure is the result label value for a failed operation.
 	ResultFailure = "failure"
+	// ResultRevoked is the auth_attempts_total result label value for a
+	// client certificate rejected because it has been revoked.
+	ResultRevoked = "revoked"
+	// ResultLockedOut is the auth_attempts_total result label value for an
+	// attempt rejected because its subject or client is locked out.
+	ResultLockedOut = "locked_out"
+
+	// ScopeSubject and ScopeClient are the auth_lockouts_active scope label
+	// values for lockouts of a subject and of a client IP address.
+	ScopeSubject = "subject"
+	ScopeClient  = "client"
+
+	// ResultStored, ResultReplayed, ResultInProgress and ResultMismatch are
+	// the idempotent_requests_total result label values for a request that
+	// was processed and stored, one answered with the stored response, one
+	// rejected while the first request with its key is processed and one
+	// rejected for reusing a key with a different request.
+	ResultStored     = "stored"
+	ResultReplayed   = "replayed"
+	ResultInProgress = "in_progress"
+	ResultMismatch   = "mismatch"
+
+	// DecisionAllow is the decision label value for an allowed request.
+	DecisionAllow = "allow"
+	// DecisionDeny is the decision label value for a denied request.
+	DecisionDeny = "deny"
 
 	labelMethod    = "method"
 	labelResult    = "result"
@@ -27,6 +53,11 @@ const (
 	labelVersion   = "version"
 	labelCommit    = "commit"
 	labelBuildTime = "build_time"
+	labelResource  = "resource"
+	labelDecision  = "decision"
+	labelSource    = "source"
+	labelScope     = "scope"
+	labelClass     = "class"
 )
 
 // Domain and runtime Prometheus metrics.
@@ -39,7 +70,8 @@ var (
 	// AuthAttemptsTotal counts authentication attempts.
 	// Labels:
 	//   method - the authentication method (mtls|basic|apikey|oidc|multi).
-	//   result - success|failure.
+	//   result - success|failure|revoked (mtls client certificate revoked)|
+	//            locked_out (rejected during a lockout).
 	AuthAttemptsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "auth_attempts_total",
@@ -48,6 +80,53 @@ var (
 		[]string{labelMethod, labelResult},
 	)
 
+	// AuthLockoutsActive tracks the subjects and client IP addresses that are
+	// currently locked out after repeated failed authentication attempts.
+	// Labels:
+	//   method - the authentication method (basic|apikey).
+	//   scope  - subject|client.
+	AuthLockoutsActive = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "auth_lockouts_active",
+			Help: "Number of currently locked out subjects and clients by method and scope",
+		},
+		[]string{labelMethod, labelScope},
+	)
+
+	// RateLimitRejectionsTotal counts requests rejected by the rate limiter.
+	// Label:
+	//   class - the route class whose limit was exceeded (read|write|graphql).
+	RateLimitRejectionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limit_rejections_total",
+			Help: "Total number of requests rejected by the rate limiter by route class",
+		},
+		[]string{labelClass},
+	)
+
+	// IdempotentRequestsTotal counts requests made with an Idempotency-Key.
+	// Label:
+	//   result - stored|replayed|in_progress|mismatch.
+	IdempotentRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "idempotent_requests_total",
+			Help: "Total number of requests made with an Idempotency-Key by result",
+		},
+		[]string{labelResult},
+	)
+
+	// AuthzDecisionsTotal counts authorization decisions.
+	// Labels:
+	//   resource - route|graphql_field.
+	//   decision - allow|deny.
+	AuthzDecisionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "authz_decisions_total",
+			Help: "Total number of authorization decisions by resource kind and decision",
+		},
+		[]string{labelResource, labelDecision},
+	)
+
 	// WebSocketActiveConnections tracks the number of live WebSocket
 	// connections. Incremented on register, decremented on unregister.
 	WebSocketActiveConnections = promauto.NewGauge(
@@ -57,9 +136,18 @@ var (
 		},
 	)
 
+	// ItemEventsDroppedTotal counts item change events not delivered to a
+	// subscriber because its buffer was full.
+	ItemEventsDroppedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "item_events_dropped_total",
+			Help: "Total number of item change events dropped for slow subscribers",
+		},
+	)
+
 	// StoreOperationsTotal counts store operations.
 	// Labels:
-	//   operation - list|get|create|update|delete.
+	//   operation - list|get|create|update|delete|batch.
 	//   result    - success|failure.
 	StoreOperationsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -71,7 +159,7 @@ var (
 
 	// StoreOperationDuration observes store operation latency in seconds.
 	// Label:
-	//   operation - list|get|create|update|delete.
+	//   operation - list|get|create|update|delete|batch.
 	StoreOperationDuration = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "store_operation_duration_seconds",
@@ -81,6 +169,31 @@ var (
 		[]string{labelOperation},
 	)
 
+	// TLSCertificateExpiry reports the expiry (not-after) of the TLS server
+	// certificate currently served, as a Unix timestamp in seconds.
+	// Label:
+	//   source - where the certificate comes from (vault|file).
+	TLSCertificateExpiry = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "tls_certificate_expiry_timestamp_seconds",
+			Help: "Expiry of the served TLS server certificate as a Unix timestamp by source",
+		},
+		[]string{labelSource},
+	)
+
+	// TLSCertificateRenewalsTotal counts TLS server certificate renewals:
+	// issuances from Vault and (re)loads of the certificate and CA files.
+	// Labels:
+	//   source - where the certificate comes from (vault|file).
+	//   result - success|failure.
+	TLSCertificateRenewalsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tls_certificate_renewals_total",
+			Help: "Total number of TLS server certificate renewals by source and result",
+		},
+		[]string{labelSource, labelResult},
+	)
+
 	// PanicsRecoveredTotal counts panics recovered by the Recovery middleware.
 	PanicsRecoveredTotal = promauto.NewCounter(
 		prometheus.CounterOpts{
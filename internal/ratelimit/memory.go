@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxMemoryBuckets bounds the buckets of a MemoryLimiter.
+const maxMemoryBuckets = 100000
+
+// MemoryLimiter keeps token buckets in memory. Its limits apply to one
+// server instance.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// bucket is the state of a token bucket.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // when the bucket is full again
+}
+
+// NewMemoryLimiter creates a MemoryLimiter.
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of key. When too many keys are
+// tracked and none of their buckets is full again, requests for new keys
+// are allowed without being tracked.
+func (m *MemoryLimiter) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	b, ok := m.buckets[key]
+	if !ok {
+		if len(m.buckets) >= maxMemoryBuckets {
+			m.prune(now)
+			if len(m.buckets) >= maxMemoryBuckets {
+				return limit.result(true, float64(limit.Burst-1)), nil
+			}
+		}
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		m.buckets[key] = b
+	}
+
+	b.tokens = min(float64(limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*limit.rate())
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	result := limit.result(allowed, b.tokens)
+	b.full = now.Add(result.Reset)
+
+	return result, nil
+}
+
+// Close implements Limiter.
+func (m *MemoryLimiter) Close() error {
+	return nil
+}
+
+// prune drops the buckets that are full again, which are the same as new
+// ones.
+func (m *MemoryLimiter) prune(now time.Time) {
+	for key, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newTestMemoryLimiter returns a MemoryLimiter whose clock is *now.
+func newTestMemoryLimiter(now *time.Time) *MemoryLimiter {
+	m := NewMemoryLimiter()
+	m.now = func() time.Time { return *now }
+	return m
+}
+
+func TestMemoryLimiter_Allow(t *testing.T) {
+	t.Parallel()
+
+	limit := Limit{Requests: 2, Period: time.Second, Burst: 3}
+
+	tests := []struct {
+		name          string
+		requests      int
+		wait          time.Duration
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+		wantReset     time.Duration
+	}{
+		{
+			name:          "first request",
+			requests:      1,
+			wantAllowed:   true,
+			wantRemaining: 2,
+			wantReset:     500 * time.Millisecond,
+		},
+		{
+			name:          "last token of the burst",
+			requests:      3,
+			wantAllowed:   true,
+			wantRemaining: 0,
+			wantReset:     1500 * time.Millisecond,
+		},
+		{
+			name:          "burst exhausted",
+			requests:      4,
+			wantAllowed:   false,
+			wantRemaining: 0,
+			wantRetry:     500 * time.Millisecond,
+			wantReset:     1500 * time.Millisecond,
+		},
+		{
+			name:          "refilled after waiting",
+			requests:      4,
+			wait:          500 * time.Millisecond,
+			wantAllowed:   true,
+			wantRemaining: 0,
+			wantReset:     1500 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			now := time.Unix(1700000000, 0)
+			limiter := newTestMemoryLimiter(&now)
+			for range tt.requests - 1 {
+				_, _ = limiter.Allow(context.Background(), "client", limit)
+			}
+			now = now.Add(tt.wait)
+
+			// Act
+			result, err := limiter.Allow(context.Background(), "client", limit)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("Allow() error = %v", err)
+			}
+			if result.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %v, want %v", result.Allowed, tt.wantAllowed)
+			}
+			if result.Remaining != tt.wantRemaining {
+				t.Errorf("Remaining = %d, want %d", result.Remaining, tt.wantRemaining)
+			}
+			if result.RetryAfter != tt.wantRetry {
+				t.Errorf("RetryAfter = %v, want %v", result.RetryAfter, tt.wantRetry)
+			}
+			if result.Reset != tt.wantReset {
+				t.Errorf("Reset = %v, want %v", result.Reset, tt.wantReset)
+			}
+		})
+	}
+}
+
+func TestMemoryLimiter_SeparateKeys(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	now := time.Unix(1700000000, 0)
+	limiter := newTestMemoryLimiter(&now)
+	limit := Limit{Requests: 1, Period: time.Minute, Burst: 1}
+	_, _ = limiter.Allow(context.Background(), "alice", limit)
+
+	// Act
+	alice, _ := limiter.Allow(context.Background(), "alice", limit)
+	bob, _ := limiter.Allow(context.Background(), "bob", limit)
+
+	// Assert
+	if alice.Allowed {
+		t.Error("Allowed for alice = true, want false")
+	}
+	if !bob.Allowed {
+		t.Error("Allowed for bob = false, want true")
+	}
+}
+
+func TestMemoryLimiter_Prune(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	now := time.Unix(1700000000, 0)
+	limiter := newTestMemoryLimiter(&now)
+	limit := Limit{Requests: 1, Period: time.Second, Burst: 1}
+	for i := range maxMemoryBuckets {
+		_, _ = limiter.Allow(context.Background(), strconv.Itoa(i), limit)
+	}
+
+	// Act
+	untracked, _ := limiter.Allow(context.Background(), "new", limit)
+	trackedBefore := len(limiter.buckets)
+	now = now.Add(time.Second)
+	_, _ = limiter.Allow(context.Background(), "new", limit)
+	trackedAfter := len(limiter.buckets)
+
+	// Assert
+	if !untracked.Allowed {
+		t.Error("Allowed with a full limiter = false, want true")
+	}
+	if trackedBefore != maxMemoryBuckets {
+		t.Errorf("buckets before pruning = %d, want %d", trackedBefore, maxMemoryBuckets)
+	}
+	if trackedAfter != 1 {
+		t.Errorf("buckets after pruning = %d, want 1", trackedAfter)
+	}
+}
+
+func TestLimit_Enabled(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		limit Limit
+		want  bool
+	}{
+		{name: "complete", limit: Limit{Requests: 1, Period: time.Second, Burst: 1}, want: true},
+		{name: "zero", limit: Limit{}, want: false},
+		{name: "no period", limit: Limit{Requests: 1, Burst: 1}, want: false},
+		{name: "no burst", limit: Limit{Requests: 1, Period: time.Second}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			got := tt.limit.Enabled()
+
+			// Assert
+			if got != tt.want {
+				t.Errorf("Enabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
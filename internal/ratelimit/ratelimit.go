@@ -0,0 +1,69 @@
+// Package ratelimit provides token bucket rate limiters with an in-memory
+// backend for single instances and a Redis backend shared by replicas.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit is a token bucket: it holds up to Burst tokens, one is taken per
+// request, and Requests tokens are added every Period.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// Enabled reports whether l limits anything.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0 && l.Burst > 0
+}
+
+// rate returns the tokens added per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// result describes a bucket holding tokens after a request was allowed or
+// not.
+func (l Limit) result(allowed bool, tokens float64) Result {
+	rate := l.rate()
+	r := Result{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(l.Burst) - tokens) / rate),
+	}
+	if !allowed {
+		r.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return r
+}
+
+// seconds converts s seconds to a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Max(s, 0) * float64(time.Second))
+}
+
+// Result is the outcome of a request to a Limiter.
+type Result struct {
+	// Allowed reports whether the request may proceed.
+	Allowed bool
+	// Remaining is the number of requests allowed right now.
+	Remaining int
+	// RetryAfter is how long until a request is allowed again; zero if
+	// this one was.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Limiter takes tokens from the bucket of a key.
+type Limiter interface {
+	// Allow takes a token from the bucket of key with the given limit.
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+
+	// Close releases the resources of the limiter.
+	Close() error
+}
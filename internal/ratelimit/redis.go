@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec // SHA-1 names scripts in Redis, it does not protect anything
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vyrodovalexey/restapi-example/internal/redis"
+)
+
+// ErrRedis is returned when the Redis server cannot be reached or fails a
+// command.
+var ErrRedis = errors.New("redis rate limiter")
+
+// defaultRedisPrefix is the default prefix of bucket keys.
+const defaultRedisPrefix = "ratelimit:"
+
+// tokenBucketScript updates the token bucket in KEYS[1] and takes a token
+// if one is left. ARGV holds the tokens added per millisecond, the burst
+// and the TTL of the bucket in milliseconds. It returns whether the token
+// was taken and the tokens left. The clock of the Redis server is used, so
+// that replicas with skewed clocks share buckets consistently.
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return {allowed, tostring(tokens)}
+`
+
+// tokenBucketSHA is the SHA-1 digest Redis knows tokenBucketScript by.
+var tokenBucketSHA = func() string {
+	sum := sha1.Sum([]byte(tokenBucketScript)) //nolint:gosec // see import
+	return hex.EncodeToString(sum[:])
+}()
+
+// RedisConfig configures a RedisLimiter.
+type RedisConfig struct {
+	redis.Config
+	// Prefix is prepended to bucket keys. It defaults to "ratelimit:".
+	Prefix string
+}
+
+// RedisLimiter keeps token buckets in a Redis server, or any server
+// speaking the Redis protocol with Lua scripting, so that replicas share
+// their limits. Buckets are updated atomically by a script and expire once
+// they would be full again.
+type RedisLimiter struct {
+	prefix string
+	client *redis.Client
+}
+
+// NewRedisLimiter creates a RedisLimiter. Connections are opened when
+// needed.
+func NewRedisLimiter(config RedisConfig) *RedisLimiter {
+	if config.Prefix == "" {
+		config.Prefix = defaultRedisPrefix
+	}
+
+	return &RedisLimiter{
+		prefix: config.Prefix,
+		client: redis.NewClient(config.Config),
+	}
+}
+
+// Allow takes a token from the bucket of key.
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	ttl := time.Duration(float64(limit.Burst)/limit.rate()*float64(time.Second)) + time.Second
+	reply, err := l.eval(ctx, l.prefix+key,
+		strconv.FormatFloat(limit.rate()/1000, 'g', -1, 64),
+		strconv.Itoa(limit.Burst),
+		strconv.FormatInt(ttl.Milliseconds(), 10),
+	)
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %w", ErrRedis, err)
+	}
+
+	values, ok := reply.([]any)
+	if !ok || len(values) != 2 {
+		return Result{}, fmt.Errorf("%w: unexpected script reply %v", ErrRedis, reply)
+	}
+	allowed, _ := values[0].(int64)
+	left, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(left, 64)
+	if err != nil || math.IsNaN(tokens) {
+		return Result{}, fmt.Errorf("%w: unexpected script reply %v", ErrRedis, reply)
+	}
+
+	return limit.result(allowed == 1, tokens), nil
+}
+
+// Close closes the idle connections. Connections in use are closed when
+// they are returned.
+func (l *RedisLimiter) Close() error {
+	return l.client.Close()
+}
+
+// eval runs the token bucket script for key, loading it into the script
+// cache if the server does not know it yet.
+func (l *RedisLimiter) eval(ctx context.Context, key string, args ...string) (any, error) {
+	reply, err := l.client.Do(ctx, append([]string{"EVALSHA", tokenBucketSHA, "1", key}, args...)...)
+	var replyErr redis.Error
+	if errors.As(err, &replyErr) && strings.HasPrefix(string(replyErr), "NOSCRIPT") {
+		reply, err = l.client.Do(ctx, append([]string{"EVAL", tokenBucketScript, "1", key}, args...)...)
+	}
+	return reply, err
+}
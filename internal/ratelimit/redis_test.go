@@ -0,0 +1,253 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vyrodovalexey/restapi-example/internal/redis"
+)
+
+// fakeRedis is a Redis server that understands the commands of
+// RedisLimiter. Its token bucket script takes tokens without refilling.
+type fakeRedis struct {
+	addr     string
+	password string
+
+	mu       sync.Mutex
+	commands []string
+	loaded   bool
+	tokens   map[string]int
+}
+
+// newFakeRedis starts a fakeRedis requiring password, if any.
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	f := &fakeRedis{addr: listener.Addr().String(), password: password, tokens: make(map[string]int)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+// serve answers the commands sent on conn.
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+	authenticated := f.password == ""
+	for {
+		request, err := redis.ReadReply(r)
+		if err != nil {
+			return
+		}
+		args, _ := request.([]any)
+		if len(args) == 0 {
+			return
+		}
+		command, _ := args[0].(string)
+		f.record(command)
+
+		switch {
+		case command == "AUTH":
+			authenticated = args[1] == f.password
+			if !authenticated {
+				fmt.Fprint(w, "-WRONGPASS invalid password\r\n")
+				break
+			}
+			fmt.Fprint(w, "+OK\r\n")
+		case !authenticated:
+			fmt.Fprint(w, "-NOAUTH Authentication required.\r\n")
+		case command == "SELECT":
+			fmt.Fprint(w, "+OK\r\n")
+		case command == "EVALSHA" && args[1] != tokenBucketSHA:
+			fmt.Fprint(w, "-ERR unexpected script digest\r\n")
+		case command == "EVALSHA" || command == "EVAL":
+			f.eval(w, command, args)
+		default:
+			fmt.Fprintf(w, "-ERR unknown command '%s'\r\n", command)
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// eval runs the token bucket script, or replies NOSCRIPT to EVALSHA before
+// the script was loaded by EVAL.
+func (f *fakeRedis) eval(w *bufio.Writer, command string, args []any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if command == "EVAL" {
+		f.loaded = true
+	} else if !f.loaded {
+		fmt.Fprint(w, "-NOSCRIPT No matching script. Please use EVAL.\r\n")
+		return
+	}
+
+	key, _ := args[3].(string)
+	burst, _ := strconv.Atoi(args[5].(string))
+	tokens, ok := f.tokens[key]
+	if !ok {
+		tokens = burst
+	}
+	allowed := 0
+	if tokens >= 1 {
+		tokens--
+		allowed = 1
+	}
+	f.tokens[key] = tokens
+
+	left := strconv.Itoa(tokens)
+	fmt.Fprintf(w, "*2\r\n:%d\r\n$%d\r\n%s\r\n", allowed, len(left), left)
+}
+
+// record notes that command was received.
+func (f *fakeRedis) record(command string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.commands = append(f.commands, command)
+}
+
+// received returns the commands received so far.
+func (f *fakeRedis) received() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.commands...)
+}
+
+func TestRedisLimiter_Allow(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	server := newFakeRedis(t, "secret")
+	limiter := NewRedisLimiter(RedisConfig{Config: redis.Config{Addr: server.addr, Password: "secret", DB: 2}})
+	t.Cleanup(func() { _ = limiter.Close() })
+	limit := Limit{Requests: 1, Period: time.Second, Burst: 2}
+
+	// Act
+	var results []Result
+	for range 3 {
+		result, err := limiter.Allow(context.Background(), "client", limit)
+		if err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		results = append(results, result)
+	}
+
+	// Assert
+	for i, want := range []bool{true, true, false} {
+		if results[i].Allowed != want {
+			t.Errorf("request %d: Allowed = %v, want %v", i+1, results[i].Allowed, want)
+		}
+	}
+	if results[0].Remaining != 1 || results[1].Remaining != 0 {
+		t.Errorf("Remaining = %d, %d, want 1, 0", results[0].Remaining, results[1].Remaining)
+	}
+	if results[2].RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %v, want 1s", results[2].RetryAfter)
+	}
+	want := []string{"AUTH", "SELECT", "EVALSHA", "EVAL", "EVALSHA", "EVALSHA"}
+	if got := server.received(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("commands = %v, want %v (one pooled connection)", got, want)
+	}
+}
+
+func TestRedisLimiter_Allow_Errors(t *testing.T) {
+	t.Parallel()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	closedAddr := closed.Addr().String()
+	_ = closed.Close()
+
+	tests := []struct {
+		name   string
+		config func(t *testing.T) RedisConfig
+	}{
+		{
+			name: "server unreachable",
+			config: func(_ *testing.T) RedisConfig {
+				return RedisConfig{Config: redis.Config{Addr: closedAddr, Timeout: 100 * time.Millisecond}}
+			},
+		},
+		{
+			name: "wrong password",
+			config: func(t *testing.T) RedisConfig {
+				return RedisConfig{Config: redis.Config{Addr: newFakeRedis(t, "secret").addr, Password: "wrong"}}
+			},
+		},
+		{
+			name: "password required",
+			config: func(t *testing.T) RedisConfig {
+				return RedisConfig{Config: redis.Config{Addr: newFakeRedis(t, "secret").addr}}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			limiter := NewRedisLimiter(tt.config(t))
+			t.Cleanup(func() { _ = limiter.Close() })
+
+			// Act
+			_, err := limiter.Allow(context.Background(), "client", Limit{Requests: 1, Period: time.Second, Burst: 1})
+
+			// Assert
+			if !errors.Is(err, ErrRedis) {
+				t.Errorf("Allow() error = %v, want %v", err, ErrRedis)
+			}
+		})
+	}
+}
+
+func TestRedisLimiter_Closed(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	server := newFakeRedis(t, "")
+	limiter := NewRedisLimiter(RedisConfig{Config: redis.Config{Addr: server.addr}})
+	limit := Limit{Requests: 1, Period: time.Second, Burst: 1}
+	if _, err := limiter.Allow(context.Background(), "client", limit); err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+
+	// Act
+	closeErr := limiter.Close()
+	_, err := limiter.Allow(context.Background(), "client", limit)
+
+	// Assert
+	if closeErr != nil {
+		t.Errorf("Close() error = %v", closeErr)
+	}
+	if !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Allow() after Close() error = %v, want %v", err, net.ErrClosed)
+	}
+}
@@ -0,0 +1,267 @@
+// Package redis provides a minimal client for Redis, or any server speaking
+// its protocol, with a pool of connections. It backs the shared state of
+// replicas, such as rate limit buckets and idempotency records.
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client defaults.
+const (
+	defaultPoolSize = 10
+	defaultTimeout  = time.Second
+	// maxBulk bounds the size of bulk string replies, such as stored
+	// responses.
+	maxBulk = 4 << 20
+)
+
+// Config configures a Client.
+type Config struct {
+	// Addr is the host:port of the Redis server.
+	Addr     string
+	Password string
+	DB       int
+	// PoolSize is the number of idle connections kept. It defaults to 10.
+	PoolSize int
+	// Timeout bounds connecting and each command. It defaults to 1s.
+	Timeout time.Duration
+}
+
+// Client sends commands to a Redis server. Connections are opened when
+// needed and kept for reuse.
+type Client struct {
+	config Config
+
+	mu     sync.Mutex
+	idle   []*conn
+	closed bool
+}
+
+// conn is a connection to the Redis server.
+type conn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// Error is an error reply of the Redis server.
+type Error string
+
+// Error implements error.
+func (e Error) Error() string {
+	return string(e)
+}
+
+// NewClient creates a Client.
+func NewClient(config Config) *Client {
+	if config.PoolSize <= 0 {
+		config.PoolSize = defaultPoolSize
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	return &Client{config: config}
+}
+
+// Do sends a command and returns its reply: a string, an int64, nil or a
+// []any of replies. Error replies are returned as Error.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := cn.do(c.deadline(ctx), args...)
+	if err != nil {
+		_ = cn.conn.Close()
+		return nil, err
+	}
+	c.put(cn)
+
+	if replyErr, ok := reply.(Error); ok {
+		return nil, replyErr
+	}
+	return reply, nil
+}
+
+// Close closes the idle connections. Connections in use are closed when
+// they are returned.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	var errs []error
+	for _, cn := range c.idle {
+		errs = append(errs, cn.conn.Close())
+	}
+	c.idle = nil
+
+	return errors.Join(errs...)
+}
+
+// deadline returns when the current command must be done.
+func (c *Client) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(c.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		return d
+	}
+	return deadline
+}
+
+// get returns an idle connection or opens a new one.
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, net.ErrClosed
+	}
+	if n := len(c.idle); n > 0 {
+		cn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return cn, nil
+	}
+	c.mu.Unlock()
+
+	return c.dial(ctx)
+}
+
+// put returns a connection to the pool, closing it if the pool is full or
+// closed.
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed || len(c.idle) >= c.config.PoolSize {
+		_ = cn.conn.Close()
+		return
+	}
+	c.idle = append(c.idle, cn)
+}
+
+// dial opens a connection, authenticates and selects the database.
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	dialer := net.Dialer{Timeout: c.config.Timeout}
+	nc, err := dialer.DialContext(ctx, "tcp", c.config.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", c.config.Addr, err)
+	}
+	cn := &conn{conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+
+	var setup [][]string
+	if c.config.Password != "" {
+		setup = append(setup, []string{"AUTH", c.config.Password})
+	}
+	if c.config.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.config.DB)})
+	}
+	for _, args := range setup {
+		reply, err := cn.do(c.deadline(ctx), args...)
+		if err == nil {
+			if replyErr, ok := reply.(Error); ok {
+				err = replyErr
+			}
+		}
+		if err != nil {
+			_ = nc.Close()
+			return nil, fmt.Errorf("%s: %w", args[0], err)
+		}
+	}
+
+	return cn, nil
+}
+
+// do sends a command and reads its reply before deadline.
+func (cn *conn) do(deadline time.Time, args ...string) (any, error) {
+	if err := cn.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(cn.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(cn.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := cn.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return ReadReply(cn.r)
+}
+
+// ReadReply reads a value of the Redis protocol from r: a string, an
+// int64, an Error, nil or a []any of values. Commands are arrays of
+// strings, so servers read them with ReadReply too.
+func ReadReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+
+	kind, value := line[0], line[1:]
+	switch kind {
+	case '+':
+		return value, nil
+	case '-':
+		return Error(value), nil
+	case ':':
+		return strconv.ParseInt(value, 10, 64)
+	case '$':
+		return readBulk(r, value)
+	case '*':
+		return readArray(r, value)
+	default:
+		return nil, fmt.Errorf("unexpected reply %q", line)
+	}
+}
+
+// readBulk reads a bulk string of the given length.
+func readBulk(r *bufio.Reader, length string) (any, error) {
+	n, err := strconv.Atoi(length)
+	if err != nil || n > maxBulk {
+		return nil, fmt.Errorf("invalid bulk length %q", length)
+	}
+	if n < 0 {
+		return nil, nil
+	}
+
+	buf := make([]byte, n+2)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return string(buf[:n]), nil
+}
+
+// readArray reads an array of the given length.
+func readArray(r *bufio.Reader, length string) (any, error) {
+	n, err := strconv.Atoi(length)
+	if err != nil || n > maxBulk {
+		return nil, fmt.Errorf("invalid array length %q", length)
+	}
+	if n < 0 {
+		return nil, nil
+	}
+
+	values := make([]any, n)
+	for i := range values {
+		if values[i], err = ReadReply(r); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,178 @@
+package redis_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vyrodovalexey/restapi-example/internal/redis"
+	"github.com/vyrodovalexey/restapi-example/internal/redis/redistest"
+)
+
+func TestClient_Do(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	server := redistest.NewServer(t, "secret")
+	client := redis.NewClient(redis.Config{Addr: server.Addr, Password: "secret", DB: 1})
+	t.Cleanup(func() { _ = client.Close() })
+	ctx := context.Background()
+
+	// Act
+	set, setErr := client.Do(ctx, "SET", "key", "value", "NX", "PX", "60000")
+	setAgain, setAgainErr := client.Do(ctx, "SET", "key", "other", "NX")
+	get, getErr := client.Do(ctx, "GET", "key")
+	del, delErr := client.Do(ctx, "DEL", "key")
+	missing, missingErr := client.Do(ctx, "GET", "key")
+	_, unknownErr := client.Do(ctx, "FLUSHALL")
+
+	// Assert
+	for _, err := range []error{setErr, setAgainErr, getErr, delErr, missingErr} {
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+	}
+	if set != "OK" || setAgain != nil {
+		t.Errorf("SET NX replies = %v, %v, want OK, nil", set, setAgain)
+	}
+	if get != "value" {
+		t.Errorf("GET reply = %v, want value", get)
+	}
+	if del != int64(1) || missing != nil {
+		t.Errorf("DEL, GET replies = %v, %v, want 1, nil", del, missing)
+	}
+	var replyErr redis.Error
+	if !errors.As(unknownErr, &replyErr) || !strings.HasPrefix(string(replyErr), "ERR") {
+		t.Errorf("Do() error = %v, want an ERR reply", unknownErr)
+	}
+	want := []string{"SELECT", "SET", "SET", "GET", "DEL", "GET", "FLUSHALL"}
+	if got := server.Commands(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("commands = %v, want %v (one pooled connection)", got, want)
+	}
+}
+
+func TestClient_Do_Errors(t *testing.T) {
+	t.Parallel()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	closedAddr := closed.Addr().String()
+	_ = closed.Close()
+
+	tests := []struct {
+		name    string
+		config  func(t *testing.T) redis.Config
+		wantErr error
+	}{
+		{
+			name: "server unreachable",
+			config: func(_ *testing.T) redis.Config {
+				return redis.Config{Addr: closedAddr, Timeout: 100 * time.Millisecond}
+			},
+		},
+		{
+			name: "wrong password",
+			config: func(t *testing.T) redis.Config {
+				return redis.Config{Addr: redistest.NewServer(t, "secret").Addr, Password: "wrong"}
+			},
+			wantErr: redis.Error("WRONGPASS invalid password"),
+		},
+		{
+			name: "password required",
+			config: func(t *testing.T) redis.Config {
+				return redis.Config{Addr: redistest.NewServer(t, "secret").Addr}
+			},
+			wantErr: redis.Error("NOAUTH Authentication required."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			client := redis.NewClient(tt.config(t))
+			t.Cleanup(func() { _ = client.Close() })
+
+			// Act
+			_, err := client.Do(context.Background(), "GET", "key")
+
+			// Assert
+			if err == nil {
+				t.Fatal("Do() error = nil, want error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Do() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_Closed(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	server := redistest.NewServer(t, "")
+	client := redis.NewClient(redis.Config{Addr: server.Addr})
+	if _, err := client.Do(context.Background(), "PING"); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	// Act
+	closeErr := client.Close()
+	_, err := client.Do(context.Background(), "PING")
+
+	// Assert
+	if closeErr != nil {
+		t.Errorf("Close() error = %v", closeErr)
+	}
+	if !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Do() after Close() error = %v, want %v", err, net.ErrClosed)
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		want    any
+		wantErr bool
+	}{
+		{name: "simple string", input: "+OK\r\n", want: "OK"},
+		{name: "error", input: "-ERR bad\r\n", want: redis.Error("ERR bad")},
+		{name: "integer", input: ":42\r\n", want: int64(42)},
+		{name: "bulk string", input: "$5\r\nhello\r\n", want: "hello"},
+		{name: "nil bulk string", input: "$-1\r\n", want: nil},
+		{name: "array", input: "*2\r\n:1\r\n$1\r\nx\r\n", want: []any{int64(1), "x"}},
+		{name: "empty line", input: "\r\n", wantErr: true},
+		{name: "unknown kind", input: "?1\r\n", wantErr: true},
+		{name: "oversized bulk string", input: "$99999999\r\n", wantErr: true},
+		{name: "truncated bulk string", input: "$5\r\nhel", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			got, err := redis.ReadReply(bufio.NewReader(strings.NewReader(tt.input)))
+
+			// Assert
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadReply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && fmt.Sprintf("%#v", got) != fmt.Sprintf("%#v", tt.want) {
+				t.Errorf("ReadReply() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
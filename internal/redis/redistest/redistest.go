@@ -0,0 +1,191 @@
+// Package redistest provides an in-memory Redis server for tests of Redis
+// backed components.
+package redistest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vyrodovalexey/restapi-example/internal/redis"
+)
+
+// Server is a Redis server understanding AUTH, SELECT, PING, GET, DEL and
+// SET with the NX and PX options. It is stopped when the test ends.
+type Server struct {
+	// Addr is the host:port the server listens on.
+	Addr string
+
+	password string
+
+	mu       sync.Mutex
+	commands []string
+	values   map[string]value
+}
+
+// value is a stored string and when it expires, if ever.
+type value struct {
+	data    string
+	expires time.Time
+}
+
+// NewServer starts a Server requiring password, if any.
+func NewServer(t testing.TB, password string) *Server {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	s := &Server{Addr: listener.Addr().String(), password: password, values: make(map[string]value)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// Commands returns the names of the commands received so far.
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.commands...)
+}
+
+// Get returns the value of key, if it is set and not expired.
+func (s *Server) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.lookup(key)
+	return v.data, ok
+}
+
+// serve answers the commands sent on conn.
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+	authenticated := s.password == ""
+	for {
+		request, err := redis.ReadReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := request.([]any)
+		args := make([]string, 0, len(items))
+		for _, item := range items {
+			arg, _ := item.(string)
+			args = append(args, arg)
+		}
+		if len(args) == 0 {
+			return
+		}
+
+		command := strings.ToUpper(args[0])
+		switch {
+		case command == "AUTH" && len(args) == 2:
+			authenticated = args[1] == s.password
+			if !authenticated {
+				fmt.Fprint(w, "-WRONGPASS invalid password\r\n")
+				break
+			}
+			fmt.Fprint(w, "+OK\r\n")
+		case !authenticated:
+			fmt.Fprint(w, "-NOAUTH Authentication required.\r\n")
+		default:
+			s.do(w, command, args[1:])
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// do runs a data command and writes its reply.
+func (s *Server) do(w *bufio.Writer, command string, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.commands = append(s.commands, command)
+	switch {
+	case command == "SELECT" || command == "PING":
+		fmt.Fprint(w, "+OK\r\n")
+	case command == "GET" && len(args) == 1:
+		if v, ok := s.lookup(args[0]); ok {
+			fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v.data), v.data)
+			return
+		}
+		fmt.Fprint(w, "$-1\r\n")
+	case command == "DEL" && len(args) >= 1:
+		deleted := 0
+		for _, key := range args {
+			if _, ok := s.lookup(key); ok {
+				delete(s.values, key)
+				deleted++
+			}
+		}
+		fmt.Fprintf(w, ":%d\r\n", deleted)
+	case command == "SET" && len(args) >= 2:
+		s.set(w, args)
+	default:
+		fmt.Fprintf(w, "-ERR unknown command '%s'\r\n", command)
+	}
+}
+
+// set runs SET key value [NX] [PX milliseconds].
+func (s *Server) set(w *bufio.Writer, args []string) {
+	v := value{data: args[1]}
+	nx := false
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "PX":
+			if i+1 == len(args) {
+				fmt.Fprint(w, "-ERR syntax error\r\n")
+				return
+			}
+			ms, err := strconv.Atoi(args[i+1])
+			if err != nil || ms <= 0 {
+				fmt.Fprint(w, "-ERR invalid expire time in 'set' command\r\n")
+				return
+			}
+			v.expires = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			i++
+		default:
+			fmt.Fprint(w, "-ERR syntax error\r\n")
+			return
+		}
+	}
+
+	if _, ok := s.lookup(args[0]); ok && nx {
+		fmt.Fprint(w, "$-1\r\n")
+		return
+	}
+	s.values[args[0]] = v
+	fmt.Fprint(w, "+OK\r\n")
+}
+
+// lookup returns the value of key, dropping it if it expired.
+func (s *Server) lookup(key string) (value, bool) {
+	v, ok := s.values[key]
+	if ok && !v.expires.IsZero() && !time.Now().Before(v.expires) {
+		delete(s.values, key)
+		return value{}, false
+	}
+	return v, ok
+}
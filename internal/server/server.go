@@ -4,11 +4,10 @@ package server
 import (
 	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -18,9 +17,14 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/authz"
+	"github.com/vyrodovalexey/restapi-example/internal/certs"
 	"github.com/vyrodovalexey/restapi-example/internal/config"
 	"github.com/vyrodovalexey/restapi-example/internal/handler"
+	"github.com/vyrodovalexey/restapi-example/internal/idempotency"
 	"github.com/vyrodovalexey/restapi-example/internal/middleware"
+	"github.com/vyrodovalexey/restapi-example/internal/ratelimit"
+	"github.com/vyrodovalexey/restapi-example/internal/redis"
 	"github.com/vyrodovalexey/restapi-example/internal/store"
 )
 
@@ -33,9 +37,20 @@ type Server struct {
 	config        *config.Config
 	logger        *zap.Logger
 	wsHandler     *handler.WebSocketHandler
+	gqlHandler    *handler.GraphQLHandler
 	authenticator auth.Authenticator
+	authorizer    *authz.Authorizer
+	rateLimiter   ratelimit.Limiter // limits each client's requests, if enabled
+	rateLimits    map[string]ratelimit.Limit
+	idempotency   idempotency.Store        // replays responses to retried POST requests, if enabled
+	certRotator   *certs.Rotator           // serves the Vault-issued certificate, if enabled
+	certReloader  *certs.FileReloader      // serves the certificate and client CAs from disk, if any
+	revocation    *certs.RevocationChecker // rejects revoked client certificates, if enabled
 	tracer        trace.Tracer
-	initErr       error // deferred error from initialization (e.g. TLS config)
+	initErr       error // deferred error from initialization (e.g. TLS config, authz policy)
+
+	// cors is the current CORS policy, replaced by SetCORSPolicy.
+	cors atomic.Pointer[middleware.CORSPolicy]
 }
 
 // New creates a new Server instance.
@@ -43,7 +58,12 @@ type Server struct {
 // An optional tracer may be supplied; when omitted (or nil) the global OTel
 // tracer is used, which is a no-op when tracing is disabled. Accepting it as a
 // variadic keeps the constructor backward compatible with existing callers.
-// If TLS configuration fails, the error is deferred and returned by Start().
+// Authorization is enabled when the config names a policy file, and rate
+// limiting and idempotency keys when the config enables them. If TLS
+// configuration, loading the policy, parsing the rate limits, creating the
+// CORS policy or compiling the item ID pattern fails, the error is deferred
+// and returned by Start(). When TLS and Vault are both enabled, the server
+// certificate is issued by Vault PKI on Start() and renewed before it expires.
 func New(
 	cfg *config.Config,
 	logger *zap.Logger,
@@ -63,11 +83,22 @@ func New(
 		tracer:        t,
 	}
 
+	authzErr := s.setupAuthorizer()
+	rateLimitErr := s.setupRateLimiter()
+	corsErr := s.setupCORS()
+	s.setupIdempotency()
+	s.setupCertRotator()
+
+	ids, idsErr := store.NewIDValidator(cfg.StoreIDPattern)
+	if idsErr != nil {
+		idsErr = fmt.Errorf("creating item ID validator: %w", idsErr)
+	}
+
 	s.setupMiddleware()
-	s.setupRoutes(itemStore)
+	s.setupRoutes(itemStore, ids)
 	s.setupProbeRoutes(itemStore)
 	s.setupProbeServer()
-	s.initErr = s.setupHTTPServer()
+	s.initErr = errors.Join(authzErr, rateLimitErr, corsErr, idsErr, s.setupHTTPServer())
 
 	return s
 }
@@ -81,23 +112,180 @@ func resolveTracer(tracer []trace.Tracer) trace.Tracer {
 	return otel.Tracer("github.com/vyrodovalexey/restapi-example/internal/server")
 }
 
-// setupMiddleware configures the middleware chain.
-func (s *Server) setupMiddleware() {
-	allowedOrigins := []string{"*"}
-	allowedMethods := []string{
-		http.MethodGet,
-		http.MethodPost,
-		http.MethodPut,
-		http.MethodDelete,
-		http.MethodOptions,
+// setupAuthorizer loads the authorization policy named by the config, if any.
+func (s *Server) setupAuthorizer() error {
+	if s.config.AuthzPolicyFile == "" {
+		return nil
+	}
+
+	policy, err := authz.LoadPolicy(s.config.AuthzPolicyFile)
+	if err != nil {
+		return fmt.Errorf("loading authorization policy: %w", err)
+	}
+
+	s.authorizer, err = authz.NewAuthorizer(policy)
+	if err != nil {
+		return fmt.Errorf("creating authorizer: %w", err)
 	}
-	allowedHeaders := []string{
-		"Content-Type",
-		"Authorization",
-		middleware.RequestIDHeader,
-		"X-API-Key",
+
+	s.logger.Info("authorization enabled", zap.String("policy_file", s.config.AuthzPolicyFile))
+	return nil
+}
+
+// setupRateLimiter creates the rate limiter and the limits of each route
+// class when rate limiting is enabled.
+func (s *Server) setupRateLimiter() error {
+	if !s.config.RateLimitEnabled {
+		return nil
 	}
 
+	limits := make(map[string]ratelimit.Limit, 3)
+	for class, value := range map[string]string{
+		middleware.RateLimitRead:    s.config.RateLimitRead,
+		middleware.RateLimitWrite:   s.config.RateLimitWrite,
+		middleware.RateLimitGraphQL: s.config.RateLimitGraphQL,
+	} {
+		limit, err := config.ParseRateLimit(value)
+		if err != nil {
+			return fmt.Errorf("parsing %s rate limit: %w", class, err)
+		}
+		limits[class] = ratelimit.Limit(limit)
+	}
+	s.rateLimits = limits
+
+	if s.config.RateLimitBackend == "redis" {
+		s.rateLimiter = ratelimit.NewRedisLimiter(ratelimit.RedisConfig{
+			Config: redis.Config{
+				Addr:     s.config.RateLimitRedisAddr,
+				Password: s.config.RateLimitRedisPassword,
+				DB:       s.config.RateLimitRedisDB,
+			},
+		})
+	} else {
+		s.rateLimiter = ratelimit.NewMemoryLimiter()
+	}
+
+	s.logger.Info("rate limiting enabled",
+		zap.String("backend", s.config.RateLimitBackend),
+		zap.String("read", s.config.RateLimitRead),
+		zap.String("write", s.config.RateLimitWrite),
+		zap.String("graphql", s.config.RateLimitGraphQL),
+	)
+	return nil
+}
+
+// setupIdempotency creates the store of idempotency keys when idempotency
+// is enabled.
+func (s *Server) setupIdempotency() {
+	if !s.config.IdempotencyEnabled {
+		return
+	}
+
+	if s.config.IdempotencyBackend == "redis" {
+		s.idempotency = idempotency.NewRedisStore(idempotency.RedisConfig{
+			Config: redis.Config{
+				Addr:     s.config.IdempotencyRedisAddr,
+				Password: s.config.IdempotencyRedisPassword,
+				DB:       s.config.IdempotencyRedisDB,
+			},
+		})
+	} else {
+		s.idempotency = idempotency.NewMemoryStore()
+	}
+
+	s.logger.Info("idempotency keys enabled",
+		zap.String("backend", s.config.IdempotencyBackend),
+		zap.Duration("ttl", s.config.IdempotencyTTL),
+	)
+}
+
+// setupCertRotator creates the rotator for the Vault-issued server
+// certificate when TLS and Vault are both enabled.
+func (s *Server) setupCertRotator() {
+	if !s.config.TLSEnabled || !s.config.VaultEnabled {
+		return
+	}
+
+	issuer := certs.NewVaultIssuer(certs.VaultConfig{
+		Addr:       s.config.VaultAddr,
+		Token:      s.config.VaultToken,
+		PKIPath:    s.config.VaultPKIPath,
+		Role:       s.config.VaultPKIRole,
+		CommonName: s.config.VaultPKICommonName,
+		AltNames:   config.SplitList(s.config.VaultPKIAltNames),
+		IPSANs:     config.SplitList(s.config.VaultPKIIPSANs),
+		TTL:        s.config.VaultPKITTL,
+	})
+	s.certRotator = certs.NewRotator(issuer, "vault", s.logger)
+}
+
+// SetCORSPolicy applies the CORS settings of cfg to cross-origin requests
+// and WebSocket upgrades. Empty lists fall back to the defaults. On error
+// the current policy is kept. It is safe to call while the server is
+// running.
+func (s *Server) SetCORSPolicy(cfg *config.Config) error {
+	apply, err := s.PrepareCORSPolicy(cfg)
+	if err != nil {
+		return err
+	}
+
+	apply()
+	return nil
+}
+
+// PrepareCORSPolicy creates the CORS policy of cfg like SetCORSPolicy and
+// returns a function that applies it, so that callers can check several
+// settings before applying any.
+func (s *Server) PrepareCORSPolicy(cfg *config.Config) (func(), error) {
+	policy, err := middleware.NewCORSPolicy(middleware.CORSConfig{
+		AllowedOrigins:     listOrDefault(cfg.CORSAllowedOrigins, config.DefaultCORSOrigins),
+		AllowedOriginRegex: cfg.CORSOriginRegex,
+		AllowedMethods:     listOrDefault(cfg.CORSAllowedMethods, config.DefaultCORSMethods),
+		AllowedHeaders:     listOrDefault(cfg.CORSAllowedHeaders, config.DefaultCORSHeaders),
+		ExposedHeaders:     listOrDefault(cfg.CORSExposedHeaders, config.DefaultCORSExposedHeaders),
+		AllowCredentials:   cfg.CORSAllowCredentials,
+		MaxAge:             cfg.CORSMaxAge,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating CORS policy: %w", err)
+	}
+
+	return func() { s.cors.Store(policy) }, nil
+}
+
+// listOrDefault splits list, or def if list is empty.
+func listOrDefault(list, def string) []string {
+	if items := config.SplitList(list); len(items) > 0 {
+		return items
+	}
+	return config.SplitList(def)
+}
+
+// setupCORS applies the configured CORS policy. If it is invalid, no
+// cross-origin requests are allowed and the error is returned.
+func (s *Server) setupCORS() error {
+	err := s.SetCORSPolicy(s.config)
+	if err != nil {
+		denyAll, _ := middleware.NewCORSPolicy(middleware.CORSConfig{})
+		s.cors.Store(denyAll)
+	}
+	return err
+}
+
+// corsMiddleware applies the current CORS policy.
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.cors.Load().Middleware()(next).ServeHTTP(w, r)
+	})
+}
+
+// checkOrigin applies the current CORS policy to WebSocket upgrades.
+func (s *Server) checkOrigin(r *http.Request) bool {
+	return s.cors.Load().CheckOrigin(r)
+}
+
+// setupMiddleware configures the middleware chain.
+func (s *Server) setupMiddleware() {
 	// Apply middleware in order (first applied = outermost)
 	s.router.Use(mux.MiddlewareFunc(middleware.Recovery(s.logger)))
 	s.router.Use(mux.MiddlewareFunc(middleware.RequestID()))
@@ -114,31 +302,68 @@ func (s *Server) setupMiddleware() {
 		s.router.Use(mux.MiddlewareFunc(middleware.Metrics()))
 	}
 
+	// Only the WebSocket routes, which authenticate and authorize clients
+	// in-band, accept upgrades without credentials.
+	inBandRoutes := []string{handler.RouteWebSocket, handler.RouteGraphQLSubscriptions}
+
 	// Add auth middleware if authenticator is provided
 	if s.authenticator != nil {
 		s.router.Use(mux.MiddlewareFunc(
-			middleware.Auth(s.authenticator, s.logger),
+			middleware.Auth(s.authenticator, s.logger, inBandRoutes...),
+		))
+	}
+
+	// Rate limiting keys clients by the identity attached by Auth.
+	if s.rateLimiter != nil {
+		s.router.Use(mux.MiddlewareFunc(
+			middleware.RateLimit(s.rateLimiter, s.rateLimits, s.logger),
+		))
+	}
+
+	// Authorization needs the identity attached by Auth.
+	if s.authorizer != nil {
+		s.router.Use(mux.MiddlewareFunc(
+			middleware.Authorize(s.authorizer, s.logger, inBandRoutes...),
 		))
 	}
 
 	s.router.Use(mux.MiddlewareFunc(middleware.Logging(s.logger)))
-	s.router.Use(mux.MiddlewareFunc(
-		middleware.CORS(allowedOrigins, allowedMethods, allowedHeaders),
-	))
+	s.router.Use(s.corsMiddleware)
+
+	// Idempotency runs last, so that retries are authenticated, authorized
+	// and rate limited like any request, and keys are scoped by Auth.
+	if s.idempotency != nil {
+		s.router.Use(mux.MiddlewareFunc(
+			middleware.Idempotency(s.idempotency, s.config.IdempotencyTTL, s.logger),
+		))
+	}
 }
 
-// setupRoutes configures the API routes.
-func (s *Server) setupRoutes(itemStore store.Store) {
+// setupRoutes configures the API routes. Writes from every API go through an
+// EventStore so WebSocket clients can subscribe to item changes, and the
+// item IDs chosen by clients are checked by ids.
+func (s *Server) setupRoutes(itemStore store.Store, ids *store.IDValidator) {
+	events := store.NewEventStore(itemStore)
+	itemStore = events
+
 	// REST API handler
 	restHandler := handler.NewRESTHandler(itemStore, s.logger)
+	restHandler.SetIDValidator(ids)
 	restHandler.RegisterRoutes(s.router)
 
 	// GraphQL handler
-	graphqlHandler := handler.NewGraphQLHandler(itemStore, s.logger)
-	graphqlHandler.RegisterRoutes(s.router)
+	s.gqlHandler = handler.NewGraphQLHandler(itemStore, s.logger, events)
+	s.gqlHandler.SetAuthenticator(s.authenticator)
+	s.gqlHandler.SetAuthorizer(s.authorizer)
+	s.gqlHandler.SetCheckOrigin(s.checkOrigin)
+	s.gqlHandler.SetIDValidator(ids)
+	s.gqlHandler.RegisterRoutes(s.router)
 
 	// WebSocket handler
-	s.wsHandler = handler.NewWebSocketHandler(s.logger)
+	s.wsHandler = handler.NewWebSocketHandler(s.logger, events)
+	s.wsHandler.SetAuthenticator(s.authenticator)
+	s.wsHandler.SetAuthorizer(s.authorizer)
+	s.wsHandler.SetCheckOrigin(s.checkOrigin)
 	s.wsHandler.RegisterRoutes(s.router)
 
 	// Metrics endpoint
@@ -147,6 +372,19 @@ func (s *Server) setupRoutes(itemStore store.Store) {
 	}
 }
 
+// EnableAPIKeyManagement serves the API key admin API backed by keys. The
+// API is only served when an authorization policy is configured, so that
+// access to it must be granted explicitly by a route rule.
+func (s *Server) EnableAPIKeyManagement(keys handler.APIKeyManager) {
+	if s.authorizer == nil {
+		s.logger.Warn("API key management API disabled: it requires an authorization policy")
+		return
+	}
+
+	handler.NewAPIKeyHandler(keys, s.logger).RegisterRoutes(s.router)
+	s.logger.Info("API key management API enabled")
+}
+
 // setupProbeRoutes configures the probe server routes.
 // The probe server serves health, readiness, and metrics endpoints
 // on a dedicated HTTP port without any authentication middleware.
@@ -206,18 +444,25 @@ func (s *Server) setupHTTPServer() error {
 	return nil
 }
 
-// buildTLSConfig creates a TLS configuration from the server config.
+// buildTLSConfig creates a TLS configuration from the server config. The
+// certificate comes from the rotator when Vault issues it and is otherwise
+// loaded from the configured files. Certificate and CA files are reloaded
+// when they change. Client certificates are checked for revocation when CRL
+// or OCSP checking is configured.
 func (s *Server) buildTLSConfig() (*tls.Config, error) {
-	cert, err := tls.LoadX509KeyPair(
-		s.config.TLSCertPath, s.config.TLSKeyPath,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("loading TLS key pair: %w", err)
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
 	}
 
-	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		MinVersion:   tls.VersionTLS12,
+	files := certs.FileConfig{
+		CAPath:   s.config.TLSCAPath,
+		Interval: s.config.TLSReloadInterval,
+	}
+	if s.certRotator != nil {
+		tlsConfig.GetCertificate = s.certRotator.GetCertificate
+	} else {
+		files.CertPath = s.config.TLSCertPath
+		files.KeyPath = s.config.TLSKeyPath
 	}
 
 	switch s.config.TLSClientAuth {
@@ -229,19 +474,44 @@ func (s *Server) buildTLSConfig() (*tls.Config, error) {
 		tlsConfig.ClientAuth = tls.NoClientCert
 	}
 
-	if s.config.TLSCAPath != "" {
-		caCert, err := os.ReadFile(s.config.TLSCAPath)
-		if err != nil {
-			return nil, fmt.Errorf("reading TLS CA cert: %w", err)
-		}
-		caPool := x509.NewCertPool()
-		if !caPool.AppendCertsFromPEM(caCert) {
-			return nil, fmt.Errorf("parsing TLS CA cert: no valid certificates found in %s", s.config.TLSCAPath)
-		}
-		tlsConfig.ClientCAs = caPool
+	if err := s.setupRevocation(tlsConfig); err != nil {
+		return nil, err
+	}
+
+	if files.CertPath == "" && files.CAPath == "" {
+		return tlsConfig, nil
 	}
 
-	return tlsConfig, nil
+	reloader, err := certs.NewFileReloader(files, tlsConfig, s.logger)
+	if err != nil {
+		return nil, err
+	}
+	s.certReloader = reloader
+
+	return reloader.TLSConfig(), nil
+}
+
+// setupRevocation installs client certificate revocation checking in
+// tlsConfig when it is configured.
+func (s *Server) setupRevocation(tlsConfig *tls.Config) error {
+	if !s.config.RevocationEnabled() {
+		return nil
+	}
+
+	checker, err := certs.NewRevocationChecker(certs.RevocationConfig{
+		CRLPath:    s.config.TLSCRLPath,
+		CRLURL:     s.config.TLSCRLURL,
+		CRLRefresh: s.config.TLSCRLRefresh,
+		OCSP:       s.config.TLSOCSPEnabled,
+		OCSPStrict: s.config.TLSOCSPStrict,
+	}, s.logger)
+	if err != nil {
+		return fmt.Errorf("setting up revocation checking: %w", err)
+	}
+
+	s.revocation = checker
+	tlsConfig.VerifyPeerCertificate = checker.VerifyPeerCertificate
+	return nil
 }
 
 // Start starts the HTTP server. It returns any deferred initialization error
@@ -251,6 +521,18 @@ func (s *Server) Start() error {
 		return fmt.Errorf("server initialization: %w", s.initErr)
 	}
 
+	if s.certRotator != nil {
+		if err := s.certRotator.Start(context.Background()); err != nil {
+			return fmt.Errorf("starting certificate rotation: %w", err)
+		}
+	}
+	if s.certReloader != nil {
+		s.certReloader.Start()
+	}
+	if s.revocation != nil {
+		s.revocation.Start()
+	}
+
 	// Start probe server if configured
 	if s.probeServer != nil {
 		go func() {
@@ -268,9 +550,8 @@ func (s *Server) Start() error {
 			zap.String("address", s.config.Address()),
 			zap.String("client_auth", s.config.TLSClientAuth),
 		)
-		err := s.httpServer.ListenAndServeTLS(
-			s.config.TLSCertPath, s.config.TLSKeyPath,
-		)
+		// The certificate is already part of the TLS config.
+		err := s.httpServer.ListenAndServeTLS("", "")
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			return fmt.Errorf("server listen and serve TLS: %w", err)
 		}
@@ -295,12 +576,35 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	if s.wsHandler != nil {
 		s.wsHandler.CloseAllConnections()
 	}
+	if s.gqlHandler != nil {
+		s.gqlHandler.CloseSubscriptions()
+	}
 
 	// Shutdown HTTP server
 	if err := s.httpServer.Shutdown(ctx); err != nil {
 		return fmt.Errorf("server shutdown: %w", err)
 	}
 
+	if s.certRotator != nil {
+		s.certRotator.Stop()
+	}
+	if s.certReloader != nil {
+		s.certReloader.Stop()
+	}
+	if s.revocation != nil {
+		s.revocation.Stop()
+	}
+	if s.rateLimiter != nil {
+		if err := s.rateLimiter.Close(); err != nil {
+			s.logger.Warn("closing rate limiter failed", zap.Error(err))
+		}
+	}
+	if s.idempotency != nil {
+		if err := s.idempotency.Close(); err != nil {
+			s.logger.Warn("closing idempotency store failed", zap.Error(err))
+		}
+	}
+
 	// Shutdown probe server
 	if s.probeServer != nil {
 		if err := s.probeServer.Shutdown(ctx); err != nil {
@@ -11,6 +11,7 @@ import (
 	"crypto/x509/pkix"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"math/big"
 	"net/http"
 	"net/http/httptest"
@@ -20,11 +21,15 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 
 	"github.com/vyrodovalexey/restapi-example/internal/auth"
 	"github.com/vyrodovalexey/restapi-example/internal/config"
+	"github.com/vyrodovalexey/restapi-example/internal/idempotency"
+	"github.com/vyrodovalexey/restapi-example/internal/middleware"
 	"github.com/vyrodovalexey/restapi-example/internal/model"
+	"github.com/vyrodovalexey/restapi-example/internal/ratelimit"
 	"github.com/vyrodovalexey/restapi-example/internal/store"
 )
 
@@ -524,6 +529,123 @@ func TestServer_CORSPreflight(t *testing.T) {
 	}
 }
 
+func TestServer_SetCORSPolicy(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		ServerPort:         8080,
+		LogLevel:           "info",
+		ShutdownTimeout:    30 * time.Second,
+		CORSAllowedOrigins: "https://app.example.com",
+	}
+	server := New(cfg, zap.NewNop(), store.NewMemoryStore(), nil)
+	allowedOrigin := func(origin string) string {
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.Header.Set("Origin", origin)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	// Act
+	configured := allowedOrigin("https://app.example.com")
+	otherBefore := allowedOrigin("https://admin.example.com")
+	setErr := server.SetCORSPolicy(&config.Config{CORSAllowedOrigins: "https://*.example.com"})
+	invalidErr := server.SetCORSPolicy(&config.Config{CORSAllowedOrigins: "*", CORSAllowCredentials: true})
+	subdomain := allowedOrigin("https://admin.example.com")
+	parent := allowedOrigin("https://example.com")
+
+	// Assert
+	if configured != "https://app.example.com" || otherBefore != "" {
+		t.Errorf("before reload: allowed origins = %q, %q, want only the configured one", configured, otherBefore)
+	}
+	if setErr != nil {
+		t.Fatalf("SetCORSPolicy() error = %v", setErr)
+	}
+	if !errors.Is(invalidErr, middleware.ErrInvalidCORSConfig) {
+		t.Errorf("SetCORSPolicy() error = %v, want %v", invalidErr, middleware.ErrInvalidCORSConfig)
+	}
+	if subdomain != "https://admin.example.com" || parent != "" {
+		t.Errorf("after reload: allowed origins = %q, %q, want only subdomains", subdomain, parent)
+	}
+}
+
+func TestServer_CORSPolicyInvalid(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		ServerPort:      8080,
+		LogLevel:        "info",
+		ShutdownTimeout: 30 * time.Second,
+		CORSOriginRegex: "(",
+	}
+	server := New(cfg, zap.NewNop(), store.NewMemoryStore(), nil)
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rr := httptest.NewRecorder()
+
+	// Act
+	server.router.ServeHTTP(rr, req)
+	err := server.Start()
+
+	// Assert
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want none with an invalid policy", got)
+	}
+	if !errors.Is(err, middleware.ErrInvalidCORSConfig) {
+		t.Errorf("Start() error = %v, want %v", err, middleware.ErrInvalidCORSConfig)
+	}
+}
+
+func TestServer_WebSocketOrigin(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		ServerPort:         8080,
+		LogLevel:           "info",
+		ShutdownTimeout:    30 * time.Second,
+		CORSAllowedOrigins: "https://app.example.com",
+	}
+	server := New(cfg, zap.NewNop(), store.NewMemoryStore(), nil)
+	ts := httptest.NewServer(server.router)
+	t.Cleanup(ts.Close)
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	tests := []struct {
+		name       string
+		path       string
+		origin     string
+		wantStatus int
+	}{
+		{name: "allowed origin", path: "/ws", origin: "https://app.example.com", wantStatus: http.StatusSwitchingProtocols},
+		{name: "no origin", path: "/ws", wantStatus: http.StatusSwitchingProtocols},
+		{name: "same origin", path: "/ws", origin: ts.URL, wantStatus: http.StatusSwitchingProtocols},
+		{name: "other origin", path: "/ws", origin: "https://evil.example", wantStatus: http.StatusForbidden},
+		{name: "graphql other origin", path: "/graphql", origin: "https://evil.example", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+			dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+
+			// Act
+			conn, resp, err := dialer.Dial(wsURL+tt.path, header)
+
+			// Assert
+			if conn != nil {
+				_ = conn.Close()
+			}
+			if resp == nil {
+				t.Fatalf("Dial() error = %v, want a response", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
 func TestServer_RecoveryMiddleware(t *testing.T) {
 	// This test verifies that the recovery middleware is in place
 	// by checking that the server doesn't crash on normal requests
@@ -648,8 +770,11 @@ func TestBuildTLSConfig_ValidCert(t *testing.T) {
 	if tlsConfig == nil {
 		t.Fatal("buildTLSConfig() returned nil")
 	}
-	if len(tlsConfig.Certificates) != 1 {
-		t.Errorf("Certificates count = %d, want 1", len(tlsConfig.Certificates))
+	if tlsConfig.GetCertificate == nil {
+		t.Fatal("GetCertificate should be set to serve the reloadable certificate")
+	}
+	if cert, err := tlsConfig.GetCertificate(&tls.ClientHelloInfo{}); err != nil || cert.Leaf.Subject.CommonName != "test" {
+		t.Errorf("GetCertificate() = %v, %v, want the certificate from %s", cert, err, certPath)
 	}
 	if tlsConfig.MinVersion != tls.VersionTLS12 {
 		t.Errorf("MinVersion = %d, want %d", tlsConfig.MinVersion, tls.VersionTLS12)
@@ -895,6 +1020,58 @@ func TestBuildTLSConfig_InvalidCACert(t *testing.T) {
 	}
 }
 
+func TestBuildTLSConfig_Revocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		crlPath string
+		wantErr string
+	}{
+		{name: "OCSP only"},
+		{name: "missing CRL", crlPath: "/nonexistent/ca.crl", wantErr: "setting up revocation checking"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			dir := t.TempDir()
+			certPath, keyPath := generateTestCert(t, dir)
+			s := &Server{
+				config: &config.Config{
+					TLSEnabled:     true,
+					TLSCertPath:    certPath,
+					TLSKeyPath:     keyPath,
+					TLSCAPath:      generateTestCA(t, dir),
+					TLSClientAuth:  "require",
+					TLSCRLPath:     tt.crlPath,
+					TLSOCSPEnabled: true,
+				},
+				logger: zap.NewNop(),
+			}
+
+			// Act
+			tlsConfig, err := s.buildTLSConfig()
+
+			// Assert
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("buildTLSConfig() error = %v, want to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildTLSConfig() error = %v", err)
+			}
+			if tlsConfig.VerifyPeerCertificate == nil || s.revocation == nil {
+				t.Error("VerifyPeerCertificate should check client certificates for revocation")
+			}
+			forClient, _ := tlsConfig.GetConfigForClient(&tls.ClientHelloInfo{})
+			if forClient.VerifyPeerCertificate == nil {
+				t.Error("the config served to clients should check for revocation")
+			}
+		})
+	}
+}
+
 func TestSetupHTTPServer_WithTLS(t *testing.T) {
 	// Arrange
 	dir := t.TempDir()
@@ -1018,6 +1195,182 @@ func TestServer_Start_WithTLS(t *testing.T) {
 	}
 }
 
+func TestServer_Start_ReloadsTLSCertificate(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	certPath, keyPath := generateTestCert(t, dir)
+
+	cfg := &config.Config{
+		ServerPort:        0,
+		ProbePort:         0,
+		LogLevel:          "info",
+		ShutdownTimeout:   5 * time.Second,
+		TLSEnabled:        true,
+		TLSCertPath:       certPath,
+		TLSKeyPath:        keyPath,
+		TLSClientAuth:     "none",
+		TLSReloadInterval: 10 * time.Millisecond,
+	}
+	server := New(cfg, zap.NewNop(), store.NewMemoryStore(), nil)
+	getCertificate := server.httpServer.TLSConfig.GetCertificate
+	before, err := getCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Start()
+	}()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+		if err := <-errCh; err != nil {
+			t.Errorf("Start() error = %v, want nil", err)
+		}
+	}()
+
+	// Act - replace the files as cert-manager would
+	generateTestCert(t, dir)
+
+	// Assert
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		after, err := getCertificate(&tls.ClientHelloInfo{})
+		if err != nil {
+			t.Fatalf("GetCertificate() error = %v", err)
+		}
+		if string(after.Certificate[0]) != string(before.Certificate[0]) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("certificate was not reloaded after the files changed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// newStubVault serves the certificate and key at the given paths from the
+// PKI issue endpoint of role "web", or fails with status when it is non-zero.
+func newStubVault(t *testing.T, certPath, keyPath string, status int) *httptest.Server {
+	t.Helper()
+
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		t.Fatalf("failed to read cert: %v", err)
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		t.Fatalf("failed to read key: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != 0 || r.URL.Path != "/v1/pki/issue/web" {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]string{"certificate": string(certPEM), "private_key": string(keyPEM)},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// newVaultTLSConfig returns a TLS config whose certificate is issued by vault.
+func newVaultTLSConfig(vault *httptest.Server) *config.Config {
+	return &config.Config{
+		ServerPort:         0,
+		ProbePort:          0,
+		LogLevel:           "info",
+		ShutdownTimeout:    5 * time.Second,
+		TLSEnabled:         true,
+		TLSClientAuth:      "none",
+		VaultEnabled:       true,
+		VaultAddr:          vault.URL,
+		VaultToken:         "token",
+		VaultPKIPath:       "pki",
+		VaultPKIRole:       "web",
+		VaultPKICommonName: "test",
+		VaultPKIAltNames:   "test, localhost,",
+	}
+}
+
+func TestNew_WithVaultPKI(t *testing.T) {
+	// Arrange
+	certPath, keyPath := generateTestCert(t, t.TempDir())
+	cfg := newVaultTLSConfig(newStubVault(t, certPath, keyPath, 0))
+
+	// Act
+	server := New(cfg, zap.NewNop(), store.NewMemoryStore(), nil)
+
+	// Assert
+	if server.initErr != nil {
+		t.Fatalf("initErr = %v, want nil", server.initErr)
+	}
+	if server.certRotator == nil {
+		t.Fatal("certRotator should be set when TLS and Vault are enabled")
+	}
+	tlsConfig := server.httpServer.TLSConfig
+	if len(tlsConfig.Certificates) != 0 {
+		t.Errorf("Certificates count = %d, want 0", len(tlsConfig.Certificates))
+	}
+	if tlsConfig.GetCertificate == nil {
+		t.Error("GetCertificate should be set when Vault issues the certificate")
+	}
+}
+
+func TestServer_Start_WithVaultPKI(t *testing.T) {
+	// Arrange
+	certPath, keyPath := generateTestCert(t, t.TempDir())
+	server := New(newVaultTLSConfig(newStubVault(t, certPath, keyPath, 0)), zap.NewNop(), store.NewMemoryStore(), nil)
+
+	// Act
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Start()
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	cert, certErr := server.httpServer.TLSConfig.GetCertificate(&tls.ClientHelloInfo{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = server.Shutdown(ctx)
+
+	// Assert
+	if err := <-errCh; err != nil {
+		t.Fatalf("Start() error = %v, want nil", err)
+	}
+	if certErr != nil {
+		t.Fatalf("GetCertificate() error = %v", certErr)
+	}
+	if cert.Leaf.Subject.CommonName != "test" {
+		t.Errorf("served certificate CN = %q, want test", cert.Leaf.Subject.CommonName)
+	}
+}
+
+func TestServer_Start_VaultPKIFailure(t *testing.T) {
+	// Arrange
+	certPath, keyPath := generateTestCert(t, t.TempDir())
+	cfg := newVaultTLSConfig(newStubVault(t, certPath, keyPath, http.StatusForbidden))
+	server := New(cfg, zap.NewNop(), store.NewMemoryStore(), nil)
+
+	// Act
+	err := server.Start()
+
+	// Assert
+	if err == nil {
+		t.Fatal("Start() expected error when Vault refuses to issue a certificate")
+	}
+	if !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("error = %v, want to contain 'permission denied'", err)
+	}
+}
+
 func TestNew_WithAuthenticator(t *testing.T) {
 	// Arrange
 	cfg := &config.Config{
@@ -1064,6 +1417,422 @@ func TestNew_WithAuthenticator(t *testing.T) {
 	}
 }
 
+func TestNew_WithAuthenticator_UpgradeHeaderOnRESTRoute(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		ServerPort:      8080,
+		ProbePort:       0,
+		LogLevel:        "info",
+		ShutdownTimeout: 30 * time.Second,
+	}
+	itemStore := store.NewMemoryStore()
+	authenticator := &testAuthenticator{
+		err:    auth.ErrUnauthenticated,
+		method: auth.AuthMethodBasic,
+	}
+	server := New(cfg, zap.NewNop(), itemStore, authenticator)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/items", strings.NewReader(`{"name":"x","price":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	rr := httptest.NewRecorder()
+
+	// Act
+	server.router.ServeHTTP(rr, req)
+
+	// Assert
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d for an upgrade request to a REST route", rr.Code, http.StatusUnauthorized)
+	}
+	page, err := itemStore.List(context.Background(), store.ListOptions{})
+	if err != nil || len(page.Items) != 0 {
+		t.Errorf("List() = %v, %v, want no items created", page, err)
+	}
+}
+
+func TestNew_WithAuthorizationPolicy(t *testing.T) {
+	// Arrange
+	policyPath := filepath.Join(t.TempDir(), "policy.json")
+	policy := `{
+		"bindings": {"api_keys": {"viewer": ["reader"]}},
+		"routes": [{"methods": ["GET"], "path": "/api/v1/items*", "roles": ["reader"]}]
+	}`
+	if err := os.WriteFile(policyPath, []byte(policy), 0o600); err != nil {
+		t.Fatalf("writing policy: %v", err)
+	}
+	cfg := &config.Config{
+		ServerPort:      8080,
+		ProbePort:       0,
+		LogLevel:        "info",
+		ShutdownTimeout: 30 * time.Second,
+		AuthzPolicyFile: policyPath,
+	}
+	authenticator := &testAuthenticator{
+		info:   &auth.AuthInfo{Method: auth.AuthMethodAPIKey, Subject: "viewer"},
+		method: auth.AuthMethodAPIKey,
+	}
+
+	// Act
+	server := New(cfg, zap.NewNop(), store.NewMemoryStore(), authenticator)
+
+	// Assert
+	if server.initErr != nil {
+		t.Fatalf("initErr = %v, want nil", server.initErr)
+	}
+
+	tests := []struct {
+		method     string
+		wantStatus int
+	}{
+		{http.MethodGet, http.StatusOK},
+		{http.MethodPost, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/api/v1/items", strings.NewReader(`{"name":"x","price":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		if rr.Code != tt.wantStatus {
+			t.Errorf("%s /api/v1/items status = %d, want %d", tt.method, rr.Code, tt.wantStatus)
+		}
+	}
+}
+
+func TestNew_WithRateLimit(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		ServerPort:       8080,
+		ProbePort:        0,
+		LogLevel:         "info",
+		ShutdownTimeout:  30 * time.Second,
+		RateLimitEnabled: true,
+		RateLimitBackend: "memory",
+		RateLimitRead:    "2/1h",
+		RateLimitWrite:   "0",
+		RateLimitGraphQL: "0",
+	}
+	authenticator := &testAuthenticator{
+		info:   &auth.AuthInfo{Method: auth.AuthMethodAPIKey, Subject: "viewer"},
+		method: auth.AuthMethodAPIKey,
+	}
+
+	// Act
+	server := New(cfg, zap.NewNop(), store.NewMemoryStore(), authenticator)
+
+	// Assert
+	if server.initErr != nil {
+		t.Fatalf("initErr = %v, want nil", server.initErr)
+	}
+
+	tests := []struct {
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{http.MethodGet, "/api/v1/items", http.StatusOK},
+		{http.MethodGet, "/api/v1/items", http.StatusOK},
+		{http.MethodGet, "/api/v1/items", http.StatusTooManyRequests},
+		{http.MethodPost, "/api/v1/items", http.StatusCreated},
+		{http.MethodGet, "/health", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"name":"x","price":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		if rr.Code != tt.wantStatus {
+			t.Errorf("%s %s status = %d, want %d", tt.method, tt.path, rr.Code, tt.wantStatus)
+		}
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+}
+
+func TestNew_WithInvalidRateLimit(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		ServerPort:       8080,
+		ProbePort:        0,
+		LogLevel:         "info",
+		ShutdownTimeout:  30 * time.Second,
+		RateLimitEnabled: true,
+		RateLimitRead:    "many",
+	}
+
+	// Act
+	server := New(cfg, zap.NewNop(), store.NewMemoryStore(), nil)
+
+	// Assert
+	if !errors.Is(server.initErr, config.ErrInvalidRateLimit) {
+		t.Errorf("initErr = %v, want %v", server.initErr, config.ErrInvalidRateLimit)
+	}
+}
+
+func TestNew_WithRedisRateLimit(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		ServerPort:         8080,
+		ProbePort:          0,
+		LogLevel:           "info",
+		ShutdownTimeout:    30 * time.Second,
+		RateLimitEnabled:   true,
+		RateLimitBackend:   "redis",
+		RateLimitRedisAddr: "127.0.0.1:6379",
+	}
+
+	// Act
+	server := New(cfg, zap.NewNop(), store.NewMemoryStore(), nil)
+
+	// Assert
+	if server.initErr != nil {
+		t.Fatalf("initErr = %v, want nil", server.initErr)
+	}
+	if _, ok := server.rateLimiter.(*ratelimit.RedisLimiter); !ok {
+		t.Errorf("rateLimiter = %T, want *ratelimit.RedisLimiter", server.rateLimiter)
+	}
+}
+
+func TestNew_WithIdempotency(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		ServerPort:         8080,
+		ProbePort:          0,
+		LogLevel:           "info",
+		ShutdownTimeout:    30 * time.Second,
+		IdempotencyEnabled: true,
+		IdempotencyBackend: "memory",
+		IdempotencyTTL:     time.Hour,
+	}
+	itemStore := store.NewMemoryStore()
+	server := New(cfg, zap.NewNop(), itemStore, nil)
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/items", strings.NewReader(`{"name":"x","price":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.IdempotencyKeyHeader, "create-x")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Act
+	first := post()
+	retry := post()
+
+	// Assert
+	if first.Code != http.StatusCreated || retry.Code != http.StatusCreated {
+		t.Fatalf("statuses = %d, %d, want %d", first.Code, retry.Code, http.StatusCreated)
+	}
+	if retry.Body.String() != first.Body.String() {
+		t.Errorf("retry body = %q, want %q", retry.Body.String(), first.Body.String())
+	}
+	if got := retry.Header().Get(middleware.IdempotencyReplayedHeader); got != "true" {
+		t.Errorf("Idempotency-Replayed = %q, want true", got)
+	}
+	page, err := itemStore.List(context.Background(), store.ListOptions{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(page.Items) != 1 {
+		t.Errorf("items = %d, want 1", len(page.Items))
+	}
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+}
+
+func TestNew_WithRedisIdempotency(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		ServerPort:           8080,
+		ProbePort:            0,
+		LogLevel:             "info",
+		ShutdownTimeout:      30 * time.Second,
+		IdempotencyEnabled:   true,
+		IdempotencyBackend:   "redis",
+		IdempotencyRedisAddr: "127.0.0.1:6379",
+	}
+
+	// Act
+	server := New(cfg, zap.NewNop(), store.NewMemoryStore(), nil)
+
+	// Assert
+	if server.initErr != nil {
+		t.Fatalf("initErr = %v, want nil", server.initErr)
+	}
+	if _, ok := server.idempotency.(*idempotency.RedisStore); !ok {
+		t.Errorf("idempotency = %T, want *idempotency.RedisStore", server.idempotency)
+	}
+}
+
+func TestNew_WithStoreIDPattern(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		ServerPort:      8080,
+		ProbePort:       0,
+		LogLevel:        "info",
+		ShutdownTimeout: 30 * time.Second,
+		StoreIDPattern:  `sku-[0-9]+`,
+	}
+	server := New(cfg, zap.NewNop(), store.NewMemoryStore(), nil)
+	put := func(id string) int {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/items/"+id+"?upsert=true",
+			strings.NewReader(`{"name":"x","price":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Act
+	matching := put("sku-1")
+	other := put("item-1")
+
+	// Assert
+	if server.initErr != nil {
+		t.Fatalf("initErr = %v, want nil", server.initErr)
+	}
+	if matching != http.StatusCreated || other != http.StatusBadRequest {
+		t.Errorf("statuses = %d, %d, want %d, %d", matching, other, http.StatusCreated, http.StatusBadRequest)
+	}
+}
+
+func TestNew_WithInvalidStoreIDPattern(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		ServerPort:      8080,
+		ProbePort:       0,
+		LogLevel:        "info",
+		ShutdownTimeout: 30 * time.Second,
+		StoreIDPattern:  "(",
+	}
+
+	// Act
+	server := New(cfg, zap.NewNop(), store.NewMemoryStore(), nil)
+
+	// Assert
+	if server.initErr == nil || !strings.Contains(server.initErr.Error(), "item ID validator") {
+		t.Errorf("initErr = %v, want an item ID validator error", server.initErr)
+	}
+}
+
+func TestNew_WithInvalidAuthorizationPolicy(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		ServerPort:      8080,
+		ProbePort:       0,
+		LogLevel:        "info",
+		ShutdownTimeout: 30 * time.Second,
+		AuthzPolicyFile: filepath.Join(t.TempDir(), "missing.json"),
+	}
+
+	// Act
+	server := New(cfg, zap.NewNop(), store.NewMemoryStore(), &testAuthenticator{method: auth.AuthMethodAPIKey})
+
+	// Assert
+	if server.initErr == nil {
+		t.Fatal("initErr should be set when the authorization policy cannot be loaded")
+	}
+	if !strings.Contains(server.initErr.Error(), "loading authorization policy") {
+		t.Errorf("initErr = %v, want to contain 'loading authorization policy'", server.initErr)
+	}
+}
+
+func TestServer_EnableAPIKeyManagement(t *testing.T) {
+	policyPath := filepath.Join(t.TempDir(), "policy.json")
+	policy := `{
+		"bindings": {"api_keys": {"ops": ["admin"]}},
+		"routes": [{"path": "/api/v1/admin/*", "roles": ["admin"]}]
+	}`
+	if err := os.WriteFile(policyPath, []byte(policy), 0o600); err != nil {
+		t.Fatalf("writing policy: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		policyFile string
+		wantStatus int
+	}{
+		{name: "with authorization policy", policyFile: policyPath, wantStatus: http.StatusOK},
+		{name: "without authorization policy", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			cfg := &config.Config{
+				ServerPort:      8080,
+				ProbePort:       0,
+				LogLevel:        "info",
+				ShutdownTimeout: 30 * time.Second,
+				AuthzPolicyFile: tt.policyFile,
+			}
+			authenticator := &testAuthenticator{
+				info:   &auth.AuthInfo{Method: auth.AuthMethodAPIKey, Subject: "ops"},
+				method: auth.AuthMethodAPIKey,
+			}
+			keys, err := auth.NewAPIKeyStoreAuthenticator("", auth.NewMemoryAPIKeyStore())
+			if err != nil {
+				t.Fatalf("NewAPIKeyStoreAuthenticator() error = %v", err)
+			}
+			server := New(cfg, zap.NewNop(), store.NewMemoryStore(), authenticator)
+
+			// Act
+			server.EnableAPIKeyManagement(keys)
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/api-keys", nil)
+			rr := httptest.NewRecorder()
+			server.router.ServeHTTP(rr, req)
+
+			// Assert
+			if rr.Code != tt.wantStatus {
+				t.Errorf("GET /api/v1/admin/api-keys status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestServer_APIKeyManagement_RejectsUnauthenticatedUpgrade(t *testing.T) {
+	// Arrange
+	policyPath := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(policyPath, []byte(`{"routes":[{"path":"*","roles":["admin"]}]}`), 0o600); err != nil {
+		t.Fatalf("writing policy: %v", err)
+	}
+	cfg := &config.Config{
+		ServerPort:      8080,
+		ProbePort:       0,
+		LogLevel:        "info",
+		ShutdownTimeout: 30 * time.Second,
+		AuthzPolicyFile: policyPath,
+	}
+	authenticator := &testAuthenticator{
+		err:    auth.ErrUnauthenticated,
+		method: auth.AuthMethodAPIKey,
+	}
+	keys, err := auth.NewAPIKeyStoreAuthenticator("", auth.NewMemoryAPIKeyStore())
+	if err != nil {
+		t.Fatalf("NewAPIKeyStoreAuthenticator() error = %v", err)
+	}
+	server := New(cfg, zap.NewNop(), store.NewMemoryStore(), authenticator)
+	server.EnableAPIKeyManagement(keys)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/api-keys",
+		strings.NewReader(`{"subject":"intruder","roles":["admin"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	rr := httptest.NewRecorder()
+
+	// Act
+	server.router.ServeHTTP(rr, req)
+
+	// Assert
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("POST /api/v1/admin/api-keys status = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+}
+
 func TestNew_WithProbeServer(t *testing.T) {
 	// Arrange
 	cfg := &config.Config{
@@ -0,0 +1,171 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+)
+
+// Batch errors.
+var (
+	ErrInvalidBatchOp = errors.New("invalid batch operation")
+
+	// ErrBatchAborted is reported for operations of an atomic batch that
+	// were rolled back or never attempted because another operation failed.
+	ErrBatchAborted = errors.New("batch aborted")
+)
+
+// BatchOpType identifies the kind of a batch operation.
+type BatchOpType string
+
+// Supported batch operation types.
+const (
+	BatchCreate BatchOpType = "create"
+	BatchUpdate BatchOpType = "update"
+	BatchDelete BatchOpType = "delete"
+)
+
+// BatchOp is a single create, update or delete within a batch.
+type BatchOp struct {
+	Type BatchOpType
+
+	// ID identifies the item to update or delete; ignored for create,
+	// which keeps the ID of Item if it has one.
+	ID string
+
+	// Item holds the new field values for create and update.
+	Item *model.Item
+
+	// ExpectedVersion makes an update or delete conditional, as for
+	// Store.Update and Store.Delete (0 = unconditional).
+	ExpectedVersion int64
+}
+
+// BatchResult is the outcome of one BatchOp.
+type BatchResult struct {
+	// Item is the created or updated item; nil for delete and on failure.
+	Item *model.Item
+
+	// Err is the operation error, or nil on success.
+	Err error
+}
+
+// batchTx is the read/write view of a store used to apply batch operations.
+// Every operation either fails before writing or writes exactly once, so a
+// failed operation never leaves partial changes behind.
+type batchTx interface {
+	get(id string) (*model.Item, error)
+	put(item *model.Item) error
+	remove(id string) error
+}
+
+// applyBatch runs ops against tx. In atomic mode it stops at the first
+// failure and reports every other operation as ErrBatchAborted; the caller
+// must then discard the writes made through tx. It reports whether any
+// operation failed.
+func applyBatch(tx batchTx, ops []BatchOp, atomic bool) ([]BatchResult, bool) {
+	results := make([]BatchResult, len(ops))
+	failed := false
+
+	for i := range ops {
+		item, err := applyBatchOp(tx, &ops[i])
+		results[i] = BatchResult{Item: item, Err: err}
+		if err == nil {
+			continue
+		}
+
+		failed = true
+		if atomic {
+			abortBatch(results, i)
+			break
+		}
+	}
+
+	return results, failed
+}
+
+// abortBatch marks every result except the failed one as ErrBatchAborted.
+func abortBatch(results []BatchResult, failedIndex int) {
+	for i := range results {
+		if i != failedIndex {
+			results[i] = BatchResult{Err: ErrBatchAborted}
+		}
+	}
+}
+
+// applyBatchOp applies a single operation through tx with the same
+// semantics as the corresponding Store method.
+func applyBatchOp(tx batchTx, op *BatchOp) (*model.Item, error) {
+	switch op.Type {
+	case BatchCreate:
+		if op.Item == nil {
+			return nil, ErrNilItem
+		}
+		now := time.Now().UTC()
+		newItem := model.Item{
+			ID:          itemID(op.Item),
+			Name:        op.Item.Name,
+			Description: op.Item.Description,
+			Price:       op.Item.Price,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			Version:     1,
+		}
+		if _, err := tx.get(newItem.ID); !errors.Is(err, ErrNotFound) {
+			if err == nil {
+				err = ErrAlreadyExists
+			}
+			return nil, err
+		}
+		if err := tx.put(&newItem); err != nil {
+			return nil, err
+		}
+		return &newItem, nil
+
+	case BatchUpdate:
+		if op.ID == "" {
+			return nil, ErrInvalidID
+		}
+		if op.Item == nil {
+			return nil, ErrNilItem
+		}
+		existing, err := tx.get(op.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkVersion(existing.Version, op.ExpectedVersion); err != nil {
+			return nil, err
+		}
+		updatedItem := model.Item{
+			ID:          op.ID,
+			Name:        op.Item.Name,
+			Description: op.Item.Description,
+			Price:       op.Item.Price,
+			CreatedAt:   existing.CreatedAt,
+			UpdatedAt:   time.Now().UTC(),
+			Version:     existing.Version + 1,
+		}
+		if err := tx.put(&updatedItem); err != nil {
+			return nil, err
+		}
+		return &updatedItem, nil
+
+	case BatchDelete:
+		if op.ID == "" {
+			return nil, ErrInvalidID
+		}
+		existing, err := tx.get(op.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkVersion(existing.Version, op.ExpectedVersion); err != nil {
+			return nil, err
+		}
+		return nil, tx.remove(op.ID)
+
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidBatchOp, op.Type)
+	}
+}
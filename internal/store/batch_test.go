@@ -0,0 +1,184 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+)
+
+// batchStores returns fresh instances of the stores whose Batch shares the
+// applyBatch implementation.
+func batchStores(t *testing.T) map[string]Store {
+	t.Helper()
+
+	fileStore, _ := newTestFileStore(t)
+	return map[string]Store{
+		"memory": NewMemoryStore(),
+		"file":   fileStore,
+	}
+}
+
+func TestStore_Batch(t *testing.T) {
+	tests := []struct {
+		name        string
+		atomic      bool
+		ops         func(existing *model.Item) []BatchOp
+		wantErrs    []error
+		wantVersion int64 // version of the existing item afterwards, 0 = deleted
+		wantCount   int
+	}{
+		{
+			name:   "atomic success",
+			atomic: true,
+			ops: func(existing *model.Item) []BatchOp {
+				return []BatchOp{
+					{Type: BatchCreate, Item: &model.Item{Name: "New", Price: 1}},
+					{Type: BatchUpdate, ID: existing.ID, Item: &model.Item{Name: "Renamed", Price: 2}, ExpectedVersion: 1},
+				}
+			},
+			wantErrs:    []error{nil, nil},
+			wantVersion: 2,
+			wantCount:   2,
+		},
+		{
+			name:   "atomic failure rolls back",
+			atomic: true,
+			ops: func(existing *model.Item) []BatchOp {
+				return []BatchOp{
+					{Type: BatchCreate, Item: &model.Item{Name: "New", Price: 1}},
+					{Type: BatchUpdate, ID: existing.ID, Item: &model.Item{Name: "Renamed", Price: 2}},
+					{Type: BatchDelete, ID: "missing"},
+					{Type: BatchDelete, ID: existing.ID},
+				}
+			},
+			wantErrs:    []error{ErrBatchAborted, ErrBatchAborted, ErrNotFound, ErrBatchAborted},
+			wantVersion: 1,
+			wantCount:   1,
+		},
+		{
+			name:   "create with client IDs",
+			atomic: false,
+			ops: func(existing *model.Item) []BatchOp {
+				return []BatchOp{
+					{Type: BatchCreate, Item: &model.Item{ID: "sku-1", Name: "New", Price: 1}},
+					{Type: BatchCreate, Item: &model.Item{ID: "sku-1", Name: "Again", Price: 1}},
+					{Type: BatchCreate, Item: &model.Item{ID: existing.ID, Name: "Taken", Price: 1}},
+				}
+			},
+			wantErrs:    []error{nil, ErrAlreadyExists, ErrAlreadyExists},
+			wantVersion: 1,
+			wantCount:   2,
+		},
+		{
+			name:   "best effort applies what it can",
+			atomic: false,
+			ops: func(existing *model.Item) []BatchOp {
+				return []BatchOp{
+					{Type: BatchCreate, Item: &model.Item{Name: "New", Price: 1}},
+					{Type: BatchDelete, ID: existing.ID, ExpectedVersion: 7},
+					{Type: BatchUpdate, ID: existing.ID, Item: &model.Item{Name: "Renamed", Price: 2}},
+					{Type: "rename", ID: existing.ID},
+				}
+			},
+			wantErrs:    []error{nil, ErrVersionConflict, nil, ErrInvalidBatchOp},
+			wantVersion: 2,
+			wantCount:   2,
+		},
+		{
+			name:   "later operations see earlier writes",
+			atomic: true,
+			ops: func(existing *model.Item) []BatchOp {
+				return []BatchOp{
+					{Type: BatchUpdate, ID: existing.ID, Item: &model.Item{Name: "A", Price: 1}, ExpectedVersion: 1},
+					{Type: BatchUpdate, ID: existing.ID, Item: &model.Item{Name: "B", Price: 1}, ExpectedVersion: 2},
+					{Type: BatchDelete, ID: existing.ID, ExpectedVersion: 3},
+				}
+			},
+			wantErrs:    []error{nil, nil, nil},
+			wantVersion: 0,
+			wantCount:   0,
+		},
+		{
+			name:   "invalid operations",
+			atomic: false,
+			ops: func(*model.Item) []BatchOp {
+				return []BatchOp{
+					{Type: BatchCreate},
+					{Type: BatchUpdate, Item: &model.Item{Name: "X"}},
+					{Type: BatchDelete},
+				}
+			},
+			wantErrs:    []error{ErrNilItem, ErrInvalidID, ErrInvalidID},
+			wantVersion: 1,
+			wantCount:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		for storeName, s := range batchStores(t) {
+			t.Run(tt.name+"/"+storeName, func(t *testing.T) {
+				// Arrange
+				ctx := context.Background()
+				existing, err := s.Create(ctx, &model.Item{Name: "Existing", Price: 5})
+				if err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+
+				// Act
+				results, err := s.Batch(ctx, tt.ops(existing), tt.atomic)
+
+				// Assert
+				if err != nil {
+					t.Fatalf("Batch() error = %v", err)
+				}
+				if len(results) != len(tt.wantErrs) {
+					t.Fatalf("Batch() returned %d results, want %d", len(results), len(tt.wantErrs))
+				}
+				for i, want := range tt.wantErrs {
+					if !errors.Is(results[i].Err, want) {
+						t.Errorf("results[%d].Err = %v, want %v", i, results[i].Err, want)
+					}
+					if want != nil && results[i].Item != nil {
+						t.Errorf("results[%d].Item = %+v, want nil on failure", i, results[i].Item)
+					}
+				}
+
+				stored, err := s.Get(ctx, existing.ID)
+				switch {
+				case tt.wantVersion == 0 && !errors.Is(err, ErrNotFound):
+					t.Errorf("Get() error = %v, want %v", err, ErrNotFound)
+				case tt.wantVersion != 0 && (err != nil || stored.Version != tt.wantVersion):
+					t.Errorf("Get() = %+v, %v; want version %d", stored, err, tt.wantVersion)
+				}
+
+				page, err := s.List(ctx, ListOptions{})
+				if err != nil {
+					t.Fatalf("List() error = %v", err)
+				}
+				if page.Total != tt.wantCount {
+					t.Errorf("item count = %d, want %d", page.Total, tt.wantCount)
+				}
+			})
+		}
+	}
+}
+
+func TestStore_Batch_ContextCancellation(t *testing.T) {
+	for storeName, s := range batchStores(t) {
+		t.Run(storeName, func(t *testing.T) {
+			// Arrange
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			// Act
+			_, err := s.Batch(ctx, []BatchOp{{Type: BatchCreate, Item: &model.Item{Name: "X"}}}, true)
+
+			// Assert
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Batch() error = %v, want %v", err, context.Canceled)
+			}
+		})
+	}
+}
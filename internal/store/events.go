@@ -0,0 +1,169 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+)
+
+// ItemEventType identifies the kind of change an ItemEvent describes.
+type ItemEventType string
+
+// Item event types.
+const (
+	ItemCreated ItemEventType = "created"
+	ItemUpdated ItemEventType = "updated"
+	ItemDeleted ItemEventType = "deleted"
+)
+
+// ItemEvent describes a committed change to an item.
+type ItemEvent struct {
+	Type   ItemEventType
+	ItemID string
+
+	// Item is the item after the change; nil for ItemDeleted. It is shared
+	// by all subscribers and must not be modified.
+	Item *model.Item
+
+	Time time.Time
+}
+
+// EventStore decorates a Store and publishes an ItemEvent to every subscriber
+// after each successful write. Publishing never blocks writers: a subscriber
+// whose buffer is full misses the event, which is counted in
+// item_events_dropped_total.
+type EventStore struct {
+	delegate Store
+
+	mu          sync.RWMutex
+	nextID      int
+	subscribers map[int]chan ItemEvent
+}
+
+// NewEventStore wraps the given Store with change event publishing.
+func NewEventStore(delegate Store) *EventStore {
+	return &EventStore{
+		delegate:    delegate,
+		subscribers: make(map[int]chan ItemEvent),
+	}
+}
+
+// Subscribe registers a subscriber with the given channel buffer size. The
+// returned function unsubscribes and closes the channel; it is safe to call
+// more than once.
+func (s *EventStore) Subscribe(buffer int) (<-chan ItemEvent, func()) {
+	ch := make(chan ItemEvent, buffer)
+
+	s.mu.Lock()
+	id := s.nextID
+	s.nextID++
+	s.subscribers[id] = ch
+	s.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			delete(s.subscribers, id)
+			s.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// publish delivers an event to every subscriber without blocking.
+func (s *EventStore) publish(eventType ItemEventType, id string, item *model.Item) {
+	event := ItemEvent{Type: eventType, ItemID: id, Item: item, Time: time.Now().UTC()}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+			observability.ItemEventsDroppedTotal.Inc()
+		}
+	}
+}
+
+// List returns a page of items from the underlying store.
+func (s *EventStore) List(ctx context.Context, opts ListOptions) (*ListPage, error) {
+	return s.delegate.List(ctx, opts)
+}
+
+// Get retrieves an item by ID from the underlying store.
+func (s *EventStore) Get(ctx context.Context, id string) (*model.Item, error) {
+	return s.delegate.Get(ctx, id)
+}
+
+// Create adds an item and publishes an ItemCreated event.
+func (s *EventStore) Create(ctx context.Context, item *model.Item) (*model.Item, error) {
+	created, err := s.delegate.Create(ctx, item)
+	if err == nil {
+		s.publish(ItemCreated, created.ID, created)
+	}
+	return created, err
+}
+
+// Update modifies an item and publishes an ItemUpdated event.
+func (s *EventStore) Update(
+	ctx context.Context, id string, item *model.Item, expectedVersion int64,
+) (*model.Item, error) {
+	updated, err := s.delegate.Update(ctx, id, item, expectedVersion)
+	if err == nil {
+		s.publish(ItemUpdated, updated.ID, updated)
+	}
+	return updated, err
+}
+
+// Upsert updates or creates an item and publishes an ItemUpdated or
+// ItemCreated event.
+func (s *EventStore) Upsert(ctx context.Context, id string, item *model.Item) (*model.Item, bool, error) {
+	upserted, created, err := s.delegate.Upsert(ctx, id, item)
+	if err == nil {
+		eventType := ItemUpdated
+		if created {
+			eventType = ItemCreated
+		}
+		s.publish(eventType, upserted.ID, upserted)
+	}
+	return upserted, created, err
+}
+
+// Delete removes an item and publishes an ItemDeleted event.
+func (s *EventStore) Delete(ctx context.Context, id string, expectedVersion int64) error {
+	err := s.delegate.Delete(ctx, id, expectedVersion)
+	if err == nil {
+		s.publish(ItemDeleted, id, nil)
+	}
+	return err
+}
+
+// Batch applies a batch of operations and publishes an event for every
+// operation that took effect.
+func (s *EventStore) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	results, err := s.delegate.Batch(ctx, ops, atomic)
+	if err != nil {
+		return results, err
+	}
+
+	for i, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		switch ops[i].Type {
+		case BatchCreate:
+			s.publish(ItemCreated, result.Item.ID, result.Item)
+		case BatchUpdate:
+			s.publish(ItemUpdated, result.Item.ID, result.Item)
+		case BatchDelete:
+			s.publish(ItemDeleted, ops[i].ID, nil)
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+	"github.com/vyrodovalexey/restapi-example/internal/observability"
+)
+
+// receiveEvent returns the next buffered event or fails the test.
+func receiveEvent(t *testing.T, events <-chan ItemEvent) ItemEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	default:
+		t.Fatal("expected an event, got none")
+		return ItemEvent{}
+	}
+}
+
+func TestEventStore_PublishesWrites(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	s := NewEventStore(NewMemoryStore())
+	events, unsubscribe := s.Subscribe(10)
+	defer unsubscribe()
+
+	// Act
+	created, err := s.Create(ctx, &model.Item{Name: "Widget", Price: 1})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := s.Update(ctx, created.ID, &model.Item{Name: "Gadget", Price: 2}, 0); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := s.Delete(ctx, created.ID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	// Assert
+	wantTypes := []ItemEventType{ItemCreated, ItemUpdated, ItemDeleted}
+	for _, want := range wantTypes {
+		event := receiveEvent(t, events)
+		if event.Type != want || event.ItemID != created.ID || event.Time.IsZero() {
+			t.Errorf("event = %+v, want %s of %s", event, want, created.ID)
+		}
+		if (event.Item == nil) != (want == ItemDeleted) {
+			t.Errorf("event %s Item = %v", want, event.Item)
+		}
+	}
+}
+
+func TestEventStore_Upsert(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	s := NewEventStore(NewMemoryStore())
+	events, unsubscribe := s.Subscribe(10)
+	defer unsubscribe()
+
+	// Act
+	for range 2 {
+		if _, _, err := s.Upsert(ctx, "sku-1", &model.Item{Name: "Widget", Price: 1}); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+	}
+
+	// Assert
+	for _, want := range []ItemEventType{ItemCreated, ItemUpdated} {
+		event := receiveEvent(t, events)
+		if event.Type != want || event.ItemID != "sku-1" || event.Item == nil {
+			t.Errorf("event = %+v, want %s of sku-1", event, want)
+		}
+	}
+}
+
+func TestEventStore_FailedWritesPublishNothing(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	s := NewEventStore(NewMemoryStore())
+	events, unsubscribe := s.Subscribe(10)
+	defer unsubscribe()
+
+	// Act
+	_, _ = s.Update(ctx, "missing", &model.Item{Name: "X"}, 0)
+	_ = s.Delete(ctx, "missing", 0)
+	_, _ = s.Create(ctx, nil)
+
+	// Assert
+	if len(events) != 0 {
+		t.Errorf("got %d events, want 0", len(events))
+	}
+}
+
+func TestEventStore_Batch(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	s := NewEventStore(NewMemoryStore())
+	existing, _ := s.Create(ctx, &model.Item{Name: "Existing", Price: 1})
+	events, unsubscribe := s.Subscribe(10)
+	defer unsubscribe()
+
+	// Act
+	_, err := s.Batch(ctx, []BatchOp{
+		{Type: BatchCreate, Item: &model.Item{Name: "New", Price: 1}},
+		{Type: BatchDelete, ID: "missing"},
+		{Type: BatchDelete, ID: existing.ID},
+	}, false)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Batch() error = %v", err)
+	}
+	if event := receiveEvent(t, events); event.Type != ItemCreated {
+		t.Errorf("first event = %s, want %s", event.Type, ItemCreated)
+	}
+	if event := receiveEvent(t, events); event.Type != ItemDeleted || event.ItemID != existing.ID {
+		t.Errorf("second event = %+v, want deletion of %s", event, existing.ID)
+	}
+	if len(events) != 0 {
+		t.Errorf("got %d extra events, want 0", len(events))
+	}
+}
+
+func TestEventStore_AtomicBatchFailurePublishesNothing(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	s := NewEventStore(NewMemoryStore())
+	events, unsubscribe := s.Subscribe(10)
+	defer unsubscribe()
+
+	// Act
+	_, _ = s.Batch(ctx, []BatchOp{
+		{Type: BatchCreate, Item: &model.Item{Name: "New", Price: 1}},
+		{Type: BatchDelete, ID: "missing"},
+	}, true)
+
+	// Assert
+	if len(events) != 0 {
+		t.Errorf("got %d events, want 0", len(events))
+	}
+}
+
+func TestEventStore_SlowSubscriberDropsEvents(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	s := NewEventStore(NewMemoryStore())
+	events, unsubscribe := s.Subscribe(1)
+	defer unsubscribe()
+	before := testutil.ToFloat64(observability.ItemEventsDroppedTotal)
+
+	// Act
+	for range 3 {
+		if _, err := s.Create(ctx, &model.Item{Name: "Widget", Price: 1}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	// Assert
+	if len(events) != 1 {
+		t.Errorf("buffered events = %d, want 1", len(events))
+	}
+	if dropped := testutil.ToFloat64(observability.ItemEventsDroppedTotal) - before; dropped != 2 {
+		t.Errorf("item_events_dropped_total delta = %v, want 2", dropped)
+	}
+}
+
+func TestEventStore_Unsubscribe(t *testing.T) {
+	// Arrange
+	s := NewEventStore(NewMemoryStore())
+	events, unsubscribe := s.Subscribe(1)
+
+	// Act
+	unsubscribe()
+	unsubscribe()
+	_, err := s.Create(context.Background(), &model.Item{Name: "Widget", Price: 1})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, ok := <-events; ok {
+		t.Error("channel should be closed after unsubscribe")
+	}
+}
+
+func TestEventStore_ImplementsStore(t *testing.T) {
+	var _ Store = (*EventStore)(nil)
+}
@@ -0,0 +1,343 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+)
+
+// itemsBucket is the bbolt bucket holding JSON-encoded items keyed by ID.
+var itemsBucket = []byte("items")
+
+// fileOpenTimeout bounds how long OpenFileStore waits for the exclusive file
+// lock, so a second process pointed at the same file fails fast instead of
+// hanging forever.
+const fileOpenTimeout = 5 * time.Second
+
+// FileStore implements Store interface on top of an embedded bbolt database
+// file. Every write runs in a single fsync'ed transaction, so the file is
+// never left partially updated after a crash.
+type FileStore struct {
+	db *bolt.DB
+}
+
+// OpenFileStore opens (creating if necessary) the database file at path.
+func OpenFileStore(path string) (*FileStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: fileOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("opening store file %s: %w", path, err)
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(itemsBucket)
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("initializing store file %s: %w", path, err)
+	}
+
+	return &FileStore{db: db}, nil
+}
+
+// Close releases the database file lock and flushes pending state.
+func (s *FileStore) Close() error {
+	return s.db.Close()
+}
+
+// List returns the page of items selected by opts.
+func (s *FileStore) List(ctx context.Context, opts ListOptions) (*ListPage, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("list items: %w", ctx.Err())
+	default:
+	}
+
+	items := make([]model.Item, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(itemsBucket).ForEach(func(_, v []byte) error {
+			var item model.Item
+			if err := decodeFileItem(v, &item); err != nil {
+				return err
+			}
+			items = append(items, item)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list items: %w", err)
+	}
+
+	page, err := paginate(items, opts)
+	if err != nil {
+		return nil, fmt.Errorf("list items: %w", err)
+	}
+
+	return page, nil
+}
+
+// Get retrieves an item by its ID.
+func (s *FileStore) Get(ctx context.Context, id string) (*model.Item, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("get item: %w", ctx.Err())
+	default:
+	}
+
+	if id == "" {
+		return nil, ErrInvalidID
+	}
+
+	var item model.Item
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getFileItem(tx, id, &item)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// Create adds a new item to the store and returns the created item, with a
+// generated ID unless it has one.
+func (s *FileStore) Create(ctx context.Context, item *model.Item) (*model.Item, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("create item: %w", ctx.Err())
+	default:
+	}
+
+	if item == nil {
+		return nil, fmt.Errorf("create item: %w", ErrNilItem)
+	}
+
+	now := time.Now().UTC()
+	newItem := model.Item{
+		ID:          itemID(item),
+		Name:        item.Name,
+		Description: item.Description,
+		Price:       item.Price,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Version:     1,
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(itemsBucket).Get([]byte(newItem.ID)) != nil {
+			return ErrAlreadyExists
+		}
+		return putFileItem(tx, &newItem)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create item: %w", err)
+	}
+
+	return &newItem, nil
+}
+
+// Update modifies an existing item in the store.
+func (s *FileStore) Update(
+	ctx context.Context, id string, item *model.Item, expectedVersion int64,
+) (*model.Item, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("update item: %w", ctx.Err())
+	default:
+	}
+
+	if id == "" {
+		return nil, ErrInvalidID
+	}
+
+	if item == nil {
+		return nil, fmt.Errorf("update item: %w", ErrNilItem)
+	}
+
+	var updatedItem model.Item
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var existing model.Item
+		if err := getFileItem(tx, id, &existing); err != nil {
+			return err
+		}
+
+		if err := checkVersion(existing.Version, expectedVersion); err != nil {
+			return fmt.Errorf("update item: %w", err)
+		}
+
+		updatedItem = model.Item{
+			ID:          id,
+			Name:        item.Name,
+			Description: item.Description,
+			Price:       item.Price,
+			CreatedAt:   existing.CreatedAt,
+			UpdatedAt:   time.Now().UTC(),
+			Version:     existing.Version + 1,
+		}
+
+		return putFileItem(tx, &updatedItem)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &updatedItem, nil
+}
+
+// Upsert updates the item with id or creates it with that ID.
+func (s *FileStore) Upsert(ctx context.Context, id string, item *model.Item) (*model.Item, bool, error) {
+	select {
+	case <-ctx.Done():
+		return nil, false, fmt.Errorf("upsert item: %w", ctx.Err())
+	default:
+	}
+
+	if id == "" {
+		return nil, false, ErrInvalidID
+	}
+
+	if item == nil {
+		return nil, false, fmt.Errorf("upsert item: %w", ErrNilItem)
+	}
+
+	var upserted model.Item
+	var created bool
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var existing *model.Item
+		var stored model.Item
+		switch err := getFileItem(tx, id, &stored); {
+		case err == nil:
+			existing = &stored
+		case !errors.Is(err, ErrNotFound):
+			return err
+		}
+
+		upserted = upsertedItem(id, item, existing, time.Now().UTC())
+		created = existing == nil
+		return putFileItem(tx, &upserted)
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("upsert item: %w", err)
+	}
+
+	return &upserted, created, nil
+}
+
+// Delete removes an item from the store by its ID.
+func (s *FileStore) Delete(ctx context.Context, id string, expectedVersion int64) error {
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("delete item: %w", ctx.Err())
+	default:
+	}
+
+	if id == "" {
+		return ErrInvalidID
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		var existing model.Item
+		if err := getFileItem(tx, id, &existing); err != nil {
+			return err
+		}
+
+		if err := checkVersion(existing.Version, expectedVersion); err != nil {
+			return fmt.Errorf("delete item: %w", err)
+		}
+
+		return tx.Bucket(itemsBucket).Delete([]byte(id))
+	})
+}
+
+// errRollbackBatch makes bbolt roll back an atomic batch that had a failing
+// operation; it never leaves Batch.
+var errRollbackBatch = errors.New("rollback batch")
+
+// Batch applies ops in a single bbolt transaction, which is rolled back when
+// an atomic batch has a failing operation.
+func (s *FileStore) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("batch items: %w", ctx.Err())
+	default:
+	}
+
+	var results []BatchResult
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var failed bool
+		results, failed = applyBatch(fileBatchTx{tx: tx}, ops, atomic)
+		if atomic && failed {
+			return errRollbackBatch
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errRollbackBatch) {
+		return nil, fmt.Errorf("batch items: %w", err)
+	}
+
+	return results, nil
+}
+
+// fileBatchTx applies batch operations within a bbolt write transaction.
+type fileBatchTx struct {
+	tx *bolt.Tx
+}
+
+func (b fileBatchTx) get(id string) (*model.Item, error) {
+	var item model.Item
+	if err := getFileItem(b.tx, id, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (b fileBatchTx) put(item *model.Item) error {
+	return putFileItem(b.tx, item)
+}
+
+func (b fileBatchTx) remove(id string) error {
+	return b.tx.Bucket(itemsBucket).Delete([]byte(id))
+}
+
+// getFileItem decodes the item stored under id into dst.
+func getFileItem(tx *bolt.Tx, id string, dst *model.Item) error {
+	data := tx.Bucket(itemsBucket).Get([]byte(id))
+	if data == nil {
+		return ErrNotFound
+	}
+
+	if err := decodeFileItem(data, dst); err != nil {
+		return fmt.Errorf("decoding item %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// decodeFileItem unmarshals a stored item. Items written before versioning
+// was introduced have no version and are reported as version 1.
+func decodeFileItem(data []byte, dst *model.Item) error {
+	if err := json.Unmarshal(data, dst); err != nil {
+		return err
+	}
+
+	if dst.Version == 0 {
+		dst.Version = 1
+	}
+
+	return nil
+}
+
+// putFileItem encodes item and stores it under its ID.
+func putFileItem(tx *bolt.Tx, item *model.Item) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("encoding item %s: %w", item.ID, err)
+	}
+
+	return tx.Bucket(itemsBucket).Put([]byte(item.ID), data)
+}
@@ -0,0 +1,281 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+)
+
+func newTestFileStore(t *testing.T) (*FileStore, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "items.db")
+	s, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	return s, path
+}
+
+func TestOpenFileStore_CreatesFile(t *testing.T) {
+	// Arrange & Act
+	_, path := newTestFileStore(t)
+
+	// Assert
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("store file not created: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("file mode = %o, want 600", perm)
+	}
+}
+
+func TestOpenFileStore_InvalidPath(t *testing.T) {
+	// Act
+	_, err := OpenFileStore(filepath.Join(t.TempDir(), "missing", "dir", "items.db"))
+
+	// Assert
+	if err == nil {
+		t.Fatal("OpenFileStore() expected error for missing directory, got nil")
+	}
+}
+
+func TestFileStore_CRUD(t *testing.T) {
+	// Arrange
+	s, _ := newTestFileStore(t)
+	ctx := context.Background()
+
+	// Act & Assert: create
+	created, err := s.Create(ctx, &model.Item{Name: "Widget", Description: "Blue", Price: 9.99})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ID == "" {
+		t.Fatal("Create() should generate an ID")
+	}
+
+	// get
+	got, err := s.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Name != "Widget" || got.Description != "Blue" || got.Price != 9.99 {
+		t.Errorf("Get() = %+v, want created fields", got)
+	}
+
+	// update
+	updated, err := s.Update(ctx, created.ID, &model.Item{Name: "Gadget", Price: 1}, 0)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Error("Update() should preserve CreatedAt")
+	}
+	if updated.Name != "Gadget" {
+		t.Errorf("Name = %s, want Gadget", updated.Name)
+	}
+
+	// list
+	page, err := s.List(ctx, ListOptions{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(page.Items) != 1 {
+		t.Fatalf("List() returned %d items, want 1", len(page.Items))
+	}
+
+	// delete
+	if err := s.Delete(ctx, created.ID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Get(ctx, created.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after delete error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestFileStore_PersistsAcrossReopen(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "items.db")
+	s, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	created, err := s.Create(context.Background(), &model.Item{Name: "Durable", Price: 5})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Act
+	reopened, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() reopen error = %v", err)
+	}
+	defer reopened.Close()
+
+	got, err := reopened.Get(context.Background(), created.ID)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Get() after reopen error = %v", err)
+	}
+	if got.Name != "Durable" {
+		t.Errorf("Name = %s, want Durable", got.Name)
+	}
+	if !got.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("CreatedAt = %v, want %v", got.CreatedAt, created.CreatedAt)
+	}
+}
+
+func TestFileStore_Versioning(t *testing.T) {
+	// Arrange
+	s, _ := newTestFileStore(t)
+	ctx := context.Background()
+	created, err := s.Create(ctx, &model.Item{Name: "Versioned", Price: 1})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Act & Assert
+	if created.Version != 1 {
+		t.Errorf("Create() Version = %d, want 1", created.Version)
+	}
+
+	updated, err := s.Update(ctx, created.ID, &model.Item{Name: "V2"}, 1)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("Update() Version = %d, want 2", updated.Version)
+	}
+
+	if _, err := s.Update(ctx, created.ID, &model.Item{Name: "Lost"}, 1); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Update() with stale version error = %v, want %v", err, ErrVersionConflict)
+	}
+	if err := s.Delete(ctx, created.ID, 1); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Delete() with stale version error = %v, want %v", err, ErrVersionConflict)
+	}
+	if err := s.Delete(ctx, created.ID, 2); err != nil {
+		t.Errorf("Delete() with current version error = %v", err)
+	}
+}
+
+func TestFileStore_LegacyItemWithoutVersion(t *testing.T) {
+	// Arrange: write an item as stored before versioning existed.
+	s, _ := newTestFileStore(t)
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(itemsBucket).Put([]byte("legacy"), []byte(`{"id":"legacy","name":"Old","price":1}`))
+	})
+	if err != nil {
+		t.Fatalf("seeding legacy item: %v", err)
+	}
+
+	// Act
+	got, err := s.Get(context.Background(), "legacy")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Version != 1 {
+		t.Errorf("Version = %d, want 1", got.Version)
+	}
+}
+
+func TestFileStore_Errors(t *testing.T) {
+	s, _ := newTestFileStore(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		op      func() error
+		wantErr error
+	}{
+		{
+			name:    "get empty id",
+			op:      func() error { _, err := s.Get(ctx, ""); return err },
+			wantErr: ErrInvalidID,
+		},
+		{
+			name:    "get missing",
+			op:      func() error { _, err := s.Get(ctx, "missing"); return err },
+			wantErr: ErrNotFound,
+		},
+		{
+			name:    "create nil",
+			op:      func() error { _, err := s.Create(ctx, nil); return err },
+			wantErr: ErrNilItem,
+		},
+		{
+			name:    "update empty id",
+			op:      func() error { _, err := s.Update(ctx, "", &model.Item{Name: "x"}, 0); return err },
+			wantErr: ErrInvalidID,
+		},
+		{
+			name:    "update nil",
+			op:      func() error { _, err := s.Update(ctx, "id", nil, 0); return err },
+			wantErr: ErrNilItem,
+		},
+		{
+			name:    "update missing",
+			op:      func() error { _, err := s.Update(ctx, "missing", &model.Item{Name: "x"}, 0); return err },
+			wantErr: ErrNotFound,
+		},
+		{
+			name:    "delete empty id",
+			op:      func() error { return s.Delete(ctx, "", 0) },
+			wantErr: ErrInvalidID,
+		},
+		{
+			name:    "delete missing",
+			op:      func() error { return s.Delete(ctx, "missing", 0) },
+			wantErr: ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.op(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileStore_ContextCancellation(t *testing.T) {
+	// Arrange
+	s, _ := newTestFileStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ops := map[string]func() error{
+		"list":   func() error { _, err := s.List(ctx, ListOptions{}); return err },
+		"get":    func() error { _, err := s.Get(ctx, "id"); return err },
+		"create": func() error { _, err := s.Create(ctx, &model.Item{Name: "x"}); return err },
+		"update": func() error { _, err := s.Update(ctx, "id", &model.Item{Name: "x"}, 0); return err },
+		"delete": func() error { return s.Delete(ctx, "id", 0) },
+	}
+
+	for name, op := range ops {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			err := op()
+
+			// Assert
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("error = %v, want %v", err, context.Canceled)
+			}
+		})
+	}
+}
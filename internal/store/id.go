@@ -0,0 +1,81 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+)
+
+// MaxIDLength is the maximum length of item IDs chosen by clients.
+const MaxIDLength = 255
+
+// uuidPattern matches UUIDs in their canonical textual form, the format of
+// the IDs generated by the stores.
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// IDValidator checks the format of item IDs chosen by clients. A nil
+// IDValidator accepts UUIDs.
+type IDValidator struct {
+	pattern *regexp.Regexp
+}
+
+// NewIDValidator returns an IDValidator accepting the IDs that pattern
+// matches in full, or UUIDs if pattern is empty.
+func NewIDValidator(pattern string) (*IDValidator, error) {
+	if pattern == "" {
+		return &IDValidator{pattern: uuidPattern}, nil
+	}
+
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("compiling item ID pattern: %w", err)
+	}
+
+	return &IDValidator{pattern: re}, nil
+}
+
+// Validate returns an error wrapping ErrInvalidID unless id is accepted.
+// IDs longer than MaxIDLength are never accepted.
+func (v *IDValidator) Validate(id string) error {
+	pattern := uuidPattern
+	if v != nil {
+		pattern = v.pattern
+	}
+
+	if id == "" || len(id) > MaxIDLength || !pattern.MatchString(id) {
+		return fmt.Errorf("%w: %q", ErrInvalidID, id)
+	}
+
+	return nil
+}
+
+// itemID returns the ID chosen for item, or a new UUID if it has none.
+func itemID(item *model.Item) string {
+	if item.ID != "" {
+		return item.ID
+	}
+	return uuid.New().String()
+}
+
+// upsertedItem returns the item stored by an upsert of item under id, given
+// the existing item, if any.
+func upsertedItem(id string, item, existing *model.Item, now time.Time) model.Item {
+	upserted := model.Item{
+		ID:          id,
+		Name:        item.Name,
+		Description: item.Description,
+		Price:       item.Price,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Version:     1,
+	}
+	if existing != nil {
+		upserted.CreatedAt = existing.CreatedAt
+		upserted.Version = existing.Version + 1
+	}
+	return upserted
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+)
+
+func TestIDValidator(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		id      string
+		wantErr bool
+	}{
+		{name: "uuid", id: "5b7c4a38-4f5a-4f5e-9d2a-0f4c1d1e2a3b"},
+		{name: "upper case uuid", id: "5B7C4A38-4F5A-4F5E-9D2A-0F4C1D1E2A3B"},
+		{name: "not a uuid", id: "sku-1", wantErr: true},
+		{name: "uuid with suffix", id: "5b7c4a38-4f5a-4f5e-9d2a-0f4c1d1e2a3b-1", wantErr: true},
+		{name: "empty", id: "", wantErr: true},
+		{name: "pattern", pattern: `sku-[0-9]+`, id: "sku-1"},
+		{name: "pattern matched in full", pattern: `sku-[0-9]+`, id: "sku-1x", wantErr: true},
+		{name: "pattern alternatives anchored", pattern: `a|b`, id: "ab", wantErr: true},
+		{name: "too long", pattern: `.+`, id: strings.Repeat("a", MaxIDLength+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			v, err := NewIDValidator(tt.pattern)
+			if err != nil {
+				t.Fatalf("NewIDValidator() error = %v", err)
+			}
+
+			// Act
+			err = v.Validate(tt.id)
+
+			// Assert
+			if tt.wantErr != errors.Is(err, ErrInvalidID) {
+				t.Errorf("Validate(%q) error = %v, want error %v", tt.id, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIDValidator_Nil(t *testing.T) {
+	// Arrange
+	var v *IDValidator
+
+	// Act
+	uuidErr := v.Validate("5b7c4a38-4f5a-4f5e-9d2a-0f4c1d1e2a3b")
+	otherErr := v.Validate("sku-1")
+
+	// Assert
+	if uuidErr != nil || !errors.Is(otherErr, ErrInvalidID) {
+		t.Errorf("Validate() errors = %v, %v, want only UUIDs accepted", uuidErr, otherErr)
+	}
+}
+
+func TestNewIDValidator_InvalidPattern(t *testing.T) {
+	// Act
+	_, err := NewIDValidator("(")
+
+	// Assert
+	if err == nil {
+		t.Error("NewIDValidator() expected error for an invalid pattern, got nil")
+	}
+}
+
+func TestStore_CreateWithID(t *testing.T) {
+	for storeName, s := range batchStores(t) {
+		t.Run(storeName, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+
+			// Act
+			created, err := s.Create(ctx, &model.Item{ID: "sku-1", Name: "Widget", Price: 1})
+			_, dupErr := s.Create(ctx, &model.Item{ID: "sku-1", Name: "Other", Price: 2})
+
+			// Assert
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if created.ID != "sku-1" || created.Version != 1 {
+				t.Errorf("Create() = %+v, want ID sku-1 at version 1", created)
+			}
+			if !errors.Is(dupErr, ErrAlreadyExists) {
+				t.Errorf("Create() of a taken ID error = %v, want %v", dupErr, ErrAlreadyExists)
+			}
+			stored, err := s.Get(ctx, "sku-1")
+			if err != nil || stored.Name != "Widget" {
+				t.Errorf("Get() = %+v, %v, want the first item", stored, err)
+			}
+		})
+	}
+}
+
+func TestStore_Upsert(t *testing.T) {
+	for storeName, s := range batchStores(t) {
+		t.Run(storeName, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+
+			// Act
+			created, wasCreated, createErr := s.Upsert(ctx, "sku-1", &model.Item{Name: "Widget", Price: 1})
+			time.Sleep(time.Millisecond)
+			updated, wasCreatedAgain, updateErr := s.Upsert(ctx, "sku-1", &model.Item{Name: "Gadget", Price: 2})
+
+			// Assert
+			if createErr != nil || updateErr != nil {
+				t.Fatalf("Upsert() errors = %v, %v", createErr, updateErr)
+			}
+			if !wasCreated || created.ID != "sku-1" || created.Version != 1 {
+				t.Errorf("first Upsert() = %+v, %v, want sku-1 created at version 1", created, wasCreated)
+			}
+			if wasCreatedAgain || updated.Name != "Gadget" || updated.Version != 2 {
+				t.Errorf("second Upsert() = %+v, %v, want Gadget updated to version 2", updated, wasCreatedAgain)
+			}
+			if !updated.CreatedAt.Equal(created.CreatedAt) || !updated.UpdatedAt.After(created.UpdatedAt) {
+				t.Errorf("timestamps = %v/%v, want CreatedAt kept and UpdatedAt advanced", created, updated)
+			}
+		})
+	}
+}
+
+func TestStore_Upsert_Errors(t *testing.T) {
+	for storeName, s := range batchStores(t) {
+		t.Run(storeName, func(t *testing.T) {
+			// Arrange
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			// Act
+			_, _, idErr := s.Upsert(context.Background(), "", &model.Item{Name: "Widget"})
+			_, _, nilErr := s.Upsert(context.Background(), "sku-1", nil)
+			_, _, ctxErr := s.Upsert(ctx, "sku-1", &model.Item{Name: "Widget"})
+
+			// Assert
+			if !errors.Is(idErr, ErrInvalidID) {
+				t.Errorf("Upsert() without ID error = %v, want %v", idErr, ErrInvalidID)
+			}
+			if !errors.Is(nilErr, ErrNilItem) {
+				t.Errorf("Upsert() of nil error = %v, want %v", nilErr, ErrNilItem)
+			}
+			if !errors.Is(ctxErr, context.Canceled) {
+				t.Errorf("Upsert() with cancelled context error = %v, want %v", ctxErr, context.Canceled)
+			}
+		})
+	}
+}
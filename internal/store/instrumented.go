@@ -15,7 +15,9 @@ const (
 	opGet    = "get"
 	opCreate = "create"
 	opUpdate = "update"
+	opUpsert = "upsert"
 	opDelete = "delete"
+	opBatch  = "batch"
 )
 
 // InstrumentedStore decorates a Store with Prometheus instrumentation,
@@ -46,12 +48,12 @@ func observe(operation string, start time.Time, err error) {
 		Inc()
 }
 
-// List returns all items, recording instrumentation for the list operation.
-func (s *InstrumentedStore) List(ctx context.Context) ([]model.Item, error) {
+// List returns a page of items, recording instrumentation for the list operation.
+func (s *InstrumentedStore) List(ctx context.Context, opts ListOptions) (*ListPage, error) {
 	start := time.Now()
-	items, err := s.delegate.List(ctx)
+	page, err := s.delegate.List(ctx, opts)
 	observe(opList, start, err)
-	return items, err
+	return page, err
 }
 
 // Get retrieves an item by ID, recording instrumentation for the get operation.
@@ -71,17 +73,37 @@ func (s *InstrumentedStore) Create(ctx context.Context, item *model.Item) (*mode
 }
 
 // Update modifies an item, recording instrumentation for the update operation.
-func (s *InstrumentedStore) Update(ctx context.Context, id string, item *model.Item) (*model.Item, error) {
+func (s *InstrumentedStore) Update(
+	ctx context.Context, id string, item *model.Item, expectedVersion int64,
+) (*model.Item, error) {
 	start := time.Now()
-	updated, err := s.delegate.Update(ctx, id, item)
+	updated, err := s.delegate.Update(ctx, id, item, expectedVersion)
 	observe(opUpdate, start, err)
 	return updated, err
 }
 
+// Upsert updates or creates an item, recording instrumentation for the
+// upsert operation.
+func (s *InstrumentedStore) Upsert(ctx context.Context, id string, item *model.Item) (*model.Item, bool, error) {
+	start := time.Now()
+	upserted, created, err := s.delegate.Upsert(ctx, id, item)
+	observe(opUpsert, start, err)
+	return upserted, created, err
+}
+
 // Delete removes an item, recording instrumentation for the delete operation.
-func (s *InstrumentedStore) Delete(ctx context.Context, id string) error {
+func (s *InstrumentedStore) Delete(ctx context.Context, id string, expectedVersion int64) error {
 	start := time.Now()
-	err := s.delegate.Delete(ctx, id)
+	err := s.delegate.Delete(ctx, id, expectedVersion)
 	observe(opDelete, start, err)
 	return err
 }
+
+// Batch applies a batch of operations, recording instrumentation for the
+// batch as a single operation.
+func (s *InstrumentedStore) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	start := time.Now()
+	results, err := s.delegate.Batch(ctx, ops, atomic)
+	observe(opBatch, start, err)
+	return results, err
+}
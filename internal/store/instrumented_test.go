@@ -15,22 +15,26 @@ import (
 // InstrumentedStore decorator delegates correctly and records both the success
 // and failure metric paths.
 type fakeStore struct {
-	listFn   func(ctx context.Context) ([]model.Item, error)
+	listFn   func(ctx context.Context, opts ListOptions) (*ListPage, error)
 	getFn    func(ctx context.Context, id string) (*model.Item, error)
 	createFn func(ctx context.Context, item *model.Item) (*model.Item, error)
-	updateFn func(ctx context.Context, id string, item *model.Item) (*model.Item, error)
-	deleteFn func(ctx context.Context, id string) error
+	updateFn func(ctx context.Context, id string, item *model.Item, version int64) (*model.Item, error)
+	upsertFn func(ctx context.Context, id string, item *model.Item) (*model.Item, bool, error)
+	deleteFn func(ctx context.Context, id string, version int64) error
+	batchFn  func(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error)
 
 	listCalls   int
 	getCalls    int
 	createCalls int
 	updateCalls int
+	upsertCalls int
 	deleteCalls int
+	batchCalls  int
 }
 
-func (f *fakeStore) List(ctx context.Context) ([]model.Item, error) {
+func (f *fakeStore) List(ctx context.Context, opts ListOptions) (*ListPage, error) {
 	f.listCalls++
-	return f.listFn(ctx)
+	return f.listFn(ctx, opts)
 }
 
 func (f *fakeStore) Get(ctx context.Context, id string) (*model.Item, error) {
@@ -43,14 +47,24 @@ func (f *fakeStore) Create(ctx context.Context, item *model.Item) (*model.Item,
 	return f.createFn(ctx, item)
 }
 
-func (f *fakeStore) Update(ctx context.Context, id string, item *model.Item) (*model.Item, error) {
+func (f *fakeStore) Update(ctx context.Context, id string, item *model.Item, version int64) (*model.Item, error) {
 	f.updateCalls++
-	return f.updateFn(ctx, id, item)
+	return f.updateFn(ctx, id, item, version)
 }
 
-func (f *fakeStore) Delete(ctx context.Context, id string) error {
+func (f *fakeStore) Upsert(ctx context.Context, id string, item *model.Item) (*model.Item, bool, error) {
+	f.upsertCalls++
+	return f.upsertFn(ctx, id, item)
+}
+
+func (f *fakeStore) Delete(ctx context.Context, id string, version int64) error {
 	f.deleteCalls++
-	return f.deleteFn(ctx, id)
+	return f.deleteFn(ctx, id, version)
+}
+
+func (f *fakeStore) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	f.batchCalls++
+	return f.batchFn(ctx, ops, atomic)
 }
 
 func storeOpCount(t *testing.T, operation, result string) float64 {
@@ -77,20 +91,24 @@ func TestNewInstrumentedStore(t *testing.T) {
 func TestInstrumentedStore_Delegates(t *testing.T) {
 	ctx := context.Background()
 	wantItem := &model.Item{ID: "1", Name: "widget", Price: 1.5}
-	wantList := []model.Item{*wantItem}
+	wantList := &ListPage{Items: []model.Item{*wantItem}, Total: 1}
 
 	fake := &fakeStore{
-		listFn:   func(context.Context) ([]model.Item, error) { return wantList, nil },
+		listFn:   func(context.Context, ListOptions) (*ListPage, error) { return wantList, nil },
 		getFn:    func(context.Context, string) (*model.Item, error) { return wantItem, nil },
 		createFn: func(context.Context, *model.Item) (*model.Item, error) { return wantItem, nil },
-		updateFn: func(context.Context, string, *model.Item) (*model.Item, error) { return wantItem, nil },
-		deleteFn: func(context.Context, string) error { return nil },
+		updateFn: func(context.Context, string, *model.Item, int64) (*model.Item, error) { return wantItem, nil },
+		upsertFn: func(context.Context, string, *model.Item) (*model.Item, bool, error) { return wantItem, true, nil },
+		deleteFn: func(context.Context, string, int64) error { return nil },
+		batchFn: func(context.Context, []BatchOp, bool) ([]BatchResult, error) {
+			return []BatchResult{{Item: wantItem}}, nil
+		},
 	}
 	is := NewInstrumentedStore(fake)
 
 	// List
-	gotList, err := is.List(ctx)
-	if err != nil || len(gotList) != 1 {
+	gotList, err := is.List(ctx, ListOptions{})
+	if err != nil || len(gotList.Items) != 1 {
 		t.Fatalf("List() = %v, %v; want 1 item, nil", gotList, err)
 	}
 	if fake.listCalls != 1 {
@@ -116,7 +134,7 @@ func TestInstrumentedStore_Delegates(t *testing.T) {
 	}
 
 	// Update
-	gotItem, err = is.Update(ctx, "1", wantItem)
+	gotItem, err = is.Update(ctx, "1", wantItem, 0)
 	if err != nil || gotItem != wantItem {
 		t.Fatalf("Update() = %v, %v; want item, nil", gotItem, err)
 	}
@@ -124,13 +142,31 @@ func TestInstrumentedStore_Delegates(t *testing.T) {
 		t.Errorf("Update delegate calls = %d, want 1", fake.updateCalls)
 	}
 
+	// Upsert
+	gotItem, created, err := is.Upsert(ctx, "1", wantItem)
+	if err != nil || gotItem != wantItem || !created {
+		t.Fatalf("Upsert() = %v, %v, %v; want item, true, nil", gotItem, created, err)
+	}
+	if fake.upsertCalls != 1 {
+		t.Errorf("Upsert delegate calls = %d, want 1", fake.upsertCalls)
+	}
+
 	// Delete
-	if err := is.Delete(ctx, "1"); err != nil {
+	if err := is.Delete(ctx, "1", 0); err != nil {
 		t.Fatalf("Delete() error = %v, want nil", err)
 	}
 	if fake.deleteCalls != 1 {
 		t.Errorf("Delete delegate calls = %d, want 1", fake.deleteCalls)
 	}
+
+	// Batch
+	gotResults, err := is.Batch(ctx, []BatchOp{{Type: BatchCreate, Item: wantItem}}, true)
+	if err != nil || len(gotResults) != 1 || gotResults[0].Item != wantItem {
+		t.Fatalf("Batch() = %v, %v; want 1 result, nil", gotResults, err)
+	}
+	if fake.batchCalls != 1 {
+		t.Errorf("Batch delegate calls = %d, want 1", fake.batchCalls)
+	}
 }
 
 // TestInstrumentedStore_RecordsSuccessAndFailureMetrics verifies the metric
@@ -149,7 +185,7 @@ func TestInstrumentedStore_RecordsSuccessAndFailureMetrics(t *testing.T) {
 			name:      "list success",
 			operation: opList,
 			invoke: func(is *InstrumentedStore) error {
-				_, err := is.List(context.Background())
+				_, err := is.List(context.Background(), ListOptions{})
 				return err
 			},
 		},
@@ -175,7 +211,16 @@ func TestInstrumentedStore_RecordsSuccessAndFailureMetrics(t *testing.T) {
 			operation: opUpdate,
 			wantErr:   true,
 			invoke: func(is *InstrumentedStore) error {
-				_, err := is.Update(context.Background(), "x", &model.Item{})
+				_, err := is.Update(context.Background(), "x", &model.Item{}, 0)
+				return err
+			},
+		},
+		{
+			name:      "upsert failure",
+			operation: opUpsert,
+			wantErr:   true,
+			invoke: func(is *InstrumentedStore) error {
+				_, _, err := is.Upsert(context.Background(), "x", &model.Item{})
 				return err
 			},
 		},
@@ -183,7 +228,16 @@ func TestInstrumentedStore_RecordsSuccessAndFailureMetrics(t *testing.T) {
 			name:      "delete success",
 			operation: opDelete,
 			invoke: func(is *InstrumentedStore) error {
-				return is.Delete(context.Background(), "x")
+				return is.Delete(context.Background(), "x", 0)
+			},
+		},
+		{
+			name:      "batch failure",
+			operation: opBatch,
+			wantErr:   true,
+			invoke: func(is *InstrumentedStore) error {
+				_, err := is.Batch(context.Background(), nil, true)
+				return err
 			},
 		},
 		{
@@ -191,7 +245,7 @@ func TestInstrumentedStore_RecordsSuccessAndFailureMetrics(t *testing.T) {
 			operation: opDelete,
 			wantErr:   true,
 			invoke: func(is *InstrumentedStore) error {
-				return is.Delete(context.Background(), "x")
+				return is.Delete(context.Background(), "x", 0)
 			},
 		},
 	}
@@ -206,11 +260,13 @@ func TestInstrumentedStore_RecordsSuccessAndFailureMetrics(t *testing.T) {
 			}
 
 			fake := &fakeStore{
-				listFn:   func(context.Context) ([]model.Item, error) { return nil, retErr },
+				listFn:   func(context.Context, ListOptions) (*ListPage, error) { return nil, retErr },
 				getFn:    func(context.Context, string) (*model.Item, error) { return nil, retErr },
 				createFn: func(context.Context, *model.Item) (*model.Item, error) { return nil, retErr },
-				updateFn: func(context.Context, string, *model.Item) (*model.Item, error) { return nil, retErr },
-				deleteFn: func(context.Context, string) error { return retErr },
+				updateFn: func(context.Context, string, *model.Item, int64) (*model.Item, error) { return nil, retErr },
+				upsertFn: func(context.Context, string, *model.Item) (*model.Item, bool, error) { return nil, false, retErr },
+				deleteFn: func(context.Context, string, int64) error { return retErr },
+				batchFn:  func(context.Context, []BatchOp, bool) ([]BatchResult, error) { return nil, retErr },
 			}
 			is := NewInstrumentedStore(fake)
 
@@ -239,11 +295,11 @@ func TestInstrumentedStore_ObservesDuration(t *testing.T) {
 	observability.StoreOperationDuration.Reset()
 
 	fake := &fakeStore{
-		listFn: func(context.Context) ([]model.Item, error) { return nil, nil },
+		listFn: func(context.Context, ListOptions) (*ListPage, error) { return &ListPage{}, nil },
 	}
 	is := NewInstrumentedStore(fake)
 
-	if _, err := is.List(context.Background()); err != nil {
+	if _, err := is.List(context.Background(), ListOptions{}); err != nil {
 		t.Fatalf("List() error = %v", err)
 	}
 
@@ -0,0 +1,275 @@
+package store
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+)
+
+// List option errors.
+var (
+	ErrInvalidListOptions = errors.New("invalid list options")
+	ErrInvalidCursor      = errors.New("invalid pagination cursor")
+)
+
+// SortField identifies the item attribute used to order List results.
+type SortField string
+
+// Supported sort fields.
+const (
+	SortByCreatedAt SortField = "created_at"
+	SortByName      SortField = "name"
+	SortByPrice     SortField = "price"
+)
+
+// ListOptions controls filtering, ordering and pagination of List results.
+// The zero value lists every item ordered by creation time.
+type ListOptions struct {
+	// Limit caps the number of returned items (0 = no limit).
+	Limit int
+
+	// Offset skips the given number of matching items. It cannot be
+	// combined with Cursor.
+	Offset int
+
+	// Cursor resumes listing after the last item of a previous page, as
+	// returned in ListPage.NextCursor. It must be used with the same sort
+	// settings that produced it.
+	Cursor string
+
+	// SortBy selects the ordering attribute (default SortByCreatedAt). Ties
+	// are broken by item ID so the ordering is total.
+	SortBy SortField
+
+	// Descending reverses the sort order.
+	Descending bool
+
+	// NamePrefix keeps only items whose name starts with the given prefix
+	// (case-sensitive).
+	NamePrefix string
+
+	// MinPrice and MaxPrice keep only items within the inclusive price range.
+	MinPrice *float64
+	MaxPrice *float64
+}
+
+// ListPage is a single page of List results.
+type ListPage struct {
+	// Items holds the items of this page.
+	Items []model.Item
+
+	// Total is the number of items matching the filters, ignoring pagination.
+	Total int
+
+	// NextCursor resumes listing after this page; empty on the last page.
+	NextCursor string
+}
+
+// sortFieldOrDefault returns the sort field, defaulting to SortByCreatedAt.
+func (o *ListOptions) sortFieldOrDefault() SortField {
+	if o.SortBy == "" {
+		return SortByCreatedAt
+	}
+	return o.SortBy
+}
+
+// Validate checks that the options are consistent.
+func (o *ListOptions) Validate() error {
+	switch o.sortFieldOrDefault() {
+	case SortByCreatedAt, SortByName, SortByPrice:
+	default:
+		return fmt.Errorf("%w: unsupported sort field %q", ErrInvalidListOptions, o.SortBy)
+	}
+
+	if o.Limit < 0 {
+		return fmt.Errorf("%w: limit must not be negative", ErrInvalidListOptions)
+	}
+
+	if o.Offset < 0 {
+		return fmt.Errorf("%w: offset must not be negative", ErrInvalidListOptions)
+	}
+
+	if o.Cursor != "" && o.Offset > 0 {
+		return fmt.Errorf("%w: cursor and offset are mutually exclusive", ErrInvalidListOptions)
+	}
+
+	if o.MinPrice != nil && o.MaxPrice != nil && *o.MinPrice > *o.MaxPrice {
+		return fmt.Errorf("%w: min price exceeds max price", ErrInvalidListOptions)
+	}
+
+	return nil
+}
+
+// matches reports whether the item passes the option filters.
+func (o *ListOptions) matches(item *model.Item) bool {
+	if o.NamePrefix != "" && !strings.HasPrefix(item.Name, o.NamePrefix) {
+		return false
+	}
+
+	if o.MinPrice != nil && item.Price < *o.MinPrice {
+		return false
+	}
+
+	if o.MaxPrice != nil && item.Price > *o.MaxPrice {
+		return false
+	}
+
+	return true
+}
+
+// listCursor is the decoded form of an opaque pagination cursor. It records
+// the sort key of the last returned item so the next page starts strictly
+// after it, which keeps pages stable while items are inserted or deleted.
+type listCursor struct {
+	SortBy     SortField `json:"s"`
+	Descending bool      `json:"d,omitempty"`
+	ID         string    `json:"i"`
+	Name       string    `json:"n,omitempty"`
+	Price      float64   `json:"p,omitempty"`
+	CreatedAt  time.Time `json:"c,omitzero"`
+}
+
+// newListCursor builds the cursor positioned after the given item.
+func newListCursor(opts *ListOptions, item *model.Item) listCursor {
+	c := listCursor{
+		SortBy:     opts.sortFieldOrDefault(),
+		Descending: opts.Descending,
+		ID:         item.ID,
+	}
+
+	switch c.SortBy {
+	case SortByName:
+		c.Name = item.Name
+	case SortByPrice:
+		c.Price = item.Price
+	case SortByCreatedAt:
+		c.CreatedAt = item.CreatedAt
+	}
+
+	return c
+}
+
+// encode returns the opaque string form of the cursor.
+func (c listCursor) encode() string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		// listCursor only holds JSON-safe fields; Marshal cannot fail.
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// item returns a placeholder item carrying the cursor's sort key, so it can
+// be compared with compareItems.
+func (c listCursor) item() model.Item {
+	return model.Item{
+		ID:        c.ID,
+		Name:      c.Name,
+		Price:     c.Price,
+		CreatedAt: c.CreatedAt,
+	}
+}
+
+// decodeListCursor parses an opaque cursor and checks that it was produced
+// with the sort settings in opts.
+func decodeListCursor(opts *ListOptions) (listCursor, error) {
+	var c listCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(opts.Cursor)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return c, ErrInvalidCursor
+	}
+
+	if c.SortBy != opts.sortFieldOrDefault() || c.Descending != opts.Descending {
+		return c, fmt.Errorf("%w: cursor was issued for a different sort order", ErrInvalidCursor)
+	}
+
+	return c, nil
+}
+
+// compareItems orders two items by the given field, breaking ties by ID.
+func compareItems(a, b *model.Item, field SortField) int {
+	var c int
+	switch field {
+	case SortByName:
+		c = strings.Compare(a.Name, b.Name)
+	case SortByPrice:
+		c = cmp.Compare(a.Price, b.Price)
+	default:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+// paginate applies ListOptions to a full, unordered item set. It backs the
+// stores that keep every item in process (MemoryStore, FileStore).
+func paginate(items []model.Item, opts ListOptions) (*ListPage, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	field := opts.sortFieldOrDefault()
+	direction := 1
+	if opts.Descending {
+		direction = -1
+	}
+
+	matched := make([]model.Item, 0, len(items))
+	for i := range items {
+		if opts.matches(&items[i]) {
+			matched = append(matched, items[i])
+		}
+	}
+
+	slices.SortFunc(matched, func(a, b model.Item) int {
+		return direction * compareItems(&a, &b, field)
+	})
+
+	total := len(matched)
+	start := min(opts.Offset, total)
+
+	if opts.Cursor != "" {
+		cursor, err := decodeListCursor(&opts)
+		if err != nil {
+			return nil, err
+		}
+		after := cursor.item()
+		start, _ = slices.BinarySearchFunc(matched, after, func(e, t model.Item) int {
+			if direction*compareItems(&e, &t, field) <= 0 {
+				return -1
+			}
+			return 1
+		})
+	}
+
+	end := total
+	if opts.Limit > 0 && start+opts.Limit < total {
+		end = start + opts.Limit
+	}
+
+	page := &ListPage{
+		Items: matched[start:end],
+		Total: total,
+	}
+
+	if end < total && end > start {
+		page.NextCursor = newListCursor(&opts, &matched[end-1]).encode()
+	}
+
+	return page, nil
+}
@@ -0,0 +1,219 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+)
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+func paginationFixture() []model.Item {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []model.Item{
+		{ID: "c", Name: "Cherry", Price: 3, CreatedAt: base.Add(2 * time.Hour)},
+		{ID: "a", Name: "Apple", Price: 1, CreatedAt: base},
+		{ID: "e", Name: "Apricot", Price: 5, CreatedAt: base.Add(4 * time.Hour)},
+		{ID: "b", Name: "Banana", Price: 2, CreatedAt: base.Add(time.Hour)},
+		{ID: "d", Name: "Date", Price: 4, CreatedAt: base.Add(3 * time.Hour)},
+	}
+}
+
+func itemIDs(items []model.Item) string {
+	ids := ""
+	for _, item := range items {
+		ids += item.ID
+	}
+	return ids
+}
+
+func TestListOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ListOptions
+		wantErr bool
+	}{
+		{name: "zero value", opts: ListOptions{}},
+		{name: "all fields", opts: ListOptions{
+			Limit: 10, Offset: 5, SortBy: SortByPrice, Descending: true,
+			NamePrefix: "A", MinPrice: float64Ptr(1), MaxPrice: float64Ptr(2),
+		}},
+		{name: "unknown sort", opts: ListOptions{SortBy: "color"}, wantErr: true},
+		{name: "negative limit", opts: ListOptions{Limit: -1}, wantErr: true},
+		{name: "negative offset", opts: ListOptions{Offset: -1}, wantErr: true},
+		{name: "cursor with offset", opts: ListOptions{Cursor: "x", Offset: 1}, wantErr: true},
+		{name: "inverted price range", opts: ListOptions{MinPrice: float64Ptr(5), MaxPrice: float64Ptr(1)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidListOptions) {
+					t.Errorf("Validate() error = %v, want %v", err, ErrInvalidListOptions)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Validate() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestPaginate_SortAndFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ListOptions
+		wantIDs string
+		total   int
+	}{
+		{name: "default created_at ascending", opts: ListOptions{}, wantIDs: "abcde", total: 5},
+		{name: "name ascending", opts: ListOptions{SortBy: SortByName}, wantIDs: "aebcd", total: 5},
+		{name: "price descending", opts: ListOptions{SortBy: SortByPrice, Descending: true}, wantIDs: "edcba", total: 5},
+		{name: "name prefix", opts: ListOptions{NamePrefix: "Ap"}, wantIDs: "ae", total: 2},
+		{name: "price range", opts: ListOptions{MinPrice: float64Ptr(2), MaxPrice: float64Ptr(4)}, wantIDs: "bcd", total: 3},
+		{name: "limit and offset", opts: ListOptions{Limit: 2, Offset: 1}, wantIDs: "bc", total: 5},
+		{name: "offset past end", opts: ListOptions{Offset: 10}, wantIDs: "", total: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			page, err := paginate(paginationFixture(), tt.opts)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("paginate() error = %v", err)
+			}
+			if got := itemIDs(page.Items); got != tt.wantIDs {
+				t.Errorf("items = %q, want %q", got, tt.wantIDs)
+			}
+			if page.Total != tt.total {
+				t.Errorf("Total = %d, want %d", page.Total, tt.total)
+			}
+		})
+	}
+}
+
+func TestPaginate_CursorWalk(t *testing.T) {
+	for _, desc := range []bool{false, true} {
+		opts := ListOptions{Limit: 2, SortBy: SortByPrice, Descending: desc}
+		want := "abcde"
+		if desc {
+			want = "edcba"
+		}
+
+		// Act: follow cursors until exhausted.
+		got := ""
+		pages := 0
+		for {
+			page, err := paginate(paginationFixture(), opts)
+			if err != nil {
+				t.Fatalf("paginate() error = %v", err)
+			}
+			got += itemIDs(page.Items)
+			pages++
+			if page.NextCursor == "" {
+				break
+			}
+			opts.Cursor = page.NextCursor
+		}
+
+		// Assert
+		if got != want {
+			t.Errorf("descending=%v: walked %q, want %q", desc, got, want)
+		}
+		if pages != 3 {
+			t.Errorf("descending=%v: pages = %d, want 3", desc, pages)
+		}
+	}
+}
+
+func TestPaginate_CursorSurvivesDeletion(t *testing.T) {
+	// Arrange
+	items := paginationFixture()
+	first, err := paginate(items, ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("paginate() error = %v", err)
+	}
+
+	// Remove the last item of the first page ("b") before fetching page two.
+	remaining := make([]model.Item, 0, len(items))
+	for _, item := range items {
+		if item.ID != "b" {
+			remaining = append(remaining, item)
+		}
+	}
+
+	// Act
+	second, err := paginate(remaining, ListOptions{Limit: 2, Cursor: first.NextCursor})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("paginate() error = %v", err)
+	}
+	if got := itemIDs(second.Items); got != "cd" {
+		t.Errorf("second page = %q, want %q", got, "cd")
+	}
+}
+
+func TestPaginate_InvalidCursor(t *testing.T) {
+	// Arrange
+	page, err := paginate(paginationFixture(), ListOptions{Limit: 2, SortBy: SortByName})
+	if err != nil {
+		t.Fatalf("paginate() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts ListOptions
+	}{
+		{name: "garbage", opts: ListOptions{Cursor: "!!not-base64!!"}},
+		{name: "not json", opts: ListOptions{Cursor: "bm90LWpzb24"}},
+		{name: "different sort", opts: ListOptions{Cursor: page.NextCursor, SortBy: SortByPrice}},
+		{name: "different direction", opts: ListOptions{Cursor: page.NextCursor, SortBy: SortByName, Descending: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, err := paginate(paginationFixture(), tt.opts)
+
+			// Assert
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("paginate() error = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestMemoryStore_List_Options(t *testing.T) {
+	// Arrange
+	s := NewMemoryStore()
+	for _, item := range paginationFixture() {
+		s.items[item.ID] = item
+	}
+
+	// Act
+	page, err := s.List(t.Context(), ListOptions{Limit: 3, SortBy: SortByName})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if got := itemIDs(page.Items); got != "aeb" {
+		t.Errorf("items = %q, want %q", got, "aeb")
+	}
+	if page.Total != 5 || page.NextCursor == "" {
+		t.Errorf("Total = %d, NextCursor = %q; want 5 and a cursor", page.Total, page.NextCursor)
+	}
+
+	if _, err := s.List(t.Context(), ListOptions{SortBy: "color"}); !errors.Is(err, ErrInvalidListOptions) {
+		t.Errorf("List() error = %v, want %v", err, ErrInvalidListOptions)
+	}
+}
@@ -6,8 +6,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/google/uuid"
-
 	"github.com/vyrodovalexey/restapi-example/internal/model"
 )
 
@@ -24,8 +22,8 @@ func NewMemoryStore() *MemoryStore {
 	}
 }
 
-// List returns all items from the store.
-func (s *MemoryStore) List(ctx context.Context) ([]model.Item, error) {
+// List returns the page of items selected by opts.
+func (s *MemoryStore) List(ctx context.Context, opts ListOptions) (*ListPage, error) {
 	select {
 	case <-ctx.Done():
 		return nil, fmt.Errorf("list items: %w", ctx.Err())
@@ -40,7 +38,12 @@ func (s *MemoryStore) List(ctx context.Context) ([]model.Item, error) {
 		items = append(items, item)
 	}
 
-	return items, nil
+	page, err := paginate(items, opts)
+	if err != nil {
+		return nil, fmt.Errorf("list items: %w", err)
+	}
+
+	return page, nil
 }
 
 // Get retrieves an item by its ID.
@@ -66,7 +69,8 @@ func (s *MemoryStore) Get(ctx context.Context, id string) (*model.Item, error) {
 	return &item, nil
 }
 
-// Create adds a new item to the store and returns the created item with generated ID.
+// Create adds a new item to the store and returns the created item, with a
+// generated ID unless it has one.
 func (s *MemoryStore) Create(ctx context.Context, item *model.Item) (*model.Item, error) {
 	select {
 	case <-ctx.Done():
@@ -83,12 +87,17 @@ func (s *MemoryStore) Create(ctx context.Context, item *model.Item) (*model.Item
 
 	now := time.Now().UTC()
 	newItem := model.Item{
-		ID:          uuid.New().String(),
+		ID:          itemID(item),
 		Name:        item.Name,
 		Description: item.Description,
 		Price:       item.Price,
 		CreatedAt:   now,
 		UpdatedAt:   now,
+		Version:     1,
+	}
+
+	if _, exists := s.items[newItem.ID]; exists {
+		return nil, fmt.Errorf("create item: %w", ErrAlreadyExists)
 	}
 
 	s.items[newItem.ID] = newItem
@@ -97,7 +106,9 @@ func (s *MemoryStore) Create(ctx context.Context, item *model.Item) (*model.Item
 }
 
 // Update modifies an existing item in the store.
-func (s *MemoryStore) Update(ctx context.Context, id string, item *model.Item) (*model.Item, error) {
+func (s *MemoryStore) Update(
+	ctx context.Context, id string, item *model.Item, expectedVersion int64,
+) (*model.Item, error) {
 	select {
 	case <-ctx.Done():
 		return nil, fmt.Errorf("update item: %w", ctx.Err())
@@ -120,6 +131,10 @@ func (s *MemoryStore) Update(ctx context.Context, id string, item *model.Item) (
 		return nil, ErrNotFound
 	}
 
+	if err := checkVersion(existing.Version, expectedVersion); err != nil {
+		return nil, fmt.Errorf("update item: %w", err)
+	}
+
 	updatedItem := model.Item{
 		ID:          id,
 		Name:        item.Name,
@@ -127,6 +142,7 @@ func (s *MemoryStore) Update(ctx context.Context, id string, item *model.Item) (
 		Price:       item.Price,
 		CreatedAt:   existing.CreatedAt,
 		UpdatedAt:   time.Now().UTC(),
+		Version:     existing.Version + 1,
 	}
 
 	s.items[id] = updatedItem
@@ -134,8 +150,38 @@ func (s *MemoryStore) Update(ctx context.Context, id string, item *model.Item) (
 	return &updatedItem, nil
 }
 
+// Upsert updates the item with id or creates it with that ID.
+func (s *MemoryStore) Upsert(ctx context.Context, id string, item *model.Item) (*model.Item, bool, error) {
+	select {
+	case <-ctx.Done():
+		return nil, false, fmt.Errorf("upsert item: %w", ctx.Err())
+	default:
+	}
+
+	if id == "" {
+		return nil, false, ErrInvalidID
+	}
+
+	if item == nil {
+		return nil, false, fmt.Errorf("upsert item: %w", ErrNilItem)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var existing *model.Item
+	if stored, exists := s.items[id]; exists {
+		existing = &stored
+	}
+
+	upserted := upsertedItem(id, item, existing, time.Now().UTC())
+	s.items[id] = upserted
+
+	return &upserted, existing == nil, nil
+}
+
 // Delete removes an item from the store by its ID.
-func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+func (s *MemoryStore) Delete(ctx context.Context, id string, expectedVersion int64) error {
 	select {
 	case <-ctx.Done():
 		return fmt.Errorf("delete item: %w", ctx.Err())
@@ -149,11 +195,79 @@ func (s *MemoryStore) Delete(ctx context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.items[id]; !exists {
+	existing, exists := s.items[id]
+	if !exists {
 		return ErrNotFound
 	}
 
+	if err := checkVersion(existing.Version, expectedVersion); err != nil {
+		return fmt.Errorf("delete item: %w", err)
+	}
+
 	delete(s.items, id)
 
 	return nil
 }
+
+// Batch applies ops under a single write lock. Writes are staged and only
+// copied into the store once the batch is known to commit.
+func (s *MemoryStore) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("batch items: %w", ctx.Err())
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &memoryBatchTx{items: s.items, staged: make(map[string]*model.Item)}
+	results, failed := applyBatch(tx, ops, atomic)
+	if atomic && failed {
+		return results, nil
+	}
+
+	for id, item := range tx.staged {
+		if item == nil {
+			delete(s.items, id)
+			continue
+		}
+		s.items[id] = *item
+	}
+
+	return results, nil
+}
+
+// memoryBatchTx overlays staged writes on the store's items. A nil staged
+// entry marks a deleted item.
+type memoryBatchTx struct {
+	items  map[string]model.Item
+	staged map[string]*model.Item
+}
+
+func (tx *memoryBatchTx) get(id string) (*model.Item, error) {
+	if item, ok := tx.staged[id]; ok {
+		if item == nil {
+			return nil, ErrNotFound
+		}
+		return item, nil
+	}
+
+	item, exists := tx.items[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	return &item, nil
+}
+
+func (tx *memoryBatchTx) put(item *model.Item) error {
+	stored := *item
+	tx.staged[item.ID] = &stored
+	return nil
+}
+
+func (tx *memoryBatchTx) remove(id string) error {
+	tx.staged[id] = nil
+	return nil
+}
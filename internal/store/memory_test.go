@@ -253,15 +253,18 @@ func TestMemoryStore_List(t *testing.T) {
 			tt.setup(store, ctx)
 
 			// Act
-			items, err := store.List(ctx)
+			page, err := store.List(ctx, ListOptions{})
 
 			// Assert
 			if err != nil {
 				t.Fatalf("List() unexpected error: %v", err)
 			}
 
-			if len(items) != tt.wantCount {
-				t.Errorf("List() returned %d items, want %d", len(items), tt.wantCount)
+			if len(page.Items) != tt.wantCount {
+				t.Errorf("List() returned %d items, want %d", len(page.Items), tt.wantCount)
+			}
+			if page.Total != tt.wantCount {
+				t.Errorf("List() total = %d, want %d", page.Total, tt.wantCount)
 			}
 		})
 	}
@@ -274,13 +277,13 @@ func TestMemoryStore_List_ContextCancellation(t *testing.T) {
 	cancel() // Cancel immediately
 
 	// Act
-	items, err := store.List(ctx)
+	page, err := store.List(ctx, ListOptions{})
 
 	// Assert
 	if err == nil {
 		t.Error("List() expected error for cancelled context")
 	}
-	if items != nil {
+	if page != nil {
 		t.Error("List() should return nil for cancelled context")
 	}
 }
@@ -352,7 +355,7 @@ func TestMemoryStore_Update(t *testing.T) {
 			}
 
 			// Act
-			updated, err := store.Update(ctx, id, tt.update)
+			updated, err := store.Update(ctx, id, tt.update, 0)
 
 			// Assert
 			if tt.wantErr != nil {
@@ -413,7 +416,7 @@ func TestMemoryStore_Update_ContextCancellation(t *testing.T) {
 	}
 
 	// Act
-	updated, err := store.Update(ctx, "some-id", update)
+	updated, err := store.Update(ctx, "some-id", update, 0)
 
 	// Assert
 	if err == nil {
@@ -470,7 +473,7 @@ func TestMemoryStore_Delete(t *testing.T) {
 			}
 
 			// Act
-			err := store.Delete(ctx, id)
+			err := store.Delete(ctx, id, 0)
 
 			// Assert
 			if tt.wantErr != nil {
@@ -502,7 +505,7 @@ func TestMemoryStore_Delete_ContextCancellation(t *testing.T) {
 	cancel() // Cancel immediately
 
 	// Act
-	err := store.Delete(ctx, "some-id")
+	err := store.Delete(ctx, "some-id", 0)
 
 	// Assert
 	if err == nil {
@@ -510,6 +513,52 @@ func TestMemoryStore_Delete_ContextCancellation(t *testing.T) {
 	}
 }
 
+func TestMemoryStore_Versioning(t *testing.T) {
+	// Arrange
+	store := NewMemoryStore()
+	ctx := context.Background()
+	created, err := store.Create(ctx, &model.Item{Name: "Versioned", Price: 1})
+	if err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+	if created.Version != 1 {
+		t.Fatalf("Create() Version = %d, want 1", created.Version)
+	}
+
+	// Act & Assert: unconditional update increments the version.
+	updated, err := store.Update(ctx, created.ID, &model.Item{Name: "V2", Price: 2}, 0)
+	if err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("Update() Version = %d, want 2", updated.Version)
+	}
+
+	// Conditional update against the current version succeeds.
+	updated, err = store.Update(ctx, created.ID, &model.Item{Name: "V3", Price: 3}, 2)
+	if err != nil {
+		t.Fatalf("Update() with current version unexpected error: %v", err)
+	}
+	if updated.Version != 3 {
+		t.Errorf("Update() Version = %d, want 3", updated.Version)
+	}
+
+	// Stale versions are rejected without modifying the item.
+	if _, err := store.Update(ctx, created.ID, &model.Item{Name: "Lost", Price: 4}, 1); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Update() with stale version error = %v, want %v", err, ErrVersionConflict)
+	}
+	if err := store.Delete(ctx, created.ID, 2); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Delete() with stale version error = %v, want %v", err, ErrVersionConflict)
+	}
+	if got, _ := store.Get(ctx, created.ID); got.Name != "V3" || got.Version != 3 {
+		t.Errorf("Get() = %+v, want unchanged V3 at version 3", got)
+	}
+
+	if err := store.Delete(ctx, created.ID, 3); err != nil {
+		t.Errorf("Delete() with current version unexpected error: %v", err)
+	}
+}
+
 func TestMemoryStore_ConcurrentAccess(t *testing.T) {
 	// Arrange
 	store := NewMemoryStore()
@@ -540,17 +589,17 @@ func TestMemoryStore_ConcurrentAccess(t *testing.T) {
 				_, _ = store.Get(ctx, created.ID)
 
 				// List
-				_, _ = store.List(ctx)
+				_, _ = store.List(ctx, ListOptions{})
 
 				// Update
 				update := &model.Item{
 					Name:  "Updated Item",
 					Price: float64(id * j * 2),
 				}
-				_, _ = store.Update(ctx, created.ID, update)
+				_, _ = store.Update(ctx, created.ID, update, 0)
 
 				// Delete
-				_ = store.Delete(ctx, created.ID)
+				_ = store.Delete(ctx, created.ID, 0)
 			}
 		}(i)
 	}
@@ -558,10 +607,11 @@ func TestMemoryStore_ConcurrentAccess(t *testing.T) {
 	wg.Wait()
 
 	// Assert - No panic occurred and store is in consistent state
-	items, err := store.List(ctx)
+	page, err := store.List(ctx, ListOptions{})
 	if err != nil {
 		t.Fatalf("List() after concurrent access failed: %v", err)
 	}
+	items := page.Items
 
 	// All items should be deleted
 	if len(items) != 0 {
@@ -591,7 +641,7 @@ func TestMemoryStore_ConcurrentReads(t *testing.T) {
 		go func() {
 			defer wg.Done()
 			for j := 0; j < 100; j++ {
-				_, _ = store.List(ctx)
+				_, _ = store.List(ctx, ListOptions{})
 			}
 		}()
 	}
@@ -599,10 +649,11 @@ func TestMemoryStore_ConcurrentReads(t *testing.T) {
 	wg.Wait()
 
 	// Assert - No panic occurred
-	items, err := store.List(ctx)
+	page, err := store.List(ctx, ListOptions{})
 	if err != nil {
 		t.Fatalf("List() after concurrent reads failed: %v", err)
 	}
+	items := page.Items
 	if len(items) != 10 {
 		t.Errorf("Expected 10 items, got %d", len(items))
 	}
@@ -631,10 +682,11 @@ func TestMemoryStore_ConcurrentWrites(t *testing.T) {
 	wg.Wait()
 
 	// Assert
-	items, err := store.List(ctx)
+	page, err := store.List(ctx, ListOptions{})
 	if err != nil {
 		t.Fatalf("List() after concurrent writes failed: %v", err)
 	}
+	items := page.Items
 	if len(items) != numGoroutines {
 		t.Errorf("Expected %d items, got %d", numGoroutines, len(items))
 	}
@@ -706,7 +758,7 @@ func TestMemoryStore_Timestamps(t *testing.T) {
 		Name:  "Updated Item",
 		Price: 19.99,
 	}
-	updated, err := store.Update(ctx, created.ID, update)
+	updated, err := store.Update(ctx, created.ID, update, 0)
 	if err != nil {
 		t.Fatalf("Update() failed: %v", err)
 	}
@@ -760,7 +812,7 @@ func TestMemoryStore_Update_NilItem_ReturnsErrNilItem(t *testing.T) {
 	}
 
 	// Act
-	updated, err := store.Update(ctx, created.ID, nil)
+	updated, err := store.Update(ctx, created.ID, nil, 0)
 
 	// Assert
 	if err == nil {
@@ -0,0 +1,569 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" database/sql driver
+
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+)
+
+// postgresDriverName is the database/sql driver name registered by pgx/stdlib.
+const postgresDriverName = "pgx"
+
+// PostgreSQL error codes mapped onto store sentinel errors.
+// See https://www.postgresql.org/docs/current/errcodes-appendix.html.
+const (
+	pgCodeUniqueViolation = "23505"
+)
+
+// migrationLockID is the advisory lock key held while applying migrations so
+// that replicas starting concurrently do not race on schema changes.
+const migrationLockID = 7_262_010_001
+
+// postgresMigrations is the ordered list of schema migrations. The version of
+// a migration is its 1-based index; applied versions are recorded in the
+// schema_migrations table. Never edit or reorder existing entries — append.
+var postgresMigrations = []string{
+	`CREATE TABLE IF NOT EXISTS items (
+		id          UUID PRIMARY KEY,
+		name        VARCHAR(255) NOT NULL,
+		description VARCHAR(1000) NOT NULL DEFAULT '',
+		price       DOUBLE PRECISION NOT NULL,
+		created_at  TIMESTAMPTZ NOT NULL,
+		updated_at  TIMESTAMPTZ NOT NULL
+	)`,
+	`ALTER TABLE items ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1`,
+	// IDs chosen by clients need not be UUIDs. Since then, looking up an ID
+	// that is not a UUID finds no item instead of failing with 22P02.
+	`ALTER TABLE items ALTER COLUMN id TYPE VARCHAR(255)`,
+}
+
+// itemColumns is the column list shared by all item SELECT statements.
+const itemColumns = "id, name, description, price, created_at, updated_at, version"
+
+// PostgresConfig holds the connection settings for a PostgresStore.
+type PostgresConfig struct {
+	// DSN is a PostgreSQL connection string (URL or key/value form).
+	DSN string
+
+	// MaxOpenConns limits open connections in the pool (0 = unlimited).
+	MaxOpenConns int
+
+	// MaxIdleConns limits idle connections kept in the pool.
+	MaxIdleConns int
+
+	// ConnMaxLifetime bounds how long a pooled connection is reused
+	// (0 = forever).
+	ConnMaxLifetime time.Duration
+}
+
+// PostgresStore implements Store interface on top of a PostgreSQL database.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// OpenPostgres opens a connection pool with the given settings, verifies
+// connectivity and applies pending schema migrations.
+func OpenPostgres(ctx context.Context, cfg PostgresConfig) (*PostgresStore, error) {
+	db, err := sql.Open(postgresDriverName, cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("opening postgres: %w", err)
+	}
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("connecting to postgres: %w", err)
+	}
+
+	s, err := NewPostgresStore(ctx, db)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// NewPostgresStore creates a PostgresStore using an already opened database
+// handle and applies pending schema migrations. The store takes ownership of
+// db; call Close to release it.
+func NewPostgresStore(ctx context.Context, db *sql.DB) (*PostgresStore, error) {
+	s := &PostgresStore{db: db}
+
+	if err := s.migrate(ctx); err != nil {
+		return nil, fmt.Errorf("applying postgres migrations: %w", err)
+	}
+
+	return s, nil
+}
+
+// Close releases the underlying connection pool.
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
+
+// migrate applies every migration newer than the recorded schema version in
+// a single transaction guarded by an advisory lock.
+func (s *PostgresStore) migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning migration transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+
+	var current int
+	if err := tx.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(version), 0) FROM schema_migrations",
+	).Scan(&current); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+
+	for i := current; i < len(postgresMigrations); i++ {
+		version := i + 1
+		if _, err := tx.ExecContext(ctx, postgresMigrations[i]); err != nil {
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO schema_migrations (version) VALUES ($1)", version,
+		); err != nil {
+			return fmt.Errorf("recording migration %d: %w", version, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing migrations: %w", err)
+	}
+
+	return nil
+}
+
+// postgresSortColumns maps sort fields onto item columns.
+var postgresSortColumns = map[SortField]string{
+	SortByCreatedAt: "created_at",
+	SortByName:      "name",
+	SortByPrice:     "price",
+}
+
+// List returns the page of items selected by opts. Filters, ordering and
+// pagination are pushed down to PostgreSQL; cursors use keyset pagination.
+func (s *PostgresStore) List(ctx context.Context, opts ListOptions) (*ListPage, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("list items: %w", err)
+	}
+
+	q := &postgresQuery{}
+	if opts.NamePrefix != "" {
+		q.where("name LIKE " + q.arg(escapeLike(opts.NamePrefix)+"%") + " ESCAPE '\\'")
+	}
+	if opts.MinPrice != nil {
+		q.where("price >= " + q.arg(*opts.MinPrice))
+	}
+	if opts.MaxPrice != nil {
+		q.where("price <= " + q.arg(*opts.MaxPrice))
+	}
+
+	page := &ListPage{}
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM items"+q.whereClause(), q.args...,
+	).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("list items: %w", mapPostgresError(err))
+	}
+
+	column := postgresSortColumns[opts.sortFieldOrDefault()]
+	direction, keysetOp := "ASC", ">"
+	if opts.Descending {
+		direction, keysetOp = "DESC", "<"
+	}
+
+	if opts.Cursor != "" {
+		cursor, err := decodeListCursor(&opts)
+		if err != nil {
+			return nil, fmt.Errorf("list items: %w", err)
+		}
+		var value any
+		switch cursor.SortBy {
+		case SortByName:
+			value = cursor.Name
+		case SortByPrice:
+			value = cursor.Price
+		default:
+			value = cursor.CreatedAt
+		}
+		q.where(fmt.Sprintf("(%s, id) %s (%s, %s)", column, keysetOp, q.arg(value), q.arg(cursor.ID)))
+	}
+
+	query := "SELECT " + itemColumns + " FROM items" + q.whereClause() +
+		fmt.Sprintf(" ORDER BY %s %s, id %s", column, direction, direction)
+	if opts.Limit > 0 {
+		// Fetch one extra row to learn whether another page follows.
+		query += " LIMIT " + q.arg(opts.Limit+1)
+	}
+	if opts.Offset > 0 {
+		query += " OFFSET " + q.arg(opts.Offset)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("list items: %w", mapPostgresError(err))
+	}
+	defer rows.Close()
+
+	page.Items = make([]model.Item, 0)
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list items: %w", err)
+		}
+		page.Items = append(page.Items, *item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list items: %w", mapPostgresError(err))
+	}
+
+	if opts.Limit > 0 && len(page.Items) > opts.Limit {
+		page.Items = page.Items[:opts.Limit]
+		page.NextCursor = newListCursor(&opts, &page.Items[opts.Limit-1]).encode()
+	}
+
+	return page, nil
+}
+
+// postgresQuery accumulates WHERE conditions and positional arguments.
+type postgresQuery struct {
+	conditions []string
+	args       []any
+}
+
+// arg appends a positional argument and returns its placeholder.
+func (q *postgresQuery) arg(v any) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+// where appends a condition joined with AND.
+func (q *postgresQuery) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+// whereClause renders the accumulated conditions, or "" when there are none.
+func (q *postgresQuery) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// escapeLike escapes LIKE wildcards so a prefix is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Get retrieves an item by its ID.
+func (s *PostgresStore) Get(ctx context.Context, id string) (*model.Item, error) {
+	if id == "" {
+		return nil, ErrInvalidID
+	}
+
+	row := s.db.QueryRowContext(ctx,
+		"SELECT "+itemColumns+" FROM items WHERE id = $1", id,
+	)
+
+	item, err := scanItem(row)
+	if err != nil {
+		return nil, fmt.Errorf("get item: %w", mapPostgresError(err))
+	}
+
+	return item, nil
+}
+
+// postgresQuerier is satisfied by both *sql.DB and *sql.Tx, so item writes
+// can run standalone or as part of a batch transaction.
+type postgresQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Create adds a new item to the store and returns the created item, with a
+// generated ID unless it has one.
+func (s *PostgresStore) Create(ctx context.Context, item *model.Item) (*model.Item, error) {
+	return createPostgresItem(ctx, s.db, item)
+}
+
+// Update modifies an existing item in the store. The version check and
+// increment happen in the same UPDATE statement, so concurrent writers
+// cannot both succeed against the same expected version.
+func (s *PostgresStore) Update(
+	ctx context.Context, id string, item *model.Item, expectedVersion int64,
+) (*model.Item, error) {
+	return updatePostgresItem(ctx, s.db, id, item, expectedVersion)
+}
+
+// Upsert updates the item with id or creates it with that ID in a single
+// INSERT ... ON CONFLICT statement, so concurrent upserts of a new ID cannot
+// both create it.
+func (s *PostgresStore) Upsert(ctx context.Context, id string, item *model.Item) (*model.Item, bool, error) {
+	if id == "" {
+		return nil, false, ErrInvalidID
+	}
+
+	if item == nil {
+		return nil, false, fmt.Errorf("upsert item: %w", ErrNilItem)
+	}
+
+	upserted := model.Item{
+		ID:          id,
+		Name:        item.Name,
+		Description: item.Description,
+		Price:       item.Price,
+		UpdatedAt:   postgresNow(),
+	}
+
+	// xmax is 0 for rows inserted by the statement and set for updated rows.
+	var created bool
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO items (`+itemColumns+`) VALUES ($1, $2, $3, $4, $5, $5, 1)
+		ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description,
+		price = EXCLUDED.price, updated_at = EXCLUDED.updated_at, version = items.version + 1
+		RETURNING created_at, version, xmax = 0`,
+		id, upserted.Name, upserted.Description, upserted.Price, upserted.UpdatedAt,
+	).Scan(&upserted.CreatedAt, &upserted.Version, &created)
+	if err != nil {
+		return nil, false, fmt.Errorf("upsert item: %w", mapPostgresError(err))
+	}
+	upserted.CreatedAt = upserted.CreatedAt.UTC()
+
+	return &upserted, created, nil
+}
+
+// Delete removes an item from the store by its ID.
+func (s *PostgresStore) Delete(ctx context.Context, id string, expectedVersion int64) error {
+	return deletePostgresItem(ctx, s.db, id, expectedVersion)
+}
+
+// Batch applies ops in order. An atomic batch runs in one transaction that
+// is rolled back at the first failing operation; a best-effort batch runs
+// every operation on its own.
+func (s *PostgresStore) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	if !atomic {
+		results := make([]BatchResult, len(ops))
+		for i := range ops {
+			item, err := applyPostgresBatchOp(ctx, s.db, &ops[i])
+			results[i] = BatchResult{Item: item, Err: err}
+		}
+		return results, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("batch items: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	results := make([]BatchResult, len(ops))
+	for i := range ops {
+		item, err := applyPostgresBatchOp(ctx, tx, &ops[i])
+		if err != nil {
+			abortBatch(results, i)
+			results[i] = BatchResult{Err: err}
+			return results, nil
+		}
+		results[i] = BatchResult{Item: item}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("batch items: %w", err)
+	}
+
+	return results, nil
+}
+
+// applyPostgresBatchOp runs a single batch operation through q.
+func applyPostgresBatchOp(ctx context.Context, q postgresQuerier, op *BatchOp) (*model.Item, error) {
+	switch op.Type {
+	case BatchCreate:
+		return createPostgresItem(ctx, q, op.Item)
+	case BatchUpdate:
+		return updatePostgresItem(ctx, q, op.ID, op.Item, op.ExpectedVersion)
+	case BatchDelete:
+		return nil, deletePostgresItem(ctx, q, op.ID, op.ExpectedVersion)
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidBatchOp, op.Type)
+	}
+}
+
+func createPostgresItem(ctx context.Context, q postgresQuerier, item *model.Item) (*model.Item, error) {
+	if item == nil {
+		return nil, fmt.Errorf("create item: %w", ErrNilItem)
+	}
+
+	now := postgresNow()
+	newItem := model.Item{
+		ID:          itemID(item),
+		Name:        item.Name,
+		Description: item.Description,
+		Price:       item.Price,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Version:     1,
+	}
+
+	if _, err := q.ExecContext(ctx,
+		"INSERT INTO items ("+itemColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		newItem.ID, newItem.Name, newItem.Description, newItem.Price,
+		newItem.CreatedAt, newItem.UpdatedAt, newItem.Version,
+	); err != nil {
+		return nil, fmt.Errorf("create item: %w", mapPostgresError(err))
+	}
+
+	return &newItem, nil
+}
+
+func updatePostgresItem(
+	ctx context.Context, q postgresQuerier, id string, item *model.Item, expectedVersion int64,
+) (*model.Item, error) {
+	if id == "" {
+		return nil, ErrInvalidID
+	}
+
+	if item == nil {
+		return nil, fmt.Errorf("update item: %w", ErrNilItem)
+	}
+
+	updatedItem := model.Item{
+		ID:          id,
+		Name:        item.Name,
+		Description: item.Description,
+		Price:       item.Price,
+		UpdatedAt:   postgresNow(),
+	}
+
+	err := q.QueryRowContext(ctx,
+		`UPDATE items SET name = $2, description = $3, price = $4, updated_at = $5,
+		version = version + 1
+		WHERE id = $1 AND ($6::BIGINT = 0 OR version = $6) RETURNING created_at, version`,
+		id, updatedItem.Name, updatedItem.Description, updatedItem.Price,
+		updatedItem.UpdatedAt, expectedVersion,
+	).Scan(&updatedItem.CreatedAt, &updatedItem.Version)
+	if errors.Is(err, sql.ErrNoRows) && expectedVersion != 0 {
+		err = missOrConflict(ctx, q, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update item: %w", mapPostgresError(err))
+	}
+	updatedItem.CreatedAt = updatedItem.CreatedAt.UTC()
+
+	return &updatedItem, nil
+}
+
+func deletePostgresItem(ctx context.Context, q postgresQuerier, id string, expectedVersion int64) error {
+	if id == "" {
+		return ErrInvalidID
+	}
+
+	result, err := q.ExecContext(ctx,
+		"DELETE FROM items WHERE id = $1 AND ($2::BIGINT = 0 OR version = $2)",
+		id, expectedVersion,
+	)
+	if err != nil {
+		return fmt.Errorf("delete item: %w", mapPostgresError(err))
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete item: %w", err)
+	}
+
+	if affected == 0 {
+		if expectedVersion != 0 {
+			return fmt.Errorf("delete item: %w", mapPostgresError(missOrConflict(ctx, q, id)))
+		}
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// missOrConflict explains why a conditional write matched no row: the item
+// either does not exist (ErrNotFound) or has a different version
+// (ErrVersionConflict).
+func missOrConflict(ctx context.Context, q postgresQuerier, id string) error {
+	var exists bool
+	if err := q.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM items WHERE id = $1)", id,
+	).Scan(&exists); err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrVersionConflict
+	}
+	return ErrNotFound
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanItem reads a single item row selected with itemColumns.
+func scanItem(row rowScanner) (*model.Item, error) {
+	var item model.Item
+	if err := row.Scan(
+		&item.ID, &item.Name, &item.Description, &item.Price,
+		&item.CreatedAt, &item.UpdatedAt, &item.Version,
+	); err != nil {
+		return nil, err
+	}
+
+	item.CreatedAt = item.CreatedAt.UTC()
+	item.UpdatedAt = item.UpdatedAt.UTC()
+
+	return &item, nil
+}
+
+// postgresNow returns the current UTC time truncated to the microsecond
+// precision of TIMESTAMPTZ, so returned items match what is persisted.
+func postgresNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// mapPostgresError translates SQL and PostgreSQL errors into store sentinel
+// errors. Errors without a sentinel equivalent are returned unchanged.
+func mapPostgresError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgCodeUniqueViolation:
+			return ErrAlreadyExists
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,694 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/vyrodovalexey/restapi-example/internal/model"
+)
+
+// newMockPostgres returns a sqlmock-backed database with the migration
+// statements already expected for a fresh schema at the given version.
+func newMockPostgres(t *testing.T, currentVersion int) (*PostgresStore, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	expectMigrations(mock, currentVersion)
+
+	s, err := NewPostgresStore(context.Background(), db)
+	if err != nil {
+		t.Fatalf("NewPostgresStore() error = %v", err)
+	}
+
+	return s, mock
+}
+
+func expectMigrations(mock sqlmock.Sqlmock, currentVersion int) {
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).
+		WithArgs(migrationLockID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(MAX(version), 0) FROM schema_migrations")).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(currentVersion))
+	for i := currentVersion; i < len(postgresMigrations); i++ {
+		mock.ExpectExec(regexp.QuoteMeta(postgresMigrations[i])).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version) VALUES ($1)")).
+			WithArgs(i + 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+}
+
+func itemRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version"})
+}
+
+func TestNewPostgresStore_AppliesMigrations(t *testing.T) {
+	// Arrange & Act
+	_, mock := newMockPostgres(t, 0)
+
+	// Assert
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestNewPostgresStore_SchemaUpToDate(t *testing.T) {
+	// Arrange & Act
+	_, mock := newMockPostgres(t, len(postgresMigrations))
+
+	// Assert
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestNewPostgresStore_MigrationError(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnError(errors.New("permission denied"))
+
+	// Act
+	s, err := NewPostgresStore(context.Background(), db)
+
+	// Assert
+	if err == nil {
+		t.Fatal("NewPostgresStore() expected error, got nil")
+	}
+	if s != nil {
+		t.Error("NewPostgresStore() should return nil store on error")
+	}
+}
+
+func TestPostgresStore_List(t *testing.T) {
+	// Arrange
+	s, mock := newMockPostgres(t, len(postgresMigrations))
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM items")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery("SELECT .* FROM items ORDER BY created_at ASC, id ASC$").
+		WillReturnRows(itemRows().
+			AddRow("id-1", "First", "", 1.5, now, now, 1).
+			AddRow("id-2", "Second", "desc", 2.5, now, now, 1))
+
+	// Act
+	page, err := s.List(context.Background(), ListOptions{})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(page.Items) != 2 {
+		t.Fatalf("List() returned %d items, want 2", len(page.Items))
+	}
+	if page.Total != 2 {
+		t.Errorf("Total = %d, want 2", page.Total)
+	}
+	if page.Items[1].Description != "desc" {
+		t.Errorf("Description = %q, want %q", page.Items[1].Description, "desc")
+	}
+	if page.NextCursor != "" {
+		t.Errorf("NextCursor = %q, want empty", page.NextCursor)
+	}
+}
+
+func TestPostgresStore_List_FiltersAndPagination(t *testing.T) {
+	// Arrange
+	s, mock := newMockPostgres(t, len(postgresMigrations))
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	minPrice, maxPrice := 1.0, 10.0
+	opts := ListOptions{
+		Limit:      2,
+		SortBy:     SortByPrice,
+		Descending: true,
+		NamePrefix: "50%_off",
+		MinPrice:   &minPrice,
+		MaxPrice:   &maxPrice,
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT COUNT(*) FROM items WHERE name LIKE $1 ESCAPE '\' AND price >= $2 AND price <= $3`,
+	)).
+		WithArgs(`50\%\_off%`, minPrice, maxPrice).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY price DESC, id DESC LIMIT $4")).
+		WithArgs(`50\%\_off%`, minPrice, maxPrice, 3).
+		WillReturnRows(itemRows().
+			AddRow("id-1", "50%_off a", "", 9.0, now, now, 1).
+			AddRow("id-2", "50%_off b", "", 8.0, now, now, 1).
+			AddRow("id-3", "50%_off c", "", 7.0, now, now, 1))
+
+	// Act
+	page, err := s.List(context.Background(), opts)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(page.Items) != 2 {
+		t.Fatalf("List() returned %d items, want 2", len(page.Items))
+	}
+	if page.Total != 5 {
+		t.Errorf("Total = %d, want 5", page.Total)
+	}
+	if page.NextCursor == "" {
+		t.Fatal("NextCursor should be set when more rows follow")
+	}
+
+	// Follow the cursor: the keyset condition must start after id-2.
+	opts.Cursor = page.NextCursor
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM items")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	mock.ExpectQuery(regexp.QuoteMeta("AND (price, id) < ($4, $5) ORDER BY price DESC, id DESC LIMIT $6")).
+		WithArgs(`50\%\_off%`, minPrice, maxPrice, 8.0, "id-2", 3).
+		WillReturnRows(itemRows().AddRow("id-3", "50%_off c", "", 7.0, now, now, 1))
+
+	next, err := s.List(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("List() with cursor error = %v", err)
+	}
+	if len(next.Items) != 1 || next.NextCursor != "" {
+		t.Errorf("second page = %d items, cursor %q; want 1 item, no cursor", len(next.Items), next.NextCursor)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresStore_List_InvalidOptions(t *testing.T) {
+	// Arrange
+	s, _ := newMockPostgres(t, len(postgresMigrations))
+
+	// Act
+	_, err := s.List(context.Background(), ListOptions{SortBy: "color"})
+
+	// Assert
+	if !errors.Is(err, ErrInvalidListOptions) {
+		t.Errorf("List() error = %v, want %v", err, ErrInvalidListOptions)
+	}
+}
+
+func TestPostgresStore_Get(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		setup   func(mock sqlmock.Sqlmock)
+		wantErr error
+	}{
+		{
+			name: "found",
+			id:   "5b7c4a38-4f5a-4f5e-9d2a-0f4c1d1e2a3b",
+			setup: func(mock sqlmock.Sqlmock) {
+				now := time.Now()
+				mock.ExpectQuery("SELECT .* FROM items WHERE id = \\$1").
+					WithArgs("5b7c4a38-4f5a-4f5e-9d2a-0f4c1d1e2a3b").
+					WillReturnRows(itemRows().AddRow(
+						"5b7c4a38-4f5a-4f5e-9d2a-0f4c1d1e2a3b", "Item", "", 3.0, now, now, 4,
+					))
+			},
+		},
+		{
+			name: "not found",
+			id:   "5b7c4a38-4f5a-4f5e-9d2a-0f4c1d1e2a3b",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT .* FROM items WHERE id = \\$1").
+					WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrNotFound,
+		},
+		{
+			name:    "empty id",
+			id:      "",
+			setup:   func(sqlmock.Sqlmock) {},
+			wantErr: ErrInvalidID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			s, mock := newMockPostgres(t, len(postgresMigrations))
+			tt.setup(mock)
+
+			// Act
+			item, err := s.Get(context.Background(), tt.id)
+
+			// Assert
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Get() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if item.ID != tt.id {
+				t.Errorf("ID = %q, want %q", item.ID, tt.id)
+			}
+			if item.CreatedAt.Location() != time.UTC {
+				t.Error("CreatedAt should be normalized to UTC")
+			}
+		})
+	}
+}
+
+func TestPostgresStore_Create(t *testing.T) {
+	// Arrange
+	s, mock := newMockPostgres(t, len(postgresMigrations))
+	mock.ExpectExec("INSERT INTO items").
+		WithArgs(sqlmock.AnyArg(), "Widget", "A widget", 9.99, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Act
+	created, err := s.Create(context.Background(), &model.Item{
+		Name:        "Widget",
+		Description: "A widget",
+		Price:       9.99,
+	})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ID == "" {
+		t.Error("Create() should generate an ID")
+	}
+	if created.CreatedAt.IsZero() || !created.CreatedAt.Equal(created.UpdatedAt) {
+		t.Error("CreatedAt and UpdatedAt should be set to the same time")
+	}
+	if created.Version != 1 {
+		t.Errorf("Version = %d, want 1", created.Version)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresStore_Create_WithID(t *testing.T) {
+	// Arrange
+	s, mock := newMockPostgres(t, len(postgresMigrations))
+	mock.ExpectExec("INSERT INTO items").
+		WithArgs("sku-1", "Widget", "", 9.99, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Act
+	created, err := s.Create(context.Background(), &model.Item{ID: "sku-1", Name: "Widget", Price: 9.99})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ID != "sku-1" {
+		t.Errorf("ID = %q, want sku-1", created.ID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresStore_Upsert(t *testing.T) {
+	tests := []struct {
+		name        string
+		inserted    bool
+		version     int64
+		wantCreated bool
+	}{
+		{name: "created", inserted: true, version: 1, wantCreated: true},
+		{name: "updated", inserted: false, version: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			s, mock := newMockPostgres(t, len(postgresMigrations))
+			createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			mock.ExpectQuery("INSERT INTO items .* ON CONFLICT \\(id\\) DO UPDATE").
+				WithArgs("sku-1", "Widget", "", 9.99, sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"created_at", "version", "inserted"}).
+					AddRow(createdAt, tt.version, tt.inserted))
+
+			// Act
+			item, created, err := s.Upsert(context.Background(), "sku-1", &model.Item{Name: "Widget", Price: 9.99})
+
+			// Assert
+			if err != nil {
+				t.Fatalf("Upsert() error = %v", err)
+			}
+			if created != tt.wantCreated {
+				t.Errorf("created = %v, want %v", created, tt.wantCreated)
+			}
+			if item.ID != "sku-1" || item.Version != tt.version || !item.CreatedAt.Equal(createdAt) {
+				t.Errorf("Upsert() = %+v, want sku-1 at version %d", item, tt.version)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStore_Upsert_Errors(t *testing.T) {
+	// Arrange
+	s, mock := newMockPostgres(t, len(postgresMigrations))
+	mock.ExpectQuery("INSERT INTO items").WillReturnError(errors.New("connection reset"))
+
+	// Act
+	_, _, idErr := s.Upsert(context.Background(), "", &model.Item{Name: "Widget"})
+	_, _, nilErr := s.Upsert(context.Background(), "sku-1", nil)
+	_, _, dbErr := s.Upsert(context.Background(), "sku-1", &model.Item{Name: "Widget"})
+
+	// Assert
+	if !errors.Is(idErr, ErrInvalidID) {
+		t.Errorf("Upsert() without ID error = %v, want %v", idErr, ErrInvalidID)
+	}
+	if !errors.Is(nilErr, ErrNilItem) {
+		t.Errorf("Upsert() of nil error = %v, want %v", nilErr, ErrNilItem)
+	}
+	if dbErr == nil {
+		t.Error("Upsert() expected database error, got nil")
+	}
+}
+
+func TestPostgresStore_Create_UniqueViolation(t *testing.T) {
+	// Arrange
+	s, mock := newMockPostgres(t, len(postgresMigrations))
+	mock.ExpectExec("INSERT INTO items").
+		WillReturnError(&pgconn.PgError{Code: pgCodeUniqueViolation})
+
+	// Act
+	_, err := s.Create(context.Background(), &model.Item{Name: "Dup", Price: 1})
+
+	// Assert
+	if !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("Create() error = %v, want %v", err, ErrAlreadyExists)
+	}
+}
+
+func TestPostgresStore_Create_NilItem(t *testing.T) {
+	// Arrange
+	s, _ := newMockPostgres(t, len(postgresMigrations))
+
+	// Act
+	_, err := s.Create(context.Background(), nil)
+
+	// Assert
+	if !errors.Is(err, ErrNilItem) {
+		t.Errorf("Create() error = %v, want %v", err, ErrNilItem)
+	}
+}
+
+func TestPostgresStore_Update(t *testing.T) {
+	// Arrange
+	s, mock := newMockPostgres(t, len(postgresMigrations))
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery("UPDATE items SET").
+		WithArgs("id-1", "Renamed", "", 4.0, sqlmock.AnyArg(), int64(0)).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "version"}).AddRow(createdAt, 3))
+
+	// Act
+	updated, err := s.Update(context.Background(), "id-1", &model.Item{Name: "Renamed", Price: 4}, 0)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !updated.CreatedAt.Equal(createdAt) {
+		t.Errorf("CreatedAt = %v, want %v", updated.CreatedAt, createdAt)
+	}
+	if !updated.UpdatedAt.After(createdAt) {
+		t.Error("UpdatedAt should be refreshed")
+	}
+	if updated.Version != 3 {
+		t.Errorf("Version = %d, want 3", updated.Version)
+	}
+}
+
+func TestPostgresStore_Update_VersionMismatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		exists  bool
+		wantErr error
+	}{
+		{name: "stale version", exists: true, wantErr: ErrVersionConflict},
+		{name: "missing item", exists: false, wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			s, mock := newMockPostgres(t, len(postgresMigrations))
+			mock.ExpectQuery("UPDATE items SET").
+				WithArgs("id-1", "X", "", 0.0, sqlmock.AnyArg(), int64(2)).
+				WillReturnError(sql.ErrNoRows)
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM items WHERE id = $1)")).
+				WithArgs("id-1").
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tt.exists))
+
+			// Act
+			_, err := s.Update(context.Background(), "id-1", &model.Item{Name: "X"}, 2)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Update() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPostgresStore_Update_NotFound(t *testing.T) {
+	// Arrange
+	s, mock := newMockPostgres(t, len(postgresMigrations))
+	mock.ExpectQuery("UPDATE items SET").WillReturnError(sql.ErrNoRows)
+
+	// Act
+	_, err := s.Update(context.Background(), "missing", &model.Item{Name: "X"}, 0)
+
+	// Assert
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestPostgresStore_Delete(t *testing.T) {
+	tests := []struct {
+		name     string
+		affected int64
+		wantErr  error
+	}{
+		{name: "deleted", affected: 1},
+		{name: "not found", affected: 0, wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			s, mock := newMockPostgres(t, len(postgresMigrations))
+			mock.ExpectExec(regexp.QuoteMeta("DELETE FROM items WHERE id = $1")).
+				WithArgs("id-1", int64(0)).
+				WillReturnResult(sqlmock.NewResult(0, tt.affected))
+
+			// Act
+			err := s.Delete(context.Background(), "id-1", 0)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Delete() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPostgresStore_Delete_VersionConflict(t *testing.T) {
+	// Arrange
+	s, mock := newMockPostgres(t, len(postgresMigrations))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM items WHERE id = $1")).
+		WithArgs("id-1", int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS")).
+		WithArgs("id-1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	// Act
+	err := s.Delete(context.Background(), "id-1", 5)
+
+	// Assert
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Delete() error = %v, want %v", err, ErrVersionConflict)
+	}
+}
+
+func TestPostgresStore_Batch_Atomic(t *testing.T) {
+	// Arrange
+	s, mock := newMockPostgres(t, len(postgresMigrations))
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO items").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("UPDATE items SET").
+		WithArgs("id-1", "Renamed", "", 4.0, sqlmock.AnyArg(), int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "version"}).AddRow(createdAt, 2))
+	mock.ExpectCommit()
+
+	// Act
+	results, err := s.Batch(context.Background(), []BatchOp{
+		{Type: BatchCreate, Item: &model.Item{Name: "New", Price: 1}},
+		{Type: BatchUpdate, ID: "id-1", Item: &model.Item{Name: "Renamed", Price: 4}, ExpectedVersion: 1},
+	}, true)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Batch() error = %v", err)
+	}
+	for i, r := range results {
+		if r.Err != nil || r.Item == nil {
+			t.Errorf("results[%d] = %+v, want item", i, r)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresStore_Batch_AtomicRollback(t *testing.T) {
+	// Arrange
+	s, mock := newMockPostgres(t, len(postgresMigrations))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO items").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM items WHERE id = $1")).
+		WithArgs("missing", int64(0)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	// Act
+	results, err := s.Batch(context.Background(), []BatchOp{
+		{Type: BatchCreate, Item: &model.Item{Name: "New", Price: 1}},
+		{Type: BatchDelete, ID: "missing"},
+		{Type: BatchDelete, ID: "id-1"},
+	}, true)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Batch() error = %v", err)
+	}
+	wantErrs := []error{ErrBatchAborted, ErrNotFound, ErrBatchAborted}
+	for i, want := range wantErrs {
+		if !errors.Is(results[i].Err, want) || results[i].Item != nil {
+			t.Errorf("results[%d] = %+v, want error %v", i, results[i], want)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresStore_Batch_BestEffort(t *testing.T) {
+	// Arrange
+	s, mock := newMockPostgres(t, len(postgresMigrations))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM items WHERE id = $1")).
+		WithArgs("missing", int64(0)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO items").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Act
+	results, err := s.Batch(context.Background(), []BatchOp{
+		{Type: BatchDelete, ID: "missing"},
+		{Type: BatchCreate, Item: &model.Item{Name: "New", Price: 1}},
+		{Type: "rename"},
+	}, false)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Batch() error = %v", err)
+	}
+	if !errors.Is(results[0].Err, ErrNotFound) {
+		t.Errorf("results[0].Err = %v, want %v", results[0].Err, ErrNotFound)
+	}
+	if results[1].Err != nil || results[1].Item == nil {
+		t.Errorf("results[1] = %+v, want created item", results[1])
+	}
+	if !errors.Is(results[2].Err, ErrInvalidBatchOp) {
+		t.Errorf("results[2].Err = %v, want %v", results[2].Err, ErrInvalidBatchOp)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresStore_ContextCancellation(t *testing.T) {
+	// Arrange
+	s, _ := newMockPostgres(t, len(postgresMigrations))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Act
+	_, err := s.List(ctx, ListOptions{})
+
+	// Assert
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("List() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestMapPostgresError(t *testing.T) {
+	other := errors.New("connection reset")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "no rows", err: sql.ErrNoRows, want: ErrNotFound},
+		{name: "unique violation", err: &pgconn.PgError{Code: pgCodeUniqueViolation}, want: ErrAlreadyExists},
+		{name: "other pg error", err: &pgconn.PgError{Code: "40001"}, want: nil},
+		{name: "passthrough", err: other, want: other},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mapPostgresError(tt.err)
+			want := tt.want
+			if want == nil {
+				want = tt.err
+			}
+			if !errors.Is(got, want) {
+				t.Errorf("mapPostgresError() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestOpenPostgres_InvalidDSN(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// Act
+	s, err := OpenPostgres(ctx, PostgresConfig{DSN: "postgres://127.0.0.1:1/none?connect_timeout=1"})
+
+	// Assert
+	if err == nil {
+		_ = s.Close()
+		t.Fatal("OpenPostgres() expected error for unreachable server, got nil")
+	}
+}
@@ -14,22 +14,53 @@ var (
 	ErrAlreadyExists = errors.New("item already exists")
 	ErrInvalidID     = errors.New("invalid item ID")
 	ErrNilItem       = errors.New("item cannot be nil")
+
+	// ErrVersionConflict is returned by Update and Delete when the stored
+	// item version differs from the expected version.
+	ErrVersionConflict = errors.New("item version conflict")
 )
 
 // Store defines the interface for item storage operations.
 type Store interface {
-	// List returns all items from the store.
-	List(ctx context.Context) ([]model.Item, error)
+	// List returns the page of items selected by opts. It returns an error
+	// wrapping ErrInvalidListOptions or ErrInvalidCursor for bad options.
+	List(ctx context.Context, opts ListOptions) (*ListPage, error)
 
 	// Get retrieves an item by its ID.
 	Get(ctx context.Context, id string) (*model.Item, error)
 
-	// Create adds a new item to the store and returns the created item with generated ID.
+	// Create adds a new item to the store and returns the created item. The
+	// item keeps its ID if it has one, failing with ErrAlreadyExists if the
+	// ID is taken, and is given a new UUID otherwise.
 	Create(ctx context.Context, item *model.Item) (*model.Item, error)
 
-	// Update modifies an existing item in the store.
-	Update(ctx context.Context, id string, item *model.Item) (*model.Item, error)
+	// Update modifies an existing item in the store and increments its
+	// version. A non-zero expectedVersion makes the update conditional: it
+	// fails with ErrVersionConflict unless the stored version matches.
+	Update(ctx context.Context, id string, item *model.Item, expectedVersion int64) (*model.Item, error)
+
+	// Upsert updates the item with the given ID, as an unconditional Update,
+	// or creates it with that ID if there is none. It reports whether the
+	// item was created.
+	Upsert(ctx context.Context, id string, item *model.Item) (*model.Item, bool, error)
+
+	// Delete removes an item from the store by its ID. A non-zero
+	// expectedVersion makes the delete conditional, as for Update.
+	Delete(ctx context.Context, id string, expectedVersion int64) error
+
+	// Batch applies ops in order and returns one result per operation. In
+	// atomic mode either every operation is applied or none is: after the
+	// first failure all other results carry ErrBatchAborted. Otherwise each
+	// operation succeeds or fails on its own. The returned error is reserved
+	// for failures of the batch as a whole, such as a cancelled context.
+	Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error)
+}
 
-	// Delete removes an item from the store by its ID.
-	Delete(ctx context.Context, id string) error
+// checkVersion returns ErrVersionConflict when expected is set and differs
+// from the stored version.
+func checkVersion(stored, expected int64) error {
+	if expected != 0 && stored != expected {
+		return ErrVersionConflict
+	}
+	return nil
 }
@@ -61,6 +61,10 @@ func startOIDCServer(t *testing.T) *e2eServer {
 func startMTLSServer(t *testing.T) *e2eServer {
 	t.Helper()
 	skipIfCertsUnavailable(t)
+	authenticator, err := auth.NewMTLSAuthenticator(auth.MTLSConfig{})
+	if err != nil {
+		t.Fatalf("creating mTLS authenticator: %v", err)
+	}
 	return startServer(t, &config.Config{
 		MetricsEnabled: true,
 		AuthMode:       "mtls",
@@ -69,7 +73,7 @@ func startMTLSServer(t *testing.T) *e2eServer {
 		TLSKeyPath:     serverKeyPath(),
 		TLSCAPath:      caCertPath(),
 		TLSClientAuth:  "require",
-	}, auth.NewMTLSAuthenticator())
+	}, authenticator)
 }
 
 func apiKeyHeaders() map[string]string {
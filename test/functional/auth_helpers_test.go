@@ -134,8 +134,13 @@ func NewFunctionalMTLSServer(t *testing.T) *mtlsTestServer {
 		TLSClientAuth:   "require",
 	}
 
+	authenticator, err := auth.NewMTLSAuthenticator(auth.MTLSConfig{})
+	if err != nil {
+		t.Fatalf("creating mTLS authenticator: %v", err)
+	}
+
 	itemStore := store.NewMemoryStore()
-	srv := server.New(cfg, zap.NewNop(), itemStore, auth.NewMTLSAuthenticator())
+	srv := server.New(cfg, zap.NewNop(), itemStore, authenticator)
 
 	go func() {
 		if err := srv.Start(); err != nil && err != http.ErrServerClosed {
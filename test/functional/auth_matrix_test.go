@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"testing"
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 // TestFunctional_AUTH_OIDC_GraphQL verifies the GraphQL endpoint is reachable
@@ -89,23 +91,27 @@ func TestFunctional_AUTH_MULTI_GraphQL(t *testing.T) {
 	AssertStatusCode(t, resp, http.StatusUnauthorized)
 }
 
-// TestFunctional_AUTH_WS_UpgradeSkipsAuth documents and verifies the current
-// behaviour that WebSocket upgrade requests bypass the auth middleware (the
-// Auth middleware explicitly skips Upgrade: websocket). The upgrade should
-// succeed even on an auth-enabled server.
-func TestFunctional_AUTH_WS_UpgradeSkipsAuth(t *testing.T) {
-	LogTestStart(t, "FT-AUTH-WS", "WebSocket upgrade bypasses auth")
+// TestFunctional_AUTH_WS_RequiresAuth verifies that WebSocket connections are
+// authenticated on an auth-enabled server: credentials on the upgrade request
+// are accepted, invalid ones rejected with 401, and a connection without
+// credentials is closed when its first message is not a valid auth message.
+func TestFunctional_AUTH_WS_RequiresAuth(t *testing.T) {
+	LogTestStart(t, "FT-AUTH-WS", "WebSocket connections require auth")
 	defer LogTestEnd(t, "FT-AUTH-WS")
 
 	ts := NewTestServerWithAPIKeyAuth(t, testAPIKeyConfig)
 	ts.Start()
 	defer ts.Stop()
 
-	// No auth header on the WS handshake; upgrade should still succeed.
-	wsClient, err := NewWebSocketClient(t, ts.WSURL+"/ws")
+	dialer := websocket.Dialer{HandshakeTimeout: DefaultWebSocketTimeout}
+
+	// Valid API key on the handshake: random values are streamed.
+	conn, resp, err := dialer.Dial(ts.WSURL+"/ws", http.Header{"X-API-Key": []string{testAPIKey}})
 	if err != nil {
-		t.Fatalf("WebSocket upgrade failed on auth-enabled server: %v", err)
+		t.Fatalf("WebSocket upgrade with valid API key failed: %v", err)
 	}
+	resp.Body.Close()
+	wsClient := &WebSocketClient{conn: conn, t: t}
 	defer wsClient.Close()
 
 	msg, err := wsClient.ReadMessage(3 * time.Second)
@@ -115,4 +121,32 @@ func TestFunctional_AUTH_WS_UpgradeSkipsAuth(t *testing.T) {
 	if msg.Type != "random_value" {
 		t.Errorf("expected message type 'random_value', got %q", msg.Type)
 	}
+
+	// Invalid API key on the handshake: 401.
+	_, resp, err = dialer.Dial(ts.WSURL+"/ws", http.Header{"X-API-Key": []string{"wrong-key"}})
+	if err == nil {
+		t.Fatal("WebSocket upgrade with invalid API key succeeded")
+	}
+	if resp == nil {
+		t.Fatalf("expected HTTP response for rejected upgrade, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status %d for invalid API key, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	// No credentials: the first message must authenticate.
+	anonymous, err := NewWebSocketClient(t, ts.WSURL+"/ws")
+	if err != nil {
+		t.Fatalf("WebSocket upgrade without credentials failed: %v", err)
+	}
+	defer anonymous.Close()
+
+	if err := anonymous.conn.WriteJSON(map[string]string{"type": "subscribe"}); err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+	_, err = anonymous.ReadMessage(3 * time.Second)
+	if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Errorf("expected close %d, got %v", websocket.ClosePolicyViolation, err)
+	}
 }
@@ -1,7 +1,8 @@
 //go:build integration
 
 // Package integration_test contains integration tests that exercise the
-// server against the live docker-compose environment (Vault PKI + Keycloak).
+// server against the live docker-compose environment (Vault PKI, Keycloak
+// and Redis).
 //
 // Unlike functional tests, these tests start in-process server instances
 // configured per authentication mode and drive real credentials obtained
@@ -44,6 +45,7 @@ import (
 const (
 	EnvKeycloakURL = "INTEGRATION_KEYCLOAK_URL"
 	EnvVaultAddr   = "VAULT_ADDR"
+	EnvRedisAddr   = "INTEGRATION_REDIS_ADDR"
 
 	EnvAPIKey    = "INTEGRATION_API_KEY"
 	EnvBasicUser = "INTEGRATION_BASIC_USER"
@@ -68,6 +70,7 @@ const (
 const (
 	DefaultKeycloakURL = "http://localhost:8090"
 	DefaultVaultAddr   = "http://localhost:8200"
+	DefaultRedisAddr   = "localhost:6379"
 
 	DefaultAPIKey      = "test-key"
 	DefaultAPIKeyName  = "test-service"
@@ -129,6 +129,11 @@ func startMTLSServer(t *testing.T) *testServer {
 	t.Helper()
 	skipIfCertsUnavailable(t)
 
+	authenticator, err := auth.NewMTLSAuthenticator(auth.MTLSConfig{})
+	if err != nil {
+		t.Fatalf("creating mTLS authenticator: %v", err)
+	}
+
 	return startServer(t, &config.Config{
 		MetricsEnabled: true,
 		AuthMode:       "mtls",
@@ -137,7 +142,7 @@ func startMTLSServer(t *testing.T) *testServer {
 		TLSKeyPath:     serverKeyPath(),
 		TLSCAPath:      caCertPath(),
 		TLSClientAuth:  "require",
-	}, auth.NewMTLSAuthenticator())
+	}, authenticator)
 }
 
 // ----------------------------------------------------------------------------
@@ -0,0 +1,120 @@
+//go:build integration
+
+package integration_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/vyrodovalexey/restapi-example/internal/auth"
+	"github.com/vyrodovalexey/restapi-example/internal/config"
+	"github.com/vyrodovalexey/restapi-example/internal/ratelimit"
+	"github.com/vyrodovalexey/restapi-example/internal/redis"
+)
+
+// redisAddr returns the Redis address, skipping the test when nothing
+// listens on it.
+func redisAddr(t *testing.T) string {
+	t.Helper()
+
+	addr := getEnvOrDefault(EnvRedisAddr, DefaultRedisAddr)
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		t.Skipf("Redis unavailable at %s: %v", addr, err)
+	}
+	_ = conn.Close()
+	return addr
+}
+
+// TestIntegration_RedisLimiter verifies that limiters sharing a Redis server
+// share their token buckets (RL-REDIS-1).
+func TestIntegration_RedisLimiter(t *testing.T) {
+	addr := redisAddr(t)
+	prefix := fmt.Sprintf("ratelimit-test:%d:", time.Now().UnixNano())
+	replicas := []*ratelimit.RedisLimiter{
+		ratelimit.NewRedisLimiter(ratelimit.RedisConfig{Config: redis.Config{Addr: addr}, Prefix: prefix}),
+		ratelimit.NewRedisLimiter(ratelimit.RedisConfig{Config: redis.Config{Addr: addr}, Prefix: prefix}),
+	}
+	for _, limiter := range replicas {
+		t.Cleanup(func() { _ = limiter.Close() })
+	}
+	limit := ratelimit.Limit{Requests: 3, Period: time.Hour, Burst: 3}
+
+	var allowed []bool
+	for i := range 4 {
+		result, err := replicas[i%2].Allow(context.Background(), "client", limit)
+		if err != nil {
+			t.Fatalf("RL-REDIS-1: Allow() error = %v", err)
+		}
+		allowed = append(allowed, result.Allowed)
+	}
+
+	if fmt.Sprint(allowed) != "[true true true false]" {
+		t.Errorf("RL-REDIS-1: allowed = %v, want [true true true false]", allowed)
+	}
+}
+
+// TestIntegration_RateLimitMode verifies that two servers using the Redis
+// backend limit a client together and answer with 429 and the RateLimit
+// headers once its bucket is empty (RL-REDIS-2).
+func TestIntegration_RateLimitMode(t *testing.T) {
+	addr := redisAddr(t)
+	// A key name per run keeps the buckets of earlier runs out of the way.
+	keys := fmt.Sprintf("rate-limit-key:rl-%d", time.Now().UnixNano())
+	startReplica := func() *testServer {
+		a, err := auth.NewAPIKeyAuthenticator(keys)
+		if err != nil {
+			t.Fatalf("Failed to create API key authenticator: %v", err)
+		}
+		return startServer(t, &config.Config{
+			AuthMode:           "apikey",
+			APIKeys:            keys,
+			RateLimitEnabled:   true,
+			RateLimitBackend:   "redis",
+			RateLimitRedisAddr: addr,
+			RateLimitRead:      "3/1h",
+			RateLimitWrite:     "0",
+			RateLimitGraphQL:   "0",
+		}, a)
+	}
+	replicas := []*testServer{startReplica(), startReplica()}
+	client := newHTTPClient()
+
+	var statuses []int
+	var last *http.Response
+	for i := range 4 {
+		req, err := http.NewRequest(http.MethodGet, replicas[i%2].baseURL+"/api/v1/items", nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set(auth.APIKeyHeader, "rate-limit-key")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		statuses = append(statuses, resp.StatusCode)
+		last = resp
+	}
+
+	want := fmt.Sprint([]int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests})
+	if fmt.Sprint(statuses) != want {
+		t.Fatalf("RL-REDIS-2: statuses = %v, want %s", statuses, want)
+	}
+	for name, value := range map[string]string{
+		"RateLimit-Limit":     "3",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Policy":    "3;w=3600;burst=3",
+	} {
+		if got := last.Header.Get(name); got != value {
+			t.Errorf("RL-REDIS-2: %s = %q, want %q", name, got, value)
+		}
+	}
+	if last.Header.Get("Retry-After") == "" {
+		t.Error("RL-REDIS-2: Retry-After missing from 429 response")
+	}
+}